@@ -0,0 +1,114 @@
+/*
+Copyright © 2025 Adharsh Manikandan <debugslayer@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"spsyncpro_api/infra"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// initDB opens the database for the commands, tests replace it with sqlite
+var initDB = infra.InitGormDB
+
+// createAdminCmd creates an admin account directly in the database, it seeds
+// the first account of a deployment without going through the api
+var createAdminCmd = &cobra.Command{
+	Use:          "createadmin --email <email> --password <password>",
+	Short:        "create an admin account",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		email, _ := cmd.Flags().GetString("email")
+		password, _ := cmd.Flags().GetString("password")
+
+		db, err := initDB()
+		if err != nil {
+			return fmt.Errorf("error connecting to database: %w", err)
+		}
+
+		acc, err := createAdmin(
+			context.Background(),
+			account.NewAccountRepository(db),
+			account.NewAccountService(nil, nil),
+			email,
+			password,
+		)
+		if err != nil {
+			return fmt.Errorf("error creating admin %s: %w", email, err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "created admin account %d with email %s\n", acc.ID, acc.Email)
+		return nil
+	},
+}
+
+// createAdmin creates a verified account with the admin role, an existing
+// account with the email is ErrAccountAlreadyExists and is left unchanged
+func createAdmin(
+	ctx context.Context,
+	repository domain.AccountRepository,
+	service domain.AccountService,
+	email string,
+	password string,
+) (*domain.Account, error) {
+	email, err := utils.NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repository.GetAccountByEmail(ctx, email)
+	if err == nil {
+		return nil, domain.ErrAccountAlreadyExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := account.NewPasswordPolicy().Validate(password); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := service.HashPassword(ctx, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// the email comes from whoever runs the deployment, there is no
+	// verification mail to wait for
+	now := time.Now()
+	acc, err := repository.CreateAccount(ctx, &domain.Account{
+		Email:         email,
+		Password:      hashedPassword,
+		Role:          domain.RoleAdmin,
+		EmailVerified: true,
+		VerifiedAt:    &now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the account exists at this point, a failed activity log is reported
+	// without failing the command
+	if err := repository.LogAccountActivity(ctx, acc.ID, domain.ActivityRegister); err != nil {
+		log.Printf("error logging activity: %v", err)
+	}
+	return acc, nil
+}
+
+func init() {
+	createAdminCmd.Flags().String("email", "", "email of the admin account")
+	createAdminCmd.Flags().String("password", "", "password of the admin account")
+	_ = createAdminCmd.MarkFlagRequired("email")
+	_ = createAdminCmd.MarkFlagRequired("password")
+	rootCmd.AddCommand(createAdminCmd)
+}
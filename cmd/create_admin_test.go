@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/database"
+	"spsyncpro_api/pkg/domain"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestCreateAdminCmd(t *testing.T) {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	setup := func(t *testing.T) *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+			Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+		})
+		assert.NoError(t, err)
+		sqlDB, err := db.DB()
+		assert.NoError(t, err)
+		sqlDB.SetMaxOpenConns(1)
+		assert.NoError(t, database.AutoMigrate(db))
+
+		original := initDB
+		initDB = func() (*gorm.DB, error) { return db, nil }
+		t.Cleanup(func() { initDB = original })
+		return db
+	}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&out)
+		rootCmd.SetArgs(append([]string{"createadmin"}, args...))
+		defer rootCmd.SetArgs(nil)
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+
+	t.Run("should create a verified admin account", func(t *testing.T) {
+		db := setup(t)
+
+		out, err := run("--email", " Admin@Example.com ", "--password", "Sup3rSecret!")
+		assert.NoError(t, err)
+		assert.Contains(t, out, "created admin account")
+		assert.Contains(t, out, "admin@example.com")
+
+		repository := account.NewAccountRepository(db)
+		acc, err := repository.GetAccountByEmail(context.Background(), "admin@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RoleAdmin, acc.Role)
+		assert.True(t, acc.EmailVerified)
+
+		valid, _, err := account.NewAccountService(nil, nil).ComparePassword(context.Background(), "Sup3rSecret!", acc.Password)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("should fail when the email exists", func(t *testing.T) {
+		db := setup(t)
+
+		_, err := run("--email", "admin@example.com", "--password", "Sup3rSecret!")
+		assert.NoError(t, err)
+
+		out, err := run("--email", "admin@example.com", "--password", "An0therSecret!")
+		assert.ErrorIs(t, err, domain.ErrAccountAlreadyExists)
+		assert.Contains(t, out, "account already exists")
+
+		var count int64
+		db.Model(&domain.Account{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("should reject a password outside the policy", func(t *testing.T) {
+		db := setup(t)
+
+		_, err := run("--email", "admin@example.com", "--password", "short")
+		assert.Error(t, err)
+
+		var count int64
+		db.Model(&domain.Account{}).Count(&count)
+		assert.Zero(t, count)
+	})
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2025 Adharsh Manikandan <debugslayer@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"log"
+	"spsyncpro_api/infra"
+	"spsyncpro_api/pkg/database"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// migrateCmd groups the versioned schema migrations, deployed databases are
+// changed with these instead of AutoMigrate
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "apply, revert or inspect the versioned schema migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "apply every pending migration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		db := openMigrationDB()
+
+		applied, err := database.MigrateUp(ctx, db)
+		for _, migration := range applied {
+			log.Printf("applied migration %d %s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("error applying migrations: %v", err)
+		}
+
+		logSchemaVersion(ctx, db)
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [steps]",
+	Short: "revert the last applied migrations, one when steps is not given",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		steps := 1
+		if len(args) == 1 {
+			var err error
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				log.Fatalf("error reverting migrations: steps must be a positive number, got %q", args[0])
+			}
+		}
+
+		ctx := context.Background()
+		db := openMigrationDB()
+
+		reverted, err := database.MigrateDown(ctx, db, steps)
+		for _, migration := range reverted {
+			log.Printf("reverted migration %d %s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("error reverting migrations: %v", err)
+		}
+
+		logSchemaVersion(ctx, db)
+	},
+}
+
+var migrateVersionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "print the schema version of the database",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logSchemaVersion(context.Background(), openMigrationDB())
+	},
+}
+
+func openMigrationDB() *gorm.DB {
+	db, err := infra.OpenGormDB()
+	if err != nil {
+		log.Fatalf("error connecting to database: %v", err)
+	}
+	return db
+}
+
+func logSchemaVersion(ctx context.Context, db *gorm.DB) {
+	version, err := database.SchemaVersion(ctx, db)
+	if err != nil {
+		log.Fatalf("error reading schema version: %v", err)
+	}
+	log.Printf("schema is at version %d, latest is %d", version, database.LatestVersion())
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateVersionCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
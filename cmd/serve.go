@@ -10,10 +10,14 @@ import (
 	"os"
 	"os/signal"
 	"spsyncpro_api/infra"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/utils"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // serveCmd represents the serve command
@@ -27,9 +31,18 @@ var serveCmd = &cobra.Command{
 			return
 		}
 
+		if err := infra.ValidateConfig(infra.RequiredConfigKeys()); err != nil {
+			log.Fatalf("error validating config: %v", err)
+		}
+		if err := infra.ValidateEncryptionKey(); err != nil {
+			log.Fatalf("error validating config: %v", err)
+		}
+
 		logger := logrus.New()
+		logger.AddHook(&utils.RedactHook{})
+		logger.AddHook(&utils.TraceHook{})
 
-		shutdown, err := infra.SetupOtelSDK(context.Background())
+		shutdown, err := infra.SetupOtelSDK(context.Background(), logger)
 		if err != nil {
 			log.Printf("error setting up otel sdk: %v", err)
 			return
@@ -37,12 +50,24 @@ var serveCmd = &cobra.Command{
 		defer shutdown(context.Background())
 
 		config := infra.Config{
-			Port: port,
+			Port:      port,
+			AdminPort: viper.GetInt("ADMIN_PORT"),
 		}
 
-		db := infra.InitGormDB()
+		db, err := infra.InitGormDB()
+		if err != nil {
+			log.Printf("error connecting to database: %v", err)
+			return
+		}
+		infra.EncryptOrganizationSecrets(context.Background(), db, logger)
+		infra.DeleteOrphanedOrganizations(context.Background(), db, logger)
+
+		workerCtx, stopWorkers := context.WithCancel(context.Background())
+		defer stopWorkers()
 
-		srv := infra.NewServer(db, logger, config)
+		emailService := mailer.NewEmailService(logger)
+		activityLogger := account.NewActivityLogger(logger, account.NewAccountRepository(db))
+		srv := infra.NewServer(workerCtx, db, logger, emailService, activityLogger, config)
 
 		ch := make(chan os.Signal, 1)
 		signal.Notify(ch, os.Interrupt)
@@ -55,9 +80,23 @@ var serveCmd = &cobra.Command{
 
 		log.Println("api running on port", port)
 
+		var adminSrv *http.Server
+		if config.AdminPort != 0 {
+			adminSrv = infra.NewAdminServer(db, config)
+
+			go func() {
+				if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Fatalf("error serving the admin api: %v", err)
+				}
+			}()
+
+			log.Println("admin api running on port", config.AdminPort)
+		}
+
 		// block until the signal is received
 		<-ch
 		log.Println("shutting down server...")
+		stopWorkers()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -66,6 +105,20 @@ var serveCmd = &cobra.Command{
 			log.Fatalf("error shutting down server: %v", err)
 		}
 
+		if adminSrv != nil {
+			if err := adminSrv.Shutdown(ctx); err != nil {
+				log.Fatalf("error shutting down admin server: %v", err)
+			}
+		}
+
+		// requests have finished, send the emails they queued
+		if err := emailService.Shutdown(ctx); err != nil {
+			log.Printf("error draining the email queue: %v", err)
+		}
+		if err := activityLogger.Shutdown(ctx); err != nil {
+			log.Printf("error writing the buffered account activities: %v", err)
+		}
+
 		log.Println("server shutdown...")
 	},
 }
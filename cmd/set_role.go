@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 Adharsh Manikandan <debugslayer@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"log"
+	"slices"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/domain"
+
+	"github.com/spf13/cobra"
+)
+
+// setRoleCmd changes the role of an account directly in the database, it
+// makes the first admin who can then manage roles over the api
+var setRoleCmd = &cobra.Command{
+	Use:   "set-role <email> <role>",
+	Short: "set the role of an account to user or admin",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		email, role := args[0], args[1]
+		if !slices.Contains(domain.Roles, role) {
+			log.Fatalf("error setting role: %v", domain.ErrInvalidRole)
+		}
+
+		ctx := context.Background()
+		db, err := initDB()
+		if err != nil {
+			log.Fatalf("error connecting to database: %v", err)
+		}
+		repository := account.NewAccountRepository(db)
+
+		acc, err := repository.GetAccountByEmail(ctx, email)
+		if err != nil {
+			log.Fatalf("error getting account %s: %v", email, err)
+		}
+
+		acc.Role = role
+		if _, err := repository.UpdateAccount(ctx, acc); err != nil {
+			log.Fatalf("error setting role: %v", err)
+		}
+		if err := repository.LogAccountActivity(ctx, acc.ID, domain.ActivityRoleChange); err != nil {
+			log.Printf("error logging activity: %v", err)
+		}
+
+		log.Printf("account %s now has the %s role", email, role)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setRoleCmd)
+}
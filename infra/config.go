@@ -0,0 +1,112 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ErrMissingConfig is returned by ValidateConfig, the missing keys are listed
+// in the message
+var ErrMissingConfig = errors.New("missing required config")
+
+// DatabaseConfigKeys are needed to connect to postgres, DB_PASSWORD is left
+// out as trust and peer authentication connect without one
+var DatabaseConfigKeys = []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_NAME"}
+
+// LoadConfig reads the config file into viper under the environment, an env
+// var (including one from .env) takes precedence over the same key in the
+// file. cfgFile is read when given, otherwise config.yaml in the working
+// directory and then $HOME/.spsyncpro_api.yaml, running without a file is
+// fine. It returns the file that was read
+func LoadConfig(cfgFile string) (string, error) {
+	viper.AutomaticEnv()
+
+	if cfgFile == "" {
+		cfgFile = findConfigFile()
+		if cfgFile == "" {
+			return "", nil
+		}
+	}
+
+	viper.SetConfigFile(cfgFile)
+	if err := viper.ReadInConfig(); err != nil {
+		return "", fmt.Errorf("failed to read config file %s: %w", cfgFile, err)
+	}
+	return viper.ConfigFileUsed(), nil
+}
+
+// findConfigFile returns the first config file that exists, empty when there
+// is none
+func findConfigFile() string {
+	candidates := []string{"config.yaml"}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".spsyncpro_api.yaml"))
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// RequiredConfigKeys are the keys the server cannot start without, RS256
+// signs with JWT_PRIVATE_KEY_PATH in place of JWT_SECRET
+func RequiredConfigKeys() []string {
+	jwtKey := "JWT_SECRET"
+	if viper.GetString("JWT_ALGORITHM") == "RS256" {
+		jwtKey = "JWT_PRIVATE_KEY_PATH"
+	}
+	return append([]string{jwtKey, "ENCRYPTION_KEY"}, DatabaseConfigKeys...)
+}
+
+// ValidateEncryptionKey checks ENCRYPTION_KEY and every key in
+// ENCRYPTION_PREVIOUS_KEYS is an AES key size, secrets cannot be encrypted or
+// decrypted with a key of any other length
+func ValidateEncryptionKey() error {
+	length := len(viper.GetString("ENCRYPTION_KEY"))
+	if !validAESKeyLength(length) {
+		return fmt.Errorf("ENCRYPTION_KEY must be 16, 24, or 32 bytes, got %d", length)
+	}
+
+	for i, key := range strings.Split(viper.GetString("ENCRYPTION_PREVIOUS_KEYS"), ",") {
+		key = strings.TrimSpace(key)
+		if key != "" && !validAESKeyLength(len(key)) {
+			return fmt.Errorf("ENCRYPTION_PREVIOUS_KEYS key %d must be 16, 24, or 32 bytes, got %d", i+1, len(key))
+		}
+	}
+	return nil
+}
+
+func validAESKeyLength(length int) bool {
+	return length == 16 || length == 24 || length == 32
+}
+
+// MissingConfigKeys returns the keys without a value, an empty env var counts
+// as missing
+func MissingConfigKeys(keys []string) []string {
+	var missing []string
+	for _, key := range keys {
+		if viper.GetString(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// ValidateConfig returns ErrMissingConfig listing every key without a value,
+// so a deploy fails on startup with the whole list instead of one key at a
+// time on first use
+func ValidateConfig(keys []string) error {
+	missing := MissingConfigKeys(keys)
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingConfig, strings.Join(missing, ", "))
+	}
+	return nil
+}
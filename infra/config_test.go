@@ -0,0 +1,133 @@
+package infra
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	t.Run("should list every missing key", func(t *testing.T) {
+		viper.Reset()
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		viper.Set("DB_HOST", "localhost")
+		viper.Set("DB_PORT", "")
+		defer viper.Reset()
+
+		err := ValidateConfig(RequiredConfigKeys())
+		assert.ErrorIs(t, err, ErrMissingConfig)
+		assert.EqualError(t, err, "missing required config: JWT_SECRET, DB_PORT, DB_USER, DB_NAME")
+	})
+
+	t.Run("should pass with every key set", func(t *testing.T) {
+		for _, key := range RequiredConfigKeys() {
+			viper.Set(key, "value")
+		}
+		defer viper.Reset()
+
+		assert.NoError(t, ValidateConfig(RequiredConfigKeys()))
+	})
+
+	t.Run("should require the private key for RS256", func(t *testing.T) {
+		viper.Set("JWT_ALGORITHM", "RS256")
+		defer viper.Reset()
+
+		missing := MissingConfigKeys(RequiredConfigKeys())
+		assert.Contains(t, missing, "JWT_PRIVATE_KEY_PATH")
+		assert.NotContains(t, missing, "JWT_SECRET")
+	})
+
+	t.Run("should report missing database config before connecting", func(t *testing.T) {
+		viper.Reset()
+
+		_, err := OpenGormDB()
+		assert.ErrorIs(t, err, ErrMissingConfig)
+	})
+}
+
+func TestValidateEncryptionKey(t *testing.T) {
+	t.Run("should describe a key of the wrong length", func(t *testing.T) {
+		for _, key := range []string{"", "short", "myverystrongpasswordo32bitlength-and-more"} {
+			viper.Set("ENCRYPTION_KEY", key)
+
+			err := ValidateEncryptionKey()
+			assert.EqualError(t, err, fmt.Sprintf("ENCRYPTION_KEY must be 16, 24, or 32 bytes, got %d", len(key)))
+		}
+		viper.Reset()
+	})
+
+	t.Run("should describe a previous key of the wrong length", func(t *testing.T) {
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		viper.Set("ENCRYPTION_PREVIOUS_KEYS", "myoldpasswordthatwas32bytes-long, short")
+		defer viper.Reset()
+
+		err := ValidateEncryptionKey()
+		assert.EqualError(t, err, "ENCRYPTION_PREVIOUS_KEYS key 2 must be 16, 24, or 32 bytes, got 5")
+	})
+
+	t.Run("should accept every aes key size", func(t *testing.T) {
+		defer viper.Reset()
+
+		for _, size := range []int{16, 24, 32} {
+			viper.Set("ENCRYPTION_KEY", strings.Repeat("k", size))
+
+			assert.NoError(t, ValidateEncryptionKey())
+		}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	writeConfig := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(path, []byte("jwt_secret: from-file\ndb_host: file-host\n"), 0o600)
+		assert.NoError(t, err)
+		return path
+	}
+
+	t.Run("should read the given config file", func(t *testing.T) {
+		viper.Reset()
+		defer viper.Reset()
+		path := writeConfig(t)
+
+		used, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, path, used)
+		assert.Equal(t, "from-file", viper.GetString("JWT_SECRET"))
+	})
+
+	t.Run("should prefer env over the config file", func(t *testing.T) {
+		viper.Reset()
+		defer viper.Reset()
+		t.Setenv("DB_HOST", "env-host")
+
+		_, err := LoadConfig(writeConfig(t))
+		assert.NoError(t, err)
+		assert.Equal(t, "env-host", viper.GetString("DB_HOST"))
+		assert.Equal(t, "from-file", viper.GetString("JWT_SECRET"))
+	})
+
+	t.Run("should read config.yaml from the working directory", func(t *testing.T) {
+		viper.Reset()
+		defer viper.Reset()
+		path := writeConfig(t)
+		t.Chdir(filepath.Dir(path))
+
+		used, err := LoadConfig("")
+		assert.NoError(t, err)
+		assert.Equal(t, "config.yaml", used)
+		assert.Equal(t, "file-host", viper.GetString("DB_HOST"))
+	})
+
+	t.Run("should fail on an unreadable config file", func(t *testing.T) {
+		viper.Reset()
+		defer viper.Reset()
+
+		_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
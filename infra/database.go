@@ -1,18 +1,154 @@
 package infra
 
 import (
+	"context"
 	"fmt"
-	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/internal/organization"
+	"spsyncpro_api/pkg/database"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func InitGormDB() *gorm.DB {
-	var db *gorm.DB
-	var err error
+// pool defaults for when DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS or
+// DB_CONN_MAX_LIFETIME are not set
+const (
+	defaultDBMaxOpenConns    = 25
+	defaultDBMaxIdleConns    = 10
+	defaultDBConnMaxLifetime = 30 * time.Minute
+)
+
+// InitGormDB opens the database with OpenGormDB and, when DB_AUTO_MIGRATE is
+// on, brings the schema up to the models with AutoMigrate. A failed
+// connection or migration is returned for the caller to handle
+func InitGormDB() (*gorm.DB, error) {
+	db, err := OpenGormDB()
+	if err != nil {
+		return nil, err
+	}
+
+	if autoMigrate() {
+		if err := database.AutoMigrate(db); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	return db, nil
+}
+
+// OpenGormDB connects to postgres and sizes the connection pool without
+// touching the schema, the migrate command runs on it. Missing DB_* config
+// is reported before connecting
+func OpenGormDB() (*gorm.DB, error) {
+	if err := ValidateConfig(DatabaseConfigKeys); err != nil {
+		return nil, err
+	}
+
+	connStr := postgresDSN()
+
+	// TranslateError maps unique violations to gorm.ErrDuplicatedKey
+	db, err := gorm.Open(postgres.Open(connStr), &gorm.Config{
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	if err := configurePool(db); err != nil {
+		return nil, err
+	}
+
+	if viper.IsSet("DB_RETRY_ATTEMPTS") {
+		database.DefaultRetryPolicy.MaxAttempts = viper.GetInt("DB_RETRY_ATTEMPTS")
+	}
+	if delay := viper.GetDuration("DB_RETRY_DELAY"); delay > 0 {
+		database.DefaultRetryPolicy.BaseDelay = delay
+	}
+
+	return db, nil
+}
+
+// autoMigrate reports whether the server runs AutoMigrate on startup,
+// DB_AUTO_MIGRATE when it is set and outside production mode otherwise.
+// Production schemas are changed with the migrate command
+func autoMigrate() bool {
+	if viper.GetString("DB_AUTO_MIGRATE") != "" {
+		return viper.GetBool("DB_AUTO_MIGRATE")
+	}
+	return viper.GetString("SERVER_MODE") != "production"
+}
+
+// configurePool applies DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and
+// DB_CONN_MAX_LIFETIME to the pool under db, database/sql leaves open
+// connections unbounded otherwise
+func configurePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database pool: %w", err)
+	}
 
+	maxOpenConns := defaultDBMaxOpenConns
+	if viper.IsSet("DB_MAX_OPEN_CONNS") {
+		maxOpenConns = viper.GetInt("DB_MAX_OPEN_CONNS")
+	}
+	maxIdleConns := defaultDBMaxIdleConns
+	if viper.IsSet("DB_MAX_IDLE_CONNS") {
+		maxIdleConns = viper.GetInt("DB_MAX_IDLE_CONNS")
+	}
+	connMaxLifetime := defaultDBConnMaxLifetime
+	if viper.IsSet("DB_CONN_MAX_LIFETIME") {
+		connMaxLifetime = viper.GetDuration("DB_CONN_MAX_LIFETIME")
+	}
+
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	sqlDB.SetConnMaxLifetime(connMaxLifetime)
+	return nil
+}
+
+// EncryptOrganizationSecrets encrypts client secrets stored in plaintext by
+// earlier versions, a failure is logged and does not stop the server
+func EncryptOrganizationSecrets(ctx context.Context, db *gorm.DB, logger *logrus.Logger) {
+	organizationRepository := organization.NewOrganizationRepository(db)
+	organizationService, err := organization.NewOrganizationService(organizationRepository)
+	if err != nil {
+		logger.Errorf("failed to encrypt plaintext client secrets: %v", err)
+		return
+	}
+
+	encrypted, err := organization.EncryptPlaintextSecrets(ctx, organizationService, organizationRepository)
+	if err != nil {
+		logger.Errorf("failed to encrypt plaintext client secrets: %v", err)
+		return
+	}
+	if encrypted > 0 {
+		logger.Infof("encrypted %d plaintext client secrets", encrypted)
+	}
+}
+
+// DeleteOrphanedOrganizations removes organizations left behind by owner
+// accounts deleted before organizations were deleted with them, a failure is
+// logged and does not stop the server
+func DeleteOrphanedOrganizations(ctx context.Context, db *gorm.DB, logger *logrus.Logger) {
+	organizationRepository := organization.NewOrganizationRepository(db)
+
+	deleted, err := organizationRepository.DeleteOrphanedOrganizations(ctx)
+	if err != nil {
+		logger.Errorf("failed to delete orphaned organizations: %v", err)
+		return
+	}
+	if deleted > 0 {
+		logger.Infof("deleted %d organizations of deleted accounts", deleted)
+	}
+}
+
+// postgresDSN builds the postgres connection string from the DB_* config.
+// statement_timeout is passed as a runtime parameter so every session on the
+// pool is bounded, DB_STATEMENT_TIMEOUT is a duration like "30s"
+func postgresDSN() string {
 	host := viper.GetString("DB_HOST")
 	port := viper.GetString("DB_PORT")
 	user := viper.GetString("DB_USER")
@@ -29,17 +165,10 @@ func InitGormDB() *gorm.DB {
 
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s timezone=%s", host, port, user, password, dbname, sslmode, timezone)
 
-	db, err = gorm.Open(postgres.Open(connStr), &gorm.Config{})
-
-	if err != nil {
-		panic("failed to connect database")
+	statementTimeout := viper.GetDuration("DB_STATEMENT_TIMEOUT")
+	if statementTimeout > 0 {
+		connStr += fmt.Sprintf(" statement_timeout=%d", statementTimeout.Milliseconds())
 	}
 
-	db.AutoMigrate(
-		&domain.Account{},
-		&domain.AccountActivity{},
-		&domain.Organization{},
-	)
-
-	return db
+	return connStr
 }
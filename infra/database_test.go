@@ -0,0 +1,133 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	t.Run("should not set statement timeout by default", func(t *testing.T) {
+		viper.Reset()
+
+		assert.NotContains(t, postgresDSN(), "statement_timeout")
+	})
+
+	t.Run("should set statement timeout in milliseconds", func(t *testing.T) {
+		viper.Set("DB_STATEMENT_TIMEOUT", "1500ms")
+		defer viper.Reset()
+
+		assert.Contains(t, postgresDSN(), "statement_timeout=1500")
+	})
+}
+
+func TestConfigurePool(t *testing.T) {
+	openDB := func(t *testing.T) (*gorm.DB, *sql.DB) {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+			Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+		})
+		assert.NoError(t, err)
+		sqlDB, err := db.DB()
+		assert.NoError(t, err)
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		return db, sqlDB
+	}
+
+	// hold opens n connections at once and releases them back to the pool
+	hold := func(t *testing.T, sqlDB *sql.DB, n int) {
+		conns := make([]*sql.Conn, n)
+		for i := range conns {
+			conn, err := sqlDB.Conn(context.Background())
+			assert.NoError(t, err)
+			conns[i] = conn
+		}
+		for _, conn := range conns {
+			assert.NoError(t, conn.Close())
+		}
+	}
+
+	t.Run("should bound the pool by default", func(t *testing.T) {
+		viper.Reset()
+		db, sqlDB := openDB(t)
+
+		assert.NoError(t, configurePool(db))
+		assert.Equal(t, defaultDBMaxOpenConns, sqlDB.Stats().MaxOpenConnections)
+	})
+
+	t.Run("should apply the configured pool size", func(t *testing.T) {
+		viper.Set("DB_MAX_OPEN_CONNS", 4)
+		viper.Set("DB_MAX_IDLE_CONNS", 2)
+		defer viper.Reset()
+		db, sqlDB := openDB(t)
+
+		assert.NoError(t, configurePool(db))
+		assert.Equal(t, 4, sqlDB.Stats().MaxOpenConnections)
+
+		hold(t, sqlDB, 4)
+		assert.Equal(t, 2, sqlDB.Stats().Idle)
+		assert.Equal(t, int64(2), sqlDB.Stats().MaxIdleClosed)
+	})
+
+	t.Run("should close connections past their lifetime", func(t *testing.T) {
+		viper.Set("DB_CONN_MAX_LIFETIME", "10ms")
+		defer viper.Reset()
+		db, sqlDB := openDB(t)
+
+		assert.NoError(t, configurePool(db))
+
+		hold(t, sqlDB, 1)
+		time.Sleep(20 * time.Millisecond)
+		hold(t, sqlDB, 1)
+		assert.Equal(t, int64(1), sqlDB.Stats().MaxLifetimeClosed)
+	})
+}
+
+// requires a running postgres, configured through the usual DB_* env vars
+func TestInitGormDB_StatementTimeout(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST not set, skipping postgres integration test")
+	}
+	viper.AutomaticEnv()
+	viper.Set("DB_STATEMENT_TIMEOUT", "2s")
+	defer viper.Reset()
+
+	db, err := InitGormDB()
+	assert.NoError(t, err)
+
+	var timeout string
+	err = db.Raw("SHOW statement_timeout").Scan(&timeout).Error
+	assert.NoError(t, err)
+	assert.Equal(t, "2s", timeout)
+}
+
+func TestAutoMigrate(t *testing.T) {
+	t.Run("should auto migrate outside production", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "debug")
+		defer viper.Reset()
+
+		assert.True(t, autoMigrate())
+	})
+
+	t.Run("should not auto migrate in production", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "production")
+		defer viper.Reset()
+
+		assert.False(t, autoMigrate())
+	})
+
+	t.Run("should follow DB_AUTO_MIGRATE when set", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "production")
+		viper.Set("DB_AUTO_MIGRATE", "true")
+		defer viper.Reset()
+
+		assert.True(t, autoMigrate())
+	})
+}
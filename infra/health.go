@@ -0,0 +1,68 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"spsyncpro_api/pkg/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// readinessTimeout bounds the database ping, a probe must not hang on a
+// database that accepts connections but never answers
+const readinessTimeout = 2 * time.Second
+
+const (
+	readinessOK          = "ok"
+	readinessUnavailable = "unavailable"
+)
+
+var errDatabaseNotConfigured = errors.New("database is not configured")
+
+type ReadinessResponse struct {
+	Status  string            `json:"status"`
+	Version string            `json:"version"`
+	Checks  map[string]string `json:"checks"`
+}
+
+// readinessHandler answers 200 once the database responds to a ping and 503
+// with the failed check otherwise
+func readinessHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		utils.SetCacheControl(c, "CACHE_CONTROL_HEALTH", utils.CacheControlHealth)
+
+		response := ReadinessResponse{
+			Status:  readinessOK,
+			Version: Version,
+			Checks:  map[string]string{"database": readinessOK},
+		}
+
+		if err := pingDatabase(c.Request.Context(), db); err != nil {
+			response.Status = readinessUnavailable
+			response.Checks["database"] = err.Error()
+			c.JSON(http.StatusServiceUnavailable, response)
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func pingDatabase(ctx context.Context, db *gorm.DB) error {
+	if db == nil {
+		return errDatabaseNotConfigured
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,65 @@
+package infra
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	openDB := func(t *testing.T) *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+			Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+		})
+		assert.NoError(t, err)
+		return db
+	}
+
+	ready := func(db *gorm.DB) (*httptest.ResponseRecorder, ReadinessResponse) {
+		router := gin.New()
+		setupAdminRoutes(router, db)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health/ready", nil))
+
+		var response ReadinessResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	t.Run("should be ready when the database answers", func(t *testing.T) {
+		w, response := ready(openDB(t))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ok", response.Status)
+		assert.Equal(t, Version, response.Version)
+		assert.Equal(t, "ok", response.Checks["database"])
+	})
+
+	t.Run("should answer 503 when the database handle is closed", func(t *testing.T) {
+		db := openDB(t)
+		sqlDB, err := db.DB()
+		assert.NoError(t, err)
+		assert.NoError(t, sqlDB.Close())
+
+		w, response := ready(db)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "unavailable", response.Status)
+		assert.Equal(t, Version, response.Version)
+		assert.Contains(t, response.Checks["database"], "closed")
+	})
+
+	t.Run("should answer 503 without a database", func(t *testing.T) {
+		w, response := ready(nil)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "database is not configured", response.Checks["database"])
+	})
+}
@@ -4,26 +4,62 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"spsyncpro_api/pkg/utils"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/log/global"
+	lognoop "go.opentelemetry.io/otel/log/noop"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
 	"go.opentelemetry.io/otel/propagation"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-func SetupOtelSDK(ctx context.Context) (func(context.Context) error, error) {
+// provider constructors of SetupOtelSDK, tests replace them to fail
+var (
+	tracerProviderFactory = newTracerProvider
+	meterProviderFactory  = newMetricProvider
+	loggerProviderFactory = newLoggerProvider
+)
+
+// otelEnabled is OTEL_ENABLED, on unless turned off for local development and
+// tests without a collector. The standard OTEL_SDK_DISABLED turns it off too
+func otelEnabled() bool {
+	if viper.GetBool("OTEL_SDK_DISABLED") {
+		return false
+	}
+	return viper.GetString("OTEL_ENABLED") == "" || viper.GetBool("OTEL_ENABLED")
+}
+
+// setNoopProviders installs providers that record nothing, instrumented code
+// runs unchanged without exporting
+func setNoopProviders() {
+	otel.SetTracerProvider(tracenoop.NewTracerProvider())
+	otel.SetMeterProvider(metricnoop.NewMeterProvider())
+	global.SetLoggerProvider(lognoop.NewLoggerProvider())
+}
+
+// SetupOtelSDK registers the global providers exporting to the collector, an
+// unreachable collector never blocks the app and its errors go to logger
+// rate limited. The globals are only replaced once every provider is built, a
+// failure shuts down the ones built so far and is returned. With otel turned
+// off no connection is made, the globals are no-ops and shutdown does nothing.
+// /metrics then serves only the runtime metrics of the prometheus registry.
+func SetupOtelSDK(ctx context.Context, logger logrus.FieldLogger) (func(context.Context) error, error) {
 	var shutdownFuncs []func(context.Context) error
-	var err error
 
 	// shutdown calls cleanup functions registered via shutdownFuncs.
 	// The errors from the calls are joined.
@@ -37,43 +73,54 @@ func SetupOtelSDK(ctx context.Context) (func(context.Context) error, error) {
 		return err
 	}
 	// handleErr calls shutdown for cleanup and makes sure that all errors are returned.
-	handleErr := func(inErr error) {
-		err = errors.Join(inErr, shutdown(ctx))
+	handleErr := func(inErr error) error {
+		return errors.Join(inErr, shutdown(ctx))
 	}
 
 	// propagators are used to propagate the trace context and baggage across the different services.
 	initPropagators()
 
+	if !otelEnabled() {
+		logger.Info("opentelemetry export is disabled")
+		setNoopProviders()
+		return func(context.Context) error { return nil }, nil
+	}
+
+	guard := newExportGuard(logger)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(guard.handleError))
+
 	// initialize the gRPC connection
 	conn, err := initConn()
 	if err != nil {
 		return nil, err
 	}
+	shutdownFuncs = append(shutdownFuncs, func(context.Context) error { return conn.Close() })
 
 	// tracer provider is used to create and manage the tracers.
-	tp, err := newTracerProvider(ctx, conn)
+	tp, err := tracerProviderFactory(ctx, conn, guard)
 	if err != nil {
-		handleErr(err)
+		return nil, handleErr(fmt.Errorf("failed to create tracer provider: %w", err))
 	}
-	otel.SetTracerProvider(tp)
 	shutdownFuncs = append(shutdownFuncs, tp.Shutdown)
 
 	// metric provider is used to create and manage the metrics.
-	mp, err := newMetricProvider(ctx, conn)
+	mp, err := meterProviderFactory(ctx, conn, guard)
 	if err != nil {
-		handleErr(err)
+		return nil, handleErr(fmt.Errorf("failed to create meter provider: %w", err))
 	}
-	otel.SetMeterProvider(mp)
 	shutdownFuncs = append(shutdownFuncs, mp.Shutdown)
 
 	// logger provider is used to create and manage the loggers.
-	lp, err := newLoggerProvider(ctx, conn)
+	lp, err := loggerProviderFactory(ctx, conn, guard)
 	if err != nil {
-		handleErr(err)
+		return nil, handleErr(fmt.Errorf("failed to create logger provider: %w", err))
 	}
-	global.SetLoggerProvider(lp)
 	shutdownFuncs = append(shutdownFuncs, lp.Shutdown)
 
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+	global.SetLoggerProvider(lp)
+
 	return shutdown, nil
 }
 
@@ -108,7 +155,7 @@ func initConn() (*grpc.ClientConn, error) {
 	return conn, err
 }
 
-func newTracerProvider(ctx context.Context, conn *grpc.ClientConn) (*sdktrace.TracerProvider, error) {
+func newTracerProvider(ctx context.Context, conn *grpc.ClientConn, guard *exportGuard) (*sdktrace.TracerProvider, error) {
 	traceExporter, err := otlptracegrpc.New(
 		ctx,
 		otlptracegrpc.WithGRPCConn(conn),
@@ -125,14 +172,17 @@ func newTracerProvider(ctx context.Context, conn *grpc.ClientConn) (*sdktrace.Tr
 	}
 
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithBatcher(&guardedSpanExporter{
+			SpanExporter: &redactingExporter{SpanExporter: traceExporter},
+			guard:        guard,
+		}),
 		sdktrace.WithResource(res),
 	)
 
 	return tp, nil
 }
 
-func newMetricProvider(ctx context.Context, conn *grpc.ClientConn) (*sdkmetric.MeterProvider, error) {
+func newMetricProvider(ctx context.Context, conn *grpc.ClientConn, guard *exportGuard) (*sdkmetric.MeterProvider, error) {
 	metricExporter, err := otlpmetricgrpc.New(ctx,
 		otlpmetricgrpc.WithGRPCConn(conn),
 		otlpmetricgrpc.WithInsecure(),
@@ -147,15 +197,22 @@ func newMetricProvider(ctx context.Context, conn *grpc.ClientConn) (*sdkmetric.M
 		return nil, err
 	}
 
+	// prometheus reader backs the /metrics endpoint
+	promExporter, err := otelprom.New()
+	if err != nil {
+		return nil, err
+	}
+
 	mp := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(&guardedMetricExporter{Exporter: metricExporter, guard: guard})),
+		sdkmetric.WithReader(promExporter),
 		sdkmetric.WithResource(res),
 	)
 
 	return mp, nil
 }
 
-func newLoggerProvider(ctx context.Context, conn *grpc.ClientConn) (*sdklog.LoggerProvider, error) {
+func newLoggerProvider(ctx context.Context, conn *grpc.ClientConn, guard *exportGuard) (*sdklog.LoggerProvider, error) {
 	logExporter, err := otlploggrpc.New(
 		ctx,
 		otlploggrpc.WithGRPCConn(conn),
@@ -173,7 +230,7 @@ func newLoggerProvider(ctx context.Context, conn *grpc.ClientConn) (*sdklog.Logg
 
 	loggerProvider := sdklog.NewLoggerProvider(
 		sdklog.WithProcessor(
-			sdklog.NewBatchProcessor(logExporter),
+			sdklog.NewBatchProcessor(&guardedLogExporter{Exporter: logExporter, guard: guard}),
 		),
 		sdklog.WithResource(res),
 	)
@@ -181,6 +238,27 @@ func newLoggerProvider(ctx context.Context, conn *grpc.ClientConn) (*sdklog.Logg
 	return loggerProvider, nil
 }
 
+// redactingExporter masks secret attributes before spans leave the process
+type redactingExporter struct {
+	sdktrace.SpanExporter
+}
+
+func (e *redactingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	redacted := make([]sdktrace.ReadOnlySpan, len(spans))
+	for i, span := range spans {
+		redacted[i] = &redactedSpan{ReadOnlySpan: span}
+	}
+	return e.SpanExporter.ExportSpans(ctx, redacted)
+}
+
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s *redactedSpan) Attributes() []attribute.KeyValue {
+	return utils.RedactAttributes(s.ReadOnlySpan.Attributes())
+}
+
 // newResource creates a new OpenTelemetry resource with service name and version
 func newResource() (*resource.Resource, error) {
 	return resource.New(
@@ -189,7 +267,7 @@ func newResource() (*resource.Resource, error) {
 			// Service name - this will replace "unknown_service:main"
 			semconv.ServiceNameKey.String("spsyncpro-api"),
 			// Service version
-			semconv.ServiceVersionKey.String("1.0.0"),
+			semconv.ServiceVersionKey.String(Version),
 			// Service namespace (optional)
 			semconv.ServiceNamespaceKey.String("knullsoft"),
 			// Additional attributes
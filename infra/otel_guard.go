@@ -0,0 +1,179 @@
+package infra
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	defaultOtelExportTimeout    = 10 * time.Second
+	defaultOtelErrorLogInterval = time.Minute
+	defaultOtelProbeInterval    = 30 * time.Second
+)
+
+// exportGuard keeps an unreachable collector from affecting the app. Every
+// export is bounded by a timeout, failures are logged at most once per
+// interval, and after OTEL_EXPORT_DROP_AFTER consecutive failures telemetry is
+// dropped, with a single export let through per probe interval to notice the
+// collector coming back.
+type exportGuard struct {
+	logger        logrus.FieldLogger
+	timeout       time.Duration
+	logInterval   time.Duration
+	dropAfter     int
+	probeInterval time.Duration
+	now           func() time.Time
+
+	mu         sync.Mutex
+	failures   int
+	suppressed int
+	lastLog    time.Time
+	lastProbe  time.Time
+}
+
+func newExportGuard(logger logrus.FieldLogger) *exportGuard {
+	timeout := viper.GetDuration("OTEL_EXPORT_TIMEOUT")
+	if timeout <= 0 {
+		timeout = defaultOtelExportTimeout
+	}
+	logInterval := viper.GetDuration("OTEL_ERROR_LOG_INTERVAL")
+	if logInterval <= 0 {
+		logInterval = defaultOtelErrorLogInterval
+	}
+	probeInterval := viper.GetDuration("OTEL_EXPORT_PROBE_INTERVAL")
+	if probeInterval <= 0 {
+		probeInterval = defaultOtelProbeInterval
+	}
+
+	return &exportGuard{
+		logger:        logger,
+		timeout:       timeout,
+		logInterval:   logInterval,
+		dropAfter:     max(viper.GetInt("OTEL_EXPORT_DROP_AFTER"), 0),
+		probeInterval: probeInterval,
+		now:           time.Now,
+	}
+}
+
+// export runs fn unless telemetry is being dropped. The error is recorded
+// here instead of returned so the sdk does not log every failure again.
+func (g *exportGuard) export(ctx context.Context, signal string, fn func(context.Context) error) error {
+	if !g.allow() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	g.record(signal, fn(ctx))
+	return nil
+}
+
+func (g *exportGuard) dropping() bool {
+	return g.dropAfter > 0 && g.failures >= g.dropAfter
+}
+
+func (g *exportGuard) allow() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.dropping() {
+		return true
+	}
+	now := g.now()
+	if now.Sub(g.lastProbe) < g.probeInterval {
+		return false
+	}
+	g.lastProbe = now
+	return true
+}
+
+func (g *exportGuard) record(signal string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err == nil {
+		if g.dropping() {
+			g.logger.Infof("otel collector is reachable again, resuming telemetry")
+		}
+		g.failures = 0
+		return
+	}
+
+	g.failures++
+	if g.dropAfter > 0 && g.failures == g.dropAfter {
+		g.lastProbe = g.now()
+		g.logger.Warnf("dropping telemetry after %d consecutive otel export failures: %v", g.failures, err)
+		return
+	}
+	g.logf("otel %s export failed (%d consecutive failures): %v", signal, g.failures, err)
+}
+
+// handleError is the otel error handler, sdk errors outside exports share the
+// rate limit of export failures
+func (g *exportGuard) handleError(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.logf("otel error: %v", err)
+}
+
+// logf logs at most once per log interval, counting what it suppressed in
+// between. Callers hold mu.
+func (g *exportGuard) logf(format string, args ...any) {
+	now := g.now()
+	if !g.lastLog.IsZero() && now.Sub(g.lastLog) < g.logInterval {
+		g.suppressed++
+		return
+	}
+
+	entry := g.logger
+	if g.suppressed > 0 {
+		entry = entry.WithField("suppressed", g.suppressed)
+	}
+	entry.Warnf(format, args...)
+
+	g.lastLog = now
+	g.suppressed = 0
+}
+
+type guardedSpanExporter struct {
+	sdktrace.SpanExporter
+	guard *exportGuard
+}
+
+func (e *guardedSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return e.guard.export(ctx, "trace", func(ctx context.Context) error {
+		return e.SpanExporter.ExportSpans(ctx, spans)
+	})
+}
+
+type guardedMetricExporter struct {
+	sdkmetric.Exporter
+	guard *exportGuard
+}
+
+func (e *guardedMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.guard.export(ctx, "metric", func(ctx context.Context) error {
+		return e.Exporter.Export(ctx, rm)
+	})
+}
+
+type guardedLogExporter struct {
+	sdklog.Exporter
+	guard *exportGuard
+}
+
+func (e *guardedLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	return e.guard.export(ctx, "log", func(ctx context.Context) error {
+		return e.Exporter.Export(ctx, records)
+	})
+}
@@ -0,0 +1,140 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// unreachableExporter fails every export, blocking until the deadline like a
+// collector that does not answer
+type unreachableExporter struct {
+	mu      sync.Mutex
+	calls   int
+	healthy bool
+}
+
+func (e *unreachableExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	e.calls++
+	healthy := e.healthy
+	e.mu.Unlock()
+
+	if healthy {
+		return nil
+	}
+	<-ctx.Done()
+	return errors.New("collector unreachable")
+}
+
+func (e *unreachableExporter) Shutdown(ctx context.Context) error { return nil }
+
+func (e *unreachableExporter) callCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+func (e *unreachableExporter) setHealthy(healthy bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.healthy = healthy
+}
+
+func TestExportGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	setup := func(t *testing.T) (*exportGuard, *unreachableExporter, *logtest.Hook, *time.Time, http.Handler) {
+		logger, hook := logtest.NewNullLogger()
+		guard := newExportGuard(logger)
+		now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+		guard.now = func() time.Time { return now }
+
+		exporter := &unreachableExporter{}
+		// a syncer exports as each span ends, a blocked exporter would hold
+		// up every request without the guard
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(&guardedSpanExporter{SpanExporter: exporter, guard: guard}))
+		t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+		router := gin.New()
+		router.Use(otelgin.Middleware("spsyncpro-api", otelgin.WithTracerProvider(tp)))
+		router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		return guard, exporter, hook, &now, router
+	}
+
+	serve := func(t *testing.T, handler http.Handler, requests int) {
+		start := time.Now()
+		for range requests {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		assert.Less(t, time.Since(start), time.Second)
+	}
+
+	t.Run("should keep serving and rate limit the errors", func(t *testing.T) {
+		viper.Set("OTEL_EXPORT_TIMEOUT", "5ms")
+		defer viper.Reset()
+
+		_, exporter, hook, now, handler := setup(t)
+
+		serve(t, handler, 10)
+		assert.Equal(t, 10, exporter.callCount())
+		assert.Len(t, hook.AllEntries(), 1)
+		assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+
+		// the next error after the interval reports what was suppressed
+		*now = now.Add(defaultOtelErrorLogInterval)
+		serve(t, handler, 1)
+		assert.Len(t, hook.AllEntries(), 2)
+		assert.Equal(t, 9, hook.LastEntry().Data["suppressed"])
+	})
+
+	t.Run("should drop telemetry after the threshold and recover", func(t *testing.T) {
+		viper.Set("OTEL_EXPORT_TIMEOUT", "5ms")
+		viper.Set("OTEL_EXPORT_DROP_AFTER", 3)
+		viper.Set("OTEL_EXPORT_PROBE_INTERVAL", "30s")
+		defer viper.Reset()
+
+		guard, exporter, hook, now, handler := setup(t)
+
+		serve(t, handler, 10)
+		assert.Equal(t, 3, exporter.callCount())
+		assert.Contains(t, hook.LastEntry().Message, "dropping telemetry")
+
+		// a single probe per interval while the collector is still down
+		*now = now.Add(30 * time.Second)
+		serve(t, handler, 5)
+		assert.Equal(t, 4, exporter.callCount())
+
+		exporter.setHealthy(true)
+		*now = now.Add(30 * time.Second)
+		serve(t, handler, 5)
+		assert.Equal(t, 9, exporter.callCount())
+		assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+		assert.Zero(t, guard.failures)
+	})
+
+	t.Run("should rate limit sdk errors with export failures", func(t *testing.T) {
+		logger, hook := logtest.NewNullLogger()
+		guard := newExportGuard(logger)
+
+		for range 5 {
+			guard.handleError(errors.New("queue full"))
+		}
+		assert.Len(t, hook.AllEntries(), 1)
+	})
+}
@@ -0,0 +1,115 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/mailer"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+)
+
+func TestSetupOtelSDK(t *testing.T) {
+	logger := logrus.New()
+
+	// every test starts from a no-op global that setup must not replace on failure
+	installed := func(t *testing.T) noop.TracerProvider {
+		provider := noop.NewTracerProvider()
+		otel.SetTracerProvider(provider)
+		t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+		return provider
+	}
+
+	t.Run("should install no-op providers when disabled", func(t *testing.T) {
+		// no provider is built, a failing one would fail setup
+		original := tracerProviderFactory
+		tracerProviderFactory = func(context.Context, *grpc.ClientConn, *exportGuard) (*sdktrace.TracerProvider, error) {
+			return nil, errors.New("exporter failed")
+		}
+		t.Cleanup(func() { tracerProviderFactory = original })
+		defer viper.Reset()
+
+		for key, value := range map[string]bool{"OTEL_ENABLED": false, "OTEL_SDK_DISABLED": true} {
+			viper.Reset()
+			viper.Set(key, value)
+			installed(t)
+
+			shutdown, err := SetupOtelSDK(context.Background(), logger)
+			assert.NoError(t, err, key)
+
+			_, span := otel.Tracer("test").Start(context.Background(), "span")
+			assert.False(t, span.IsRecording(), key)
+			assert.NoError(t, shutdown(context.Background()), key)
+		}
+	})
+
+	t.Run("should serve requests with otel disabled", func(t *testing.T) {
+		viper.Set("OTEL_ENABLED", false)
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+		installed(t)
+
+		shutdown, err := SetupOtelSDK(context.Background(), logger)
+		assert.NoError(t, err)
+		defer shutdown(context.Background())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		srv := NewServer(ctx, nil, logrus.New(), mailer.NewEmailService(logrus.New()), nil, Config{Port: 8080})
+
+		for _, path := range []string{"/api/v1/health", "/metrics"} {
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			assert.Equal(t, http.StatusOK, w.Code, path)
+		}
+	})
+
+	t.Run("should return the error of the tracer provider", func(t *testing.T) {
+		original := tracerProviderFactory
+		tracerProviderFactory = func(context.Context, *grpc.ClientConn, *exportGuard) (*sdktrace.TracerProvider, error) {
+			return nil, errors.New("exporter failed")
+		}
+		t.Cleanup(func() { tracerProviderFactory = original })
+		provider := installed(t)
+
+		shutdown, err := SetupOtelSDK(context.Background(), logger)
+		assert.ErrorContains(t, err, "failed to create tracer provider: exporter failed")
+		assert.Nil(t, shutdown)
+		assert.Equal(t, provider, otel.GetTracerProvider())
+	})
+
+	t.Run("should shut down the tracer provider when a later provider fails", func(t *testing.T) {
+		var built *sdktrace.TracerProvider
+		originalTracer, originalMeter := tracerProviderFactory, meterProviderFactory
+		tracerProviderFactory = func(ctx context.Context, conn *grpc.ClientConn, guard *exportGuard) (*sdktrace.TracerProvider, error) {
+			tp, err := originalTracer(ctx, conn, guard)
+			built = tp
+			return tp, err
+		}
+		meterProviderFactory = func(context.Context, *grpc.ClientConn, *exportGuard) (*sdkmetric.MeterProvider, error) {
+			return nil, errors.New("exporter failed")
+		}
+		t.Cleanup(func() { tracerProviderFactory, meterProviderFactory = originalTracer, originalMeter })
+		provider := installed(t)
+
+		shutdown, err := SetupOtelSDK(context.Background(), logger)
+		assert.ErrorContains(t, err, "failed to create meter provider")
+		assert.Nil(t, shutdown)
+		assert.Equal(t, provider, otel.GetTracerProvider())
+
+		// a shut down provider hands out tracers that do not record
+		if assert.NotNil(t, built) {
+			_, span := built.Tracer("test").Start(context.Background(), "span")
+			assert.False(t, span.IsRecording())
+		}
+	})
+}
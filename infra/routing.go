@@ -1,43 +1,172 @@
 package infra
 
 import (
+	"context"
 	"spsyncpro_api/internal/account"
+	"spsyncpro_api/internal/debug"
 	"spsyncpro_api/internal/organization"
+	"spsyncpro_api/pkg/breachcheck"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/featureflag"
 	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/msgraphapi"
+	"spsyncpro_api/pkg/utils"
+	"spsyncpro_api/pkg/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
 
 func SetupRoutes(
+	ctx context.Context,
 	rg *gin.RouterGroup,
 	db *gorm.DB,
 	logger *logrus.Logger,
+	emailService mailer.EmailService,
+	activityLogger domain.ActivityLogger,
 ) {
-	emailService := mailer.NewEmailService()
+	features := featureflag.NewEnvProvider()
+
+	// emailed links are built on SERVER_URL and FRONTEND_URL, a plaintext
+	// url in production fails at startup instead of on the first email
+	if viper.GetString("SERVER_URL") != "" {
+		if err := account.CheckServerURL(); err != nil {
+			panic(err)
+		}
+	}
 
 	accountRepository := account.NewAccountRepository(db)
-	accountService := account.NewAccountService(emailService)
+	signingKeyRepository := account.NewSigningKeyRepository(db)
+	keyStore, err := account.NewKeyStore(signingKeyRepository)
+	if err != nil {
+		panic(err)
+	}
+	accountService := account.NewAccountService(emailService, keyStore)
 	accountHandler := account.NewAccountHandler(logger, accountService, accountRepository)
+	accountHandler.SetBreachedPasswordChecker(breachcheck.NewHIBPChecker())
+	if activityLogger != nil {
+		accountHandler.SetActivityLogger(activityLogger)
+	}
+
+	// office and ci ips exempt from login rate limiting and lockout
+	securityBypass, err := utils.ParseIPAllowList(viper.GetString("SECURITY_BYPASS_CIDRS"))
+	if err != nil {
+		panic(err)
+	}
+	accountHandler.SetSecurityBypass(securityBypass)
 
 	rg.POST("/account/register", accountHandler.RegisterAccount)
-	rg.POST("/account/login", accountHandler.LoginAccount)
+	loginLimiter := account.NewLoginLimiter(account.NewMemoryLoginAttemptStore())
+	loginLimiter.SetBypass(securityBypass)
+	rg.POST("/account/login", account.LoginRateLimitMiddleware(loginLimiter), accountHandler.LoginAccount)
+	rg.POST("/account/refresh", featureflag.Require(features, domain.FeatureRefreshTokens), accountHandler.RefreshToken)
 	rg.POST("/account/forgot-password", accountHandler.ForgotPassword)
 	rg.POST("/account/reset-password", accountHandler.ResetPassword)
+	rg.GET("/account/reset-password/validate", accountHandler.ValidateResetToken)
+	rg.POST("/account/auth-methods", accountHandler.GetAuthMethods)
+	rg.POST("/account/verify-email", featureflag.Require(features, domain.FeatureEmailVerification), accountHandler.VerifyEmail)
+	rg.POST("/account/resend-verification", featureflag.Require(features, domain.FeatureEmailVerification), accountHandler.ResendVerification)
+	rg.POST("/account/change-email/confirm", accountHandler.ConfirmEmailChange)
+	rg.GET("/account/pending-action", accountHandler.GetPendingAction)
+	rg.POST("/account/pending-action/cancel", accountHandler.CancelPendingAction)
+	rg.GET("/account/csrf-token", accountHandler.IssueCSRFToken)
+
+	pendingActionWorker := account.NewPendingActionWorker(logger, accountRepository)
+	revokedTokenCleanupWorker := account.NewRevokedTokenCleanupWorker(logger, accountRepository)
+	inactivityWorker := account.NewInactivityWorker(logger, accountRepository, accountService)
+
+	webhookRepository := account.NewAccountWebhookRepository(db)
+	webhookSender := webhook.NewSender()
+	if features.IsEnabled(ctx, domain.FeatureWebhooks) {
+		notifier, err := account.NewActivityWebhookNotifier(logger, webhookRepository, webhookSender)
+		if err != nil {
+			panic(err)
+		}
+		accountHandler.SetActivityNotifier(notifier)
+		pendingActionWorker.SetActivityNotifier(notifier)
+		inactivityWorker.SetActivityNotifier(notifier)
+	}
+
+	go pendingActionWorker.Start(ctx)
+	go revokedTokenCleanupWorker.Start(ctx)
+	go inactivityWorker.Start(ctx)
+
+	// debug only routes, never registered in production mode
+	if ginServerMode() == gin.DebugMode {
+		debugHandler := debug.NewDebugHandler(logger)
+		rg.GET("/debug/email-preview", debugHandler.EmailPreview)
+	}
 
-	rg.Use(account.AuthMiddleware(accountService))
+	rg.Use(account.AuthMiddleware(accountService, accountRepository), account.CSRFMiddleware())
 
-	rg.GET("/account/profile", accountHandler.GetProfile)
+	rg.GET("/account/activity", accountHandler.GetActivityHistory)
 	rg.POST("/account/logout", accountHandler.LogoutAccount)
-	rg.POST("/account/change-password", accountHandler.ChangePassword)
+	rg.POST("/account/logout/all", accountHandler.LogoutAllSessions)
+
+	// routes that read the account load it once in the middleware
+	withAccount := rg.Group("", account.LoadAccountMiddleware(accountRepository))
+	withAccount.GET("/account/profile", accountHandler.GetProfile)
+	withAccount.POST("/account/change-password", accountHandler.ChangePassword)
+	withAccount.POST("/account/change-email", accountHandler.ChangeEmail)
+	withAccount.DELETE("/account", accountHandler.DeleteAccount)
+	rg.POST("/account/api-keys", accountHandler.CreateAPIKey)
+	rg.GET("/account/api-keys", accountHandler.ListAPIKeys)
+	rg.DELETE("/account/api-keys/:id", accountHandler.RevokeAPIKey)
+
+	msgraphapi.DefaultTenantLimiter.SetMinInterval(viper.GetDuration("GRAPH_TENANT_TOKEN_INTERVAL"))
+	if version := viper.GetString("GRAPH_API_VERSION"); version != "" {
+		msgraphapi.DefaultAPIVersion = version
+	}
+	if timeout := viper.GetDuration("GRAPH_REQUEST_TIMEOUT"); timeout > 0 {
+		msgraphapi.DefaultRequestTimeout = timeout
+	}
+	if viper.IsSet("GRAPH_MAX_RETRIES") {
+		msgraphapi.DefaultRetryPolicy.MaxRetries = viper.GetInt("GRAPH_MAX_RETRIES")
+	}
 
 	organizationRepository := organization.NewOrganizationRepository(db)
-	organizationService := organization.NewOrganizationService()
+	organizationService, err := organization.NewOrganizationService(organizationRepository)
+	if err != nil {
+		panic(err)
+	}
 	organizationHandler := organization.NewOrganizationHandler(organizationService, organizationRepository)
 
 	rg.POST("/organization/upsert", organizationHandler.UpsertOrganization)
 	rg.GET("/organization/get", organizationHandler.GetOrganization)
 	rg.DELETE("/organization/delete", organizationHandler.DeleteOrganization)
 	rg.GET("/organization/check-authorization", organizationHandler.CheckAuthorization)
+	rg.POST("/organization/:id/refresh-token", organizationHandler.RefreshToken)
+	rg.POST("/organization/rotate-secret", organizationHandler.RotateClientSecret)
+
+	// admin routes are for accounts with the admin role, the first admin is
+	// made with the createadmin command
+	admin := rg.Group("/admin", account.RequireRole(domain.RoleAdmin))
+	admin.POST("/jwt/rotate", accountHandler.RotateSigningKey)
+	admin.POST("/account/unlock", accountHandler.UnlockAccount)
+	admin.GET("/organizations/health", organizationHandler.GetOrganizationHealth)
+
+	accountAdmin := admin.Group("/accounts")
+	accountAdmin.GET("", accountHandler.ListAccounts)
+	accountAdmin.PUT("/:id/role", accountHandler.SetAccountRole)
+
+	webhookHandler, err := account.NewWebhookHandler(logger, webhookRepository, webhookSender)
+	if err != nil {
+		panic(err)
+	}
+	webhooks := admin.Group("/webhooks", featureflag.Require(features, domain.FeatureWebhooks))
+	webhooks.POST("", webhookHandler.CreateWebhook)
+	webhooks.GET("", webhookHandler.ListWebhooks)
+	webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+	webhooks.POST("/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+	webhooks.POST("/:id/test", webhookHandler.TestWebhook)
+
+	// the owner of an organization can test the webhooks configured for it
+	rg.POST(
+		"/organization/:id/webhooks/:wid/test",
+		featureflag.Require(features, domain.FeatureWebhooks),
+		organizationHandler.RequireOwnership,
+		webhookHandler.TestOrganizationWebhook,
+	)
 }
@@ -1,10 +1,15 @@
 package infra
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	swaggerfiles "github.com/swaggo/files"
@@ -14,8 +19,14 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// Version is reported by the version endpoint and in telemetry, release
+// builds set it with -ldflags "-X spsyncpro_api/infra.Version=..."
+var Version = "1.0.0"
+
 type Config struct {
 	Port int
+	// AdminPort serves health and metrics on a separate internal server when set
+	AdminPort int
 }
 
 func ginServerMode() string {
@@ -25,25 +36,39 @@ func ginServerMode() string {
 	return gin.ReleaseMode
 }
 
+// NewServer wires the routes and returns the http server, background workers
+// started while wiring run until ctx is cancelled
 func NewServer(
+	ctx context.Context,
 	db *gorm.DB,
 	logger *logrus.Logger,
+	emailService mailer.EmailService,
+	activityLogger domain.ActivityLogger,
 	config Config,
 ) *http.Server {
 	gin.SetMode(ginServerMode())
+	utils.SetResponseLogger(logger)
+	utils.OutboundUserAgent = outboundUserAgent()
 
 	router := gin.Default()
 	router.Use(otelgin.Middleware("spsyncpro-api"))
+	router.Use(slowRequestLogger(logger, slowRequestThreshold()))
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
 	rg := router.Group("/api/v1")
 
-	rg.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	rg.GET("/version", func(c *gin.Context) {
+		utils.SetCacheControl(c, "CACHE_CONTROL_VERSION", utils.CacheControlVersion)
+		c.JSON(http.StatusOK, gin.H{"version": Version})
 	})
 
-	SetupRoutes(rg, db, logger)
+	// health and metrics move to the admin server when it is enabled
+	if config.AdminPort == 0 {
+		setupAdminRoutes(router, db)
+	}
+
+	SetupRoutes(ctx, rg, db, logger, emailService, activityLogger)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", config.Port),
@@ -52,3 +77,40 @@ func NewServer(
 
 	return srv
 }
+
+// NewAdminServer returns the internal server exposing health and metrics,
+// it is only used when an admin port is configured
+func NewAdminServer(db *gorm.DB, config Config) *http.Server {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	setupAdminRoutes(router, db)
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.AdminPort),
+		Handler: router,
+	}
+
+	return srv
+}
+
+func setupAdminRoutes(router *gin.Engine, db *gorm.DB) {
+	// liveness, answered without touching any dependency
+	router.GET("/api/v1/health", func(c *gin.Context) {
+		utils.SetCacheControl(c, "CACHE_CONTROL_HEALTH", utils.CacheControlHealth)
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	router.GET("/api/v1/health/ready", readinessHandler(db))
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+}
+
+// outboundUserAgent reads OUTBOUND_USER_AGENT, Graph and webhook requests are
+// sent as spsyncpro-api/<version> when it is unset
+func outboundUserAgent() string {
+	if userAgent := viper.GetString("OUTBOUND_USER_AGENT"); userAgent != "" {
+		return userAgent
+	}
+	return "spsyncpro-api/" + Version
+}
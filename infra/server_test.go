@@ -0,0 +1,85 @@
+package infra
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/mailer"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServer_AdminPort(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("should serve metrics on the main port by default", func(t *testing.T) {
+		srv := NewServer(ctx, nil, logrus.New(), mailer.NewEmailService(logrus.New()), nil, Config{Port: 8080})
+
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should serve metrics only on the admin port when enabled", func(t *testing.T) {
+		config := Config{Port: 8080, AdminPort: 9090}
+		srv := NewServer(ctx, nil, logrus.New(), mailer.NewEmailService(logrus.New()), nil, config)
+		adminSrv := NewAdminServer(nil, config)
+
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		adminSrv.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		adminSrv.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, ":9090", adminSrv.Addr)
+	})
+}
+
+func TestNewServer_CacheControl(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := NewServer(ctx, nil, logrus.New(), mailer.NewEmailService(logrus.New()), nil, Config{Port: 8080})
+
+	t.Run("should let the version be cached briefly", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/version", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+		assert.JSONEq(t, `{"version":"`+Version+`"}`, w.Body.String())
+	})
+
+	t.Run("should use a short ttl for health", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "public, max-age=5", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("should use the configured version directive", func(t *testing.T) {
+		viper.Set("CACHE_CONTROL_VERSION", "no-cache")
+
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/version", nil))
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	})
+}
@@ -0,0 +1,55 @@
+package infra
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// defaultSlowRequestThreshold is the latency budget when SLOW_REQUEST_MS is
+// not set
+const defaultSlowRequestThreshold = time.Second
+
+// slowRequestThreshold reads SLOW_REQUEST_MS, 0 turns the warning off
+func slowRequestThreshold() time.Duration {
+	if viper.GetString("SLOW_REQUEST_MS") != "" {
+		return time.Duration(viper.GetInt("SLOW_REQUEST_MS")) * time.Millisecond
+	}
+	return defaultSlowRequestThreshold
+}
+
+// slowRequestLogger warns about requests that take longer than threshold, on
+// top of the access log, so a latency regression stands out by its message.
+// It is registered after otelgin so the warning carries the trace id
+func slowRequestLogger(logger *logrus.Logger, threshold time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if threshold <= 0 {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		latency := time.Since(start)
+		if latency <= threshold {
+			return
+		}
+
+		// unmatched routes are grouped instead of logging every probed path
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"method":       c.Request.Method,
+			"route":        route,
+			"status":       c.Writer.Status(),
+			"latency_ms":   latency.Milliseconds(),
+			"threshold_ms": threshold.Milliseconds(),
+			"request_id":   c.GetHeader("X-Request-ID"),
+		}).Warn("slow request")
+	}
+}
@@ -0,0 +1,77 @@
+package infra
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	setup := func(threshold time.Duration) (*logtest.Hook, http.Handler) {
+		logger, hook := logtest.NewNullLogger()
+
+		router := gin.New()
+		router.Use(slowRequestLogger(logger, threshold))
+		router.GET("/slow/:id", func(c *gin.Context) {
+			time.Sleep(20 * time.Millisecond)
+			c.Status(http.StatusAccepted)
+		})
+		router.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		return hook, router
+	}
+
+	t.Run("should warn about a request over the threshold", func(t *testing.T) {
+		hook, handler := setup(10 * time.Millisecond)
+
+		req := httptest.NewRequest("GET", "/slow/42", nil)
+		req.Header.Set("X-Request-ID", "req-123")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Len(t, hook.AllEntries(), 1)
+		entry := hook.LastEntry()
+		assert.Equal(t, logrus.WarnLevel, entry.Level)
+		assert.Equal(t, "slow request", entry.Message)
+		assert.Equal(t, "GET", entry.Data["method"])
+		assert.Equal(t, "/slow/:id", entry.Data["route"])
+		assert.Equal(t, http.StatusAccepted, entry.Data["status"])
+		assert.GreaterOrEqual(t, entry.Data["latency_ms"], int64(20))
+		assert.Equal(t, int64(10), entry.Data["threshold_ms"])
+		assert.Equal(t, "req-123", entry.Data["request_id"])
+	})
+
+	t.Run("should not warn about a request within the threshold", func(t *testing.T) {
+		hook, handler := setup(time.Second)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow/42", nil))
+
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("should not warn when turned off", func(t *testing.T) {
+		viper.Set("SLOW_REQUEST_MS", "0")
+		defer viper.Reset()
+
+		hook, handler := setup(slowRequestThreshold())
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow/42", nil))
+
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("should default the threshold", func(t *testing.T) {
+		viper.Reset()
+
+		assert.Equal(t, defaultSlowRequestThreshold, slowRequestThreshold())
+	})
+}
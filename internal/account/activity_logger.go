@@ -0,0 +1,179 @@
+package account
+
+import (
+	"context"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultActivityLogBatchSize     = 100
+	defaultActivityLogFlushInterval = time.Second
+)
+
+// NewActivityLogger returns the buffered activity logger when
+// ACTIVITY_LOG_BUFFERED is on, and otherwise one that writes every activity
+// in the request that performed it
+func NewActivityLogger(logger *logrus.Logger, accountRepository domain.AccountRepository) domain.ActivityLogger {
+	if viper.GetBool("ACTIVITY_LOG_BUFFERED") {
+		return NewBufferedActivityLogger(logger, accountRepository)
+	}
+	return &syncActivityLogger{accountRepository: accountRepository}
+}
+
+type syncActivityLogger struct {
+	accountRepository domain.AccountRepository
+}
+
+func (l *syncActivityLogger) LogAccountActivity(ctx context.Context, accountID uint, activity string) error {
+	return l.accountRepository.LogAccountActivity(ctx, accountID, activity)
+}
+
+func (l *syncActivityLogger) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// BufferedActivityLogger collects activities in memory and inserts them in
+// batches, a batch is written once it holds ACTIVITY_LOG_BATCH_SIZE
+// activities or ACTIVITY_LOG_FLUSH_INTERVAL after the last write. The writer
+// starts with the first activity, Shutdown writes what is left and later
+// activities are written directly.
+type BufferedActivityLogger struct {
+	logger            *logrus.Logger
+	tracer            trace.Tracer
+	accountRepository domain.AccountRepository
+	batchSize         int
+	interval          time.Duration
+
+	mu      sync.Mutex
+	pending []domain.AccountActivity
+	closed  bool
+
+	start sync.Once
+	full  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func NewBufferedActivityLogger(logger *logrus.Logger, accountRepository domain.AccountRepository) *BufferedActivityLogger {
+	batchSize := viper.GetInt("ACTIVITY_LOG_BATCH_SIZE")
+	if batchSize <= 0 {
+		batchSize = defaultActivityLogBatchSize
+	}
+	interval := viper.GetDuration("ACTIVITY_LOG_FLUSH_INTERVAL")
+	if interval <= 0 {
+		interval = defaultActivityLogFlushInterval
+	}
+	return &BufferedActivityLogger{
+		logger:            logger,
+		tracer:            otel.Tracer("bufferedActivityLogger"),
+		accountRepository: accountRepository,
+		batchSize:         batchSize,
+		interval:          interval,
+		full:              make(chan struct{}, 1),
+		stop:              make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+}
+
+// LogAccountActivity buffers the activity with the session id carried by ctx,
+// see utils.WithSessionID
+func (l *BufferedActivityLogger) LogAccountActivity(ctx context.Context, accountID uint, activity string) error {
+	accountActivity := domain.AccountActivity{
+		AccountID: accountID,
+		Activity:  activity,
+		CreatedAt: time.Now(),
+	}
+	if sessionID := utils.SessionIDFromContext(ctx); sessionID != "" {
+		accountActivity.SessionID = &sessionID
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return l.accountRepository.LogAccountActivities(ctx, []domain.AccountActivity{accountActivity})
+	}
+	l.pending = append(l.pending, accountActivity)
+	full := len(l.pending) >= l.batchSize
+	l.start.Do(func() { go l.run() })
+	l.mu.Unlock()
+
+	if full {
+		select {
+		case l.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Shutdown stops buffering and writes the buffered activities. When ctx ends
+// first ctx.Err() is returned and the write carries on in the background.
+func (l *BufferedActivityLogger) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	// a logger that never received an activity has no writer to wait for
+	l.start.Do(func() { close(l.done) })
+	l.mu.Unlock()
+
+	close(l.stop)
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *BufferedActivityLogger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.flush()
+		case <-l.full:
+			l.flush()
+		case <-l.stop:
+			l.flush()
+			return
+		}
+	}
+}
+
+// flush writes the buffered activities, a failed batch is logged and dropped
+// so a database outage does not grow the buffer without bound
+func (l *BufferedActivityLogger) flush() {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, span := l.tracer.Start(context.Background(), "flush")
+	defer span.End()
+
+	err := l.accountRepository.LogAccountActivities(ctx, batch)
+	if err != nil {
+		span.RecordError(err)
+		l.logger.Errorf("failed to write %d account activities: %v", len(batch), err)
+	}
+}
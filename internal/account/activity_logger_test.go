@@ -0,0 +1,135 @@
+package account_test
+
+import (
+	"context"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestBufferedActivityLogger(t *testing.T) {
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should write a full batch in a single insert", func(t *testing.T) {
+		viper.Set("ACTIVITY_LOG_BATCH_SIZE", 3)
+		viper.Set("ACTIVITY_LOG_FLUSH_INTERVAL", "1h")
+		defer viper.Reset()
+
+		written := make(chan []domain.AccountActivity, 1)
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("LogAccountActivities", anyContext, mock.AnythingOfType("[]domain.AccountActivity")).
+			Run(func(args mock.Arguments) {
+				written <- args.Get(1).([]domain.AccountActivity)
+			}).
+			Return(nil).
+			Once()
+
+		logger := account.NewBufferedActivityLogger(logrus.New(), repository)
+		for _, activity := range []string{domain.ActivityLogin, domain.ActivityLogout, domain.ActivityLogin} {
+			assert.NoError(t, logger.LogAccountActivity(context.Background(), 1, activity))
+		}
+
+		select {
+		case batch := <-written:
+			assert.Len(t, batch, 3)
+			assert.Equal(t, domain.ActivityLogout, batch[1].Activity)
+		case <-time.After(time.Second):
+			t.Fatal("full batch was not written")
+		}
+
+		assert.NoError(t, logger.Shutdown(context.Background()))
+	})
+
+	t.Run("should write a partial batch after the flush interval", func(t *testing.T) {
+		viper.Set("ACTIVITY_LOG_FLUSH_INTERVAL", "10ms")
+		defer viper.Reset()
+
+		db := newTestDB(t)
+		logger := account.NewBufferedActivityLogger(logrus.New(), account.NewAccountRepository(db))
+
+		assert.NoError(t, logger.LogAccountActivity(utils.WithSessionID(context.Background(), "session"), 1, domain.ActivityLogin))
+
+		assert.Eventually(t, func() bool {
+			var count int64
+			db.Model(&domain.AccountActivity{}).Count(&count)
+			return count == 1
+		}, time.Second, 5*time.Millisecond)
+
+		var stored domain.AccountActivity
+		assert.NoError(t, db.First(&stored).Error)
+		assert.Equal(t, domain.ActivityLogin, stored.Activity)
+		assert.NotNil(t, stored.SessionID)
+		assert.Equal(t, "session", *stored.SessionID)
+
+		assert.NoError(t, logger.Shutdown(context.Background()))
+	})
+
+	t.Run("should drain the buffer on shutdown without loss", func(t *testing.T) {
+		viper.Set("ACTIVITY_LOG_BATCH_SIZE", 100)
+		viper.Set("ACTIVITY_LOG_FLUSH_INTERVAL", "1h")
+		defer viper.Reset()
+
+		db := newTestDB(t)
+		logger := account.NewBufferedActivityLogger(logrus.New(), account.NewAccountRepository(db))
+
+		for i := range 5 {
+			assert.NoError(t, logger.LogAccountActivity(context.Background(), uint(i+1), domain.ActivityLogin))
+		}
+
+		var count int64
+		assert.NoError(t, db.Model(&domain.AccountActivity{}).Count(&count).Error)
+		assert.Zero(t, count)
+
+		assert.NoError(t, logger.Shutdown(context.Background()))
+
+		assert.NoError(t, db.Model(&domain.AccountActivity{}).Count(&count).Error)
+		assert.Equal(t, int64(5), count)
+
+		// an activity after shutdown is written directly
+		assert.NoError(t, logger.LogAccountActivity(context.Background(), 6, domain.ActivityLogout))
+		assert.NoError(t, db.Model(&domain.AccountActivity{}).Count(&count).Error)
+		assert.Equal(t, int64(6), count)
+	})
+
+	t.Run("should shut down a logger that never received an activity", func(t *testing.T) {
+		logger := account.NewBufferedActivityLogger(logrus.New(), domain.NewMockAccountRepository(t))
+
+		assert.NoError(t, logger.Shutdown(context.Background()))
+	})
+}
+
+func TestNewActivityLogger(t *testing.T) {
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should write synchronously by default", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityLogin).Return(nil).Once()
+
+		logger := account.NewActivityLogger(logrus.New(), repository)
+
+		assert.NoError(t, logger.LogAccountActivity(context.Background(), 1, domain.ActivityLogin))
+		assert.NoError(t, logger.Shutdown(context.Background()))
+	})
+
+	t.Run("should buffer when enabled", func(t *testing.T) {
+		viper.Set("ACTIVITY_LOG_BUFFERED", true)
+		defer viper.Reset()
+
+		logger := account.NewActivityLogger(logrus.New(), domain.NewMockAccountRepository(t))
+
+		assert.IsType(t, &account.BufferedActivityLogger{}, logger)
+	})
+}
@@ -0,0 +1,110 @@
+package account
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	AuthCookieName = "auth_token"
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderKey  = "X-CSRF-Token"
+
+	csrfTokenBytes = 32
+)
+
+// cookieAuthEnabled is AUTH_COOKIE_ENABLED, issued auth tokens are then also
+// set as an http only cookie and accepted from it
+func cookieAuthEnabled() bool {
+	return viper.GetBool("AUTH_COOKIE_ENABLED")
+}
+
+// cookieSecure is AUTH_COOKIE_SECURE, cookies are only sent over https unless
+// it is turned off for local development
+func cookieSecure() bool {
+	return !viper.IsSet("AUTH_COOKIE_SECURE") || viper.GetBool("AUTH_COOKIE_SECURE")
+}
+
+// setAuthCookie sets the auth token cookie when cookie auth is enabled, the
+// token stays in the response body for header based clients
+func setAuthCookie(c *gin.Context, token string) {
+	if !cookieAuthEnabled() {
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(AuthCookieName, token, int(AuthTokenExpiry.Seconds()), "/", "", cookieSecure(), true)
+}
+
+func clearAuthCookie(c *gin.Context) {
+	if !cookieAuthEnabled() {
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(AuthCookieName, "", -1, "/", "", cookieSecure(), true)
+}
+
+// CSRFMiddleware rejects state changing requests authenticated with the auth
+// cookie unless the X-CSRF-Token header repeats the csrf_token cookie. Requests
+// authenticated with the Authorization header or an API key cannot be forged
+// by a browser and are exempt.
+func CSRFMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cookieAuthEnabled() || !c.GetBool(utils.CookieAuthContextKey) {
+			c.Next()
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		cookie, err := c.Cookie(CSRFCookieName)
+		header := c.GetHeader(CSRFHeaderKey)
+		if err != nil || cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) != 1 {
+			utils.RespondError(c, domain.ErrCSRFTokenInvalid)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+type CSRFTokenResponse struct {
+	CSRFToken string `json:"csrf_token"`
+}
+
+// @Summary		Issue a CSRF token
+// @Description	Set a csrf_token cookie and return its value, cookie authenticated requests other than GET must send it in the X-CSRF-Token header
+// @Tags			account
+// @Produce		json
+// @Success		200	{object}	CSRFTokenResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/account/csrf-token [get]
+func (h *AccountHandler) IssueCSRFToken(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "IssueCSRFToken")
+	defer span.End()
+
+	token, err := utils.GenerateToken(csrfTokenBytes)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to generate csrf token: %v", err)
+		utils.RespondError(c, domain.ErrTokenGenerationFailed)
+		return
+	}
+
+	// readable by the frontend script, which repeats it in the header
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(CSRFCookieName, token, 0, "/", "", cookieSecure(), false)
+
+	utils.RespondJSON(c, http.StatusOK, CSRFTokenResponse{
+		CSRFToken: token,
+	})
+}
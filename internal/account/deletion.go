@@ -0,0 +1,29 @@
+package account
+
+import (
+	"context"
+	"spsyncpro_api/pkg/domain"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	DeletionModeDelete    = "delete"
+	DeletionModeAnonymize = "anonymize"
+)
+
+// anonymizeOnDelete is ACCOUNT_DELETION_MODE=anonymize, deleting an account
+// then anonymizes it in place instead of removing the row
+func anonymizeOnDelete() bool {
+	return viper.GetString("ACCOUNT_DELETION_MODE") == DeletionModeAnonymize
+}
+
+// removeAccount deletes or anonymizes the account as configured and returns
+// the activity to record for it
+func removeAccount(ctx context.Context, accountRepository domain.AccountRepository, id uint) (string, error) {
+	if anonymizeOnDelete() {
+		return domain.ActivityAnonymize, accountRepository.AnonymizeAccount(ctx, id, time.Now())
+	}
+	return domain.ActivityDelete, accountRepository.DeleteAccount(ctx, id)
+}
@@ -1,15 +1,24 @@
 package account
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"spsyncpro_api/pkg/domain"
 	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
@@ -22,10 +31,18 @@ type AccountHandler struct {
 
 	accountService    domain.AccountService
 	accountRepository domain.AccountRepository
+
+	breachedPasswordChecker domain.BreachedPasswordChecker
+	activityNotifier        domain.ActivityNotifier
+	activityLogger          domain.ActivityLogger
+	securityBypass          *utils.IPAllowList
 }
 
 const (
 	name = "accountHandler"
+
+	defaultAccountLockoutDuration = 30 * time.Minute
+	defaultAuthMethodsMinDuration = 300 * time.Millisecond
 )
 
 func NewAccountHandler(
@@ -44,26 +61,133 @@ func NewAccountHandler(
 	}
 }
 
+// SetBreachedPasswordChecker enables rejecting breached passwords on register,
+// reset and change while CHECK_BREACHED_PASSWORDS is on
+func (h *AccountHandler) SetBreachedPasswordChecker(checker domain.BreachedPasswordChecker) {
+	h.breachedPasswordChecker = checker
+}
+
+// SetSecurityBypass exempts logins from the client IPs of the allow list from
+// the account lockout, their failed logins are still logged
+func (h *AccountHandler) SetSecurityBypass(bypass *utils.IPAllowList) {
+	h.securityBypass = bypass
+}
+
+// SetActivityNotifier reports every recorded activity to the notifier, which
+// delivers it to the subscribed webhooks
+func (h *AccountHandler) SetActivityNotifier(notifier domain.ActivityNotifier) {
+	h.activityNotifier = notifier
+}
+
+// SetActivityLogger records activities through the logger instead of
+// writing each one to the repository in the request
+func (h *AccountHandler) SetActivityLogger(logger domain.ActivityLogger) {
+	h.activityLogger = logger
+}
+
+// logActivity records the activity and notifies the activity notifier once
+// it is recorded, or buffered by the activity logger
+func (h *AccountHandler) logActivity(ctx context.Context, accountID uint, activity string) error {
+	var err error
+	if h.activityLogger != nil {
+		err = h.activityLogger.LogAccountActivity(ctx, accountID, activity)
+	} else {
+		err = h.accountRepository.LogAccountActivity(ctx, accountID, activity)
+	}
+	if err != nil {
+		return err
+	}
+	if h.activityNotifier != nil {
+		h.activityNotifier.NotifyActivity(ctx, accountID, activity)
+	}
+	return nil
+}
+
+// loadAccount returns the account loaded by LoadAccountMiddleware, and reads
+// it from the repository on routes without the middleware
+func (h *AccountHandler) loadAccount(c *gin.Context, ctx context.Context, accountID uint) (*domain.Account, error) {
+	if acc, ok := utils.AccountFromContext(c); ok && acc.ID == accountID {
+		return acc, nil
+	}
+	return h.accountRepository.GetAccountByID(ctx, accountID)
+}
+
+// checkNewPassword enforces the password policy and then the breach check on
+// a password about to be set
+func (h *AccountHandler) checkNewPassword(ctx context.Context, password string) error {
+	if err := NewPasswordPolicy().Validate(password); err != nil {
+		return err
+	}
+	return h.checkBreachedPassword(ctx, password)
+}
+
+// passwordHashError is the error to respond with for a failed hash or
+// compare, busy hashing workers are a 503 the client can retry
+func passwordHashError(err error) error {
+	if errors.Is(err, domain.ErrPasswordHashingBusy) {
+		return domain.ErrPasswordHashingBusy
+	}
+	return domain.ErrInternal
+}
+
+// checkBreachedPassword returns ErrPasswordBreached for a password known from
+// a breach. A failed lookup lets the password through, an unreachable
+// provider must not block registrations and resets.
+func (h *AccountHandler) checkBreachedPassword(ctx context.Context, password string) error {
+	if h.breachedPasswordChecker == nil || !viper.GetBool("CHECK_BREACHED_PASSWORDS") {
+		return nil
+	}
+
+	breached, err := h.breachedPasswordChecker.IsBreached(ctx, password)
+	if err != nil {
+		h.logger.WithContext(ctx).Warnf("breached password lookup failed: %v", err)
+		return nil
+	}
+	if breached {
+		return domain.ErrPasswordBreached
+	}
+	return nil
+}
+
 type RegisterAccountRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" binding:"required,max=254"`
+	Password string `json:"password" binding:"required,max=128"`
 }
 
 type RegisterAccountResponse struct {
-	ID    uint   `json:"id"`
-	Email string `json:"email"`
-	Token string `json:"token"`
+	ID           uint   `json:"id"`
+	Email        string `json:"email"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RegisterAccountAcceptedResponse is the response of every registration while
+// REGISTRATION_HIDE_EXISTING_ACCOUNTS is on, new and existing emails alike
+type RegisterAccountAcceptedResponse struct {
+	Message string `json:"message"`
+}
+
+// registerAcceptedMessage does not tell whether the email already had an account
+const registerAcceptedMessage = "registration received, check your email to continue"
+
+// hideExistingAccounts is REGISTRATION_HIDE_EXISTING_ACCOUNTS, off by default
+// so registering an existing email is ErrAccountAlreadyExists
+func hideExistingAccounts() bool {
+	return viper.GetBool("REGISTRATION_HIDE_EXISTING_ACCOUNTS")
 }
 
 // @Summary		Register a new account
-// @Description	Register a new account
+// @Description	Register a new account and log in. With REGISTRATION_HIDE_EXISTING_ACCOUNTS on, the response is 202 for new and existing emails alike and the account is not logged in
 // @Tags			account
 // @Accept			json
 // @Produce		json
 // @Param			account	body		RegisterAccountRequest	true	"Account"
 // @Success		200		{object}	RegisterAccountResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
+// @Success		202		{object}	RegisterAccountAcceptedResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Failure		503		{object}	utils.ErrorResponse
 // @Router			/api/v1/account/register [post]
 func (h *AccountHandler) RegisterAccount(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -72,30 +196,47 @@ func (h *AccountHandler) RegisterAccount(c *gin.Context) {
 
 	var req RegisterAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	email, err := utils.NormalizeEmail(req.Email)
+	if err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+	req.Email = email
+
+	if hideExistingAccounts() {
+		h.registerHidingExisting(ctx, c, req)
 		return
 	}
 
 	// Check if account already exists
 	existingAcc, err := h.accountRepository.GetAccountByEmail(ctx, req.Email)
 	if err == nil && existingAcc != nil {
-		h.logger.WithField("userId", existingAcc.ID).Errorf("account already exists")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "account already exists"})
+		h.logger.WithContext(ctx).WithField("userId", existingAcc.ID).Errorf("account already exists")
+		utils.RespondError(c, domain.ErrAccountAlreadyExists)
 		return
 	}
 	if err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			h.logger.Errorf("failed to get account by email: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			h.logger.WithContext(ctx).Errorf("failed to get account by email: %v", err)
+			utils.RespondError(c, domain.ErrInternal)
 			return
 		}
 	}
 
+	if err := h.checkNewPassword(ctx, req.Password); err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
 	// Hash the password before storing
 	hashedPassword, err := h.accountService.HashPassword(ctx, req.Password)
 	if err != nil {
-		h.logger.Errorf("failed to hash password: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).Errorf("failed to hash password: %v", err)
+		utils.RespondError(c, passwordHashError(err))
 		return
 	}
 
@@ -106,37 +247,131 @@ func (h *AccountHandler) RegisterAccount(c *gin.Context) {
 
 	acc, err = h.accountRepository.CreateAccount(ctx, acc)
 	if err != nil {
-		h.logger.Errorf("failed to create account: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.logger.WithContext(ctx).Errorf("failed to create account: %v", err)
+		// a concurrent registration took the email after the lookup
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.RespondError(c, domain.ErrAccountAlreadyExists)
+			return
+		}
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
 	token, err := h.accountService.GenerateAuthToken(ctx, acc)
 	if err != nil {
-		h.logger.WithField("userId", acc.ID).Errorf("failed to generate token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to generate token: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	refreshToken, err := h.issueRefreshToken(ctx, acc, false)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to issue refresh token: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	err = h.accountRepository.LogAccountActivity(ctx, acc.ID, domain.ActivityRegister)
+	err = h.logActivity(h.sessionContext(ctx, token), acc.ID, domain.ActivityRegister)
 	if err != nil {
-		h.logger.WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
 	}
 
-	c.JSON(http.StatusOK, RegisterAccountResponse{
-		ID:    acc.ID,
-		Email: acc.Email,
-		Token: token,
+	// the account is already created, a failed email can be retried through resend-verification
+	if err := h.sendVerificationEmail(ctx, acc); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to send verification email: %v", err)
+	}
+
+	setAuthCookie(c, token)
+
+	utils.RespondJSON(c, http.StatusOK, RegisterAccountResponse{
+		ID:           acc.ID,
+		Email:        acc.Email,
+		Token:        token,
+		RefreshToken: refreshToken,
+	})
+}
+
+// registerHidingExisting registers an account without telling whether the
+// email already has one. A new email gets an account and a verification
+// email, an existing one gets an email saying it already has an account.
+// Neither is logged in and both get the same response.
+func (h *AccountHandler) registerHidingExisting(ctx context.Context, c *gin.Context, req RegisterAccountRequest) {
+	if err := h.checkNewPassword(ctx, req.Password); err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
+	// hashed for an existing email too, so it does not answer faster
+	hashedPassword, err := h.accountService.HashPassword(ctx, req.Password)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to hash password: %v", err)
+		utils.RespondError(c, passwordHashError(err))
+		return
+	}
+
+	existingAcc, err := h.accountRepository.GetAccountByEmail(ctx, req.Email)
+	switch {
+	case err == nil:
+		h.logger.WithContext(ctx).WithField("userId", existingAcc.ID).Infof("registration with the email of an existing account")
+		if err := h.sendAccountExistsEmail(ctx, existingAcc); err != nil {
+			h.logger.WithContext(ctx).WithField("userId", existingAcc.ID).Errorf("failed to send account exists email: %v", err)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		acc, err := h.accountRepository.CreateAccount(ctx, &domain.Account{
+			Email:    req.Email,
+			Password: hashedPassword,
+		})
+		if err != nil {
+			h.logger.WithContext(ctx).Errorf("failed to create account: %v", err)
+			utils.RespondError(c, domain.ErrInternal)
+			return
+		}
+
+		if err := h.logActivity(ctx, acc.ID, domain.ActivityRegister); err != nil {
+			h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
+		}
+
+		// the account is already created, a failed email can be retried through resend-verification
+		if err := h.sendVerificationEmail(ctx, acc); err != nil {
+			h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to send verification email: %v", err)
+		}
+	default:
+		h.logger.WithContext(ctx).Errorf("failed to get account by email: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusAccepted, RegisterAccountAcceptedResponse{
+		Message: registerAcceptedMessage,
 	})
 }
 
+// sendAccountExistsEmail tells the owner of acc that a registration was
+// attempted with its email and logs the email
+func (h *AccountHandler) sendAccountExistsEmail(ctx context.Context, acc *domain.Account) error {
+	emailLog, err := h.accountService.SendAccountExistsEmail(ctx, acc.Email)
+	if err != nil {
+		return err
+	}
+
+	emailLog.AccountID = acc.ID
+	if err := h.accountRepository.LogEmail(ctx, emailLog); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log email %s: %v", emailLog.MessageID, err)
+	}
+
+	return nil
+}
+
 type LoginAccountRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	// RememberMe issues a refresh token with the longer JWT_REFRESH_TTL_REMEMBER lifetime
+	RememberMe bool `json:"remember_me"`
 }
 
 type LoginAccountResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // @Summary		Login a user
@@ -146,8 +381,13 @@ type LoginAccountResponse struct {
 // @Produce		json
 // @Param			account	body		LoginAccountRequest	true	"Account"
 // @Success		200		{object}	LoginAccountResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		403		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		423		{object}	utils.ErrorResponse
+// @Failure		429		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Failure		503		{object}	utils.ErrorResponse
 // @Router			/api/v1/account/login [post]
 func (h *AccountHandler) LoginAccount(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -156,80 +396,175 @@ func (h *AccountHandler) LoginAccount(c *gin.Context) {
 
 	var req LoginAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	email, err := utils.NormalizeEmail(req.Email)
+	if err != nil {
+		utils.RespondError(c, err)
 		return
 	}
+	req.Email = email
 
 	acc, err := h.accountRepository.GetAccountByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			h.logger.WithField("email", req.Email).Errorf("account not found")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credentials"})
+			h.logger.WithContext(ctx).WithField("email", req.Email).Errorf("account not found")
+			utils.RespondError(c, domain.ErrInvalidCredentials)
+			return
 		}
-		h.logger.Errorf("failed to get account by email: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).Errorf("failed to get account by email: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	ok, err := h.accountService.ComparePassword(ctx, req.Password, acc.Password)
+	bypassed := h.securityBypass.Contains(c.ClientIP())
+	if bypassed {
+		span.SetAttributes(attribute.Bool("security.bypass", true))
+	}
+
+	// checked before the password so a locked account gives no signal to guesses
+	if acc.LockedUntil != nil && time.Now().Before(*acc.LockedUntil) {
+		if !bypassed {
+			h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("login to locked account")
+			utils.RespondError(c, domain.ErrAccountLocked)
+			return
+		}
+		h.logger.WithContext(ctx).WithFields(logrus.Fields{"userId": acc.ID, "ip": c.ClientIP()}).Warnf("login to locked account from allow-listed ip")
+	}
+
+	ok, needsRehash, err := h.accountService.ComparePassword(ctx, req.Password, acc.Password)
 	if err != nil {
-		h.logger.WithField("userId", acc.ID).Errorf("failed to compare password: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to compare password: %v", err)
+		utils.RespondError(c, passwordHashError(err))
 		return
 	}
 	if !ok {
-		h.logger.WithField("userId", acc.ID).Errorf("invalid password")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credentials"})
+		if bypassed {
+			h.logger.WithContext(ctx).WithFields(logrus.Fields{"userId": acc.ID, "ip": c.ClientIP()}).Warnf("invalid password from allow-listed ip, not counted for lockout")
+		} else {
+			h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("invalid password")
+			h.recordFailedLogin(ctx, acc)
+		}
+		utils.RespondError(c, domain.ErrInvalidCredentials)
+		return
+	}
+
+	if acc.DisabledAt != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("login to disabled account")
+		utils.RespondError(c, domain.ErrAccountDisabled)
 		return
 	}
 
+	if viper.GetBool("REQUIRE_EMAIL_VERIFICATION") && !acc.EmailVerified {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("login to unverified account")
+		utils.RespondError(c, domain.ErrEmailNotVerified)
+		return
+	}
+
+	if needsRehash {
+		h.rehashPassword(ctx, acc, req.Password)
+	}
+
+	loginAt := time.Now()
+	acc.LastLoginAt = &loginAt
+	acc.InactivityWarnedAt = nil
+	acc.FailedLoginCount = 0
+	acc.LockedUntil = nil
+	if _, err := h.accountRepository.UpdateAccount(ctx, acc); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to record login: %v", err)
+	}
+
 	token, err := h.accountService.GenerateAuthToken(ctx, acc)
 	if err != nil {
-		h.logger.WithField("userId", acc.ID).Errorf("failed to generate token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to generate token: %v", err)
+		utils.RespondError(c, domain.ErrTokenGenerationFailed)
+		return
+	}
+
+	refreshToken, err := h.issueRefreshToken(ctx, acc, req.RememberMe)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to issue refresh token: %v", err)
+		utils.RespondError(c, domain.ErrTokenGenerationFailed)
 		return
 	}
 
-	err = h.accountRepository.LogAccountActivity(ctx, acc.ID, domain.ActivityLogin)
+	err = h.logActivity(h.sessionContext(ctx, token), acc.ID, domain.ActivityLogin)
 	if err != nil {
-		h.logger.WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
 	}
 
-	c.JSON(
+	setAuthCookie(c, token)
+
+	utils.RespondJSON(
+		c,
 		http.StatusOK,
 		LoginAccountResponse{
-			Token: token,
+			Token:        token,
+			RefreshToken: refreshToken,
 		},
 	)
 }
 
+type LogoutRequest struct {
+	// RefreshToken is the refresh token of the session to end, optional
+	RefreshToken string `json:"refresh_token"`
+}
+
 // @Summary		Logout a user
-// @Description	Logout a user
+// @Description	Revoke the auth token of the request and the refresh token of the same session when given, other sessions stay logged in
 // @Tags			account
 // @Accept			json
 // @Produce		json
+// @Param			account	body		LogoutRequest	false	"Refresh token of the session"
 // @Success		200		{object}	map[string]string
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
 // @Router			/api/v1/account/logout [post]
 func (h *AccountHandler) LogoutAccount(c *gin.Context) {
 	ctx := c.Request.Context()
 	ctx, span := h.tracer.Start(ctx, "LogoutAccount")
 	defer span.End()
 
+	// the body is optional, a client without a refresh token sends none
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondBindError(c, err)
+		return
+	}
+
 	accountID := c.GetUint(utils.AccountIdContextKey)
 	if accountID == 0 {
-		h.logger.Errorf("accountID not found")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	if err := h.revokeRequestToken(c, ctx, accountID); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to revoke token: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	err := h.accountRepository.LogAccountActivity(ctx, accountID, domain.ActivityLogout)
+	if req.RefreshToken != "" {
+		err := h.accountRepository.RevokeRefreshToken(ctx, accountID, utils.HashToken(req.RefreshToken), time.Now())
+		if err != nil {
+			h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to revoke refresh token: %v", err)
+			utils.RespondError(c, domain.ErrInternal)
+			return
+		}
+	}
+
+	err := h.logActivity(ctx, accountID, domain.ActivityLogout)
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to log activity: %v", err)
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to log activity: %v", err)
 	}
 
-	c.JSON(
+	clearAuthCookie(c)
+
+	utils.RespondJSON(
+		c,
 		http.StatusOK,
 		gin.H{
 			"message": "logout successful",
@@ -237,276 +572,2000 @@ func (h *AccountHandler) LogoutAccount(c *gin.Context) {
 	)
 }
 
-type GetProfileResponse struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
-
-// @Summary		Get Profile
-// @Description	Get Profile of the authenticated user
+// @Summary		Logout everywhere
+// @Description	Revoke the auth token of the request and every refresh token of the account, ending all sessions
 // @Tags			account
 // @Accept			json
 // @Produce		json
-// @Success		200		{object}	GetProfileResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
-// @Router			/api/v1/account/profile [get]
-func (h *AccountHandler) GetProfile(c *gin.Context) {
+// @Success		200		{object}	map[string]string
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/logout/all [post]
+func (h *AccountHandler) LogoutAllSessions(c *gin.Context) {
 	ctx := c.Request.Context()
-	ctx, span := h.tracer.Start(ctx, "GetProfile")
+	ctx, span := h.tracer.Start(ctx, "LogoutAllSessions")
 	defer span.End()
 
 	accountID := c.GetUint(utils.AccountIdContextKey)
 	if accountID == 0 {
-		h.logger.Errorf("accountID not found")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	acc, err := h.accountRepository.GetAccountByID(ctx, accountID)
+	if err := h.revokeRequestToken(c, ctx, accountID); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to revoke token: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	err := h.accountRepository.RevokeRefreshTokens(ctx, accountID, time.Now())
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to revoke refresh tokens: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	c.JSON(http.StatusOK, GetProfileResponse{
-		ID:        acc.ID,
-		Email:     acc.Email,
-		CreatedAt: acc.CreatedAt,
-		UpdatedAt: acc.UpdatedAt,
-	})
+	err = h.logActivity(ctx, accountID, domain.ActivityLogout)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to log activity: %v", err)
+	}
+
+	clearAuthCookie(c)
+
+	utils.RespondJSON(
+		c,
+		http.StatusOK,
+		gin.H{
+			"message": "logged out of all sessions",
+		},
+	)
 }
 
-type ForgotPasswordRequest struct {
-	Email string `json:"email"`
+// revokeRequestToken revokes the auth token used for the request so it stops
+// working before it expires, an API key has no token to revoke
+func (h *AccountHandler) revokeRequestToken(c *gin.Context, ctx context.Context, accountID uint) error {
+	claims, ok := c.Get(utils.AuthClaimsContextKey)
+	if !ok {
+		return nil
+	}
+	authClaims := claims.(*domain.AuthClaims)
+	if authClaims.TokenID == "" {
+		return nil
+	}
+	return h.accountRepository.RevokeToken(ctx, &domain.RevokedToken{
+		TokenID:   authClaims.TokenID,
+		AccountID: accountID,
+		ExpiresAt: authClaims.ExpiresAt,
+	})
 }
 
-type ForgotPasswordResponse struct {
-	Message string `json:"message"`
+// sessionContext returns ctx carrying the jti of a freshly issued auth token,
+// so the activity that opened the session is grouped with the ones after it
+func (h *AccountHandler) sessionContext(ctx context.Context, token string) context.Context {
+	claims, err := h.accountService.ParseClaims(ctx, token)
+	if err != nil || claims.TokenID == "" {
+		return ctx
+	}
+	return utils.WithSessionID(ctx, claims.TokenID)
 }
 
-// @Summary		Forgot Password
-// @Description	Forgot Password
-// @Tags			account
-// @Accept			json
-// @Produce		json
-// @Param			account	body		ForgotPasswordRequest	true	"Account"
-// @Success		200		{object}	ForgotPasswordResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
-// @Router			/api/v1/account/forgot-password [post]
-func (h *AccountHandler) ForgotPassword(c *gin.Context) {
-	ctx := c.Request.Context()
-	ctx, span := h.tracer.Start(ctx, "ForgotPassword")
-	defer span.End()
+// rehashPassword upgrades the hash to the current Argon2 parameters, it is
+// persisted with the login and a failure never fails the login
+func (h *AccountHandler) rehashPassword(ctx context.Context, acc *domain.Account, password string) {
+	hashedPassword, err := h.accountService.HashPassword(ctx, password)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to rehash password: %v", err)
+		return
+	}
 
-	var req ForgotPasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	acc.Password = hashedPassword
+}
+
+// recordFailedLogin counts a wrong password and locks the account for
+// ACCOUNT_LOCKOUT_DURATION once ACCOUNT_LOCKOUT_THRESHOLD consecutive failures are reached
+func (h *AccountHandler) recordFailedLogin(ctx context.Context, acc *domain.Account) {
+	threshold := viper.GetInt("ACCOUNT_LOCKOUT_THRESHOLD")
+	if threshold <= 0 {
 		return
 	}
 
-	acc, err := h.accountRepository.GetAccountByEmail(ctx, req.Email)
-	if err != nil {
-		h.logger.Errorf("failed to get account by email: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	acc.FailedLoginCount++
+	locked := acc.FailedLoginCount >= threshold
+	if locked {
+		duration := viper.GetDuration("ACCOUNT_LOCKOUT_DURATION")
+		if duration <= 0 {
+			duration = defaultAccountLockoutDuration
+		}
+		lockedUntil := time.Now().Add(duration)
+		acc.LockedUntil = &lockedUntil
+		acc.FailedLoginCount = 0
+	}
+
+	if _, err := h.accountRepository.UpdateAccount(ctx, acc); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to record failed login: %v", err)
 		return
 	}
 
-	if acc == nil {
-		h.logger.Errorf("account not found")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "account not found"})
+	if !locked {
 		return
 	}
 
-	token, err := h.accountService.GeneratePasswordResetToken(ctx, acc)
+	if err := h.logActivity(ctx, acc.ID, domain.ActivityLock); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
+	}
+
+	if err := h.accountService.SendAccountLockedEmail(ctx, acc.Email, *acc.LockedUntil); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to send account locked email: %v", err)
+	}
+}
+
+// issueRefreshToken generates a refresh token and stores its hash so it can be
+// revoked, rememberMe picks the longer lifetime
+func (h *AccountHandler) issueRefreshToken(ctx context.Context, acc *domain.Account, rememberMe bool) (string, error) {
+	refreshToken, expiresAt, err := h.accountService.GenerateRefreshToken(ctx, acc, rememberMe)
 	if err != nil {
-		h.logger.Errorf("failed to generate token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
-		return
+		return "", err
 	}
 
-	err = h.accountService.SendPasswordResetEmail(ctx, acc.Email, token)
+	_, err = h.accountRepository.CreateRefreshToken(ctx, &domain.RefreshToken{
+		AccountID:  acc.ID,
+		TokenHash:  utils.HashToken(refreshToken),
+		ExpiresAt:  expiresAt,
+		RememberMe: rememberMe,
+	})
 	if err != nil {
-		h.logger.Errorf("failed to send password reset email: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send password reset email"})
+		return "", err
+	}
+
+	h.evictSessions(ctx, acc.ID)
+
+	return refreshToken, nil
+}
+
+// evictSessions revokes the least recently used sessions of the account beyond
+// MAX_SESSIONS_PER_ACCOUNT, unlimited when 0. A failure keeps the extra
+// sessions and never fails the login that opened the new one.
+func (h *AccountHandler) evictSessions(ctx context.Context, accountID uint) {
+	maxSessions := viper.GetInt("MAX_SESSIONS_PER_ACCOUNT")
+	if maxSessions <= 0 {
 		return
 	}
 
-	err = h.accountRepository.LogAccountActivity(ctx, acc.ID, domain.ActivityForgotPassword)
+	evicted, err := h.accountRepository.EvictRefreshTokens(ctx, accountID, maxSessions, time.Now())
 	if err != nil {
-		h.logger.Errorf("failed to log activity: %v", err)
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to evict sessions: %v", err)
+		return
+	}
+	if evicted > 0 {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Infof("evicted %d sessions over the limit of %d", evicted, maxSessions)
 	}
-
-	c.JSON(
-		http.StatusOK,
-		ForgotPasswordResponse{
-			Message: "password reset email sent",
-		},
-	)
 }
 
-type ResetPasswordRequest struct {
-	Token    string `json:"token"`
-	Password string `json:"password"`
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
-type ResetPasswordResponse struct {
-	Message string `json:"message"`
+type RefreshTokenResponse struct {
+	Token string `json:"token"`
 }
 
-// @Summary		Reset Password
-// @Description	Reset Password
+// @Summary		Refresh access token
+// @Description	Exchange a refresh token for a new access token
 // @Tags			account
 // @Accept			json
 // @Produce		json
-// @Param			account	body		ResetPasswordRequest	true	"Account"
-// @Success		200		{object}	ResetPasswordResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
-// @Router			/api/v1/account/reset-password [post]
-func (h *AccountHandler) ResetPassword(c *gin.Context) {
+// @Param			account	body		RefreshTokenRequest	true	"Refresh Token"
+// @Success		200		{object}	RefreshTokenResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		401		{object}	utils.ErrorResponse
+// @Failure		403		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/refresh [post]
+func (h *AccountHandler) RefreshToken(c *gin.Context) {
 	ctx := c.Request.Context()
-	ctx, span := h.tracer.Start(ctx, "ResetPassword")
+	ctx, span := h.tracer.Start(ctx, "RefreshToken")
 	defer span.End()
 
-	var req ResetPasswordRequest
+	var req RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		utils.RespondBindError(c, err)
 		return
 	}
 
-	token := req.Token
-	password := req.Password
-
-	accountID, err := h.accountService.ValidatePasswordResetToken(ctx, token)
+	accountID, err := h.accountService.ValidateRefreshToken(ctx, req.RefreshToken)
 	if err != nil {
-		h.logger.Errorf("failed to validate token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).Errorf("invalid refresh token: %v", err)
+		utils.RespondError(c, domain.ErrInvalidRefreshToken)
 		return
 	}
 
-	acc, err := h.accountRepository.GetAccountByID(ctx, accountID)
+	storedToken, err := h.accountRepository.GetRefreshTokenByHash(ctx, utils.HashToken(req.RefreshToken))
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("refresh token not found")
+			utils.RespondError(c, domain.ErrInvalidRefreshToken)
+			return
+		}
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get refresh token: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	hashedPassword, err := h.accountService.HashPassword(ctx, password)
-	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to hash password: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	if storedToken.RevokedAt != nil || storedToken.AccountID != accountID {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("refresh token revoked")
+		utils.RespondError(c, domain.ErrRefreshTokenRevoked)
 		return
 	}
 
-	acc.Password = hashedPassword
-
-	acc, err = h.accountRepository.UpdateAccount(ctx, acc)
+	acc, err := h.accountRepository.GetAccountByID(ctx, accountID)
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to update account: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInvalidRefreshToken)
+		return
+	}
+
+	// a session outlives disabling the account unless it is checked here
+	if acc.AnonymizedAt != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("refresh for anonymized account")
+		utils.RespondError(c, domain.ErrInvalidRefreshToken)
+		return
+	}
+	if acc.DisabledAt != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("refresh for disabled account")
+		utils.RespondError(c, domain.ErrAccountDisabled)
 		return
 	}
 
-	err = h.accountRepository.LogAccountActivity(ctx, acc.ID, domain.ActivityResetPassword)
+	// keeps the session from being evicted first, best effort
+	if err := h.accountRepository.TouchRefreshToken(ctx, storedToken.ID, time.Now()); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to record refresh token use: %v", err)
+	}
+
+	token, err := h.accountService.GenerateAuthToken(ctx, acc)
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to log activity: %v", err)
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to generate token: %v", err)
+		utils.RespondError(c, domain.ErrTokenGenerationFailed)
+		return
 	}
 
-	c.JSON(
-		http.StatusOK,
-		ResetPasswordResponse{
-			Message: "password reset successful",
-		},
-	)
-}
+	setAuthCookie(c, token)
 
-type ChangePasswordRequest struct {
-	OldPassword string `json:"old_password"`
-	NewPassword string `json:"new_password"`
+	utils.RespondJSON(c, http.StatusOK, RefreshTokenResponse{
+		Token: token,
+	})
 }
 
-type ChangePasswordResponse struct {
-	Message string `json:"message"`
+type GetProfileResponse struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// @Summary		Change Password
-// @Description	Change Password
+// @Summary		Get Profile
+// @Description	Get Profile of the authenticated user
 // @Tags			account
 // @Accept			json
 // @Produce		json
-// @Param			account	body		ChangePasswordRequest	true	"Account"
-// @Success		200		{object}	ChangePasswordResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
-// @Router			/api/v1/account/change-password [post]
-func (h *AccountHandler) ChangePassword(c *gin.Context) {
+// @Success		200		{object}	GetProfileResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/profile [get]
+func (h *AccountHandler) GetProfile(c *gin.Context) {
 	ctx := c.Request.Context()
-	ctx, span := h.tracer.Start(ctx, "ChangePassword")
+	ctx, span := h.tracer.Start(ctx, "GetProfile")
 	defer span.End()
 
-	var req ChangePasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	utils.SetCacheControl(c, "CACHE_CONTROL_PROFILE", utils.CacheControlPrivate)
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	acc, err := h.loadAccount(c, ctx, accountID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
+	utils.RespondJSON(c, http.StatusOK, GetProfileResponse{
+		ID:        acc.ID,
+		Email:     acc.Email,
+		CreatedAt: acc.CreatedAt,
+		UpdatedAt: acc.UpdatedAt,
+	})
+}
+
+const (
+	defaultActivityPageSize = 20
+	maxActivityPageSize     = 100
+)
+
+type GetActivityHistoryRequest struct {
+	Since time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type ActivityResponse struct {
+	ID        uint      `json:"id"`
+	Activity  string    `json:"activity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetActivityHistoryResponse struct {
+	Activities []ActivityResponse `json:"activities"`
+	Total      int64              `json:"total"`
+	Limit      int                `json:"limit"`
+	Offset     int                `json:"offset"`
+}
+
+// @Summary		Get Activity History
+// @Description	Get the activity of the authenticated user, newest first
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			limit	query		int		false	"Page size, default 20, max 100"
+// @Param			offset	query		int		false	"Number of activities to skip"
+// @Param			since	query		string	false	"Only activity at or after this RFC 3339 time"
+// @Success		200		{object}	GetActivityHistoryResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/activity [get]
+func (h *AccountHandler) GetActivityHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "GetActivityHistory")
+	defer span.End()
+
+	utils.SetCacheControl(c, "CACHE_CONTROL_ACCOUNT", utils.CacheControlPrivate)
+
 	accountID := c.GetUint(utils.AccountIdContextKey)
 	if accountID == 0 {
-		h.logger.Errorf("accountID not found")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	acc, err := h.accountRepository.GetAccountByID(ctx, accountID)
+	var req GetActivityHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+	page, err := utils.ParsePagination(c, defaultActivityPageSize, maxActivityPageSize)
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		respondListQueryError(c, err)
 		return
 	}
 
-	ok, err := h.accountService.ComparePassword(ctx, req.OldPassword, acc.Password)
+	activities, total, err := h.accountRepository.GetActivityHistory(ctx, accountID, req.Since, page.Limit, page.Offset)
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to compare password: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get activity history: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	if !ok {
-		h.logger.WithField("userId", accountID).Errorf("invalid old password")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid old password"})
+	response := GetActivityHistoryResponse{
+		Activities: make([]ActivityResponse, 0, len(activities)),
+		Total:      total,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+	}
+	for _, activity := range activities {
+		response.Activities = append(response.Activities, ActivityResponse{
+			ID:        activity.ID,
+			Activity:  activity.Activity,
+			CreatedAt: activity.CreatedAt,
+		})
+	}
+
+	utils.RespondJSON(c, http.StatusOK, response)
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ForgotPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+// forgotPasswordMessage is the response of every forgot password request, it
+// does not tell whether the email belongs to an account
+const forgotPasswordMessage = "if an account exists for the email, a password reset link has been sent"
+
+// @Summary		Forgot Password
+// @Description	Sends a password reset link when the email belongs to an account, the response is the same whether it does or not
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		ForgotPasswordRequest	true	"Account"
+// @Success		200		{object}	ForgotPasswordResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/forgot-password [post]
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ForgotPassword")
+	defer span.End()
+
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
 		return
 	}
 
-	hashedPassword, err := h.accountService.HashPassword(ctx, req.NewPassword)
+	email, err := utils.NormalizeEmail(req.Email)
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to hash password: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		utils.RespondError(c, err)
 		return
 	}
+	req.Email = email
 
-	acc.Password = hashedPassword
+	acc, err := h.accountRepository.GetAccountByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.logger.WithContext(ctx).Errorf("failed to get account by email: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
 
-	acc, err = h.accountRepository.UpdateAccount(ctx, acc)
+	// an unknown email gets the same response as a known one, so the endpoint
+	// cannot be used to find out which emails have accounts. Failing to send
+	// the link is only logged for the same reason.
+	if err == nil {
+		if err := h.sendPasswordReset(ctx, acc); err != nil {
+			h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to send password reset: %v", err)
+		}
+	} else {
+		h.logger.WithContext(ctx).Infof("password reset requested for an unknown email")
+	}
+
+	utils.RespondJSON(
+		c,
+		http.StatusOK,
+		ForgotPasswordResponse{
+			Message: forgotPasswordMessage,
+		},
+	)
+}
+
+// sendPasswordReset issues a reset token for acc and queues the email with the
+// link
+func (h *AccountHandler) sendPasswordReset(ctx context.Context, acc *domain.Account) error {
+	token, err := h.issuePasswordResetToken(ctx, acc)
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// the email is sent in the background, a slow mail server must not hold
+	// up the response
+	logCtx := context.WithoutCancel(ctx)
+	err = h.accountService.EnqueuePasswordResetEmail(ctx, acc.Email, token, func(emailLog *domain.EmailLog) {
+		emailLog.AccountID = acc.ID
+		if err := h.accountRepository.LogEmail(logCtx, emailLog); err != nil {
+			h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log email %s: %v", emailLog.MessageID, err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", domain.ErrResetEmailFailed, err)
+	}
+
+	if err := h.logActivity(ctx, acc.ID, domain.ActivityForgotPassword); err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to log activity: %v", err)
+	}
+	return nil
+}
+
+// issuePasswordResetToken generates a reset token and stores its hash, any
+// token issued before it stops working
+func (h *AccountHandler) issuePasswordResetToken(ctx context.Context, acc *domain.Account) (string, error) {
+	token, err := h.accountService.GeneratePasswordResetToken(ctx, acc)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = h.accountRepository.InvalidatePasswordResetTokens(ctx, acc.ID, now)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = h.accountRepository.CreatePasswordResetToken(ctx, &domain.PasswordResetToken{
+		AccountID: acc.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(passwordResetTokenExpiry()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// redeemPasswordResetToken returns the stored record of a reset token that is
+// neither used, invalidated nor expired
+func (h *AccountHandler) redeemPasswordResetToken(ctx context.Context, token string) (*domain.PasswordResetToken, error) {
+	tokenHash := utils.HashToken(token)
+
+	storedToken, err := h.accountRepository.GetPasswordResetTokenByHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrResetTokenInvalid
+		}
+		return nil, err
+	}
+
+	// the lookup is by index, compare again without leaking timing on the hash
+	if subtle.ConstantTimeCompare([]byte(storedToken.TokenHash), []byte(tokenHash)) != 1 {
+		return nil, domain.ErrResetTokenInvalid
+	}
+
+	// a newer reset request or a completed reset invalidates the links sent before it
+	if storedToken.UsedAt != nil || storedToken.InvalidatedAt != nil || !time.Now().Before(storedToken.ExpiresAt) {
+		return nil, domain.ErrResetTokenInvalid
+	}
+
+	return storedToken, nil
+}
+
+type AuthMethodsRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type AuthMethodsResponse struct {
+	Methods []string `json:"methods"`
+}
+
+// unknownAccountAuthMethods are reported for emails without an account, the
+// same set as a password account so the response does not confirm existence
+var unknownAccountAuthMethods = []string{domain.AuthMethodPassword}
+
+// authMethodsMinDuration is AUTH_METHODS_MIN_DURATION, every lookup takes at
+// least this long so timing does not tell existing accounts apart
+func authMethodsMinDuration() time.Duration {
+	if viper.IsSet("AUTH_METHODS_MIN_DURATION") {
+		return viper.GetDuration("AUTH_METHODS_MIN_DURATION")
+	}
+	return defaultAuthMethodsMinDuration
+}
+
+// @Summary		Get the sign in methods of an account
+// @Description	Tell the login form which methods to offer for an email, unknown emails get the same answer as a password account
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		AuthMethodsRequest	true	"Email"
+// @Success		200		{object}	AuthMethodsResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/auth-methods [post]
+func (h *AccountHandler) GetAuthMethods(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "GetAuthMethods")
+	defer span.End()
+
+	var req AuthMethodsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	email, err := utils.NormalizeEmail(req.Email)
+	if err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
+	// every lookup is padded to the same duration, a found account must not
+	// answer faster or slower than an unknown email
+	deadline := time.Now().Add(authMethodsMinDuration())
+	methods, err := h.authMethods(ctx, email)
+	time.Sleep(time.Until(deadline))
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to get auth methods: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, AuthMethodsResponse{Methods: methods})
+}
+
+// authMethods returns the sign in methods configured for the account of email
+func (h *AccountHandler) authMethods(ctx context.Context, email string) ([]string, error) {
+	acc, err := h.accountRepository.GetAccountByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return unknownAccountAuthMethods, nil
+		}
+		return nil, err
+	}
+
+	methods := []string{}
+	if acc.Password != "" {
+		methods = append(methods, domain.AuthMethodPassword)
+	}
+	return methods, nil
+}
+
+type ValidateResetTokenResponse struct {
+	Valid     bool       `json:"valid"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// @Summary		Validate a password reset token
+// @Description	Check a reset link before showing the reset form, the token is not consumed
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			token	query		string	true	"Reset token"
+// @Success		200		{object}	ValidateResetTokenResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/reset-password/validate [get]
+func (h *AccountHandler) ValidateResetToken(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ValidateResetToken")
+	defer span.End()
+
+	token := c.Query("token")
+	if token == "" {
+		utils.RespondError(c, domain.ErrTokenRequired)
+		return
+	}
+
+	storedToken, err := h.redeemPasswordResetToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, domain.ErrResetTokenInvalid) {
+			utils.RespondJSON(c, http.StatusOK, ValidateResetTokenResponse{Valid: false})
+			return
+		}
+		h.logger.WithContext(ctx).Errorf("failed to look up reset token: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// signed tokens are also checked against their own claims
+	if !IsOpaqueResetToken(token) {
+		tokenAccountID, err := h.accountService.ValidatePasswordResetToken(ctx, token)
+		if err != nil || tokenAccountID != storedToken.AccountID {
+			utils.RespondJSON(c, http.StatusOK, ValidateResetTokenResponse{Valid: false})
+			return
+		}
+	}
+
+	utils.RespondJSON(c, http.StatusOK, ValidateResetTokenResponse{
+		Valid:     true,
+		ExpiresAt: &storedToken.ExpiresAt,
+	})
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,max=128"`
+}
+
+type ResetPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary		Reset Password
+// @Description	Reset Password
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		ResetPasswordRequest	true	"Account"
+// @Success		200		{object}	ResetPasswordResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		401		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Failure		503		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/reset-password [post]
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ResetPassword")
+	defer span.End()
+
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	token := req.Token
+	password := req.Password
+
+	storedToken, err := h.redeemPasswordResetToken(ctx, token)
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to update account: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		if errors.Is(err, domain.ErrResetTokenInvalid) {
+			h.logger.WithContext(ctx).Errorf("invalid reset token")
+			utils.RespondError(c, domain.ErrResetTokenInvalid)
+			return
+		}
+		h.logger.WithContext(ctx).Errorf("failed to redeem reset token: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+	accountID := storedToken.AccountID
+
+	// signed tokens are also checked against their own claims
+	if !IsOpaqueResetToken(token) {
+		tokenAccountID, err := h.accountService.ValidatePasswordResetToken(ctx, token)
+		if err != nil {
+			h.logger.WithContext(ctx).Errorf("failed to validate token: %v", err)
+			if errors.Is(err, domain.ErrTokenExpired) {
+				utils.RespondError(c, domain.ErrTokenExpired)
+				return
+			}
+			utils.RespondError(c, domain.ErrResetTokenInvalid)
+			return
+		}
+		if tokenAccountID != accountID {
+			h.logger.WithContext(ctx).WithField("userId", tokenAccountID).Errorf("reset token issued for another account")
+			utils.RespondError(c, domain.ErrResetTokenInvalid)
+			return
+		}
+	}
+
+	acc, err := h.accountRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// checked before consuming so the same link can be retried with another password
+	if err := h.checkNewPassword(ctx, password); err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
+	// hashed before the token is consumed so a busy hashing pool leaves the
+	// link usable for a retry
+	hashedPassword, err := h.accountService.HashPassword(ctx, password)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to hash password: %v", err)
+		utils.RespondError(c, passwordHashError(err))
+		return
+	}
+
+	// the token is consumed together with the password change so a replayed
+	// or concurrent request with the same token cannot reset it again
+	consumed, err := h.accountRepository.ResetPasswordWithToken(ctx, storedToken.ID, accountID, hashedPassword, time.Now())
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to reset password: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+	if !consumed {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("reset token already used")
+		utils.RespondError(c, domain.ErrResetTokenInvalid)
 		return
 	}
 
-	err = h.accountRepository.LogAccountActivity(ctx, acc.ID, domain.ActivityChangePassword)
+	err = h.logActivity(ctx, acc.ID, domain.ActivityResetPassword)
 	if err != nil {
-		h.logger.WithField("userId", accountID).Errorf("failed to log activity: %v", err)
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to log activity: %v", err)
 	}
 
-	c.JSON(
+	utils.RespondJSON(
+		c,
 		http.StatusOK,
-		ChangePasswordResponse{
-			Message: "password changed successfully",
+		ResetPasswordResponse{
+			Message: "password reset successful",
 		},
 	)
 }
+
+// sendVerificationEmail emails a fresh verification token to the account and logs the email
+func (h *AccountHandler) sendVerificationEmail(ctx context.Context, acc *domain.Account) error {
+	token, err := h.accountService.GenerateEmailVerificationToken(ctx, acc)
+	if err != nil {
+		return err
+	}
+
+	emailLog, err := h.accountService.SendVerificationEmail(ctx, acc.Email, token)
+	if err != nil {
+		return err
+	}
+
+	emailLog.AccountID = acc.ID
+	if err := h.accountRepository.LogEmail(ctx, emailLog); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log email %s: %v", emailLog.MessageID, err)
+	}
+
+	return nil
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type VerifyEmailResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary		Verify Email
+// @Description	Mark the account email as verified using the emailed token
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		VerifyEmailRequest	true	"Token"
+// @Success		200		{object}	VerifyEmailResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/verify-email [post]
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "VerifyEmail")
+	defer span.End()
+
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	accountID, email, err := h.accountService.ValidateEmailVerificationToken(ctx, req.Token)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to validate verification token: %v", err)
+		utils.RespondError(c, domain.ErrVerificationTokenInvalid)
+		return
+	}
+
+	acc, err := h.accountRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, domain.ErrVerificationTokenInvalid)
+			return
+		}
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// a token issued for a previous email must not verify the current one
+	if acc.Email != email {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("verification token issued for a different email")
+		utils.RespondError(c, domain.ErrVerificationTokenInvalid)
+		return
+	}
+
+	if acc.EmailVerified {
+		utils.RespondError(c, domain.ErrEmailAlreadyVerified)
+		return
+	}
+
+	verifiedAt := time.Now()
+	acc.EmailVerified = true
+	acc.VerifiedAt = &verifiedAt
+
+	acc, err = h.accountRepository.UpdateAccount(ctx, acc)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to update account: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	err = h.logActivity(ctx, acc.ID, domain.ActivityVerifyEmail)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to log activity: %v", err)
+	}
+
+	utils.RespondJSON(
+		c,
+		http.StatusOK,
+		VerifyEmailResponse{
+			Message: "email verified",
+		},
+	)
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResendVerificationResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary		Resend Verification
+// @Description	Send a new verification email, the response does not reveal whether the account exists
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		ResendVerificationRequest	true	"Account"
+// @Success		200		{object}	ResendVerificationResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/resend-verification [post]
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ResendVerification")
+	defer span.End()
+
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	email, err := utils.NormalizeEmail(req.Email)
+	if err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+	req.Email = email
+
+	response := ResendVerificationResponse{
+		Message: "if the account exists and is unverified, a verification email has been sent",
+	}
+
+	acc, err := h.accountRepository.GetAccountByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondJSON(c, http.StatusOK, response)
+			return
+		}
+		h.logger.WithContext(ctx).Errorf("failed to get account by email: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	if acc.EmailVerified {
+		utils.RespondJSON(c, http.StatusOK, response)
+		return
+	}
+
+	if err := h.sendVerificationEmail(ctx, acc); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to send verification email: %v", err)
+		utils.RespondError(c, domain.ErrVerificationEmailFailed)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, response)
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,max=254"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ChangeEmailResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary		Change Email
+// @Description	Send a confirmation link to the new email, the email changes once the link is confirmed
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		ChangeEmailRequest	true	"Account"
+// @Success		200		{object}	ChangeEmailResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Failure		503		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/change-email [post]
+func (h *AccountHandler) ChangeEmail(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ChangeEmail")
+	defer span.End()
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	email, err := utils.NormalizeEmail(req.NewEmail)
+	if err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+	req.NewEmail = email
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	acc, err := h.loadAccount(c, ctx, accountID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	ok, _, err := h.accountService.ComparePassword(ctx, req.Password, acc.Password)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to compare password: %v", err)
+		utils.RespondError(c, passwordHashError(err))
+		return
+	}
+	if !ok {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("invalid password on email change")
+		utils.RespondError(c, domain.ErrInvalidCredentials)
+		return
+	}
+
+	if req.NewEmail == acc.Email {
+		utils.RespondError(c, domain.ErrEmailUnchanged)
+		return
+	}
+
+	if err := h.checkEmailAvailable(ctx, req.NewEmail); err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
+	token, err := h.accountService.GenerateEmailChangeToken(ctx, acc, req.NewEmail)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to generate email change token: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	emailLog, err := h.accountService.SendEmailChangeEmail(ctx, req.NewEmail, token)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to send email change email: %v", err)
+		utils.RespondError(c, domain.ErrVerificationEmailFailed)
+		return
+	}
+
+	emailLog.AccountID = acc.ID
+	if err := h.accountRepository.LogEmail(ctx, emailLog); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log email %s: %v", emailLog.MessageID, err)
+	}
+
+	// logged on request as well so the history shows a change that was
+	// never confirmed
+	if err := h.logActivity(ctx, acc.ID, domain.ActivityUpdate); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
+	}
+
+	utils.RespondJSON(c, http.StatusOK, ChangeEmailResponse{
+		Message: "a confirmation link was sent to the new email",
+	})
+}
+
+// checkEmailAvailable returns ErrAccountAlreadyExists when another account
+// uses email
+func (h *AccountHandler) checkEmailAvailable(ctx context.Context, email string) error {
+	_, err := h.accountRepository.GetAccountByEmail(ctx, email)
+	if err == nil {
+		return domain.ErrAccountAlreadyExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.logger.WithContext(ctx).Errorf("failed to get account by email: %v", err)
+		return domain.ErrInternal
+	}
+	return nil
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ConfirmEmailChangeResponse struct {
+	Message      string    `json:"message"`
+	ExecuteAfter time.Time `json:"execute_after"`
+}
+
+// @Summary		Confirm Email Change
+// @Description	Schedule an email change with the token sent to the new email, it is applied once ACCOUNT_ACTION_GRACE_PERIOD has passed unless cancelled from the link sent to the current email
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		ConfirmEmailChangeRequest	true	"Token"
+// @Success		202		{object}	ConfirmEmailChangeResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		409		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/change-email/confirm [post]
+func (h *AccountHandler) ConfirmEmailChange(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ConfirmEmailChange")
+	defer span.End()
+
+	var req ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	accountID, email, newEmail, err := h.accountService.ValidateEmailChangeToken(ctx, req.Token)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to validate email change token: %v", err)
+		utils.RespondError(c, domain.ErrVerificationTokenInvalid)
+		return
+	}
+
+	acc, err := h.accountRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, domain.ErrVerificationTokenInvalid)
+			return
+		}
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// the token only applies to the email it was issued from, so it works
+	// once and a later change voids it
+	if acc.Email != email {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("email change token issued for a different email")
+		utils.RespondError(c, domain.ErrVerificationTokenInvalid)
+		return
+	}
+
+	// the new email may have been registered since the change was requested
+	if err := h.checkEmailAvailable(ctx, newEmail); err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
+	// the change is held back for the grace period so the owner of the
+	// current email can cancel a change made from a stolen session
+	pendingAction, err := h.schedulePendingAction(ctx, acc, domain.PendingActionChangeEmail, newEmail)
+	if err != nil {
+		if errors.Is(err, domain.ErrPendingActionExists) {
+			utils.RespondError(c, err)
+			return
+		}
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to schedule email change: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusAccepted, ConfirmEmailChangeResponse{
+		Message:      "email change scheduled",
+		ExecuteAfter: pendingAction.ExecuteAfter,
+	})
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,max=128"`
+}
+
+type ChangePasswordResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary		Change Password
+// @Description	Change Password
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		ChangePasswordRequest	true	"Account"
+// @Success		200		{object}	ChangePasswordResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Failure		503		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/change-password [post]
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ChangePassword")
+	defer span.End()
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	acc, err := h.loadAccount(c, ctx, accountID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// the new password is hashed with the current parameters, so an outdated
+	// hash of the old password needs no rehash here
+	ok, _, err := h.accountService.ComparePassword(ctx, req.OldPassword, acc.Password)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to compare password: %v", err)
+		utils.RespondError(c, passwordHashError(err))
+		return
+	}
+
+	if !ok {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("invalid old password")
+		utils.RespondError(c, domain.ErrInvalidOldPassword)
+		return
+	}
+
+	if err := h.checkNewPassword(ctx, req.NewPassword); err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
+	hashedPassword, err := h.accountService.HashPassword(ctx, req.NewPassword)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to hash password: %v", err)
+		utils.RespondError(c, passwordHashError(err))
+		return
+	}
+
+	acc.Password = hashedPassword
+
+	acc, err = h.accountRepository.UpdateAccount(ctx, acc)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to update account: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	err = h.logActivity(ctx, acc.ID, domain.ActivityChangePassword)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to log activity: %v", err)
+	}
+
+	utils.RespondJSON(
+		c,
+		http.StatusOK,
+		ChangePasswordResponse{
+			Message: "password changed successfully",
+		},
+	)
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type DeleteAccountResponse struct {
+	Message      string    `json:"message"`
+	ExecuteAfter time.Time `json:"execute_after"`
+}
+
+// @Summary		Delete account
+// @Description	Schedule the deletion of the current account and the organization it owns, the password must be confirmed. It is applied once ACCOUNT_ACTION_GRACE_PERIOD has passed unless cancelled from the emailed link. With ACCOUNT_DELETION_MODE=anonymize the account is anonymized instead.
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		DeleteAccountRequest	true	"Account"
+// @Success		202		{object}	DeleteAccountResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		409		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Failure		503		{object}	utils.ErrorResponse
+// @Router			/api/v1/account [delete]
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "DeleteAccount")
+	defer span.End()
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	acc, err := h.loadAccount(c, ctx, accountID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// a stolen token alone must not be enough to delete the account
+	ok, _, err := h.accountService.ComparePassword(ctx, req.Password, acc.Password)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to compare password: %v", err)
+		utils.RespondError(c, passwordHashError(err))
+		return
+	}
+
+	if !ok {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("invalid password for account deletion")
+		utils.RespondError(c, domain.ErrInvalidCredentials)
+		return
+	}
+
+	// the PendingActionWorker removes the account once the grace period is
+	// over, the owner can cancel from the emailed link until then
+	pendingAction, err := h.schedulePendingAction(ctx, acc, domain.PendingActionDelete, "")
+	if err != nil {
+		if errors.Is(err, domain.ErrPendingActionExists) {
+			utils.RespondError(c, err)
+			return
+		}
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to schedule account deletion: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusAccepted, DeleteAccountResponse{
+		Message:      "account deletion scheduled",
+		ExecuteAfter: pendingAction.ExecuteAfter,
+	})
+}
+
+const defaultPendingActionGracePeriod = 24 * time.Hour
+
+// schedulePendingAction holds back a destructive account change for the
+// configured grace period and emails the owner a link to cancel it, the
+// PendingActionWorker applies it once the grace period is over
+func (h *AccountHandler) schedulePendingAction(ctx context.Context, acc *domain.Account, action string, payload string) (*domain.PendingAccountAction, error) {
+	gracePeriod := viper.GetDuration("ACCOUNT_ACTION_GRACE_PERIOD")
+	if gracePeriod <= 0 {
+		gracePeriod = defaultPendingActionGracePeriod
+	}
+
+	_, err := h.accountRepository.GetOpenPendingAction(ctx, acc.ID, action)
+	if err == nil {
+		return nil, domain.ErrPendingActionExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	cancelToken, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingAction, err := h.accountRepository.CreatePendingAction(ctx, &domain.PendingAccountAction{
+		AccountID:    acc.ID,
+		Action:       action,
+		Payload:      payload,
+		CancelToken:  utils.HashToken(cancelToken),
+		ExecuteAfter: time.Now().Add(gracePeriod),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.accountService.SendPendingActionEmail(ctx, acc.Email, pendingAction, cancelToken)
+	if err != nil {
+		// without the email the owner cannot cancel the change, withdraw it so
+		// the request can be retried
+		cancelledAt := time.Now()
+		pendingAction.CancelledAt = &cancelledAt
+		if _, cancelErr := h.accountRepository.UpdatePendingAction(ctx, pendingAction); cancelErr != nil {
+			h.logger.WithContext(ctx).Errorf("failed to withdraw pending account action: %v", cancelErr)
+		}
+		return nil, err
+	}
+
+	return pendingAction, nil
+}
+
+type PendingActionResponse struct {
+	Action       string    `json:"action"`
+	ExecuteAfter time.Time `json:"execute_after"`
+	// Cancellable is false once the action was applied or cancelled
+	Cancellable bool `json:"cancellable"`
+}
+
+type CancelPendingActionRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type CancelPendingActionResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary		Get a pending account action
+// @Description	Describe the pending account change of a cancel link so the confirmation page can show it, nothing is cancelled
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			token	query		string	true	"Cancel token"
+// @Success		200		{object}	PendingActionResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		404		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/pending-action [get]
+func (h *AccountHandler) GetPendingAction(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "GetPendingAction")
+	defer span.End()
+
+	token := c.Query("token")
+	if token == "" {
+		utils.RespondError(c, domain.ErrTokenRequired)
+		return
+	}
+
+	pendingAction, err := h.pendingActionByCancelToken(ctx, token)
+	if err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, PendingActionResponse{
+		Action:       pendingAction.Action,
+		ExecuteAfter: pendingAction.ExecuteAfter,
+		Cancellable:  pendingAction.AppliedAt == nil && pendingAction.CancelledAt == nil,
+	})
+}
+
+// @Summary		Cancel a pending account action
+// @Description	Cancel a pending account change using the token from the notification email, the link in the email opens a confirmation page that posts here
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			request	body		CancelPendingActionRequest	true	"Cancel token"
+// @Success		200		{object}	CancelPendingActionResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		404		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/pending-action/cancel [post]
+func (h *AccountHandler) CancelPendingAction(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "CancelPendingAction")
+	defer span.End()
+
+	var req CancelPendingActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	pendingAction, err := h.pendingActionByCancelToken(ctx, req.Token)
+	if err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+
+	if pendingAction.AppliedAt != nil || pendingAction.CancelledAt != nil {
+		utils.RespondError(c, domain.ErrPendingActionExpired)
+		return
+	}
+
+	cancelledAt := time.Now()
+	pendingAction.CancelledAt = &cancelledAt
+
+	_, err = h.accountRepository.UpdatePendingAction(ctx, pendingAction)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", pendingAction.AccountID).Errorf("failed to cancel pending action: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(
+		c,
+		http.StatusOK,
+		CancelPendingActionResponse{
+			Message: "pending action cancelled",
+		},
+	)
+}
+
+// pendingActionByCancelToken returns the pending action of a cancel token,
+// ErrPendingActionNotFound for an unknown token
+func (h *AccountHandler) pendingActionByCancelToken(ctx context.Context, token string) (*domain.PendingAccountAction, error) {
+	pendingAction, err := h.accountRepository.GetPendingActionByCancelToken(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrPendingActionNotFound
+		}
+		h.logger.WithContext(ctx).Errorf("failed to get pending action: %v", err)
+		return nil, domain.ErrInternal
+	}
+	return pendingAction, nil
+}
+
+type RotateSigningKeyResponse struct {
+	Kid                 string    `json:"kid"`
+	PreviousKid         string    `json:"previous_kid"`
+	GracePeriodEndAt    time.Time `json:"grace_period_end_at"`
+	OutstandingSessions int64     `json:"outstanding_sessions"`
+}
+
+// @Summary		Rotate the JWT signing key
+// @Description	Generate a new signing key and make it current, the previous key keeps validating tokens for the grace period
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	RotateSigningKeyResponse
+// @Failure		403	{object}	utils.ErrorResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/jwt/rotate [post]
+func (h *AccountHandler) RotateSigningKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "RotateSigningKey")
+	defer span.End()
+
+	rotation, err := h.accountService.RotateSigningKey(ctx)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to rotate signing key: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// sessions are the refresh tokens neither expired nor revoked, they were
+	// all issued on a key before this one
+	outstanding, err := h.accountRepository.CountActiveRefreshTokens(ctx, rotation.RotatedAt)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to count outstanding sessions: %v", err)
+	}
+
+	h.logger.WithContext(ctx).WithField("kid", rotation.Kid).Infof("signing key rotated, %d sessions outstanding on previous key", outstanding)
+
+	utils.RespondJSON(c, http.StatusOK, RotateSigningKeyResponse{
+		Kid:                 rotation.Kid,
+		PreviousKid:         rotation.PreviousKid,
+		GracePeriodEndAt:    rotation.GracePeriodEndAt,
+		OutstandingSessions: outstanding,
+	})
+}
+
+type UnlockAccountRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type UnlockAccountResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary		Unlock an account
+// @Description	Clear the lockout and failed login count of an account before its cooldown ends
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			account	body		UnlockAccountRequest	true	"Account"
+// @Success		200		{object}	UnlockAccountResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		403		{object}	utils.ErrorResponse
+// @Failure		404		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/account/unlock [post]
+func (h *AccountHandler) UnlockAccount(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "UnlockAccount")
+	defer span.End()
+
+	var req UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	email, err := utils.NormalizeEmail(req.Email)
+	if err != nil {
+		utils.RespondError(c, err)
+		return
+	}
+	req.Email = email
+
+	acc, err := h.accountRepository.GetAccountByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, domain.ErrAccountNotFound)
+			return
+		}
+		h.logger.WithContext(ctx).Errorf("failed to get account by email: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	acc.FailedLoginCount = 0
+	acc.LockedUntil = nil
+
+	if _, err := h.accountRepository.UpdateAccount(ctx, acc); err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to unlock account: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	err = h.logActivity(ctx, acc.ID, domain.ActivityUnlock)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
+	}
+
+	utils.RespondJSON(c, http.StatusOK, UnlockAccountResponse{
+		Message: "account unlocked",
+	})
+}
+
+// respondListQueryError answers a list request whose pagination or sorting
+// does not parse, with the error code when it has one
+func respondListQueryError(c *gin.Context, err error) {
+	if errors.Is(err, domain.ErrInvalidPagination) || errors.Is(err, domain.ErrInvalidSort) || errors.Is(err, domain.ErrInvalidSortOrder) {
+		utils.RespondError(c, err)
+		return
+	}
+	utils.RespondErrorStatus(c, http.StatusBadRequest, err)
+}
+
+const (
+	defaultAccountPageSize = 20
+	maxAccountPageSize     = 100
+)
+
+// accountSortFields are the sort values of the admin account listing
+var accountSortFields = utils.SortFields{
+	"id":            "id",
+	"email":         "email",
+	"created_at":    "created_at",
+	"last_login_at": "last_login_at",
+}
+
+type AdminAccountResponse struct {
+	ID            uint       `json:"id"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"email_verified"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastLoginAt   *time.Time `json:"last_login_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	DisabledAt    *time.Time `json:"disabled_at"`
+}
+
+func newAdminAccountResponse(acc *domain.Account) AdminAccountResponse {
+	return AdminAccountResponse{
+		ID:            acc.ID,
+		Email:         acc.Email,
+		Role:          acc.Role,
+		EmailVerified: acc.EmailVerified,
+		CreatedAt:     acc.CreatedAt,
+		LastLoginAt:   acc.LastLoginAt,
+		LockedUntil:   acc.LockedUntil,
+		DisabledAt:    acc.DisabledAt,
+	}
+}
+
+type ListAccountsResponse struct {
+	Accounts []AdminAccountResponse `json:"accounts"`
+	Total    int64                  `json:"total"`
+	Limit    int                    `json:"limit"`
+	Offset   int                    `json:"offset"`
+	Sort     string                 `json:"sort"`
+	Order    string                 `json:"order"`
+}
+
+// @Summary		List accounts
+// @Description	List the accounts, newest first unless sorted otherwise, for accounts with the admin role
+// @Tags			admin
+// @Produce		json
+// @Param			limit	query		int		false	"Page size, default 20, max 100"
+// @Param			offset	query		int		false	"Number of accounts to skip"
+// @Param			sort	query		string	false	"id, email, created_at or last_login_at, default created_at"
+// @Param			order	query		string	false	"asc or desc, default desc"
+// @Success		200		{object}	ListAccountsResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		403		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/accounts [get]
+func (h *AccountHandler) ListAccounts(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ListAccounts")
+	defer span.End()
+
+	page, err := utils.ParsePagination(c, defaultAccountPageSize, maxAccountPageSize)
+	if err != nil {
+		respondListQueryError(c, err)
+		return
+	}
+	sorting, err := utils.ParseSorting(c, accountSortFields, "created_at", utils.SortDesc)
+	if err != nil {
+		respondListQueryError(c, err)
+		return
+	}
+
+	accounts, total, err := h.accountRepository.ListAccounts(ctx, sorting.Scope(), page.Limit, page.Offset)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to list accounts: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	response := ListAccountsResponse{
+		Accounts: make([]AdminAccountResponse, 0, len(accounts)),
+		Total:    total,
+		Limit:    page.Limit,
+		Offset:   page.Offset,
+		Sort:     sorting.Field,
+		Order:    sorting.Order,
+	}
+	for i := range accounts {
+		response.Accounts = append(response.Accounts, newAdminAccountResponse(&accounts[i]))
+	}
+
+	utils.RespondJSON(c, http.StatusOK, response)
+}
+
+type SetAccountRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// @Summary		Change the role of an account
+// @Description	Give an account the user or admin role, tokens issued before the change keep the old role
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int						true	"Account ID"
+// @Param			role	body		SetAccountRoleRequest	true	"Role"
+// @Success		200		{object}	AdminAccountResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		403		{object}	utils.ErrorResponse
+// @Failure		404		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/accounts/{id}/role [put]
+func (h *AccountHandler) SetAccountRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "SetAccountRole")
+	defer span.End()
+
+	// an id that does not parse cannot name an account
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondError(c, domain.ErrAccountNotFound)
+		return
+	}
+
+	var req SetAccountRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+	if !slices.Contains(domain.Roles, req.Role) {
+		utils.RespondError(c, domain.ErrInvalidRole)
+		return
+	}
+
+	acc, err := h.accountRepository.GetAccountByID(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, domain.ErrAccountNotFound)
+			return
+		}
+		h.logger.WithContext(ctx).WithField("userId", id).Errorf("failed to get account by id: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	if acc.Role != req.Role {
+		acc.Role = req.Role
+		if _, err := h.accountRepository.UpdateAccount(ctx, acc); err != nil {
+			h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to change role: %v", err)
+			utils.RespondError(c, domain.ErrInternal)
+			return
+		}
+
+		h.logger.WithContext(ctx).WithField("userId", acc.ID).
+			WithField("adminId", c.GetUint(utils.AccountIdContextKey)).
+			Infof("role changed to %s", acc.Role)
+
+		if err := h.logActivity(ctx, acc.ID, domain.ActivityRoleChange); err != nil {
+			h.logger.WithContext(ctx).WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
+		}
+	}
+
+	utils.RespondJSON(c, http.StatusOK, newAdminAccountResponse(acc))
+}
+
+const (
+	apiKeyPrefix       = "sk_"
+	apiKeyBytes        = 32
+	apiKeyDisplayChars = 8
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyResponse describes a key without the key itself, Prefix is enough for
+// the owner to tell keys apart
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	// Key is only returned once, on creation
+	Key string `json:"key"`
+}
+
+type ListAPIKeysResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
+
+type RevokeAPIKeyResponse struct {
+	Message string `json:"message"`
+}
+
+func newAPIKeyResponse(key domain.APIKey) APIKeyResponse {
+	scopes := []string{}
+	if key.Scopes != "" {
+		scopes = strings.Split(key.Scopes, ",")
+	}
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
+
+// @Summary		Create an API key
+// @Description	Create an API key for the current account, the key is only shown in this response
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			account	body		CreateAPIKeyRequest	true	"API key"
+// @Success		200		{object}	CreateAPIKeyResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/api-keys [post]
+func (h *AccountHandler) CreateAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "CreateAPIKey")
+	defer span.End()
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.RespondError(c, domain.ErrAPIKeyNameMissing)
+		return
+	}
+
+	token, err := utils.GenerateToken(apiKeyBytes)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to generate api key: %v", err)
+		utils.RespondError(c, domain.ErrTokenGenerationFailed)
+		return
+	}
+	key := apiKeyPrefix + token
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	apiKey, err := h.accountRepository.CreateAPIKey(ctx, &domain.APIKey{
+		AccountID: accountID,
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+apiKeyDisplayChars],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    strings.Join(scopes, ","),
+	})
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to create api key: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, CreateAPIKeyResponse{
+		APIKeyResponse: newAPIKeyResponse(*apiKey),
+		Key:            key,
+	})
+}
+
+// @Summary		List API keys
+// @Description	List the active API keys of the current account, the keys themselves are never returned
+// @Tags			account
+// @Produce		json
+// @Success		200		{object}	ListAPIKeysResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/api-keys [get]
+func (h *AccountHandler) ListAPIKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ListAPIKeys")
+	defer span.End()
+
+	utils.SetCacheControl(c, "CACHE_CONTROL_ACCOUNT", utils.CacheControlPrivate)
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	keys, err := h.accountRepository.ListAPIKeys(ctx, accountID)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to list api keys: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	response := ListAPIKeysResponse{APIKeys: make([]APIKeyResponse, 0, len(keys))}
+	for _, key := range keys {
+		response.APIKeys = append(response.APIKeys, newAPIKeyResponse(key))
+	}
+
+	utils.RespondJSON(c, http.StatusOK, response)
+}
+
+// @Summary		Revoke an API key
+// @Description	Revoke an API key of the current account
+// @Tags			account
+// @Produce		json
+// @Param			id	path		int	true	"API key id"
+// @Success		200		{object}	RevokeAPIKeyResponse
+// @Failure		404		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/account/api-keys/{id} [delete]
+func (h *AccountHandler) RevokeAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "RevokeAPIKey")
+	defer span.End()
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		h.logger.WithContext(ctx).Errorf("accountID not found")
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// an id that does not parse cannot name a key of this account
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondError(c, domain.ErrAPIKeyNotFound)
+		return
+	}
+
+	revoked, err := h.accountRepository.RevokeAPIKey(ctx, accountID, uint(id), time.Now())
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to revoke api key: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+	if !revoked {
+		utils.RespondError(c, domain.ErrAPIKeyNotFound)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, RevokeAPIKeyResponse{
+		Message: "api key revoked",
+	})
+}
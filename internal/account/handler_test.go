@@ -4,17 +4,28 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"spsyncpro_api/internal/account"
 	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace/noop"
 	"gorm.io/gorm"
 )
@@ -154,6 +165,18 @@ func TestAccountHandler_RegisterAccount(t *testing.T) {
 		// Mock service methods
 		service.On("HashPassword", anyContext, "password").Return("hashed_password", nil)
 		service.On("GenerateAuthToken", anyContext, mock.AnythingOfType("*domain.Account")).Return("auth_token", nil)
+		service.On("ParseClaims", anyContext, "auth_token").Return(&domain.AuthClaims{AccountID: 1, TokenID: "jti"}, nil)
+		// the session is stored with the expiry of the token
+		refreshExpiresAt := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+		service.On("GenerateRefreshToken", anyContext, mock.AnythingOfType("*domain.Account"), false).Return("refresh_token", refreshExpiresAt, nil)
+		repository.On("CreateRefreshToken", anyContext, mock.MatchedBy(func(token *domain.RefreshToken) bool {
+			return token.AccountID == 1 && token.TokenHash == utils.HashToken("refresh_token") && token.ExpiresAt.Equal(refreshExpiresAt)
+		})).Return(&domain.RefreshToken{ID: 1}, nil)
+		service.On("GenerateEmailVerificationToken", anyContext, mock.AnythingOfType("*domain.Account")).Return("verify_token", nil)
+		service.On("SendVerificationEmail", anyContext, "test@example.com", "verify_token").Return(&domain.EmailLog{MessageID: "<message-id@developer.com>"}, nil)
+		repository.On("LogEmail", anyContext, mock.MatchedBy(func(l *domain.EmailLog) bool {
+			return l.AccountID == 1
+		})).Return(nil)
 
 		handler := account.NewAccountHandler(logger, service, repository)
 
@@ -177,6 +200,29 @@ func TestAccountHandler_RegisterAccount(t *testing.T) {
 		assert.Equal(t, "test@example.com", response.Email)
 		assert.Equal(t, uint(1), response.ID)
 		assert.Equal(t, "auth_token", response.Token)
+		assert.Equal(t, "refresh_token", response.RefreshToken)
+	})
+
+	t.Run("should return 503 when the hashing workers stay busy", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(nil, gorm.ErrRecordNotFound)
+		service.On("HashPassword", anyContext, "password").Return("", domain.ErrPasswordHashingBusy)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{
+			Email:    "test@example.com",
+			Password: "password",
+		}, nil)
+
+		var response utils.ErrorResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "account.hashing_busy", response.Code)
+		repository.AssertNotCalled(t, "CreateAccount", mock.Anything, mock.Anything)
 	})
 
 	t.Run("should return error when account already exists", func(t *testing.T) {
@@ -206,6 +252,2545 @@ func TestAccountHandler_RegisterAccount(t *testing.T) {
 		httpHelper.AssertJSONResponse(t, w, &response)
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 		assert.Equal(t, "account already exists", response["error"])
+		assert.Equal(t, "account.already_exists", response["code"])
+	})
+
+	t.Run("should dedup a mixed case email against the existing account", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(&domain.Account{ID: 1, Email: "test@example.com"}, nil)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{
+			Email:    "  Test@Example.COM ",
+			Password: "password",
+		}, nil)
+
+		var response utils.ErrorResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "account.already_exists", response.Code)
+		repository.AssertNotCalled(t, "CreateAccount", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should store a new email lowercased", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+		repository.On("CreateAccount", anyContext, mock.MatchedBy(func(acc *domain.Account) bool {
+			return acc.Email == "new@example.com"
+		})).Return(&domain.Account{ID: 1, Email: "new@example.com"}, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityRegister).Return(nil)
+		repository.On("CreateRefreshToken", anyContext, mock.AnythingOfType("*domain.RefreshToken")).Return(&domain.RefreshToken{ID: 1}, nil)
+		repository.On("LogEmail", anyContext, mock.AnythingOfType("*domain.EmailLog")).Return(nil)
+		service.On("HashPassword", anyContext, "password").Return("hashed_password", nil)
+		service.On("GenerateAuthToken", anyContext, mock.AnythingOfType("*domain.Account")).Return("auth_token", nil)
+		service.On("ParseClaims", anyContext, "auth_token").Return(&domain.AuthClaims{AccountID: 1, TokenID: "jti"}, nil)
+		service.On("GenerateRefreshToken", anyContext, mock.AnythingOfType("*domain.Account"), false).Return("refresh_token", time.Now().Add(time.Hour), nil)
+		service.On("GenerateEmailVerificationToken", anyContext, mock.AnythingOfType("*domain.Account")).Return("verify_token", nil)
+		service.On("SendVerificationEmail", anyContext, "new@example.com", "verify_token").Return(&domain.EmailLog{}, nil)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{
+			Email:    "New@Example.com",
+			Password: "password",
+		}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should list the missing and invalid fields", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/register", map[string]string{}, nil)
+
+		var response utils.ErrorResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, "request.validation_failed", response.Code)
+		assert.Equal(t, []string{"email is required", "password is required"}, response.Details)
+
+		w = httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{
+			Email:    "test@example.com",
+			Password: strings.Repeat("p", 129),
+		}, nil)
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, []string{"password must be at most 128 characters"}, response.Details)
+		repository.AssertNotCalled(t, "GetAccountByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject a malformed email", func(t *testing.T) {
+		for _, email := range []string{"   ", "not-an-email", "test@localhost", "Test <test@example.com>"} {
+			repository := domain.NewMockAccountRepository(t)
+			handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+			httpHelper := NewHTTPTestHelper()
+			httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+
+			w := httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{
+				Email:    email,
+				Password: "password",
+			}, nil)
+
+			var response utils.ErrorResponse
+			httpHelper.AssertJSONResponse(t, w, &response)
+			assert.Equal(t, http.StatusBadRequest, w.Code, email)
+			assert.Equal(t, "account.invalid_email", response.Code, email)
+			repository.AssertNotCalled(t, "GetAccountByEmail", mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func TestAccountHandler_RegisterAccountHidingExisting(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("REGISTRATION_HIDE_EXISTING_ACCOUNTS", true)
+	defer viper.Reset()
+
+	register := func(handler *account.AccountHandler, email string) *httptest.ResponseRecorder {
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+		return httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{Email: email, Password: "password"}, nil)
+	}
+
+	t.Run("should create a new account without logging in", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		service.On("HashPassword", anyContext, "password").Return("hashed_password", nil)
+		repository.On("GetAccountByEmail", anyContext, "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+		repository.On("CreateAccount", anyContext, mock.MatchedBy(func(acc *domain.Account) bool {
+			return acc.Email == "new@example.com" && acc.Password == "hashed_password"
+		})).Return(&domain.Account{ID: 1, Email: "new@example.com"}, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityRegister).Return(nil)
+		service.On("GenerateEmailVerificationToken", anyContext, mock.AnythingOfType("*domain.Account")).Return("verify_token", nil)
+		service.On("SendVerificationEmail", anyContext, "new@example.com", "verify_token").Return(&domain.EmailLog{MessageID: "<message-id@developer.com>"}, nil)
+		repository.On("LogEmail", anyContext, mock.MatchedBy(func(l *domain.EmailLog) bool { return l.AccountID == 1 })).Return(nil)
+
+		w := register(account.NewAccountHandler(logrus.New(), service, repository), "new@example.com")
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Empty(t, w.Result().Cookies())
+		assert.NotContains(t, w.Body.String(), "token")
+	})
+
+	t.Run("should email the owner of an existing account", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		existing := &domain.Account{ID: 2, Email: "test@example.com"}
+		service.On("HashPassword", anyContext, "password").Return("hashed_password", nil)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(existing, nil)
+		service.On("SendAccountExistsEmail", anyContext, "test@example.com").Return(&domain.EmailLog{MessageID: "<message-id@developer.com>"}, nil)
+		repository.On("LogEmail", anyContext, mock.MatchedBy(func(l *domain.EmailLog) bool { return l.AccountID == 2 })).Return(nil)
+
+		w := register(account.NewAccountHandler(logrus.New(), service, repository), "test@example.com")
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		repository.AssertNotCalled(t, "CreateAccount", anyContext, mock.Anything)
+	})
+
+	t.Run("should answer new and existing emails with the same response", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		service.On("HashPassword", anyContext, "password").Return("hashed_password", nil)
+		repository.On("GetAccountByEmail", anyContext, "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(&domain.Account{ID: 2, Email: "test@example.com"}, nil)
+		repository.On("CreateAccount", anyContext, mock.AnythingOfType("*domain.Account")).Return(&domain.Account{ID: 1, Email: "new@example.com"}, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityRegister).Return(nil)
+		service.On("GenerateEmailVerificationToken", anyContext, mock.AnythingOfType("*domain.Account")).Return("verify_token", nil)
+		service.On("SendVerificationEmail", anyContext, "new@example.com", "verify_token").Return(nil, errors.New("smtp down"))
+		service.On("SendAccountExistsEmail", anyContext, "test@example.com").Return(nil, errors.New("smtp down"))
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		created := register(handler, "new@example.com")
+		existing := register(handler, "test@example.com")
+
+		assert.Equal(t, created.Code, existing.Code)
+		assert.Equal(t, created.Body.String(), existing.Body.String())
+
+		var response account.RegisterAccountAcceptedResponse
+		assert.NoError(t, json.Unmarshal(existing.Body.Bytes(), &response))
+		assert.NotEmpty(t, response.Message)
+		assert.NotContains(t, existing.Body.String(), "account.already_exists")
+	})
+
+	t.Run("should keep rejecting an existing email when turned off", func(t *testing.T) {
+		viper.Set("REGISTRATION_HIDE_EXISTING_ACCOUNTS", false)
+		defer viper.Set("REGISTRATION_HIDE_EXISTING_ACCOUNTS", true)
+
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(&domain.Account{ID: 2, Email: "test@example.com"}, nil)
+
+		w := register(account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository), "test@example.com")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "account.already_exists")
+	})
+}
+
+func TestAccountHandler_LogsTraceIDs(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	repository := domain.NewMockAccountRepository(t)
+	repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(nil, errors.New("connection refused"))
+
+	logger, hook := logtest.NewNullLogger()
+	logger.AddHook(&utils.TraceHook{})
+	handler := account.NewAccountHandler(logger, domain.NewMockAccountService(t), repository)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+
+	w := httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{Email: "test@example.com", Password: "password"}, nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	entry := hook.LastEntry()
+	assert.Equal(t, spans[0].SpanContext().TraceID().String(), entry.Data["trace_id"])
+	assert.Equal(t, spans[0].SpanContext().SpanID().String(), entry.Data["span_id"])
+}
+
+func TestAccountHandler_BreachedPassword(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	setup := func(handler *account.AccountHandler) *HTTPTestHelper {
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+		httpHelper.router.POST("/account/change-password", func(c *gin.Context) {
+			c.Set(utils.AccountIdContextKey, uint(1))
+		}, handler.ChangePassword)
+		return httpHelper
+	}
+
+	t.Run("should reject a breached password on register", func(t *testing.T) {
+		viper.Set("CHECK_BREACHED_PASSWORDS", true)
+		defer viper.Reset()
+
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(nil, gorm.ErrRecordNotFound)
+
+		checker := domain.NewMockBreachedPasswordChecker(t)
+		checker.On("IsBreached", anyContext, "password123").Return(true, nil)
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		handler.SetBreachedPasswordChecker(checker)
+
+		w := setup(handler).MakeRequest("POST", "/account/register", account.RegisterAccountRequest{Email: "test@example.com", Password: "password123"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response utils.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, domain.ErrPasswordBreached.Error(), response.Error)
+		assert.Equal(t, "account.password_breached", response.Code)
+		repository.AssertNotCalled(t, "CreateAccount", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject a breached new password on change", func(t *testing.T) {
+		viper.Set("CHECK_BREACHED_PASSWORDS", true)
+		defer viper.Reset()
+
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(&domain.Account{ID: 1, Password: "hash"}, nil)
+		service.On("ComparePassword", anyContext, "old_password", "hash").Return(true, false, nil)
+
+		checker := domain.NewMockBreachedPasswordChecker(t)
+		checker.On("IsBreached", anyContext, "password123").Return(true, nil)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		handler.SetBreachedPasswordChecker(checker)
+
+		w := setup(handler).MakeRequest("POST", "/account/change-password", account.ChangePasswordRequest{OldPassword: "old_password", NewPassword: "password123"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		repository.AssertNotCalled(t, "UpdateAccount", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should let the password through when the lookup fails or the check is off", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(func(context.Context, uint) *domain.Account {
+			return &domain.Account{ID: 1, Password: "hash"}
+		}, nil)
+		service.On("ComparePassword", anyContext, "old_password", "hash").Return(true, false, nil)
+		service.On("HashPassword", anyContext, "password123").Return("new_hash", nil)
+		repository.On("UpdateAccount", anyContext, mock.AnythingOfType("*domain.Account")).Return(&domain.Account{ID: 1}, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityChangePassword).Return(nil)
+
+		checker := domain.NewMockBreachedPasswordChecker(t)
+		checker.On("IsBreached", anyContext, "password123").Return(false, context.DeadlineExceeded).Once()
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		handler.SetBreachedPasswordChecker(checker)
+		httpHelper := setup(handler)
+
+		viper.Set("CHECK_BREACHED_PASSWORDS", true)
+		w := httpHelper.MakeRequest("POST", "/account/change-password", account.ChangePasswordRequest{OldPassword: "old_password", NewPassword: "password123"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		viper.Reset()
+		w = httpHelper.MakeRequest("POST", "/account/change-password", account.ChangePasswordRequest{OldPassword: "old_password", NewPassword: "password123"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestAccountHandler_PasswordPolicy(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should reject a weak password on register with the unmet rules", func(t *testing.T) {
+		viper.Set("PASSWORD_REQUIRE_DIGIT", true)
+		defer viper.Reset()
+
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(nil, gorm.ErrRecordNotFound)
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{Email: "test@example.com", Password: "short"}, nil)
+
+		var response utils.ErrorResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "account.password_weak", response.Code)
+		assert.Equal(t, []string{domain.PasswordRuleMinLength, domain.PasswordRuleDigit}, response.Details)
+		repository.AssertNotCalled(t, "CreateAccount", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject a weak new password on change", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(&domain.Account{ID: 1, Password: "hash"}, nil)
+		service.On("ComparePassword", anyContext, "old_password", "hash").Return(true, false, nil)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.router.POST("/account/change-password", func(c *gin.Context) {
+			c.Set(utils.AccountIdContextKey, uint(1))
+		}, handler.ChangePassword)
+
+		w := httpHelper.MakeRequest("POST", "/account/change-password", account.ChangePasswordRequest{OldPassword: "old_password", NewPassword: "short"}, nil)
+
+		var response utils.ErrorResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, []string{domain.PasswordRuleMinLength}, response.Details)
+		repository.AssertNotCalled(t, "UpdateAccount", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAccountHandler_GetAuthMethods(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	lookup := func(t *testing.T, acc *domain.Account, lookupErr error) (*httptest.ResponseRecorder, account.AuthMethodsResponse) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, lookupErr)
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/auth-methods", handler.GetAuthMethods)
+
+		w := httpHelper.MakeRequest("POST", "/account/auth-methods", account.AuthMethodsRequest{Email: "Test@Example.com"}, nil)
+
+		var response account.AuthMethodsResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		return w, response
+	}
+
+	t.Run("should report password for a password account", func(t *testing.T) {
+		viper.Set("AUTH_METHODS_MIN_DURATION", "0s")
+		defer viper.Reset()
+
+		w, response := lookup(t, &domain.Account{ID: 1, Email: "test@example.com", Password: "hash"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{domain.AuthMethodPassword}, response.Methods)
+	})
+
+	t.Run("should report no password for an account without one", func(t *testing.T) {
+		viper.Set("AUTH_METHODS_MIN_DURATION", "0s")
+		defer viper.Reset()
+
+		w, response := lookup(t, &domain.Account{ID: 1, Email: "test@example.com"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, response.Methods)
+		assert.NotNil(t, response.Methods)
+	})
+
+	t.Run("should answer an unknown email like a password account", func(t *testing.T) {
+		viper.Set("AUTH_METHODS_MIN_DURATION", "0s")
+		defer viper.Reset()
+
+		known, _ := lookup(t, &domain.Account{ID: 1, Email: "test@example.com", Password: "hash"}, nil)
+		unknown, _ := lookup(t, nil, gorm.ErrRecordNotFound)
+		assert.Equal(t, known.Code, unknown.Code)
+		assert.Equal(t, known.Body.String(), unknown.Body.String())
+	})
+
+	t.Run("should take the minimum duration for known and unknown emails", func(t *testing.T) {
+		viper.Set("AUTH_METHODS_MIN_DURATION", "50ms")
+		defer viper.Reset()
+
+		start := time.Now()
+		lookup(t, &domain.Account{ID: 1, Email: "test@example.com", Password: "hash"}, nil)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		start = time.Now()
+		lookup(t, nil, gorm.ErrRecordNotFound)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+}
+
+func TestAccountHandler_LoginAccount(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should upgrade a password hashed with old parameters", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		defer viper.Reset()
+
+		logger := logrus.New()
+		service := account.NewAccountService(nil, nil)
+		repository := domain.NewMockAccountRepository(t)
+
+		oldHash, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+
+		viper.Set("ARGON2_TIME", 2)
+
+		var upgradedHash string
+		acc := &domain.Account{ID: 1, Email: "test@example.com", Password: oldHash}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		repository.On("UpdateAccount", anyContext, mock.AnythingOfType("*domain.Account")).
+			Run(func(args mock.Arguments) { upgradedHash = args.Get(1).(*domain.Account).Password }).
+			Return(acc, nil).Once()
+		repository.On("CreateRefreshToken", anyContext, mock.AnythingOfType("*domain.RefreshToken")).Return(&domain.RefreshToken{}, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityLogin).Return(nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{
+			Email:    "test@example.com",
+			Password: "password",
+		}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.NotEqual(t, oldHash, upgradedHash)
+		assert.Contains(t, upgradedHash, ",t=2,")
+
+		ok, needsRehash, err := service.ComparePassword(context.Background(), "password", upgradedHash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.False(t, needsRehash)
+	})
+
+	t.Run("should look up a mixed case email normalized", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(&domain.Account{ID: 1, Email: "test@example.com", Password: "hash"}, nil)
+		service.On("ComparePassword", anyContext, "wrong", "hash").Return(false, false, nil)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{Email: "TEST@example.com", Password: "wrong"}, nil)
+
+		var response utils.ErrorResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, "auth.invalid_credentials", response.Code)
+	})
+
+	t.Run("should reject a malformed email", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{Email: "test@", Password: "password"}, nil)
+
+		var response utils.ErrorResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "account.invalid_email", response.Code)
+	})
+
+	t.Run("should respond once with invalid credentials for an unknown email", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "missing@example.com").Return(nil, gorm.ErrRecordNotFound)
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{Email: "missing@example.com", Password: "password"}, nil)
+
+		// a second write would append another json object to the body
+		var response utils.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "auth.invalid_credentials", response.Code)
 	})
 
+	t.Run("should respond once with an internal error when the lookup fails", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(nil, errors.New("connection refused"))
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{Email: "test@example.com", Password: "password"}, nil)
+
+		var response utils.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "server.internal", response.Code)
+	})
+
+	t.Run("should issue a longer lived refresh token when remembered", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("JWT_REFRESH_EXPIRY", "24h")
+		viper.Set("JWT_REFRESH_TTL_REMEMBER", "720h")
+		defer viper.Reset()
+
+		service := account.NewAccountService(nil, nil)
+		hash, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+
+		login := func(t *testing.T, rememberMe bool) (*domain.RefreshToken, time.Time) {
+			repository := domain.NewMockAccountRepository(t)
+			acc := &domain.Account{ID: 1, Email: "test@example.com", Password: hash}
+			repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+			repository.On("UpdateAccount", anyContext, acc).Return(acc, nil)
+			repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityLogin).Return(nil)
+
+			var session *domain.RefreshToken
+			repository.On("CreateRefreshToken", anyContext, mock.AnythingOfType("*domain.RefreshToken")).
+				Run(func(args mock.Arguments) { session = args.Get(1).(*domain.RefreshToken) }).
+				Return(&domain.RefreshToken{ID: 1}, nil)
+
+			handler := account.NewAccountHandler(logrus.New(), service, repository)
+			httpHelper := NewHTTPTestHelper()
+			httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+			w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{
+				Email:      "test@example.com",
+				Password:   "password",
+				RememberMe: rememberMe,
+			}, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response account.LoginAccountResponse
+			httpHelper.AssertJSONResponse(t, w, &response)
+			parsed, _, err := jwt.NewParser().ParseUnverified(response.RefreshToken, jwt.MapClaims{})
+			assert.NoError(t, err)
+			exp, err := parsed.Claims.GetExpirationTime()
+			assert.NoError(t, err)
+
+			return session, exp.Time
+		}
+
+		session, exp := login(t, false)
+		assert.False(t, session.RememberMe)
+		assert.WithinDuration(t, time.Now().Add(24*time.Hour), session.ExpiresAt, time.Minute)
+		assert.WithinDuration(t, session.ExpiresAt, exp, time.Minute)
+
+		session, exp = login(t, true)
+		assert.True(t, session.RememberMe)
+		assert.WithinDuration(t, time.Now().Add(720*time.Hour), session.ExpiresAt, time.Minute)
+		assert.WithinDuration(t, session.ExpiresAt, exp, time.Minute)
+	})
+}
+
+func TestAccountHandler_LoginDisabledAccount(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should reject login to a disabled account", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		disabledAt := time.Now()
+		acc := &domain.Account{ID: 1, Email: "test@example.com", Password: "hash", DisabledAt: &disabledAt}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		service.On("ComparePassword", anyContext, "password", "hash").Return(true, false, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{
+			Email:    "test@example.com",
+			Password: "password",
+		}, nil)
+
+		var response map[string]string
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, domain.ErrAccountDisabled.Error(), response["error"])
+		assert.Equal(t, "account.disabled", response["code"])
+	})
+}
+
+func TestAccountHandler_VerifyEmail(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should verify the email once and reject reuse of the token", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		defer viper.Reset()
+
+		logger := logrus.New()
+		service := account.NewAccountService(nil, nil)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com"}
+		token, err := service.GenerateEmailVerificationToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(acc, nil)
+		repository.On("UpdateAccount", anyContext, acc).Return(acc, nil).Once()
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityVerifyEmail).Return(nil).Once()
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/verify-email", handler.VerifyEmail)
+
+		w := httpHelper.MakeRequest("POST", "/account/verify-email", account.VerifyEmailRequest{Token: token}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, acc.EmailVerified)
+		assert.NotNil(t, acc.VerifiedAt)
+
+		w = httpHelper.MakeRequest("POST", "/account/verify-email", account.VerifyEmailRequest{Token: token}, nil)
+
+		var response map[string]string
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, domain.ErrEmailAlreadyVerified.Error(), response["error"])
+		assert.Equal(t, "account.email_already_verified", response["code"])
+	})
+
+	t.Run("should reject a token issued for a previous email", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		defer viper.Reset()
+
+		logger := logrus.New()
+		service := account.NewAccountService(nil, nil)
+		repository := domain.NewMockAccountRepository(t)
+
+		token, err := service.GenerateEmailVerificationToken(context.Background(), &domain.Account{ID: 1, Email: "old@example.com"})
+		assert.NoError(t, err)
+
+		acc := &domain.Account{ID: 1, Email: "new@example.com"}
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(acc, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/verify-email", handler.VerifyEmail)
+
+		w := httpHelper.MakeRequest("POST", "/account/verify-email", account.VerifyEmailRequest{Token: token}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.False(t, acc.EmailVerified)
+	})
+
+	t.Run("should reject login to an unverified account when required", func(t *testing.T) {
+		viper.Set("REQUIRE_EMAIL_VERIFICATION", true)
+		defer viper.Reset()
+
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com", Password: "hash"}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		service.On("ComparePassword", anyContext, "password", "hash").Return(true, false, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+		w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{
+			Email:    "test@example.com",
+			Password: "password",
+		}, nil)
+
+		var response map[string]string
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, domain.ErrEmailNotVerified.Error(), response["error"])
+		assert.Equal(t, "account.email_not_verified", response["code"])
+	})
+}
+
+func TestAccountHandler_AccountLockout(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	login := func(handler *account.AccountHandler, password string) *httptest.ResponseRecorder {
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+		return httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{
+			Email:    "test@example.com",
+			Password: password,
+		}, nil)
+	}
+
+	t.Run("should count failed logins and lock at the threshold", func(t *testing.T) {
+		viper.Set("ACCOUNT_LOCKOUT_THRESHOLD", 2)
+		viper.Set("ACCOUNT_LOCKOUT_DURATION", "10m")
+		defer viper.Reset()
+
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com", Password: "hash"}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		service.On("ComparePassword", anyContext, "wrong", "hash").Return(false, false, nil)
+		repository.On("UpdateAccount", anyContext, acc).Return(acc, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityLock).Return(nil).Once()
+		service.On("SendAccountLockedEmail", anyContext, "test@example.com", mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+		w := login(handler, "wrong")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, 1, acc.FailedLoginCount)
+		assert.Nil(t, acc.LockedUntil)
+
+		w = login(handler, "wrong")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NotNil(t, acc.LockedUntil)
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), *acc.LockedUntil, time.Minute)
+
+		// the password is not checked while locked
+		w = login(handler, "password")
+		assert.Equal(t, http.StatusLocked, w.Code)
+		assert.Contains(t, w.Body.String(), domain.ErrAccountLocked.Error())
+		service.AssertNotCalled(t, "ComparePassword", anyContext, "password", "hash")
+	})
+
+	t.Run("should allow login again once the cooldown has passed", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("ACCOUNT_LOCKOUT_THRESHOLD", 2)
+		defer viper.Reset()
+
+		service := account.NewAccountService(nil, nil)
+		repository := domain.NewMockAccountRepository(t)
+
+		hash, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+
+		lockedUntil := time.Now().Add(-time.Minute)
+		acc := &domain.Account{ID: 1, Email: "test@example.com", Password: hash, LockedUntil: &lockedUntil}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		repository.On("UpdateAccount", anyContext, acc).Return(acc, nil).Once()
+		repository.On("CreateRefreshToken", anyContext, mock.AnythingOfType("*domain.RefreshToken")).Return(&domain.RefreshToken{}, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityLogin).Return(nil)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+		w := login(handler, "password")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, acc.LockedUntil)
+	})
+
+	t.Run("should reset the failed count on a successful login", func(t *testing.T) {
+		viper.Set("ACCOUNT_LOCKOUT_THRESHOLD", 3)
+		defer viper.Reset()
+
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com", Password: "hash"}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		service.On("ComparePassword", anyContext, "wrong", "hash").Return(false, false, nil)
+		service.On("ComparePassword", anyContext, "password", "hash").Return(true, false, nil)
+		repository.On("UpdateAccount", anyContext, acc).Return(acc, nil)
+		service.On("GenerateAuthToken", anyContext, acc).Return("auth_token", nil)
+		service.On("ParseClaims", anyContext, "auth_token").Return(&domain.AuthClaims{AccountID: 1, TokenID: "jti"}, nil)
+		service.On("GenerateRefreshToken", anyContext, acc, false).Return("refresh_token", time.Now().Add(time.Hour), nil)
+		repository.On("CreateRefreshToken", anyContext, mock.AnythingOfType("*domain.RefreshToken")).Return(&domain.RefreshToken{}, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityLogin).Return(nil)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+		for range 2 {
+			assert.Equal(t, http.StatusBadRequest, login(handler, "wrong").Code)
+		}
+		assert.Equal(t, 2, acc.FailedLoginCount)
+
+		assert.Equal(t, http.StatusOK, login(handler, "password").Code)
+		assert.Equal(t, 0, acc.FailedLoginCount)
+
+		// a fresh count does not lock on the next failure
+		assert.Equal(t, http.StatusBadRequest, login(handler, "wrong").Code)
+		assert.Nil(t, acc.LockedUntil)
+	})
+
+	t.Run("should not lock out an allow-listed ip but log its failures", func(t *testing.T) {
+		viper.Set("ACCOUNT_LOCKOUT_THRESHOLD", 1)
+		defer viper.Reset()
+
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com", Password: "hash"}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		service.On("ComparePassword", anyContext, "wrong", "hash").Return(false, false, nil)
+
+		logger, hook := logtest.NewNullLogger()
+		handler := account.NewAccountHandler(logger, service, repository)
+		bypass, err := utils.ParseIPAllowList("10.0.0.0/8")
+		assert.NoError(t, err)
+		handler.SetSecurityBypass(bypass)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+
+		for range 3 {
+			body, _ := json.Marshal(account.LoginAccountRequest{Email: "test@example.com", Password: "wrong"})
+			req := httptest.NewRequest("POST", "/account/login", bytes.NewReader(body))
+			req.RemoteAddr = "10.1.2.3:40000"
+
+			w := httptest.NewRecorder()
+			httpHelper.router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+
+		assert.Zero(t, acc.FailedLoginCount)
+		assert.Nil(t, acc.LockedUntil)
+		repository.AssertNotCalled(t, "UpdateAccount", mock.Anything, mock.Anything)
+
+		assert.Len(t, hook.AllEntries(), 3)
+		assert.Equal(t, "10.1.2.3", hook.LastEntry().Data["ip"])
+	})
+}
+
+func TestAccountHandler_UnlockAccount(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should clear the lockout of an account", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+
+		lockedUntil := time.Now().Add(time.Hour)
+		acc := &domain.Account{ID: 1, Email: "test@example.com", FailedLoginCount: 3, LockedUntil: &lockedUntil}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		repository.On("UpdateAccount", anyContext, acc).Return(acc, nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityUnlock).Return(nil)
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/admin/account/unlock", handler.UnlockAccount)
+
+		w := httpHelper.MakeRequest("POST", "/admin/account/unlock", account.UnlockAccountRequest{Email: "test@example.com"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, acc.LockedUntil)
+		assert.Equal(t, 0, acc.FailedLoginCount)
+	})
+
+	t.Run("should return not found for an unknown email", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "unknown@example.com").Return(nil, gorm.ErrRecordNotFound)
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/admin/account/unlock", handler.UnlockAccount)
+
+		w := httpHelper.MakeRequest("POST", "/admin/account/unlock", account.UnlockAccountRequest{Email: "unknown@example.com"}, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAccountHandler_ChangeEmail(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	type fixture struct {
+		db          *gorm.DB
+		acc         *domain.Account
+		httpHelper  *HTTPTestHelper
+		worker      *account.PendingActionWorker
+		token       string
+		cancelToken string
+	}
+
+	setup := func(t *testing.T) *fixture {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		t.Cleanup(viper.Reset)
+
+		f := &fixture{db: newTestDB(t)}
+		repository := account.NewAccountRepository(f.db)
+
+		emailService := mailer.NewMockEmailService(t)
+		emailService.On("SendTemplate", mock.Anything, "new@example.com", mailer.TemplateChangeEmail, mock.AnythingOfType("mailer.ChangeEmailData")).
+			Run(func(args mock.Arguments) {
+				link := args.Get(3).(mailer.ChangeEmailData).Link
+				f.token = link[strings.Index(link, "token=")+len("token="):]
+			}).
+			Return(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()}, nil).
+			Maybe()
+		// the cancel link goes to the current email
+		emailService.On("SendTemplate", mock.Anything, "old@example.com", mailer.TemplatePendingAction, mock.AnythingOfType("mailer.PendingActionData")).
+			Run(func(args mock.Arguments) {
+				link := args.Get(3).(mailer.PendingActionData).CancelLink
+				f.cancelToken = link[strings.Index(link, "token=")+len("token="):]
+			}).
+			Return(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()}, nil).
+			Maybe()
+
+		service := account.NewAccountService(emailService, nil)
+		hashedPassword, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+		f.acc, err = repository.CreateAccount(context.Background(), &domain.Account{Email: "old@example.com", Password: hashedPassword})
+		assert.NoError(t, err)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		f.worker = account.NewPendingActionWorker(logrus.New(), repository)
+
+		f.httpHelper = NewHTTPTestHelper()
+		f.httpHelper.router.POST("/account/change-email", func(c *gin.Context) {
+			c.Set(utils.AccountIdContextKey, f.acc.ID)
+		}, handler.ChangeEmail)
+		f.httpHelper.SetupHandler("POST", "/account/change-email/confirm", handler.ConfirmEmailChange)
+		f.httpHelper.SetupHandler("POST", "/account/pending-action/cancel", handler.CancelPendingAction)
+
+		return f
+	}
+
+	storedEmail := func(t *testing.T, db *gorm.DB, id uint) string {
+		var stored domain.Account
+		assert.NoError(t, db.First(&stored, id).Error)
+		return stored.Email
+	}
+
+	t.Run("should change the email once confirmed and the grace period has passed", func(t *testing.T) {
+		f := setup(t)
+
+		w := f.httpHelper.MakeRequest("POST", "/account/change-email", account.ChangeEmailRequest{NewEmail: "new@example.com", Password: "password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, f.token)
+
+		// pending until the link is confirmed
+		assert.Equal(t, "old@example.com", storedEmail(t, f.db, f.acc.ID))
+
+		var emails int64
+		assert.NoError(t, f.db.Model(&domain.EmailLog{}).Where("recipient = ?", "new@example.com").Count(&emails).Error)
+		assert.Equal(t, int64(1), emails)
+
+		countUpdates := func() int64 {
+			var updates int64
+			assert.NoError(t, f.db.Model(&domain.AccountActivity{}).Where("account_id = ? AND activity = ?", f.acc.ID, domain.ActivityUpdate).Count(&updates).Error)
+			return updates
+		}
+		// logged when requested
+		assert.Equal(t, int64(1), countUpdates())
+
+		w = f.httpHelper.MakeRequest("POST", "/account/change-email/confirm", account.ConfirmEmailChangeRequest{Token: f.token}, nil)
+		var response account.ConfirmEmailChangeResponse
+		f.httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.NotEmpty(t, f.cancelToken)
+
+		// deferred for the grace period
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), time.Now()))
+		assert.Equal(t, "old@example.com", storedEmail(t, f.db, f.acc.ID))
+
+		// confirming again does not schedule a second change
+		w = f.httpHelper.MakeRequest("POST", "/account/change-email/confirm", account.ConfirmEmailChangeRequest{Token: f.token}, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "account.pending_action_exists")
+
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter))
+		assert.Equal(t, "new@example.com", storedEmail(t, f.db, f.acc.ID))
+
+		var stored domain.Account
+		assert.NoError(t, f.db.First(&stored, f.acc.ID).Error)
+		assert.True(t, stored.EmailVerified)
+		assert.NotNil(t, stored.VerifiedAt)
+
+		// and again when applied
+		assert.Equal(t, int64(2), countUpdates())
+
+		// the link works once
+		w = f.httpHelper.MakeRequest("POST", "/account/change-email/confirm", account.ConfirmEmailChangeRequest{Token: f.token}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "account.verification_token_invalid")
+	})
+
+	t.Run("should not change the email when cancelled within the grace period", func(t *testing.T) {
+		f := setup(t)
+
+		w := f.httpHelper.MakeRequest("POST", "/account/change-email", account.ChangeEmailRequest{NewEmail: "new@example.com", Password: "password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = f.httpHelper.MakeRequest("POST", "/account/change-email/confirm", account.ConfirmEmailChangeRequest{Token: f.token}, nil)
+		var response account.ConfirmEmailChangeResponse
+		f.httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		w = f.httpHelper.MakeRequest("POST", "/account/pending-action/cancel", account.CancelPendingActionRequest{Token: f.cancelToken}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter.Add(time.Minute)))
+		assert.Equal(t, "old@example.com", storedEmail(t, f.db, f.acc.ID))
+	})
+
+	t.Run("should not change the email when it was registered during the grace period", func(t *testing.T) {
+		f := setup(t)
+
+		w := f.httpHelper.MakeRequest("POST", "/account/change-email", account.ChangeEmailRequest{NewEmail: "new@example.com", Password: "password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = f.httpHelper.MakeRequest("POST", "/account/change-email/confirm", account.ConfirmEmailChangeRequest{Token: f.token}, nil)
+		var response account.ConfirmEmailChangeResponse
+		f.httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		assert.NoError(t, f.db.Create(&domain.Account{Email: "new@example.com", Password: "hashed"}).Error)
+
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter))
+		assert.Equal(t, "old@example.com", storedEmail(t, f.db, f.acc.ID))
+
+		// the change is dropped rather than retried
+		var pending domain.PendingAccountAction
+		assert.NoError(t, f.db.Where("account_id = ?", f.acc.ID).First(&pending).Error)
+		assert.NotNil(t, pending.CancelledAt)
+		assert.Nil(t, pending.AppliedAt)
+	})
+
+	t.Run("should reject an email that is taken", func(t *testing.T) {
+		f := setup(t)
+		assert.NoError(t, f.db.Create(&domain.Account{Email: "new@example.com", Password: "hashed"}).Error)
+
+		w := f.httpHelper.MakeRequest("POST", "/account/change-email", account.ChangeEmailRequest{NewEmail: "new@example.com", Password: "password"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "account.already_exists")
+		assert.Empty(t, f.token)
+		assert.Equal(t, "old@example.com", storedEmail(t, f.db, f.acc.ID))
+	})
+
+	t.Run("should reject a pending change when the email was registered since", func(t *testing.T) {
+		f := setup(t)
+
+		w := f.httpHelper.MakeRequest("POST", "/account/change-email", account.ChangeEmailRequest{NewEmail: "new@example.com", Password: "password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.NoError(t, f.db.Create(&domain.Account{Email: "new@example.com", Password: "hashed"}).Error)
+
+		w = f.httpHelper.MakeRequest("POST", "/account/change-email/confirm", account.ConfirmEmailChangeRequest{Token: f.token}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "account.already_exists")
+		assert.Empty(t, f.cancelToken)
+		assert.Equal(t, "old@example.com", storedEmail(t, f.db, f.acc.ID))
+	})
+
+	t.Run("should require the current password and a different email", func(t *testing.T) {
+		f := setup(t)
+
+		w := f.httpHelper.MakeRequest("POST", "/account/change-email", account.ChangeEmailRequest{NewEmail: "new@example.com", Password: "wrong"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "auth.invalid_credentials")
+
+		w = f.httpHelper.MakeRequest("POST", "/account/change-email", account.ChangeEmailRequest{NewEmail: "old@example.com", Password: "password"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "account.email_unchanged")
+
+		w = f.httpHelper.MakeRequest("POST", "/account/change-email", account.ChangeEmailRequest{NewEmail: "not-an-email", Password: "password"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAccountHandler_DeleteAccount(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	type fixture struct {
+		db           *gorm.DB
+		acc          *domain.Account
+		emailService *mailer.MockEmailService
+		httpHelper   *HTTPTestHelper
+		worker       *account.PendingActionWorker
+		cancelToken  string
+	}
+
+	setup := func(t *testing.T, sendErr error) *fixture {
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		t.Cleanup(viper.Reset)
+
+		f := &fixture{db: newTestDB(t)}
+		repository := account.NewAccountRepository(f.db)
+
+		f.emailService = mailer.NewMockEmailService(t)
+		f.emailService.On("SendTemplate", mock.Anything, "test@example.com", mailer.TemplatePendingAction, mock.AnythingOfType("mailer.PendingActionData")).
+			Run(func(args mock.Arguments) {
+				link := args.Get(3).(mailer.PendingActionData).CancelLink
+				f.cancelToken = link[strings.Index(link, "token=")+len("token="):]
+			}).
+			Return(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()}, sendErr).
+			Maybe()
+
+		service := account.NewAccountService(f.emailService, nil)
+		hashedPassword, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+		f.acc, err = repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com", Password: hashedPassword})
+		assert.NoError(t, err)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		f.worker = account.NewPendingActionWorker(logrus.New(), repository)
+
+		f.httpHelper = NewHTTPTestHelper()
+		f.httpHelper.router.DELETE("/account", func(c *gin.Context) {
+			c.Set(utils.AccountIdContextKey, f.acc.ID)
+		}, handler.DeleteAccount)
+		f.httpHelper.SetupHandler("GET", "/account/pending-action", handler.GetPendingAction)
+		f.httpHelper.SetupHandler("POST", "/account/pending-action/cancel", handler.CancelPendingAction)
+		f.httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+
+		return f
+	}
+
+	scheduleDeletion := func(t *testing.T, f *fixture) account.DeleteAccountResponse {
+		w := f.httpHelper.MakeRequest("DELETE", "/account", account.DeleteAccountRequest{Password: "password"}, nil)
+		var response account.DeleteAccountResponse
+		f.httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.NotEmpty(t, f.cancelToken)
+		return response
+	}
+
+	accountExists := func(t *testing.T, db *gorm.DB, id uint) bool {
+		var count int64
+		assert.NoError(t, db.Model(&domain.Account{}).Where("id = ?", id).Count(&count).Error)
+		return count == 1
+	}
+
+	t.Run("should delete the account once the grace period has passed", func(t *testing.T) {
+		f := setup(t, nil)
+
+		response := scheduleDeletion(t, f)
+
+		// deferred for the grace period
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), time.Now()))
+		assert.True(t, accountExists(t, f.db, f.acc.ID))
+
+		// deleting again does not schedule a second deletion
+		w := f.httpHelper.MakeRequest("DELETE", "/account", account.DeleteAccountRequest{Password: "password"}, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "account.pending_action_exists")
+
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter))
+		assert.False(t, accountExists(t, f.db, f.acc.ID))
+
+		var activities int64
+		assert.NoError(t, f.db.Model(&domain.AccountActivity{}).Where("account_id = ? AND activity = ?", f.acc.ID, domain.ActivityDelete).Count(&activities).Error)
+		assert.Equal(t, int64(1), activities)
+	})
+
+	t.Run("should allow the email to be registered again once the account is deleted", func(t *testing.T) {
+		f := setup(t, nil)
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		f.emailService.On("SendTemplate", mock.Anything, "test@example.com", mailer.TemplateVerifyEmail, mock.AnythingOfType("mailer.VerifyEmailData")).
+			Return(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()}, nil)
+
+		response := scheduleDeletion(t, f)
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter))
+		assert.False(t, accountExists(t, f.db, f.acc.ID))
+
+		w := f.httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{Email: "test@example.com", Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var registered account.RegisterAccountResponse
+		f.httpHelper.AssertJSONResponse(t, w, &registered)
+		assert.NotEqual(t, f.acc.ID, registered.ID)
+
+		var deleted domain.Account
+		assert.NoError(t, f.db.Unscoped().First(&deleted, f.acc.ID).Error)
+		assert.NotEqual(t, "test@example.com", deleted.Email)
+	})
+
+	t.Run("should delete the owned organization with the account once the grace period has passed", func(t *testing.T) {
+		f := setup(t, nil)
+		other := domain.Account{Email: "other@example.com"}
+		assert.NoError(t, f.db.Create(&other).Error)
+		owned := domain.Organization{OwnerID: f.acc.ID, Name: "owned", ClientSecret: "encrypted"}
+		kept := domain.Organization{OwnerID: other.ID, Name: "other", ClientSecret: "encrypted"}
+		assert.NoError(t, f.db.Create(&owned).Error)
+		assert.NoError(t, f.db.Create(&kept).Error)
+
+		response := scheduleDeletion(t, f)
+
+		// the organization stays with the account during the grace period
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), time.Now()))
+		var stored domain.Organization
+		assert.NoError(t, f.db.First(&stored, owned.ID).Error)
+		assert.Equal(t, "encrypted", stored.ClientSecret)
+
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter))
+		assert.False(t, accountExists(t, f.db, f.acc.ID))
+
+		var deleted domain.Organization
+		assert.NoError(t, f.db.Unscoped().First(&deleted, owned.ID).Error)
+		assert.True(t, deleted.DeletedAt.Valid)
+		assert.Empty(t, deleted.ClientSecret)
+
+		var remaining domain.Organization
+		assert.NoError(t, f.db.First(&remaining, kept.ID).Error)
+		assert.Equal(t, "encrypted", remaining.ClientSecret)
+	})
+
+	t.Run("should anonymize the account and delete the owned organization when configured", func(t *testing.T) {
+		f := setup(t, nil)
+		owned := domain.Organization{OwnerID: f.acc.ID, Name: "owned", ClientSecret: "encrypted"}
+		assert.NoError(t, f.db.Create(&owned).Error)
+		viper.Set("ACCOUNT_DELETION_MODE", account.DeletionModeAnonymize)
+
+		response := scheduleDeletion(t, f)
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter))
+
+		var stored domain.Account
+		assert.NoError(t, f.db.First(&stored, f.acc.ID).Error)
+		assert.NotNil(t, stored.AnonymizedAt)
+		assert.NotEqual(t, "test@example.com", stored.Email)
+
+		var organization domain.Organization
+		assert.NoError(t, f.db.Unscoped().First(&organization, owned.ID).Error)
+		assert.True(t, organization.DeletedAt.Valid)
+	})
+
+	t.Run("should not delete the account when cancelled within the grace period", func(t *testing.T) {
+		f := setup(t, nil)
+
+		response := scheduleDeletion(t, f)
+
+		w := f.httpHelper.MakeRequest("POST", "/account/pending-action/cancel", account.CancelPendingActionRequest{Token: f.cancelToken}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter.Add(time.Minute)))
+		assert.True(t, accountExists(t, f.db, f.acc.ID))
+	})
+
+	t.Run("should still delete the account when only the cancel page was opened", func(t *testing.T) {
+		f := setup(t, nil)
+
+		response := scheduleDeletion(t, f)
+
+		w := f.httpHelper.MakeRequest("GET", "/account/pending-action?token="+f.cancelToken, nil, nil)
+		var pendingAction account.PendingActionResponse
+		f.httpHelper.AssertJSONResponse(t, w, &pendingAction)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, domain.PendingActionDelete, pendingAction.Action)
+		assert.True(t, pendingAction.Cancellable)
+
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), response.ExecuteAfter.Add(time.Minute)))
+		assert.False(t, accountExists(t, f.db, f.acc.ID))
+	})
+
+	t.Run("should withdraw the deletion when the cancel email cannot be sent", func(t *testing.T) {
+		f := setup(t, errors.New("smtp unavailable"))
+
+		w := f.httpHelper.MakeRequest("DELETE", "/account", account.DeleteAccountRequest{Password: "password"}, nil)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		var open int64
+		assert.NoError(t, f.db.Model(&domain.PendingAccountAction{}).Where("cancelled_at IS NULL").Count(&open).Error)
+		assert.Equal(t, int64(0), open)
+
+		// the request can be retried once the mailer is back
+		w = f.httpHelper.MakeRequest("DELETE", "/account", account.DeleteAccountRequest{Password: "password"}, nil)
+		assert.NotEqual(t, http.StatusConflict, w.Code)
+
+		assert.NoError(t, f.worker.ProcessDue(context.Background(), time.Now().Add(48*time.Hour)))
+		assert.True(t, accountExists(t, f.db, f.acc.ID))
+	})
+
+	t.Run("should not schedule the deletion with a wrong password", func(t *testing.T) {
+		f := setup(t, nil)
+
+		w := f.httpHelper.MakeRequest("DELETE", "/account", account.DeleteAccountRequest{Password: "wrong"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), domain.ErrInvalidCredentials.Error())
+		assert.Empty(t, f.cancelToken)
+
+		var pending int64
+		assert.NoError(t, f.db.Model(&domain.PendingAccountAction{}).Count(&pending).Error)
+		assert.Equal(t, int64(0), pending)
+	})
+
+	t.Run("should require the password", func(t *testing.T) {
+		f := setup(t, nil)
+
+		w := f.httpHelper.MakeRequest("DELETE", "/account", account.DeleteAccountRequest{}, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.True(t, accountExists(t, f.db, f.acc.ID))
+	})
+}
+
+func TestAccountHandler_APIKeys(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+	handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+
+	setup := func(accountID uint) *HTTPTestHelper {
+		httpHelper := NewHTTPTestHelper()
+		group := httpHelper.router.Group("/", func(c *gin.Context) {
+			c.Set(utils.AccountIdContextKey, accountID)
+		})
+		group.POST("/account/api-keys", handler.CreateAPIKey)
+		group.GET("/account/api-keys", handler.ListAPIKeys)
+		group.DELETE("/account/api-keys/:id", handler.RevokeAPIKey)
+		return httpHelper
+	}
+	httpHelper := setup(1)
+
+	var created account.CreateAPIKeyResponse
+	w := httpHelper.MakeRequest("POST", "/account/api-keys", account.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"sites.read", " "}}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	httpHelper.AssertJSONResponse(t, w, &created)
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+	assert.Equal(t, []string{"sites.read"}, created.Scopes)
+
+	t.Run("should list keys without the key itself", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/account/api-keys", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), created.Key)
+		assert.NotContains(t, w.Body.String(), utils.HashToken(created.Key))
+
+		var response account.ListAPIKeysResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Len(t, response.APIKeys, 1)
+		assert.Equal(t, created.ID, response.APIKeys[0].ID)
+		assert.Equal(t, "ci", response.APIKeys[0].Name)
+		assert.Equal(t, created.Prefix, response.APIKeys[0].Prefix)
+		assert.Equal(t, []string{"sites.read"}, response.APIKeys[0].Scopes)
+		assert.Nil(t, response.APIKeys[0].LastUsedAt)
+	})
+
+	t.Run("should require a name", func(t *testing.T) {
+		w := httpHelper.MakeRequest("POST", "/account/api-keys", account.CreateAPIKeyRequest{Name: " "}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should not revoke a key of another account", func(t *testing.T) {
+		w := setup(2).MakeRequest("DELETE", "/account/api-keys/"+strconv.FormatUint(uint64(created.ID), 10), nil, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should revoke a key", func(t *testing.T) {
+		path := "/account/api-keys/" + strconv.FormatUint(uint64(created.ID), 10)
+
+		w := httpHelper.MakeRequest("DELETE", path, nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httpHelper.MakeRequest("GET", "/account/api-keys", nil, nil)
+		assert.JSONEq(t, `{"api_keys":[]}`, w.Body.String())
+
+		w = httpHelper.MakeRequest("DELETE", path, nil, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAccountHandler_GetActivityHistory(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	setup := func(handler *account.AccountHandler) *HTTPTestHelper {
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.router.GET("/account/activity", func(c *gin.Context) {
+			c.Set(utils.AccountIdContextKey, uint(1))
+		}, handler.GetActivityHistory)
+		return httpHelper
+	}
+
+	t.Run("should return a page of activity with the total", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+
+		since := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+		repository.On("GetActivityHistory", anyContext, uint(1), mock.MatchedBy(since.Equal), 2, 4).Return([]domain.AccountActivity{
+			{ID: 5, Activity: domain.ActivityLogin},
+		}, int64(5), nil)
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+
+		w := setup(handler).MakeRequest("GET", "/account/activity?limit=2&offset=4&since=2025-01-01T00:00:00Z", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response account.GetActivityHistoryResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(5), response.Total)
+		assert.Equal(t, 2, response.Limit)
+		assert.Equal(t, 4, response.Offset)
+		assert.Len(t, response.Activities, 1)
+		assert.Equal(t, domain.ActivityLogin, response.Activities[0].Activity)
+	})
+
+	t.Run("should default and cap the page size", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetActivityHistory", anyContext, uint(1), time.Time{}, 20, 0).Return([]domain.AccountActivity{}, int64(0), nil).Once()
+		repository.On("GetActivityHistory", anyContext, uint(1), time.Time{}, 100, 0).Return([]domain.AccountActivity{}, int64(0), nil).Once()
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		httpHelper := setup(handler)
+
+		w := httpHelper.MakeRequest("GET", "/account/activity", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"activities":[],"total":0,"limit":20,"offset":0}`, w.Body.String())
+
+		w = httpHelper.MakeRequest("GET", "/account/activity?limit=1000", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should reject a negative offset or malformed since", func(t *testing.T) {
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), domain.NewMockAccountRepository(t))
+		httpHelper := setup(handler)
+
+		w := httpHelper.MakeRequest("GET", "/account/activity?offset=-1", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = httpHelper.MakeRequest("GET", "/account/activity?since=yesterday", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAccountHandler_ListAccounts(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db := newTestDB(t)
+	base := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, email := range []string{"carol@example.com", "alice@example.com", "bob@example.com"} {
+		assert.NoError(t, db.Create(&domain.Account{
+			Email:     email,
+			Password:  "hashed",
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}).Error)
+	}
+
+	handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), account.NewAccountRepository(db))
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.SetupHandler("GET", "/admin/accounts", handler.ListAccounts)
+
+	emails := func(response account.ListAccountsResponse) []string {
+		result := make([]string, 0, len(response.Accounts))
+		for _, acc := range response.Accounts {
+			result = append(result, acc.Email)
+		}
+		return result
+	}
+
+	t.Run("should sort by newest first by default", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/admin/accounts", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "hashed")
+
+		var response account.ListAccountsResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, []string{"bob@example.com", "alice@example.com", "carol@example.com"}, emails(response))
+		assert.Equal(t, int64(3), response.Total)
+		assert.Equal(t, "created_at", response.Sort)
+		assert.Equal(t, utils.SortDesc, response.Order)
+		assert.Equal(t, 20, response.Limit)
+	})
+
+	t.Run("should sort and page by a requested field", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/admin/accounts?sort=email&order=asc&limit=2&offset=1", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response account.ListAccountsResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, []string{"bob@example.com", "carol@example.com"}, emails(response))
+		assert.Equal(t, int64(3), response.Total)
+		assert.Equal(t, "email", response.Sort)
+		assert.Equal(t, utils.SortAsc, response.Order)
+	})
+
+	t.Run("should reject a sort field that is not supported", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/admin/accounts?sort=password", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "request.invalid_sort")
+
+		w = httpHelper.MakeRequest("GET", "/admin/accounts?order=random", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "request.invalid_sort_order")
+	})
+}
+
+func TestAccountHandler_SetAccountRole(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+
+	acc, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com", Password: "hashed"})
+	assert.NoError(t, err)
+
+	handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.SetupHandler("PUT", "/admin/accounts/:id/role", handler.SetAccountRole)
+
+	path := "/admin/accounts/" + strconv.FormatUint(uint64(acc.ID), 10) + "/role"
+
+	t.Run("should change the role and log it", func(t *testing.T) {
+		w := httpHelper.MakeRequest("PUT", path, account.SetAccountRoleRequest{Role: domain.RoleAdmin}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response account.AdminAccountResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, acc.ID, response.ID)
+		assert.Equal(t, domain.RoleAdmin, response.Role)
+
+		stored, err := repository.GetAccountByID(context.Background(), acc.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RoleAdmin, stored.Role)
+
+		var activities int64
+		assert.NoError(t, db.Model(&domain.AccountActivity{}).Where("activity = ?", domain.ActivityRoleChange).Count(&activities).Error)
+		assert.Equal(t, int64(1), activities)
+	})
+
+	t.Run("should reject an unknown role", func(t *testing.T) {
+		w := httpHelper.MakeRequest("PUT", path, account.SetAccountRoleRequest{Role: "owner"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "account.invalid_role")
+	})
+
+	t.Run("should report an unknown account", func(t *testing.T) {
+		w := httpHelper.MakeRequest("PUT", "/admin/accounts/999/role", account.SetAccountRoleRequest{Role: domain.RoleUser}, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httpHelper.MakeRequest("PUT", "/admin/accounts/abc/role", account.SetAccountRoleRequest{Role: domain.RoleUser}, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAccountHandler_RefreshToken(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should return a new access token for a valid refresh token", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com"}
+		service.On("ValidateRefreshToken", anyContext, "refresh_token").Return(uint(1), nil)
+		repository.On("GetRefreshTokenByHash", anyContext, utils.HashToken("refresh_token")).Return(&domain.RefreshToken{ID: 3, AccountID: 1}, nil)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(acc, nil)
+		repository.On("TouchRefreshToken", anyContext, uint(3), mock.AnythingOfType("time.Time")).Return(nil)
+		service.On("GenerateAuthToken", anyContext, acc).Return("auth_token", nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/refresh", handler.RefreshToken)
+
+		w := httpHelper.MakeRequest("POST", "/account/refresh", account.RefreshTokenRequest{RefreshToken: "refresh_token"}, nil)
+
+		var response account.RefreshTokenResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "auth_token", response.Token)
+	})
+
+	t.Run("should not refresh for a disabled or anonymized account", func(t *testing.T) {
+		disabledAt := time.Now()
+		for _, tc := range []struct {
+			acc    *domain.Account
+			status int
+			code   string
+		}{
+			{&domain.Account{ID: 1, Email: "test@example.com", DisabledAt: &disabledAt}, http.StatusForbidden, "account.disabled"},
+			{&domain.Account{ID: 1, Email: "anonymized@example.invalid", DisabledAt: &disabledAt, AnonymizedAt: &disabledAt}, http.StatusUnauthorized, "auth.invalid_refresh_token"},
+		} {
+			service := domain.NewMockAccountService(t)
+			repository := domain.NewMockAccountRepository(t)
+
+			service.On("ValidateRefreshToken", anyContext, "refresh_token").Return(uint(1), nil)
+			repository.On("GetRefreshTokenByHash", anyContext, utils.HashToken("refresh_token")).Return(&domain.RefreshToken{ID: 3, AccountID: 1}, nil)
+			repository.On("GetAccountByID", anyContext, uint(1)).Return(tc.acc, nil)
+
+			handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+			httpHelper := NewHTTPTestHelper()
+			httpHelper.SetupHandler("POST", "/account/refresh", handler.RefreshToken)
+
+			w := httpHelper.MakeRequest("POST", "/account/refresh", account.RefreshTokenRequest{RefreshToken: "refresh_token"}, nil)
+
+			assert.Equal(t, tc.status, w.Code)
+			assert.Contains(t, w.Body.String(), tc.code)
+			service.AssertNotCalled(t, "GenerateAuthToken", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("should reject an invalid or wrong type token", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		service.On("ValidateRefreshToken", anyContext, "access_token").Return(uint(0), domain.ErrInvalidTokenType)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/refresh", handler.RefreshToken)
+
+		w := httpHelper.MakeRequest("POST", "/account/refresh", account.RefreshTokenRequest{RefreshToken: "access_token"}, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject a revoked refresh token", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		revokedAt := time.Now()
+		service.On("ValidateRefreshToken", anyContext, "refresh_token").Return(uint(1), nil)
+		repository.On("GetRefreshTokenByHash", anyContext, utils.HashToken("refresh_token")).Return(&domain.RefreshToken{AccountID: 1, RevokedAt: &revokedAt}, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/refresh", handler.RefreshToken)
+
+		w := httpHelper.MakeRequest("POST", "/account/refresh", account.RefreshTokenRequest{RefreshToken: "refresh_token"}, nil)
+
+		var response map[string]string
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, domain.ErrRefreshTokenRevoked.Error(), response["error"])
+		assert.Equal(t, "auth.refresh_token_revoked", response["code"])
+	})
+
+	t.Run("should reject a refresh token that was never issued", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		service.On("ValidateRefreshToken", anyContext, "refresh_token").Return(uint(1), nil)
+		repository.On("GetRefreshTokenByHash", anyContext, utils.HashToken("refresh_token")).Return(nil, gorm.ErrRecordNotFound)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/refresh", handler.RefreshToken)
+
+		w := httpHelper.MakeRequest("POST", "/account/refresh", account.RefreshTokenRequest{RefreshToken: "refresh_token"}, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAccountHandler_Logout(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	setup := func(t *testing.T) (*HTTPTestHelper, string, []string) {
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+		acc, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com"})
+		assert.NoError(t, err)
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		// one refresh token per logged in device
+		var refreshTokens []string
+		for range 2 {
+			refreshToken, _, err := service.GenerateRefreshToken(context.Background(), acc, false)
+			assert.NoError(t, err)
+			_, err = repository.CreateRefreshToken(context.Background(), &domain.RefreshToken{
+				AccountID: acc.ID,
+				TokenHash: utils.HashToken(refreshToken),
+				ExpiresAt: time.Now().Add(time.Hour),
+			})
+			assert.NoError(t, err)
+			refreshTokens = append(refreshTokens, refreshToken)
+		}
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/refresh", handler.RefreshToken)
+		authorized := httpHelper.router.Group("/", account.AuthMiddleware(service, repository))
+		authorized.POST("/account/logout", handler.LogoutAccount)
+		authorized.POST("/account/logout/all", handler.LogoutAllSessions)
+
+		return httpHelper, token, refreshTokens
+	}
+
+	refresh := func(httpHelper *HTTPTestHelper, refreshToken string) int {
+		w := httpHelper.MakeRequest("POST", "/account/refresh", account.RefreshTokenRequest{RefreshToken: refreshToken}, nil)
+		return w.Code
+	}
+
+	t.Run("should end only the session of the given refresh token", func(t *testing.T) {
+		httpHelper, token, refreshTokens := setup(t)
+
+		w := httpHelper.MakeAuthenticatedRequest("POST", "/account/logout", account.LogoutRequest{RefreshToken: refreshTokens[0]}, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, refresh(httpHelper, refreshTokens[0]))
+		assert.Equal(t, http.StatusOK, refresh(httpHelper, refreshTokens[1]))
+	})
+
+	t.Run("should keep every refresh token without one in the request", func(t *testing.T) {
+		httpHelper, token, refreshTokens := setup(t)
+
+		w := httpHelper.MakeAuthenticatedRequest("POST", "/account/logout", nil, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// the auth token itself is revoked
+		w = httpHelper.MakeAuthenticatedRequest("POST", "/account/logout", nil, token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		assert.Equal(t, http.StatusOK, refresh(httpHelper, refreshTokens[0]))
+		assert.Equal(t, http.StatusOK, refresh(httpHelper, refreshTokens[1]))
+	})
+
+	t.Run("should end every session when logging out everywhere", func(t *testing.T) {
+		httpHelper, token, refreshTokens := setup(t)
+
+		w := httpHelper.MakeAuthenticatedRequest("POST", "/account/logout/all", nil, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, refresh(httpHelper, refreshTokens[0]))
+		assert.Equal(t, http.StatusUnauthorized, refresh(httpHelper, refreshTokens[1]))
+	})
+}
+
+func TestAccountHandler_ForgotPassword(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should log the message id of the reset email", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com"}
+		emailLog := &domain.EmailLog{
+			Recipient:  "test@example.com",
+			Template:   "password_reset",
+			MessageID:  "<message-id@developer.com>",
+			AcceptedAt: time.Now(),
+		}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		service.On("GeneratePasswordResetToken", anyContext, acc).Return("reset_token", nil)
+		repository.On("InvalidatePasswordResetTokens", anyContext, uint(1), mock.AnythingOfType("time.Time")).Return(nil)
+		repository.On("CreatePasswordResetToken", anyContext, mock.MatchedBy(func(token *domain.PasswordResetToken) bool {
+			return token.AccountID == 1 && token.TokenHash == utils.HashToken("reset_token")
+		})).Return(&domain.PasswordResetToken{ID: 1}, nil)
+		service.On("EnqueuePasswordResetEmail", anyContext, "test@example.com", "reset_token", mock.AnythingOfType("func(*domain.EmailLog)")).
+			Run(func(args mock.Arguments) {
+				args.Get(3).(func(*domain.EmailLog))(emailLog)
+			}).
+			Return(nil)
+		repository.On("LogEmail", anyContext, mock.MatchedBy(func(l *domain.EmailLog) bool {
+			return l.AccountID == 1 && l.MessageID == "<message-id@developer.com>"
+		})).Return(nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityForgotPassword).Return(nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/forgot-password", handler.ForgotPassword)
+
+		w := httpHelper.MakeRequest("POST", "/account/forgot-password", account.ForgotPasswordRequest{Email: "test@example.com"}, nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should respond the same for existing and unknown emails", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com"}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		repository.On("GetAccountByEmail", anyContext, "missing@example.com").Return(nil, gorm.ErrRecordNotFound)
+		service.On("GeneratePasswordResetToken", anyContext, acc).Return("reset_token", nil)
+		repository.On("InvalidatePasswordResetTokens", anyContext, uint(1), mock.AnythingOfType("time.Time")).Return(nil)
+		repository.On("CreatePasswordResetToken", anyContext, mock.AnythingOfType("*domain.PasswordResetToken")).Return(&domain.PasswordResetToken{ID: 1}, nil)
+		// only the existing account is sent an email
+		service.On("EnqueuePasswordResetEmail", anyContext, "test@example.com", "reset_token", mock.AnythingOfType("func(*domain.EmailLog)")).Return(nil).Once()
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityForgotPassword).Return(nil)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/forgot-password", handler.ForgotPassword)
+
+		existing := httpHelper.MakeRequest("POST", "/account/forgot-password", account.ForgotPasswordRequest{Email: "test@example.com"}, nil)
+		unknown := httpHelper.MakeRequest("POST", "/account/forgot-password", account.ForgotPasswordRequest{Email: "missing@example.com"}, nil)
+
+		assert.Equal(t, http.StatusOK, existing.Code)
+		assert.Equal(t, http.StatusOK, unknown.Code)
+		assert.Equal(t, existing.Body.String(), unknown.Body.String())
+
+		var response account.ForgotPasswordResponse
+		httpHelper.AssertJSONResponse(t, unknown, &response)
+		assert.NotEmpty(t, response.Message)
+	})
+
+	t.Run("should not reveal a failure to send the email", func(t *testing.T) {
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com"}
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(acc, nil)
+		service.On("GeneratePasswordResetToken", anyContext, acc).Return("reset_token", nil)
+		repository.On("InvalidatePasswordResetTokens", anyContext, uint(1), mock.AnythingOfType("time.Time")).Return(nil)
+		repository.On("CreatePasswordResetToken", anyContext, mock.AnythingOfType("*domain.PasswordResetToken")).Return(&domain.PasswordResetToken{ID: 1}, nil)
+		service.On("EnqueuePasswordResetEmail", anyContext, "test@example.com", "reset_token", mock.AnythingOfType("func(*domain.EmailLog)")).Return(errors.New("queue full"))
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/forgot-password", handler.ForgotPassword)
+
+		w := httpHelper.MakeRequest("POST", "/account/forgot-password", account.ForgotPasswordRequest{Email: "test@example.com"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should fail when the account lookup fails", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("GetAccountByEmail", anyContext, "test@example.com").Return(nil, errors.New("connection refused"))
+
+		handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/forgot-password", handler.ForgotPassword)
+
+		w := httpHelper.MakeRequest("POST", "/account/forgot-password", account.ForgotPasswordRequest{Email: "test@example.com"}, nil)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("should reject an earlier reset token after a re-request", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		defer viper.Reset()
+
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		var links []string
+		emailService := mailer.NewMockEmailService(t)
+		emailService.On("EnqueueEmail", mock.Anything, mock.AnythingOfType("mailer.EmailJob")).
+			Run(func(args mock.Arguments) {
+				job := args.Get(1).(mailer.EmailJob)
+				link := job.Data.(mailer.PasswordResetData).Link
+				start := strings.Index(link, "token=") + len("token=")
+				links = append(links, link[start:])
+				job.OnSent(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()})
+			}).
+			Return(nil)
+
+		service := account.NewAccountService(emailService, nil)
+		_, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com"})
+		assert.NoError(t, err)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/forgot-password", handler.ForgotPassword)
+		httpHelper.SetupHandler("POST", "/account/reset-password", handler.ResetPassword)
+
+		for range 2 {
+			w := httpHelper.MakeRequest("POST", "/account/forgot-password", account.ForgotPasswordRequest{Email: "test@example.com"}, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		assert.Len(t, links, 2)
+
+		w := httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: links[0], Password: "new_password"}, nil)
+
+		var response map[string]string
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, domain.ErrResetTokenInvalid.Error(), response["error"])
+		assert.Equal(t, "auth.reset_token_invalid", response["code"])
+
+		w = httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: links[1], Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should reject a reset token that was already used", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		defer viper.Reset()
+
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		var links []string
+		emailService := mailer.NewMockEmailService(t)
+		emailService.On("EnqueueEmail", mock.Anything, mock.AnythingOfType("mailer.EmailJob")).
+			Run(func(args mock.Arguments) {
+				job := args.Get(1).(mailer.EmailJob)
+				link := job.Data.(mailer.PasswordResetData).Link
+				start := strings.Index(link, "token=") + len("token=")
+				links = append(links, link[start:])
+				job.OnSent(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()})
+			}).
+			Return(nil)
+
+		service := account.NewAccountService(emailService, nil)
+		_, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com"})
+		assert.NoError(t, err)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/forgot-password", handler.ForgotPassword)
+		httpHelper.SetupHandler("POST", "/account/reset-password", handler.ResetPassword)
+
+		w := httpHelper.MakeRequest("POST", "/account/forgot-password", account.ForgotPasswordRequest{Email: "test@example.com"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, links, 1)
+
+		w = httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: links[0], Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: links[0], Password: "another_password"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), domain.ErrResetTokenInvalid.Error())
+
+		var stored domain.PasswordResetToken
+		assert.NoError(t, db.First(&stored).Error)
+		assert.NotNil(t, stored.UsedAt)
+	})
+}
+
+func TestAccountHandler_OpaquePasswordReset(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should store only the hash of an opaque token and redeem it", func(t *testing.T) {
+		viper.Set("PASSWORD_RESET_TOKEN_FORMAT", "opaque")
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		defer viper.Reset()
+
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		var token string
+		emailService := mailer.NewMockEmailService(t)
+		emailService.On("EnqueueEmail", mock.Anything, mock.AnythingOfType("mailer.EmailJob")).
+			Run(func(args mock.Arguments) {
+				job := args.Get(1).(mailer.EmailJob)
+				link := job.Data.(mailer.PasswordResetData).Link
+				start := strings.Index(link, "token=") + len("token=")
+				token = link[start:]
+				job.OnSent(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()})
+			}).
+			Return(nil)
+
+		service := account.NewAccountService(emailService, nil)
+		_, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com"})
+		assert.NoError(t, err)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/forgot-password", handler.ForgotPassword)
+		httpHelper.SetupHandler("POST", "/account/reset-password", handler.ResetPassword)
+
+		w := httpHelper.MakeRequest("POST", "/account/forgot-password", account.ForgotPasswordRequest{Email: "test@example.com"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, account.IsOpaqueResetToken(token))
+
+		var stored []domain.PasswordResetToken
+		assert.NoError(t, db.Find(&stored).Error)
+		assert.Len(t, stored, 1)
+		assert.Equal(t, utils.HashToken(token), stored[0].TokenHash)
+		assert.NotEqual(t, token, stored[0].TokenHash)
+
+		var raw int64
+		assert.NoError(t, db.Model(&domain.PasswordResetToken{}).Where("token_hash = ?", token).Count(&raw).Error)
+		assert.Zero(t, raw)
+
+		w = httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: token, Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: "unknown", Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should keep the token usable when hashing is busy", func(t *testing.T) {
+		anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+		acc, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com", Password: "old_hash"})
+		assert.NoError(t, err)
+		assert.NoError(t, db.Create(&domain.PasswordResetToken{
+			AccountID: acc.ID,
+			TokenHash: utils.HashToken("reset-token"),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}).Error)
+
+		service := domain.NewMockAccountService(t)
+		service.On("HashPassword", anyContext, "new_password").Return("", domain.ErrPasswordHashingBusy).Once()
+		service.On("HashPassword", anyContext, "new_password").Return("new_hash", nil).Once()
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/reset-password", handler.ResetPassword)
+
+		w := httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: "reset-token", Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var stored domain.PasswordResetToken
+		assert.NoError(t, db.Where("token_hash = ?", utils.HashToken("reset-token")).First(&stored).Error)
+		assert.Nil(t, stored.UsedAt)
+		assert.Nil(t, stored.InvalidatedAt)
+
+		w = httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: "reset-token", Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var updated domain.Account
+		assert.NoError(t, db.First(&updated, acc.ID).Error)
+		assert.Equal(t, "new_hash", updated.Password)
+
+		w = httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: "reset-token", Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAccountHandler_ResetPassword_SignedToken(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+	acc, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com"})
+	assert.NoError(t, err)
+
+	// the stored token is still valid, only the signed claims reject it
+	store := func(t *testing.T, token string) {
+		assert.NoError(t, db.Create(&domain.PasswordResetToken{
+			AccountID: acc.ID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}).Error)
+	}
+
+	handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.SetupHandler("POST", "/account/reset-password", handler.ResetPassword)
+
+	t.Run("should report an expired reset token", func(t *testing.T) {
+		issuedAt := time.Now().Add(-24 * time.Hour)
+		past := account.NewAccountServiceWithClock(mailer.NewMockEmailService(t), nil, utils.ClockFunc(func() time.Time { return issuedAt }))
+		token, err := past.GeneratePasswordResetToken(context.Background(), acc)
+		assert.NoError(t, err)
+		store(t, token)
+
+		w := httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: token, Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "auth.token_expired")
+	})
+
+	t.Run("should reject a reset token signed with another key", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "another_secret_key_for_jwt_signing")
+		token, err := account.NewAccountService(mailer.NewMockEmailService(t), nil).GeneratePasswordResetToken(context.Background(), acc)
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		assert.NoError(t, err)
+		store(t, token)
+
+		w := httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: token, Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "auth.reset_token_invalid")
+	})
+}
+
+func TestAccountHandler_ValidateResetToken(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+	acc, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com"})
+	assert.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	for token, expiry := range map[string]time.Time{
+		"valid-token":   expiresAt,
+		"expired-token": time.Now().Add(-time.Minute),
+	} {
+		assert.NoError(t, db.Create(&domain.PasswordResetToken{
+			AccountID: acc.ID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: expiry,
+		}).Error)
+	}
+
+	handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.SetupHandler("GET", "/account/reset-password/validate", handler.ValidateResetToken)
+	httpHelper.SetupHandler("POST", "/account/reset-password", handler.ResetPassword)
+
+	validate := func(token string) account.ValidateResetTokenResponse {
+		w := httpHelper.MakeRequest("GET", "/account/reset-password/validate?token="+token, nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response account.ValidateResetTokenResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		return response
+	}
+
+	t.Run("should report a valid token without consuming it", func(t *testing.T) {
+		response := validate("valid-token")
+		assert.True(t, response.Valid)
+		assert.NotNil(t, response.ExpiresAt)
+		assert.True(t, expiresAt.Equal(*response.ExpiresAt))
+
+		assert.True(t, validate("valid-token").Valid)
+
+		var stored domain.PasswordResetToken
+		assert.NoError(t, db.Where("token_hash = ?", utils.HashToken("valid-token")).First(&stored).Error)
+		assert.Nil(t, stored.UsedAt)
+	})
+
+	t.Run("should report an expired token", func(t *testing.T) {
+		response := validate("expired-token")
+		assert.False(t, response.Valid)
+		assert.Nil(t, response.ExpiresAt)
+	})
+
+	t.Run("should report an unknown token", func(t *testing.T) {
+		assert.False(t, validate("unknown-token").Valid)
+	})
+
+	t.Run("should report a token that was already used", func(t *testing.T) {
+		w := httpHelper.MakeRequest("POST", "/account/reset-password", account.ResetPasswordRequest{Token: "valid-token", Password: "new_password"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.False(t, validate("valid-token").Valid)
+	})
+
+	t.Run("should require a token", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/account/reset-password/validate", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAccountHandler_CancelPendingAction(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should cancel a pending action before the grace period ends", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		pendingAction := &domain.PendingAccountAction{
+			ID:           1,
+			AccountID:    1,
+			Action:       domain.PendingActionDelete,
+			ExecuteAfter: time.Now().Add(time.Hour),
+		}
+		repository.On("GetPendingActionByCancelToken", anyContext, utils.HashToken("cancel_token")).Return(pendingAction, nil)
+		repository.On("UpdatePendingAction", anyContext, mock.MatchedBy(func(a *domain.PendingAccountAction) bool {
+			return a.CancelledAt != nil
+		})).Return(pendingAction, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/pending-action/cancel", handler.CancelPendingAction)
+
+		w := httpHelper.MakeRequest("POST", "/account/pending-action/cancel", account.CancelPendingActionRequest{Token: "cancel_token"}, nil)
+
+		var response account.CancelPendingActionResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "pending action cancelled", response.Message)
+	})
+
+	t.Run("should not cancel an action that was already applied", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		appliedAt := time.Now()
+		pendingAction := &domain.PendingAccountAction{
+			ID:        1,
+			AccountID: 1,
+			Action:    domain.PendingActionDelete,
+			AppliedAt: &appliedAt,
+		}
+		repository.On("GetPendingActionByCancelToken", anyContext, utils.HashToken("cancel_token")).Return(pendingAction, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/pending-action/cancel", handler.CancelPendingAction)
+
+		w := httpHelper.MakeRequest("POST", "/account/pending-action/cancel", account.CancelPendingActionRequest{Token: "cancel_token"}, nil)
+
+		var response map[string]string
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, domain.ErrPendingActionExpired.Error(), response["error"])
+		assert.Equal(t, "account.pending_action_expired", response["code"])
+	})
+
+	t.Run("should return not found for an unknown token", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		repository.On("GetPendingActionByCancelToken", anyContext, utils.HashToken("unknown")).Return(nil, gorm.ErrRecordNotFound)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/pending-action/cancel", handler.CancelPendingAction)
+
+		w := httpHelper.MakeRequest("POST", "/account/pending-action/cancel", account.CancelPendingActionRequest{Token: "unknown"}, nil)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAccountHandler_GetPendingAction(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should describe a pending action without cancelling it", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		executeAfter := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		pendingAction := &domain.PendingAccountAction{
+			ID:           1,
+			AccountID:    1,
+			Action:       domain.PendingActionDelete,
+			ExecuteAfter: executeAfter,
+		}
+		repository.On("GetPendingActionByCancelToken", anyContext, utils.HashToken("cancel_token")).Return(pendingAction, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("GET", "/account/pending-action", handler.GetPendingAction)
+
+		w := httpHelper.MakeRequest("GET", "/account/pending-action?token=cancel_token", nil, nil)
+
+		var response account.PendingActionResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, domain.PendingActionDelete, response.Action)
+		assert.True(t, executeAfter.Equal(response.ExecuteAfter))
+		assert.True(t, response.Cancellable)
+		repository.AssertNotCalled(t, "UpdatePendingAction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should report an applied action as not cancellable", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		appliedAt := time.Now()
+		pendingAction := &domain.PendingAccountAction{
+			ID:        1,
+			AccountID: 1,
+			Action:    domain.PendingActionDelete,
+			AppliedAt: &appliedAt,
+		}
+		repository.On("GetPendingActionByCancelToken", anyContext, utils.HashToken("cancel_token")).Return(pendingAction, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("GET", "/account/pending-action", handler.GetPendingAction)
+
+		w := httpHelper.MakeRequest("GET", "/account/pending-action?token=cancel_token", nil, nil)
+
+		var response account.PendingActionResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, response.Cancellable)
+	})
+
+	t.Run("should return not found for an unknown token", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		repository.On("GetPendingActionByCancelToken", anyContext, utils.HashToken("unknown")).Return(nil, gorm.ErrRecordNotFound)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("GET", "/account/pending-action", handler.GetPendingAction)
+
+		w := httpHelper.MakeRequest("GET", "/account/pending-action?token=unknown", nil, nil)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should require a token", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("GET", "/account/pending-action", handler.GetPendingAction)
+
+		w := httpHelper.MakeRequest("GET", "/account/pending-action", nil, nil)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAccountHandler_RotateSigningKey(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should report the new key and outstanding sessions", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		rotatedAt := time.Now()
+		service.On("RotateSigningKey", anyContext).Return(&domain.KeyRotation{
+			Kid:              "new_kid",
+			PreviousKid:      "old_kid",
+			RotatedAt:        rotatedAt,
+			GracePeriodEndAt: rotatedAt.Add(24 * time.Hour),
+		}, nil)
+		repository.On("CountActiveRefreshTokens", anyContext, rotatedAt).Return(int64(7), nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/admin/jwt/rotate", handler.RotateSigningKey)
+
+		w := httpHelper.MakeRequest("POST", "/admin/jwt/rotate", nil, nil)
+
+		var response account.RotateSigningKeyResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "new_kid", response.Kid)
+		assert.Equal(t, "old_kid", response.PreviousKid)
+		assert.Equal(t, int64(7), response.OutstandingSessions)
+	})
+
+	t.Run("should reject accounts without the admin role", func(t *testing.T) {
+		logger := logrus.New()
+		service := domain.NewMockAccountService(t)
+		repository := domain.NewMockAccountRepository(t)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.router.POST("/admin/jwt/rotate", func(c *gin.Context) {
+			c.Set(utils.AuthClaimsContextKey, &domain.AuthClaims{AccountID: 1, Role: domain.RoleUser})
+		}, account.RequireRole(domain.RoleAdmin), handler.RotateSigningKey)
+
+		w := httpHelper.MakeRequest("POST", "/admin/jwt/rotate", nil, nil)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestAccountHandler_GetProfileCacheControl(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	repository := domain.NewMockAccountRepository(t)
+	repository.On("GetAccountByID", anyContext, uint(1)).Return(&domain.Account{ID: 1, Email: "test@example.com"}, nil)
+
+	handler := account.NewAccountHandler(logrus.New(), domain.NewMockAccountService(t), repository)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.router.GET("/account/profile", func(c *gin.Context) {
+		c.Set(utils.AccountIdContextKey, uint(1))
+	}, handler.GetProfile)
+
+	t.Run("should keep the profile out of caches by default", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("should use the configured directive", func(t *testing.T) {
+		viper.Set("CACHE_CONTROL_PROFILE", "private, max-age=30")
+		defer viper.Reset()
+
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, max-age=30", w.Header().Get("Cache-Control"))
+	})
+}
+
+func TestAccountHandler_MaxSessions(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	setup := func(t *testing.T) (*HTTPTestHelper, *gorm.DB) {
+		db := newTestDB(t)
+		service := account.NewAccountService(nil, nil)
+		repository := account.NewAccountRepository(db)
+
+		password, err := service.HashPassword(context.Background(), "password123")
+		assert.NoError(t, err)
+		_, err = repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com", Password: password})
+		assert.NoError(t, err)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+		httpHelper.SetupHandler("POST", "/account/refresh", handler.RefreshToken)
+		return httpHelper, db
+	}
+
+	login := func(t *testing.T, httpHelper *HTTPTestHelper) string {
+		w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{Email: "test@example.com", Password: "password123"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response account.LoginAccountResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		return response.RefreshToken
+	}
+
+	refresh := func(httpHelper *HTTPTestHelper, refreshToken string) int {
+		return httpHelper.MakeRequest("POST", "/account/refresh", account.RefreshTokenRequest{RefreshToken: refreshToken}, nil).Code
+	}
+
+	t.Run("should evict the least recently used session past the cap", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("MAX_SESSIONS_PER_ACCOUNT", 2)
+		defer viper.Reset()
+
+		httpHelper, _ := setup(t)
+
+		first := login(t, httpHelper)
+		second := login(t, httpHelper)
+		// using the first session makes the second the least recently used
+		assert.Equal(t, http.StatusOK, refresh(httpHelper, first))
+
+		third := login(t, httpHelper)
+
+		assert.Equal(t, http.StatusUnauthorized, refresh(httpHelper, second))
+		assert.Equal(t, http.StatusOK, refresh(httpHelper, first))
+		assert.Equal(t, http.StatusOK, refresh(httpHelper, third))
+	})
+
+	t.Run("should evict the oldest unused session", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("MAX_SESSIONS_PER_ACCOUNT", 2)
+		defer viper.Reset()
+
+		httpHelper, db := setup(t)
+
+		var tokens []string
+		for range 4 {
+			tokens = append(tokens, login(t, httpHelper))
+		}
+
+		var active int64
+		assert.NoError(t, db.Model(&domain.RefreshToken{}).Where("revoked_at IS NULL").Count(&active).Error)
+		assert.Equal(t, int64(2), active)
+
+		assert.Equal(t, http.StatusUnauthorized, refresh(httpHelper, tokens[0]))
+		assert.Equal(t, http.StatusUnauthorized, refresh(httpHelper, tokens[1]))
+		assert.Equal(t, http.StatusOK, refresh(httpHelper, tokens[2]))
+		assert.Equal(t, http.StatusOK, refresh(httpHelper, tokens[3]))
+	})
+
+	t.Run("should keep every session by default", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		defer viper.Reset()
+
+		httpHelper, db := setup(t)
+
+		for range 4 {
+			login(t, httpHelper)
+		}
+
+		var active int64
+		assert.NoError(t, db.Model(&domain.RefreshToken{}).Where("revoked_at IS NULL").Count(&active).Error)
+		assert.Equal(t, int64(4), active)
+	})
 }
@@ -0,0 +1,105 @@
+package account
+
+import (
+	"context"
+	"spsyncpro_api/pkg/domain"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// defaultArgon2MemoryBudget is the memory in KiB argon2 may hold across
+	// all hashing workers, 4 workers at the default 64MB
+	defaultArgon2MemoryBudget = 256 * 1024
+	defaultHashQueueTimeout   = 5 * time.Second
+)
+
+// HashPool runs password hashes on a fixed set of workers. Each argon2 hash
+// holds ARGON2_MEMORY for its duration, bounding the workers bounds peak
+// memory however many logins and registrations arrive at once. A hash waits
+// for a free worker up to the queue timeout.
+type HashPool struct {
+	jobs         chan hashJob
+	size         int
+	queueTimeout time.Duration
+}
+
+type hashJob struct {
+	run  func()
+	done chan struct{}
+}
+
+// NewHashPool starts size workers, they run for the life of the process
+func NewHashPool(size int, queueTimeout time.Duration) *HashPool {
+	if size < 1 {
+		size = 1
+	}
+	pool := &HashPool{
+		// unbuffered, a job is only handed over once a worker is free
+		jobs:         make(chan hashJob),
+		size:         size,
+		queueTimeout: queueTimeout,
+	}
+	for range size {
+		go pool.work()
+	}
+	return pool
+}
+
+// NewHashPoolFromConfig sizes the pool with hashPoolSize and reads
+// ARGON2_QUEUE_TIMEOUT
+func NewHashPoolFromConfig() *HashPool {
+	queueTimeout := defaultHashQueueTimeout
+	if timeout := viper.GetDuration("ARGON2_QUEUE_TIMEOUT"); timeout > 0 {
+		queueTimeout = timeout
+	}
+	return NewHashPool(hashPoolSize(), queueTimeout)
+}
+
+// hashPoolSize is ARGON2_WORKERS when set, otherwise as many workers as fit
+// ARGON2_MEMORY into ARGON2_MEMORY_BUDGET (KiB), at least one
+func hashPoolSize() int {
+	if workers := viper.GetInt("ARGON2_WORKERS"); workers > 0 {
+		return workers
+	}
+
+	budget := uint32(defaultArgon2MemoryBudget)
+	if configured := viper.GetUint32("ARGON2_MEMORY_BUDGET"); configured > 0 {
+		budget = configured
+	}
+	return max(int(budget/currentArgon2Params().memory), 1)
+}
+
+// Size is the number of workers
+func (p *HashPool) Size() int {
+	return p.size
+}
+
+// Run runs fn on a worker and waits for it to finish. It returns
+// ErrPasswordHashingBusy when no worker frees up within the queue timeout,
+// or the error of ctx when it ends first, fn is not run in either case.
+func (p *HashPool) Run(ctx context.Context, fn func()) error {
+	job := hashJob{run: fn, done: make(chan struct{})}
+
+	timer := time.NewTimer(p.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case p.jobs <- job:
+	case <-timer.C:
+		return domain.ErrPasswordHashingBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	<-job.done
+	return nil
+}
+
+func (p *HashPool) work() {
+	for job := range p.jobs {
+		job.run()
+		close(job.done)
+	}
+}
@@ -0,0 +1,105 @@
+package account_test
+
+import (
+	"context"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/domain"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestHashPool(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should never run more hashes at once than workers", func(t *testing.T) {
+		pool := account.NewHashPool(3, 10*time.Second)
+
+		var active, peak, completed atomic.Int32
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Go(func() {
+				err := pool.Run(context.Background(), func() {
+					current := active.Add(1)
+					for {
+						highest := peak.Load()
+						if current <= highest || peak.CompareAndSwap(highest, current) {
+							break
+						}
+					}
+					time.Sleep(2 * time.Millisecond)
+					active.Add(-1)
+					completed.Add(1)
+				})
+				assert.NoError(t, err)
+			})
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(50), completed.Load())
+		assert.LessOrEqual(t, peak.Load(), int32(pool.Size()))
+		assert.Equal(t, int32(3), peak.Load())
+	})
+
+	t.Run("should fail when no worker frees up within the queue timeout", func(t *testing.T) {
+		pool := account.NewHashPool(1, 20*time.Millisecond)
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		go func() {
+			_ = pool.Run(context.Background(), func() {
+				close(started)
+				<-release
+			})
+		}()
+		<-started
+		defer close(release)
+
+		ran := false
+		err := pool.Run(context.Background(), func() { ran = true })
+		assert.ErrorIs(t, err, domain.ErrPasswordHashingBusy)
+		assert.False(t, ran)
+	})
+
+	t.Run("should size the pool from the memory budget", func(t *testing.T) {
+		viper.Set("ARGON2_MEMORY", 64*1024)
+		viper.Set("ARGON2_MEMORY_BUDGET", 200*1024)
+		defer viper.Reset()
+
+		assert.Equal(t, 3, account.NewHashPoolFromConfig().Size())
+
+		viper.Set("ARGON2_MEMORY_BUDGET", 1024)
+		assert.Equal(t, 1, account.NewHashPoolFromConfig().Size())
+
+		viper.Set("ARGON2_WORKERS", 6)
+		assert.Equal(t, 6, account.NewHashPoolFromConfig().Size())
+	})
+
+	t.Run("should hash a burst of passwords through the pool", func(t *testing.T) {
+		viper.Set("ARGON2_MEMORY", 1024)
+		viper.Set("ARGON2_WORKERS", 2)
+		defer viper.Reset()
+
+		service := account.NewAccountService(nil, nil)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				hash, err := service.HashPassword(context.Background(), "password")
+				assert.NoError(t, err)
+
+				ok, _, err := service.ComparePassword(context.Background(), "password", hash)
+				assert.NoError(t, err)
+				assert.True(t, ok)
+			})
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,200 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultKeyGracePeriod = 24 * time.Hour
+	keyCacheTTL           = time.Minute
+	signingKeyLen         = 32
+	kidLen                = 8
+)
+
+// KeyStore resolves JWT signing keys by kid and rotates the current key.
+// Retired keys keep validating tokens until the grace period ends.
+type KeyStore struct {
+	tracer      trace.Tracer
+	repository  domain.SigningKeyRepository
+	encryptor   *utils.Encryptor
+	gracePeriod time.Duration
+
+	mu      sync.Mutex
+	current *cachedKey
+	keys    map[string]*cachedKey
+}
+
+type cachedKey struct {
+	key       *domain.SigningKey
+	secret    []byte
+	fetchedAt time.Time
+}
+
+func NewKeyStore(repository domain.SigningKeyRepository) (*KeyStore, error) {
+	encryptor, err := utils.NewEncryptorFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	gracePeriod := viper.GetDuration("JWT_KEY_GRACE_PERIOD")
+	if gracePeriod <= 0 {
+		gracePeriod = defaultKeyGracePeriod
+	}
+
+	return &KeyStore{
+		tracer:      otel.Tracer("keyStore"),
+		repository:  repository,
+		encryptor:   encryptor,
+		gracePeriod: gracePeriod,
+		keys:        map[string]*cachedKey{},
+	}, nil
+}
+
+// Current returns the current signing key and its secret, the key is nil
+// when no key has been rotated in yet
+func (k *KeyStore) Current(ctx context.Context) (*domain.SigningKey, []byte, error) {
+	ctx, span := k.tracer.Start(ctx, "Current")
+	defer span.End()
+
+	k.mu.Lock()
+	current := k.current
+	k.mu.Unlock()
+	if current != nil && time.Since(current.fetchedAt) < keyCacheTTL {
+		return current.key, current.secret, nil
+	}
+
+	key, err := k.repository.GetCurrentSigningKey(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	cached, err := k.cache(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k.mu.Lock()
+	k.current = cached
+	k.mu.Unlock()
+
+	return cached.key, cached.secret, nil
+}
+
+// Lookup returns the secret for kid, rejecting keys retired longer than the
+// grace period before now
+func (k *KeyStore) Lookup(ctx context.Context, kid string, now time.Time) ([]byte, error) {
+	ctx, span := k.tracer.Start(ctx, "Lookup")
+	defer span.End()
+
+	k.mu.Lock()
+	cached, ok := k.keys[kid]
+	k.mu.Unlock()
+
+	if !ok || time.Since(cached.fetchedAt) >= keyCacheTTL {
+		key, err := k.repository.GetSigningKeyByKid(ctx, kid)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, domain.ErrSigningKeyNotFound
+			}
+			return nil, err
+		}
+
+		cached, err = k.cache(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cached.key.RetiredAt != nil && now.After(cached.key.RetiredAt.Add(k.gracePeriod)) {
+		return nil, domain.ErrSigningKeyRetired
+	}
+
+	return cached.secret, nil
+}
+
+// Rotate retires the current key and makes a freshly generated key current
+func (k *KeyStore) Rotate(ctx context.Context) (*domain.KeyRotation, error) {
+	ctx, span := k.tracer.Start(ctx, "Rotate")
+	defer span.End()
+
+	previous, _, err := k.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateToken(signingKeyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := utils.GenerateToken(kidLen)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := k.encryptor.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	rotatedAt := time.Now()
+	if err := k.repository.RetireSigningKeys(ctx, rotatedAt); err != nil {
+		return nil, err
+	}
+
+	key, err := k.repository.CreateSigningKey(ctx, &domain.SigningKey{
+		Kid:    kid,
+		Secret: encryptedSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.current = nil
+	k.keys = map[string]*cachedKey{}
+	k.mu.Unlock()
+
+	rotation := &domain.KeyRotation{
+		Kid:              key.Kid,
+		RotatedAt:        rotatedAt,
+		GracePeriodEndAt: rotatedAt.Add(k.gracePeriod),
+	}
+	if previous != nil {
+		rotation.PreviousKid = previous.Kid
+	}
+
+	return rotation, nil
+}
+
+func (k *KeyStore) cache(key *domain.SigningKey) (*cachedKey, error) {
+	secret, err := k.encryptor.Decrypt(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &cachedKey{
+		key:       key,
+		secret:    []byte(secret),
+		fetchedAt: time.Now(),
+	}
+
+	k.mu.Lock()
+	k.keys[key.Kid] = cached
+	k.mu.Unlock()
+
+	return cached, nil
+}
@@ -0,0 +1,68 @@
+package account
+
+import (
+	"context"
+	"spsyncpro_api/pkg/database"
+	"spsyncpro_api/pkg/domain"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepo struct {
+	db    *gorm.DB
+	trace trace.Tracer
+}
+
+func NewSigningKeyRepository(db *gorm.DB) domain.SigningKeyRepository {
+	trace := otel.Tracer("signingKeyRepository")
+	return &SigningKeyRepo{
+		db:    db,
+		trace: trace,
+	}
+}
+
+// conn joins the transaction carried by ctx, see database.DB.WithTransaction
+func (r *SigningKeyRepo) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, r.db)
+}
+
+func (r *SigningKeyRepo) CreateSigningKey(ctx context.Context, key *domain.SigningKey) (*domain.SigningKey, error) {
+	_, span := r.trace.Start(ctx, "CreateSigningKey")
+	defer span.End()
+	err := r.conn(ctx).Create(key).Error
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *SigningKeyRepo) GetCurrentSigningKey(ctx context.Context) (*domain.SigningKey, error) {
+	_, span := r.trace.Start(ctx, "GetCurrentSigningKey")
+	defer span.End()
+	var key domain.SigningKey
+	err := r.conn(ctx).Where("retired_at IS NULL").Order("id desc").First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *SigningKeyRepo) GetSigningKeyByKid(ctx context.Context, kid string) (*domain.SigningKey, error) {
+	_, span := r.trace.Start(ctx, "GetSigningKeyByKid")
+	defer span.End()
+	var key domain.SigningKey
+	err := r.conn(ctx).Where("kid = ?", kid).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *SigningKeyRepo) RetireSigningKeys(ctx context.Context, retiredAt time.Time) error {
+	_, span := r.trace.Start(ctx, "RetireSigningKeys")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.SigningKey{}).Where("retired_at IS NULL").Update("retired_at", retiredAt).Error
+}
@@ -0,0 +1,162 @@
+package account_test
+
+import (
+	"context"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+)
+
+const testEncryptionKey = "myverystrongpasswordo32bitlength"
+
+func encryptedSigningKey(t *testing.T, kid, secret string, retiredAt *time.Time) *domain.SigningKey {
+	encryptor, err := utils.NewEncryptor([]byte(testEncryptionKey))
+	assert.NoError(t, err)
+
+	encryptedSecret, err := encryptor.Encrypt(secret)
+	assert.NoError(t, err)
+
+	return &domain.SigningKey{Kid: kid, Secret: encryptedSecret, RetiredAt: retiredAt}
+}
+
+func TestAccountService_RotateSigningKey(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("ENCRYPTION_KEY", testEncryptionKey)
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	acc := &domain.Account{ID: 123, Email: "test@example.com"}
+
+	t.Run("should sign new tokens with the rotated key", func(t *testing.T) {
+		repository := domain.NewMockSigningKeyRepository(t)
+		oldKey := encryptedSigningKey(t, "old_kid", "old_secret", nil)
+
+		var newKey *domain.SigningKey
+		repository.On("GetCurrentSigningKey", anyContext).Return(oldKey, nil).Once()
+		repository.On("RetireSigningKeys", anyContext, mock.AnythingOfType("time.Time")).Return(nil)
+		repository.On("CreateSigningKey", anyContext, mock.AnythingOfType("*domain.SigningKey")).
+			Run(func(args mock.Arguments) { newKey = args.Get(1).(*domain.SigningKey) }).
+			Return(func(_ context.Context, key *domain.SigningKey) *domain.SigningKey { return key }, nil)
+
+		keyStore, err := account.NewKeyStore(repository)
+		assert.NoError(t, err)
+		service := account.NewAccountService(mailer.NewMockEmailService(t), keyStore)
+
+		rotation, err := service.RotateSigningKey(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "old_kid", rotation.PreviousKid)
+		assert.NotEmpty(t, rotation.Kid)
+		assert.Equal(t, rotation.RotatedAt.Add(24*time.Hour), rotation.GracePeriodEndAt)
+
+		repository.On("GetCurrentSigningKey", anyContext).Return(newKey, nil)
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		assert.Equal(t, rotation.Kid, parsed.Header["kid"])
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+
+	t.Run("should accept tokens from the previous key within the grace period", func(t *testing.T) {
+		repository := domain.NewMockSigningKeyRepository(t)
+		retiredAt := time.Now().Add(-time.Hour)
+		oldKey := encryptedSigningKey(t, "old_kid", "old_secret", &retiredAt)
+
+		repository.On("GetSigningKeyByKid", anyContext, "old_kid").Return(oldKey, nil)
+
+		keyStore, err := account.NewKeyStore(repository)
+		assert.NoError(t, err)
+		service := account.NewAccountService(mailer.NewMockEmailService(t), keyStore)
+
+		token := signedTestToken(t, "old_kid", "old_secret")
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+
+	t.Run("should reject tokens from the previous key after the grace period", func(t *testing.T) {
+		repository := domain.NewMockSigningKeyRepository(t)
+		retiredAt := time.Now().Add(-25 * time.Hour)
+		oldKey := encryptedSigningKey(t, "old_kid", "old_secret", &retiredAt)
+
+		repository.On("GetSigningKeyByKid", anyContext, "old_kid").Return(oldKey, nil)
+
+		keyStore, err := account.NewKeyStore(repository)
+		assert.NoError(t, err)
+		service := account.NewAccountService(mailer.NewMockEmailService(t), keyStore)
+
+		token := signedTestToken(t, "old_kid", "old_secret")
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, domain.ErrSigningKeyRetired)
+		assert.Equal(t, uint(0), accountID)
+	})
+
+	t.Run("should judge the grace period on the service clock", func(t *testing.T) {
+		repository := domain.NewMockSigningKeyRepository(t)
+		retiredAt := time.Now().Add(-25 * time.Hour)
+		oldKey := encryptedSigningKey(t, "old_kid", "old_secret", &retiredAt)
+
+		repository.On("GetSigningKeyByKid", anyContext, "old_kid").Return(oldKey, nil)
+
+		keyStore, err := account.NewKeyStore(repository)
+		assert.NoError(t, err)
+		// an hour after retirement on the service clock, within the grace period
+		clock := utils.ClockFunc(func() time.Time { return retiredAt.Add(time.Hour) })
+		service := account.NewAccountServiceWithClock(mailer.NewMockEmailService(t), keyStore, clock)
+
+		token := signedTestToken(t, "old_kid", "old_secret")
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+
+	t.Run("should reject tokens with an unknown kid", func(t *testing.T) {
+		repository := domain.NewMockSigningKeyRepository(t)
+		repository.On("GetSigningKeyByKid", anyContext, "unknown_kid").Return(nil, gorm.ErrRecordNotFound)
+
+		keyStore, err := account.NewKeyStore(repository)
+		assert.NoError(t, err)
+		service := account.NewAccountService(mailer.NewMockEmailService(t), keyStore)
+
+		token := signedTestToken(t, "unknown_kid", "old_secret")
+
+		_, err = service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, domain.ErrSigningKeyNotFound)
+	})
+}
+
+func signedTestToken(t *testing.T, kid, secret string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": 123,
+		"iss": "spsyncpro_api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString([]byte(secret))
+	assert.NoError(t, err)
+	return signed
+}
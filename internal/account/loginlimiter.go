@@ -0,0 +1,159 @@
+package account
+
+import (
+	"context"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultLoginMaxAttempts = 5
+	defaultLoginWindow      = 15 * time.Minute
+)
+
+type loginAttempts struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryLoginAttemptStore keeps attempt windows in process memory, counts are
+// not shared between instances
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+}
+
+func NewMemoryLoginAttemptStore() domain.LoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts: make(map[string]*loginAttempts),
+	}
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	entry, ok := s.attempts[key]
+	if !ok {
+		entry = &loginAttempts{resetAt: now.Add(window)}
+		s.attempts[key] = entry
+	}
+	entry.count++
+
+	return entry.count, entry.resetAt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Failures(ctx context.Context, key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.attempts[key]
+	if !ok || !time.Now().Before(entry.resetAt) {
+		return 0, time.Time{}, nil
+	}
+
+	return entry.count, entry.resetAt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// prune drops windows that have ended so the map does not grow with every
+// email an attacker tries
+func (s *MemoryLoginAttemptStore) prune(now time.Time) {
+	for key, entry := range s.attempts {
+		if !now.Before(entry.resetAt) {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+// LoginLimiter blocks logins for a client IP or an email once it has failed
+// LOGIN_MAX_ATTEMPTS times within LOGIN_WINDOW
+type LoginLimiter struct {
+	store       domain.LoginAttemptStore
+	maxAttempts int
+	window      time.Duration
+	bypass      *utils.IPAllowList
+}
+
+func NewLoginLimiter(store domain.LoginAttemptStore) *LoginLimiter {
+	maxAttempts := viper.GetInt("LOGIN_MAX_ATTEMPTS")
+	if maxAttempts <= 0 {
+		maxAttempts = defaultLoginMaxAttempts
+	}
+	window := viper.GetDuration("LOGIN_WINDOW")
+	if window <= 0 {
+		window = defaultLoginWindow
+	}
+	return &LoginLimiter{
+		store:       store,
+		maxAttempts: maxAttempts,
+		window:      window,
+	}
+}
+
+// SetBypass exempts the client IPs of the allow list from limiting, their
+// failures are neither counted nor blocked
+func (l *LoginLimiter) SetBypass(bypass *utils.IPAllowList) {
+	l.bypass = bypass
+}
+
+// Bypassed reports whether ip is exempt from limiting
+func (l *LoginLimiter) Bypassed(ip string) bool {
+	return l.bypass.Contains(ip)
+}
+
+// Blocked returns how long the caller must wait before trying again, zero
+// when none of the keys has exhausted its attempts
+func (l *LoginLimiter) Blocked(ctx context.Context, keys ...string) (time.Duration, error) {
+	var retryAfter time.Duration
+	for _, key := range keys {
+		count, resetAt, err := l.store.Failures(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if count >= l.maxAttempts {
+			retryAfter = max(retryAfter, time.Until(resetAt))
+		}
+	}
+	return retryAfter, nil
+}
+
+func (l *LoginLimiter) Fail(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if _, _, err := l.store.RecordFailure(ctx, key, l.window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *LoginLimiter) Reset(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := l.store.Reset(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loginLimitKeys(ip, email string) []string {
+	return []string{
+		"login:ip:" + ip,
+		"login:email:" + strings.ToLower(strings.TrimSpace(email)),
+	}
+}
@@ -1,33 +1,309 @@
 package account
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"slices"
 	"spsyncpro_api/pkg/domain"
 	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
-const AuthHeaderKey = "Authorization"
+const (
+	AuthHeaderKey   = "Authorization"
+	APIKeyHeaderKey = "X-API-Key"
 
-func AuthMiddleware(accountService domain.AccountService) gin.HandlerFunc {
+	bearerScheme = "Bearer"
+)
+
+// AuthMiddleware authenticates the request with an API key when the
+// X-API-Key header is set, otherwise with the auth token. The account is
+// loaded into the context, a disabled or anonymized account is rejected.
+func AuthMiddleware(accountService domain.AccountService, accountRepository domain.AccountRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.GetHeader(AuthHeaderKey)
+		if apiKey := c.GetHeader(APIKeyHeaderKey); apiKey != "" {
+			acc, err := authenticateAPIKey(c.Request.Context(), accountRepository, apiKey)
+			if err != nil {
+				utils.RespondError(c, err)
+				c.Abort()
+				return
+			}
+
+			c.Set(utils.AccountIdContextKey, acc.ID)
+			c.Set(utils.AccountContextKey, acc)
+
+			c.Next()
+			return
+		}
+
+		token, err := bearerToken(c.GetHeader(AuthHeaderKey))
+		if err != nil {
+			utils.RespondError(c, err)
+			c.Abort()
+			return
+		}
+		if token == "" && cookieAuthEnabled() {
+			if cookie, err := c.Cookie(AuthCookieName); err == nil && cookie != "" {
+				token = cookie
+				c.Set(utils.CookieAuthContextKey, true)
+			}
+		}
 		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			utils.RespondError(c, domain.ErrUnauthorized)
 			c.Abort()
 			return
 		}
 
-		accountID, err := accountService.ValidateAuthToken(c.Request.Context(), token)
+		claims, err := accountService.ParseClaims(c.Request.Context(), token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			// an expired token has its own code, the client refreshes
+			// instead of logging in again
+			if errors.Is(err, domain.ErrTokenExpired) {
+				utils.RespondError(c, domain.ErrTokenExpired)
+			} else {
+				utils.RespondError(c, domain.ErrUnauthorized)
+			}
+			c.Abort()
+			return
+		}
+
+		if claims.TokenID != "" {
+			revoked, err := accountRepository.IsTokenRevoked(c.Request.Context(), claims.TokenID)
+			if err != nil {
+				utils.RespondError(c, domain.ErrInternal)
+				c.Abort()
+				return
+			}
+			if revoked {
+				utils.RespondError(c, domain.ErrUnauthorized)
+				c.Abort()
+				return
+			}
+		}
+
+		// the token outlives anonymizing or disabling the account, the
+		// account is checked on every request rather than tracking its tokens
+		acc, err := activeAccount(c.Request.Context(), accountRepository, claims.AccountID)
+		if err != nil {
+			utils.RespondError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Set(utils.AccountIdContextKey, claims.AccountID)
+		c.Set(utils.AccountContextKey, acc)
+		c.Set(utils.AuthClaimsContextKey, claims)
+		if claims.TokenID != "" {
+			c.Request = c.Request.WithContext(utils.WithSessionID(c.Request.Context(), claims.TokenID))
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole admits requests whose auth token carries one of roles, it runs
+// after AuthMiddleware. The role is read from the token claims, a role change
+// applies to tokens issued after it. Requests without a token, such as those
+// authenticated with an API key, are forbidden.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.Get(utils.AuthClaimsContextKey)
+		if !ok {
+			utils.RespondError(c, domain.ErrForbidden)
 			c.Abort()
 			return
 		}
 
-		c.Set(utils.AccountIdContextKey, accountID)
+		authClaims, ok := claims.(*domain.AuthClaims)
+		if !ok || !slices.Contains(roles, authClaims.Role) {
+			utils.RespondError(c, domain.ErrForbidden)
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// bearerToken returns the token of an Authorization header, the Bearer scheme
+// is matched case-insensitively. A bare token is accepted while
+// AUTH_ALLOW_BARE_TOKEN is on, anything else is ErrMalformedAuthHeader.
+func bearerToken(header string) (string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", nil
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found {
+		if strings.EqualFold(header, bearerScheme) || !allowBareToken() {
+			return "", domain.ErrMalformedAuthHeader
+		}
+		return header, nil
+	}
+
+	token = strings.TrimSpace(token)
+	if !strings.EqualFold(scheme, bearerScheme) || token == "" || strings.ContainsAny(token, " \t") {
+		return "", domain.ErrMalformedAuthHeader
+	}
+	return token, nil
+}
+
+// allowBareToken is AUTH_ALLOW_BARE_TOKEN, on unless turned off so clients
+// sending the token without a scheme keep working
+func allowBareToken() bool {
+	return !viper.IsSet("AUTH_ALLOW_BARE_TOKEN") || viper.GetBool("AUTH_ALLOW_BARE_TOKEN")
+}
+
+// LoadAccountMiddleware loads the authenticated account once for the routes
+// of a group, handlers read it with utils.AccountFromContext. It runs after
+// AuthMiddleware, a request without an authenticated account or whose
+// account no longer exists or was anonymized is rejected as unauthorized.
+func LoadAccountMiddleware(accountRepository domain.AccountRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID := c.GetUint(utils.AccountIdContextKey)
+		if accountID == 0 {
+			utils.RespondError(c, domain.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		// AuthMiddleware already loaded and checked it
+		if acc, ok := utils.AccountFromContext(c); ok && acc.ID == accountID {
+			c.Next()
+			return
+		}
+
+		acc, err := accountRepository.GetAccountByID(c.Request.Context(), accountID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondError(c, domain.ErrUnauthorized)
+			} else {
+				utils.RespondError(c, domain.ErrInternal)
+			}
+			c.Abort()
+			return
+		}
+
+		// an anonymized account keeps its row but can no longer be used
+		if acc.AnonymizedAt != nil {
+			utils.RespondError(c, domain.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		c.Set(utils.AccountContextKey, acc)
+
+		c.Next()
+	}
+}
+
+// authenticateAPIKey returns the account of an active API key and records its
+// use, recording the use is best effort and never fails the request. The key
+// of a disabled or anonymized account is rejected.
+func authenticateAPIKey(ctx context.Context, accountRepository domain.AccountRepository, apiKey string) (*domain.Account, error) {
+	keyHash := utils.HashToken(apiKey)
+
+	key, err := accountRepository.GetAPIKeyByHash(ctx, keyHash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrUnauthorized
+		}
+		return nil, domain.ErrInternal
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(keyHash)) != 1 || key.RevokedAt != nil {
+		return nil, domain.ErrUnauthorized
+	}
+
+	acc, err := activeAccount(ctx, accountRepository, key.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = accountRepository.TouchAPIKey(ctx, key.ID, time.Now())
+
+	return acc, nil
+}
+
+// activeAccount loads an authenticated account, one that no longer exists or
+// was anonymized is unauthorized and a disabled one is ErrAccountDisabled
+func activeAccount(ctx context.Context, accountRepository domain.AccountRepository, accountID uint) (*domain.Account, error) {
+	acc, err := accountRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrUnauthorized
+		}
+		return nil, domain.ErrInternal
+	}
+	if acc.AnonymizedAt != nil {
+		return nil, domain.ErrUnauthorized
+	}
+	if acc.DisabledAt != nil {
+		return nil, domain.ErrAccountDisabled
+	}
+	return acc, nil
+}
+
+// LoginRateLimitMiddleware rejects logins with 429 once the client IP or the
+// submitted email has exhausted its failed attempts. Invalid credentials count
+// as a failure and a successful login resets both counters, client IPs in the
+// limiter's bypass list are not limited.
+func LoginRateLimitMiddleware(limiter *LoginLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		// allow-listed IPs skip limiting, the handler still logs their failures
+		if limiter.Bypassed(c.ClientIP()) {
+			c.Next()
+			return
+		}
+
+		// read the email without consuming the body the handler binds
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			utils.RespondErrorStatus(c, http.StatusBadRequest, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req LoginAccountRequest
+		_ = json.Unmarshal(body, &req)
+		keys := loginLimitKeys(c.ClientIP(), req.Email)
+
+		retryAfter, err := limiter.Blocked(ctx, keys...)
+		if err != nil {
+			utils.RespondError(c, domain.ErrInternal)
+			c.Abort()
+			return
+		}
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			utils.RespondError(c, domain.ErrTooManyLoginAttempts)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		switch c.Writer.Status() {
+		case http.StatusOK:
+			_ = limiter.Reset(ctx, keys...)
+		case http.StatusBadRequest:
+			_ = limiter.Fail(ctx, keys...)
+		}
+	}
+}
@@ -0,0 +1,708 @@
+package account_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+)
+
+func TestAuthMiddleware_RevokedToken(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	t.Run("should reject a token after logout", func(t *testing.T) {
+		logger := logrus.New()
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+		repository := domain.NewMockAccountRepository(t)
+
+		acc := &domain.Account{ID: 1, Email: "test@example.com"}
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		claims, err := service.ParseClaims(context.Background(), token)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, claims.TokenID)
+
+		revoked := false
+		repository.On("IsTokenRevoked", anyContext, claims.TokenID).Return(func(context.Context, string) bool { return revoked }, nil)
+		repository.On("RevokeToken", anyContext, mock.MatchedBy(func(r *domain.RevokedToken) bool {
+			return r.TokenID == claims.TokenID && r.AccountID == 1 && r.ExpiresAt.Equal(claims.ExpiresAt)
+		})).Run(func(mock.Arguments) { revoked = true }).Return(nil)
+		repository.On("LogAccountActivity", anyContext, uint(1), domain.ActivityLogout).Return(nil)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(acc, nil)
+
+		handler := account.NewAccountHandler(logger, service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		authorized := httpHelper.router.Group("/", account.AuthMiddleware(service, repository))
+		authorized.GET("/account/profile", handler.GetProfile)
+		authorized.POST("/account/logout", handler.LogoutAccount)
+
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/account/profile", nil, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httpHelper.MakeAuthenticatedRequest("POST", "/account/logout", nil, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httpHelper.MakeAuthenticatedRequest("GET", "/account/profile", nil, token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject a refresh token", func(t *testing.T) {
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+		repository := domain.NewMockAccountRepository(t)
+
+		refreshToken, _, err := service.GenerateRefreshToken(context.Background(), &domain.Account{ID: 1}, false)
+		assert.NoError(t, err)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.router.GET("/account/profile", account.AuthMiddleware(service, repository), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/account/profile", nil, refreshToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthMiddleware_AnonymizedAccount(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+	hashedPassword, err := service.HashPassword(context.Background(), "password")
+	assert.NoError(t, err)
+	acc, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com", Password: hashedPassword})
+	assert.NoError(t, err)
+
+	handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+	httpHelper.SetupHandler("POST", "/account/refresh", handler.RefreshToken)
+	// outside the routes that load the account
+	authorized := httpHelper.router.Group("/", account.AuthMiddleware(service, repository))
+	authorized.GET("/account/activity", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	var login account.LoginAccountResponse
+	w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{Email: "test@example.com", Password: "password", RememberMe: true}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	httpHelper.AssertJSONResponse(t, w, &login)
+
+	w = httpHelper.MakeAuthenticatedRequest("GET", "/account/activity", nil, login.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.NoError(t, repository.AnonymizeAccount(context.Background(), acc.ID, time.Now()))
+
+	t.Run("should reject the access token of an anonymized account", func(t *testing.T) {
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/account/activity", nil, login.Token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should not refresh the session of an anonymized account", func(t *testing.T) {
+		w := httpHelper.MakeRequest("POST", "/account/refresh", account.RefreshTokenRequest{RefreshToken: login.RefreshToken}, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthMiddleware_BearerToken(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+	repository := domain.NewMockAccountRepository(t)
+	repository.On("IsTokenRevoked", anyContext, mock.AnythingOfType("string")).Return(false, nil).Maybe()
+	repository.On("GetAccountByID", anyContext, uint(1)).Return(&domain.Account{ID: 1}, nil).Maybe()
+
+	token, err := service.GenerateAuthToken(context.Background(), &domain.Account{ID: 1})
+	assert.NoError(t, err)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.router.GET("/account/profile", account.AuthMiddleware(service, repository), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(header string) *httptest.ResponseRecorder {
+		return httpHelper.MakeRequest("GET", "/account/profile", nil, map[string]string{account.AuthHeaderKey: header})
+	}
+
+	t.Run("should accept the bearer scheme in any case", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("Bearer "+token).Code)
+		assert.Equal(t, http.StatusOK, request("bearer "+token).Code)
+		assert.Equal(t, http.StatusOK, request("BEARER  "+token).Code)
+	})
+
+	t.Run("should accept a bare token", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request(token).Code)
+	})
+
+	t.Run("should reject a bare token when turned off", func(t *testing.T) {
+		viper.Set("AUTH_ALLOW_BARE_TOKEN", false)
+		defer viper.Set("AUTH_ALLOW_BARE_TOKEN", true)
+
+		w := request(token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "auth.malformed_header")
+
+		assert.Equal(t, http.StatusOK, request("Bearer "+token).Code)
+	})
+
+	t.Run("should reject malformed headers", func(t *testing.T) {
+		for _, header := range []string{
+			"Bearer",
+			"Bearer ",
+			"Basic " + token,
+			"Bearer " + token + " extra",
+			"Token" + token + " " + token,
+		} {
+			w := request(header)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, header)
+			assert.Contains(t, w.Body.String(), "auth.malformed_header", header)
+		}
+	})
+
+	t.Run("should reject an invalid bearer token", func(t *testing.T) {
+		w := request("Bearer not-a-token")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "auth.unauthorized")
+	})
+
+	t.Run("should report an expired token with its own code", func(t *testing.T) {
+		issuedAt := time.Now().Add(-2 * account.AuthTokenExpiry)
+		past := account.NewAccountServiceWithClock(mailer.NewMockEmailService(t), nil, utils.ClockFunc(func() time.Time { return issuedAt }))
+		expired, err := past.GenerateAuthToken(context.Background(), &domain.Account{ID: 1})
+		assert.NoError(t, err)
+
+		w := request("Bearer " + expired)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "auth.token_expired")
+	})
+}
+
+func TestRequireRole(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+	repository := domain.NewMockAccountRepository(t)
+	repository.On("IsTokenRevoked", anyContext, mock.AnythingOfType("string")).Return(false, nil).Maybe()
+	repository.On("GetAccountByID", anyContext, uint(1)).Return(&domain.Account{ID: 1}, nil).Maybe()
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.router.GET("/admin/accounts", account.AuthMiddleware(service, repository), account.RequireRole(domain.RoleAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tokenFor := func(role string) string {
+		token, err := service.GenerateAuthToken(context.Background(), &domain.Account{ID: 1, Role: role})
+		assert.NoError(t, err)
+		return token
+	}
+
+	t.Run("should allow an admin", func(t *testing.T) {
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/admin/accounts", nil, tokenFor(domain.RoleAdmin))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should deny a user", func(t *testing.T) {
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/admin/accounts", nil, tokenFor(domain.RoleUser))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// an account without a stored role is a user
+		w = httpHelper.MakeAuthenticatedRequest("GET", "/admin/accounts", nil, tokenFor(""))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("should deny a request without auth claims", func(t *testing.T) {
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.router.GET("/admin/accounts", func(c *gin.Context) {
+			c.Set(utils.AccountIdContextKey, uint(1))
+		}, account.RequireRole(domain.RoleAdmin), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httpHelper.MakeRequest("GET", "/admin/accounts", nil, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestLoginRateLimitMiddleware(t *testing.T) {
+
+	setup := func() *HTTPTestHelper {
+		limiter := account.NewLoginLimiter(account.NewMemoryLoginAttemptStore())
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.router.POST("/account/login", account.LoginRateLimitMiddleware(limiter), func(c *gin.Context) {
+			var req account.LoginAccountRequest
+			if err := c.ShouldBindJSON(&req); err != nil || req.Password != "password" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credentials"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"token": "auth_token"})
+		})
+		return httpHelper
+	}
+
+	login := func(httpHelper *HTTPTestHelper, email, password string) *httptest.ResponseRecorder {
+		return httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{
+			Email:    email,
+			Password: password,
+		}, nil)
+	}
+
+	t.Run("should lock out after the maximum failed attempts", func(t *testing.T) {
+		viper.Set("LOGIN_MAX_ATTEMPTS", 3)
+		viper.Set("LOGIN_WINDOW", "10m")
+		defer viper.Reset()
+
+		httpHelper := setup()
+
+		for range 3 {
+			w := login(httpHelper, "test@example.com", "wrong")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+
+		// the correct password is rejected too while locked out
+		w := login(httpHelper, "test@example.com", "password")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		assert.NoError(t, err)
+		assert.InDelta(t, 600, retryAfter, 5)
+
+		// the lockout also covers other emails from the same ip
+		w = login(httpHelper, "other@example.com", "password")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("should reset the attempts after a successful login", func(t *testing.T) {
+		viper.Set("LOGIN_MAX_ATTEMPTS", 3)
+		defer viper.Reset()
+
+		httpHelper := setup()
+
+		for range 2 {
+			assert.Equal(t, http.StatusBadRequest, login(httpHelper, "test@example.com", "wrong").Code)
+		}
+		assert.Equal(t, http.StatusOK, login(httpHelper, "test@example.com", "password").Code)
+
+		for range 2 {
+			assert.Equal(t, http.StatusBadRequest, login(httpHelper, "test@example.com", "wrong").Code)
+		}
+		assert.Equal(t, http.StatusOK, login(httpHelper, "test@example.com", "password").Code)
+	})
+
+	t.Run("should allow attempts again once the window has passed", func(t *testing.T) {
+		viper.Set("LOGIN_MAX_ATTEMPTS", 1)
+		viper.Set("LOGIN_WINDOW", "50ms")
+		defer viper.Reset()
+
+		httpHelper := setup()
+
+		assert.Equal(t, http.StatusBadRequest, login(httpHelper, "test@example.com", "wrong").Code)
+		assert.Equal(t, http.StatusTooManyRequests, login(httpHelper, "test@example.com", "password").Code)
+
+		time.Sleep(60 * time.Millisecond)
+
+		assert.Equal(t, http.StatusOK, login(httpHelper, "test@example.com", "password").Code)
+	})
+
+	t.Run("should not limit an allow-listed ip", func(t *testing.T) {
+		viper.Set("LOGIN_MAX_ATTEMPTS", 2)
+		defer viper.Reset()
+
+		bypass, err := utils.ParseIPAllowList("203.0.113.0/24")
+		assert.NoError(t, err)
+
+		httpHelper := setup()
+		limiter := account.NewLoginLimiter(account.NewMemoryLoginAttemptStore())
+		limiter.SetBypass(bypass)
+		httpHelper.router.POST("/account/login-bypass", account.LoginRateLimitMiddleware(limiter), func(c *gin.Context) {
+			var req account.LoginAccountRequest
+			if err := c.ShouldBindJSON(&req); err != nil || req.Password != "password" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credentials"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"token": "auth_token"})
+		})
+
+		loginFrom := func(ip, password string) int {
+			body, _ := json.Marshal(account.LoginAccountRequest{Email: "ci@example.com", Password: password})
+			req := httptest.NewRequest("POST", "/account/login-bypass", bytes.NewReader(body))
+			req.RemoteAddr = ip + ":40000"
+
+			w := httptest.NewRecorder()
+			httpHelper.router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		for range 5 {
+			assert.Equal(t, http.StatusBadRequest, loginFrom("203.0.113.10", "wrong"))
+		}
+		assert.Equal(t, http.StatusOK, loginFrom("203.0.113.10", "password"))
+
+		for range 2 {
+			assert.Equal(t, http.StatusBadRequest, loginFrom("198.51.100.1", "wrong"))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, loginFrom("198.51.100.1", "password"))
+
+		// the failures of the allow-listed ip did not count against the email either
+		assert.Equal(t, http.StatusOK, loginFrom("203.0.113.11", "password"))
+	})
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+	service := domain.NewMockAccountService(t)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.router.GET("/account/profile", account.AuthMiddleware(service, repository), func(c *gin.Context) {
+		c.String(http.StatusOK, strconv.FormatUint(uint64(c.GetUint(utils.AccountIdContextKey)), 10))
+	})
+
+	assert.NoError(t, db.Create(&domain.Account{ID: 7, Email: "test@example.com", Password: "hashed"}).Error)
+
+	key := "sk_test-api-key"
+	apiKey, err := repository.CreateAPIKey(context.Background(), &domain.APIKey{
+		AccountID: 7,
+		Name:      "ci",
+		Prefix:    key[:8],
+		KeyHash:   utils.HashToken(key),
+	})
+	assert.NoError(t, err)
+
+	t.Run("should authenticate with an api key and record its use", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, map[string]string{account.APIKeyHeaderKey: key})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "7", w.Body.String())
+
+		var stored domain.APIKey
+		assert.NoError(t, db.First(&stored, apiKey.ID).Error)
+		assert.NotNil(t, stored.LastUsedAt)
+		assert.WithinDuration(t, time.Now(), *stored.LastUsedAt, time.Minute)
+	})
+
+	t.Run("should reject an unknown api key", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, map[string]string{account.APIKeyHeaderKey: "sk_unknown"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject the api key of a disabled account", func(t *testing.T) {
+		assert.NoError(t, db.Model(&domain.Account{}).Where("id = ?", 7).Update("disabled_at", time.Now()).Error)
+		defer db.Model(&domain.Account{}).Where("id = ?", 7).Update("disabled_at", nil)
+
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, map[string]string{account.APIKeyHeaderKey: key})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "account.disabled")
+	})
+
+	t.Run("should reject the api key of an anonymized account", func(t *testing.T) {
+		assert.NoError(t, db.Model(&domain.Account{}).Where("id = ?", 7).Update("anonymized_at", time.Now()).Error)
+		defer db.Model(&domain.Account{}).Where("id = ?", 7).Update("anonymized_at", nil)
+
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, map[string]string{account.APIKeyHeaderKey: key})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject a revoked api key", func(t *testing.T) {
+		revoked, err := repository.RevokeAPIKey(context.Background(), 7, apiKey.ID, time.Now())
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, map[string]string{account.APIKeyHeaderKey: key})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthMiddleware_SessionActivity(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+	hashedPassword, err := service.HashPassword(context.Background(), "password")
+	assert.NoError(t, err)
+	_, err = repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com", Password: hashedPassword})
+	assert.NoError(t, err)
+
+	handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.SetupHandler("POST", "/account/login", handler.LoginAccount)
+	authorized := httpHelper.router.Group("/", account.AuthMiddleware(service, repository))
+	authorized.POST("/account/change-password", handler.ChangePassword)
+
+	w := httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{Email: "test@example.com", Password: "wrong"}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var login account.LoginAccountResponse
+	w = httpHelper.MakeRequest("POST", "/account/login", account.LoginAccountRequest{Email: "test@example.com", Password: "password"}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	httpHelper.AssertJSONResponse(t, w, &login)
+
+	claims, err := service.ParseClaims(context.Background(), login.Token)
+	assert.NoError(t, err)
+
+	w = httpHelper.MakeAuthenticatedRequest("POST", "/account/change-password", account.ChangePasswordRequest{OldPassword: "password", NewPassword: "new_password"}, login.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var activities []domain.AccountActivity
+	assert.NoError(t, db.Order("id").Find(&activities).Error)
+
+	sessions := map[string]*string{}
+	for _, activity := range activities {
+		sessions[activity.Activity] = activity.SessionID
+	}
+
+	if assert.NotNil(t, sessions[domain.ActivityChangePassword]) {
+		assert.Equal(t, claims.TokenID, *sessions[domain.ActivityChangePassword])
+	}
+	if assert.NotNil(t, sessions[domain.ActivityLogin]) {
+		assert.Equal(t, claims.TokenID, *sessions[domain.ActivityLogin])
+	}
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	viper.Set("AUTH_COOKIE_ENABLED", true)
+	defer viper.Reset()
+
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+	repository := domain.NewMockAccountRepository(t)
+	repository.On("IsTokenRevoked", anyContext, mock.Anything).Return(false, nil).Maybe()
+	repository.On("GetAccountByID", anyContext, uint(1)).Return(&domain.Account{ID: 1, Email: "test@example.com"}, nil).Maybe()
+	handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+	token, err := service.GenerateAuthToken(context.Background(), &domain.Account{ID: 1, Email: "test@example.com"})
+	assert.NoError(t, err)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.router.GET("/account/csrf-token", handler.IssueCSRFToken)
+	authorized := httpHelper.router.Group("/", account.AuthMiddleware(service, repository), account.CSRFMiddleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	authorized.GET("/account/profile", ok)
+	authorized.POST("/account/change-password", ok)
+
+	w := httpHelper.MakeRequest("GET", "/account/csrf-token", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var issued account.CSRFTokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.NotEmpty(t, issued.CSRFToken)
+
+	var csrfCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == account.CSRFCookieName {
+			csrfCookie = cookie
+		}
+	}
+	assert.NotNil(t, csrfCookie)
+	assert.Equal(t, issued.CSRFToken, csrfCookie.Value)
+	assert.False(t, csrfCookie.HttpOnly)
+
+	authCookie := account.AuthCookieName + "=" + token
+
+	t.Run("should reject a cookie authenticated mutation without a csrf token", func(t *testing.T) {
+		w := httpHelper.MakeRequest("POST", "/account/change-password", nil, map[string]string{"Cookie": authCookie})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "auth.csrf_invalid")
+	})
+
+	t.Run("should reject a csrf header that does not match the cookie", func(t *testing.T) {
+		w := httpHelper.MakeRequest("POST", "/account/change-password", nil, map[string]string{
+			"Cookie":              authCookie + "; " + account.CSRFCookieName + "=" + issued.CSRFToken,
+			account.CSRFHeaderKey: "forged",
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("should reject a csrf header without the cookie", func(t *testing.T) {
+		w := httpHelper.MakeRequest("POST", "/account/change-password", nil, map[string]string{
+			"Cookie":              authCookie,
+			account.CSRFHeaderKey: issued.CSRFToken,
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("should accept a matching csrf header and cookie", func(t *testing.T) {
+		w := httpHelper.MakeRequest("POST", "/account/change-password", nil, map[string]string{
+			"Cookie":              authCookie + "; " + account.CSRFCookieName + "=" + issued.CSRFToken,
+			account.CSRFHeaderKey: issued.CSRFToken,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should not require a csrf token for safe methods", func(t *testing.T) {
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, map[string]string{"Cookie": authCookie})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should exempt requests authenticated with the header", func(t *testing.T) {
+		w := httpHelper.MakeAuthenticatedRequest("POST", "/account/change-password", nil, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should ignore the auth cookie when cookie auth is disabled", func(t *testing.T) {
+		viper.Set("AUTH_COOKIE_ENABLED", false)
+		defer viper.Set("AUTH_COOKIE_ENABLED", true)
+
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, map[string]string{"Cookie": authCookie})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestLoadAccountMiddleware(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	acc := &domain.Account{ID: 1, Email: "test@example.com"}
+
+	setup := func(t *testing.T, repository *domain.MockAccountRepository) *HTTPTestHelper {
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+
+		httpHelper := NewHTTPTestHelper()
+		withAccount := httpHelper.router.Group("/", account.AuthMiddleware(service, repository), account.LoadAccountMiddleware(repository))
+		withAccount.GET("/account/profile", handler.GetProfile)
+		withAccount.GET("/account/loaded", func(c *gin.Context) {
+			loaded, ok := utils.AccountFromContext(c)
+			assert.True(t, ok)
+			c.JSON(http.StatusOK, gin.H{"email": loaded.Email})
+		})
+		return httpHelper
+	}
+
+	token := func(t *testing.T) string {
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+		return token
+	}
+
+	t.Run("should load the account once for the handler", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("IsTokenRevoked", anyContext, mock.AnythingOfType("string")).Return(false, nil)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(acc, nil).Once()
+
+		httpHelper := setup(t, repository)
+
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/account/profile", nil, token(t))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response account.GetProfileResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.Equal(t, "test@example.com", response.Email)
+	})
+
+	t.Run("should expose the account through the context helper", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("IsTokenRevoked", anyContext, mock.AnythingOfType("string")).Return(false, nil)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(acc, nil).Once()
+
+		httpHelper := setup(t, repository)
+
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/account/loaded", nil, token(t))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "test@example.com")
+	})
+
+	t.Run("should not load an account without a token", func(t *testing.T) {
+		httpHelper := setup(t, domain.NewMockAccountRepository(t))
+
+		w := httpHelper.MakeRequest("GET", "/account/profile", nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject a token of a deleted account", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("IsTokenRevoked", anyContext, mock.AnythingOfType("string")).Return(false, nil)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(nil, gorm.ErrRecordNotFound)
+
+		httpHelper := setup(t, repository)
+
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/account/profile", nil, token(t))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject a token of an anonymized account", func(t *testing.T) {
+		anonymizedAt := time.Now()
+		repository := domain.NewMockAccountRepository(t)
+		repository.On("IsTokenRevoked", anyContext, mock.AnythingOfType("string")).Return(false, nil)
+		repository.On("GetAccountByID", anyContext, uint(1)).Return(&domain.Account{ID: 1, AnonymizedAt: &anonymizedAt}, nil)
+
+		httpHelper := setup(t, repository)
+
+		w := httpHelper.MakeAuthenticatedRequest("GET", "/account/profile", nil, token(t))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject a route group without authentication", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/account/profile", account.LoadAccountMiddleware(domain.NewMockAccountRepository(t)), func(c *gin.Context) {
+			t.Error("handler called without an account")
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/account/profile", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
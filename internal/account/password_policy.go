@@ -0,0 +1,78 @@
+package account
+
+import (
+	"spsyncpro_api/pkg/domain"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+const defaultPasswordMinLength = 8
+
+// PasswordPolicy is the strength required of a new password, a zero field
+// turns its rule off
+type PasswordPolicy struct {
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+}
+
+// NewPasswordPolicy reads PASSWORD_MIN_LENGTH and the PASSWORD_REQUIRE_*
+// character classes, the minimum length defaults to 8
+func NewPasswordPolicy() PasswordPolicy {
+	minLength := defaultPasswordMinLength
+	if viper.IsSet("PASSWORD_MIN_LENGTH") {
+		minLength = viper.GetInt("PASSWORD_MIN_LENGTH")
+	}
+	return PasswordPolicy{
+		MinLength:        minLength,
+		RequireUppercase: viper.GetBool("PASSWORD_REQUIRE_UPPERCASE"),
+		RequireLowercase: viper.GetBool("PASSWORD_REQUIRE_LOWERCASE"),
+		RequireDigit:     viper.GetBool("PASSWORD_REQUIRE_DIGIT"),
+		RequireSymbol:    viper.GetBool("PASSWORD_REQUIRE_SYMBOL"),
+	}
+}
+
+// Validate returns a *domain.PasswordPolicyError listing every rule the
+// password does not meet, nil when it meets them all
+func (p PasswordPolicy) Validate(password string) error {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	var unmet []string
+	// counted in characters, a multibyte character is not worth more
+	if utf8.RuneCountInString(password) < p.MinLength {
+		unmet = append(unmet, domain.PasswordRuleMinLength)
+	}
+	if p.RequireUppercase && !hasUpper {
+		unmet = append(unmet, domain.PasswordRuleUppercase)
+	}
+	if p.RequireLowercase && !hasLower {
+		unmet = append(unmet, domain.PasswordRuleLowercase)
+	}
+	if p.RequireDigit && !hasDigit {
+		unmet = append(unmet, domain.PasswordRuleDigit)
+	}
+	if p.RequireSymbol && !hasSymbol {
+		unmet = append(unmet, domain.PasswordRuleSymbol)
+	}
+
+	if len(unmet) > 0 {
+		return &domain.PasswordPolicyError{Unmet: unmet}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package account_test
+
+import (
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/domain"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	strict := account.PasswordPolicy{
+		MinLength:        10,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		unmet    []string
+	}{
+		{name: "should reject a short password", password: "Sh0rt!pw", unmet: []string{domain.PasswordRuleMinLength}},
+		{name: "should reject a password without uppercase", password: "lowercase1!", unmet: []string{domain.PasswordRuleUppercase}},
+		{name: "should reject a password without lowercase", password: "UPPERCASE1!", unmet: []string{domain.PasswordRuleLowercase}},
+		{name: "should reject a password without a digit", password: "NoDigitsHere!", unmet: []string{domain.PasswordRuleDigit}},
+		{name: "should reject a password without a symbol", password: "NoSymbols123", unmet: []string{domain.PasswordRuleSymbol}},
+		{name: "should list every unmet rule", password: "abc", unmet: []string{domain.PasswordRuleMinLength, domain.PasswordRuleUppercase, domain.PasswordRuleDigit, domain.PasswordRuleSymbol}},
+		{name: "should count characters instead of bytes", password: "Pässwörd1€", unmet: nil},
+		{name: "should accept a password meeting every rule", password: "Correct-Horse-9", unmet: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := strict.Validate(tt.password)
+			if tt.unmet == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, domain.ErrPasswordTooWeak)
+			var policyErr *domain.PasswordPolicyError
+			assert.ErrorAs(t, err, &policyErr)
+			assert.Equal(t, tt.unmet, policyErr.Unmet)
+		})
+	}
+
+	t.Run("should only require the minimum length by default", func(t *testing.T) {
+		policy := account.NewPasswordPolicy()
+		assert.Equal(t, account.PasswordPolicy{MinLength: 8}, policy)
+
+		assert.NoError(t, policy.Validate("password"))
+		assert.ErrorIs(t, policy.Validate("passwd"), domain.ErrPasswordTooWeak)
+	})
+
+	t.Run("should read the policy from config", func(t *testing.T) {
+		viper.Set("PASSWORD_MIN_LENGTH", 12)
+		viper.Set("PASSWORD_REQUIRE_UPPERCASE", true)
+		viper.Set("PASSWORD_REQUIRE_DIGIT", true)
+		defer viper.Reset()
+
+		policy := account.NewPasswordPolicy()
+		assert.Equal(t, account.PasswordPolicy{MinLength: 12, RequireUppercase: true, RequireDigit: true}, policy)
+	})
+}
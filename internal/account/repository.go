@@ -2,12 +2,17 @@ package account
 
 import (
 	"context"
+	"spsyncpro_api/pkg/database"
 	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AccountRepo struct {
@@ -23,21 +28,30 @@ func NewAccountRepository(db *gorm.DB) domain.AccountRepository {
 	}
 }
 
+// conn joins the transaction carried by ctx, see database.DB.WithTransaction
+func (r *AccountRepo) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, r.db)
+}
+
 func (r *AccountRepo) CreateAccount(ctx context.Context, account *domain.Account) (*domain.Account, error) {
 	_, span := r.trace.Start(ctx, "CreateAccount")
 	defer span.End()
-	err := r.db.Create(account).Error
+	err := r.conn(ctx).Create(account).Error
 	if err != nil {
 		return nil, err
 	}
 	return account, nil
 }
 
+// GetAccountByEmail looks up the lower case email, emails are stored
+// normalized since the "lowercase account emails" migration
 func (r *AccountRepo) GetAccountByEmail(ctx context.Context, email string) (*domain.Account, error) {
 	_, span := r.trace.Start(ctx, "GetAccountByEmail")
 	defer span.End()
 	var account domain.Account
-	err := r.db.Where("email = ?", email).First(&account).Error
+	err := database.Retry(ctx, func() error {
+		return r.conn(ctx).Where("email = ?", strings.ToLower(email)).First(&account).Error
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +62,9 @@ func (r *AccountRepo) GetAccountByID(ctx context.Context, id uint) (*domain.Acco
 	_, span := r.trace.Start(ctx, "GetAccountByID")
 	defer span.End()
 	var account domain.Account
-	err := r.db.Where("id = ?", id).First(&account).Error
+	err := database.Retry(ctx, func() error {
+		return r.conn(ctx).Where("id = ?", id).First(&account).Error
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -58,21 +74,524 @@ func (r *AccountRepo) GetAccountByID(ctx context.Context, id uint) (*domain.Acco
 func (r *AccountRepo) UpdateAccount(ctx context.Context, account *domain.Account) (*domain.Account, error) {
 	_, span := r.trace.Start(ctx, "UpdateAccount")
 	defer span.End()
-	err := r.db.Save(account).Error
+	err := r.conn(ctx).Save(account).Error
 	if err != nil {
 		return nil, err
 	}
 	return account, nil
 }
 
+// DeleteAccount soft deletes the account together with the organization it
+// owns, the organization's client secret is cleared before the delete so no
+// encrypted credentials outlive the account. The email is replaced with a
+// tombstone so the address can be registered again.
 func (r *AccountRepo) DeleteAccount(ctx context.Context, id uint) error {
 	_, span := r.trace.Start(ctx, "DeleteAccount")
 	defer span.End()
-	return r.db.Delete(&domain.Account{}, id).Error
+
+	email, err := tombstoneEmail("deleted")
+	if err != nil {
+		return err
+	}
+
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Organization{}).Where("owner_id = ?", id).Update("client_secret", "").Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("owner_id = ?", id).Delete(&domain.Organization{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.Account{}).Where("id = ?", id).Update("email", email).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&domain.Account{}, id).Error
+	})
+}
+
+// anonymizedEmailDomain is a reserved domain, a tombstone email can never
+// receive mail or collide with a real address
+const anonymizedEmailDomain = "anonymized.invalid"
+
+// tombstoneEmail returns a random address on the reserved domain, random so
+// the tombstone reveals nothing about the email it replaces
+func tombstoneEmail(prefix string) (string, error) {
+	token, err := utils.GenerateToken(16)
+	if err != nil {
+		return "", err
+	}
+	// lower case like every stored email
+	return strings.ToLower(prefix + "-" + token + "@" + anonymizedEmailDomain), nil
+}
+
+// AnonymizeAccount replaces the email with a random tombstone, clears the
+// password and disables the account. Refresh tokens, reset tokens, API keys
+// and pending actions are removed and the recipient of its email log is
+// replaced, the owned organization goes as on delete. The account row and its
+// activity remain so activity counts are unchanged.
+func (r *AccountRepo) AnonymizeAccount(ctx context.Context, id uint, anonymizedAt time.Time) error {
+	_, span := r.trace.Start(ctx, "AnonymizeAccount")
+	defer span.End()
+
+	email, err := tombstoneEmail("anonymized")
+	if err != nil {
+		return err
+	}
+
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Account{}).Where("id = ?", id).Updates(map[string]any{
+			"email":                email,
+			"password":             "",
+			"email_verified":       false,
+			"verified_at":          nil,
+			"failed_login_count":   0,
+			"locked_until":         nil,
+			"inactivity_warned_at": nil,
+			"disabled_at":          gorm.Expr("COALESCE(disabled_at, ?)", anonymizedAt),
+			"anonymized_at":        anonymizedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		for _, model := range []any{&domain.RefreshToken{}, &domain.PasswordResetToken{}, &domain.APIKey{}, &domain.PendingAccountAction{}} {
+			if err := tx.Unscoped().Where("account_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Model(&domain.EmailLog{}).Where("account_id = ?", id).Update("recipient", email).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.Organization{}).Where("owner_id = ?", id).Update("client_secret", "").Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("owner_id = ?", id).Delete(&domain.Organization{}).Error
+	})
+}
+
+// GetInactiveAccounts returns enabled non-admin accounts without a login since
+// lastLoginBefore, accounts that never logged in count from their creation
+func (r *AccountRepo) GetInactiveAccounts(ctx context.Context, lastLoginBefore time.Time) ([]domain.Account, error) {
+	_, span := r.trace.Start(ctx, "GetInactiveAccounts")
+	defer span.End()
+	var accounts []domain.Account
+	err := r.conn(ctx).
+		Where("disabled_at IS NULL AND role <> ?", domain.RoleAdmin).
+		Where("COALESCE(last_login_at, created_at) < ?", lastLoginBefore).
+		Find(&accounts).Error
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
 }
 
+// LogAccountActivity records the activity with the session id carried by ctx,
+// see utils.WithSessionID
 func (r *AccountRepo) LogAccountActivity(ctx context.Context, accountID uint, activity string) error {
 	_, span := r.trace.Start(ctx, "LogAccountActivity")
 	defer span.End()
-	return r.db.Create(&domain.AccountActivity{AccountID: accountID, Activity: activity}).Error
+	accountActivity := &domain.AccountActivity{AccountID: accountID, Activity: activity}
+	if sessionID := utils.SessionIDFromContext(ctx); sessionID != "" {
+		accountActivity.SessionID = &sessionID
+	}
+	return r.conn(ctx).Create(accountActivity).Error
+}
+
+// activityBatchSize bounds the rows of a single insert of LogAccountActivities
+const activityBatchSize = 500
+
+// LogAccountActivities records activities collected earlier in one round
+// trip, their CreatedAt and SessionID are kept as given
+func (r *AccountRepo) LogAccountActivities(ctx context.Context, activities []domain.AccountActivity) error {
+	_, span := r.trace.Start(ctx, "LogAccountActivities")
+	defer span.End()
+	if len(activities) == 0 {
+		return nil
+	}
+	return r.conn(ctx).CreateInBatches(activities, activityBatchSize).Error
+}
+
+func (r *AccountRepo) LogEmail(ctx context.Context, emailLog *domain.EmailLog) error {
+	_, span := r.trace.Start(ctx, "LogEmail")
+	defer span.End()
+	return r.conn(ctx).Create(emailLog).Error
+}
+
+// GetActivityHistory returns a page of the account's activity, newest first,
+// with the total number of rows matching since (a zero since matches all)
+func (r *AccountRepo) GetActivityHistory(ctx context.Context, accountID uint, since time.Time, limit, offset int) ([]domain.AccountActivity, int64, error) {
+	_, span := r.trace.Start(ctx, "GetActivityHistory")
+	defer span.End()
+
+	query := r.conn(ctx).Model(&domain.AccountActivity{}).Where("account_id = ?", accountID)
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	activities := []domain.AccountActivity{}
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&activities).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return activities, total, nil
+}
+
+func (r *AccountRepo) ListAccounts(ctx context.Context, sort func(*gorm.DB) *gorm.DB, limit, offset int) ([]domain.Account, int64, error) {
+	_, span := r.trace.Start(ctx, "ListAccounts")
+	defer span.End()
+
+	var total int64
+	if err := r.conn(ctx).Model(&domain.Account{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	accounts := []domain.Account{}
+	err := r.conn(ctx).Scopes(sort).Limit(limit).Offset(offset).Find(&accounts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return accounts, total, nil
+}
+
+func (r *AccountRepo) CountActivitiesSince(ctx context.Context, activities []string, since time.Time) (int64, error) {
+	_, span := r.trace.Start(ctx, "CountActivitiesSince")
+	defer span.End()
+	var count int64
+	err := r.conn(ctx).Model(&domain.AccountActivity{}).
+		Where("activity IN ? AND created_at >= ?", activities, since).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *AccountRepo) CreatePendingAction(ctx context.Context, action *domain.PendingAccountAction) (*domain.PendingAccountAction, error) {
+	_, span := r.trace.Start(ctx, "CreatePendingAction")
+	defer span.End()
+	err := r.conn(ctx).Create(action).Error
+	if err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func (r *AccountRepo) GetPendingActionByCancelToken(ctx context.Context, cancelToken string) (*domain.PendingAccountAction, error) {
+	_, span := r.trace.Start(ctx, "GetPendingActionByCancelToken")
+	defer span.End()
+	var action domain.PendingAccountAction
+	err := r.conn(ctx).Where("cancel_token = ?", cancelToken).First(&action).Error
+	if err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+func (r *AccountRepo) GetDuePendingActions(ctx context.Context, now time.Time) ([]domain.PendingAccountAction, error) {
+	_, span := r.trace.Start(ctx, "GetDuePendingActions")
+	defer span.End()
+	var actions []domain.PendingAccountAction
+	err := r.conn(ctx).
+		Where("execute_after <= ? AND cancelled_at IS NULL AND applied_at IS NULL", now).
+		Order("execute_after asc").
+		Find(&actions).Error
+	if err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+func (r *AccountRepo) GetOpenPendingAction(ctx context.Context, accountID uint, action string) (*domain.PendingAccountAction, error) {
+	_, span := r.trace.Start(ctx, "GetOpenPendingAction")
+	defer span.End()
+	var pendingAction domain.PendingAccountAction
+	err := r.conn(ctx).
+		Where("account_id = ? AND action = ? AND cancelled_at IS NULL AND applied_at IS NULL", accountID, action).
+		First(&pendingAction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pendingAction, nil
+}
+
+func (r *AccountRepo) UpdatePendingAction(ctx context.Context, action *domain.PendingAccountAction) (*domain.PendingAccountAction, error) {
+	_, span := r.trace.Start(ctx, "UpdatePendingAction")
+	defer span.End()
+	err := r.conn(ctx).Save(action).Error
+	if err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func (r *AccountRepo) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) (*domain.RefreshToken, error) {
+	_, span := r.trace.Start(ctx, "CreateRefreshToken")
+	defer span.End()
+	err := r.conn(ctx).Create(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *AccountRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	_, span := r.trace.Start(ctx, "GetRefreshTokenByHash")
+	defer span.End()
+	var token domain.RefreshToken
+	err := database.Retry(ctx, func() error {
+		return r.conn(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *AccountRepo) RevokeRefreshTokens(ctx context.Context, accountID uint, revokedAt time.Time) error {
+	_, span := r.trace.Start(ctx, "RevokeRefreshTokens")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.RefreshToken{}).
+		Where("account_id = ? AND revoked_at IS NULL", accountID).
+		Update("revoked_at", revokedAt).Error
+}
+
+// CountActiveRefreshTokens counts the refresh tokens of every account that
+// are neither revoked nor expired at at
+func (r *AccountRepo) CountActiveRefreshTokens(ctx context.Context, at time.Time) (int64, error) {
+	_, span := r.trace.Start(ctx, "CountActiveRefreshTokens")
+	defer span.End()
+	var count int64
+	err := r.conn(ctx).Model(&domain.RefreshToken{}).
+		Where("revoked_at IS NULL AND expires_at > ?", at).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RevokeRefreshToken revokes the refresh token of one session of the
+// account, a token that is unknown or already revoked is left alone
+func (r *AccountRepo) RevokeRefreshToken(ctx context.Context, accountID uint, tokenHash string, revokedAt time.Time) error {
+	_, span := r.trace.Start(ctx, "RevokeRefreshToken")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.RefreshToken{}).
+		Where("account_id = ? AND token_hash = ? AND revoked_at IS NULL", accountID, tokenHash).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *AccountRepo) TouchRefreshToken(ctx context.Context, id uint, usedAt time.Time) error {
+	_, span := r.trace.Start(ctx, "TouchRefreshToken")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.RefreshToken{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
+
+// EvictRefreshTokens revokes the active sessions of the account beyond the
+// keep most recently used, a session never exchanged counts as used when it
+// was issued. It returns the number of sessions revoked.
+func (r *AccountRepo) EvictRefreshTokens(ctx context.Context, accountID uint, keep int, revokedAt time.Time) (int64, error) {
+	_, span := r.trace.Start(ctx, "EvictRefreshTokens")
+	defer span.End()
+
+	var evicted []uint
+	err := r.conn(ctx).Model(&domain.RefreshToken{}).
+		Where("account_id = ? AND revoked_at IS NULL AND expires_at > ?", accountID, revokedAt).
+		Order("COALESCE(last_used_at, created_at) DESC, id DESC").
+		Offset(keep).
+		Pluck("id", &evicted).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(evicted) == 0 {
+		return 0, nil
+	}
+
+	result := r.conn(ctx).Model(&domain.RefreshToken{}).
+		Where("id IN ? AND revoked_at IS NULL", evicted).
+		Update("revoked_at", revokedAt)
+	return result.RowsAffected, result.Error
+}
+
+func (r *AccountRepo) CreatePasswordResetToken(ctx context.Context, token *domain.PasswordResetToken) (*domain.PasswordResetToken, error) {
+	_, span := r.trace.Start(ctx, "CreatePasswordResetToken")
+	defer span.End()
+	err := r.conn(ctx).Create(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *AccountRepo) GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	_, span := r.trace.Start(ctx, "GetPasswordResetTokenByHash")
+	defer span.End()
+	var token domain.PasswordResetToken
+	err := database.Retry(ctx, func() error {
+		return r.conn(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ResetPasswordWithToken consumes a reset token, invalidates the other reset
+// tokens of the account and sets the new password in one transaction. It
+// reports false without changing anything when the token was already used
+// or invalidated so concurrent redeems fail.
+func (r *AccountRepo) ResetPasswordWithToken(ctx context.Context, tokenID uint, accountID uint, hashedPassword string, usedAt time.Time) (bool, error) {
+	_, span := r.trace.Start(ctx, "ResetPasswordWithToken")
+	defer span.End()
+	consumed := false
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.PasswordResetToken{}).
+			Where("id = ? AND account_id = ? AND used_at IS NULL AND invalidated_at IS NULL", tokenID, accountID).
+			Update("used_at", usedAt)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+
+		err := tx.Model(&domain.PasswordResetToken{}).
+			Where("account_id = ? AND invalidated_at IS NULL", accountID).
+			Update("invalidated_at", usedAt).Error
+		if err != nil {
+			return err
+		}
+
+		result = tx.Model(&domain.Account{}).Where("id = ?", accountID).Update("password", hashedPassword)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		consumed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return consumed, nil
+}
+
+func (r *AccountRepo) InvalidatePasswordResetTokens(ctx context.Context, accountID uint, invalidatedAt time.Time) error {
+	_, span := r.trace.Start(ctx, "InvalidatePasswordResetTokens")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.PasswordResetToken{}).
+		Where("account_id = ? AND invalidated_at IS NULL", accountID).
+		Update("invalidated_at", invalidatedAt).Error
+}
+
+func (r *AccountRepo) CreateAPIKey(ctx context.Context, key *domain.APIKey) (*domain.APIKey, error) {
+	_, span := r.trace.Start(ctx, "CreateAPIKey")
+	defer span.End()
+	err := r.conn(ctx).Create(key).Error
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *AccountRepo) GetAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	_, span := r.trace.Start(ctx, "GetAPIKeyByHash")
+	defer span.End()
+	var key domain.APIKey
+	err := database.Retry(ctx, func() error {
+		return r.conn(ctx).Where("key_hash = ?", keyHash).First(&key).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns the keys of the account that are not revoked, newest first
+func (r *AccountRepo) ListAPIKeys(ctx context.Context, accountID uint) ([]domain.APIKey, error) {
+	_, span := r.trace.Start(ctx, "ListAPIKeys")
+	defer span.End()
+	keys := []domain.APIKey{}
+	err := r.conn(ctx).
+		Where("account_id = ? AND revoked_at IS NULL", accountID).
+		Order("created_at DESC, id DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key of the account, it reports false when the key
+// does not exist, belongs to another account or is already revoked
+func (r *AccountRepo) RevokeAPIKey(ctx context.Context, accountID uint, id uint, revokedAt time.Time) (bool, error) {
+	_, span := r.trace.Start(ctx, "RevokeAPIKey")
+	defer span.End()
+	result := r.conn(ctx).Model(&domain.APIKey{}).
+		Where("id = ? AND account_id = ? AND revoked_at IS NULL", id, accountID).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *AccountRepo) RevokeAPIKeys(ctx context.Context, accountID uint, revokedAt time.Time) error {
+	_, span := r.trace.Start(ctx, "RevokeAPIKeys")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.APIKey{}).
+		Where("account_id = ? AND revoked_at IS NULL", accountID).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *AccountRepo) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error {
+	_, span := r.trace.Start(ctx, "TouchAPIKey")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
+
+func (r *AccountRepo) RevokeToken(ctx context.Context, token *domain.RevokedToken) error {
+	_, span := r.trace.Start(ctx, "RevokeToken")
+	defer span.End()
+	// revoking the same token twice is not an error
+	return r.conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+func (r *AccountRepo) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	_, span := r.trace.Start(ctx, "IsTokenRevoked")
+	defer span.End()
+	var count int64
+	err := database.Retry(ctx, func() error {
+		return r.conn(ctx).Model(&domain.RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *AccountRepo) PurgeExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error) {
+	_, span := r.trace.Start(ctx, "PurgeExpiredRevokedTokens")
+	defer span.End()
+	result := r.conn(ctx).Where("expires_at < ?", now).Delete(&domain.RevokedToken{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
 }
@@ -0,0 +1,424 @@
+package account_test
+
+import (
+	"context"
+	"errors"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/internal/organization"
+	"spsyncpro_api/pkg/database"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+)
+
+func TestAccountRepository_GetActivityHistory(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+
+	// five activities for account 1, one minute apart, and one for another account
+	for i := range 5 {
+		assert.NoError(t, db.Create(&domain.AccountActivity{
+			AccountID: 1,
+			Activity:  domain.ActivityLogin,
+			CreatedAt: now.Add(time.Duration(i-5) * time.Minute),
+		}).Error)
+	}
+	assert.NoError(t, db.Create(&domain.AccountActivity{AccountID: 2, Activity: domain.ActivityLogin, CreatedAt: now}).Error)
+
+	t.Run("should return an empty page for an account without activity", func(t *testing.T) {
+		activities, total, err := repository.GetActivityHistory(context.Background(), 3, time.Time{}, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, activities)
+		assert.NotNil(t, activities)
+		assert.Equal(t, int64(0), total)
+	})
+
+	t.Run("should order activity newest first", func(t *testing.T) {
+		activities, total, err := repository.GetActivityHistory(context.Background(), 1, time.Time{}, 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		assert.Len(t, activities, 5)
+		for i := 1; i < len(activities); i++ {
+			assert.True(t, activities[i-1].CreatedAt.After(activities[i].CreatedAt))
+		}
+	})
+
+	t.Run("should page through activity without overlap", func(t *testing.T) {
+		first, total, err := repository.GetActivityHistory(context.Background(), 1, time.Time{}, 2, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		assert.Len(t, first, 2)
+
+		last, total, err := repository.GetActivityHistory(context.Background(), 1, time.Time{}, 2, 4)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		assert.Len(t, last, 1)
+		assert.True(t, first[1].CreatedAt.After(last[0].CreatedAt))
+
+		beyond, total, err := repository.GetActivityHistory(context.Background(), 1, time.Time{}, 2, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		assert.Empty(t, beyond)
+	})
+
+	t.Run("should only count activity since the given time", func(t *testing.T) {
+		activities, total, err := repository.GetActivityHistory(context.Background(), 1, now.Add(-2*time.Minute), 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Len(t, activities, 2)
+	})
+}
+
+func TestAccountRepository_CountActiveRefreshTokens(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+	for i, token := range []domain.RefreshToken{
+		{AccountID: 1, ExpiresAt: now.Add(time.Hour)},
+		{AccountID: 2, ExpiresAt: now.Add(time.Hour)},
+		{AccountID: 1, ExpiresAt: now.Add(-time.Hour)},
+		{AccountID: 1, ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt},
+	} {
+		token.TokenHash = utils.HashToken(strconv.Itoa(i))
+		assert.NoError(t, db.Create(&token).Error)
+	}
+
+	count, err := repository.CountActiveRefreshTokens(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestAccountRepository_GetAccountByEmail(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should find an account stored before emails were normalized once migrated", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		legacy := domain.Account{Email: "Legacy@Example.com"}
+		assert.NoError(t, db.Create(&legacy).Error)
+
+		_, err := database.MigrateUp(context.Background(), db)
+		assert.NoError(t, err)
+
+		acc, err := repository.GetAccountByEmail(context.Background(), "legacy@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, legacy.ID, acc.ID)
+
+		_, err = repository.GetAccountByEmail(context.Background(), "other@example.com")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestAccountRepository_DeleteAccount(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should delete the owned organization with its secret", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		owner := domain.Account{Email: "owner@example.com"}
+		other := domain.Account{Email: "other@example.com"}
+		assert.NoError(t, db.Create(&owner).Error)
+		assert.NoError(t, db.Create(&other).Error)
+		assert.NoError(t, db.Create(&domain.Organization{OwnerID: owner.ID, Name: "owned", ClientSecret: "encrypted"}).Error)
+		assert.NoError(t, db.Create(&domain.Organization{OwnerID: other.ID, Name: "other", ClientSecret: "encrypted"}).Error)
+
+		err := repository.DeleteAccount(context.Background(), owner.ID)
+		assert.NoError(t, err)
+
+		_, err = repository.GetAccountByID(context.Background(), owner.ID)
+		assert.Error(t, err)
+
+		var deleted domain.Organization
+		assert.NoError(t, db.Unscoped().Where("owner_id = ?", owner.ID).First(&deleted).Error)
+		assert.True(t, deleted.DeletedAt.Valid)
+		assert.Empty(t, deleted.ClientSecret)
+
+		var kept domain.Organization
+		assert.NoError(t, db.Where("owner_id = ?", other.ID).First(&kept).Error)
+		assert.Equal(t, "encrypted", kept.ClientSecret)
+	})
+
+	t.Run("should delete an account without an organization", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		acc := domain.Account{Email: "solo@example.com"}
+		assert.NoError(t, db.Create(&acc).Error)
+
+		assert.NoError(t, repository.DeleteAccount(context.Background(), acc.ID))
+
+		var count int64
+		db.Model(&domain.Account{}).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestAccountRepository_AnonymizeAccount(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db := newTestDB(t)
+	repository := account.NewAccountRepository(db)
+
+	acc := domain.Account{Email: "private@example.com", Password: "hashed", EmailVerified: true}
+	other := domain.Account{Email: "other@example.com", Password: "hashed"}
+	assert.NoError(t, db.Create(&acc).Error)
+	assert.NoError(t, db.Create(&other).Error)
+
+	for _, id := range []uint{acc.ID, acc.ID, other.ID} {
+		assert.NoError(t, db.Create(&domain.AccountActivity{AccountID: id, Activity: domain.ActivityLogin}).Error)
+		assert.NoError(t, db.Create(&domain.RefreshToken{AccountID: id, TokenHash: utils.HashToken(time.Now().String()), ExpiresAt: time.Now().Add(time.Hour)}).Error)
+	}
+	assert.NoError(t, db.Create(&domain.APIKey{AccountID: acc.ID, Name: "ci", KeyHash: "key-hash"}).Error)
+	assert.NoError(t, db.Create(&domain.PasswordResetToken{AccountID: acc.ID, TokenHash: "reset-hash", ExpiresAt: time.Now().Add(time.Hour)}).Error)
+	assert.NoError(t, db.Create(&domain.PendingAccountAction{AccountID: acc.ID, Action: domain.PendingActionChangeEmail, Payload: "new@example.com", CancelToken: "cancel"}).Error)
+	assert.NoError(t, db.Create(&domain.EmailLog{AccountID: acc.ID, Recipient: "private@example.com", Template: "verify_email"}).Error)
+	assert.NoError(t, db.Create(&domain.Organization{OwnerID: acc.ID, Name: "owned", ClientSecret: "encrypted"}).Error)
+
+	anonymizedAt := time.Now().UTC().Truncate(time.Second)
+	assert.NoError(t, repository.AnonymizeAccount(context.Background(), acc.ID, anonymizedAt))
+
+	t.Run("should keep the row without personal data", func(t *testing.T) {
+		stored, err := repository.GetAccountByID(context.Background(), acc.ID)
+		assert.NoError(t, err)
+		assert.NotContains(t, stored.Email, "private")
+		assert.True(t, strings.HasSuffix(stored.Email, "@anonymized.invalid"))
+		assert.Empty(t, stored.Password)
+		assert.False(t, stored.EmailVerified)
+		assert.NotNil(t, stored.AnonymizedAt)
+		assert.True(t, anonymizedAt.Equal(*stored.AnonymizedAt))
+		assert.NotNil(t, stored.DisabledAt)
+
+		_, err = repository.GetAccountByEmail(context.Background(), "private@example.com")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		var logs []domain.EmailLog
+		assert.NoError(t, db.Where("account_id = ?", acc.ID).Find(&logs).Error)
+		assert.Len(t, logs, 1)
+		assert.Equal(t, stored.Email, logs[0].Recipient)
+	})
+
+	t.Run("should remove credentials and tokens", func(t *testing.T) {
+		for _, model := range []any{&domain.RefreshToken{}, &domain.APIKey{}, &domain.PasswordResetToken{}, &domain.PendingAccountAction{}} {
+			var count int64
+			assert.NoError(t, db.Unscoped().Model(model).Where("account_id = ?", acc.ID).Count(&count).Error)
+			assert.Zero(t, count, "%T", model)
+		}
+
+		var organization domain.Organization
+		assert.NoError(t, db.Unscoped().Where("owner_id = ?", acc.ID).First(&organization).Error)
+		assert.True(t, organization.DeletedAt.Valid)
+		assert.Empty(t, organization.ClientSecret)
+	})
+
+	t.Run("should keep the activity counts", func(t *testing.T) {
+		count, err := repository.CountActivitiesSince(context.Background(), []string{domain.ActivityLogin}, time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		_, total, err := repository.GetActivityHistory(context.Background(), acc.ID, time.Time{}, 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+	})
+
+	t.Run("should leave other accounts alone", func(t *testing.T) {
+		stored, err := repository.GetAccountByID(context.Background(), other.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "other@example.com", stored.Email)
+		assert.Nil(t, stored.AnonymizedAt)
+
+		var tokens int64
+		assert.NoError(t, db.Model(&domain.RefreshToken{}).Where("account_id = ?", other.ID).Count(&tokens).Error)
+		assert.Equal(t, int64(1), tokens)
+	})
+
+	t.Run("should report an unknown account", func(t *testing.T) {
+		err := repository.AnonymizeAccount(context.Background(), 999, anonymizedAt)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestRepositories_WithTransaction(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	errStep := errors.New("step failed")
+
+	// register runs the steps of a registration across two repositories, the
+	// last step fails when fail is set
+	register := func(ctx context.Context, db *gorm.DB, email string, fail bool) error {
+		accountRepository := account.NewAccountRepository(db)
+		organizationRepository := organization.NewOrganizationRepository(db)
+
+		acc, err := accountRepository.CreateAccount(ctx, &domain.Account{Email: email})
+		if err != nil {
+			return err
+		}
+		if err := accountRepository.LogAccountActivity(ctx, acc.ID, domain.ActivityRegister); err != nil {
+			return err
+		}
+		if _, err := organizationRepository.UpsertOrganization(ctx, &domain.Organization{OwnerID: acc.ID, Name: "default"}); err != nil {
+			return err
+		}
+		if fail {
+			return errStep
+		}
+		return nil
+	}
+
+	count := func(t *testing.T, db *gorm.DB, model any) int64 {
+		var n int64
+		assert.NoError(t, db.Model(model).Count(&n).Error)
+		return n
+	}
+
+	t.Run("should roll back every step on a failure mid-way", func(t *testing.T) {
+		db := newTestDB(t)
+
+		err := database.NewDB(db).WithTransaction(context.Background(), func(ctx context.Context) error {
+			return register(ctx, db, "test@example.com", true)
+		})
+		assert.ErrorIs(t, err, errStep)
+
+		assert.Zero(t, count(t, db, &domain.Account{}))
+		assert.Zero(t, count(t, db, &domain.AccountActivity{}))
+		assert.Zero(t, count(t, db, &domain.Organization{}))
+	})
+
+	t.Run("should commit every step", func(t *testing.T) {
+		db := newTestDB(t)
+
+		err := database.NewDB(db).WithTransaction(context.Background(), func(ctx context.Context) error {
+			return register(ctx, db, "test@example.com", false)
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(1), count(t, db, &domain.Account{}))
+		assert.Equal(t, int64(1), count(t, db, &domain.AccountActivity{}))
+		assert.Equal(t, int64(1), count(t, db, &domain.Organization{}))
+	})
+
+	t.Run("should roll back a nested transaction only", func(t *testing.T) {
+		db := newTestDB(t)
+		transactor := database.NewDB(db)
+
+		err := transactor.WithTransaction(context.Background(), func(ctx context.Context) error {
+			if err := register(ctx, db, "outer@example.com", false); err != nil {
+				return err
+			}
+			err := transactor.WithTransaction(ctx, func(ctx context.Context) error {
+				return register(ctx, db, "inner@example.com", true)
+			})
+			assert.ErrorIs(t, err, errStep)
+			return nil
+		})
+		assert.NoError(t, err)
+
+		repository := account.NewAccountRepository(db)
+		_, err = repository.GetAccountByEmail(context.Background(), "outer@example.com")
+		assert.NoError(t, err)
+		_, err = repository.GetAccountByEmail(context.Background(), "inner@example.com")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestRepositories_RetryTransient(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	policy := database.DefaultRetryPolicy
+	database.DefaultRetryPolicy = utils.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	defer func() { database.DefaultRetryPolicy = policy }()
+
+	// failQueries fails the next n queries with err, like a postgres that
+	// aborted the statement, and counts every query run
+	failQueries := func(t *testing.T, db *gorm.DB, n int, err error) *int {
+		queries := 0
+		assert.NoError(t, db.Callback().Query().Before("gorm:query").Register("test:fail", func(tx *gorm.DB) {
+			queries++
+			if queries <= n {
+				_ = tx.AddError(err)
+			}
+		}))
+		return &queries
+	}
+
+	t.Run("should retry a read after a serialization failure", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+		acc, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com"})
+		assert.NoError(t, err)
+
+		queries := failQueries(t, db, 1, &pgconn.PgError{Code: "40001"})
+
+		found, err := repository.GetAccountByID(context.Background(), acc.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, acc.ID, found.ID)
+		assert.Equal(t, 2, *queries)
+	})
+
+	t.Run("should give up after the attempts", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		queries := failQueries(t, db, 10, &pgconn.PgError{Code: "40P01"})
+
+		_, err := repository.GetAccountByEmail(context.Background(), "test@example.com")
+		var pgErr *pgconn.PgError
+		assert.ErrorAs(t, err, &pgErr)
+		assert.Equal(t, 3, *queries)
+	})
+
+	t.Run("should not retry other failures", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		queries := failQueries(t, db, 10, &pgconn.PgError{Code: "23505"})
+
+		_, err := repository.GetAccountByEmail(context.Background(), "test@example.com")
+		assert.Error(t, err)
+		assert.Equal(t, 1, *queries)
+	})
+
+	t.Run("should retry the whole transaction and not its statements", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+		acc, err := repository.CreateAccount(context.Background(), &domain.Account{Email: "test@example.com"})
+		assert.NoError(t, err)
+
+		queries := failQueries(t, db, 1, &pgconn.PgError{Code: "40001"})
+
+		runs := 0
+		err = database.NewDB(db).WithTransaction(context.Background(), func(ctx context.Context) error {
+			runs++
+			_, err := repository.GetAccountByID(ctx, acc.ID)
+			return err
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, runs)
+		assert.Equal(t, 2, *queries)
+	})
+}
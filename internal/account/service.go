@@ -4,13 +4,19 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/rand"
+	"crypto/rsa"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
+	"os"
 	"spsyncpro_api/pkg/domain"
 	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/utils"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -22,21 +28,81 @@ import (
 
 var (
 	ErrFailedToGenerateSalt = errors.New("failed to generate salt")
+	ErrSaltTooShort         = errors.New("salt length is below the safe minimum")
+	ErrResetTokenTooShort   = errors.New("reset token length is below the safe minimum")
 	ErrJWTSecretNotSet      = errors.New("jwt secret is not set")
 	ErrSubjectClaimNotFound = errors.New("subject claim not found in token")
 	ErrInvalidSubjectClaim  = errors.New("invalid subject claim type")
+	ErrKeyStoreNotSet       = errors.New("signing key store is not configured")
+
+	ErrUnsupportedJWTAlgorithm = errors.New("unsupported jwt algorithm")
+	ErrJWTKeyNotSet            = errors.New("jwt private key path is not set")
+	ErrKeyRotationUnsupported  = errors.New("signing key rotation is only supported for HS256")
+)
+
+const (
+	defaultSaltLen = 16
+	minSaltLen     = 8
+
+	defaultResetTokenBytes = 32
+	minResetTokenBytes     = 16
+
+	resetTokenFormatJWT    = "jwt"
+	resetTokenFormatOpaque = "opaque"
+
+	defaultArgon2Memory  = 64 * 1024 // 64 MB
+	defaultArgon2Time    = 1
+	defaultArgon2Threads = 4
+
+	AuthTokenExpiry                = time.Hour * 24
+	defaultPasswordResetExpiry     = time.Hour
+	defaultRefreshTokenExpiry      = time.Hour * 24
+	defaultRememberMeExpiry        = time.Hour * 24 * 30
+	defaultEmailVerificationExpiry = time.Hour * 48
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+
+	// tokenIssuer is the iss claim of every token the service signs
+	tokenIssuer = "spsyncpro_api"
 )
 
 type AccountService struct {
 	tracer       trace.Tracer
 	emailService mailer.EmailService
+	keyStore     *KeyStore
+	hashPool     *HashPool
+	clock        utils.Clock
+
+	rsaMu   sync.Mutex
+	rsaKeys rsaKeyCache
+}
+
+// rsaKeyCache holds the parsed RS256 keys for the paths they were loaded from
+type rsaKeyCache struct {
+	privatePath string
+	private     *rsa.PrivateKey
+	publicPath  string
+	public      *rsa.PublicKey
+}
+
+// NewAccountService creates the account service, auth tokens are signed with
+// JWT_SECRET until a key is rotated into the key store, keyStore may be nil.
+// Password hashes run on a HashPool sized from the ARGON2_* config
+func NewAccountService(emailService mailer.EmailService, keyStore *KeyStore) domain.AccountService {
+	return NewAccountServiceWithClock(emailService, keyStore, utils.RealClock{})
 }
 
-func NewAccountService(emailService mailer.EmailService) domain.AccountService {
+// NewAccountServiceWithClock creates the account service with the clock
+// tokens are issued and validated against
+func NewAccountServiceWithClock(emailService mailer.EmailService, keyStore *KeyStore, clock utils.Clock) domain.AccountService {
 	tracer := otel.Tracer("accountService")
 	return &AccountService{
 		tracer:       tracer,
 		emailService: emailService,
+		keyStore:     keyStore,
+		hashPool:     NewHashPoolFromConfig(),
+		clock:        clock,
 	}
 }
 
@@ -53,21 +119,35 @@ func (s *AccountService) HashPassword(ctx context.Context, password string) (str
 
 	// Argon2id parameters
 	var (
-		memory  uint32 = 64 * 1024 // 64 MB
-		time    uint32 = 1         // 1 iteration
-		threads uint8  = 4         // 4 threads
-		keyLen  uint32 = 32        // 32 bytes
-		saltLen int    = 16        // 16 bytes
+		params         = currentArgon2Params()
+		memory         = params.memory
+		time           = params.time
+		threads        = params.threads
+		keyLen  uint32 = 32 // 32 bytes
+		saltLen int    = defaultSaltLen
 	)
 
+	if viper.IsSet("ARGON2_SALT_LEN") {
+		saltLen = viper.GetInt("ARGON2_SALT_LEN")
+	}
+	if saltLen < minSaltLen {
+		return "", fmt.Errorf("%w: got %d bytes, need at least %d", ErrSaltTooShort, saltLen, minSaltLen)
+	}
+
 	// Generate a random salt
 	salt := make([]byte, saltLen)
 	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("%w: %w", ErrFailedToGenerateSalt, err)
 	}
 
-	// Hash the password using Argon2id
-	hash := argon2.IDKey([]byte(password), salt, time, memory, threads, keyLen)
+	// Hash the password using Argon2id on a worker of the pool
+	var hash []byte
+	err := s.hashPool.Run(ctx, func() {
+		hash = argon2.IDKey([]byte(password), salt, time, memory, threads, keyLen)
+	})
+	if err != nil {
+		return "", err
+	}
 
 	// Encode salt and hash to base64 for storage
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
@@ -79,7 +159,38 @@ func (s *AccountService) HashPassword(ctx context.Context, password string) (str
 	return encoded, nil
 }
 
-func (s *AccountService) ComparePassword(ctx context.Context, password, hash string) (bool, error) {
+// argon2Params are the tunable Argon2id cost parameters
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// currentArgon2Params reads ARGON2_MEMORY (KiB), ARGON2_TIME and ARGON2_THREADS,
+// unset or invalid values fall back to 64MB, t=1, p=4
+func currentArgon2Params() argon2Params {
+	params := argon2Params{
+		memory:  defaultArgon2Memory,
+		time:    defaultArgon2Time,
+		threads: defaultArgon2Threads,
+	}
+
+	if memory := viper.GetUint32("ARGON2_MEMORY"); memory > 0 {
+		params.memory = memory
+	}
+	if time := viper.GetUint32("ARGON2_TIME"); time > 0 {
+		params.time = time
+	}
+	if threads := viper.GetUint("ARGON2_THREADS"); threads > 0 && threads <= math.MaxUint8 {
+		params.threads = uint8(threads)
+	}
+
+	return params
+}
+
+// ComparePassword verifies password against hash, needsRehash reports that the
+// hash was created with parameters other than the current configuration
+func (s *AccountService) ComparePassword(ctx context.Context, password, hash string) (bool, bool, error) {
 	ctx, span := s.tracer.Start(ctx, "ComparePassword")
 	defer span.End()
 
@@ -87,153 +198,514 @@ func (s *AccountService) ComparePassword(ctx context.Context, password, hash str
 	// Expected format: $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
 	parts := strings.Split(hash, "$")
 	if len(parts) != 6 {
-		return false, domain.ErrInvalidHashFormat
+		return false, false, domain.ErrInvalidHashFormat
 	}
 
 	// Validate the algorithm and version
 	if parts[1] != "argon2id" {
-		return false, domain.ErrInvalidHashFormat
+		return false, false, domain.ErrInvalidHashFormat
 	}
 
 	// Parse the parameters from the third part: m=65536,t=1,p=4
 	params := strings.Split(parts[3], ",")
 	if len(params) != 3 {
-		return false, domain.ErrInvalidHashFormat
+		return false, false, domain.ErrInvalidHashFormat
 	}
 
 	// Extract memory parameter
 	memoryStr := strings.TrimPrefix(params[0], "m=")
 	memory, err := strconv.ParseUint(memoryStr, 10, 32)
 	if err != nil {
-		return false, domain.ErrInvalidHashFormat
+		return false, false, domain.ErrInvalidHashFormat
 	}
 
 	// Extract time parameter
 	timeStr := strings.TrimPrefix(params[1], "t=")
 	time, err := strconv.ParseUint(timeStr, 10, 32)
 	if err != nil {
-		return false, domain.ErrInvalidHashFormat
+		return false, false, domain.ErrInvalidHashFormat
 	}
 
 	// Extract threads parameter
 	threadsStr := strings.TrimPrefix(params[2], "p=")
 	threads, err := strconv.ParseUint(threadsStr, 10, 32)
 	if err != nil {
-		return false, domain.ErrInvalidHashFormat
+		return false, false, domain.ErrInvalidHashFormat
 	}
 
 	// Extract the salt and hash (parts[4] and parts[5])
 	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, domain.ErrInvalidHashFormat
+		return false, false, domain.ErrInvalidHashFormat
 	}
 
 	hashBytes, err := base64.RawStdEncoding.DecodeString(parts[5])
 	if err != nil {
-		return false, domain.ErrInvalidHashFormat
+		return false, false, domain.ErrInvalidHashFormat
 	}
 
 	// Use the same keyLen as in HashPassword (32 bytes)
 	keyLen := uint32(32)
 
-	// Verify the password
-	computedHash := argon2.IDKey([]byte(password), salt, uint32(time), uint32(memory), uint8(threads), keyLen)
+	// Verify the password on a worker of the pool
+	var computedHash []byte
+	err = s.hashPool.Run(ctx, func() {
+		computedHash = argon2.IDKey([]byte(password), salt, uint32(time), uint32(memory), uint8(threads), keyLen)
+	})
+	if err != nil {
+		return false, false, err
+	}
 
 	// Compare the computed hash with the stored hash
-	return hmac.Equal(hashBytes, computedHash), nil
+	if !hmac.Equal(hashBytes, computedHash) {
+		return false, false, nil
+	}
+
+	current := currentArgon2Params()
+	needsRehash := uint32(memory) != current.memory ||
+		uint32(time) != current.time ||
+		uint8(threads) != current.threads
+
+	return true, needsRehash, nil
 }
 
 func (s *AccountService) GenerateAuthToken(ctx context.Context, account *domain.Account) (string, error) {
 	ctx, span := s.tracer.Start(ctx, "GenerateAuthToken")
 	defer span.End()
 
+	// the jti identifies the token for revocation on logout
+	jti, err := utils.GenerateToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := s.clock.Now()
+	claims := jwt.MapClaims{
+		"sub":  account.ID,
+		"iss":  tokenIssuer,
+		"iat":  now.Unix(),
+		"exp":  now.Add(AuthTokenExpiry).Unix(),
+		"typ":  tokenTypeAccess,
+		"jti":  jti,
+		"role": accountRole(account),
+	}
+
+	// the token is not accepted before the offset has passed
+	if offset := viper.GetDuration("JWT_NOT_BEFORE_OFFSET"); offset != 0 {
+		claims["nbf"] = now.Add(offset).Unix()
+	}
+
+	// embedding PII in the token is opt-in
+	if viper.GetBool("JWT_INCLUDE_EMAIL") {
+		claims["email"] = account.Email
+	}
+
+	return s.signToken(ctx, claims)
+}
+
+// accountRole is the role of an account, user when it has none
+func accountRole(account *domain.Account) string {
+	if account.Role == "" {
+		return domain.RoleUser
+	}
+	return account.Role
+}
+
+// jwtAlgorithm returns the configured JWT_ALGORITHM, HS256 unless set
+func jwtAlgorithm() (string, error) {
+	algorithm := viper.GetString("JWT_ALGORITHM")
+	switch algorithm {
+	case "":
+		return jwt.SigningMethodHS256.Alg(), nil
+	case jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg():
+		return algorithm, nil
+	default:
+		return "", ErrUnsupportedJWTAlgorithm
+	}
+}
+
+// signToken signs claims with the configured algorithm
+func (s *AccountService) signToken(ctx context.Context, claims jwt.MapClaims) (string, error) {
+	algorithm, err := jwtAlgorithm()
+	if err != nil {
+		return "", err
+	}
+
+	if algorithm == jwt.SigningMethodRS256.Alg() {
+		privateKey, err := s.rsaPrivateKey()
+		if err != nil {
+			return "", err
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+	}
+
+	secret, kid, err := s.signingKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	return token.SignedString(secret)
+}
+
+// parseToken verifies token with the configured algorithm, tokens signed with
+// any other algorithm are rejected to prevent alg confusion. The exp and nbf
+// claims are checked against the service clock with a JWT_LEEWAY tolerance for
+// clock skew, an expired token is domain.ErrTokenExpired so clients can tell
+// it apart and refresh. Tokens from any issuer but this service are rejected.
+func (s *AccountService) parseToken(ctx context.Context, token string) (jwt.MapClaims, error) {
+	algorithm, err := jwtAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if algorithm == jwt.SigningMethodRS256.Alg() {
+			return s.rsaPublicKey()
+		}
+		return s.verificationKey(ctx, token)
+	},
+		jwt.WithValidMethods([]string{algorithm}),
+		jwt.WithLeeway(max(viper.GetDuration("JWT_LEEWAY"), 0)),
+		jwt.WithTimeFunc(s.clock.Now),
+		jwt.WithIssuer(tokenIssuer),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, fmt.Errorf("%w: %w", domain.ErrTokenExpired, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.Claims.(jwt.MapClaims), nil
+}
+
+func (s *AccountService) rsaPrivateKey() (*rsa.PrivateKey, error) {
+	path := viper.GetString("JWT_PRIVATE_KEY_PATH")
+	if path == "" {
+		return nil, ErrJWTKeyNotSet
+	}
+
+	s.rsaMu.Lock()
+	defer s.rsaMu.Unlock()
+
+	if s.rsaKeys.privatePath == path && s.rsaKeys.private != nil {
+		return s.rsaKeys.private, nil
+	}
+
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	s.rsaKeys.privatePath = path
+	s.rsaKeys.private = privateKey
+
+	return privateKey, nil
+}
+
+// rsaPublicKey loads JWT_PUBLIC_KEY_PATH, falling back to the public half of the private key
+func (s *AccountService) rsaPublicKey() (*rsa.PublicKey, error) {
+	path := viper.GetString("JWT_PUBLIC_KEY_PATH")
+	if path == "" {
+		privateKey, err := s.rsaPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		return &privateKey.PublicKey, nil
+	}
+
+	s.rsaMu.Lock()
+	defer s.rsaMu.Unlock()
+
+	if s.rsaKeys.publicPath == path && s.rsaKeys.public != nil {
+		return s.rsaKeys.public, nil
+	}
+
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	s.rsaKeys.publicPath = path
+	s.rsaKeys.public = publicKey
+
+	return publicKey, nil
+}
+
+// signingKey returns the current key from the key store, falling back to JWT_SECRET
+func (s *AccountService) signingKey(ctx context.Context) ([]byte, string, error) {
+	if s.keyStore != nil {
+		key, secret, err := s.keyStore.Current(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if key != nil {
+			return secret, key.Kid, nil
+		}
+	}
+
 	jwtSecret := viper.GetString("JWT_SECRET")
 	if jwtSecret == "" {
-		return "", ErrJWTSecretNotSet
+		return nil, "", ErrJWTSecretNotSet
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": account.ID,
-		"iss": "spsyncpro_api",
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-	})
+	return []byte(jwtSecret), "", nil
+}
+
+// verificationKey resolves the key a token was signed with from its kid header,
+// tokens without a kid were signed with JWT_SECRET
+func (s *AccountService) verificationKey(ctx context.Context, token *jwt.Token) ([]byte, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		jwtSecret := viper.GetString("JWT_SECRET")
+		if jwtSecret == "" {
+			return nil, ErrJWTSecretNotSet
+		}
+		return []byte(jwtSecret), nil
+	}
 
-	return token.SignedString([]byte(jwtSecret))
+	if s.keyStore == nil {
+		return nil, domain.ErrSigningKeyNotFound
+	}
+
+	return s.keyStore.Lookup(ctx, kid, s.clock.Now())
+}
+
+func (s *AccountService) RotateSigningKey(ctx context.Context) (*domain.KeyRotation, error) {
+	ctx, span := s.tracer.Start(ctx, "RotateSigningKey")
+	defer span.End()
+
+	if s.keyStore == nil {
+		return nil, ErrKeyStoreNotSet
+	}
+
+	// the key store only holds HS256 secrets
+	if algorithm, err := jwtAlgorithm(); err != nil || algorithm != jwt.SigningMethodHS256.Alg() {
+		return nil, ErrKeyRotationUnsupported
+	}
+
+	return s.keyStore.Rotate(ctx)
 }
 
 func (s *AccountService) ValidateAuthToken(ctx context.Context, token string) (uint, error) {
 	ctx, span := s.tracer.Start(ctx, "ValidateAuthToken")
 	defer span.End()
 
-	jwtSecret := viper.GetString("JWT_SECRET")
-	if jwtSecret == "" {
-		return 0, ErrJWTSecretNotSet
+	claims, err := s.ParseClaims(ctx, token)
+	if err != nil {
+		return 0, err
 	}
 
-	claims, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+	return claims.AccountID, nil
+}
+
+func (s *AccountService) ParseClaims(ctx context.Context, token string) (*domain.AuthClaims, error) {
+	ctx, span := s.tracer.Start(ctx, "ParseClaims")
+	defer span.End()
+
+	mapClaims, err := s.parseToken(ctx, token)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	// tokens issued before the typ claim was introduced are access tokens
+	if typ, ok := mapClaims["typ"].(string); ok && typ != tokenTypeAccess {
+		return nil, domain.ErrInvalidTokenType
 	}
 
 	// Extract the subject claim and convert from float64 (JSON number) to uint
-	subClaim, ok := claims.Claims.(jwt.MapClaims)["sub"]
+	subClaim, ok := mapClaims["sub"]
 	if !ok {
-		return 0, ErrSubjectClaimNotFound
+		return nil, ErrSubjectClaimNotFound
 	}
 
 	// Convert float64 to uint (JWT library returns JSON numbers as float64)
 	accountIDFloat, ok := subClaim.(float64)
+	if !ok {
+		return nil, ErrInvalidSubjectClaim
+	}
+
+	authClaims := &domain.AuthClaims{
+		AccountID: uint(accountIDFloat),
+	}
+
+	if email, ok := mapClaims["email"].(string); ok {
+		authClaims.Email = email
+	}
+
+	if jti, ok := mapClaims["jti"].(string); ok {
+		authClaims.TokenID = jti
+	}
+
+	// tokens issued before roles were introduced carry no role
+	authClaims.Role = domain.RoleUser
+	if role, ok := mapClaims["role"].(string); ok && role != "" {
+		authClaims.Role = role
+	}
+
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		authClaims.ExpiresAt = exp.Time
+	}
+
+	return authClaims, nil
+}
+
+// RefreshTokenExpiry returns the configured refresh token lifetime,
+// JWT_REFRESH_TTL_REMEMBER for logins that asked to be remembered and
+// JWT_REFRESH_EXPIRY otherwise
+func RefreshTokenExpiry(rememberMe bool) time.Duration {
+	if rememberMe {
+		expiry := viper.GetDuration("JWT_REFRESH_TTL_REMEMBER")
+		if expiry <= 0 {
+			return defaultRememberMeExpiry
+		}
+		return expiry
+	}
+
+	expiry := viper.GetDuration("JWT_REFRESH_EXPIRY")
+	if expiry <= 0 {
+		return defaultRefreshTokenExpiry
+	}
+	return expiry
+}
+
+func (s *AccountService) GenerateRefreshToken(ctx context.Context, account *domain.Account, rememberMe bool) (string, time.Time, error) {
+	ctx, span := s.tracer.Start(ctx, "GenerateRefreshToken")
+	defer span.End()
+
+	// the jti keeps tokens issued within the same second unique, they are stored by hash
+	jti, err := utils.GenerateToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := s.clock.Now()
+	expiresAt := now.Add(RefreshTokenExpiry(rememberMe))
+	token, err := s.signToken(ctx, jwt.MapClaims{
+		"sub": account.ID,
+		"iss": tokenIssuer,
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
+		"typ": tokenTypeRefresh,
+		"jti": jti,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+func (s *AccountService) ValidateRefreshToken(ctx context.Context, token string) (uint, error) {
+	ctx, span := s.tracer.Start(ctx, "ValidateRefreshToken")
+	defer span.End()
+
+	mapClaims, err := s.parseToken(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	if typ, _ := mapClaims["typ"].(string); typ != tokenTypeRefresh {
+		return 0, domain.ErrInvalidTokenType
+	}
+
+	subClaim, ok := mapClaims["sub"].(float64)
 	if !ok {
 		return 0, ErrInvalidSubjectClaim
 	}
 
-	return uint(accountIDFloat), nil
+	return uint(subClaim), nil
 }
 
 func (s *AccountService) GeneratePasswordResetToken(ctx context.Context, account *domain.Account) (string, error) {
 	ctx, span := s.tracer.Start(ctx, "GeneratePasswordResetToken")
 	defer span.End()
 
-	jwtSecret := viper.GetString("JWT_SECRET")
-	if jwtSecret == "" {
-		return "", ErrJWTSecretNotSet
+	if viper.GetString("PASSWORD_RESET_TOKEN_FORMAT") == resetTokenFormatOpaque {
+		return generateOpaqueResetToken()
+	}
+
+	// the jti keeps tokens issued within the same second unique, they are stored by hash
+	jti, err := utils.GenerateToken(16)
+	if err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	now := s.clock.Now()
+	return s.signToken(ctx, jwt.MapClaims{
 		"sub": strconv.FormatUint(uint64(account.ID), 10) + ":password-reset",
-		"iss": "spsyncpro_api",
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
+		"iss": tokenIssuer,
+		"iat": now.Unix(),
+		"exp": now.Add(passwordResetTokenExpiry()).Unix(),
+		"jti": jti,
 	})
+}
 
-	return token.SignedString([]byte(jwtSecret))
+// passwordResetTokenExpiry is PASSWORD_RESET_TOKEN_EXPIRY, kept short and
+// independent of the auth token expiry
+func passwordResetTokenExpiry() time.Duration {
+	expiry := viper.GetDuration("PASSWORD_RESET_TOKEN_EXPIRY")
+	if expiry <= 0 {
+		return defaultPasswordResetExpiry
+	}
+	return expiry
+}
+
+// generateOpaqueResetToken returns a random token of PASSWORD_RESET_TOKEN_BYTES,
+// it carries no claims so the account and expiry come from the stored record
+func generateOpaqueResetToken() (string, error) {
+	byteLen := defaultResetTokenBytes
+	if viper.IsSet("PASSWORD_RESET_TOKEN_BYTES") {
+		byteLen = viper.GetInt("PASSWORD_RESET_TOKEN_BYTES")
+	}
+	if byteLen < minResetTokenBytes {
+		return "", fmt.Errorf("%w: got %d bytes, need at least %d", ErrResetTokenTooShort, byteLen, minResetTokenBytes)
+	}
+
+	return utils.GenerateToken(byteLen)
+}
+
+// IsOpaqueResetToken reports whether token was issued by the opaque generator,
+// a signed token always has three dot separated parts
+func IsOpaqueResetToken(token string) bool {
+	return !strings.Contains(token, ".")
 }
 
 func (s *AccountService) ValidatePasswordResetToken(ctx context.Context, token string) (uint, error) {
 	ctx, span := s.tracer.Start(ctx, "ValidatePasswordResetToken")
 	defer span.End()
 
-	jwtSecret := viper.GetString("JWT_SECRET")
-	if jwtSecret == "" {
-		return 0, ErrJWTSecretNotSet
-	}
-
-	claims, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+	claims, err := s.parseToken(ctx, token)
 	if err != nil {
 		return 0, err
 	}
 
-	subClaim, ok := claims.Claims.(jwt.MapClaims)["sub"]
+	subClaim, ok := claims["sub"]
 	if !ok {
 		return 0, ErrSubjectClaimNotFound
 	}
 
-	parts := strings.Split(subClaim.(string), ":")
+	sub, ok := subClaim.(string)
+	if !ok {
+		return 0, ErrInvalidSubjectClaim
+	}
+
+	parts := strings.Split(sub, ":")
 	if len(parts) != 2 {
 		return 0, ErrInvalidSubjectClaim
 	}
@@ -250,26 +722,342 @@ func (s *AccountService) ValidatePasswordResetToken(ctx context.Context, token s
 	return uint(accountID), nil
 }
 
-func (s *AccountService) SendPasswordResetEmail(ctx context.Context, email string, token string) error {
-	ctx, span := s.tracer.Start(ctx, "SendPasswordResetEmail")
+// GenerateEmailVerificationToken issues a token bound to the current email, so a
+// token issued before an email change cannot verify the new address
+func (s *AccountService) GenerateEmailVerificationToken(ctx context.Context, account *domain.Account) (string, error) {
+	ctx, span := s.tracer.Start(ctx, "GenerateEmailVerificationToken")
 	defer span.End()
 
+	expiry := viper.GetDuration("EMAIL_VERIFICATION_EXPIRY")
+	if expiry <= 0 {
+		expiry = defaultEmailVerificationExpiry
+	}
+
+	now := s.clock.Now()
+	return s.signToken(ctx, jwt.MapClaims{
+		"sub":   strconv.FormatUint(uint64(account.ID), 10) + ":email-verification",
+		"email": account.Email,
+		"iss":   tokenIssuer,
+		"iat":   now.Unix(),
+		"exp":   now.Add(expiry).Unix(),
+	})
+}
+
+// ValidateEmailVerificationToken returns the account id and the email the token was issued for
+func (s *AccountService) ValidateEmailVerificationToken(ctx context.Context, token string) (uint, string, error) {
+	ctx, span := s.tracer.Start(ctx, "ValidateEmailVerificationToken")
+	defer span.End()
+
+	claims, err := s.parseToken(ctx, token)
+	if err != nil {
+		return 0, "", err
+	}
+
+	sub, ok := claims["sub"].(string)
+	if !ok {
+		return 0, "", ErrInvalidSubjectClaim
+	}
+
+	parts := strings.Split(sub, ":")
+	if len(parts) != 2 || parts[1] != "email-verification" {
+		return 0, "", ErrInvalidSubjectClaim
+	}
+
+	accountID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+
+	email, _ := claims["email"].(string)
+
+	return uint(accountID), email, nil
+}
+
+// GenerateEmailChangeToken issues a token confirming newEmail for the account,
+// it stops working once the current email changes
+func (s *AccountService) GenerateEmailChangeToken(ctx context.Context, account *domain.Account, newEmail string) (string, error) {
+	ctx, span := s.tracer.Start(ctx, "GenerateEmailChangeToken")
+	defer span.End()
+
+	expiry := viper.GetDuration("EMAIL_VERIFICATION_EXPIRY")
+	if expiry <= 0 {
+		expiry = defaultEmailVerificationExpiry
+	}
+
+	now := s.clock.Now()
+	return s.signToken(ctx, jwt.MapClaims{
+		"sub":       strconv.FormatUint(uint64(account.ID), 10) + ":email-change",
+		"email":     account.Email,
+		"new_email": newEmail,
+		"iss":       tokenIssuer,
+		"iat":       now.Unix(),
+		"exp":       now.Add(expiry).Unix(),
+	})
+}
+
+// ValidateEmailChangeToken returns the account id, the email the token was
+// issued from and the email it confirms
+func (s *AccountService) ValidateEmailChangeToken(ctx context.Context, token string) (uint, string, string, error) {
+	ctx, span := s.tracer.Start(ctx, "ValidateEmailChangeToken")
+	defer span.End()
+
+	claims, err := s.parseToken(ctx, token)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	sub, ok := claims["sub"].(string)
+	if !ok {
+		return 0, "", "", ErrInvalidSubjectClaim
+	}
+
+	parts := strings.Split(sub, ":")
+	if len(parts) != 2 || parts[1] != "email-change" {
+		return 0, "", "", ErrInvalidSubjectClaim
+	}
+
+	accountID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	email, _ := claims["email"].(string)
+	newEmail, _ := claims["new_email"].(string)
+	if newEmail == "" {
+		return 0, "", "", ErrInvalidSubjectClaim
+	}
+
+	return uint(accountID), email, newEmail, nil
+}
+
+// requireHTTPSLinks reports whether emailed links must use https,
+// REQUIRE_HTTPS_LINKS when set and on in production mode otherwise
+func requireHTTPSLinks() bool {
+	if viper.GetString("REQUIRE_HTTPS_LINKS") != "" {
+		return viper.GetBool("REQUIRE_HTTPS_LINKS")
+	}
+	return viper.GetString("SERVER_MODE") == "production"
+}
+
+// CheckServerURL validates SERVER_URL and FRONTEND_URL as the bases of
+// emailed links, a plaintext url is rejected when https links are required
+func CheckServerURL() error {
 	serverUrl := viper.GetString("SERVER_URL")
 	if serverUrl == "" {
 		return domain.ErrServerURLNotSet
 	}
-	link := serverUrl + "/api/v1/account/reset-password?token=" + token
+	if err := checkLinkBase(serverUrl); err != nil {
+		return err
+	}
+	return checkLinkBase(frontendURL())
+}
 
-	resetPasswordTemplate := `
-		<html>
-		<body>
-			<h1>Password Reset Request</h1>
-			<p><a href="` + link + `">Click here to reset your password</a></p>
-			<p>If you did not request a password reset, please ignore this email.</p>
-			<p>Thank you for using our service.</p>
-		</body>
-		</html>
-	`
+func checkLinkBase(baseUrl string) error {
+	base, err := utils.NormalizeBaseURL(baseUrl)
+	if err != nil {
+		return err
+	}
+	if requireHTTPSLinks() && !strings.HasPrefix(base, "https://") {
+		return fmt.Errorf("%w: %s", domain.ErrInsecureServerURL, base)
+	}
+	return nil
+}
+
+// frontendURL is the base of the pages emailed links open, FRONTEND_URL or
+// SERVER_URL when the frontend is served from the same host
+func frontendURL() string {
+	if frontendUrl := viper.GetString("FRONTEND_URL"); frontendUrl != "" {
+		return frontendUrl
+	}
+	return viper.GetString("SERVER_URL")
+}
+
+// frontendLink builds an emailed link to a page of the frontend, the API
+// routes behind those pages take POST requests a mail client cannot make
+func frontendLink(path string, query url.Values) (string, error) {
+	if err := CheckServerURL(); err != nil {
+		return "", err
+	}
+	return utils.JoinURL(frontendURL(), path, query)
+}
+
+func (s *AccountService) SendVerificationEmail(ctx context.Context, email string, token string) (*domain.EmailLog, error) {
+	ctx, span := s.tracer.Start(ctx, "SendVerificationEmail")
+	defer span.End()
+
+	link, err := frontendLink("/verify-email", url.Values{"token": {token}})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.emailService.SendTemplate(ctx, email, mailer.TemplateVerifyEmail, mailer.VerifyEmailData{Link: link})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.EmailLog{
+		Recipient:  email,
+		Template:   mailer.TemplateVerifyEmail,
+		MessageID:  result.MessageID,
+		AcceptedAt: result.AcceptedAt,
+	}, nil
+}
+
+// SendEmailChangeEmail sends the confirmation link of an email change to the
+// new address
+func (s *AccountService) SendEmailChangeEmail(ctx context.Context, email string, token string) (*domain.EmailLog, error) {
+	ctx, span := s.tracer.Start(ctx, "SendEmailChangeEmail")
+	defer span.End()
+
+	link, err := frontendLink("/change-email/confirm", url.Values{"token": {token}})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.emailService.SendTemplate(ctx, email, mailer.TemplateChangeEmail, mailer.ChangeEmailData{Link: link})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.EmailLog{
+		Recipient:  email,
+		Template:   mailer.TemplateChangeEmail,
+		MessageID:  result.MessageID,
+		AcceptedAt: result.AcceptedAt,
+	}, nil
+}
+
+func passwordResetLink(token string) (string, error) {
+	return frontendLink("/reset-password", url.Values{"token": {token}})
+}
+
+// SendPasswordResetEmail sends the reset link and returns the email log entry
+// for the accepted message, the caller persists it with the account id
+func (s *AccountService) SendPasswordResetEmail(ctx context.Context, email string, token string) (*domain.EmailLog, error) {
+	ctx, span := s.tracer.Start(ctx, "SendPasswordResetEmail")
+	defer span.End()
+
+	link, err := passwordResetLink(token)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.emailService.SendTemplate(ctx, email, mailer.TemplatePasswordReset, mailer.PasswordResetData{Link: link})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.EmailLog{
+		Recipient:  email,
+		Template:   mailer.TemplatePasswordReset,
+		MessageID:  result.MessageID,
+		AcceptedAt: result.AcceptedAt,
+	}, nil
+}
+
+// EnqueuePasswordResetEmail queues the reset link to be sent in the
+// background, onSent receives the email log entry once the message is accepted
+func (s *AccountService) EnqueuePasswordResetEmail(ctx context.Context, email string, token string, onSent func(emailLog *domain.EmailLog)) error {
+	ctx, span := s.tracer.Start(ctx, "EnqueuePasswordResetEmail")
+	defer span.End()
+
+	link, err := passwordResetLink(token)
+	if err != nil {
+		return err
+	}
+
+	return s.emailService.EnqueueEmail(ctx, mailer.EmailJob{
+		To:       email,
+		Template: mailer.TemplatePasswordReset,
+		Data:     mailer.PasswordResetData{Link: link},
+		OnSent: func(result *mailer.SendResult) {
+			onSent(&domain.EmailLog{
+				Recipient:  email,
+				Template:   mailer.TemplatePasswordReset,
+				MessageID:  result.MessageID,
+				AcceptedAt: result.AcceptedAt,
+			})
+		},
+	})
+}
+
+func (s *AccountService) SendInactivityWarningEmail(ctx context.Context, email string, disableAt time.Time) error {
+	ctx, span := s.tracer.Start(ctx, "SendInactivityWarningEmail")
+	defer span.End()
+
+	loginLink, err := frontendLink("", nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.emailService.SendTemplate(ctx, email, mailer.TemplateInactivity, mailer.InactivityData{
+		DisableAt: disableAt,
+		LoginLink: loginLink,
+	})
+	return err
+}
+
+func (s *AccountService) SendAccountLockedEmail(ctx context.Context, email string, lockedUntil time.Time) error {
+	ctx, span := s.tracer.Start(ctx, "SendAccountLockedEmail")
+	defer span.End()
+
+	resetLink, err := frontendLink("/forgot-password", nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.emailService.SendTemplate(ctx, email, mailer.TemplateAccountLocked, mailer.AccountLockedData{
+		LockedUntil: lockedUntil,
+		ResetLink:   resetLink,
+	})
+	return err
+}
+
+// SendAccountExistsEmail is sent in place of the verification email when a
+// registration uses the email of an existing account, with links to log in
+// and reset the password
+func (s *AccountService) SendAccountExistsEmail(ctx context.Context, email string) (*domain.EmailLog, error) {
+	ctx, span := s.tracer.Start(ctx, "SendAccountExistsEmail")
+	defer span.End()
 
-	return s.emailService.SendEmail(email, "Password Reset", resetPasswordTemplate)
+	loginLink, err := frontendLink("", nil)
+	if err != nil {
+		return nil, err
+	}
+	resetLink, err := frontendLink("/forgot-password", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.emailService.SendTemplate(ctx, email, mailer.TemplateAccountExists, mailer.AccountExistsData{
+		LoginLink: loginLink,
+		ResetLink: resetLink,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.EmailLog{
+		Recipient:  email,
+		Template:   mailer.TemplateAccountExists,
+		MessageID:  result.MessageID,
+		AcceptedAt: result.AcceptedAt,
+	}, nil
+}
+
+func (s *AccountService) SendPendingActionEmail(ctx context.Context, email string, action *domain.PendingAccountAction, token string) error {
+	ctx, span := s.tracer.Start(ctx, "SendPendingActionEmail")
+	defer span.End()
+
+	link, err := frontendLink("/pending-action/cancel", url.Values{"token": {token}})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.emailService.SendTemplate(ctx, email, mailer.TemplatePendingAction, mailer.PendingActionData{
+		Action:       strings.ReplaceAll(action.Action, "_", " "),
+		ExecuteAfter: action.ExecuteAfter,
+		CancelLink:   link,
+	})
+	return err
 }
@@ -2,11 +2,22 @@ package account_test
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"spsyncpro_api/internal/account"
 	"spsyncpro_api/pkg/domain"
 	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/utils"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,7 +31,7 @@ func TestAccountService_HashPassword(t *testing.T) {
 
 	emailService := mailer.NewMockEmailService(t)
 	t.Run("should hash and compare password correctly", func(t *testing.T) {
-		service := account.NewAccountService(emailService)
+		service := account.NewAccountService(emailService, nil)
 
 		password := "password"
 		hash, err := service.HashPassword(context.Background(), password)
@@ -31,19 +42,101 @@ func TestAccountService_HashPassword(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotEmpty(t, hash)
 
-		ok, err := service.ComparePassword(context.Background(), password, hash)
+		ok, needsRehash, err := service.ComparePassword(context.Background(), password, hash)
 		assert.NoError(t, err)
 		assert.True(t, ok)
+		assert.False(t, needsRehash)
 	})
 
 	t.Run("should return error if password is empty", func(t *testing.T) {
-		service := account.NewAccountService(nil)
+		service := account.NewAccountService(nil, nil)
 
 		password := ""
 		hash, err := service.HashPassword(context.Background(), password)
 		assert.ErrorIs(t, err, domain.ErrPasswordEmpty)
 		assert.Empty(t, hash)
 	})
+
+	t.Run("should hash with a configured 32 byte salt", func(t *testing.T) {
+		viper.Set("ARGON2_SALT_LEN", 32)
+		defer viper.Reset()
+
+		service := account.NewAccountService(emailService, nil)
+
+		hash, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+
+		parts := strings.Split(hash, "$")
+		assert.Len(t, parts, 6)
+		salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+		assert.NoError(t, err)
+		assert.Len(t, salt, 32)
+
+		ok, _, err := service.ComparePassword(context.Background(), "password", hash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("should reject a salt below the safe minimum", func(t *testing.T) {
+		viper.Set("ARGON2_SALT_LEN", 2)
+		defer viper.Reset()
+
+		service := account.NewAccountService(emailService, nil)
+
+		hash, err := service.HashPassword(context.Background(), "password")
+		assert.ErrorIs(t, err, account.ErrSaltTooShort)
+		assert.Empty(t, hash)
+	})
+}
+
+func TestAccountService_ComparePasswordRehash(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	emailService := mailer.NewMockEmailService(t)
+	service := account.NewAccountService(emailService, nil)
+
+	t.Run("should hash with the configured argon2 parameters", func(t *testing.T) {
+		viper.Set("ARGON2_MEMORY", 32*1024)
+		viper.Set("ARGON2_TIME", 2)
+		viper.Set("ARGON2_THREADS", 2)
+		defer viper.Reset()
+
+		hash, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+		assert.Contains(t, hash, "$m=32768,t=2,p=2$")
+
+		ok, needsRehash, err := service.ComparePassword(context.Background(), "password", hash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.False(t, needsRehash)
+	})
+
+	t.Run("should report a hash with outdated parameters", func(t *testing.T) {
+		hash, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+
+		viper.Set("ARGON2_TIME", 2)
+		defer viper.Reset()
+
+		ok, needsRehash, err := service.ComparePassword(context.Background(), "password", hash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, needsRehash)
+	})
+
+	t.Run("should not report rehash for a wrong password", func(t *testing.T) {
+		hash, err := service.HashPassword(context.Background(), "password")
+		assert.NoError(t, err)
+
+		viper.Set("ARGON2_TIME", 2)
+		defer viper.Reset()
+
+		ok, needsRehash, err := service.ComparePassword(context.Background(), "wrong", hash)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.False(t, needsRehash)
+	})
 }
 
 func TestAccountService_GenerateAndValidateToken(t *testing.T) {
@@ -52,7 +145,7 @@ func TestAccountService_GenerateAndValidateToken(t *testing.T) {
 	defer viper.Reset()
 
 	emailService := mailer.NewMockEmailService(t)
-	service := account.NewAccountService(emailService)
+	service := account.NewAccountService(emailService, nil)
 
 	t.Run("should generate and validate token correctly", func(t *testing.T) {
 		account := &domain.Account{ID: 123, Email: "test@example.com"}
@@ -93,12 +186,226 @@ func TestAccountService_GenerateAndValidateToken(t *testing.T) {
 	})
 }
 
+func TestAccountService_ParseClaims(t *testing.T) {
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	emailService := mailer.NewMockEmailService(t)
+	service := account.NewAccountService(emailService, nil)
+
+	acc := &domain.Account{ID: 123, Email: "test@example.com"}
+
+	t.Run("should include the email claim when enabled", func(t *testing.T) {
+		viper.Set("JWT_INCLUDE_EMAIL", true)
+		defer viper.Set("JWT_INCLUDE_EMAIL", false)
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		claims, err := service.ParseClaims(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), claims.AccountID)
+		assert.Equal(t, "test@example.com", claims.Email)
+	})
+
+	t.Run("should not include the email claim by default", func(t *testing.T) {
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		claims, err := service.ParseClaims(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), claims.AccountID)
+		assert.Empty(t, claims.Email)
+
+		parts := strings.Split(token, ".")
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, err)
+		assert.NotContains(t, string(payload), "email")
+	})
+
+	t.Run("should carry the role of the account", func(t *testing.T) {
+		token, err := service.GenerateAuthToken(context.Background(), &domain.Account{ID: 1, Role: domain.RoleAdmin})
+		assert.NoError(t, err)
+
+		claims, err := service.ParseClaims(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RoleAdmin, claims.Role)
+
+		token, err = service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		claims, err = service.ParseClaims(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RoleUser, claims.Role)
+	})
+
+	t.Run("should read a token without a role as a user", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": 123,
+			"iss": "spsyncpro_api",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		signed, err := token.SignedString([]byte("test_secret_key_for_jwt_validation"))
+		assert.NoError(t, err)
+
+		claims, err := service.ParseClaims(context.Background(), signed)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RoleUser, claims.Role)
+	})
+}
+
+func TestAccountService_NotBefore(t *testing.T) {
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+	acc := &domain.Account{ID: 123, Email: "test@example.com"}
+
+	notBefore := func(t *testing.T, token string) time.Time {
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		nbf, err := parsed.Claims.GetNotBefore()
+		assert.NoError(t, err)
+		if nbf == nil {
+			return time.Time{}
+		}
+		return nbf.Time
+	}
+
+	t.Run("should not set nbf by default", func(t *testing.T) {
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+		assert.True(t, notBefore(t, token).IsZero())
+	})
+
+	t.Run("should reject a token until its nbf and accept it after", func(t *testing.T) {
+		viper.Set("JWT_NOT_BEFORE_OFFSET", "2s")
+		defer viper.Set("JWT_NOT_BEFORE_OFFSET", 0)
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+		nbf := notBefore(t, token)
+		assert.True(t, nbf.After(time.Now()))
+
+		_, err = service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, jwt.ErrTokenNotValidYet)
+
+		time.Sleep(time.Until(nbf))
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+
+	t.Run("should accept a future nbf within the leeway", func(t *testing.T) {
+		viper.Set("JWT_NOT_BEFORE_OFFSET", "1m")
+		viper.Set("JWT_LEEWAY", "2m")
+		defer viper.Set("JWT_NOT_BEFORE_OFFSET", 0)
+		defer viper.Set("JWT_LEEWAY", 0)
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+}
+
+func TestAccountService_GenerateAndValidateRefreshToken(t *testing.T) {
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	emailService := mailer.NewMockEmailService(t)
+	service := account.NewAccountService(emailService, nil)
+
+	acc := &domain.Account{ID: 123, Email: "test@example.com"}
+
+	t.Run("should generate and validate refresh token correctly", func(t *testing.T) {
+		token, _, err := service.GenerateRefreshToken(context.Background(), acc, false)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+
+		accountID, err := service.ValidateRefreshToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+
+	t.Run("should issue unique refresh tokens", func(t *testing.T) {
+		first, _, err := service.GenerateRefreshToken(context.Background(), acc, false)
+		assert.NoError(t, err)
+		second, _, err := service.GenerateRefreshToken(context.Background(), acc, false)
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("should use the configured refresh expiry", func(t *testing.T) {
+		viper.Set("JWT_REFRESH_EXPIRY", "2h")
+		defer viper.Set("JWT_REFRESH_EXPIRY", "")
+
+		token, _, err := service.GenerateRefreshToken(context.Background(), acc, false)
+		assert.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		exp, err := parsed.Claims.GetExpirationTime()
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), exp.Time, time.Minute)
+	})
+
+	t.Run("should use the remembered refresh expiry", func(t *testing.T) {
+		viper.Set("JWT_REFRESH_TTL_REMEMBER", "48h")
+		defer viper.Set("JWT_REFRESH_TTL_REMEMBER", "")
+
+		token, _, err := service.GenerateRefreshToken(context.Background(), acc, true)
+		assert.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		exp, err := parsed.Claims.GetExpirationTime()
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(48*time.Hour), exp.Time, time.Minute)
+	})
+
+	t.Run("should reject an expired refresh token", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": 123,
+			"exp": time.Now().Add(-time.Hour).Unix(),
+			"typ": "refresh",
+		})
+		expired, err := token.SignedString([]byte("test_secret_key_for_jwt_validation"))
+		assert.NoError(t, err)
+
+		accountID, err := service.ValidateRefreshToken(context.Background(), expired)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+		assert.Equal(t, uint(0), accountID)
+	})
+
+	t.Run("should reject an access token on refresh", func(t *testing.T) {
+		accessToken, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		accountID, err := service.ValidateRefreshToken(context.Background(), accessToken)
+		assert.ErrorIs(t, err, domain.ErrInvalidTokenType)
+		assert.Equal(t, uint(0), accountID)
+	})
+
+	t.Run("should reject a refresh token as an auth token", func(t *testing.T) {
+		refreshToken, _, err := service.GenerateRefreshToken(context.Background(), acc, false)
+		assert.NoError(t, err)
+
+		accountID, err := service.ValidateAuthToken(context.Background(), refreshToken)
+		assert.ErrorIs(t, err, domain.ErrInvalidTokenType)
+		assert.Equal(t, uint(0), accountID)
+	})
+}
+
 func TestAccountService_GenerateAndValidatePasswordResetToken(t *testing.T) {
 	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
 	defer viper.Reset()
 
 	emailService := mailer.NewMockEmailService(t)
-	service := account.NewAccountService(emailService)
+	service := account.NewAccountService(emailService, nil)
 
 	t.Run("should generate and validate password reset token correctly", func(t *testing.T) {
 		account := &domain.Account{ID: 123, Email: "test@example.com"}
@@ -137,32 +444,216 @@ func TestAccountService_GenerateAndValidatePasswordResetToken(t *testing.T) {
 		assert.Error(t, err)
 		assert.Equal(t, uint(0), accountID)
 	})
+
+	t.Run("should expire after the configured reset expiry", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		defer viper.Reset()
+
+		expiry := func() time.Duration {
+			token, err := service.GeneratePasswordResetToken(context.Background(), &domain.Account{ID: 123})
+			assert.NoError(t, err)
+
+			claims := jwt.MapClaims{}
+			_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+			assert.NoError(t, err)
+
+			exp, err := claims.GetExpirationTime()
+			assert.NoError(t, err)
+			return time.Until(exp.Time)
+		}
+
+		assert.InDelta(t, time.Hour.Seconds(), expiry().Seconds(), 5)
+
+		viper.Set("PASSWORD_RESET_TOKEN_EXPIRY", "15m")
+		assert.InDelta(t, (15 * time.Minute).Seconds(), expiry().Seconds(), 5)
+	})
+}
+
+func TestAccountService_GenerateAndValidateEmailVerificationToken(t *testing.T) {
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	emailService := mailer.NewMockEmailService(t)
+	service := account.NewAccountService(emailService, nil)
+
+	acc := &domain.Account{ID: 123, Email: "test@example.com"}
+
+	t.Run("should generate and validate verification token correctly", func(t *testing.T) {
+		token, err := service.GenerateEmailVerificationToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		accountID, email, err := service.ValidateEmailVerificationToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+		assert.Equal(t, "test@example.com", email)
+	})
+
+	t.Run("should reject an expired verification token", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":   "123:email-verification",
+			"email": "test@example.com",
+			"exp":   time.Now().Add(-time.Hour).Unix(),
+		})
+		expired, err := token.SignedString([]byte("test_secret_key_for_jwt_validation"))
+		assert.NoError(t, err)
+
+		accountID, _, err := service.ValidateEmailVerificationToken(context.Background(), expired)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+		assert.Equal(t, uint(0), accountID)
+	})
+
+	t.Run("should reject a password reset token", func(t *testing.T) {
+		resetToken, err := service.GeneratePasswordResetToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		accountID, _, err := service.ValidateEmailVerificationToken(context.Background(), resetToken)
+		assert.ErrorIs(t, err, account.ErrInvalidSubjectClaim)
+		assert.Equal(t, uint(0), accountID)
+	})
+}
+
+func TestAccountService_GenerateAndValidateEmailChangeToken(t *testing.T) {
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+	acc := &domain.Account{ID: 123, Email: "old@example.com"}
+
+	t.Run("should carry the current and the new email", func(t *testing.T) {
+		token, err := service.GenerateEmailChangeToken(context.Background(), acc, "new@example.com")
+		assert.NoError(t, err)
+
+		accountID, email, newEmail, err := service.ValidateEmailChangeToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+		assert.Equal(t, "old@example.com", email)
+		assert.Equal(t, "new@example.com", newEmail)
+	})
+
+	t.Run("should reject an email verification token", func(t *testing.T) {
+		verificationToken, err := service.GenerateEmailVerificationToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		_, _, _, err = service.ValidateEmailChangeToken(context.Background(), verificationToken)
+		assert.ErrorIs(t, err, account.ErrInvalidSubjectClaim)
+
+		changeToken, err := service.GenerateEmailChangeToken(context.Background(), acc, "new@example.com")
+		assert.NoError(t, err)
+
+		_, _, err = service.ValidateEmailVerificationToken(context.Background(), changeToken)
+		assert.ErrorIs(t, err, account.ErrInvalidSubjectClaim)
+	})
+}
+
+func TestAccountService_OpaquePasswordResetToken(t *testing.T) {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	service := account.NewAccountService(nil, nil)
+	acc := &domain.Account{ID: 123, Email: "test@example.com"}
+
+	t.Run("should generate unique tokens of the configured length", func(t *testing.T) {
+		viper.Set("PASSWORD_RESET_TOKEN_FORMAT", "opaque")
+		defer viper.Reset()
+
+		seen := make(map[string]bool)
+		for range 1000 {
+			token, err := service.GeneratePasswordResetToken(context.Background(), acc)
+			assert.NoError(t, err)
+			assert.True(t, account.IsOpaqueResetToken(token))
+			assert.False(t, seen[token], "token generated twice")
+			seen[token] = true
+
+			decoded, err := base64.RawURLEncoding.DecodeString(token)
+			assert.NoError(t, err)
+			assert.Len(t, decoded, 32)
+		}
+
+		viper.Set("PASSWORD_RESET_TOKEN_BYTES", 48)
+		token, err := service.GeneratePasswordResetToken(context.Background(), acc)
+		assert.NoError(t, err)
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		assert.NoError(t, err)
+		assert.Len(t, decoded, 48)
+	})
+
+	t.Run("should reject a length below the safe minimum", func(t *testing.T) {
+		viper.Set("PASSWORD_RESET_TOKEN_FORMAT", "opaque")
+		viper.Set("PASSWORD_RESET_TOKEN_BYTES", 8)
+		defer viper.Reset()
+
+		token, err := service.GeneratePasswordResetToken(context.Background(), acc)
+		assert.ErrorIs(t, err, account.ErrResetTokenTooShort)
+		assert.Empty(t, token)
+	})
+
+	t.Run("should keep issuing signed tokens by default", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		defer viper.Reset()
+
+		token, err := service.GeneratePasswordResetToken(context.Background(), acc)
+		assert.NoError(t, err)
+		assert.False(t, account.IsOpaqueResetToken(token))
+	})
+}
+
+func TestAccountService_SendAccountExistsEmail(t *testing.T) {
+	viper.Set("SERVER_URL", "http://localhost:8080")
+	defer viper.Reset()
+
+	emailService := mailer.NewMockEmailService(t)
+	emailService.
+		On(
+			"SendTemplate",
+			mock.Anything,
+			"test@example.com",
+			mailer.TemplateAccountExists,
+			mailer.AccountExistsData{
+				LoginLink: "http://localhost:8080",
+				ResetLink: "http://localhost:8080/forgot-password",
+			},
+		).
+		Return(&mailer.SendResult{MessageID: "<message-id@developer.com>"}, nil).
+		Once()
+
+	service := account.NewAccountService(emailService, nil)
+
+	emailLog, err := service.SendAccountExistsEmail(context.Background(), "test@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, mailer.TemplateAccountExists, emailLog.Template)
+	assert.Equal(t, "<message-id@developer.com>", emailLog.MessageID)
 }
 
 func TestAccountService_SendPasswordResetEmail(t *testing.T) {
 
 	t.Run("should send password reset email correctly", func(t *testing.T) {
 		viper.Set("SERVER_URL", "http://localhost:8080")
+		acceptedAt := time.Now()
 		defer viper.Reset()
 
 		emailService := mailer.NewMockEmailService(t)
-		// Set up the mock to expect SendEmail to be called with the correct arguments
+		// Set up the mock to expect SendTemplate to be called with the correct arguments
 		emailService.
 			On(
-				"SendEmail",
-				mock.AnythingOfType("string"),
-				mock.AnythingOfType("string"),
-				mock.AnythingOfType("string"),
+				"SendTemplate",
+				mock.Anything,
+				"test@example.com",
+				mailer.TemplatePasswordReset,
+				mailer.PasswordResetData{Link: "http://localhost:8080/reset-password?token=test_token"},
 			).
-			Return(nil).
+			Return(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: acceptedAt}, nil).
 			Once()
 
-		service := account.NewAccountService(emailService)
+		service := account.NewAccountService(emailService, nil)
 
 		email := "test@example.com"
 		token := "test_token"
-		err := service.SendPasswordResetEmail(context.Background(), email, token)
+		emailLog, err := service.SendPasswordResetEmail(context.Background(), email, token)
 		assert.NoError(t, err)
+		assert.Equal(t, "<message-id@developer.com>", emailLog.MessageID)
+		assert.Equal(t, acceptedAt, emailLog.AcceptedAt)
+		assert.Equal(t, email, emailLog.Recipient)
+		assert.Equal(t, mailer.TemplatePasswordReset, emailLog.Template)
 	})
 
 	t.Run("should return error if server url is not set", func(t *testing.T) {
@@ -170,12 +661,491 @@ func TestAccountService_SendPasswordResetEmail(t *testing.T) {
 		defer viper.Reset()
 
 		emailService := mailer.NewMockEmailService(t)
-		service := account.NewAccountService(emailService)
+		service := account.NewAccountService(emailService, nil)
 
 		email := "test@example.com"
 		token := "test_token"
-		err := service.SendPasswordResetEmail(context.Background(), email, token)
+		emailLog, err := service.SendPasswordResetEmail(context.Background(), email, token)
 		assert.ErrorIs(t, err, domain.ErrServerURLNotSet)
+		assert.Nil(t, emailLog)
+	})
+
+	t.Run("should build the link from a server url with a trailing slash", func(t *testing.T) {
+		viper.Set("SERVER_URL", "http://localhost:8080/")
+		defer viper.Reset()
+
+		emailService := mailer.NewMockEmailService(t)
+		emailService.
+			On(
+				"SendTemplate",
+				mock.Anything,
+				"test@example.com",
+				mailer.TemplatePasswordReset,
+				mailer.PasswordResetData{Link: "http://localhost:8080/reset-password?token=test_token"},
+			).
+			Return(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()}, nil).
+			Once()
+
+		service := account.NewAccountService(emailService, nil)
+
+		_, err := service.SendPasswordResetEmail(context.Background(), "test@example.com", "test_token")
+		assert.NoError(t, err)
+	})
+
+	t.Run("should return error if server url is invalid", func(t *testing.T) {
+		viper.Set("SERVER_URL", "ftp://localhost")
+		defer viper.Reset()
+
+		emailService := mailer.NewMockEmailService(t)
+		service := account.NewAccountService(emailService, nil)
+
+		emailLog, err := service.SendPasswordResetEmail(context.Background(), "test@example.com", "test_token")
+		assert.ErrorIs(t, err, utils.ErrInvalidBaseURL)
+		assert.Nil(t, emailLog)
+	})
+
+	t.Run("should refuse a plaintext server url in production", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "production")
+		viper.Set("SERVER_URL", "http://api.example.com")
+		defer viper.Reset()
+
+		emailService := mailer.NewMockEmailService(t)
+		service := account.NewAccountService(emailService, nil)
+
+		emailLog, err := service.SendPasswordResetEmail(context.Background(), "test@example.com", "test_token")
+		assert.ErrorIs(t, err, domain.ErrInsecureServerURL)
+		assert.Nil(t, emailLog)
+		emailService.AssertNotCalled(t, "SendTemplate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should allow a plaintext server url in production when not required", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "production")
+		viper.Set("SERVER_URL", "http://api.example.com")
+		viper.Set("REQUIRE_HTTPS_LINKS", "false")
+		defer viper.Reset()
+
+		assert.NoError(t, account.CheckServerURL())
+	})
+
+	t.Run("should allow a plaintext server url in development", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "debug")
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		defer viper.Reset()
+
+		emailService := mailer.NewMockEmailService(t)
+		emailService.
+			On("SendTemplate", mock.Anything, "test@example.com", mailer.TemplatePasswordReset,
+				mailer.PasswordResetData{Link: "http://localhost:8080/reset-password?token=test_token"}).
+			Return(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()}, nil).
+			Once()
+
+		service := account.NewAccountService(emailService, nil)
+
+		_, err := service.SendPasswordResetEmail(context.Background(), "test@example.com", "test_token")
+		assert.NoError(t, err)
+	})
+
+	t.Run("should refuse a plaintext server url in development when required", func(t *testing.T) {
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		viper.Set("REQUIRE_HTTPS_LINKS", "true")
+		defer viper.Reset()
+
+		assert.ErrorIs(t, account.CheckServerURL(), domain.ErrInsecureServerURL)
+
+		viper.Set("SERVER_URL", "https://api.example.com")
+		assert.NoError(t, account.CheckServerURL())
+	})
+
+	t.Run("should link to the reset page on the frontend", func(t *testing.T) {
+		viper.Set("SERVER_URL", "https://api.example.com")
+		viper.Set("FRONTEND_URL", "https://app.example.com/")
+		defer viper.Reset()
+
+		emailService := mailer.NewMockEmailService(t)
+		emailService.
+			On("SendTemplate", mock.Anything, "test@example.com", mailer.TemplatePasswordReset,
+				mailer.PasswordResetData{Link: "https://app.example.com/reset-password?token=test_token"}).
+			Return(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: time.Now()}, nil).
+			Once()
+
+		service := account.NewAccountService(emailService, nil)
+
+		_, err := service.SendPasswordResetEmail(context.Background(), "test@example.com", "test_token")
+		assert.NoError(t, err)
+	})
+
+	t.Run("should refuse a plaintext frontend url in production", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "production")
+		viper.Set("SERVER_URL", "https://api.example.com")
+		viper.Set("FRONTEND_URL", "http://app.example.com")
+		defer viper.Reset()
+
+		assert.ErrorIs(t, account.CheckServerURL(), domain.ErrInsecureServerURL)
+	})
+}
+
+func TestAccountService_EnqueuePasswordResetEmail(t *testing.T) {
+
+	t.Run("should queue the reset link and report the sent email", func(t *testing.T) {
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		acceptedAt := time.Now()
+		defer viper.Reset()
+
+		emailService := mailer.NewMockEmailService(t)
+		emailService.
+			On("EnqueueEmail", mock.Anything, mock.MatchedBy(func(job mailer.EmailJob) bool {
+				return job.To == "test@example.com" &&
+					job.Template == mailer.TemplatePasswordReset &&
+					job.Data == mailer.PasswordResetData{Link: "http://localhost:8080/reset-password?token=test_token"}
+			})).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(mailer.EmailJob).OnSent(&mailer.SendResult{MessageID: "<message-id@developer.com>", AcceptedAt: acceptedAt})
+			}).
+			Return(nil).
+			Once()
+
+		service := account.NewAccountService(emailService, nil)
+
+		var emailLog *domain.EmailLog
+		err := service.EnqueuePasswordResetEmail(context.Background(), "test@example.com", "test_token", func(l *domain.EmailLog) {
+			emailLog = l
+		})
+		assert.NoError(t, err)
+		assert.NotNil(t, emailLog)
+		assert.Equal(t, "<message-id@developer.com>", emailLog.MessageID)
+		assert.Equal(t, acceptedAt, emailLog.AcceptedAt)
+		assert.Equal(t, "test@example.com", emailLog.Recipient)
+	})
+
+	t.Run("should return the queue error", func(t *testing.T) {
+		viper.Set("SERVER_URL", "http://localhost:8080")
+		defer viper.Reset()
+
+		emailService := mailer.NewMockEmailService(t)
+		emailService.On("EnqueueEmail", mock.Anything, mock.AnythingOfType("mailer.EmailJob")).Return(mailer.ErrEmailQueueFull)
+
+		service := account.NewAccountService(emailService, nil)
+
+		err := service.EnqueuePasswordResetEmail(context.Background(), "test@example.com", "test_token", func(*domain.EmailLog) {})
+		assert.ErrorIs(t, err, mailer.ErrEmailQueueFull)
+	})
+}
+
+// writeRSAKeyPair generates a keypair and writes it as PEM files into a temp dir
+func writeRSAKeyPair(t *testing.T) (string, string) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "jwt.key")
+	publicPath := filepath.Join(dir, "jwt.pub")
+
+	privatePem := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+	assert.NoError(t, os.WriteFile(privatePath, privatePem, 0600))
+
+	publicDer, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.NoError(t, err)
+	publicPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer})
+	assert.NoError(t, os.WriteFile(publicPath, publicPem, 0644))
+
+	return privatePath, publicPath
+}
+
+func TestAccountService_RS256(t *testing.T) {
+	privatePath, publicPath := writeRSAKeyPair(t)
+
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	viper.Set("JWT_ALGORITHM", "RS256")
+	viper.Set("JWT_PRIVATE_KEY_PATH", privatePath)
+	viper.Set("JWT_PUBLIC_KEY_PATH", publicPath)
+	defer viper.Reset()
+
+	acc := &domain.Account{ID: 123, Email: "test@example.com"}
+
+	t.Run("should sign and validate auth tokens with RS256", func(t *testing.T) {
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		assert.Equal(t, "RS256", parsed.Method.Alg())
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+
+	t.Run("should sign and validate reset tokens with RS256", func(t *testing.T) {
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+		token, err := service.GeneratePasswordResetToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		accountID, err := service.ValidatePasswordResetToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
 	})
 
+	t.Run("should verify with the public key alone", func(t *testing.T) {
+		signer := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+		token, err := signer.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		viper.Set("JWT_PRIVATE_KEY_PATH", "")
+		defer viper.Set("JWT_PRIVATE_KEY_PATH", privatePath)
+
+		verifier := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+		accountID, err := verifier.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+
+	t.Run("should reject an HS256 token when RS256 is configured", func(t *testing.T) {
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": 123,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		signed, err := token.SignedString([]byte("test_secret_key_for_jwt_validation"))
+		assert.NoError(t, err)
+
+		accountID, err := service.ValidateAuthToken(context.Background(), signed)
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+		assert.Equal(t, uint(0), accountID)
+	})
+
+	t.Run("should reject an HS256 token signed with the public key", func(t *testing.T) {
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+		publicPem, err := os.ReadFile(publicPath)
+		assert.NoError(t, err)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": 123,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		signed, err := token.SignedString(publicPem)
+		assert.NoError(t, err)
+
+		_, err = service.ValidateAuthToken(context.Background(), signed)
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject an RS256 token when HS256 is configured", func(t *testing.T) {
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		viper.Set("JWT_ALGORITHM", "HS256")
+		defer viper.Set("JWT_ALGORITHM", "RS256")
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+		assert.Equal(t, uint(0), accountID)
+	})
+
+	t.Run("should return error if the private key path is not set", func(t *testing.T) {
+		viper.Set("JWT_PRIVATE_KEY_PATH", "")
+		defer viper.Set("JWT_PRIVATE_KEY_PATH", privatePath)
+
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.ErrorIs(t, err, account.ErrJWTKeyNotSet)
+		assert.Empty(t, token)
+	})
+
+	t.Run("should reject an unsupported algorithm", func(t *testing.T) {
+		viper.Set("JWT_ALGORITHM", "none")
+		defer viper.Set("JWT_ALGORITHM", "RS256")
+
+		service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.ErrorIs(t, err, account.ErrUnsupportedJWTAlgorithm)
+		assert.Empty(t, token)
+	})
+}
+
+func TestAccountService_Clock(t *testing.T) {
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	issuedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := issuedAt
+	clock := utils.ClockFunc(func() time.Time { return now })
+	service := account.NewAccountServiceWithClock(mailer.NewMockEmailService(t), nil, clock)
+	acc := &domain.Account{ID: 123, Email: "test@example.com"}
+
+	expiresAt := func(t *testing.T, token string) time.Time {
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		exp, err := parsed.Claims.GetExpirationTime()
+		assert.NoError(t, err)
+		return exp.Time
+	}
+
+	t.Run("should issue the auth token at the clock time", func(t *testing.T) {
+		now = issuedAt
+
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+		assert.Equal(t, issuedAt.Add(account.AuthTokenExpiry), expiresAt(t, token).UTC())
+	})
+
+	t.Run("should return the refresh token expiry at the clock time", func(t *testing.T) {
+		now = issuedAt
+
+		token, expiry, err := service.GenerateRefreshToken(context.Background(), acc, false)
+		assert.NoError(t, err)
+		assert.Equal(t, issuedAt.Add(account.RefreshTokenExpiry(false)), expiry)
+		assert.Equal(t, expiry, expiresAt(t, token).UTC())
+	})
+
+	t.Run("should accept the auth token just before exp and reject it at exp", func(t *testing.T) {
+		now = issuedAt
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+		exp := expiresAt(t, token)
+
+		now = exp.Add(-time.Second)
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+
+		now = exp
+		_, err = service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+		now = exp.Add(time.Second)
+		_, err = service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("should accept the auth token within the leeway after exp", func(t *testing.T) {
+		viper.Set("JWT_LEEWAY", "30s")
+		defer viper.Set("JWT_LEEWAY", 0)
+
+		now = issuedAt
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+		exp := expiresAt(t, token)
+
+		now = exp.Add(29 * time.Second)
+		_, err = service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+
+		now = exp.Add(30 * time.Second)
+		_, err = service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("should expire the password reset token at exp", func(t *testing.T) {
+		viper.Set("PASSWORD_RESET_TOKEN_EXPIRY", "15m")
+		defer viper.Set("PASSWORD_RESET_TOKEN_EXPIRY", 0)
+
+		now = issuedAt
+		token, err := service.GeneratePasswordResetToken(context.Background(), acc)
+		assert.NoError(t, err)
+		exp := expiresAt(t, token)
+		assert.Equal(t, issuedAt.Add(15*time.Minute), exp.UTC())
+
+		now = exp.Add(-time.Second)
+		accountID, err := service.ValidatePasswordResetToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+
+		now = exp
+		_, err = service.ValidatePasswordResetToken(context.Background(), token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("should not accept a token before it was issued with nbf", func(t *testing.T) {
+		viper.Set("JWT_NOT_BEFORE_OFFSET", "1m")
+		defer viper.Set("JWT_NOT_BEFORE_OFFSET", 0)
+
+		now = issuedAt
+		token, err := service.GenerateAuthToken(context.Background(), acc)
+		assert.NoError(t, err)
+
+		_, err = service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, jwt.ErrTokenNotValidYet)
+
+		now = issuedAt.Add(time.Minute)
+		_, err = service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+	})
+}
+
+func TestAccountService_ValidateAuthTokenClaims(t *testing.T) {
+	viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+	defer viper.Reset()
+
+	service := account.NewAccountService(mailer.NewMockEmailService(t), nil)
+
+	sign := func(t *testing.T, claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test_secret_key_for_jwt_validation"))
+		assert.NoError(t, err)
+		return signed
+	}
+
+	t.Run("should accept a valid token", func(t *testing.T) {
+		token := sign(t, jwt.MapClaims{
+			"sub": 123,
+			"iss": "spsyncpro_api",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(123), accountID)
+	})
+
+	t.Run("should return ErrTokenExpired for an expired token", func(t *testing.T) {
+		token := sign(t, jwt.MapClaims{
+			"sub": 123,
+			"iss": "spsyncpro_api",
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		})
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, domain.ErrTokenExpired)
+		assert.Equal(t, uint(0), accountID)
+	})
+
+	t.Run("should reject a token from another issuer", func(t *testing.T) {
+		token := sign(t, jwt.MapClaims{
+			"sub": 123,
+			"iss": "another_service",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+
+		accountID, err := service.ValidateAuthToken(context.Background(), token)
+		assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+		assert.NotErrorIs(t, err, domain.ErrTokenExpired)
+		assert.Equal(t, uint(0), accountID)
+	})
+
+	t.Run("should reject a token without an issuer", func(t *testing.T) {
+		token := sign(t, jwt.MapClaims{
+			"sub": 123,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+
+		_, err := service.ValidateAuthToken(context.Background(), token)
+		assert.Error(t, err)
+	})
+
+	t.Run("should not report a malformed token as expired", func(t *testing.T) {
+		_, err := service.ValidateAuthToken(context.Background(), "not-a-token")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrTokenExpired)
+	})
 }
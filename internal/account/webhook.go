@@ -0,0 +1,586 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"spsyncpro_api/pkg/webhook"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	webhookSecretBytes = 32
+	webhookEventBytes  = 16
+
+	defaultWebhookSecretGracePeriod = 24 * time.Hour
+
+	// WebhookEventPrefix names the event of an activity, a registration is
+	// delivered as "account.register"
+	WebhookEventPrefix = "account."
+	// WebhookPingEvent is the event sent by the test endpoint
+	WebhookPingEvent = "ping"
+)
+
+// ActivityEventData is the data of an account activity event
+type ActivityEventData struct {
+	AccountID uint   `json:"account_id"`
+	Activity  string `json:"activity"`
+}
+
+// ActivityWebhookNotifier delivers account activities to the webhooks
+// subscribed to them. Deliveries run in the background so a slow receiver
+// never holds up the request, a failed delivery is logged and dropped.
+type ActivityWebhookNotifier struct {
+	logger     *logrus.Logger
+	tracer     trace.Tracer
+	repository domain.AccountWebhookRepository
+	sender     *webhook.Sender
+	encryptor  *utils.Encryptor
+
+	wg sync.WaitGroup
+}
+
+func NewActivityWebhookNotifier(
+	logger *logrus.Logger,
+	repository domain.AccountWebhookRepository,
+	sender *webhook.Sender,
+) (*ActivityWebhookNotifier, error) {
+	encryptor, err := utils.NewEncryptorFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &ActivityWebhookNotifier{
+		logger:     logger,
+		tracer:     otel.Tracer("activityWebhookNotifier"),
+		repository: repository,
+		sender:     sender,
+		encryptor:  encryptor,
+	}, nil
+}
+
+func (n *ActivityWebhookNotifier) NotifyActivity(ctx context.Context, accountID uint, activity string) {
+	occurredAt := time.Now()
+
+	// the delivery outlives the request that performed the activity
+	ctx = context.WithoutCancel(ctx)
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(ctx, accountID, activity, occurredAt)
+	}()
+}
+
+// Wait blocks until the deliveries in flight have finished
+func (n *ActivityWebhookNotifier) Wait() {
+	n.wg.Wait()
+}
+
+func (n *ActivityWebhookNotifier) deliver(ctx context.Context, accountID uint, activity string, occurredAt time.Time) {
+	ctx, span := n.tracer.Start(ctx, "deliver")
+	defer span.End()
+
+	webhooks, err := n.repository.ListAccountWebhooks(ctx)
+	if err != nil {
+		n.logger.WithContext(ctx).WithField("userId", accountID).Errorf("failed to list webhooks: %v", err)
+		return
+	}
+
+	for _, subscription := range webhooks {
+		if !subscription.Subscribes(activity) {
+			continue
+		}
+
+		logger := n.logger.WithContext(ctx).WithField("userId", accountID).WithField("webhookId", subscription.ID)
+
+		secrets, err := webhookSigningSecrets(n.encryptor, subscription, occurredAt)
+		if err != nil {
+			logger.Errorf("failed to decrypt webhook secret: %v", err)
+			continue
+		}
+
+		eventID, err := utils.GenerateToken(webhookEventBytes)
+		if err != nil {
+			logger.Errorf("failed to generate webhook event id: %v", err)
+			continue
+		}
+
+		err = n.sender.Send(ctx, subscription.URL, secrets, webhook.Event{
+			ID:        eventID,
+			Type:      WebhookEventPrefix + activity,
+			CreatedAt: occurredAt,
+			Data: ActivityEventData{
+				AccountID: accountID,
+				Activity:  activity,
+			},
+		})
+		if err != nil {
+			logger.Warnf("failed to deliver %s webhook: %v", activity, err)
+		}
+	}
+}
+
+// webhookSigningSecrets returns the secrets an event is signed with, the
+// current one and the previous one while its rotation grace period lasts
+func webhookSigningSecrets(encryptor *utils.Encryptor, subscription domain.AccountWebhook, now time.Time) ([]string, error) {
+	secret, err := encryptor.Decrypt(subscription.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.PreviousSecretActive(now) {
+		return []string{secret}, nil
+	}
+
+	previous, err := encryptor.Decrypt(subscription.PreviousSecret)
+	if err != nil {
+		return nil, err
+	}
+	return []string{secret, previous}, nil
+}
+
+type WebhookHandler struct {
+	logger     *logrus.Logger
+	tracer     trace.Tracer
+	repository domain.AccountWebhookRepository
+	sender     *webhook.Sender
+	encryptor  *utils.Encryptor
+}
+
+func NewWebhookHandler(
+	logger *logrus.Logger,
+	repository domain.AccountWebhookRepository,
+	sender *webhook.Sender,
+) (*WebhookHandler, error) {
+	encryptor, err := utils.NewEncryptorFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookHandler{
+		logger:     logger,
+		tracer:     otel.Tracer("webhookHandler"),
+		repository: repository,
+		sender:     sender,
+		encryptor:  encryptor,
+	}, nil
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,max=2048"`
+	Activities []string `json:"activities"`
+	// OrganizationID configures the webhook for an organization, its owner
+	// can then test the delivery
+	OrganizationID *uint `json:"organization_id"`
+}
+
+type WebhookResponse struct {
+	ID         uint      `json:"id"`
+	URL        string    `json:"url"`
+	Activities []string  `json:"activities"`
+	CreatedAt  time.Time `json:"created_at"`
+	// OrganizationID is set for a webhook configured for an organization
+	OrganizationID *uint `json:"organization_id,omitempty"`
+	// PreviousSecretExpiresAt is set while events are also signed with the
+	// secret replaced by the last rotation
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
+
+type CreateWebhookResponse struct {
+	WebhookResponse
+	// Secret signs the events of the webhook, it is only shown on creation
+	Secret string `json:"secret"`
+	// KeyID identifies the secret in the signature header
+	KeyID string `json:"key_id"`
+}
+
+type RotateWebhookSecretResponse struct {
+	WebhookResponse
+	// Secret is the new current secret, it is only shown in this response
+	Secret string `json:"secret"`
+	// KeyID identifies the new secret in the signature header
+	KeyID string `json:"key_id"`
+}
+
+type ListWebhooksResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+// PingEventData is the data of a ping event
+type PingEventData struct {
+	WebhookID uint `json:"webhook_id"`
+}
+
+// TestWebhookResponse is the result of a test delivery, a failed delivery is
+// reported here and not as an error of the request
+type TestWebhookResponse struct {
+	Delivered bool `json:"delivered"`
+	// StatusCode is the status the receiver answered with, 0 when it did not answer
+	StatusCode int    `json:"status_code"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type DeleteWebhookResponse struct {
+	Message string `json:"message"`
+}
+
+func newWebhookResponse(subscription domain.AccountWebhook) WebhookResponse {
+	response := WebhookResponse{
+		ID:             subscription.ID,
+		URL:            subscription.URL,
+		Activities:     subscription.ActivityList(),
+		CreatedAt:      subscription.CreatedAt,
+		OrganizationID: subscription.OrganizationID,
+	}
+	if subscription.PreviousSecretActive(time.Now()) {
+		response.PreviousSecretExpiresAt = subscription.PreviousSecretExpiresAt
+	}
+	return response
+}
+
+// webhookSecretGracePeriod is how long events are also signed with a rotated
+// out secret, WEBHOOK_SECRET_GRACE_PERIOD
+func webhookSecretGracePeriod() time.Duration {
+	if viper.IsSet("WEBHOOK_SECRET_GRACE_PERIOD") {
+		return viper.GetDuration("WEBHOOK_SECRET_GRACE_PERIOD")
+	}
+	return defaultWebhookSecretGracePeriod
+}
+
+// validWebhookURL accepts absolute http and https urls
+func validWebhookURL(raw string) bool {
+	target, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (target.Scheme == "http" || target.Scheme == "https") && target.Host != ""
+}
+
+// @Summary		Create an account webhook
+// @Description	Subscribe a url to account activities, each activity is posted as a signed event. The signing secret is only shown in this response.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Param			webhook	body		CreateWebhookRequest	true	"Webhook"
+// @Success		200		{object}	CreateWebhookResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		403		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "CreateWebhook")
+	defer span.End()
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	target := strings.TrimSpace(req.URL)
+	if !validWebhookURL(target) {
+		utils.RespondError(c, domain.ErrWebhookInvalidURL)
+		return
+	}
+
+	activities := make([]string, 0, len(req.Activities))
+	for _, activity := range req.Activities {
+		activity = strings.TrimSpace(activity)
+		if !slices.Contains(domain.WebhookActivities, activity) {
+			utils.RespondError(c, domain.ErrWebhookInvalidActivity)
+			return
+		}
+		if !slices.Contains(activities, activity) {
+			activities = append(activities, activity)
+		}
+	}
+	if len(activities) == 0 {
+		utils.RespondError(c, domain.ErrWebhookInvalidActivity)
+		return
+	}
+
+	secret, err := utils.GenerateToken(webhookSecretBytes)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to generate webhook secret: %v", err)
+		utils.RespondError(c, domain.ErrTokenGenerationFailed)
+		return
+	}
+
+	encryptedSecret, err := h.encryptor.Encrypt(secret)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to encrypt webhook secret: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	subscription, err := h.repository.CreateAccountWebhook(ctx, &domain.AccountWebhook{
+		URL:            target,
+		Secret:         encryptedSecret,
+		Activities:     strings.Join(activities, ","),
+		OrganizationID: req.OrganizationID,
+	})
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to create webhook: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, CreateWebhookResponse{
+		WebhookResponse: newWebhookResponse(*subscription),
+		Secret:          secret,
+		KeyID:           webhook.KeyID(secret),
+	})
+}
+
+// @Summary		List account webhooks
+// @Description	List the account webhooks, the signing secrets are never returned
+// @Tags			admin
+// @Produce		json
+// @Success		200	{object}	ListWebhooksResponse
+// @Failure		403	{object}	utils.ErrorResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "ListWebhooks")
+	defer span.End()
+
+	webhooks, err := h.repository.ListAccountWebhooks(ctx)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to list webhooks: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	response := ListWebhooksResponse{Webhooks: make([]WebhookResponse, 0, len(webhooks))}
+	for _, subscription := range webhooks {
+		response.Webhooks = append(response.Webhooks, newWebhookResponse(subscription))
+	}
+
+	utils.RespondJSON(c, http.StatusOK, response)
+}
+
+// @Summary		Delete an account webhook
+// @Description	Stop delivering account activities to a webhook
+// @Tags			admin
+// @Produce		json
+// @Param			id	path		int	true	"Webhook id"
+// @Success		200	{object}	DeleteWebhookResponse
+// @Failure		403	{object}	utils.ErrorResponse
+// @Failure		404	{object}	utils.ErrorResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "DeleteWebhook")
+	defer span.End()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondError(c, domain.ErrWebhookNotFound)
+		return
+	}
+
+	err = h.repository.DeleteAccountWebhook(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, domain.ErrWebhookNotFound) {
+			utils.RespondError(c, domain.ErrWebhookNotFound)
+			return
+		}
+		h.logger.WithContext(ctx).Errorf("failed to delete webhook: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, DeleteWebhookResponse{
+		Message: "webhook deleted",
+	})
+}
+
+// @Summary		Rotate the secret of an account webhook
+// @Description	Replace the signing secret of a webhook. Events are signed with the new and the old secret until WEBHOOK_SECRET_GRACE_PERIOD has passed, so receivers can switch without missing events. The new secret is only shown in this response.
+// @Tags			admin
+// @Produce		json
+// @Param			id	path		int	true	"Webhook id"
+// @Success		200	{object}	RotateWebhookSecretResponse
+// @Failure		403	{object}	utils.ErrorResponse
+// @Failure		404	{object}	utils.ErrorResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/webhooks/{id}/rotate-secret [post]
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "RotateWebhookSecret")
+	defer span.End()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondError(c, domain.ErrWebhookNotFound)
+		return
+	}
+
+	secret, err := utils.GenerateToken(webhookSecretBytes)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to generate webhook secret: %v", err)
+		utils.RespondError(c, domain.ErrTokenGenerationFailed)
+		return
+	}
+
+	encryptedSecret, err := h.encryptor.Encrypt(secret)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to encrypt webhook secret: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	subscription, err := h.repository.RotateAccountWebhookSecret(ctx, uint(id), encryptedSecret, time.Now().Add(webhookSecretGracePeriod()))
+	if err != nil {
+		if errors.Is(err, domain.ErrWebhookNotFound) {
+			utils.RespondError(c, domain.ErrWebhookNotFound)
+			return
+		}
+		h.logger.WithContext(ctx).Errorf("failed to rotate webhook secret: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, RotateWebhookSecretResponse{
+		WebhookResponse: newWebhookResponse(*subscription),
+		Secret:          secret,
+		KeyID:           webhook.KeyID(secret),
+	})
+}
+
+// @Summary		Test an account webhook
+// @Description	Send a signed ping event to the webhook url in a single attempt and report the response status, latency and error. The ping is signed like activity events, with the previous secret too during a rotation.
+// @Tags			admin
+// @Produce		json
+// @Param			id	path		int	true	"Webhook id"
+// @Success		200	{object}	TestWebhookResponse
+// @Failure		403	{object}	utils.ErrorResponse
+// @Failure		404	{object}	utils.ErrorResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/webhooks/{id}/test [post]
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "TestWebhook")
+	defer span.End()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondError(c, domain.ErrWebhookNotFound)
+		return
+	}
+
+	subscription, err := h.repository.GetAccountWebhook(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, domain.ErrWebhookNotFound) {
+			utils.RespondError(c, domain.ErrWebhookNotFound)
+			return
+		}
+		h.logger.WithContext(ctx).Errorf("failed to get webhook: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	h.ping(c, ctx, subscription)
+}
+
+// @Summary		Test an organization webhook
+// @Description	Send a signed ping event to a webhook configured for the organization in a single attempt and report the response status, latency and error
+// @Tags			organization
+// @Produce		json
+// @Param			id	path		int	true	"Organization id"
+// @Param			wid	path		int	true	"Webhook id"
+// @Success		200	{object}	TestWebhookResponse
+// @Failure		403	{object}	utils.ErrorResponse
+// @Failure		404	{object}	utils.ErrorResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/organization/{id}/webhooks/{wid}/test [post]
+func (h *WebhookHandler) TestOrganizationWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "TestOrganizationWebhook")
+	defer span.End()
+
+	// set by the ownership check of the organization routes
+	organizationID := c.GetUint(utils.OrganizationIdContextKey)
+	if organizationID == 0 {
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("wid"), 10, 64)
+	if err != nil {
+		utils.RespondError(c, domain.ErrWebhookNotFound)
+		return
+	}
+
+	subscription, err := h.repository.GetAccountWebhook(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, domain.ErrWebhookNotFound) {
+			utils.RespondError(c, domain.ErrWebhookNotFound)
+			return
+		}
+		h.logger.WithContext(ctx).Errorf("failed to get webhook: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// a webhook of another organization is not revealed to the owner
+	if subscription.OrganizationID == nil || *subscription.OrganizationID != organizationID {
+		utils.RespondError(c, domain.ErrWebhookNotFound)
+		return
+	}
+
+	h.ping(c, ctx, subscription)
+}
+
+// ping sends a signed ping event to the webhook in a single attempt and
+// responds with the delivery result
+func (h *WebhookHandler) ping(c *gin.Context, ctx context.Context, subscription *domain.AccountWebhook) {
+	now := time.Now()
+	secrets, err := webhookSigningSecrets(h.encryptor, *subscription, now)
+	if err != nil {
+		h.logger.WithContext(ctx).WithField("webhookId", subscription.ID).Errorf("failed to decrypt webhook secret: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	eventID, err := utils.GenerateToken(webhookEventBytes)
+	if err != nil {
+		h.logger.WithContext(ctx).Errorf("failed to generate webhook event id: %v", err)
+		utils.RespondError(c, domain.ErrTokenGenerationFailed)
+		return
+	}
+
+	delivery := h.sender.Deliver(ctx, subscription.URL, secrets, webhook.Event{
+		ID:        eventID,
+		Type:      WebhookPingEvent,
+		CreatedAt: now,
+		Data:      PingEventData{WebhookID: subscription.ID},
+	})
+
+	response := TestWebhookResponse{
+		Delivered:  delivery.Err == nil,
+		StatusCode: delivery.StatusCode,
+		LatencyMs:  delivery.Latency.Milliseconds(),
+	}
+	if delivery.Err != nil {
+		response.Error = delivery.Err.Error()
+	}
+
+	utils.RespondJSON(c, http.StatusOK, response)
+}
@@ -0,0 +1,103 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"spsyncpro_api/pkg/database"
+	"spsyncpro_api/pkg/domain"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+type AccountWebhookRepo struct {
+	db    *gorm.DB
+	trace trace.Tracer
+}
+
+func NewAccountWebhookRepository(db *gorm.DB) domain.AccountWebhookRepository {
+	trace := otel.Tracer("accountWebhookRepository")
+	return &AccountWebhookRepo{
+		db:    db,
+		trace: trace,
+	}
+}
+
+// conn joins the transaction carried by ctx, see database.DB.WithTransaction
+func (r *AccountWebhookRepo) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, r.db)
+}
+
+func (r *AccountWebhookRepo) CreateAccountWebhook(ctx context.Context, webhook *domain.AccountWebhook) (*domain.AccountWebhook, error) {
+	_, span := r.trace.Start(ctx, "CreateAccountWebhook")
+	defer span.End()
+	err := r.conn(ctx).Create(webhook).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *AccountWebhookRepo) ListAccountWebhooks(ctx context.Context) ([]domain.AccountWebhook, error) {
+	_, span := r.trace.Start(ctx, "ListAccountWebhooks")
+	defer span.End()
+	webhooks := []domain.AccountWebhook{}
+	err := r.conn(ctx).Order("id").Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *AccountWebhookRepo) GetAccountWebhook(ctx context.Context, id uint) (*domain.AccountWebhook, error) {
+	_, span := r.trace.Start(ctx, "GetAccountWebhook")
+	defer span.End()
+	var webhook domain.AccountWebhook
+	err := r.conn(ctx).First(&webhook, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *AccountWebhookRepo) DeleteAccountWebhook(ctx context.Context, id uint) error {
+	_, span := r.trace.Start(ctx, "DeleteAccountWebhook")
+	defer span.End()
+	result := r.conn(ctx).Delete(&domain.AccountWebhook{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *AccountWebhookRepo) RotateAccountWebhookSecret(ctx context.Context, id uint, secret string, previousExpiresAt time.Time) (*domain.AccountWebhook, error) {
+	_, span := r.trace.Start(ctx, "RotateAccountWebhookSecret")
+	defer span.End()
+	// the right hand side reads the row before the update, the current
+	// secret moves to previous_secret
+	result := r.conn(ctx).Model(&domain.AccountWebhook{}).Where("id = ?", id).Updates(map[string]any{
+		"previous_secret":            gorm.Expr("secret"),
+		"secret":                     secret,
+		"previous_secret_expires_at": previousExpiresAt,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	var webhook domain.AccountWebhook
+	if err := r.conn(ctx).First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
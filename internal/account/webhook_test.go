@@ -0,0 +1,450 @@
+package account_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/internal/organization"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"spsyncpro_api/pkg/webhook"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// webhookReceiver records the requests posted to it
+type webhookReceiver struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	headers  []http.Header
+	payloads [][]byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	receiver := &webhookReceiver{}
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.headers = append(receiver.headers, r.Header)
+		receiver.payloads = append(receiver.payloads, body)
+		receiver.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.server.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) received() ([]http.Header, [][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.headers, r.payloads
+}
+
+func TestActivityWebhookNotifier(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	setup := func(t *testing.T) (*HTTPTestHelper, *account.ActivityWebhookNotifier) {
+		db := newTestDB(t)
+		service := account.NewAccountService(nil, nil)
+		repository := account.NewAccountRepository(db)
+		webhookRepository := account.NewAccountWebhookRepository(db)
+
+		notifier, err := account.NewActivityWebhookNotifier(logrus.New(), webhookRepository, webhook.NewSender())
+		assert.NoError(t, err)
+		webhookHandler, err := account.NewWebhookHandler(logrus.New(), webhookRepository, webhook.NewSender())
+		assert.NoError(t, err)
+
+		handler := account.NewAccountHandler(logrus.New(), service, repository)
+		handler.SetActivityNotifier(notifier)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/account/register", handler.RegisterAccount)
+		httpHelper.SetupHandler("POST", "/admin/webhooks", webhookHandler.CreateWebhook)
+		httpHelper.SetupHandler("POST", "/admin/webhooks/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+		return httpHelper, notifier
+	}
+
+	subscribe := func(t *testing.T, httpHelper *HTTPTestHelper, url string, activities ...string) account.CreateWebhookResponse {
+		w := httpHelper.MakeRequest("POST", "/admin/webhooks", account.CreateWebhookRequest{URL: url, Activities: activities}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response account.CreateWebhookResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		return response
+	}
+
+	register := func(t *testing.T, httpHelper *HTTPTestHelper) account.RegisterAccountResponse {
+		w := httpHelper.MakeRequest("POST", "/account/register", account.RegisterAccountRequest{Email: "test@example.com", Password: "password123"}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response account.RegisterAccountResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		return response
+	}
+
+	t.Run("should deliver a signed event for a subscribed activity", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+
+		httpHelper, notifier := setup(t)
+		receiver := newWebhookReceiver(t)
+		subscription := subscribe(t, httpHelper, receiver.server.URL, domain.ActivityRegister, domain.ActivityLogin)
+
+		registered := register(t, httpHelper)
+		notifier.Wait()
+
+		headers, payloads := receiver.received()
+		assert.Len(t, payloads, 1)
+
+		timestamp := headers[0].Get(webhook.TimestampHeader)
+		assert.Equal(t, webhook.Signature(subscription.Secret, timestamp, payloads[0]), headers[0].Get(webhook.SignatureHeader))
+		assert.Contains(t, headers[0].Get(webhook.SignatureHeader), "kid="+subscription.KeyID+",")
+		assert.Equal(t, "account.register", headers[0].Get(webhook.EventHeader))
+
+		var event struct {
+			ID   string                    `json:"id"`
+			Type string                    `json:"type"`
+			Data account.ActivityEventData `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(payloads[0], &event))
+		assert.NotEmpty(t, event.ID)
+		assert.Equal(t, headers[0].Get(webhook.IDHeader), event.ID)
+		assert.Equal(t, "account.register", event.Type)
+		assert.Equal(t, registered.ID, event.Data.AccountID)
+		assert.Equal(t, domain.ActivityRegister, event.Data.Activity)
+	})
+
+	t.Run("should sign with the current and previous secret after a rotation", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+
+		httpHelper, notifier := setup(t)
+		receiver := newWebhookReceiver(t)
+		subscription := subscribe(t, httpHelper, receiver.server.URL, domain.ActivityRegister)
+
+		path := "/admin/webhooks/" + strconv.FormatUint(uint64(subscription.ID), 10) + "/rotate-secret"
+		w := httpHelper.MakeRequest("POST", path, nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var rotated account.RotateWebhookSecretResponse
+		httpHelper.AssertJSONResponse(t, w, &rotated)
+		assert.NotEqual(t, subscription.Secret, rotated.Secret)
+		assert.Equal(t, webhook.KeyID(rotated.Secret), rotated.KeyID)
+		assert.NotNil(t, rotated.PreviousSecretExpiresAt)
+
+		register(t, httpHelper)
+		notifier.Wait()
+
+		headers, payloads := receiver.received()
+		assert.Len(t, payloads, 1)
+
+		timestamp := headers[0].Get(webhook.TimestampHeader)
+		assert.Equal(t,
+			webhook.Signature(rotated.Secret, timestamp, payloads[0])+" "+webhook.Signature(subscription.Secret, timestamp, payloads[0]),
+			headers[0].Get(webhook.SignatureHeader),
+		)
+	})
+
+	t.Run("should sign with the current secret only after the grace period", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		viper.Set("WEBHOOK_SECRET_GRACE_PERIOD", "0s")
+		defer viper.Reset()
+
+		httpHelper, notifier := setup(t)
+		receiver := newWebhookReceiver(t)
+		subscription := subscribe(t, httpHelper, receiver.server.URL, domain.ActivityRegister)
+
+		path := "/admin/webhooks/" + strconv.FormatUint(uint64(subscription.ID), 10) + "/rotate-secret"
+		w := httpHelper.MakeRequest("POST", path, nil, nil)
+		var rotated account.RotateWebhookSecretResponse
+		httpHelper.AssertJSONResponse(t, w, &rotated)
+		assert.Nil(t, rotated.PreviousSecretExpiresAt)
+
+		register(t, httpHelper)
+		notifier.Wait()
+
+		headers, payloads := receiver.received()
+		assert.Len(t, payloads, 1)
+		timestamp := headers[0].Get(webhook.TimestampHeader)
+		assert.Equal(t, webhook.Signature(rotated.Secret, timestamp, payloads[0]), headers[0].Get(webhook.SignatureHeader))
+	})
+
+	t.Run("should not deliver an unsubscribed activity", func(t *testing.T) {
+		viper.Set("JWT_SECRET", "test_secret_key_for_jwt_validation")
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+
+		httpHelper, notifier := setup(t)
+		receiver := newWebhookReceiver(t)
+		subscribe(t, httpHelper, receiver.server.URL, domain.ActivityChangePassword)
+
+		register(t, httpHelper)
+		notifier.Wait()
+
+		_, payloads := receiver.received()
+		assert.Empty(t, payloads)
+	})
+}
+
+func TestWebhookHandler(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	setup := func(t *testing.T) *HTTPTestHelper {
+		db := newTestDB(t)
+		webhookHandler, err := account.NewWebhookHandler(logrus.New(), account.NewAccountWebhookRepository(db), webhook.NewSender())
+		assert.NoError(t, err)
+
+		httpHelper := NewHTTPTestHelper()
+		httpHelper.SetupHandler("POST", "/admin/webhooks", webhookHandler.CreateWebhook)
+		httpHelper.SetupHandler("GET", "/admin/webhooks", webhookHandler.ListWebhooks)
+		httpHelper.SetupHandler("DELETE", "/admin/webhooks/:id", webhookHandler.DeleteWebhook)
+		httpHelper.SetupHandler("POST", "/admin/webhooks/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+		httpHelper.SetupHandler("POST", "/admin/webhooks/:id/test", webhookHandler.TestWebhook)
+		return httpHelper
+	}
+
+	create := func(t *testing.T, httpHelper *HTTPTestHelper, url string) account.CreateWebhookResponse {
+		w := httpHelper.MakeRequest("POST", "/admin/webhooks", account.CreateWebhookRequest{
+			URL:        url,
+			Activities: []string{domain.ActivityLogin},
+		}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var created account.CreateWebhookResponse
+		httpHelper.AssertJSONResponse(t, w, &created)
+		return created
+	}
+
+	testPath := func(id uint) string {
+		return "/admin/webhooks/" + strconv.FormatUint(uint64(id), 10) + "/test"
+	}
+
+	t.Run("should reject invalid subscriptions", func(t *testing.T) {
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+
+		httpHelper := setup(t)
+
+		tests := []struct {
+			request account.CreateWebhookRequest
+			code    string
+		}{
+			{account.CreateWebhookRequest{URL: "not a url", Activities: []string{domain.ActivityLogin}}, "webhook.invalid_url"},
+			{account.CreateWebhookRequest{URL: "ftp://example.com/hook", Activities: []string{domain.ActivityLogin}}, "webhook.invalid_url"},
+			{account.CreateWebhookRequest{URL: "https://example.com/hook", Activities: []string{"unknown"}}, "webhook.invalid_activity"},
+			{account.CreateWebhookRequest{URL: "https://example.com/hook"}, "webhook.invalid_activity"},
+		}
+		for _, tt := range tests {
+			w := httpHelper.MakeRequest("POST", "/admin/webhooks", tt.request, nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response map[string]string
+			httpHelper.AssertJSONResponse(t, w, &response)
+			assert.Equal(t, tt.code, response["code"])
+		}
+	})
+
+	t.Run("should list and delete webhooks without their secrets", func(t *testing.T) {
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+
+		httpHelper := setup(t)
+
+		w := httpHelper.MakeRequest("POST", "/admin/webhooks", account.CreateWebhookRequest{
+			URL:        "https://example.com/hook",
+			Activities: []string{domain.ActivityLogin, domain.ActivityLogin, domain.ActivityDisable},
+		}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var created account.CreateWebhookResponse
+		httpHelper.AssertJSONResponse(t, w, &created)
+		assert.NotEmpty(t, created.Secret)
+		assert.Equal(t, []string{domain.ActivityLogin, domain.ActivityDisable}, created.Activities)
+
+		w = httpHelper.MakeRequest("GET", "/admin/webhooks", nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), created.Secret)
+		var listed account.ListWebhooksResponse
+		httpHelper.AssertJSONResponse(t, w, &listed)
+		assert.Len(t, listed.Webhooks, 1)
+		assert.Equal(t, created.ID, listed.Webhooks[0].ID)
+
+		path := "/admin/webhooks/" + strconv.FormatUint(uint64(created.ID), 10)
+		w = httpHelper.MakeRequest("DELETE", path, nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httpHelper.MakeRequest("DELETE", path, nil, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httpHelper.MakeRequest("POST", path+"/rotate-secret", nil, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should send a signed ping and report the delivery", func(t *testing.T) {
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+
+		httpHelper := setup(t)
+		receiver := newWebhookReceiver(t)
+		created := create(t, httpHelper, receiver.server.URL)
+
+		w := httpHelper.MakeRequest("POST", testPath(created.ID), nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response account.TestWebhookResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.True(t, response.Delivered)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+		assert.Empty(t, response.Error)
+
+		headers, payloads := receiver.received()
+		assert.Len(t, payloads, 1)
+		assert.Equal(t, account.WebhookPingEvent, headers[0].Get(webhook.EventHeader))
+		timestamp := headers[0].Get(webhook.TimestampHeader)
+		assert.Equal(t, webhook.Signature(created.Secret, timestamp, payloads[0]), headers[0].Get(webhook.SignatureHeader))
+
+		var event struct {
+			Type string                `json:"type"`
+			Data account.PingEventData `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(payloads[0], &event))
+		assert.Equal(t, account.WebhookPingEvent, event.Type)
+		assert.Equal(t, created.ID, event.Data.WebhookID)
+	})
+
+	t.Run("should report the error of a failing endpoint", func(t *testing.T) {
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+
+		httpHelper := setup(t)
+		attempts := 0
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+		created := create(t, httpHelper, failing.URL)
+
+		w := httpHelper.MakeRequest("POST", testPath(created.ID), nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response account.TestWebhookResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.False(t, response.Delivered)
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+		assert.Contains(t, response.Error, "status 500")
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("should report an unreachable endpoint", func(t *testing.T) {
+		viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+		defer viper.Reset()
+
+		httpHelper := setup(t)
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		created := create(t, httpHelper, closed.URL)
+
+		w := httpHelper.MakeRequest("POST", testPath(created.ID), nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response account.TestWebhookResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.False(t, response.Delivered)
+		assert.Zero(t, response.StatusCode)
+		assert.NotEmpty(t, response.Error)
+
+		w = httpHelper.MakeRequest("POST", testPath(created.ID+1), nil, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestWebhookHandler_OrganizationWebhook(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	db := newTestDB(t)
+	webhookHandler, err := account.NewWebhookHandler(logrus.New(), account.NewAccountWebhookRepository(db), webhook.NewSender())
+	assert.NoError(t, err)
+	organizationHandler := organization.NewOrganizationHandler(nil, organization.NewOrganizationRepository(db))
+
+	owner := domain.Account{Email: "owner@example.com"}
+	other := domain.Account{Email: "other@example.com"}
+	assert.NoError(t, db.Create(&owner).Error)
+	assert.NoError(t, db.Create(&other).Error)
+	owned := domain.Organization{OwnerID: owner.ID, Name: "owned"}
+	foreign := domain.Organization{OwnerID: other.ID, Name: "foreign"}
+	assert.NoError(t, db.Create(&owned).Error)
+	assert.NoError(t, db.Create(&foreign).Error)
+
+	httpHelper := NewHTTPTestHelper()
+	httpHelper.SetupHandler("POST", "/admin/webhooks", webhookHandler.CreateWebhook)
+	httpHelper.router.POST("/organization/:id/webhooks/:wid/test", func(c *gin.Context) {
+		c.Set(utils.AccountIdContextKey, owner.ID)
+	}, organizationHandler.RequireOwnership, webhookHandler.TestOrganizationWebhook)
+
+	receiver := newWebhookReceiver(t)
+	create := func(organizationID *uint) account.CreateWebhookResponse {
+		w := httpHelper.MakeRequest("POST", "/admin/webhooks", account.CreateWebhookRequest{
+			URL:            receiver.server.URL,
+			Activities:     []string{domain.ActivityLogin},
+			OrganizationID: organizationID,
+		}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var created account.CreateWebhookResponse
+		httpHelper.AssertJSONResponse(t, w, &created)
+		return created
+	}
+	ownedWebhook := create(&owned.ID)
+	foreignWebhook := create(&foreign.ID)
+	adminWebhook := create(nil)
+
+	testPath := func(organizationID uint, webhookID uint) string {
+		return "/organization/" + strconv.FormatUint(uint64(organizationID), 10) +
+			"/webhooks/" + strconv.FormatUint(uint64(webhookID), 10) + "/test"
+	}
+
+	t.Run("should ping a webhook of the owned organization", func(t *testing.T) {
+		assert.Equal(t, &owned.ID, ownedWebhook.OrganizationID)
+
+		w := httpHelper.MakeRequest("POST", testPath(owned.ID, ownedWebhook.ID), nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response account.TestWebhookResponse
+		httpHelper.AssertJSONResponse(t, w, &response)
+		assert.True(t, response.Delivered)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+		headers, _ := receiver.received()
+		assert.Len(t, headers, 1)
+		assert.Equal(t, account.WebhookPingEvent, headers[0].Get(webhook.EventHeader))
+	})
+
+	t.Run("should not ping a webhook of another organization", func(t *testing.T) {
+		for _, webhookID := range []uint{foreignWebhook.ID, adminWebhook.ID, adminWebhook.ID + 1} {
+			w := httpHelper.MakeRequest("POST", testPath(owned.ID, webhookID), nil, nil)
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.Contains(t, w.Body.String(), "webhook.not_found")
+		}
+	})
+
+	t.Run("should check the ownership of the organization", func(t *testing.T) {
+		w := httpHelper.MakeRequest("POST", testPath(foreign.ID, foreignWebhook.ID), nil, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httpHelper.MakeRequest("POST", testPath(foreign.ID+1, ownedWebhook.ID), nil, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		headers, _ := receiver.received()
+		assert.Len(t, headers, 1)
+	})
+}
@@ -0,0 +1,348 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"spsyncpro_api/pkg/domain"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPendingActionInterval       = time.Minute
+	defaultRevokedTokenCleanupInterval = time.Hour
+	defaultInactivityInterval          = time.Hour
+)
+
+// PendingActionWorker applies pending account actions once their grace period has passed
+type PendingActionWorker struct {
+	logger            *logrus.Logger
+	tracer            trace.Tracer
+	accountRepository domain.AccountRepository
+	activityNotifier  domain.ActivityNotifier
+	interval          time.Duration
+}
+
+func NewPendingActionWorker(logger *logrus.Logger, accountRepository domain.AccountRepository) *PendingActionWorker {
+	interval := viper.GetDuration("PENDING_ACTION_INTERVAL")
+	if interval <= 0 {
+		interval = defaultPendingActionInterval
+	}
+	return &PendingActionWorker{
+		logger:            logger,
+		tracer:            otel.Tracer("pendingActionWorker"),
+		accountRepository: accountRepository,
+		interval:          interval,
+	}
+}
+
+// SetActivityNotifier reports the activities of applied actions to the notifier
+func (w *PendingActionWorker) SetActivityNotifier(notifier domain.ActivityNotifier) {
+	w.activityNotifier = notifier
+}
+
+// Start processes due actions on every tick until the context is cancelled
+func (w *PendingActionWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := w.ProcessDue(ctx, now); err != nil {
+				w.logger.Errorf("failed to process pending actions: %v", err)
+			}
+		}
+	}
+}
+
+// ProcessDue applies every pending action whose grace period ended before now
+func (w *PendingActionWorker) ProcessDue(ctx context.Context, now time.Time) error {
+	ctx, span := w.tracer.Start(ctx, "ProcessDue")
+	defer span.End()
+
+	actions, err := w.accountRepository.GetDuePendingActions(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for i := range actions {
+		action := &actions[i]
+		if err := w.apply(ctx, action); err != nil {
+			w.logger.WithField("userId", action.AccountID).Errorf("failed to apply pending action %s: %v", action.Action, err)
+			if errors.Is(err, domain.ErrAccountAlreadyExists) {
+				w.cancel(ctx, action, now)
+			}
+			continue
+		}
+
+		appliedAt := now
+		action.AppliedAt = &appliedAt
+		if _, err := w.accountRepository.UpdatePendingAction(ctx, action); err != nil {
+			w.logger.WithField("userId", action.AccountID).Errorf("failed to mark pending action applied: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (w *PendingActionWorker) apply(ctx context.Context, action *domain.PendingAccountAction) error {
+	switch action.Action {
+	case domain.PendingActionDelete:
+		activity, err := removeAccount(ctx, w.accountRepository, action.AccountID)
+		if err != nil {
+			return err
+		}
+		w.logActivity(ctx, action.AccountID, activity)
+	case domain.PendingActionChangeEmail:
+		acc, err := w.accountRepository.GetAccountByID(ctx, action.AccountID)
+		if err != nil {
+			return err
+		}
+		// the new email may have been registered during the grace period
+		existing, err := w.accountRepository.GetAccountByEmail(ctx, action.Payload)
+		if err == nil && existing.ID != acc.ID {
+			return domain.ErrAccountAlreadyExists
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// the new email was proven by the confirmed email change token
+		verifiedAt := time.Now()
+		acc.Email = action.Payload
+		acc.EmailVerified = true
+		acc.VerifiedAt = &verifiedAt
+		if _, err := w.accountRepository.UpdateAccount(ctx, acc); err != nil {
+			// the unique index settles a registration racing this update
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return domain.ErrAccountAlreadyExists
+			}
+			return err
+		}
+		w.logActivity(ctx, action.AccountID, domain.ActivityUpdate)
+	default:
+		return fmt.Errorf("unknown pending action %q", action.Action)
+	}
+	return nil
+}
+
+// cancel drops an action that can never apply so it is not retried on every tick
+func (w *PendingActionWorker) cancel(ctx context.Context, action *domain.PendingAccountAction, now time.Time) {
+	cancelledAt := now
+	action.CancelledAt = &cancelledAt
+	if _, err := w.accountRepository.UpdatePendingAction(ctx, action); err != nil {
+		w.logger.WithField("userId", action.AccountID).Errorf("failed to cancel pending action: %v", err)
+	}
+}
+
+func (w *PendingActionWorker) logActivity(ctx context.Context, accountID uint, activity string) {
+	if err := w.accountRepository.LogAccountActivity(ctx, accountID, activity); err != nil {
+		w.logger.WithField("userId", accountID).Errorf("failed to log activity: %v", err)
+		return
+	}
+	if w.activityNotifier != nil {
+		w.activityNotifier.NotifyActivity(ctx, accountID, activity)
+	}
+}
+
+// RevokedTokenCleanupWorker purges revoked tokens once they have expired,
+// an expired token is rejected regardless of its revocation
+type RevokedTokenCleanupWorker struct {
+	logger            *logrus.Logger
+	tracer            trace.Tracer
+	accountRepository domain.AccountRepository
+	interval          time.Duration
+}
+
+func NewRevokedTokenCleanupWorker(logger *logrus.Logger, accountRepository domain.AccountRepository) *RevokedTokenCleanupWorker {
+	interval := viper.GetDuration("REVOKED_TOKEN_CLEANUP_INTERVAL")
+	if interval <= 0 {
+		interval = defaultRevokedTokenCleanupInterval
+	}
+	return &RevokedTokenCleanupWorker{
+		logger:            logger,
+		tracer:            otel.Tracer("revokedTokenCleanupWorker"),
+		accountRepository: accountRepository,
+		interval:          interval,
+	}
+}
+
+// Start purges expired entries on every tick until the context is cancelled
+func (w *RevokedTokenCleanupWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := w.Purge(ctx, now); err != nil {
+				w.logger.Errorf("failed to purge revoked tokens: %v", err)
+			}
+		}
+	}
+}
+
+// Purge removes every revoked token that expired before now
+func (w *RevokedTokenCleanupWorker) Purge(ctx context.Context, now time.Time) error {
+	ctx, span := w.tracer.Start(ctx, "Purge")
+	defer span.End()
+
+	purged, err := w.accountRepository.PurgeExpiredRevokedTokens(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		w.logger.Infof("purged %d expired revoked tokens", purged)
+	}
+
+	return nil
+}
+
+// InactivityWorker disables non-admin accounts that have not logged in for
+// ACCOUNT_INACTIVITY_DAYS, optionally warning them by email beforehand
+type InactivityWorker struct {
+	logger            *logrus.Logger
+	tracer            trace.Tracer
+	accountRepository domain.AccountRepository
+	accountService    domain.AccountService
+	activityNotifier  domain.ActivityNotifier
+	interval          time.Duration
+	threshold         time.Duration
+	warning           time.Duration
+}
+
+func NewInactivityWorker(
+	logger *logrus.Logger,
+	accountRepository domain.AccountRepository,
+	accountService domain.AccountService,
+) *InactivityWorker {
+	interval := viper.GetDuration("ACCOUNT_INACTIVITY_INTERVAL")
+	if interval <= 0 {
+		interval = defaultInactivityInterval
+	}
+	return &InactivityWorker{
+		logger:            logger,
+		tracer:            otel.Tracer("inactivityWorker"),
+		accountRepository: accountRepository,
+		accountService:    accountService,
+		interval:          interval,
+		threshold:         time.Duration(viper.GetInt("ACCOUNT_INACTIVITY_DAYS")) * 24 * time.Hour,
+		warning:           time.Duration(viper.GetInt("ACCOUNT_INACTIVITY_WARNING_DAYS")) * 24 * time.Hour,
+	}
+}
+
+// SetActivityNotifier reports disabled accounts to the notifier
+func (w *InactivityWorker) SetActivityNotifier(notifier domain.ActivityNotifier) {
+	w.activityNotifier = notifier
+}
+
+// Enabled reports whether an inactivity threshold is configured
+func (w *InactivityWorker) Enabled() bool {
+	return w.threshold > 0
+}
+
+// Start checks for dormant accounts on every tick until the context is cancelled
+func (w *InactivityWorker) Start(ctx context.Context) {
+	if !w.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := w.Process(ctx, now); err != nil {
+				w.logger.Errorf("failed to process inactive accounts: %v", err)
+			}
+		}
+	}
+}
+
+// Process disables accounts dormant past the threshold, then warns accounts
+// that will cross it within the warning window
+func (w *InactivityWorker) Process(ctx context.Context, now time.Time) error {
+	ctx, span := w.tracer.Start(ctx, "Process")
+	defer span.End()
+
+	if !w.Enabled() {
+		return nil
+	}
+
+	dormant, err := w.accountRepository.GetInactiveAccounts(ctx, now.Add(-w.threshold))
+	if err != nil {
+		return err
+	}
+
+	for i := range dormant {
+		acc := &dormant[i]
+		disabledAt := now
+		acc.DisabledAt = &disabledAt
+		if _, err := w.accountRepository.UpdateAccount(ctx, acc); err != nil {
+			w.logger.WithField("userId", acc.ID).Errorf("failed to disable inactive account: %v", err)
+			continue
+		}
+
+		if err := w.accountRepository.RevokeRefreshTokens(ctx, acc.ID, now); err != nil {
+			w.logger.WithField("userId", acc.ID).Errorf("failed to revoke refresh tokens: %v", err)
+		}
+		if err := w.accountRepository.RevokeAPIKeys(ctx, acc.ID, now); err != nil {
+			w.logger.WithField("userId", acc.ID).Errorf("failed to revoke api keys: %v", err)
+		}
+
+		if err := w.accountRepository.LogAccountActivity(ctx, acc.ID, domain.ActivityDisable); err != nil {
+			w.logger.WithField("userId", acc.ID).Errorf("failed to log activity: %v", err)
+		} else if w.activityNotifier != nil {
+			w.activityNotifier.NotifyActivity(ctx, acc.ID, domain.ActivityDisable)
+		}
+	}
+
+	if w.warning <= 0 || w.warning >= w.threshold {
+		return nil
+	}
+
+	// dormant accounts were disabled above, so these are all still enabled
+	inactive, err := w.accountRepository.GetInactiveAccounts(ctx, now.Add(-w.threshold+w.warning))
+	if err != nil {
+		return err
+	}
+
+	for i := range inactive {
+		acc := &inactive[i]
+		if acc.InactivityWarnedAt != nil {
+			continue
+		}
+
+		lastActivity := acc.CreatedAt
+		if acc.LastLoginAt != nil {
+			lastActivity = *acc.LastLoginAt
+		}
+
+		if err := w.accountService.SendInactivityWarningEmail(ctx, acc.Email, lastActivity.Add(w.threshold)); err != nil {
+			w.logger.WithField("userId", acc.ID).Errorf("failed to send inactivity warning: %v", err)
+			continue
+		}
+
+		warnedAt := now
+		acc.InactivityWarnedAt = &warnedAt
+		if _, err := w.accountRepository.UpdateAccount(ctx, acc); err != nil {
+			w.logger.WithField("userId", acc.ID).Errorf("failed to mark inactivity warning sent: %v", err)
+		}
+	}
+
+	return nil
+}
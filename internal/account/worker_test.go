@@ -0,0 +1,235 @@
+package account_test
+
+import (
+	"context"
+	"spsyncpro_api/internal/account"
+	"spsyncpro_api/pkg/database"
+	"spsyncpro_api/pkg/domain"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestPendingActionWorker_ProcessDue(t *testing.T) {
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should apply a deletion after the grace period", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		now := time.Now()
+
+		due := []domain.PendingAccountAction{
+			{ID: 1, AccountID: 7, Action: domain.PendingActionDelete, ExecuteAfter: now.Add(-time.Minute)},
+		}
+		repository.On("GetDuePendingActions", anyContext, now).Return(due, nil)
+		repository.On("DeleteAccount", anyContext, uint(7)).Return(nil)
+		repository.On("LogAccountActivity", anyContext, uint(7), domain.ActivityDelete).Return(nil)
+		repository.On("UpdatePendingAction", anyContext, mock.MatchedBy(func(a *domain.PendingAccountAction) bool {
+			return a.ID == 1 && a.AppliedAt != nil && a.AppliedAt.Equal(now)
+		})).Return(&domain.PendingAccountAction{}, nil)
+
+		worker := account.NewPendingActionWorker(logrus.New(), repository)
+		err := worker.ProcessDue(context.Background(), now)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should apply an email change after the grace period", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		now := time.Now()
+
+		due := []domain.PendingAccountAction{
+			{ID: 2, AccountID: 8, Action: domain.PendingActionChangeEmail, Payload: "new@example.com", ExecuteAfter: now.Add(-time.Minute)},
+		}
+		repository.On("GetDuePendingActions", anyContext, now).Return(due, nil)
+		repository.On("GetAccountByID", anyContext, uint(8)).Return(&domain.Account{ID: 8, Email: "old@example.com"}, nil)
+		repository.On("GetAccountByEmail", anyContext, "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+		repository.On("UpdateAccount", anyContext, mock.MatchedBy(func(a *domain.Account) bool {
+			return a.Email == "new@example.com" && a.EmailVerified && a.VerifiedAt != nil
+		})).Return(&domain.Account{ID: 8, Email: "new@example.com"}, nil)
+		repository.On("LogAccountActivity", anyContext, uint(8), domain.ActivityUpdate).Return(nil)
+		repository.On("UpdatePendingAction", anyContext, mock.AnythingOfType("*domain.PendingAccountAction")).Return(&domain.PendingAccountAction{}, nil)
+
+		worker := account.NewPendingActionWorker(logrus.New(), repository)
+		err := worker.ProcessDue(context.Background(), now)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should cancel an email change when the email was registered in the meantime", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		now := time.Now()
+
+		due := []domain.PendingAccountAction{
+			{ID: 3, AccountID: 8, Action: domain.PendingActionChangeEmail, Payload: "new@example.com", ExecuteAfter: now.Add(-time.Minute)},
+		}
+		repository.On("GetDuePendingActions", anyContext, now).Return(due, nil)
+		repository.On("GetAccountByID", anyContext, uint(8)).Return(&domain.Account{ID: 8, Email: "old@example.com"}, nil)
+		// a registration racing the update is only caught by the unique index
+		repository.On("GetAccountByEmail", anyContext, "new@example.com").Return(nil, gorm.ErrRecordNotFound)
+		repository.On("UpdateAccount", anyContext, mock.AnythingOfType("*domain.Account")).Return(nil, gorm.ErrDuplicatedKey)
+		repository.On("UpdatePendingAction", anyContext, mock.MatchedBy(func(a *domain.PendingAccountAction) bool {
+			return a.ID == 3 && a.AppliedAt == nil && a.CancelledAt != nil && a.CancelledAt.Equal(now)
+		})).Return(&domain.PendingAccountAction{}, nil)
+
+		worker := account.NewPendingActionWorker(logrus.New(), repository)
+		err := worker.ProcessDue(context.Background(), now)
+		assert.NoError(t, err)
+		repository.AssertNotCalled(t, "LogAccountActivity", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should not apply anything before the grace period ends", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		now := time.Now()
+
+		repository.On("GetDuePendingActions", anyContext, now).Return([]domain.PendingAccountAction{}, nil)
+
+		worker := account.NewPendingActionWorker(logrus.New(), repository)
+		err := worker.ProcessDue(context.Background(), now)
+		assert.NoError(t, err)
+		repository.AssertNotCalled(t, "DeleteAccount", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokedTokenCleanupWorker_Purge(t *testing.T) {
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should purge revoked tokens past their expiry", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+		now := time.Now()
+
+		repository.On("PurgeExpiredRevokedTokens", anyContext, now).Return(int64(3), nil)
+
+		worker := account.NewRevokedTokenCleanupWorker(logrus.New(), repository)
+		err := worker.Purge(context.Background(), now)
+		assert.NoError(t, err)
+	})
+}
+
+// newTestDB opens a fresh in-memory sqlite database with every table migrated
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	assert.NoError(t, err)
+
+	// every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	err = database.AutoMigrate(db)
+	assert.NoError(t, err)
+
+	return db
+}
+
+func TestInactivityWorker_Process(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	now := time.Now()
+	daysAgo := func(days int) *time.Time {
+		at := now.Add(-time.Duration(days) * 24 * time.Hour)
+		return &at
+	}
+
+	t.Run("should disable only dormant non-admin accounts", func(t *testing.T) {
+		viper.Set("ACCOUNT_INACTIVITY_DAYS", 90)
+		defer viper.Reset()
+
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		seeded := []domain.Account{
+			{Email: "recent@example.com", LastLoginAt: daysAgo(1)},
+			{Email: "borderline@example.com", LastLoginAt: daysAgo(89)},
+			{Email: "dormant@example.com", LastLoginAt: daysAgo(120)},
+			{Email: "admin@example.com", Role: domain.RoleAdmin, LastLoginAt: daysAgo(365)},
+			{Email: "never@example.com", CreatedAt: *daysAgo(100)},
+			{Email: "new@example.com"},
+		}
+		assert.NoError(t, db.Create(&seeded).Error)
+		assert.NoError(t, db.Create(&domain.APIKey{AccountID: seeded[2].ID, Name: "dormant", KeyHash: "dormant"}).Error)
+		assert.NoError(t, db.Create(&domain.APIKey{AccountID: seeded[0].ID, Name: "recent", KeyHash: "recent"}).Error)
+
+		worker := account.NewInactivityWorker(logrus.New(), repository, domain.NewMockAccountService(t))
+		err := worker.Process(context.Background(), now)
+		assert.NoError(t, err)
+
+		var keys []domain.APIKey
+		assert.NoError(t, db.Order("id").Find(&keys).Error)
+		if assert.Len(t, keys, 2) {
+			assert.NotNil(t, keys[0].RevokedAt)
+			assert.Nil(t, keys[1].RevokedAt)
+		}
+
+		var accounts []domain.Account
+		assert.NoError(t, db.Order("id").Find(&accounts).Error)
+
+		disabled := map[string]bool{}
+		for _, acc := range accounts {
+			disabled[acc.Email] = acc.DisabledAt != nil
+		}
+		assert.Equal(t, map[string]bool{
+			"recent@example.com":     false,
+			"borderline@example.com": false,
+			"dormant@example.com":    true,
+			"admin@example.com":      false,
+			"never@example.com":      true,
+			"new@example.com":        false,
+		}, disabled)
+
+		var activities int64
+		db.Model(&domain.AccountActivity{}).Where("activity = ?", domain.ActivityDisable).Count(&activities)
+		assert.Equal(t, int64(2), activities)
+	})
+
+	t.Run("should warn accounts approaching the threshold once", func(t *testing.T) {
+		viper.Set("ACCOUNT_INACTIVITY_DAYS", 90)
+		viper.Set("ACCOUNT_INACTIVITY_WARNING_DAYS", 7)
+		defer viper.Reset()
+
+		db := newTestDB(t)
+		repository := account.NewAccountRepository(db)
+
+		seeded := []domain.Account{
+			{Email: "recent@example.com", LastLoginAt: daysAgo(30)},
+			{Email: "approaching@example.com", LastLoginAt: daysAgo(85)},
+		}
+		assert.NoError(t, db.Create(&seeded).Error)
+
+		service := domain.NewMockAccountService(t)
+		service.On("SendInactivityWarningEmail", mock.Anything, "approaching@example.com", mock.MatchedBy(func(disableAt time.Time) bool {
+			return disableAt.Equal(daysAgo(85).Add(90 * 24 * time.Hour))
+		})).Return(nil).Once()
+
+		worker := account.NewInactivityWorker(logrus.New(), repository, service)
+		assert.NoError(t, worker.Process(context.Background(), now))
+		// a second run must not send the warning again
+		assert.NoError(t, worker.Process(context.Background(), now.Add(time.Hour)))
+
+		var approaching domain.Account
+		assert.NoError(t, db.Where("email = ?", "approaching@example.com").First(&approaching).Error)
+		assert.NotNil(t, approaching.InactivityWarnedAt)
+		assert.Nil(t, approaching.DisabledAt)
+	})
+
+	t.Run("should do nothing when no threshold is configured", func(t *testing.T) {
+		repository := domain.NewMockAccountRepository(t)
+
+		worker := account.NewInactivityWorker(logrus.New(), repository, domain.NewMockAccountService(t))
+		assert.False(t, worker.Enabled())
+		assert.NoError(t, worker.Process(context.Background(), now))
+	})
+}
@@ -0,0 +1,74 @@
+package debug
+
+import (
+	"errors"
+	"net/http"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/mailer"
+	"spsyncpro_api/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	errNotFound         = errors.New("not found")
+	errTemplateRequired = errors.New("template is required")
+	errTemplateNotFound = errors.New("template not found")
+)
+
+type DebugHandler struct {
+	logger *logrus.Logger
+	tracer trace.Tracer
+}
+
+func NewDebugHandler(logger *logrus.Logger) *DebugHandler {
+	tracer := otel.Tracer("debugHandler")
+	return &DebugHandler{
+		logger: logger,
+		tracer: tracer,
+	}
+}
+
+// @Summary		Preview an email template
+// @Description	Render an email template with sample data, only available outside production mode
+// @Tags			debug
+// @Produce		html
+// @Param			template	query		string	true	"Template name"
+// @Success		200			{string}	string
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		404			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/v1/debug/email-preview [get]
+func (h *DebugHandler) EmailPreview(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "EmailPreview")
+	defer span.End()
+
+	if viper.GetString("SERVER_MODE") == "production" {
+		utils.RespondErrorStatus(c, http.StatusNotFound, errNotFound)
+		return
+	}
+
+	name := c.Query("template")
+	if name == "" {
+		utils.RespondErrorStatus(c, http.StatusBadRequest, errTemplateRequired)
+		return
+	}
+
+	html, err := mailer.RenderTemplatePreview(name)
+	if err != nil {
+		if errors.Is(err, mailer.ErrTemplateNotFound) {
+			utils.RespondErrorStatus(c, http.StatusNotFound, errTemplateNotFound)
+			return
+		}
+		h.logger.WithContext(ctx).WithField("template", name).Errorf("failed to render template: %v", err)
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
@@ -0,0 +1,64 @@
+package debug_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/internal/debug"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func setupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := debug.NewDebugHandler(logrus.New())
+	router.GET("/debug/email-preview", handler.EmailPreview)
+	return router
+}
+
+func TestDebugHandler_EmailPreview(t *testing.T) {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should render a known template with sample data", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "debug")
+		defer viper.Reset()
+
+		req := httptest.NewRequest("GET", "/debug/email-preview?template=password_reset", nil)
+		w := httptest.NewRecorder()
+		setupRouter().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Body.String(), "Password Reset Request")
+		assert.Contains(t, w.Body.String(), "reset-password?token=sample-token")
+	})
+
+	t.Run("should return 404 for an unknown template", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "debug")
+		defer viper.Reset()
+
+		req := httptest.NewRequest("GET", "/debug/email-preview?template=unknown", nil)
+		w := httptest.NewRecorder()
+		setupRouter().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should return 404 in production mode", func(t *testing.T) {
+		viper.Set("SERVER_MODE", "production")
+		defer viper.Reset()
+
+		req := httptest.NewRequest("GET", "/debug/email-preview?template=password_reset", nil)
+		w := httptest.NewRecorder()
+		setupRouter().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NotContains(t, w.Body.String(), "Password Reset Request")
+	})
+}
@@ -1,15 +1,22 @@
 package organization
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"spsyncpro_api/pkg/domain"
 	"spsyncpro_api/pkg/msgraphapi"
 	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 type OrganizationHandler struct {
@@ -31,11 +38,14 @@ func NewOrganizationHandler(
 }
 
 type UpsertOrganizationRequest struct {
-	Name         string `json:"name"`
-	Description  string `json:"description"`
-	ClientID     string `json:"client_id"`
-	TenantID     string `json:"tenant_id"`
+	Name         string `json:"name" binding:"required,min=2,max=100"`
+	Description  string `json:"description" binding:"max=500"`
+	ClientID     string `json:"client_id" binding:"required"`
+	TenantID     string `json:"tenant_id" binding:"required"`
 	ClientSecret string `json:"client_secret"`
+	// ClientSecretExpiresAt is the expiry of the app registration secret, kept
+	// as stored when omitted together with the secret
+	ClientSecretExpiresAt *time.Time `json:"client_secret_expires_at"`
 }
 
 type UpsertOrganizationResponse struct {
@@ -51,8 +61,9 @@ type UpsertOrganizationResponse struct {
 // @Produce		json
 // @Param			organization	body		UpsertOrganizationRequest	true	"Organization"
 // @Success		200		{object}	UpsertOrganizationResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
 // @Router			/api/v1/organization/upsert [post]
 func (h *OrganizationHandler) UpsertOrganization(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -60,35 +71,38 @@ func (h *OrganizationHandler) UpsertOrganization(c *gin.Context) {
 	defer span.End()
 
 	var req UpsertOrganizationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	auditBody, err := utils.BindJSONWithAudit(c, &req)
+	if err != nil {
+		utils.RespondBindError(c, err)
 		return
 	}
+	span.SetAttributes(attribute.String("audit.request_body", auditBody))
 
 	accountID := c.GetUint(utils.AccountIdContextKey)
 	if accountID == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
-	clientSecret, err := h.organizationService.EncryptClientSecret(ctx, req.ClientSecret)
+	clientSecret, encryptedSecret, secretExpiresAt, err := h.resolveClientSecret(ctx, accountID, req.ClientSecret, req.ClientSecretExpiresAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	newOrg := &domain.Organization{
-		Name:         req.Name,
-		Description:  req.Description,
-		OwnerID:      accountID,
-		ClientID:     req.ClientID,
-		TenantID:     req.TenantID,
-		ClientSecret: clientSecret,
+		Name:                  req.Name,
+		Description:           req.Description,
+		OwnerID:               accountID,
+		ClientID:              req.ClientID,
+		TenantID:              req.TenantID,
+		ClientSecret:          encryptedSecret,
+		ClientSecretExpiresAt: secretExpiresAt,
 	}
 
 	newOrg, err = h.organizationRepository.UpsertOrganization(ctx, newOrg)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -100,17 +114,127 @@ func (h *OrganizationHandler) UpsertOrganization(c *gin.Context) {
 
 	ok, err := msGraphApiService.CheckAuthorized(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.recordAuthorizationError(ctx, newOrg.ID, err)
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, UpsertOrganizationResponse{
+	err = h.organizationRepository.UpdateAuthorizationStatus(ctx, newOrg.ID, ok)
+	if err != nil {
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, UpsertOrganizationResponse{
 		ID:           newOrg.ID,
 		IsAuthorized: ok,
 		AuthorizeURL: fmt.Sprintf("https://login.microsoftonline.com/%s/adminconsent?client_id=%s", newOrg.TenantID, newOrg.ClientID),
 	})
 }
 
+// resolveClientSecret returns the plaintext and encrypted client secret and
+// its expiry for an upsert, an empty secret in the request keeps the stored
+// one as it is, and its expiry unless the request sets one
+func (h *OrganizationHandler) resolveClientSecret(ctx context.Context, ownerID uint, clientSecret string, expiresAt *time.Time) (string, string, *time.Time, error) {
+	if clientSecret != "" {
+		encryptedSecret, err := h.organizationService.EncryptClientSecret(ctx, clientSecret)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return clientSecret, encryptedSecret, expiresAt, nil
+	}
+
+	existing, err := h.organizationRepository.GetOrganizationByOwnerID(ctx, ownerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", "", expiresAt, nil
+		}
+		return "", "", nil, err
+	}
+
+	clientSecret, err = h.organizationService.DecryptClientSecret(ctx, existing.ClientSecret)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if expiresAt == nil {
+		expiresAt = existing.ClientSecretExpiresAt
+	}
+	return clientSecret, existing.ClientSecret, expiresAt, nil
+}
+
+// assertOwnership returns the organization addressed by orgID when the
+// account owns it, ErrForbidden when another account does and
+// ErrOrganizationNotFound when there is no such organization. Every route
+// that takes an organization id goes through it.
+func (h *OrganizationHandler) assertOwnership(ctx context.Context, accountID uint, orgID uint) (*domain.Organization, error) {
+	ctx, span := h.tracer.Start(ctx, "assertOwnership")
+	defer span.End()
+
+	organization, err := h.organizationRepository.GetOrganizationByID(ctx, orgID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+
+	if organization.OwnerID != accountID {
+		return nil, domain.ErrForbidden
+	}
+
+	return organization, nil
+}
+
+// RequireOwnership admits requests to the /organization/:id routes of an
+// organization owned by the authenticated account, the handlers after it read
+// the id from utils.OrganizationIdContextKey
+func (h *OrganizationHandler) RequireOwnership(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "RequireOwnership")
+	defer span.End()
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		utils.RespondError(c, domain.ErrInternal)
+		c.Abort()
+		return
+	}
+
+	// an id that does not parse cannot name an organization
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondError(c, domain.ErrOrganizationNotFound)
+		c.Abort()
+		return
+	}
+
+	organization, err := h.assertOwnership(ctx, accountID, uint(id))
+	if err != nil {
+		if errors.Is(err, domain.ErrOrganizationNotFound) || errors.Is(err, domain.ErrForbidden) {
+			utils.RespondError(c, err)
+			c.Abort()
+			return
+		}
+		utils.RespondError(c, domain.ErrInternal)
+		c.Abort()
+		return
+	}
+
+	c.Set(utils.OrganizationIdContextKey, organization.ID)
+	c.Next()
+}
+
+// recordAuthorizationError stores a failed Graph check so the health overview
+// shows the organization as errored, the check error is still returned
+func (h *OrganizationHandler) recordAuthorizationError(ctx context.Context, id uint, checkErr error) {
+	ctx, span := h.tracer.Start(ctx, "recordAuthorizationError")
+	defer span.End()
+
+	if err := h.organizationRepository.UpdateAuthorizationError(ctx, id, checkErr.Error()); err != nil {
+		span.RecordError(err)
+	}
+}
+
 type GetOrganizationResponse struct {
 	ID           uint   `json:"id"`
 	Name         string `json:"name"`
@@ -126,27 +250,34 @@ type GetOrganizationResponse struct {
 // @Accept			json
 // @Produce		json
 // @Success		200		{object}	GetOrganizationResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		404		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
 // @Router			/api/v1/organization/get [get]
 func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
 	ctx := c.Request.Context()
 	ctx, span := h.tracer.Start(ctx, "GetOrganization")
 	defer span.End()
 
+	utils.SetCacheControl(c, "CACHE_CONTROL_ACCOUNT", utils.CacheControlPrivate)
+
 	accountID := c.GetUint(utils.AccountIdContextKey)
 	if accountID == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
 	organization, err := h.organizationRepository.GetOrganizationByOwnerID(ctx, accountID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, domain.ErrOrganizationNotFound)
+			return
+		}
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, GetOrganizationResponse{
+	utils.RespondJSON(c, http.StatusOK, GetOrganizationResponse{
 		ID:           organization.ID,
 		Name:         organization.Name,
 		Description:  organization.Description,
@@ -166,8 +297,8 @@ type DeleteOrganizationResponse struct {
 // @Accept			json
 // @Produce		json
 // @Success		200		{object}	DeleteOrganizationResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
 // @Router			/api/v1/organization/delete [delete]
 func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -176,17 +307,17 @@ func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
 
 	accountID := c.GetUint(utils.AccountIdContextKey)
 	if accountID == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
 	err := h.organizationRepository.DeleteOrganizationByOwnerID(ctx, accountID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, DeleteOrganizationResponse{
+	utils.RespondJSON(c, http.StatusOK, DeleteOrganizationResponse{
 		Message: "organization deleted successfully",
 	})
 }
@@ -203,8 +334,9 @@ type CheckAuthorizationResponse struct {
 // @Accept			json
 // @Produce		json
 // @Success		200		{object}	CheckAuthorizationResponse
-// @Failure		400		{object}	map[string]string
-// @Failure		500		{object}	map[string]string
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		404		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
 // @Router			/api/v1/organization/check-authorization [get]
 func (h *OrganizationHandler) CheckAuthorization(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -213,19 +345,23 @@ func (h *OrganizationHandler) CheckAuthorization(c *gin.Context) {
 
 	accountID := c.GetUint(utils.AccountIdContextKey)
 	if accountID == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		utils.RespondError(c, domain.ErrInternal)
 		return
 	}
 
 	organization, err := h.organizationRepository.GetOrganizationByOwnerID(ctx, accountID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondError(c, domain.ErrOrganizationNotFound)
+			return
+		}
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	clientSecret, err := h.organizationService.DecryptClientSecret(ctx, organization.ClientSecret)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
 		return
 	}
 	organization.ClientSecret = clientSecret
@@ -238,20 +374,225 @@ func (h *OrganizationHandler) CheckAuthorization(c *gin.Context) {
 
 	ok, err := msGraphApiService.CheckAuthorized(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.recordAuthorizationError(ctx, organization.ID, err)
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.organizationRepository.UpdateAuthorizationStatus(ctx, organization.ID, ok)
+	if err != nil {
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	if ok {
-		c.JSON(http.StatusOK, CheckAuthorizationResponse{
+		utils.RespondJSON(c, http.StatusOK, CheckAuthorizationResponse{
 			Message:      "organization authorized",
 			AuthorizeURL: fmt.Sprintf("https://login.microsoftonline.com/%s/adminconsent?client_id=%s", organization.TenantID, organization.ClientID),
 		})
 	} else {
-		c.JSON(http.StatusOK, CheckAuthorizationResponse{
+		utils.RespondJSON(c, http.StatusOK, CheckAuthorizationResponse{
 			Message:      "organization not authorized",
 			AuthorizeURL: fmt.Sprintf("https://login.microsoftonline.com/%s/adminconsent?client_id=%s", organization.TenantID, organization.ClientID),
 		})
 	}
 
 }
+
+type RotateClientSecretRequest struct {
+	ClientSecret string `json:"client_secret" binding:"required"`
+}
+
+type RotateClientSecretResponse struct {
+	ID           uint   `json:"id"`
+	IsAuthorized bool   `json:"is_authorized"`
+	AuthorizeURL string `json:"authorize_url"`
+}
+
+// @Summary		Rotate the client secret of an organization
+// @Description	Replace the client secret and check it against Graph, the token cached for the old secret is discarded. A secret that Graph rejects is stored with is_authorized false.
+// @Tags			organization
+// @Accept			json
+// @Produce		json
+// @Param			secret	body		RotateClientSecretRequest	true	"Client secret"
+// @Success		200		{object}	RotateClientSecretResponse
+// @Failure		400		{object}	utils.ErrorResponse
+// @Failure		404		{object}	utils.ErrorResponse
+// @Failure		422		{object}	utils.ErrorResponse
+// @Failure		500		{object}	utils.ErrorResponse
+// @Router			/api/v1/organization/rotate-secret [post]
+func (h *OrganizationHandler) RotateClientSecret(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "RotateClientSecret")
+	defer span.End()
+
+	var req RotateClientSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err)
+		return
+	}
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	organization, err := h.organizationService.RotateClientSecret(ctx, accountID, req.ClientSecret)
+	if err != nil {
+		if errors.Is(err, domain.ErrClientSecretRequired) || errors.Is(err, domain.ErrOrganizationNotFound) {
+			utils.RespondError(c, err)
+			return
+		}
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, RotateClientSecretResponse{
+		ID:           organization.ID,
+		IsAuthorized: organization.IsAuthorized,
+		AuthorizeURL: fmt.Sprintf("https://login.microsoftonline.com/%s/adminconsent?client_id=%s", organization.TenantID, organization.ClientID),
+	})
+}
+
+type RefreshTokenResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary		Refresh the Graph token of an organization
+// @Description	Drop the cached Graph access token of the organization and acquire a new one, for a secret rotated outside of the api
+// @Tags			organization
+// @Produce		json
+// @Param			id	path		int	true	"Organization id"
+// @Success		200	{object}	RefreshTokenResponse
+// @Failure		403	{object}	utils.ErrorResponse
+// @Failure		404	{object}	utils.ErrorResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/organization/{id}/refresh-token [post]
+func (h *OrganizationHandler) RefreshToken(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "RefreshToken")
+	defer span.End()
+
+	accountID := c.GetUint(utils.AccountIdContextKey)
+	if accountID == 0 {
+		utils.RespondError(c, domain.ErrInternal)
+		return
+	}
+
+	// an id that does not parse cannot name an organization
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondError(c, domain.ErrOrganizationNotFound)
+		return
+	}
+
+	organization, err := h.assertOwnership(ctx, accountID, uint(id))
+	if err != nil {
+		if errors.Is(err, domain.ErrOrganizationNotFound) || errors.Is(err, domain.ErrForbidden) {
+			utils.RespondError(c, err)
+			return
+		}
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	clientSecret, err := h.organizationService.DecryptClientSecret(ctx, organization.ClientSecret)
+	if err != nil {
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	msGraphApiService := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+		ClientID:     organization.ClientID,
+		TenantID:     organization.TenantID,
+		ClientSecret: clientSecret,
+	})
+
+	_, err = msGraphApiService.RefreshAccessToken(ctx)
+	if err != nil {
+		h.recordAuthorizationError(ctx, organization.ID, err)
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, RefreshTokenResponse{
+		Message: "graph token refreshed",
+	})
+}
+
+const defaultSecretExpiryWarning = 30 * 24 * time.Hour
+
+// secretExpiryWarning is ORGANIZATION_SECRET_EXPIRY_WARNING, how long before
+// its expiry a client secret is reported as expiring soon
+func secretExpiryWarning() time.Duration {
+	warning := viper.GetDuration("ORGANIZATION_SECRET_EXPIRY_WARNING")
+	if warning <= 0 {
+		return defaultSecretExpiryWarning
+	}
+	return warning
+}
+
+type OrganizationHealthItem struct {
+	ID                     uint       `json:"id"`
+	OwnerID                uint       `json:"owner_id"`
+	Name                   string     `json:"name"`
+	TenantID               string     `json:"tenant_id"`
+	IsAuthorized           bool       `json:"is_authorized"`
+	AuthorizationError     string     `json:"authorization_error,omitempty"`
+	AuthorizationCheckedAt *time.Time `json:"authorization_checked_at"`
+	ClientSecretExpiresAt  *time.Time `json:"client_secret_expires_at"`
+}
+
+type OrganizationHealthResponse struct {
+	Total              int64                    `json:"total"`
+	Authorized         int64                    `json:"authorized"`
+	Unauthorized       int64                    `json:"unauthorized"`
+	Errored            int64                    `json:"errored"`
+	SecretExpiringSoon int64                    `json:"secret_expiring_soon"`
+	AtRisk             []OrganizationHealthItem `json:"at_risk"`
+}
+
+// @Summary		Organization authorization health
+// @Description	Count organizations by their last stored Graph check and secret expiry, and list the ones that are not authorized, errored or have a secret expiring soon. Graph is not called.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	OrganizationHealthResponse
+// @Failure		403	{object}	utils.ErrorResponse
+// @Failure		500	{object}	utils.ErrorResponse
+// @Router			/api/v1/admin/organizations/health [get]
+func (h *OrganizationHandler) GetOrganizationHealth(c *gin.Context) {
+	ctx := c.Request.Context()
+	ctx, span := h.tracer.Start(ctx, "GetOrganizationHealth")
+	defer span.End()
+
+	health, err := h.organizationRepository.GetOrganizationHealth(ctx, time.Now().Add(secretExpiryWarning()))
+	if err != nil {
+		utils.RespondErrorStatus(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	atRisk := make([]OrganizationHealthItem, 0, len(health.AtRisk))
+	for _, organization := range health.AtRisk {
+		atRisk = append(atRisk, OrganizationHealthItem{
+			ID:                     organization.ID,
+			OwnerID:                organization.OwnerID,
+			Name:                   organization.Name,
+			TenantID:               organization.TenantID,
+			IsAuthorized:           organization.IsAuthorized,
+			AuthorizationError:     organization.AuthorizationError,
+			AuthorizationCheckedAt: organization.AuthorizationCheckedAt,
+			ClientSecretExpiresAt:  organization.ClientSecretExpiresAt,
+		})
+	}
+
+	utils.RespondJSON(c, http.StatusOK, OrganizationHealthResponse{
+		Total:              health.Total,
+		Authorized:         health.Authorized,
+		Unauthorized:       health.Unauthorized,
+		Errored:            health.Errored,
+		SecretExpiringSoon: health.SecretExpiringSoon,
+		AtRisk:             atRisk,
+	})
+}
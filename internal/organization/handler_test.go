@@ -0,0 +1,300 @@
+package organization_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/internal/organization"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/msgraphapi/graphtest"
+	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestOrganizationHandler_PersistAuthorization(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	gin.SetMode(gin.TestMode)
+
+	server := graphtest.NewServer(t)
+	server.UseAsDefault(t)
+
+	db := newTestDB(t)
+	repository := organization.NewOrganizationRepository(db)
+	service, err := organization.NewOrganizationService(repository)
+	assert.NoError(t, err)
+	handler := organization.NewOrganizationHandler(service, repository)
+
+	router := gin.New()
+	group := router.Group("/", func(c *gin.Context) {
+		c.Set(utils.AccountIdContextKey, uint(1))
+	})
+	group.POST("/organization/upsert", handler.UpsertOrganization)
+	group.GET("/organization/check-authorization", handler.CheckAuthorization)
+
+	request := func(method, path string, body any) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewReader(payload)))
+		return w
+	}
+
+	isAuthorized := func() bool {
+		stored, err := repository.GetOrganizationByOwnerID(context.Background(), 1)
+		assert.NoError(t, err)
+		return stored.IsAuthorized
+	}
+
+	t.Run("should store a failed check on upsert", func(t *testing.T) {
+		server.SetScenario(graphtest.ScenarioConsentMissing)
+
+		w := request("POST", "/organization/upsert", organization.UpsertOrganizationRequest{
+			Name:         "contoso",
+			ClientID:     "client",
+			TenantID:     "tenant",
+			ClientSecret: "secret",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, isAuthorized())
+		server.AssertRequests(t, 1, 1)
+	})
+
+	t.Run("should flip the flag after a successful check", func(t *testing.T) {
+		server.SetScenario(graphtest.ScenarioAuthorized)
+
+		w := request("GET", "/organization/check-authorization", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, isAuthorized())
+		// the token issued for the upsert is reused
+		server.AssertRequests(t, 1, 2)
+	})
+
+	t.Run("should flip the flag back after a failed check", func(t *testing.T) {
+		server.SetScenario(graphtest.ScenarioConsentMissing)
+
+		w := request("GET", "/organization/check-authorization", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, isAuthorized())
+	})
+
+	t.Run("should keep the stored secret on an upsert without one", func(t *testing.T) {
+		server.SetScenario(graphtest.ScenarioAuthorized)
+
+		w := request("POST", "/organization/upsert", organization.UpsertOrganizationRequest{
+			Name:     "renamed",
+			ClientID: "client",
+			TenantID: "tenant",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, isAuthorized())
+
+		var stored domain.Organization
+		assert.NoError(t, db.First(&stored).Error)
+		assert.Equal(t, "renamed", stored.Name)
+		assert.NotEmpty(t, stored.ClientSecret)
+		assert.NotEqual(t, "secret", stored.ClientSecret)
+	})
+}
+
+func TestOrganizationHandler_GetOrganizationHealth(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	gin.SetMode(gin.TestMode)
+
+	db := newTestDB(t)
+	repository := organization.NewOrganizationRepository(db)
+	service, err := organization.NewOrganizationService(repository)
+	assert.NoError(t, err)
+	handler := organization.NewOrganizationHandler(service, repository)
+
+	router := gin.New()
+	router.GET("/admin/organizations/health", handler.GetOrganizationHealth)
+
+	expiresAt := func(d time.Duration) *time.Time {
+		at := time.Now().Add(d)
+		return &at
+	}
+	seeded := []domain.Organization{
+		{OwnerID: 1, Name: "authorized", IsAuthorized: true, ClientSecretExpiresAt: expiresAt(90 * 24 * time.Hour)},
+		{OwnerID: 2, Name: "unauthorized"},
+		{OwnerID: 3, Name: "errored", IsAuthorized: true, AuthorizationError: "graph unreachable"},
+		{OwnerID: 4, Name: "expiring", IsAuthorized: true, ClientSecretExpiresAt: expiresAt(5 * 24 * time.Hour)},
+		{OwnerID: 5, Name: "expired", IsAuthorized: true, ClientSecretExpiresAt: expiresAt(-time.Hour)},
+		{OwnerID: 6, Name: "no expiry", IsAuthorized: true},
+		{OwnerID: 7, Name: "deleted"},
+	}
+	assert.NoError(t, db.Create(&seeded).Error)
+	assert.NoError(t, db.Delete(&seeded[6]).Error)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/organizations/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response organization.OrganizationHealthResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	assert.Equal(t, int64(6), response.Total)
+	assert.Equal(t, int64(4), response.Authorized)
+	assert.Equal(t, int64(1), response.Unauthorized)
+	assert.Equal(t, int64(1), response.Errored)
+	assert.Equal(t, int64(2), response.SecretExpiringSoon)
+
+	var atRisk []string
+	for _, item := range response.AtRisk {
+		atRisk = append(atRisk, item.Name)
+	}
+	assert.Equal(t, []string{"unauthorized", "errored", "expiring", "expired"}, atRisk)
+	assert.Equal(t, "graph unreachable", response.AtRisk[1].AuthorizationError)
+}
+
+func TestOrganizationHandler_RefreshToken(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	gin.SetMode(gin.TestMode)
+
+	server := graphtest.NewServer(t)
+	server.UseAsDefault(t)
+
+	db := newTestDB(t)
+	repository := organization.NewOrganizationRepository(db)
+	service, err := organization.NewOrganizationService(repository)
+	assert.NoError(t, err)
+	handler := organization.NewOrganizationHandler(service, repository)
+
+	encrypted, err := service.EncryptClientSecret(context.Background(), "refresh-secret")
+	assert.NoError(t, err)
+	org, err := repository.UpsertOrganization(context.Background(), &domain.Organization{
+		OwnerID:      1,
+		ClientID:     "refresh-client",
+		TenantID:     "refresh-tenant",
+		ClientSecret: encrypted,
+	})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	asOwner := func(ownerID uint) gin.HandlerFunc {
+		return func(c *gin.Context) { c.Set(utils.AccountIdContextKey, ownerID) }
+	}
+	router.GET("/organization/check-authorization", asOwner(1), handler.CheckAuthorization)
+	router.POST("/organization/:id/refresh-token", asOwner(1), handler.RefreshToken)
+	router.POST("/other/organization/:id/refresh-token", asOwner(2), handler.RefreshToken)
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	refreshPath := "/organization/" + strconv.FormatUint(uint64(org.ID), 10) + "/refresh-token"
+
+	// the first check acquires the token, the second reuses it
+	assert.Equal(t, http.StatusOK, request("GET", "/organization/check-authorization").Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/organization/check-authorization").Code)
+	assert.Equal(t, 1, server.TokenRequests())
+
+	t.Run("should forbid refreshing the token of another owner", func(t *testing.T) {
+		w := request("POST", "/other"+refreshPath)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "auth.forbidden")
+		assert.Equal(t, 1, server.TokenRequests())
+	})
+
+	t.Run("should forbid an owner refreshing the organization of another owner", func(t *testing.T) {
+		_, err := repository.UpsertOrganization(context.Background(), &domain.Organization{
+			OwnerID:  2,
+			ClientID: "other-client",
+			TenantID: "other-tenant",
+		})
+		assert.NoError(t, err)
+
+		w := request("POST", "/other"+refreshPath)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, 1, server.TokenRequests())
+	})
+
+	t.Run("should not refresh an unknown organization", func(t *testing.T) {
+		w := request("POST", "/organization/999/refresh-token")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, 1, server.TokenRequests())
+	})
+
+	t.Run("should fetch a fresh token and cache it", func(t *testing.T) {
+		w := request("POST", refreshPath)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, server.TokenRequests())
+
+		// the next call uses the refreshed token without another request
+		assert.Equal(t, http.StatusOK, request("GET", "/organization/check-authorization").Code)
+		assert.Equal(t, 2, server.TokenRequests())
+	})
+}
+
+func TestOrganizationHandler_RotateClientSecret(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	gin.SetMode(gin.TestMode)
+
+	server := graphtest.NewServer(t)
+	server.UseAsDefault(t)
+
+	db := newTestDB(t)
+	repository := organization.NewOrganizationRepository(db)
+	service, err := organization.NewOrganizationService(repository)
+	assert.NoError(t, err)
+	handler := organization.NewOrganizationHandler(service, repository)
+
+	_, err = repository.UpsertOrganization(context.Background(), &domain.Organization{
+		OwnerID:  1,
+		ClientID: "rotate-client",
+		TenantID: "rotate-handler-tenant",
+	})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.POST("/organization/rotate-secret", func(c *gin.Context) {
+		c.Set(utils.AccountIdContextKey, uint(1))
+	}, handler.RotateClientSecret)
+
+	request := func(body any) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/organization/rotate-secret", bytes.NewReader(payload)))
+		return w
+	}
+
+	t.Run("should rotate the secret and report the authorization", func(t *testing.T) {
+		w := request(organization.RotateClientSecretRequest{ClientSecret: "rotated-secret"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response organization.RotateClientSecretResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.IsAuthorized)
+		assert.Contains(t, response.AuthorizeURL, "rotate-handler-tenant")
+	})
+
+	t.Run("should reject a request without a secret", func(t *testing.T) {
+		w := request(organization.RotateClientSecretRequest{})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "request.validation_failed")
+		assert.Contains(t, w.Body.String(), "client_secret is required")
+	})
+}
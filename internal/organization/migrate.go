@@ -0,0 +1,40 @@
+package organization
+
+import (
+	"context"
+	"spsyncpro_api/pkg/domain"
+)
+
+// EncryptPlaintextSecrets encrypts client secrets stored before encryption was
+// in place, secrets that already decrypt with the current key are skipped so
+// it is safe to run on every start. It returns the number of secrets encrypted.
+func EncryptPlaintextSecrets(
+	ctx context.Context,
+	organizationService domain.OrganizationService,
+	organizationRepository domain.OrganizationRepository,
+) (int, error) {
+	organizations, err := organizationRepository.ListOrganizations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	encrypted := 0
+	for _, organization := range organizations {
+		if organization.ClientSecret == "" || organizationService.IsEncryptedClientSecret(ctx, organization.ClientSecret) {
+			continue
+		}
+
+		clientSecret, err := organizationService.EncryptClientSecret(ctx, organization.ClientSecret)
+		if err != nil {
+			return encrypted, err
+		}
+
+		err = organizationRepository.UpdateClientSecret(ctx, organization.ID, clientSecret)
+		if err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+
+	return encrypted, nil
+}
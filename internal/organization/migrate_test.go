@@ -0,0 +1,56 @@
+package organization_test
+
+import (
+	"context"
+	"spsyncpro_api/internal/organization"
+	"spsyncpro_api/pkg/domain"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestEncryptPlaintextSecrets(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	db := newTestDB(t)
+	repository := organization.NewOrganizationRepository(db)
+	service, err := organization.NewOrganizationService(repository)
+	assert.NoError(t, err)
+
+	alreadyEncrypted, err := service.EncryptClientSecret(context.Background(), "encrypted-secret")
+	assert.NoError(t, err)
+
+	seeded := []domain.Organization{
+		{OwnerID: 1, ClientSecret: "plaintext-secret"},
+		{OwnerID: 2, ClientSecret: alreadyEncrypted},
+		{OwnerID: 3},
+	}
+	assert.NoError(t, db.Create(&seeded).Error)
+
+	encrypted, err := organization.EncryptPlaintextSecrets(context.Background(), service, repository)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, encrypted)
+
+	var stored []domain.Organization
+	assert.NoError(t, db.Order("owner_id").Find(&stored).Error)
+
+	assert.NotEqual(t, "plaintext-secret", stored[0].ClientSecret)
+	decrypted, err := service.DecryptClientSecret(context.Background(), stored[0].ClientSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, "plaintext-secret", decrypted)
+
+	assert.Equal(t, alreadyEncrypted, stored[1].ClientSecret)
+	assert.Empty(t, stored[2].ClientSecret)
+
+	t.Run("should do nothing on a second run", func(t *testing.T) {
+		encrypted, err := organization.EncryptPlaintextSecrets(context.Background(), service, repository)
+		assert.NoError(t, err)
+		assert.Zero(t, encrypted)
+	})
+}
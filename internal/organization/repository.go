@@ -3,7 +3,9 @@ package organization
 import (
 	"context"
 	"errors"
+	"spsyncpro_api/pkg/database"
 	"spsyncpro_api/pkg/domain"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
@@ -23,42 +25,193 @@ func NewOrganizationRepository(db *gorm.DB) domain.OrganizationRepository {
 	}
 }
 
+// conn joins the transaction carried by ctx, see database.DB.WithTransaction
+func (r *OrganizationRepo) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, r.db)
+}
+
 func (r *OrganizationRepo) UpsertOrganization(ctx context.Context, organization *domain.Organization) (*domain.Organization, error) {
 	_, span := r.trace.Start(ctx, "UpsertOrganization")
 	defer span.End()
-	err := r.db.Where("owner_id = ?", organization.OwnerID).First(organization).Error
+	var existing domain.Organization
+	err := r.conn(ctx).Where("owner_id = ?", organization.OwnerID).First(&existing).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			err = r.db.Create(organization).Error
-			if err != nil {
-				return nil, err
-			}
-		} else {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+
+		err = r.conn(ctx).Create(organization).Error
+		if err == nil {
+			return organization, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
 			return nil, err
 		}
-	} else {
-		err = r.db.Save(organization).Error
+
+		// a concurrent upsert created the organization first, or a soft deleted
+		// one still holds the owner, retry as an update of that row
+		err = r.conn(ctx).Unscoped().Where("owner_id = ?", organization.OwnerID).First(&existing).Error
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	organization.ID = existing.ID
+	organization.CreatedAt = existing.CreatedAt
+	organization.DeletedAt = gorm.DeletedAt{}
+	err = r.conn(ctx).Unscoped().Save(organization).Error
+	if err != nil {
+		return nil, err
+	}
+
 	return organization, nil
 }
 
+// ownerNotDeleted leaves out organizations whose owner account is soft
+// deleted. DeleteAccount removes the organization with its owner, this covers
+// rows orphaned before that or by a delete outside the api until
+// DeleteOrphanedOrganizations cleans them up
+func ownerNotDeleted(db *gorm.DB) *gorm.DB {
+	return db.Where("NOT EXISTS (SELECT 1 FROM accounts WHERE accounts.id = organizations.owner_id AND accounts.deleted_at IS NOT NULL)")
+}
+
 func (r *OrganizationRepo) GetOrganizationByOwnerID(ctx context.Context, ownerID uint) (*domain.Organization, error) {
 	_, span := r.trace.Start(ctx, "GetOrganizationByOwnerID")
 	defer span.End()
 	var organization domain.Organization
-	err := r.db.Where("owner_id = ?", ownerID).First(&organization).Error
+	err := database.Retry(ctx, func() error {
+		return r.conn(ctx).Scopes(ownerNotDeleted).Where("owner_id = ?", ownerID).First(&organization).Error
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &organization, nil
 }
 
+func (r *OrganizationRepo) GetOrganizationByID(ctx context.Context, id uint) (*domain.Organization, error) {
+	_, span := r.trace.Start(ctx, "GetOrganizationByID")
+	defer span.End()
+	var organization domain.Organization
+	err := database.Retry(ctx, func() error {
+		return r.conn(ctx).Scopes(ownerNotDeleted).First(&organization, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
+
+func (r *OrganizationRepo) ListOrganizations(ctx context.Context) ([]domain.Organization, error) {
+	_, span := r.trace.Start(ctx, "ListOrganizations")
+	defer span.End()
+	var organizations []domain.Organization
+	err := r.conn(ctx).Scopes(ownerNotDeleted).Order("id").Find(&organizations).Error
+	if err != nil {
+		return nil, err
+	}
+	return organizations, nil
+}
+
+func (r *OrganizationRepo) UpdateClientSecret(ctx context.Context, id uint, clientSecret string) error {
+	_, span := r.trace.Start(ctx, "UpdateClientSecret")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.Organization{}).Where("id = ?", id).Update("client_secret", clientSecret).Error
+}
+
+// UpdateAuthorizationStatus stores the result of a Graph check that reached
+// Graph, clearing the error of an earlier failed check
+func (r *OrganizationRepo) UpdateAuthorizationStatus(ctx context.Context, id uint, authorized bool) error {
+	_, span := r.trace.Start(ctx, "UpdateAuthorizationStatus")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.Organization{}).Where("id = ?", id).Updates(map[string]any{
+		"is_authorized":            authorized,
+		"authorization_error":      "",
+		"authorization_checked_at": time.Now(),
+	}).Error
+}
+
+// UpdateAuthorizationError stores a Graph check that failed before it had a
+// result, the last known IsAuthorized is kept
+func (r *OrganizationRepo) UpdateAuthorizationError(ctx context.Context, id uint, checkErr string) error {
+	_, span := r.trace.Start(ctx, "UpdateAuthorizationError")
+	defer span.End()
+	return r.conn(ctx).Model(&domain.Organization{}).Where("id = ?", id).Updates(map[string]any{
+		"authorization_error":      checkErr,
+		"authorization_checked_at": time.Now(),
+	}).Error
+}
+
+// organizationErrored matches organizations whose last Graph check failed
+const organizationErrored = "COALESCE(authorization_error, '') <> ''"
+
+// GetOrganizationHealth counts organizations by their stored authorization
+// state, an errored organization is not counted as authorized or unauthorized.
+// Secrets expiring before expiringBefore, or already expired, are expiring soon.
+func (r *OrganizationRepo) GetOrganizationHealth(ctx context.Context, expiringBefore time.Time) (*domain.OrganizationHealth, error) {
+	_, span := r.trace.Start(ctx, "GetOrganizationHealth")
+	defer span.End()
+
+	var health domain.OrganizationHealth
+	err := r.conn(ctx).Model(&domain.Organization{}).Scopes(ownerNotDeleted).
+		Select(
+			"COUNT(*) AS total, "+
+				"COALESCE(SUM(CASE WHEN NOT "+organizationErrored+" AND is_authorized = ? THEN 1 ELSE 0 END), 0) AS authorized, "+
+				"COALESCE(SUM(CASE WHEN NOT "+organizationErrored+" AND is_authorized = ? THEN 1 ELSE 0 END), 0) AS unauthorized, "+
+				"COALESCE(SUM(CASE WHEN "+organizationErrored+" THEN 1 ELSE 0 END), 0) AS errored, "+
+				"COALESCE(SUM(CASE WHEN client_secret_expires_at < ? THEN 1 ELSE 0 END), 0) AS secret_expiring_soon",
+			true, false, expiringBefore,
+		).
+		Scan(&health).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.conn(ctx).Scopes(ownerNotDeleted).
+		Where(organizationErrored+" OR is_authorized = ? OR client_secret_expires_at < ?", false, expiringBefore).
+		Order("id").
+		Find(&health.AtRisk).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
+
 func (r *OrganizationRepo) DeleteOrganizationByOwnerID(ctx context.Context, ownerID uint) error {
 	_, span := r.trace.Start(ctx, "DeleteOrganizationByOwnerID")
 	defer span.End()
-	return r.db.Delete(&domain.Organization{}, ownerID).Error
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		// the row is only soft deleted, do not leave the encrypted secret behind
+		err := tx.Model(&domain.Organization{}).Where("owner_id = ?", ownerID).Update("client_secret", "").Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("owner_id = ?", ownerID).Delete(&domain.Organization{}).Error
+	})
+}
+
+// DeleteOrphanedOrganizations soft deletes the organizations whose owner
+// account is soft deleted, as DeleteAccount would have, and returns how many
+// it deleted
+func (r *OrganizationRepo) DeleteOrphanedOrganizations(ctx context.Context) (int64, error) {
+	_, span := r.trace.Start(ctx, "DeleteOrphanedOrganizations")
+	defer span.End()
+
+	var deleted int64
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		orphaned := tx.Unscoped().Model(&domain.Account{}).Select("id").Where("deleted_at IS NOT NULL")
+
+		err := tx.Model(&domain.Organization{}).Where("owner_id IN (?)", orphaned).Update("client_secret", "").Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where("owner_id IN (?)", orphaned).Delete(&domain.Organization{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
 }
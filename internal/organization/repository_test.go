@@ -0,0 +1,235 @@
+package organization_test
+
+import (
+	"context"
+	"spsyncpro_api/internal/organization"
+	"spsyncpro_api/pkg/domain"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newStubDB returns a gorm db that never reaches postgres, the query, create
+// and update callbacks are replaced by the given stubs
+func newStubDB(t *testing.T, query, create, update func(db *gorm.DB)) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Callback().Query().Replace("gorm:query", query))
+	assert.NoError(t, db.Callback().Create().Replace("gorm:create", create))
+	assert.NoError(t, db.Callback().Update().Replace("gorm:update", update))
+
+	return db
+}
+
+// newTestDB opens a fresh in-memory sqlite database with the organization tables migrated
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	assert.NoError(t, err)
+
+	// every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	assert.NoError(t, db.AutoMigrate(&domain.Account{}, &domain.Organization{}))
+
+	return db
+}
+
+func TestOrganizationRepository_UpsertOrganization(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("should retry as an update when create hits a duplicate owner", func(t *testing.T) {
+		createdAt := time.Now().Add(-time.Hour)
+		queries := 0
+		var updated *domain.Organization
+
+		db := newStubDB(t,
+			func(db *gorm.DB) {
+				queries++
+				// the first lookup races with a concurrent create
+				if queries == 1 {
+					db.AddError(gorm.ErrRecordNotFound)
+					return
+				}
+				existing := db.Statement.Dest.(*domain.Organization)
+				existing.ID = 7
+				existing.CreatedAt = createdAt
+				existing.OwnerID = 1
+				existing.Name = "concurrent"
+				db.RowsAffected = 1
+			},
+			func(db *gorm.DB) {
+				db.AddError(gorm.ErrDuplicatedKey)
+			},
+			func(db *gorm.DB) {
+				updated = db.Statement.Dest.(*domain.Organization)
+				db.RowsAffected = 1
+			},
+		)
+
+		repository := organization.NewOrganizationRepository(db)
+
+		org, err := repository.UpsertOrganization(context.Background(), &domain.Organization{
+			OwnerID: 1,
+			Name:    "new name",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, queries)
+		assert.Equal(t, uint(7), org.ID)
+		assert.Equal(t, createdAt, org.CreatedAt)
+
+		assert.NotNil(t, updated)
+		assert.Equal(t, uint(7), updated.ID)
+		assert.Equal(t, "new name", updated.Name)
+	})
+
+	t.Run("should update the existing organization with the new values", func(t *testing.T) {
+		var updated *domain.Organization
+
+		db := newStubDB(t,
+			func(db *gorm.DB) {
+				existing := db.Statement.Dest.(*domain.Organization)
+				existing.ID = 3
+				existing.OwnerID = 1
+				existing.Name = "old name"
+				db.RowsAffected = 1
+			},
+			func(db *gorm.DB) {
+				t.Fatal("create should not be called for an existing organization")
+			},
+			func(db *gorm.DB) {
+				updated = db.Statement.Dest.(*domain.Organization)
+				db.RowsAffected = 1
+			},
+		)
+
+		repository := organization.NewOrganizationRepository(db)
+
+		org, err := repository.UpsertOrganization(context.Background(), &domain.Organization{
+			OwnerID: 1,
+			Name:    "new name",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), org.ID)
+		assert.Equal(t, "new name", updated.Name)
+	})
+
+	t.Run("should store the changed fields of a second upsert", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := organization.NewOrganizationRepository(db)
+
+		first, err := repository.UpsertOrganization(context.Background(), &domain.Organization{
+			OwnerID:  1,
+			Name:     "first name",
+			ClientID: "first-client",
+		})
+		assert.NoError(t, err)
+
+		second, err := repository.UpsertOrganization(context.Background(), &domain.Organization{
+			OwnerID:  1,
+			Name:     "second name",
+			ClientID: "second-client",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+
+		stored, err := repository.GetOrganizationByOwnerID(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "second name", stored.Name)
+		assert.Equal(t, "second-client", stored.ClientID)
+
+		var count int64
+		db.Model(&domain.Organization{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestOrganizationRepository_DeletedOwner(t *testing.T) {
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	// setup soft deletes the owner of one organization directly, the way
+	// accounts were deleted before organizations went with them
+	setup := func(t *testing.T) (*gorm.DB, domain.OrganizationRepository, *domain.Organization, *domain.Organization) {
+		db := newTestDB(t)
+		repository := organization.NewOrganizationRepository(db)
+
+		deletedOwner := &domain.Account{Email: "deleted@example.com"}
+		activeOwner := &domain.Account{Email: "active@example.com"}
+		assert.NoError(t, db.Create(deletedOwner).Error)
+		assert.NoError(t, db.Create(activeOwner).Error)
+
+		orphaned, err := repository.UpsertOrganization(context.Background(), &domain.Organization{
+			OwnerID:      deletedOwner.ID,
+			Name:         "orphaned",
+			ClientSecret: "encrypted-secret",
+		})
+		assert.NoError(t, err)
+		kept, err := repository.UpsertOrganization(context.Background(), &domain.Organization{
+			OwnerID: activeOwner.ID,
+			Name:    "kept",
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, db.Delete(deletedOwner).Error)
+		return db, repository, orphaned, kept
+	}
+
+	t.Run("should not return organizations of a deleted owner", func(t *testing.T) {
+		_, repository, orphaned, kept := setup(t)
+		ctx := context.Background()
+
+		_, err := repository.GetOrganizationByOwnerID(ctx, orphaned.OwnerID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = repository.GetOrganizationByID(ctx, orphaned.ID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		organizations, err := repository.ListOrganizations(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, organizations, 1)
+		assert.Equal(t, kept.ID, organizations[0].ID)
+
+		health, err := repository.GetOrganizationHealth(ctx, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), health.Total)
+		assert.Len(t, health.AtRisk, 1)
+		assert.Equal(t, kept.ID, health.AtRisk[0].ID)
+	})
+
+	t.Run("should delete organizations of a deleted owner", func(t *testing.T) {
+		db, repository, orphaned, kept := setup(t)
+		ctx := context.Background()
+
+		deleted, err := repository.DeleteOrphanedOrganizations(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		// soft deleted like DeleteAccount does, without the client secret
+		var stored domain.Organization
+		assert.NoError(t, db.Unscoped().First(&stored, orphaned.ID).Error)
+		assert.True(t, stored.DeletedAt.Valid)
+		assert.Empty(t, stored.ClientSecret)
+
+		_, err = repository.GetOrganizationByID(ctx, kept.ID)
+		assert.NoError(t, err)
+
+		deleted, err = repository.DeleteOrphanedOrganizations(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+}
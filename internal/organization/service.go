@@ -2,39 +2,139 @@ package organization
 
 import (
 	"context"
+	"errors"
 	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/msgraphapi"
 	"spsyncpro_api/pkg/utils"
 
-	"github.com/spf13/viper"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 type OrganizationService struct {
-	tracer    trace.Tracer
-	encryptor *utils.Encryptor
+	tracer                 trace.Tracer
+	encryptor              *utils.Encryptor
+	organizationRepository domain.OrganizationRepository
 }
 
-func NewOrganizationService() domain.OrganizationService {
-	encryptor, err := utils.NewEncryptor([]byte(viper.GetString("ENCRYPTION_KEY")))
+// NewOrganizationService creates the organization service, an ENCRYPTION_KEY
+// of the wrong length is returned as an error
+func NewOrganizationService(organizationRepository domain.OrganizationRepository) (domain.OrganizationService, error) {
+	encryptor, err := utils.NewEncryptorFromConfig()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	tracer := otel.Tracer("organizationService")
 	return &OrganizationService{
-		tracer:    tracer,
-		encryptor: encryptor,
-	}
+		tracer:                 tracer,
+		encryptor:              encryptor,
+		organizationRepository: organizationRepository,
+	}, nil
 }
 
+// EncryptClientSecret encrypts the secret for storage, an empty secret stays
+// empty so an organization without one never stores ciphertext of nothing
 func (s *OrganizationService) EncryptClientSecret(ctx context.Context, clientSecret string) (string, error) {
 	_, span := s.tracer.Start(ctx, "EncryptClientSecret")
 	defer span.End()
-	return clientSecret, nil
+	if clientSecret == "" {
+		return "", nil
+	}
+	return s.encryptor.Encrypt(clientSecret)
 }
 
 func (s *OrganizationService) DecryptClientSecret(ctx context.Context, clientSecret string) (string, error) {
 	_, span := s.tracer.Start(ctx, "DecryptClientSecret")
 	defer span.End()
-	return clientSecret, nil
+	if clientSecret == "" {
+		return "", nil
+	}
+	return s.encryptor.Decrypt(clientSecret)
+}
+
+// IsEncryptedClientSecret reports whether the stored value is ciphertext of
+// the current key. GCM is authenticated, so a plaintext secret that happens
+// to be valid base64 still fails to decrypt and is reported as plaintext.
+func (s *OrganizationService) IsEncryptedClientSecret(ctx context.Context, clientSecret string) bool {
+	_, span := s.tracer.Start(ctx, "IsEncryptedClientSecret")
+	defer span.End()
+	if clientSecret == "" {
+		return false
+	}
+	_, err := s.encryptor.Decrypt(clientSecret)
+	return err == nil
+}
+
+// RotateClientSecret replaces the client secret of the organization of the
+// owner and checks the new one against Graph. The token cached for the old
+// secret is discarded. A secret that Graph rejects is still stored, the
+// organization is then returned with IsAuthorized unset. A check that fails
+// before Graph answers is recorded and returned as the error.
+func (s *OrganizationService) RotateClientSecret(ctx context.Context, ownerID uint, newSecret string) (*domain.Organization, error) {
+	ctx, span := s.tracer.Start(ctx, "RotateClientSecret")
+	defer span.End()
+
+	if newSecret == "" {
+		return nil, domain.ErrClientSecretRequired
+	}
+
+	organization, err := s.organizationRepository.GetOrganizationByOwnerID(ctx, ownerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+
+	oldSecret, err := s.DecryptClientSecret(ctx, organization.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := s.EncryptClientSecret(ctx, newSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.organizationRepository.UpdateClientSecret(ctx, organization.ID, encryptedSecret)
+	if err != nil {
+		return nil, err
+	}
+	organization.ClientSecret = encryptedSecret
+
+	msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+		ClientID:     organization.ClientID,
+		TenantID:     organization.TenantID,
+		ClientSecret: oldSecret,
+	}).DiscardAccessToken()
+
+	msGraphApiService := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+		ClientID:     organization.ClientID,
+		TenantID:     organization.TenantID,
+		ClientSecret: newSecret,
+	})
+
+	ok, checkErr := msGraphApiService.CheckAuthorized(ctx)
+	if checkErr != nil {
+		// the authorization of the old secret says nothing about the new one
+		err = s.organizationRepository.UpdateAuthorizationStatus(ctx, organization.ID, false)
+		if err != nil {
+			return nil, err
+		}
+		err = s.organizationRepository.UpdateAuthorizationError(ctx, organization.ID, checkErr.Error())
+		if err != nil {
+			span.RecordError(err)
+		}
+		return nil, checkErr
+	}
+
+	err = s.organizationRepository.UpdateAuthorizationStatus(ctx, organization.ID, ok)
+	if err != nil {
+		return nil, err
+	}
+	organization.IsAuthorized = ok
+	organization.AuthorizationError = ""
+
+	return organization, nil
 }
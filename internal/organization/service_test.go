@@ -0,0 +1,187 @@
+package organization_test
+
+import (
+	"context"
+	"spsyncpro_api/internal/organization"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/msgraphapi"
+	"spsyncpro_api/pkg/msgraphapi/graphtest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestNewOrganizationService_InvalidKey(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "too-short")
+	defer viper.Reset()
+
+	service, err := organization.NewOrganizationService(nil)
+	assert.Nil(t, service)
+	assert.ErrorContains(t, err, "got 9")
+}
+
+func TestOrganizationService_SecretNotRecordedInSpans(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	service, err := organization.NewOrganizationService(nil)
+	assert.NoError(t, err)
+	plaintext := "super-secret-client-value"
+
+	encrypted, err := service.EncryptClientSecret(context.Background(), plaintext)
+	assert.NoError(t, err)
+
+	_, err = service.DecryptClientSecret(context.Background(), encrypted)
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	assert.NotEmpty(t, spans)
+	for _, span := range spans {
+		for _, attr := range span.Attributes() {
+			assert.NotContains(t, attr.Value.Emit(), plaintext, "span %s recorded the secret in %s", span.Name(), attr.Key)
+		}
+		for _, event := range span.Events() {
+			for _, attr := range event.Attributes {
+				assert.NotContains(t, attr.Value.Emit(), plaintext, "span %s recorded the secret in event %s", span.Name(), event.Name)
+			}
+		}
+	}
+}
+
+func TestOrganizationService_ClientSecret(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	service, err := organization.NewOrganizationService(nil)
+	assert.NoError(t, err)
+
+	t.Run("should round trip a secret", func(t *testing.T) {
+		encrypted, err := service.EncryptClientSecret(context.Background(), "client-secret")
+		assert.NoError(t, err)
+		assert.NotEqual(t, "client-secret", encrypted)
+		assert.True(t, service.IsEncryptedClientSecret(context.Background(), encrypted))
+
+		decrypted, err := service.DecryptClientSecret(context.Background(), encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "client-secret", decrypted)
+	})
+
+	t.Run("should keep an empty secret empty", func(t *testing.T) {
+		encrypted, err := service.EncryptClientSecret(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Empty(t, encrypted)
+
+		decrypted, err := service.DecryptClientSecret(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Empty(t, decrypted)
+	})
+
+	t.Run("should detect plaintext secrets", func(t *testing.T) {
+		assert.False(t, service.IsEncryptedClientSecret(context.Background(), "abc8Q~plaintext.secret"))
+		// valid base64 that is not ciphertext of this key
+		assert.False(t, service.IsEncryptedClientSecret(context.Background(), "c2VjcmV0c2VjcmV0c2VjcmV0c2VjcmV0c2VjcmV0"))
+		assert.False(t, service.IsEncryptedClientSecret(context.Background(), ""))
+	})
+}
+
+func TestOrganizationService_RotateClientSecret(t *testing.T) {
+	viper.Set("ENCRYPTION_KEY", "myverystrongpasswordo32bitlength")
+	defer viper.Reset()
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	server := graphtest.NewServer(t)
+	server.UseAsDefault(t)
+
+	seed := func(t *testing.T, tenantID string) (domain.OrganizationService, domain.OrganizationRepository) {
+		repository := organization.NewOrganizationRepository(newTestDB(t))
+		service, err := organization.NewOrganizationService(repository)
+		assert.NoError(t, err)
+
+		encrypted, err := service.EncryptClientSecret(context.Background(), "old-secret")
+		assert.NoError(t, err)
+		_, err = repository.UpsertOrganization(context.Background(), &domain.Organization{
+			OwnerID:      1,
+			ClientID:     "client",
+			TenantID:     tenantID,
+			ClientSecret: encrypted,
+			IsAuthorized: true,
+		})
+		assert.NoError(t, err)
+
+		return service, repository
+	}
+
+	storedSecret := func(t *testing.T, service domain.OrganizationService, repository domain.OrganizationRepository) (*domain.Organization, string) {
+		stored, err := repository.GetOrganizationByOwnerID(context.Background(), 1)
+		assert.NoError(t, err)
+		secret, err := service.DecryptClientSecret(context.Background(), stored.ClientSecret)
+		assert.NoError(t, err)
+		return stored, secret
+	}
+
+	t.Run("should store an authorized secret and discard the old token", func(t *testing.T) {
+		server.SetScenario(graphtest.ScenarioAuthorized)
+		service, repository := seed(t, "rotate-tenant")
+
+		oldConfig := msgraphapi.MsGraphApiConfig{ClientID: "client", TenantID: "rotate-tenant", ClientSecret: "old-secret"}
+		_, err := msgraphapi.NewMsGraphApiService(oldConfig).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+		before := server.TokenRequests()
+
+		rotated, err := service.RotateClientSecret(context.Background(), 1, "new-secret")
+		assert.NoError(t, err)
+		assert.True(t, rotated.IsAuthorized)
+		assert.Equal(t, before+1, server.TokenRequests())
+
+		stored, secret := storedSecret(t, service, repository)
+		assert.Equal(t, "new-secret", secret)
+		assert.True(t, stored.IsAuthorized)
+
+		// the token of the old secret is no longer cached
+		_, err = msgraphapi.NewMsGraphApiService(oldConfig).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, before+2, server.TokenRequests())
+	})
+
+	t.Run("should store a secret that fails authorization as unauthorized", func(t *testing.T) {
+		server.SetScenario(graphtest.ScenarioConsentMissing)
+		service, repository := seed(t, "unauthorized-tenant")
+
+		rotated, err := service.RotateClientSecret(context.Background(), 1, "new-secret")
+		assert.NoError(t, err)
+		assert.False(t, rotated.IsAuthorized)
+
+		stored, secret := storedSecret(t, service, repository)
+		assert.Equal(t, "new-secret", secret)
+		assert.False(t, stored.IsAuthorized)
+		assert.NotNil(t, stored.AuthorizationCheckedAt)
+	})
+
+	t.Run("should reject an empty secret", func(t *testing.T) {
+		service, repository := seed(t, "empty-tenant")
+
+		_, err := service.RotateClientSecret(context.Background(), 1, "")
+		assert.ErrorIs(t, err, domain.ErrClientSecretRequired)
+
+		_, secret := storedSecret(t, service, repository)
+		assert.Equal(t, "old-secret", secret)
+	})
+
+	t.Run("should report a missing organization", func(t *testing.T) {
+		service, _ := seed(t, "missing-tenant")
+
+		_, err := service.RotateClientSecret(context.Background(), 2, "new-secret")
+		assert.ErrorIs(t, err, domain.ErrOrganizationNotFound)
+	})
+}
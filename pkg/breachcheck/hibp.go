@@ -0,0 +1,90 @@
+package breachcheck
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultHIBPURL     = "https://api.pwnedpasswords.com"
+	defaultHIBPTimeout = 2 * time.Second
+)
+
+// HIBPChecker looks passwords up in the HaveIBeenPwned range API. Only the
+// first five characters of the SHA-1 hash leave the server, the match against
+// the returned suffixes happens locally.
+type HIBPChecker struct {
+	tracer     trace.Tracer
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewHIBPChecker reads BREACHED_PASSWORD_API_URL and BREACHED_PASSWORD_TIMEOUT,
+// the timeout bounds the whole lookup
+func NewHIBPChecker() domain.BreachedPasswordChecker {
+	baseURL := viper.GetString("BREACHED_PASSWORD_API_URL")
+	if baseURL == "" {
+		baseURL = defaultHIBPURL
+	}
+	timeout := viper.GetDuration("BREACHED_PASSWORD_TIMEOUT")
+	if timeout <= 0 {
+		timeout = defaultHIBPTimeout
+	}
+	return &HIBPChecker{
+		tracer:     otel.Tracer("hibpChecker"),
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: utils.NewHTTPClient(timeout, nil),
+	}
+}
+
+func (c *HIBPChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	ctx, span := c.tracer.Start(ctx, "IsBreached")
+	defer span.End()
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	request, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// padding hides the real number of suffixes for the prefix, padded
+	// entries have a count of 0
+	request.Header.Set("Add-Padding", "true")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breached password lookup failed with status %d", response.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found || count == "0" {
+			continue
+		}
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
@@ -0,0 +1,75 @@
+package breachcheck_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/breachcheck"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// newRangeServer serves the HIBP range endpoint, the breached passwords are
+// listed with a count and a padded entry with count 0 is always added
+func newRangeServer(t *testing.T, breached ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get("Add-Padding"))
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		assert.Len(t, prefix, 5)
+
+		for _, password := range breached {
+			sum := sha1.Sum([]byte(password))
+			hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+			if hash[:5] == prefix {
+				fmt.Fprintf(w, "%s:42\r\n", hash[5:])
+			}
+		}
+		fmt.Fprint(w, "0000000000000000000000000000000000A:0\r\n")
+	}))
+}
+
+func TestHIBPChecker_IsBreached(t *testing.T) {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	server := newRangeServer(t, "password123")
+	defer server.Close()
+
+	viper.Set("BREACHED_PASSWORD_API_URL", server.URL)
+	defer viper.Reset()
+
+	checker := breachcheck.NewHIBPChecker()
+
+	t.Run("should report a breached password", func(t *testing.T) {
+		breached, err := checker.IsBreached(context.Background(), "password123")
+		assert.NoError(t, err)
+		assert.True(t, breached)
+	})
+
+	t.Run("should accept a clean password", func(t *testing.T) {
+		breached, err := checker.IsBreached(context.Background(), "correct horse battery staple 2026")
+		assert.NoError(t, err)
+		assert.False(t, breached)
+	})
+
+	t.Run("should give up after the timeout", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer slow.Close()
+
+		viper.Set("BREACHED_PASSWORD_API_URL", slow.URL)
+		viper.Set("BREACHED_PASSWORD_TIMEOUT", "20ms")
+
+		_, err := breachcheck.NewHIBPChecker().IsBreached(context.Background(), "password123")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"spsyncpro_api/pkg/domain"
+
+	"gorm.io/gorm"
+)
+
+// Models are the tables of the api, a new model is added here so AutoMigrate
+// and the initial schema migration create it
+var Models = []any{
+	&domain.Account{},
+	&domain.AccountActivity{},
+	&domain.EmailLog{},
+	&domain.PendingAccountAction{},
+	&domain.PasswordResetToken{},
+	&domain.RefreshToken{},
+	&domain.APIKey{},
+	&domain.RevokedToken{},
+	&domain.SigningKey{},
+	&domain.AccountWebhook{},
+	&domain.Organization{},
+}
+
+// AutoMigrate creates or updates the tables of every model in Models, it is
+// for development and tests, it cannot drop or rename columns so deployed
+// databases are changed with the versioned Migrations
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(Models...)
+}
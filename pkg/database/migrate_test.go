@@ -0,0 +1,31 @@
+package database_test
+
+import (
+	"spsyncpro_api/pkg/database"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestAutoMigrate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	assert.NoError(t, err)
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	assert.NoError(t, database.AutoMigrate(db))
+
+	for _, model := range database.Models {
+		assert.True(t, db.Migrator().HasTable(model), "%T has no table", model)
+	}
+	assert.True(t, db.Migrator().HasTable("organizations"))
+
+	// migrating an up to date schema changes nothing
+	assert.NoError(t, database.AutoMigrate(db))
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"spsyncpro_api/pkg/domain"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned change to the schema, Up applies it and Down
+// reverts it. Both run in a transaction with the schema_migrations record so
+// a failed migration leaves the version where it was
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records a migration applied to the database
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrations are applied in order by MigrateUp, a schema change is a new
+// entry with the next version, an applied migration is never edited.
+//
+// The initial schema creates the tables the way AutoMigrate does, so it also
+// adopts a database made by releases before versioned migrations. Migrations
+// after it check the Migrator before changing a table, a database migrated
+// from empty gets the current models from the initial schema
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(Models...)
+		},
+		Down: func(tx *gorm.DB) error {
+			tables := slices.Clone(Models)
+			slices.Reverse(tables)
+			return tx.Migrator().DropTable(tables...)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "release emails of deleted accounts",
+		// deleted accounts kept their email under the unique constraint, it
+		// is replaced with a tombstone on the reserved domain as a delete
+		// does now. The emails are gone, so there is nothing to revert.
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec(
+				"UPDATE accounts SET email = 'deleted-' || id || '@anonymized.invalid' " +
+					"WHERE deleted_at IS NOT NULL AND email NOT LIKE '%@anonymized.invalid'",
+			).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	},
+	{
+		Version: 3,
+		Name:    "lowercase account emails",
+		// emails are normalized to lower case, accounts registered before that
+		// are lowercased so lookups can match the column as stored. Two
+		// accounts that differ only in case have to be merged or renamed by
+		// hand first, the migration stops and lists them.
+		Up: func(tx *gorm.DB) error {
+			var conflicts []string
+			err := tx.Table("accounts").Select("LOWER(email)").
+				Group("LOWER(email)").Having("COUNT(*) > 1").
+				Scan(&conflicts).Error
+			if err != nil {
+				return err
+			}
+			if len(conflicts) > 0 {
+				return fmt.Errorf("accounts differ only in the case of their email: %s", strings.Join(conflicts, ", "))
+			}
+
+			err = tx.Exec("UPDATE accounts SET email = LOWER(email) WHERE email <> LOWER(email)").Error
+			if err != nil {
+				return err
+			}
+			return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_email_lower ON accounts (LOWER(email))").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP INDEX IF EXISTS idx_accounts_email_lower").Error
+		},
+	},
+	{
+		Version: 4,
+		Name:    "organization of account webhooks",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if !migrator.HasColumn(&domain.AccountWebhook{}, "OrganizationID") {
+				if err := migrator.AddColumn(&domain.AccountWebhook{}, "OrganizationID"); err != nil {
+					return err
+				}
+			}
+			if migrator.HasIndex(&domain.AccountWebhook{}, "OrganizationID") {
+				return nil
+			}
+			return migrator.CreateIndex(&domain.AccountWebhook{}, "OrganizationID")
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if migrator.HasIndex(&domain.AccountWebhook{}, "OrganizationID") {
+				if err := migrator.DropIndex(&domain.AccountWebhook{}, "OrganizationID"); err != nil {
+					return err
+				}
+			}
+			if !migrator.HasColumn(&domain.AccountWebhook{}, "OrganizationID") {
+				return nil
+			}
+			return migrator.DropColumn(&domain.AccountWebhook{}, "OrganizationID")
+		},
+	},
+}
+
+// LatestVersion is the version of the last migration in Migrations
+func LatestVersion() uint {
+	if len(Migrations) == 0 {
+		return 0
+	}
+	return Migrations[len(Migrations)-1].Version
+}
+
+// SchemaVersion returns the version of the last migration applied to db, 0
+// for a database that has none
+func SchemaVersion(ctx context.Context, db *gorm.DB) (uint, error) {
+	db = db.WithContext(ctx)
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var version uint
+	err := db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// MigrateUp applies every migration newer than the schema version of db and
+// returns the ones it applied
+func MigrateUp(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	version, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range Migrations {
+		if migration.Version <= version {
+			continue
+		}
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the last steps applied migrations of db, newest first,
+// and returns the ones it reverted
+func MigrateDown(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	version, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(Migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := Migrations[i]
+		if migration.Version > version {
+			continue
+		}
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("failed to revert migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
@@ -0,0 +1,162 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"spsyncpro_api/pkg/database"
+	"spsyncpro_api/pkg/domain"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	assert.NoError(t, err)
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	t.Run("should be in version order", func(t *testing.T) {
+		for i := 1; i < len(database.Migrations); i++ {
+			assert.Greater(t, database.Migrations[i].Version, database.Migrations[i-1].Version)
+		}
+	})
+
+	t.Run("should apply every migration from empty", func(t *testing.T) {
+		version, err := database.SchemaVersion(ctx, db)
+		assert.NoError(t, err)
+		assert.Zero(t, version)
+
+		applied, err := database.MigrateUp(ctx, db)
+		assert.NoError(t, err)
+		assert.Len(t, applied, len(database.Migrations))
+
+		version, err = database.SchemaVersion(ctx, db)
+		assert.NoError(t, err)
+		assert.Equal(t, database.LatestVersion(), version)
+
+		// the final schema has every column of every model
+		for _, model := range database.Models {
+			stmt := &gorm.Statement{DB: db}
+			assert.NoError(t, stmt.Parse(model))
+			assert.True(t, db.Migrator().HasTable(model), "%T has no table", model)
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName == "" {
+					continue
+				}
+				assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%T has no column %s", model, field.DBName)
+			}
+		}
+
+		// an up to date database applies nothing
+		applied, err = database.MigrateUp(ctx, db)
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("should revert every migration", func(t *testing.T) {
+		reverted, err := database.MigrateDown(ctx, db, len(database.Migrations))
+		assert.NoError(t, err)
+		assert.Len(t, reverted, len(database.Migrations))
+
+		version, err := database.SchemaVersion(ctx, db)
+		assert.NoError(t, err)
+		assert.Zero(t, version)
+		for _, model := range database.Models {
+			assert.False(t, db.Migrator().HasTable(model), "%T was not dropped", model)
+		}
+
+		// nothing is left to revert
+		reverted, err = database.MigrateDown(ctx, db, 1)
+		assert.NoError(t, err)
+		assert.Empty(t, reverted)
+	})
+
+	t.Run("should release the emails of deleted accounts", func(t *testing.T) {
+		_, err := database.MigrateUp(ctx, db)
+		assert.NoError(t, err)
+		_, err = database.MigrateDown(ctx, db, int(database.LatestVersion())-1)
+		assert.NoError(t, err)
+
+		deleted := domain.Account{Email: "deleted@example.com"}
+		active := domain.Account{Email: "active@example.com"}
+		assert.NoError(t, db.Create(&deleted).Error)
+		assert.NoError(t, db.Create(&active).Error)
+		assert.NoError(t, db.Delete(&deleted).Error)
+
+		_, err = database.MigrateUp(ctx, db)
+		assert.NoError(t, err)
+
+		assert.NoError(t, db.Unscoped().First(&deleted, deleted.ID).Error)
+		assert.Equal(t, fmt.Sprintf("deleted-%d@anonymized.invalid", deleted.ID), deleted.Email)
+		assert.NoError(t, db.First(&active, active.ID).Error)
+		assert.Equal(t, "active@example.com", active.Email)
+
+		// the email can be registered again
+		assert.NoError(t, db.Create(&domain.Account{Email: "deleted@example.com"}).Error)
+
+		_, err = database.MigrateDown(ctx, db, len(database.Migrations))
+		assert.NoError(t, err)
+	})
+
+	t.Run("should lowercase account emails", func(t *testing.T) {
+		_, err := database.MigrateUp(ctx, db)
+		assert.NoError(t, err)
+		_, err = database.MigrateDown(ctx, db, int(database.LatestVersion())-1)
+		assert.NoError(t, err)
+
+		legacy := domain.Account{Email: "Legacy@Example.com"}
+		assert.NoError(t, db.Create(&legacy).Error)
+
+		_, err = database.MigrateUp(ctx, db)
+		assert.NoError(t, err)
+
+		assert.NoError(t, db.First(&legacy, legacy.ID).Error)
+		assert.Equal(t, "legacy@example.com", legacy.Email)
+
+		// the index rejects the same email in another case
+		assert.Error(t, db.Create(&domain.Account{Email: "LEGACY@example.com"}).Error)
+
+		_, err = database.MigrateDown(ctx, db, len(database.Migrations))
+		assert.NoError(t, err)
+	})
+
+	t.Run("should stop on emails that differ only in case", func(t *testing.T) {
+		_, err := database.MigrateUp(ctx, db)
+		assert.NoError(t, err)
+		_, err = database.MigrateDown(ctx, db, int(database.LatestVersion())-1)
+		assert.NoError(t, err)
+
+		assert.NoError(t, db.Create(&domain.Account{Email: "Twice@example.com"}).Error)
+		assert.NoError(t, db.Create(&domain.Account{Email: "twice@example.com"}).Error)
+
+		_, err = database.MigrateUp(ctx, db)
+		assert.ErrorContains(t, err, "twice@example.com")
+
+		version, err := database.SchemaVersion(ctx, db)
+		assert.NoError(t, err)
+		assert.Less(t, version, database.LatestVersion())
+
+		_, err = database.MigrateDown(ctx, db, len(database.Migrations))
+		assert.NoError(t, err)
+	})
+
+	t.Run("should adopt a database made by AutoMigrate", func(t *testing.T) {
+		assert.NoError(t, database.AutoMigrate(db))
+
+		_, err := database.MigrateUp(ctx, db)
+		assert.NoError(t, err)
+
+		version, err := database.SchemaVersion(ctx, db)
+		assert.NoError(t, err)
+		assert.Equal(t, database.LatestVersion(), version)
+	})
+}
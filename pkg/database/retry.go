@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"spsyncpro_api/pkg/utils"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// DefaultRetryPolicy retries transient failures of reads and transactions,
+// infra sets it from DB_RETRY_ATTEMPTS and DB_RETRY_DELAY
+var DefaultRetryPolicy = utils.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    time.Second,
+}
+
+// transientCodes are the postgres error codes worth another attempt, the
+// same statement can succeed once the conflict or the connection is gone
+var transientCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"08000": true, // connection_exception
+	"08003": true, // connection_does_not_exist
+	"08006": true, // connection_failure
+	"08001": true, // sqlclient_unable_to_establish_sqlconnection
+	"08004": true, // sqlserver_rejected_establishment_of_sqlconnection
+	"57P01": true, // admin_shutdown
+	"53300": true, // too_many_connections
+}
+
+// IsTransient reports whether err is a failure that can pass on its own, a
+// serialization failure, a deadlock or a lost connection
+func IsTransient(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return transientCodes[pgErr.Code]
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Retry runs an idempotent operation again on transient failures. Inside a
+// transaction it runs once, the failed statement aborted the transaction and
+// only WithTransaction can retry it as a whole.
+func Retry(ctx context.Context, fn func() error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return fn()
+	}
+	return utils.Retry(ctx, DefaultRetryPolicy, IsTransient, fn)
+}
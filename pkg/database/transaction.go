@@ -0,0 +1,56 @@
+// Package database shares transactions between repositories. A transaction
+// started with WithTransaction travels in the context, repositories that
+// resolve their connection with Conn run their queries inside it.
+package database
+
+import (
+	"context"
+	"spsyncpro_api/pkg/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+type txContextKey struct{}
+
+// DB wraps the connection pool repositories are built on and implements
+// domain.Transactor
+type DB struct {
+	db     *gorm.DB
+	tracer trace.Tracer
+}
+
+func NewDB(db *gorm.DB) domain.Transactor {
+	return &DB{
+		db:     db,
+		tracer: otel.Tracer("database"),
+	}
+}
+
+// WithTransaction runs fn in a transaction that is committed when fn returns
+// nil and rolled back otherwise. Repositories called with the context passed
+// to fn join the transaction, a nested call runs as a savepoint of the outer
+// transaction. The outermost transaction is retried on transient failures.
+func (d *DB) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, span := d.tracer.Start(ctx, "WithTransaction")
+	defer span.End()
+
+	transaction := func() error {
+		return Conn(ctx, d.db).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txContextKey{}, tx))
+		})
+	}
+	// a serialization failure or deadlock aborts the whole transaction, so the
+	// outermost one is retried from the start, fn must be safe to run again
+	return Retry(ctx, transaction)
+}
+
+// Conn returns the transaction carried by ctx, or db when the call is not
+// part of a transaction
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db
+}
@@ -15,8 +15,34 @@ type Account struct {
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 	Email     string         `json:"email" gorm:"unique"`
 	Password  string         `json:"password"`
+	Role      string         `json:"role" gorm:"default:user"`
+
+	EmailVerified bool       `json:"email_verified"`
+	VerifiedAt    *time.Time `json:"verified_at"`
+
+	FailedLoginCount int        `json:"failed_login_count"`
+	LockedUntil      *time.Time `json:"locked_until"`
+
+	LastLoginAt        *time.Time `json:"last_login_at"`
+	InactivityWarnedAt *time.Time `json:"inactivity_warned_at"`
+	DisabledAt         *time.Time `json:"disabled_at"`
+	// AnonymizedAt is set when the account was anonymized instead of deleted,
+	// the row and its activity remain without any personal data
+	AnonymizedAt *time.Time `json:"anonymized_at"`
 }
 
+var (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Roles are the roles an account can be given
+var Roles = []string{RoleUser, RoleAdmin}
+
+// AuthMethodPassword is the only sign in method accounts have, social sign in
+// and two factor methods are added to the reported set once they exist
+const AuthMethodPassword = "password"
+
 var (
 	ActivityLogin          = "login"
 	ActivityLogout         = "logout"
@@ -26,6 +52,12 @@ var (
 	ActivityResetPassword  = "reset_password"
 	ActivityForgotPassword = "forgot_password"
 	ActivityChangePassword = "change_password"
+	ActivityDisable        = "disable"
+	ActivityVerifyEmail    = "verify_email"
+	ActivityLock           = "lock"
+	ActivityUnlock         = "unlock"
+	ActivityRoleChange     = "role_change"
+	ActivityAnonymize      = "anonymize"
 )
 
 type AccountActivity struct {
@@ -36,31 +68,239 @@ type AccountActivity struct {
 
 	AccountID uint   `json:"account_id"`
 	Activity  string `json:"activity"`
+
+	// SessionID is the jti of the auth token the activity was performed with,
+	// null for activities outside a session such as failed logins
+	SessionID *string `json:"session_id" gorm:"index"`
+}
+
+var (
+	PendingActionDelete      = "delete"
+	PendingActionChangeEmail = "change_email"
+)
+
+// PendingAccountAction is a destructive account change held back for a grace
+// period, during which the owner can cancel it with the emailed token.
+type PendingAccountAction struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	AccountID    uint       `json:"account_id" gorm:"index"`
+	Action       string     `json:"action"`
+	Payload      string     `json:"-"`
+	CancelToken  string     `json:"-" gorm:"uniqueIndex"`
+	ExecuteAfter time.Time  `json:"execute_after" gorm:"index"`
+	CancelledAt  *time.Time `json:"cancelled_at"`
+	AppliedAt    *time.Time `json:"applied_at"`
+}
+
+// RefreshToken records an issued refresh token by its hash so it can be
+// revoked before it expires
+type RefreshToken struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	AccountID uint       `json:"account_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	// LastUsedAt is the last exchange for an auth token, nil until the first
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// RememberMe is set when the login asked for the longer remembered lifetime
+	RememberMe bool `json:"remember_me"`
+}
+
+// PasswordResetToken records an issued reset token by its hash, issuing a new
+// one or redeeming any of them invalidates every outstanding token of the account
+type PasswordResetToken struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	AccountID     uint       `json:"account_id" gorm:"index"`
+	TokenHash     string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	InvalidatedAt *time.Time `json:"invalidated_at"`
+	UsedAt        *time.Time `json:"used_at"`
+}
+
+// APIKey authenticates requests for an account without a login, only the hash
+// of the key is stored and Prefix identifies it in listings
+type APIKey struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	AccountID  uint       `json:"account_id" gorm:"index"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex"`
+	Scopes     string     `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// RevokedToken blocks an auth token by its jti until the token expires
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	TokenID   string    `json:"token_id" gorm:"uniqueIndex"`
+	AccountID uint      `json:"account_id"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
+
+// AuthClaims are the claims carried by an auth token, Email is only set when
+// the token was issued with JWT_INCLUDE_EMAIL enabled and TokenID is empty
+// for tokens issued before revocation was supported
+type AuthClaims struct {
+	AccountID uint
+	Email     string
+	Role      string
+	TokenID   string
+	ExpiresAt time.Time
 }
 
 type AccountService interface {
 	GenerateAuthToken(ctx context.Context, account *Account) (string, error)
 	ValidateAuthToken(ctx context.Context, token string) (uint, error)
+	ParseClaims(ctx context.Context, token string) (*AuthClaims, error)
+	RotateSigningKey(ctx context.Context) (*KeyRotation, error)
+	// GenerateRefreshToken issues a refresh token with the remembered lifetime when rememberMe is set,
+	// it returns the expiry of the token so the stored session expires with it
+	GenerateRefreshToken(ctx context.Context, account *Account, rememberMe bool) (string, time.Time, error)
+	ValidateRefreshToken(ctx context.Context, token string) (uint, error)
 	HashPassword(ctx context.Context, password string) (string, error)
-	ComparePassword(ctx context.Context, password, hash string) (bool, error)
+	ComparePassword(ctx context.Context, password, hash string) (bool, bool, error)
 
 	GeneratePasswordResetToken(ctx context.Context, account *Account) (string, error)
 	ValidatePasswordResetToken(ctx context.Context, token string) (uint, error)
-	SendPasswordResetEmail(ctx context.Context, email string, token string) error
+	SendPasswordResetEmail(ctx context.Context, email string, token string) (*EmailLog, error)
+	EnqueuePasswordResetEmail(ctx context.Context, email string, token string, onSent func(emailLog *EmailLog)) error
+
+	GenerateEmailVerificationToken(ctx context.Context, account *Account) (string, error)
+	ValidateEmailVerificationToken(ctx context.Context, token string) (uint, string, error)
+	SendVerificationEmail(ctx context.Context, email string, token string) (*EmailLog, error)
+
+	// GenerateEmailChangeToken issues a token confirming newEmail, bound to the current email of account
+	GenerateEmailChangeToken(ctx context.Context, account *Account, newEmail string) (string, error)
+	// ValidateEmailChangeToken returns the account id, the email the token was issued from and the new email
+	ValidateEmailChangeToken(ctx context.Context, token string) (uint, string, string, error)
+	SendEmailChangeEmail(ctx context.Context, email string, token string) (*EmailLog, error)
+
+	SendPendingActionEmail(ctx context.Context, email string, action *PendingAccountAction, token string) error
+	SendInactivityWarningEmail(ctx context.Context, email string, disableAt time.Time) error
+	SendAccountLockedEmail(ctx context.Context, email string, lockedUntil time.Time) error
+	// SendAccountExistsEmail tells the owner of email that a registration was attempted with it
+	SendAccountExistsEmail(ctx context.Context, email string) (*EmailLog, error)
 }
 
 var (
-	ErrPasswordEmpty     = errors.New("password cannot be empty")
-	ErrInvalidHashFormat = errors.New("invalid hash format")
-	ErrServerURLNotSet   = errors.New("server url is not set")
+	ErrAccountAlreadyExists  = errors.New("account already exists")
+	ErrAccountNotFound       = errors.New("account not found")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrInvalidOldPassword    = errors.New("invalid old password")
+	ErrTokenGenerationFailed = errors.New("failed to generate token")
+	ErrTokenRequired         = errors.New("token is required")
+	ErrTooManyLoginAttempts  = errors.New("too many login attempts")
+	ErrInvalidPagination     = errors.New("offset cannot be negative")
+	ErrInvalidSort           = errors.New("sort field is not supported")
+	ErrInvalidSortOrder      = errors.New("order must be asc or desc")
+
+	ErrPasswordEmpty = errors.New("password cannot be empty")
+	// ErrPasswordHashingBusy is returned when every hashing worker stayed busy
+	// for the queue timeout
+	ErrPasswordHashingBusy = errors.New("too many password requests, try again shortly")
+	ErrInvalidHashFormat   = errors.New("invalid hash format")
+	ErrServerURLNotSet     = errors.New("server url is not set")
+	ErrInsecureServerURL   = errors.New("server url must use https when https links are required")
+	ErrAccountDisabled     = errors.New("account is disabled")
+	ErrAccountLocked       = errors.New("account is temporarily locked after too many failed logins")
+	ErrInvalidRole         = errors.New("role must be user or admin")
+	ErrInvalidEmail        = errors.New("email address is invalid")
+
+	ErrEmailNotVerified         = errors.New("email is not verified")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrVerificationTokenInvalid = errors.New("invalid or expired token")
+	ErrEmailUnchanged           = errors.New("new email is the same as the current email")
+	ErrVerificationEmailFailed  = errors.New("failed to send verification email")
+	ErrResetEmailFailed         = errors.New("failed to send password reset email")
+
+	ErrPendingActionNotFound = errors.New("pending action not found")
+	ErrPendingActionExpired  = errors.New("pending action already applied or cancelled")
+	ErrPendingActionExists   = errors.New("the same account change is already pending")
+
+	ErrInvalidTokenType    = errors.New("invalid token type")
+	ErrTokenExpired        = errors.New("token has expired")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenRevoked = errors.New("refresh token has been revoked")
+	ErrResetTokenInvalid   = errors.New("invalid or expired reset token")
+	ErrCSRFTokenInvalid    = errors.New("missing or invalid csrf token")
+	ErrMalformedAuthHeader = errors.New("authorization header must be Bearer <token>")
+
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrAPIKeyNameMissing = errors.New("api key name is required")
 )
 
+// ActivityLogger records account activities for the handlers, Shutdown
+// writes the activities it still holds
+type ActivityLogger interface {
+	LogAccountActivity(ctx context.Context, accountID uint, activity string) error
+	Shutdown(ctx context.Context) error
+}
+
 type AccountRepository interface {
 	CreateAccount(ctx context.Context, account *Account) (*Account, error)
 	GetAccountByEmail(ctx context.Context, email string) (*Account, error)
 	GetAccountByID(ctx context.Context, id uint) (*Account, error)
 	UpdateAccount(ctx context.Context, account *Account) (*Account, error)
 	DeleteAccount(ctx context.Context, id uint) error
+	// AnonymizeAccount replaces the personal data of an account with a tombstone and removes its credentials, the row and its activity are kept
+	AnonymizeAccount(ctx context.Context, id uint, anonymizedAt time.Time) error
+	GetInactiveAccounts(ctx context.Context, lastLoginBefore time.Time) ([]Account, error)
 
 	LogAccountActivity(ctx context.Context, accountID uint, activity string) error
+	LogAccountActivities(ctx context.Context, activities []AccountActivity) error
+	LogEmail(ctx context.Context, emailLog *EmailLog) error
+	CountActivitiesSince(ctx context.Context, activities []string, since time.Time) (int64, error)
+	GetActivityHistory(ctx context.Context, accountID uint, since time.Time, limit, offset int) ([]AccountActivity, int64, error)
+	// ListAccounts returns a page of accounts ordered by the sort scope and the total count
+	ListAccounts(ctx context.Context, sort func(*gorm.DB) *gorm.DB, limit, offset int) ([]Account, int64, error)
+
+	CreatePendingAction(ctx context.Context, action *PendingAccountAction) (*PendingAccountAction, error)
+	GetPendingActionByCancelToken(ctx context.Context, cancelToken string) (*PendingAccountAction, error)
+	GetDuePendingActions(ctx context.Context, now time.Time) ([]PendingAccountAction, error)
+	// GetOpenPendingAction returns the action of the kind that is neither applied nor cancelled
+	GetOpenPendingAction(ctx context.Context, accountID uint, action string) (*PendingAccountAction, error)
+	UpdatePendingAction(ctx context.Context, action *PendingAccountAction) (*PendingAccountAction, error)
+
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) (*RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, accountID uint, tokenHash string, revokedAt time.Time) error
+	CountActiveRefreshTokens(ctx context.Context, at time.Time) (int64, error)
+	RevokeRefreshTokens(ctx context.Context, accountID uint, revokedAt time.Time) error
+	TouchRefreshToken(ctx context.Context, id uint, usedAt time.Time) error
+	EvictRefreshTokens(ctx context.Context, accountID uint, keep int, revokedAt time.Time) (int64, error)
+
+	CreatePasswordResetToken(ctx context.Context, token *PasswordResetToken) (*PasswordResetToken, error)
+	GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	ResetPasswordWithToken(ctx context.Context, tokenID uint, accountID uint, hashedPassword string, usedAt time.Time) (bool, error)
+	InvalidatePasswordResetTokens(ctx context.Context, accountID uint, invalidatedAt time.Time) error
+
+	CreateAPIKey(ctx context.Context, key *APIKey) (*APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context, accountID uint) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, accountID uint, id uint, revokedAt time.Time) (bool, error)
+	RevokeAPIKeys(ctx context.Context, accountID uint, revokedAt time.Time) error
+	TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error
+
+	RevokeToken(ctx context.Context, token *RevokedToken) error
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	PurgeExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error)
 }
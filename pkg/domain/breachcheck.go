@@ -0,0 +1,13 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrPasswordBreached = errors.New("password has appeared in a data breach, choose a different password")
+
+// BreachedPasswordChecker reports whether a password is known from a breach
+type BreachedPasswordChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// EmailLog records an email accepted by the mail server, MessageID matches the
+// Message-ID header so provider delivery reports can be correlated
+type EmailLog struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	AccountID  uint      `json:"account_id" gorm:"index"`
+	Recipient  string    `json:"recipient"`
+	Template   string    `json:"template"`
+	MessageID  string    `json:"message_id" gorm:"index"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrInternal     = errors.New("internal server error")
+	ErrUnauthorized = errors.New("Unauthorized")
+	ErrForbidden    = errors.New("Forbidden")
+
+	ErrValidationFailed = errors.New("request validation failed")
+)
+
+// ValidationError lists a message for every request field that failed its
+// binding rules, the messages are returned as the details of the response
+type ValidationError struct {
+	Fields []string
+}
+
+func (e *ValidationError) Error() string {
+	return ErrValidationFailed.Error() + ": " + strings.Join(e.Fields, ", ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidationFailed
+}
+
+// Details returns the field messages
+func (e *ValidationError) Details() []string {
+	return e.Fields
+}
+
+// errorCodes are the stable machine readable codes returned next to the error
+// message, clients localize on these so a code must never change once released.
+// The status is the response status used when a handler does not pick one.
+var errorCodes = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{ErrInternal, "server.internal", http.StatusInternalServerError},
+	{ErrUnauthorized, "auth.unauthorized", http.StatusUnauthorized},
+	{ErrForbidden, "auth.forbidden", http.StatusForbidden},
+
+	{ErrInvalidCredentials, "auth.invalid_credentials", http.StatusBadRequest},
+	{ErrInvalidOldPassword, "auth.invalid_old_password", http.StatusBadRequest},
+	{ErrTokenGenerationFailed, "auth.token_generation_failed", http.StatusInternalServerError},
+	{ErrInvalidRefreshToken, "auth.invalid_refresh_token", http.StatusUnauthorized},
+	{ErrRefreshTokenRevoked, "auth.refresh_token_revoked", http.StatusUnauthorized},
+	{ErrInvalidTokenType, "auth.invalid_token_type", http.StatusUnauthorized},
+	{ErrTokenExpired, "auth.token_expired", http.StatusUnauthorized},
+	{ErrResetTokenInvalid, "auth.reset_token_invalid", http.StatusBadRequest},
+	{ErrTooManyLoginAttempts, "auth.too_many_attempts", http.StatusTooManyRequests},
+	{ErrCSRFTokenInvalid, "auth.csrf_invalid", http.StatusForbidden},
+	{ErrMalformedAuthHeader, "auth.malformed_header", http.StatusUnauthorized},
+
+	{ErrAccountAlreadyExists, "account.already_exists", http.StatusBadRequest},
+	{ErrAccountNotFound, "account.not_found", http.StatusNotFound},
+	{ErrAccountDisabled, "account.disabled", http.StatusForbidden},
+	{ErrAccountLocked, "account.locked", http.StatusLocked},
+	{ErrInvalidRole, "account.invalid_role", http.StatusBadRequest},
+	{ErrInvalidEmail, "account.invalid_email", http.StatusBadRequest},
+	{ErrPasswordEmpty, "account.password_empty", http.StatusBadRequest},
+	{ErrPasswordHashingBusy, "account.hashing_busy", http.StatusServiceUnavailable},
+	{ErrPasswordBreached, "account.password_breached", http.StatusBadRequest},
+	{ErrPasswordTooWeak, "account.password_weak", http.StatusBadRequest},
+	{ErrEmailNotVerified, "account.email_not_verified", http.StatusForbidden},
+	{ErrEmailAlreadyVerified, "account.email_already_verified", http.StatusBadRequest},
+	{ErrVerificationTokenInvalid, "account.verification_token_invalid", http.StatusBadRequest},
+	{ErrEmailUnchanged, "account.email_unchanged", http.StatusBadRequest},
+	{ErrPendingActionNotFound, "account.pending_action_not_found", http.StatusNotFound},
+	{ErrPendingActionExpired, "account.pending_action_expired", http.StatusBadRequest},
+	{ErrPendingActionExists, "account.pending_action_exists", http.StatusConflict},
+	{ErrTokenRequired, "request.token_required", http.StatusBadRequest},
+	{ErrValidationFailed, "request.validation_failed", http.StatusUnprocessableEntity},
+	{ErrInvalidPagination, "request.invalid_pagination", http.StatusBadRequest},
+	{ErrInvalidSort, "request.invalid_sort", http.StatusBadRequest},
+	{ErrInvalidSortOrder, "request.invalid_sort_order", http.StatusBadRequest},
+	{ErrVerificationEmailFailed, "email.delivery_failed", http.StatusInternalServerError},
+	{ErrResetEmailFailed, "email.delivery_failed", http.StatusInternalServerError},
+
+	{ErrAPIKeyNotFound, "api_key.not_found", http.StatusNotFound},
+	{ErrAPIKeyNameMissing, "api_key.name_required", http.StatusBadRequest},
+
+	{ErrOrganizationNotFound, "org.not_found", http.StatusNotFound},
+	{ErrClientSecretRequired, "org.client_secret_required", http.StatusBadRequest},
+
+	{ErrWebhookNotFound, "webhook.not_found", http.StatusNotFound},
+	{ErrWebhookInvalidURL, "webhook.invalid_url", http.StatusBadRequest},
+	{ErrWebhookInvalidActivity, "webhook.invalid_activity", http.StatusBadRequest},
+
+	{ErrFeatureDisabled, "resource.not_found", http.StatusNotFound},
+}
+
+// ErrorStatus returns the response status mapped to err, 500 when unmapped
+func ErrorStatus(err error) int {
+	for _, mapping := range errorCodes {
+		if errors.Is(err, mapping.err) {
+			return mapping.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorCode returns the stable code for err, errors without a mapping get a
+// generic code for the response status
+func ErrorCode(err error, status int) string {
+	for _, mapping := range errorCodes {
+		if errors.Is(err, mapping.err) {
+			return mapping.code
+		}
+	}
+
+	switch status {
+	case http.StatusBadRequest:
+		return "request.invalid"
+	case http.StatusUnauthorized:
+		return "auth.unauthorized"
+	case http.StatusForbidden:
+		return "auth.forbidden"
+	case http.StatusNotFound:
+		return "resource.not_found"
+	case http.StatusTooManyRequests:
+		return "request.rate_limited"
+	default:
+		return "server.internal"
+	}
+}
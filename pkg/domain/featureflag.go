@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	FeatureRefreshTokens     = "refresh_tokens"
+	FeatureEmailVerification = "email_verification"
+	FeatureTwoFactor         = "two_factor"
+	FeatureWebhooks          = "webhooks"
+)
+
+var ErrFeatureDisabled = errors.New("not found")
+
+// FeatureFlagProvider decides whether a feature is enabled, the context lets
+// a provider scope the decision to the calling account
+type FeatureFlagProvider interface {
+	IsEnabled(ctx context.Context, feature string) bool
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LoginAttemptStore counts failed logins per key in a fixed window, so the
+// in-memory store can be swapped for a shared one when running replicas
+type LoginAttemptStore interface {
+	// RecordFailure adds a failed attempt to the key's window, starting a new
+	// window if none is active, and returns the count and when it resets
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	// Failures returns the count and reset time of the key's active window,
+	// zero when there is none
+	Failures(ctx context.Context, key string) (int, time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
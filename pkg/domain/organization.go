@@ -2,13 +2,15 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type Organization struct {
 	gorm.Model
-	OwnerID      uint    `json:"owner_id"`
+	OwnerID      uint    `json:"owner_id" gorm:"uniqueIndex"`
 	Owner        Account `json:"owner" gorm:"foreignKey:OwnerID"`
 	Name         string  `json:"name"`
 	Description  string  `json:"description"`
@@ -16,15 +18,49 @@ type Organization struct {
 	ClientID     string  `json:"client_id"`
 	TenantID     string  `json:"tenant_id"`
 	ClientSecret string  `json:"client_secret"`
+
+	// AuthorizationError is the error of the last Graph check, empty when it
+	// reached Graph and IsAuthorized holds its result
+	AuthorizationError     string     `json:"authorization_error"`
+	AuthorizationCheckedAt *time.Time `json:"authorization_checked_at"`
+	ClientSecretExpiresAt  *time.Time `json:"client_secret_expires_at"`
+}
+
+// OrganizationHealth aggregates the stored authorization state of all
+// organizations, the secret expiry count overlaps the other counts
+type OrganizationHealth struct {
+	Total              int64
+	Authorized         int64
+	Unauthorized       int64
+	Errored            int64
+	SecretExpiringSoon int64
+	AtRisk             []Organization `gorm:"-"`
 }
 
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrClientSecretRequired = errors.New("client secret is required")
+)
+
 type OrganizationRepository interface {
 	UpsertOrganization(ctx context.Context, organization *Organization) (*Organization, error)
 	GetOrganizationByOwnerID(ctx context.Context, ownerID uint) (*Organization, error)
+	GetOrganizationByID(ctx context.Context, id uint) (*Organization, error)
 	DeleteOrganizationByOwnerID(ctx context.Context, ownerID uint) error
+	// DeleteOrphanedOrganizations soft deletes organizations whose owner account is soft deleted and returns the count
+	DeleteOrphanedOrganizations(ctx context.Context) (int64, error)
+	ListOrganizations(ctx context.Context) ([]Organization, error)
+	UpdateClientSecret(ctx context.Context, id uint, clientSecret string) error
+	UpdateAuthorizationStatus(ctx context.Context, id uint, authorized bool) error
+	UpdateAuthorizationError(ctx context.Context, id uint, checkErr string) error
+	GetOrganizationHealth(ctx context.Context, expiringBefore time.Time) (*OrganizationHealth, error)
 }
 
 type OrganizationService interface {
 	EncryptClientSecret(ctx context.Context, clientSecret string) (string, error)
 	DecryptClientSecret(ctx context.Context, clientSecret string) (string, error)
+	IsEncryptedClientSecret(ctx context.Context, clientSecret string) bool
+	// RotateClientSecret stores a new client secret for the organization of
+	// the owner and checks it against Graph, IsAuthorized holds the result
+	RotateClientSecret(ctx context.Context, ownerID uint, newSecret string) (*Organization, error)
 }
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrPasswordTooWeak = errors.New("password does not meet the requirements")
+
+// password requirements reported by PasswordPolicyError
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleUppercase = "uppercase"
+	PasswordRuleLowercase = "lowercase"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+)
+
+// PasswordPolicyError lists every requirement a new password did not meet,
+// the rules are returned as the details of the error response
+type PasswordPolicyError struct {
+	Unmet []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrPasswordTooWeak.Error() + ": " + strings.Join(e.Unmet, ", ")
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordTooWeak
+}
+
+// Details returns the unmet rules
+func (e *PasswordPolicyError) Details() []string {
+	return e.Unmet
+}
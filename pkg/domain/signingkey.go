@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SigningKey is a JWT signing secret addressed by the token "kid" header,
+// the secret is encrypted at rest with ENCRYPTION_KEY
+type SigningKey struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	Kid       string     `json:"kid" gorm:"uniqueIndex"`
+	Secret    string     `json:"-"`
+	RetiredAt *time.Time `json:"retired_at"`
+}
+
+// KeyRotation describes the outcome of rotating the signing key
+type KeyRotation struct {
+	Kid              string
+	PreviousKid      string
+	RotatedAt        time.Time
+	GracePeriodEndAt time.Time
+}
+
+var (
+	ErrSigningKeyNotFound = errors.New("signing key not found")
+	ErrSigningKeyRetired  = errors.New("signing key retired")
+)
+
+type SigningKeyRepository interface {
+	CreateSigningKey(ctx context.Context, key *SigningKey) (*SigningKey, error)
+	GetCurrentSigningKey(ctx context.Context) (*SigningKey, error)
+	GetSigningKeyByKid(ctx context.Context, kid string) (*SigningKey, error)
+	RetireSigningKeys(ctx context.Context, retiredAt time.Time) error
+}
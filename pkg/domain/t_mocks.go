@@ -6,8 +6,10 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	mock "github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // NewMockAccountService creates a new instance of MockAccountService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -38,7 +40,7 @@ func (_m *MockAccountService) EXPECT() *MockAccountService_Expecter {
 }
 
 // ComparePassword provides a mock function for the type MockAccountService
-func (_mock *MockAccountService) ComparePassword(ctx context.Context, password string, hash string) (bool, error) {
+func (_mock *MockAccountService) ComparePassword(ctx context.Context, password string, hash string) (bool, bool, error) {
 	ret := _mock.Called(ctx, password, hash)
 
 	if len(ret) == 0 {
@@ -46,8 +48,9 @@ func (_mock *MockAccountService) ComparePassword(ctx context.Context, password s
 	}
 
 	var r0 bool
-	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (bool, error)); ok {
+	var r1 bool
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (bool, bool, error)); ok {
 		return returnFunc(ctx, password, hash)
 	}
 	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
@@ -55,12 +58,17 @@ func (_mock *MockAccountService) ComparePassword(ctx context.Context, password s
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) bool); ok {
 		r1 = returnFunc(ctx, password, hash)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(bool)
 	}
-	return r0, r1
+	if returnFunc, ok := ret.Get(2).(func(context.Context, string, string) error); ok {
+		r2 = returnFunc(ctx, password, hash)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
 }
 
 // MockAccountService_ComparePassword_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ComparePassword'
@@ -99,12 +107,81 @@ func (_c *MockAccountService_ComparePassword_Call) Run(run func(ctx context.Cont
 	return _c
 }
 
-func (_c *MockAccountService_ComparePassword_Call) Return(b bool, err error) *MockAccountService_ComparePassword_Call {
-	_c.Call.Return(b, err)
+func (_c *MockAccountService_ComparePassword_Call) Return(b bool, b1 bool, err error) *MockAccountService_ComparePassword_Call {
+	_c.Call.Return(b, b1, err)
+	return _c
+}
+
+func (_c *MockAccountService_ComparePassword_Call) RunAndReturn(run func(ctx context.Context, password string, hash string) (bool, bool, error)) *MockAccountService_ComparePassword_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// EnqueuePasswordResetEmail provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) EnqueuePasswordResetEmail(ctx context.Context, email string, token string, onSent func(emailLog *EmailLog)) error {
+	ret := _mock.Called(ctx, email, token, onSent)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnqueuePasswordResetEmail")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, func(emailLog *EmailLog)) error); ok {
+		r0 = returnFunc(ctx, email, token, onSent)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountService_EnqueuePasswordResetEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EnqueuePasswordResetEmail'
+type MockAccountService_EnqueuePasswordResetEmail_Call struct {
+	*mock.Call
+}
+
+// EnqueuePasswordResetEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+//   - token string
+//   - onSent func(emailLog *EmailLog)
+func (_e *MockAccountService_Expecter) EnqueuePasswordResetEmail(ctx interface{}, email interface{}, token interface{}, onSent interface{}) *MockAccountService_EnqueuePasswordResetEmail_Call {
+	return &MockAccountService_EnqueuePasswordResetEmail_Call{Call: _e.mock.On("EnqueuePasswordResetEmail", ctx, email, token, onSent)}
+}
+
+func (_c *MockAccountService_EnqueuePasswordResetEmail_Call) Run(run func(ctx context.Context, email string, token string, onSent func(emailLog *EmailLog))) *MockAccountService_EnqueuePasswordResetEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 func(emailLog *EmailLog)
+		if args[3] != nil {
+			arg3 = args[3].(func(emailLog *EmailLog))
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_EnqueuePasswordResetEmail_Call) Return(err error) *MockAccountService_EnqueuePasswordResetEmail_Call {
+	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockAccountService_ComparePassword_Call) RunAndReturn(run func(ctx context.Context, password string, hash string) (bool, error)) *MockAccountService_ComparePassword_Call {
+func (_c *MockAccountService_EnqueuePasswordResetEmail_Call) RunAndReturn(run func(ctx context.Context, email string, token string, onSent func(emailLog *EmailLog)) error) *MockAccountService_EnqueuePasswordResetEmail_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -175,12 +252,84 @@ func (_c *MockAccountService_GenerateAuthToken_Call) RunAndReturn(run func(ctx c
 	return _c
 }
 
-// GeneratePasswordResetToken provides a mock function for the type MockAccountService
-func (_mock *MockAccountService) GeneratePasswordResetToken(ctx context.Context, account *Account) (string, error) {
+// GenerateEmailChangeToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) GenerateEmailChangeToken(ctx context.Context, account *Account, newEmail string) (string, error) {
+	ret := _mock.Called(ctx, account, newEmail)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenerateEmailChangeToken")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account, string) (string, error)); ok {
+		return returnFunc(ctx, account, newEmail)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account, string) string); ok {
+		r0 = returnFunc(ctx, account, newEmail)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *Account, string) error); ok {
+		r1 = returnFunc(ctx, account, newEmail)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_GenerateEmailChangeToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenerateEmailChangeToken'
+type MockAccountService_GenerateEmailChangeToken_Call struct {
+	*mock.Call
+}
+
+// GenerateEmailChangeToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - account *Account
+//   - newEmail string
+func (_e *MockAccountService_Expecter) GenerateEmailChangeToken(ctx interface{}, account interface{}, newEmail interface{}) *MockAccountService_GenerateEmailChangeToken_Call {
+	return &MockAccountService_GenerateEmailChangeToken_Call{Call: _e.mock.On("GenerateEmailChangeToken", ctx, account, newEmail)}
+}
+
+func (_c *MockAccountService_GenerateEmailChangeToken_Call) Run(run func(ctx context.Context, account *Account, newEmail string)) *MockAccountService_GenerateEmailChangeToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *Account
+		if args[1] != nil {
+			arg1 = args[1].(*Account)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_GenerateEmailChangeToken_Call) Return(s string, err error) *MockAccountService_GenerateEmailChangeToken_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MockAccountService_GenerateEmailChangeToken_Call) RunAndReturn(run func(ctx context.Context, account *Account, newEmail string) (string, error)) *MockAccountService_GenerateEmailChangeToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GenerateEmailVerificationToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) GenerateEmailVerificationToken(ctx context.Context, account *Account) (string, error) {
 	ret := _mock.Called(ctx, account)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GeneratePasswordResetToken")
+		panic("no return value specified for GenerateEmailVerificationToken")
 	}
 
 	var r0 string
@@ -201,19 +350,19 @@ func (_mock *MockAccountService) GeneratePasswordResetToken(ctx context.Context,
 	return r0, r1
 }
 
-// MockAccountService_GeneratePasswordResetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GeneratePasswordResetToken'
-type MockAccountService_GeneratePasswordResetToken_Call struct {
+// MockAccountService_GenerateEmailVerificationToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenerateEmailVerificationToken'
+type MockAccountService_GenerateEmailVerificationToken_Call struct {
 	*mock.Call
 }
 
-// GeneratePasswordResetToken is a helper method to define mock.On call
+// GenerateEmailVerificationToken is a helper method to define mock.On call
 //   - ctx context.Context
 //   - account *Account
-func (_e *MockAccountService_Expecter) GeneratePasswordResetToken(ctx interface{}, account interface{}) *MockAccountService_GeneratePasswordResetToken_Call {
-	return &MockAccountService_GeneratePasswordResetToken_Call{Call: _e.mock.On("GeneratePasswordResetToken", ctx, account)}
+func (_e *MockAccountService_Expecter) GenerateEmailVerificationToken(ctx interface{}, account interface{}) *MockAccountService_GenerateEmailVerificationToken_Call {
+	return &MockAccountService_GenerateEmailVerificationToken_Call{Call: _e.mock.On("GenerateEmailVerificationToken", ctx, account)}
 }
 
-func (_c *MockAccountService_GeneratePasswordResetToken_Call) Run(run func(ctx context.Context, account *Account)) *MockAccountService_GeneratePasswordResetToken_Call {
+func (_c *MockAccountService_GenerateEmailVerificationToken_Call) Run(run func(ctx context.Context, account *Account)) *MockAccountService_GenerateEmailVerificationToken_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -231,63 +380,63 @@ func (_c *MockAccountService_GeneratePasswordResetToken_Call) Run(run func(ctx c
 	return _c
 }
 
-func (_c *MockAccountService_GeneratePasswordResetToken_Call) Return(s string, err error) *MockAccountService_GeneratePasswordResetToken_Call {
+func (_c *MockAccountService_GenerateEmailVerificationToken_Call) Return(s string, err error) *MockAccountService_GenerateEmailVerificationToken_Call {
 	_c.Call.Return(s, err)
 	return _c
 }
 
-func (_c *MockAccountService_GeneratePasswordResetToken_Call) RunAndReturn(run func(ctx context.Context, account *Account) (string, error)) *MockAccountService_GeneratePasswordResetToken_Call {
+func (_c *MockAccountService_GenerateEmailVerificationToken_Call) RunAndReturn(run func(ctx context.Context, account *Account) (string, error)) *MockAccountService_GenerateEmailVerificationToken_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// HashPassword provides a mock function for the type MockAccountService
-func (_mock *MockAccountService) HashPassword(ctx context.Context, password string) (string, error) {
-	ret := _mock.Called(ctx, password)
+// GeneratePasswordResetToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) GeneratePasswordResetToken(ctx context.Context, account *Account) (string, error) {
+	ret := _mock.Called(ctx, account)
 
 	if len(ret) == 0 {
-		panic("no return value specified for HashPassword")
+		panic("no return value specified for GeneratePasswordResetToken")
 	}
 
 	var r0 string
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
-		return returnFunc(ctx, password)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) (string, error)); ok {
+		return returnFunc(ctx, account)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = returnFunc(ctx, password)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) string); ok {
+		r0 = returnFunc(ctx, account)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = returnFunc(ctx, password)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *Account) error); ok {
+		r1 = returnFunc(ctx, account)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockAccountService_HashPassword_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HashPassword'
-type MockAccountService_HashPassword_Call struct {
+// MockAccountService_GeneratePasswordResetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GeneratePasswordResetToken'
+type MockAccountService_GeneratePasswordResetToken_Call struct {
 	*mock.Call
 }
 
-// HashPassword is a helper method to define mock.On call
+// GeneratePasswordResetToken is a helper method to define mock.On call
 //   - ctx context.Context
-//   - password string
-func (_e *MockAccountService_Expecter) HashPassword(ctx interface{}, password interface{}) *MockAccountService_HashPassword_Call {
-	return &MockAccountService_HashPassword_Call{Call: _e.mock.On("HashPassword", ctx, password)}
+//   - account *Account
+func (_e *MockAccountService_Expecter) GeneratePasswordResetToken(ctx interface{}, account interface{}) *MockAccountService_GeneratePasswordResetToken_Call {
+	return &MockAccountService_GeneratePasswordResetToken_Call{Call: _e.mock.On("GeneratePasswordResetToken", ctx, account)}
 }
 
-func (_c *MockAccountService_HashPassword_Call) Run(run func(ctx context.Context, password string)) *MockAccountService_HashPassword_Call {
+func (_c *MockAccountService_GeneratePasswordResetToken_Call) Run(run func(ctx context.Context, account *Account)) *MockAccountService_GeneratePasswordResetToken_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 string
+		var arg1 *Account
 		if args[1] != nil {
-			arg1 = args[1].(string)
+			arg1 = args[1].(*Account)
 		}
 		run(
 			arg0,
@@ -297,59 +446,74 @@ func (_c *MockAccountService_HashPassword_Call) Run(run func(ctx context.Context
 	return _c
 }
 
-func (_c *MockAccountService_HashPassword_Call) Return(s string, err error) *MockAccountService_HashPassword_Call {
+func (_c *MockAccountService_GeneratePasswordResetToken_Call) Return(s string, err error) *MockAccountService_GeneratePasswordResetToken_Call {
 	_c.Call.Return(s, err)
 	return _c
 }
 
-func (_c *MockAccountService_HashPassword_Call) RunAndReturn(run func(ctx context.Context, password string) (string, error)) *MockAccountService_HashPassword_Call {
+func (_c *MockAccountService_GeneratePasswordResetToken_Call) RunAndReturn(run func(ctx context.Context, account *Account) (string, error)) *MockAccountService_GeneratePasswordResetToken_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// SendPasswordResetEmail provides a mock function for the type MockAccountService
-func (_mock *MockAccountService) SendPasswordResetEmail(ctx context.Context, email string, token string) error {
-	ret := _mock.Called(ctx, email, token)
+// GenerateRefreshToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) GenerateRefreshToken(ctx context.Context, account *Account, rememberMe bool) (string, time.Time, error) {
+	ret := _mock.Called(ctx, account, rememberMe)
 
 	if len(ret) == 0 {
-		panic("no return value specified for SendPasswordResetEmail")
+		panic("no return value specified for GenerateRefreshToken")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = returnFunc(ctx, email, token)
+	var r0 string
+	var r1 time.Time
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account, bool) (string, time.Time, error)); ok {
+		return returnFunc(ctx, account, rememberMe)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account, bool) string); ok {
+		r0 = returnFunc(ctx, account, rememberMe)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *Account, bool) time.Time); ok {
+		r1 = returnFunc(ctx, account, rememberMe)
+	} else {
+		r1 = ret.Get(1).(time.Time)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, *Account, bool) error); ok {
+		r2 = returnFunc(ctx, account, rememberMe)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
 }
 
-// MockAccountService_SendPasswordResetEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendPasswordResetEmail'
-type MockAccountService_SendPasswordResetEmail_Call struct {
+// MockAccountService_GenerateRefreshToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenerateRefreshToken'
+type MockAccountService_GenerateRefreshToken_Call struct {
 	*mock.Call
 }
 
-// SendPasswordResetEmail is a helper method to define mock.On call
+// GenerateRefreshToken is a helper method to define mock.On call
 //   - ctx context.Context
-//   - email string
-//   - token string
-func (_e *MockAccountService_Expecter) SendPasswordResetEmail(ctx interface{}, email interface{}, token interface{}) *MockAccountService_SendPasswordResetEmail_Call {
-	return &MockAccountService_SendPasswordResetEmail_Call{Call: _e.mock.On("SendPasswordResetEmail", ctx, email, token)}
+//   - account *Account
+//   - rememberMe bool
+func (_e *MockAccountService_Expecter) GenerateRefreshToken(ctx interface{}, account interface{}, rememberMe interface{}) *MockAccountService_GenerateRefreshToken_Call {
+	return &MockAccountService_GenerateRefreshToken_Call{Call: _e.mock.On("GenerateRefreshToken", ctx, account, rememberMe)}
 }
 
-func (_c *MockAccountService_SendPasswordResetEmail_Call) Run(run func(ctx context.Context, email string, token string)) *MockAccountService_SendPasswordResetEmail_Call {
+func (_c *MockAccountService_GenerateRefreshToken_Call) Run(run func(ctx context.Context, account *Account, rememberMe bool)) *MockAccountService_GenerateRefreshToken_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 string
+		var arg1 *Account
 		if args[1] != nil {
-			arg1 = args[1].(string)
+			arg1 = args[1].(*Account)
 		}
-		var arg2 string
+		var arg2 bool
 		if args[2] != nil {
-			arg2 = args[2].(string)
+			arg2 = args[2].(bool)
 		}
 		run(
 			arg0,
@@ -360,55 +524,55 @@ func (_c *MockAccountService_SendPasswordResetEmail_Call) Run(run func(ctx conte
 	return _c
 }
 
-func (_c *MockAccountService_SendPasswordResetEmail_Call) Return(err error) *MockAccountService_SendPasswordResetEmail_Call {
-	_c.Call.Return(err)
+func (_c *MockAccountService_GenerateRefreshToken_Call) Return(s string, time1 time.Time, err error) *MockAccountService_GenerateRefreshToken_Call {
+	_c.Call.Return(s, time1, err)
 	return _c
 }
 
-func (_c *MockAccountService_SendPasswordResetEmail_Call) RunAndReturn(run func(ctx context.Context, email string, token string) error) *MockAccountService_SendPasswordResetEmail_Call {
+func (_c *MockAccountService_GenerateRefreshToken_Call) RunAndReturn(run func(ctx context.Context, account *Account, rememberMe bool) (string, time.Time, error)) *MockAccountService_GenerateRefreshToken_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// ValidateAuthToken provides a mock function for the type MockAccountService
-func (_mock *MockAccountService) ValidateAuthToken(ctx context.Context, token string) (uint, error) {
-	ret := _mock.Called(ctx, token)
+// HashPassword provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) HashPassword(ctx context.Context, password string) (string, error) {
+	ret := _mock.Called(ctx, password)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ValidateAuthToken")
+		panic("no return value specified for HashPassword")
 	}
 
-	var r0 uint
+	var r0 string
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (uint, error)); ok {
-		return returnFunc(ctx, token)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return returnFunc(ctx, password)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) uint); ok {
-		r0 = returnFunc(ctx, token)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = returnFunc(ctx, password)
 	} else {
-		r0 = ret.Get(0).(uint)
+		r0 = ret.Get(0).(string)
 	}
 	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = returnFunc(ctx, token)
+		r1 = returnFunc(ctx, password)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockAccountService_ValidateAuthToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ValidateAuthToken'
-type MockAccountService_ValidateAuthToken_Call struct {
+// MockAccountService_HashPassword_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HashPassword'
+type MockAccountService_HashPassword_Call struct {
 	*mock.Call
 }
 
-// ValidateAuthToken is a helper method to define mock.On call
+// HashPassword is a helper method to define mock.On call
 //   - ctx context.Context
-//   - token string
-func (_e *MockAccountService_Expecter) ValidateAuthToken(ctx interface{}, token interface{}) *MockAccountService_ValidateAuthToken_Call {
-	return &MockAccountService_ValidateAuthToken_Call{Call: _e.mock.On("ValidateAuthToken", ctx, token)}
+//   - password string
+func (_e *MockAccountService_Expecter) HashPassword(ctx interface{}, password interface{}) *MockAccountService_HashPassword_Call {
+	return &MockAccountService_HashPassword_Call{Call: _e.mock.On("HashPassword", ctx, password)}
 }
 
-func (_c *MockAccountService_ValidateAuthToken_Call) Run(run func(ctx context.Context, token string)) *MockAccountService_ValidateAuthToken_Call {
+func (_c *MockAccountService_HashPassword_Call) Run(run func(ctx context.Context, password string)) *MockAccountService_HashPassword_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -426,33 +590,35 @@ func (_c *MockAccountService_ValidateAuthToken_Call) Run(run func(ctx context.Co
 	return _c
 }
 
-func (_c *MockAccountService_ValidateAuthToken_Call) Return(v uint, err error) *MockAccountService_ValidateAuthToken_Call {
-	_c.Call.Return(v, err)
+func (_c *MockAccountService_HashPassword_Call) Return(s string, err error) *MockAccountService_HashPassword_Call {
+	_c.Call.Return(s, err)
 	return _c
 }
 
-func (_c *MockAccountService_ValidateAuthToken_Call) RunAndReturn(run func(ctx context.Context, token string) (uint, error)) *MockAccountService_ValidateAuthToken_Call {
+func (_c *MockAccountService_HashPassword_Call) RunAndReturn(run func(ctx context.Context, password string) (string, error)) *MockAccountService_HashPassword_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// ValidatePasswordResetToken provides a mock function for the type MockAccountService
-func (_mock *MockAccountService) ValidatePasswordResetToken(ctx context.Context, token string) (uint, error) {
+// ParseClaims provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) ParseClaims(ctx context.Context, token string) (*AuthClaims, error) {
 	ret := _mock.Called(ctx, token)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ValidatePasswordResetToken")
+		panic("no return value specified for ParseClaims")
 	}
 
-	var r0 uint
+	var r0 *AuthClaims
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (uint, error)); ok {
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*AuthClaims, error)); ok {
 		return returnFunc(ctx, token)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) uint); ok {
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *AuthClaims); ok {
 		r0 = returnFunc(ctx, token)
 	} else {
-		r0 = ret.Get(0).(uint)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*AuthClaims)
+		}
 	}
 	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
 		r1 = returnFunc(ctx, token)
@@ -462,19 +628,19 @@ func (_mock *MockAccountService) ValidatePasswordResetToken(ctx context.Context,
 	return r0, r1
 }
 
-// MockAccountService_ValidatePasswordResetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ValidatePasswordResetToken'
-type MockAccountService_ValidatePasswordResetToken_Call struct {
+// MockAccountService_ParseClaims_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ParseClaims'
+type MockAccountService_ParseClaims_Call struct {
 	*mock.Call
 }
 
-// ValidatePasswordResetToken is a helper method to define mock.On call
+// ParseClaims is a helper method to define mock.On call
 //   - ctx context.Context
 //   - token string
-func (_e *MockAccountService_Expecter) ValidatePasswordResetToken(ctx interface{}, token interface{}) *MockAccountService_ValidatePasswordResetToken_Call {
-	return &MockAccountService_ValidatePasswordResetToken_Call{Call: _e.mock.On("ValidatePasswordResetToken", ctx, token)}
+func (_e *MockAccountService_Expecter) ParseClaims(ctx interface{}, token interface{}) *MockAccountService_ParseClaims_Call {
+	return &MockAccountService_ParseClaims_Call{Call: _e.mock.On("ParseClaims", ctx, token)}
 }
 
-func (_c *MockAccountService_ValidatePasswordResetToken_Call) Run(run func(ctx context.Context, token string)) *MockAccountService_ValidatePasswordResetToken_Call {
+func (_c *MockAccountService_ParseClaims_Call) Run(run func(ctx context.Context, token string)) *MockAccountService_ParseClaims_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -492,23 +658,5324 @@ func (_c *MockAccountService_ValidatePasswordResetToken_Call) Run(run func(ctx c
 	return _c
 }
 
-func (_c *MockAccountService_ValidatePasswordResetToken_Call) Return(v uint, err error) *MockAccountService_ValidatePasswordResetToken_Call {
-	_c.Call.Return(v, err)
+func (_c *MockAccountService_ParseClaims_Call) Return(authClaims *AuthClaims, err error) *MockAccountService_ParseClaims_Call {
+	_c.Call.Return(authClaims, err)
 	return _c
 }
 
-func (_c *MockAccountService_ValidatePasswordResetToken_Call) RunAndReturn(run func(ctx context.Context, token string) (uint, error)) *MockAccountService_ValidatePasswordResetToken_Call {
+func (_c *MockAccountService_ParseClaims_Call) RunAndReturn(run func(ctx context.Context, token string) (*AuthClaims, error)) *MockAccountService_ParseClaims_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// NewMockAccountRepository creates a new instance of MockAccountRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
-// The first argument is typically a *testing.T value.
-func NewMockAccountRepository(t interface {
+// RotateSigningKey provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) RotateSigningKey(ctx context.Context) (*KeyRotation, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateSigningKey")
+	}
+
+	var r0 *KeyRotation
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) (*KeyRotation, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) *KeyRotation); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*KeyRotation)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_RotateSigningKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RotateSigningKey'
+type MockAccountService_RotateSigningKey_Call struct {
+	*mock.Call
+}
+
+// RotateSigningKey is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockAccountService_Expecter) RotateSigningKey(ctx interface{}) *MockAccountService_RotateSigningKey_Call {
+	return &MockAccountService_RotateSigningKey_Call{Call: _e.mock.On("RotateSigningKey", ctx)}
+}
+
+func (_c *MockAccountService_RotateSigningKey_Call) Run(run func(ctx context.Context)) *MockAccountService_RotateSigningKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_RotateSigningKey_Call) Return(keyRotation *KeyRotation, err error) *MockAccountService_RotateSigningKey_Call {
+	_c.Call.Return(keyRotation, err)
+	return _c
+}
+
+func (_c *MockAccountService_RotateSigningKey_Call) RunAndReturn(run func(ctx context.Context) (*KeyRotation, error)) *MockAccountService_RotateSigningKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendAccountExistsEmail provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) SendAccountExistsEmail(ctx context.Context, email string) (*EmailLog, error) {
+	ret := _mock.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendAccountExistsEmail")
+	}
+
+	var r0 *EmailLog
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*EmailLog, error)); ok {
+		return returnFunc(ctx, email)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *EmailLog); ok {
+		r0 = returnFunc(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*EmailLog)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_SendAccountExistsEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendAccountExistsEmail'
+type MockAccountService_SendAccountExistsEmail_Call struct {
+	*mock.Call
+}
+
+// SendAccountExistsEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+func (_e *MockAccountService_Expecter) SendAccountExistsEmail(ctx interface{}, email interface{}) *MockAccountService_SendAccountExistsEmail_Call {
+	return &MockAccountService_SendAccountExistsEmail_Call{Call: _e.mock.On("SendAccountExistsEmail", ctx, email)}
+}
+
+func (_c *MockAccountService_SendAccountExistsEmail_Call) Run(run func(ctx context.Context, email string)) *MockAccountService_SendAccountExistsEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_SendAccountExistsEmail_Call) Return(emailLog *EmailLog, err error) *MockAccountService_SendAccountExistsEmail_Call {
+	_c.Call.Return(emailLog, err)
+	return _c
+}
+
+func (_c *MockAccountService_SendAccountExistsEmail_Call) RunAndReturn(run func(ctx context.Context, email string) (*EmailLog, error)) *MockAccountService_SendAccountExistsEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendAccountLockedEmail provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) SendAccountLockedEmail(ctx context.Context, email string, lockedUntil time.Time) error {
+	ret := _mock.Called(ctx, email, lockedUntil)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendAccountLockedEmail")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = returnFunc(ctx, email, lockedUntil)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountService_SendAccountLockedEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendAccountLockedEmail'
+type MockAccountService_SendAccountLockedEmail_Call struct {
+	*mock.Call
+}
+
+// SendAccountLockedEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+//   - lockedUntil time.Time
+func (_e *MockAccountService_Expecter) SendAccountLockedEmail(ctx interface{}, email interface{}, lockedUntil interface{}) *MockAccountService_SendAccountLockedEmail_Call {
+	return &MockAccountService_SendAccountLockedEmail_Call{Call: _e.mock.On("SendAccountLockedEmail", ctx, email, lockedUntil)}
+}
+
+func (_c *MockAccountService_SendAccountLockedEmail_Call) Run(run func(ctx context.Context, email string, lockedUntil time.Time)) *MockAccountService_SendAccountLockedEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_SendAccountLockedEmail_Call) Return(err error) *MockAccountService_SendAccountLockedEmail_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountService_SendAccountLockedEmail_Call) RunAndReturn(run func(ctx context.Context, email string, lockedUntil time.Time) error) *MockAccountService_SendAccountLockedEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendEmailChangeEmail provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) SendEmailChangeEmail(ctx context.Context, email string, token string) (*EmailLog, error) {
+	ret := _mock.Called(ctx, email, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendEmailChangeEmail")
+	}
+
+	var r0 *EmailLog
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*EmailLog, error)); ok {
+		return returnFunc(ctx, email, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *EmailLog); ok {
+		r0 = returnFunc(ctx, email, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*EmailLog)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, email, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_SendEmailChangeEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendEmailChangeEmail'
+type MockAccountService_SendEmailChangeEmail_Call struct {
+	*mock.Call
+}
+
+// SendEmailChangeEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+//   - token string
+func (_e *MockAccountService_Expecter) SendEmailChangeEmail(ctx interface{}, email interface{}, token interface{}) *MockAccountService_SendEmailChangeEmail_Call {
+	return &MockAccountService_SendEmailChangeEmail_Call{Call: _e.mock.On("SendEmailChangeEmail", ctx, email, token)}
+}
+
+func (_c *MockAccountService_SendEmailChangeEmail_Call) Run(run func(ctx context.Context, email string, token string)) *MockAccountService_SendEmailChangeEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_SendEmailChangeEmail_Call) Return(emailLog *EmailLog, err error) *MockAccountService_SendEmailChangeEmail_Call {
+	_c.Call.Return(emailLog, err)
+	return _c
+}
+
+func (_c *MockAccountService_SendEmailChangeEmail_Call) RunAndReturn(run func(ctx context.Context, email string, token string) (*EmailLog, error)) *MockAccountService_SendEmailChangeEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendInactivityWarningEmail provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) SendInactivityWarningEmail(ctx context.Context, email string, disableAt time.Time) error {
+	ret := _mock.Called(ctx, email, disableAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendInactivityWarningEmail")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = returnFunc(ctx, email, disableAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountService_SendInactivityWarningEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendInactivityWarningEmail'
+type MockAccountService_SendInactivityWarningEmail_Call struct {
+	*mock.Call
+}
+
+// SendInactivityWarningEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+//   - disableAt time.Time
+func (_e *MockAccountService_Expecter) SendInactivityWarningEmail(ctx interface{}, email interface{}, disableAt interface{}) *MockAccountService_SendInactivityWarningEmail_Call {
+	return &MockAccountService_SendInactivityWarningEmail_Call{Call: _e.mock.On("SendInactivityWarningEmail", ctx, email, disableAt)}
+}
+
+func (_c *MockAccountService_SendInactivityWarningEmail_Call) Run(run func(ctx context.Context, email string, disableAt time.Time)) *MockAccountService_SendInactivityWarningEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_SendInactivityWarningEmail_Call) Return(err error) *MockAccountService_SendInactivityWarningEmail_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountService_SendInactivityWarningEmail_Call) RunAndReturn(run func(ctx context.Context, email string, disableAt time.Time) error) *MockAccountService_SendInactivityWarningEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendPasswordResetEmail provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) SendPasswordResetEmail(ctx context.Context, email string, token string) (*EmailLog, error) {
+	ret := _mock.Called(ctx, email, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendPasswordResetEmail")
+	}
+
+	var r0 *EmailLog
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*EmailLog, error)); ok {
+		return returnFunc(ctx, email, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *EmailLog); ok {
+		r0 = returnFunc(ctx, email, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*EmailLog)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, email, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_SendPasswordResetEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendPasswordResetEmail'
+type MockAccountService_SendPasswordResetEmail_Call struct {
+	*mock.Call
+}
+
+// SendPasswordResetEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+//   - token string
+func (_e *MockAccountService_Expecter) SendPasswordResetEmail(ctx interface{}, email interface{}, token interface{}) *MockAccountService_SendPasswordResetEmail_Call {
+	return &MockAccountService_SendPasswordResetEmail_Call{Call: _e.mock.On("SendPasswordResetEmail", ctx, email, token)}
+}
+
+func (_c *MockAccountService_SendPasswordResetEmail_Call) Run(run func(ctx context.Context, email string, token string)) *MockAccountService_SendPasswordResetEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_SendPasswordResetEmail_Call) Return(emailLog *EmailLog, err error) *MockAccountService_SendPasswordResetEmail_Call {
+	_c.Call.Return(emailLog, err)
+	return _c
+}
+
+func (_c *MockAccountService_SendPasswordResetEmail_Call) RunAndReturn(run func(ctx context.Context, email string, token string) (*EmailLog, error)) *MockAccountService_SendPasswordResetEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendPendingActionEmail provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) SendPendingActionEmail(ctx context.Context, email string, action *PendingAccountAction, token string) error {
+	ret := _mock.Called(ctx, email, action, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendPendingActionEmail")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, *PendingAccountAction, string) error); ok {
+		r0 = returnFunc(ctx, email, action, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountService_SendPendingActionEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendPendingActionEmail'
+type MockAccountService_SendPendingActionEmail_Call struct {
+	*mock.Call
+}
+
+// SendPendingActionEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+//   - action *PendingAccountAction
+//   - token string
+func (_e *MockAccountService_Expecter) SendPendingActionEmail(ctx interface{}, email interface{}, action interface{}, token interface{}) *MockAccountService_SendPendingActionEmail_Call {
+	return &MockAccountService_SendPendingActionEmail_Call{Call: _e.mock.On("SendPendingActionEmail", ctx, email, action, token)}
+}
+
+func (_c *MockAccountService_SendPendingActionEmail_Call) Run(run func(ctx context.Context, email string, action *PendingAccountAction, token string)) *MockAccountService_SendPendingActionEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 *PendingAccountAction
+		if args[2] != nil {
+			arg2 = args[2].(*PendingAccountAction)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_SendPendingActionEmail_Call) Return(err error) *MockAccountService_SendPendingActionEmail_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountService_SendPendingActionEmail_Call) RunAndReturn(run func(ctx context.Context, email string, action *PendingAccountAction, token string) error) *MockAccountService_SendPendingActionEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendVerificationEmail provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) SendVerificationEmail(ctx context.Context, email string, token string) (*EmailLog, error) {
+	ret := _mock.Called(ctx, email, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendVerificationEmail")
+	}
+
+	var r0 *EmailLog
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*EmailLog, error)); ok {
+		return returnFunc(ctx, email, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *EmailLog); ok {
+		r0 = returnFunc(ctx, email, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*EmailLog)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, email, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_SendVerificationEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendVerificationEmail'
+type MockAccountService_SendVerificationEmail_Call struct {
+	*mock.Call
+}
+
+// SendVerificationEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+//   - token string
+func (_e *MockAccountService_Expecter) SendVerificationEmail(ctx interface{}, email interface{}, token interface{}) *MockAccountService_SendVerificationEmail_Call {
+	return &MockAccountService_SendVerificationEmail_Call{Call: _e.mock.On("SendVerificationEmail", ctx, email, token)}
+}
+
+func (_c *MockAccountService_SendVerificationEmail_Call) Run(run func(ctx context.Context, email string, token string)) *MockAccountService_SendVerificationEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_SendVerificationEmail_Call) Return(emailLog *EmailLog, err error) *MockAccountService_SendVerificationEmail_Call {
+	_c.Call.Return(emailLog, err)
+	return _c
+}
+
+func (_c *MockAccountService_SendVerificationEmail_Call) RunAndReturn(run func(ctx context.Context, email string, token string) (*EmailLog, error)) *MockAccountService_SendVerificationEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ValidateAuthToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) ValidateAuthToken(ctx context.Context, token string) (uint, error) {
+	ret := _mock.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateAuthToken")
+	}
+
+	var r0 uint
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (uint, error)); ok {
+		return returnFunc(ctx, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) uint); ok {
+		r0 = returnFunc(ctx, token)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_ValidateAuthToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ValidateAuthToken'
+type MockAccountService_ValidateAuthToken_Call struct {
+	*mock.Call
+}
+
+// ValidateAuthToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token string
+func (_e *MockAccountService_Expecter) ValidateAuthToken(ctx interface{}, token interface{}) *MockAccountService_ValidateAuthToken_Call {
+	return &MockAccountService_ValidateAuthToken_Call{Call: _e.mock.On("ValidateAuthToken", ctx, token)}
+}
+
+func (_c *MockAccountService_ValidateAuthToken_Call) Run(run func(ctx context.Context, token string)) *MockAccountService_ValidateAuthToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_ValidateAuthToken_Call) Return(v uint, err error) *MockAccountService_ValidateAuthToken_Call {
+	_c.Call.Return(v, err)
+	return _c
+}
+
+func (_c *MockAccountService_ValidateAuthToken_Call) RunAndReturn(run func(ctx context.Context, token string) (uint, error)) *MockAccountService_ValidateAuthToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ValidateEmailChangeToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) ValidateEmailChangeToken(ctx context.Context, token string) (uint, string, string, error) {
+	ret := _mock.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateEmailChangeToken")
+	}
+
+	var r0 uint
+	var r1 string
+	var r2 string
+	var r3 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (uint, string, string, error)); ok {
+		return returnFunc(ctx, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) uint); ok {
+		r0 = returnFunc(ctx, token)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) string); ok {
+		r1 = returnFunc(ctx, token)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, string) string); ok {
+		r2 = returnFunc(ctx, token)
+	} else {
+		r2 = ret.Get(2).(string)
+	}
+	if returnFunc, ok := ret.Get(3).(func(context.Context, string) error); ok {
+		r3 = returnFunc(ctx, token)
+	} else {
+		r3 = ret.Error(3)
+	}
+	return r0, r1, r2, r3
+}
+
+// MockAccountService_ValidateEmailChangeToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ValidateEmailChangeToken'
+type MockAccountService_ValidateEmailChangeToken_Call struct {
+	*mock.Call
+}
+
+// ValidateEmailChangeToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token string
+func (_e *MockAccountService_Expecter) ValidateEmailChangeToken(ctx interface{}, token interface{}) *MockAccountService_ValidateEmailChangeToken_Call {
+	return &MockAccountService_ValidateEmailChangeToken_Call{Call: _e.mock.On("ValidateEmailChangeToken", ctx, token)}
+}
+
+func (_c *MockAccountService_ValidateEmailChangeToken_Call) Run(run func(ctx context.Context, token string)) *MockAccountService_ValidateEmailChangeToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_ValidateEmailChangeToken_Call) Return(v uint, s string, s1 string, err error) *MockAccountService_ValidateEmailChangeToken_Call {
+	_c.Call.Return(v, s, s1, err)
+	return _c
+}
+
+func (_c *MockAccountService_ValidateEmailChangeToken_Call) RunAndReturn(run func(ctx context.Context, token string) (uint, string, string, error)) *MockAccountService_ValidateEmailChangeToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ValidateEmailVerificationToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) ValidateEmailVerificationToken(ctx context.Context, token string) (uint, string, error) {
+	ret := _mock.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateEmailVerificationToken")
+	}
+
+	var r0 uint
+	var r1 string
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (uint, string, error)); ok {
+		return returnFunc(ctx, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) uint); ok {
+		r0 = returnFunc(ctx, token)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) string); ok {
+		r1 = returnFunc(ctx, token)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = returnFunc(ctx, token)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockAccountService_ValidateEmailVerificationToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ValidateEmailVerificationToken'
+type MockAccountService_ValidateEmailVerificationToken_Call struct {
+	*mock.Call
+}
+
+// ValidateEmailVerificationToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token string
+func (_e *MockAccountService_Expecter) ValidateEmailVerificationToken(ctx interface{}, token interface{}) *MockAccountService_ValidateEmailVerificationToken_Call {
+	return &MockAccountService_ValidateEmailVerificationToken_Call{Call: _e.mock.On("ValidateEmailVerificationToken", ctx, token)}
+}
+
+func (_c *MockAccountService_ValidateEmailVerificationToken_Call) Run(run func(ctx context.Context, token string)) *MockAccountService_ValidateEmailVerificationToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_ValidateEmailVerificationToken_Call) Return(v uint, s string, err error) *MockAccountService_ValidateEmailVerificationToken_Call {
+	_c.Call.Return(v, s, err)
+	return _c
+}
+
+func (_c *MockAccountService_ValidateEmailVerificationToken_Call) RunAndReturn(run func(ctx context.Context, token string) (uint, string, error)) *MockAccountService_ValidateEmailVerificationToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ValidatePasswordResetToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) ValidatePasswordResetToken(ctx context.Context, token string) (uint, error) {
+	ret := _mock.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidatePasswordResetToken")
+	}
+
+	var r0 uint
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (uint, error)); ok {
+		return returnFunc(ctx, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) uint); ok {
+		r0 = returnFunc(ctx, token)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_ValidatePasswordResetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ValidatePasswordResetToken'
+type MockAccountService_ValidatePasswordResetToken_Call struct {
+	*mock.Call
+}
+
+// ValidatePasswordResetToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token string
+func (_e *MockAccountService_Expecter) ValidatePasswordResetToken(ctx interface{}, token interface{}) *MockAccountService_ValidatePasswordResetToken_Call {
+	return &MockAccountService_ValidatePasswordResetToken_Call{Call: _e.mock.On("ValidatePasswordResetToken", ctx, token)}
+}
+
+func (_c *MockAccountService_ValidatePasswordResetToken_Call) Run(run func(ctx context.Context, token string)) *MockAccountService_ValidatePasswordResetToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_ValidatePasswordResetToken_Call) Return(v uint, err error) *MockAccountService_ValidatePasswordResetToken_Call {
+	_c.Call.Return(v, err)
+	return _c
+}
+
+func (_c *MockAccountService_ValidatePasswordResetToken_Call) RunAndReturn(run func(ctx context.Context, token string) (uint, error)) *MockAccountService_ValidatePasswordResetToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ValidateRefreshToken provides a mock function for the type MockAccountService
+func (_mock *MockAccountService) ValidateRefreshToken(ctx context.Context, token string) (uint, error) {
+	ret := _mock.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateRefreshToken")
+	}
+
+	var r0 uint
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (uint, error)); ok {
+		return returnFunc(ctx, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) uint); ok {
+		r0 = returnFunc(ctx, token)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountService_ValidateRefreshToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ValidateRefreshToken'
+type MockAccountService_ValidateRefreshToken_Call struct {
+	*mock.Call
+}
+
+// ValidateRefreshToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token string
+func (_e *MockAccountService_Expecter) ValidateRefreshToken(ctx interface{}, token interface{}) *MockAccountService_ValidateRefreshToken_Call {
+	return &MockAccountService_ValidateRefreshToken_Call{Call: _e.mock.On("ValidateRefreshToken", ctx, token)}
+}
+
+func (_c *MockAccountService_ValidateRefreshToken_Call) Run(run func(ctx context.Context, token string)) *MockAccountService_ValidateRefreshToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountService_ValidateRefreshToken_Call) Return(v uint, err error) *MockAccountService_ValidateRefreshToken_Call {
+	_c.Call.Return(v, err)
+	return _c
+}
+
+func (_c *MockAccountService_ValidateRefreshToken_Call) RunAndReturn(run func(ctx context.Context, token string) (uint, error)) *MockAccountService_ValidateRefreshToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockActivityLogger creates a new instance of MockActivityLogger. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockActivityLogger(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockActivityLogger {
+	mock := &MockActivityLogger{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockActivityLogger is an autogenerated mock type for the ActivityLogger type
+type MockActivityLogger struct {
+	mock.Mock
+}
+
+type MockActivityLogger_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockActivityLogger) EXPECT() *MockActivityLogger_Expecter {
+	return &MockActivityLogger_Expecter{mock: &_m.Mock}
+}
+
+// LogAccountActivity provides a mock function for the type MockActivityLogger
+func (_mock *MockActivityLogger) LogAccountActivity(ctx context.Context, accountID uint, activity string) error {
+	ret := _mock.Called(ctx, accountID, activity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogAccountActivity")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) error); ok {
+		r0 = returnFunc(ctx, accountID, activity)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockActivityLogger_LogAccountActivity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LogAccountActivity'
+type MockActivityLogger_LogAccountActivity_Call struct {
+	*mock.Call
+}
+
+// LogAccountActivity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - activity string
+func (_e *MockActivityLogger_Expecter) LogAccountActivity(ctx interface{}, accountID interface{}, activity interface{}) *MockActivityLogger_LogAccountActivity_Call {
+	return &MockActivityLogger_LogAccountActivity_Call{Call: _e.mock.On("LogAccountActivity", ctx, accountID, activity)}
+}
+
+func (_c *MockActivityLogger_LogAccountActivity_Call) Run(run func(ctx context.Context, accountID uint, activity string)) *MockActivityLogger_LogAccountActivity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockActivityLogger_LogAccountActivity_Call) Return(err error) *MockActivityLogger_LogAccountActivity_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockActivityLogger_LogAccountActivity_Call) RunAndReturn(run func(ctx context.Context, accountID uint, activity string) error) *MockActivityLogger_LogAccountActivity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Shutdown provides a mock function for the type MockActivityLogger
+func (_mock *MockActivityLogger) Shutdown(ctx context.Context) error {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Shutdown")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockActivityLogger_Shutdown_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Shutdown'
+type MockActivityLogger_Shutdown_Call struct {
+	*mock.Call
+}
+
+// Shutdown is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockActivityLogger_Expecter) Shutdown(ctx interface{}) *MockActivityLogger_Shutdown_Call {
+	return &MockActivityLogger_Shutdown_Call{Call: _e.mock.On("Shutdown", ctx)}
+}
+
+func (_c *MockActivityLogger_Shutdown_Call) Run(run func(ctx context.Context)) *MockActivityLogger_Shutdown_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockActivityLogger_Shutdown_Call) Return(err error) *MockActivityLogger_Shutdown_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockActivityLogger_Shutdown_Call) RunAndReturn(run func(ctx context.Context) error) *MockActivityLogger_Shutdown_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockAccountRepository creates a new instance of MockAccountRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockAccountRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockAccountRepository {
+	mock := &MockAccountRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockAccountRepository is an autogenerated mock type for the AccountRepository type
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+type MockAccountRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockAccountRepository) EXPECT() *MockAccountRepository_Expecter {
+	return &MockAccountRepository_Expecter{mock: &_m.Mock}
+}
+
+// AnonymizeAccount provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) AnonymizeAccount(ctx context.Context, id uint, anonymizedAt time.Time) error {
+	ret := _mock.Called(ctx, id, anonymizedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AnonymizeAccount")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, time.Time) error); ok {
+		r0 = returnFunc(ctx, id, anonymizedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_AnonymizeAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AnonymizeAccount'
+type MockAccountRepository_AnonymizeAccount_Call struct {
+	*mock.Call
+}
+
+// AnonymizeAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+//   - anonymizedAt time.Time
+func (_e *MockAccountRepository_Expecter) AnonymizeAccount(ctx interface{}, id interface{}, anonymizedAt interface{}) *MockAccountRepository_AnonymizeAccount_Call {
+	return &MockAccountRepository_AnonymizeAccount_Call{Call: _e.mock.On("AnonymizeAccount", ctx, id, anonymizedAt)}
+}
+
+func (_c *MockAccountRepository_AnonymizeAccount_Call) Run(run func(ctx context.Context, id uint, anonymizedAt time.Time)) *MockAccountRepository_AnonymizeAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_AnonymizeAccount_Call) Return(err error) *MockAccountRepository_AnonymizeAccount_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_AnonymizeAccount_Call) RunAndReturn(run func(ctx context.Context, id uint, anonymizedAt time.Time) error) *MockAccountRepository_AnonymizeAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CountActiveRefreshTokens provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) CountActiveRefreshTokens(ctx context.Context, at time.Time) (int64, error) {
+	ret := _mock.Called(ctx, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountActiveRefreshTokens")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return returnFunc(ctx, at)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = returnFunc(ctx, at)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = returnFunc(ctx, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_CountActiveRefreshTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CountActiveRefreshTokens'
+type MockAccountRepository_CountActiveRefreshTokens_Call struct {
+	*mock.Call
+}
+
+// CountActiveRefreshTokens is a helper method to define mock.On call
+//   - ctx context.Context
+//   - at time.Time
+func (_e *MockAccountRepository_Expecter) CountActiveRefreshTokens(ctx interface{}, at interface{}) *MockAccountRepository_CountActiveRefreshTokens_Call {
+	return &MockAccountRepository_CountActiveRefreshTokens_Call{Call: _e.mock.On("CountActiveRefreshTokens", ctx, at)}
+}
+
+func (_c *MockAccountRepository_CountActiveRefreshTokens_Call) Run(run func(ctx context.Context, at time.Time)) *MockAccountRepository_CountActiveRefreshTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 time.Time
+		if args[1] != nil {
+			arg1 = args[1].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_CountActiveRefreshTokens_Call) Return(n int64, err error) *MockAccountRepository_CountActiveRefreshTokens_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_CountActiveRefreshTokens_Call) RunAndReturn(run func(ctx context.Context, at time.Time) (int64, error)) *MockAccountRepository_CountActiveRefreshTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CountActivitiesSince provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) CountActivitiesSince(ctx context.Context, activities []string, since time.Time) (int64, error) {
+	ret := _mock.Called(ctx, activities, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountActivitiesSince")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []string, time.Time) (int64, error)); ok {
+		return returnFunc(ctx, activities, since)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []string, time.Time) int64); ok {
+		r0 = returnFunc(ctx, activities, since)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, []string, time.Time) error); ok {
+		r1 = returnFunc(ctx, activities, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_CountActivitiesSince_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CountActivitiesSince'
+type MockAccountRepository_CountActivitiesSince_Call struct {
+	*mock.Call
+}
+
+// CountActivitiesSince is a helper method to define mock.On call
+//   - ctx context.Context
+//   - activities []string
+//   - since time.Time
+func (_e *MockAccountRepository_Expecter) CountActivitiesSince(ctx interface{}, activities interface{}, since interface{}) *MockAccountRepository_CountActivitiesSince_Call {
+	return &MockAccountRepository_CountActivitiesSince_Call{Call: _e.mock.On("CountActivitiesSince", ctx, activities, since)}
+}
+
+func (_c *MockAccountRepository_CountActivitiesSince_Call) Run(run func(ctx context.Context, activities []string, since time.Time)) *MockAccountRepository_CountActivitiesSince_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []string
+		if args[1] != nil {
+			arg1 = args[1].([]string)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_CountActivitiesSince_Call) Return(n int64, err error) *MockAccountRepository_CountActivitiesSince_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_CountActivitiesSince_Call) RunAndReturn(run func(ctx context.Context, activities []string, since time.Time) (int64, error)) *MockAccountRepository_CountActivitiesSince_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateAPIKey provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) CreateAPIKey(ctx context.Context, key *APIKey) (*APIKey, error) {
+	ret := _mock.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAPIKey")
+	}
+
+	var r0 *APIKey
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *APIKey) (*APIKey, error)); ok {
+		return returnFunc(ctx, key)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *APIKey) *APIKey); ok {
+		r0 = returnFunc(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*APIKey)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *APIKey) error); ok {
+		r1 = returnFunc(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_CreateAPIKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateAPIKey'
+type MockAccountRepository_CreateAPIKey_Call struct {
+	*mock.Call
+}
+
+// CreateAPIKey is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key *APIKey
+func (_e *MockAccountRepository_Expecter) CreateAPIKey(ctx interface{}, key interface{}) *MockAccountRepository_CreateAPIKey_Call {
+	return &MockAccountRepository_CreateAPIKey_Call{Call: _e.mock.On("CreateAPIKey", ctx, key)}
+}
+
+func (_c *MockAccountRepository_CreateAPIKey_Call) Run(run func(ctx context.Context, key *APIKey)) *MockAccountRepository_CreateAPIKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *APIKey
+		if args[1] != nil {
+			arg1 = args[1].(*APIKey)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_CreateAPIKey_Call) Return(apiKey *APIKey, err error) *MockAccountRepository_CreateAPIKey_Call {
+	_c.Call.Return(apiKey, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_CreateAPIKey_Call) RunAndReturn(run func(ctx context.Context, key *APIKey) (*APIKey, error)) *MockAccountRepository_CreateAPIKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateAccount provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) CreateAccount(ctx context.Context, account *Account) (*Account, error) {
+	ret := _mock.Called(ctx, account)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAccount")
+	}
+
+	var r0 *Account
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) (*Account, error)); ok {
+		return returnFunc(ctx, account)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) *Account); ok {
+		r0 = returnFunc(ctx, account)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Account)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *Account) error); ok {
+		r1 = returnFunc(ctx, account)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_CreateAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateAccount'
+type MockAccountRepository_CreateAccount_Call struct {
+	*mock.Call
+}
+
+// CreateAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - account *Account
+func (_e *MockAccountRepository_Expecter) CreateAccount(ctx interface{}, account interface{}) *MockAccountRepository_CreateAccount_Call {
+	return &MockAccountRepository_CreateAccount_Call{Call: _e.mock.On("CreateAccount", ctx, account)}
+}
+
+func (_c *MockAccountRepository_CreateAccount_Call) Run(run func(ctx context.Context, account *Account)) *MockAccountRepository_CreateAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *Account
+		if args[1] != nil {
+			arg1 = args[1].(*Account)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_CreateAccount_Call) Return(account1 *Account, err error) *MockAccountRepository_CreateAccount_Call {
+	_c.Call.Return(account1, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_CreateAccount_Call) RunAndReturn(run func(ctx context.Context, account *Account) (*Account, error)) *MockAccountRepository_CreateAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreatePasswordResetToken provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) CreatePasswordResetToken(ctx context.Context, token *PasswordResetToken) (*PasswordResetToken, error) {
+	ret := _mock.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePasswordResetToken")
+	}
+
+	var r0 *PasswordResetToken
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *PasswordResetToken) (*PasswordResetToken, error)); ok {
+		return returnFunc(ctx, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *PasswordResetToken) *PasswordResetToken); ok {
+		r0 = returnFunc(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PasswordResetToken)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *PasswordResetToken) error); ok {
+		r1 = returnFunc(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_CreatePasswordResetToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreatePasswordResetToken'
+type MockAccountRepository_CreatePasswordResetToken_Call struct {
+	*mock.Call
+}
+
+// CreatePasswordResetToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token *PasswordResetToken
+func (_e *MockAccountRepository_Expecter) CreatePasswordResetToken(ctx interface{}, token interface{}) *MockAccountRepository_CreatePasswordResetToken_Call {
+	return &MockAccountRepository_CreatePasswordResetToken_Call{Call: _e.mock.On("CreatePasswordResetToken", ctx, token)}
+}
+
+func (_c *MockAccountRepository_CreatePasswordResetToken_Call) Run(run func(ctx context.Context, token *PasswordResetToken)) *MockAccountRepository_CreatePasswordResetToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *PasswordResetToken
+		if args[1] != nil {
+			arg1 = args[1].(*PasswordResetToken)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_CreatePasswordResetToken_Call) Return(passwordResetToken *PasswordResetToken, err error) *MockAccountRepository_CreatePasswordResetToken_Call {
+	_c.Call.Return(passwordResetToken, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_CreatePasswordResetToken_Call) RunAndReturn(run func(ctx context.Context, token *PasswordResetToken) (*PasswordResetToken, error)) *MockAccountRepository_CreatePasswordResetToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreatePendingAction provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) CreatePendingAction(ctx context.Context, action *PendingAccountAction) (*PendingAccountAction, error) {
+	ret := _mock.Called(ctx, action)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePendingAction")
+	}
+
+	var r0 *PendingAccountAction
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *PendingAccountAction) (*PendingAccountAction, error)); ok {
+		return returnFunc(ctx, action)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *PendingAccountAction) *PendingAccountAction); ok {
+		r0 = returnFunc(ctx, action)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PendingAccountAction)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *PendingAccountAction) error); ok {
+		r1 = returnFunc(ctx, action)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_CreatePendingAction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreatePendingAction'
+type MockAccountRepository_CreatePendingAction_Call struct {
+	*mock.Call
+}
+
+// CreatePendingAction is a helper method to define mock.On call
+//   - ctx context.Context
+//   - action *PendingAccountAction
+func (_e *MockAccountRepository_Expecter) CreatePendingAction(ctx interface{}, action interface{}) *MockAccountRepository_CreatePendingAction_Call {
+	return &MockAccountRepository_CreatePendingAction_Call{Call: _e.mock.On("CreatePendingAction", ctx, action)}
+}
+
+func (_c *MockAccountRepository_CreatePendingAction_Call) Run(run func(ctx context.Context, action *PendingAccountAction)) *MockAccountRepository_CreatePendingAction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *PendingAccountAction
+		if args[1] != nil {
+			arg1 = args[1].(*PendingAccountAction)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_CreatePendingAction_Call) Return(pendingAccountAction *PendingAccountAction, err error) *MockAccountRepository_CreatePendingAction_Call {
+	_c.Call.Return(pendingAccountAction, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_CreatePendingAction_Call) RunAndReturn(run func(ctx context.Context, action *PendingAccountAction) (*PendingAccountAction, error)) *MockAccountRepository_CreatePendingAction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateRefreshToken provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) CreateRefreshToken(ctx context.Context, token *RefreshToken) (*RefreshToken, error) {
+	ret := _mock.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateRefreshToken")
+	}
+
+	var r0 *RefreshToken
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *RefreshToken) (*RefreshToken, error)); ok {
+		return returnFunc(ctx, token)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *RefreshToken) *RefreshToken); ok {
+		r0 = returnFunc(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*RefreshToken)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *RefreshToken) error); ok {
+		r1 = returnFunc(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_CreateRefreshToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateRefreshToken'
+type MockAccountRepository_CreateRefreshToken_Call struct {
+	*mock.Call
+}
+
+// CreateRefreshToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token *RefreshToken
+func (_e *MockAccountRepository_Expecter) CreateRefreshToken(ctx interface{}, token interface{}) *MockAccountRepository_CreateRefreshToken_Call {
+	return &MockAccountRepository_CreateRefreshToken_Call{Call: _e.mock.On("CreateRefreshToken", ctx, token)}
+}
+
+func (_c *MockAccountRepository_CreateRefreshToken_Call) Run(run func(ctx context.Context, token *RefreshToken)) *MockAccountRepository_CreateRefreshToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *RefreshToken
+		if args[1] != nil {
+			arg1 = args[1].(*RefreshToken)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_CreateRefreshToken_Call) Return(refreshToken *RefreshToken, err error) *MockAccountRepository_CreateRefreshToken_Call {
+	_c.Call.Return(refreshToken, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_CreateRefreshToken_Call) RunAndReturn(run func(ctx context.Context, token *RefreshToken) (*RefreshToken, error)) *MockAccountRepository_CreateRefreshToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteAccount provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) DeleteAccount(ctx context.Context, id uint) error {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAccount")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) error); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_DeleteAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteAccount'
+type MockAccountRepository_DeleteAccount_Call struct {
+	*mock.Call
+}
+
+// DeleteAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+func (_e *MockAccountRepository_Expecter) DeleteAccount(ctx interface{}, id interface{}) *MockAccountRepository_DeleteAccount_Call {
+	return &MockAccountRepository_DeleteAccount_Call{Call: _e.mock.On("DeleteAccount", ctx, id)}
+}
+
+func (_c *MockAccountRepository_DeleteAccount_Call) Run(run func(ctx context.Context, id uint)) *MockAccountRepository_DeleteAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_DeleteAccount_Call) Return(err error) *MockAccountRepository_DeleteAccount_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_DeleteAccount_Call) RunAndReturn(run func(ctx context.Context, id uint) error) *MockAccountRepository_DeleteAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// EvictRefreshTokens provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) EvictRefreshTokens(ctx context.Context, accountID uint, keep int, revokedAt time.Time) (int64, error) {
+	ret := _mock.Called(ctx, accountID, keep, revokedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EvictRefreshTokens")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, int, time.Time) (int64, error)); ok {
+		return returnFunc(ctx, accountID, keep, revokedAt)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, int, time.Time) int64); ok {
+		r0 = returnFunc(ctx, accountID, keep, revokedAt)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint, int, time.Time) error); ok {
+		r1 = returnFunc(ctx, accountID, keep, revokedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_EvictRefreshTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EvictRefreshTokens'
+type MockAccountRepository_EvictRefreshTokens_Call struct {
+	*mock.Call
+}
+
+// EvictRefreshTokens is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - keep int
+//   - revokedAt time.Time
+func (_e *MockAccountRepository_Expecter) EvictRefreshTokens(ctx interface{}, accountID interface{}, keep interface{}, revokedAt interface{}) *MockAccountRepository_EvictRefreshTokens_Call {
+	return &MockAccountRepository_EvictRefreshTokens_Call{Call: _e.mock.On("EvictRefreshTokens", ctx, accountID, keep, revokedAt)}
+}
+
+func (_c *MockAccountRepository_EvictRefreshTokens_Call) Run(run func(ctx context.Context, accountID uint, keep int, revokedAt time.Time)) *MockAccountRepository_EvictRefreshTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		var arg3 time.Time
+		if args[3] != nil {
+			arg3 = args[3].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_EvictRefreshTokens_Call) Return(n int64, err error) *MockAccountRepository_EvictRefreshTokens_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_EvictRefreshTokens_Call) RunAndReturn(run func(ctx context.Context, accountID uint, keep int, revokedAt time.Time) (int64, error)) *MockAccountRepository_EvictRefreshTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAPIKeyByHash provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	ret := _mock.Called(ctx, keyHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKeyByHash")
+	}
+
+	var r0 *APIKey
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*APIKey, error)); ok {
+		return returnFunc(ctx, keyHash)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *APIKey); ok {
+		r0 = returnFunc(ctx, keyHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*APIKey)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, keyHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetAPIKeyByHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAPIKeyByHash'
+type MockAccountRepository_GetAPIKeyByHash_Call struct {
+	*mock.Call
+}
+
+// GetAPIKeyByHash is a helper method to define mock.On call
+//   - ctx context.Context
+//   - keyHash string
+func (_e *MockAccountRepository_Expecter) GetAPIKeyByHash(ctx interface{}, keyHash interface{}) *MockAccountRepository_GetAPIKeyByHash_Call {
+	return &MockAccountRepository_GetAPIKeyByHash_Call{Call: _e.mock.On("GetAPIKeyByHash", ctx, keyHash)}
+}
+
+func (_c *MockAccountRepository_GetAPIKeyByHash_Call) Run(run func(ctx context.Context, keyHash string)) *MockAccountRepository_GetAPIKeyByHash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetAPIKeyByHash_Call) Return(apiKey *APIKey, err error) *MockAccountRepository_GetAPIKeyByHash_Call {
+	_c.Call.Return(apiKey, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetAPIKeyByHash_Call) RunAndReturn(run func(ctx context.Context, keyHash string) (*APIKey, error)) *MockAccountRepository_GetAPIKeyByHash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAccountByEmail provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetAccountByEmail(ctx context.Context, email string) (*Account, error) {
+	ret := _mock.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountByEmail")
+	}
+
+	var r0 *Account
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*Account, error)); ok {
+		return returnFunc(ctx, email)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *Account); ok {
+		r0 = returnFunc(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Account)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetAccountByEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountByEmail'
+type MockAccountRepository_GetAccountByEmail_Call struct {
+	*mock.Call
+}
+
+// GetAccountByEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+func (_e *MockAccountRepository_Expecter) GetAccountByEmail(ctx interface{}, email interface{}) *MockAccountRepository_GetAccountByEmail_Call {
+	return &MockAccountRepository_GetAccountByEmail_Call{Call: _e.mock.On("GetAccountByEmail", ctx, email)}
+}
+
+func (_c *MockAccountRepository_GetAccountByEmail_Call) Run(run func(ctx context.Context, email string)) *MockAccountRepository_GetAccountByEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetAccountByEmail_Call) Return(account *Account, err error) *MockAccountRepository_GetAccountByEmail_Call {
+	_c.Call.Return(account, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetAccountByEmail_Call) RunAndReturn(run func(ctx context.Context, email string) (*Account, error)) *MockAccountRepository_GetAccountByEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAccountByID provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetAccountByID(ctx context.Context, id uint) (*Account, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountByID")
+	}
+
+	var r0 *Account
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) (*Account, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) *Account); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Account)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetAccountByID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountByID'
+type MockAccountRepository_GetAccountByID_Call struct {
+	*mock.Call
+}
+
+// GetAccountByID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+func (_e *MockAccountRepository_Expecter) GetAccountByID(ctx interface{}, id interface{}) *MockAccountRepository_GetAccountByID_Call {
+	return &MockAccountRepository_GetAccountByID_Call{Call: _e.mock.On("GetAccountByID", ctx, id)}
+}
+
+func (_c *MockAccountRepository_GetAccountByID_Call) Run(run func(ctx context.Context, id uint)) *MockAccountRepository_GetAccountByID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetAccountByID_Call) Return(account *Account, err error) *MockAccountRepository_GetAccountByID_Call {
+	_c.Call.Return(account, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetAccountByID_Call) RunAndReturn(run func(ctx context.Context, id uint) (*Account, error)) *MockAccountRepository_GetAccountByID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetActivityHistory provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetActivityHistory(ctx context.Context, accountID uint, since time.Time, limit int, offset int) ([]AccountActivity, int64, error) {
+	ret := _mock.Called(ctx, accountID, since, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetActivityHistory")
+	}
+
+	var r0 []AccountActivity
+	var r1 int64
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, time.Time, int, int) ([]AccountActivity, int64, error)); ok {
+		return returnFunc(ctx, accountID, since, limit, offset)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, time.Time, int, int) []AccountActivity); ok {
+		r0 = returnFunc(ctx, accountID, since, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]AccountActivity)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint, time.Time, int, int) int64); ok {
+		r1 = returnFunc(ctx, accountID, since, limit, offset)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, uint, time.Time, int, int) error); ok {
+		r2 = returnFunc(ctx, accountID, since, limit, offset)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockAccountRepository_GetActivityHistory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetActivityHistory'
+type MockAccountRepository_GetActivityHistory_Call struct {
+	*mock.Call
+}
+
+// GetActivityHistory is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - since time.Time
+//   - limit int
+//   - offset int
+func (_e *MockAccountRepository_Expecter) GetActivityHistory(ctx interface{}, accountID interface{}, since interface{}, limit interface{}, offset interface{}) *MockAccountRepository_GetActivityHistory_Call {
+	return &MockAccountRepository_GetActivityHistory_Call{Call: _e.mock.On("GetActivityHistory", ctx, accountID, since, limit, offset)}
+}
+
+func (_c *MockAccountRepository_GetActivityHistory_Call) Run(run func(ctx context.Context, accountID uint, since time.Time, limit int, offset int)) *MockAccountRepository_GetActivityHistory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		var arg3 int
+		if args[3] != nil {
+			arg3 = args[3].(int)
+		}
+		var arg4 int
+		if args[4] != nil {
+			arg4 = args[4].(int)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetActivityHistory_Call) Return(accountActivitys []AccountActivity, n int64, err error) *MockAccountRepository_GetActivityHistory_Call {
+	_c.Call.Return(accountActivitys, n, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetActivityHistory_Call) RunAndReturn(run func(ctx context.Context, accountID uint, since time.Time, limit int, offset int) ([]AccountActivity, int64, error)) *MockAccountRepository_GetActivityHistory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDuePendingActions provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetDuePendingActions(ctx context.Context, now time.Time) ([]PendingAccountAction, error) {
+	ret := _mock.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDuePendingActions")
+	}
+
+	var r0 []PendingAccountAction
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) ([]PendingAccountAction, error)); ok {
+		return returnFunc(ctx, now)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) []PendingAccountAction); ok {
+		r0 = returnFunc(ctx, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]PendingAccountAction)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = returnFunc(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetDuePendingActions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDuePendingActions'
+type MockAccountRepository_GetDuePendingActions_Call struct {
+	*mock.Call
+}
+
+// GetDuePendingActions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - now time.Time
+func (_e *MockAccountRepository_Expecter) GetDuePendingActions(ctx interface{}, now interface{}) *MockAccountRepository_GetDuePendingActions_Call {
+	return &MockAccountRepository_GetDuePendingActions_Call{Call: _e.mock.On("GetDuePendingActions", ctx, now)}
+}
+
+func (_c *MockAccountRepository_GetDuePendingActions_Call) Run(run func(ctx context.Context, now time.Time)) *MockAccountRepository_GetDuePendingActions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 time.Time
+		if args[1] != nil {
+			arg1 = args[1].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetDuePendingActions_Call) Return(pendingAccountActions []PendingAccountAction, err error) *MockAccountRepository_GetDuePendingActions_Call {
+	_c.Call.Return(pendingAccountActions, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetDuePendingActions_Call) RunAndReturn(run func(ctx context.Context, now time.Time) ([]PendingAccountAction, error)) *MockAccountRepository_GetDuePendingActions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetInactiveAccounts provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetInactiveAccounts(ctx context.Context, lastLoginBefore time.Time) ([]Account, error) {
+	ret := _mock.Called(ctx, lastLoginBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInactiveAccounts")
+	}
+
+	var r0 []Account
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) ([]Account, error)); ok {
+		return returnFunc(ctx, lastLoginBefore)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) []Account); ok {
+		r0 = returnFunc(ctx, lastLoginBefore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Account)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = returnFunc(ctx, lastLoginBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetInactiveAccounts_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetInactiveAccounts'
+type MockAccountRepository_GetInactiveAccounts_Call struct {
+	*mock.Call
+}
+
+// GetInactiveAccounts is a helper method to define mock.On call
+//   - ctx context.Context
+//   - lastLoginBefore time.Time
+func (_e *MockAccountRepository_Expecter) GetInactiveAccounts(ctx interface{}, lastLoginBefore interface{}) *MockAccountRepository_GetInactiveAccounts_Call {
+	return &MockAccountRepository_GetInactiveAccounts_Call{Call: _e.mock.On("GetInactiveAccounts", ctx, lastLoginBefore)}
+}
+
+func (_c *MockAccountRepository_GetInactiveAccounts_Call) Run(run func(ctx context.Context, lastLoginBefore time.Time)) *MockAccountRepository_GetInactiveAccounts_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 time.Time
+		if args[1] != nil {
+			arg1 = args[1].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetInactiveAccounts_Call) Return(accounts []Account, err error) *MockAccountRepository_GetInactiveAccounts_Call {
+	_c.Call.Return(accounts, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetInactiveAccounts_Call) RunAndReturn(run func(ctx context.Context, lastLoginBefore time.Time) ([]Account, error)) *MockAccountRepository_GetInactiveAccounts_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetOpenPendingAction provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetOpenPendingAction(ctx context.Context, accountID uint, action string) (*PendingAccountAction, error) {
+	ret := _mock.Called(ctx, accountID, action)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOpenPendingAction")
+	}
+
+	var r0 *PendingAccountAction
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) (*PendingAccountAction, error)); ok {
+		return returnFunc(ctx, accountID, action)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) *PendingAccountAction); ok {
+		r0 = returnFunc(ctx, accountID, action)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PendingAccountAction)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint, string) error); ok {
+		r1 = returnFunc(ctx, accountID, action)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetOpenPendingAction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetOpenPendingAction'
+type MockAccountRepository_GetOpenPendingAction_Call struct {
+	*mock.Call
+}
+
+// GetOpenPendingAction is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - action string
+func (_e *MockAccountRepository_Expecter) GetOpenPendingAction(ctx interface{}, accountID interface{}, action interface{}) *MockAccountRepository_GetOpenPendingAction_Call {
+	return &MockAccountRepository_GetOpenPendingAction_Call{Call: _e.mock.On("GetOpenPendingAction", ctx, accountID, action)}
+}
+
+func (_c *MockAccountRepository_GetOpenPendingAction_Call) Run(run func(ctx context.Context, accountID uint, action string)) *MockAccountRepository_GetOpenPendingAction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetOpenPendingAction_Call) Return(pendingAccountAction *PendingAccountAction, err error) *MockAccountRepository_GetOpenPendingAction_Call {
+	_c.Call.Return(pendingAccountAction, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetOpenPendingAction_Call) RunAndReturn(run func(ctx context.Context, accountID uint, action string) (*PendingAccountAction, error)) *MockAccountRepository_GetOpenPendingAction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPasswordResetTokenByHash provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	ret := _mock.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPasswordResetTokenByHash")
+	}
+
+	var r0 *PasswordResetToken
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*PasswordResetToken, error)); ok {
+		return returnFunc(ctx, tokenHash)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *PasswordResetToken); ok {
+		r0 = returnFunc(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PasswordResetToken)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetPasswordResetTokenByHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPasswordResetTokenByHash'
+type MockAccountRepository_GetPasswordResetTokenByHash_Call struct {
+	*mock.Call
+}
+
+// GetPasswordResetTokenByHash is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tokenHash string
+func (_e *MockAccountRepository_Expecter) GetPasswordResetTokenByHash(ctx interface{}, tokenHash interface{}) *MockAccountRepository_GetPasswordResetTokenByHash_Call {
+	return &MockAccountRepository_GetPasswordResetTokenByHash_Call{Call: _e.mock.On("GetPasswordResetTokenByHash", ctx, tokenHash)}
+}
+
+func (_c *MockAccountRepository_GetPasswordResetTokenByHash_Call) Run(run func(ctx context.Context, tokenHash string)) *MockAccountRepository_GetPasswordResetTokenByHash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetPasswordResetTokenByHash_Call) Return(passwordResetToken *PasswordResetToken, err error) *MockAccountRepository_GetPasswordResetTokenByHash_Call {
+	_c.Call.Return(passwordResetToken, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetPasswordResetTokenByHash_Call) RunAndReturn(run func(ctx context.Context, tokenHash string) (*PasswordResetToken, error)) *MockAccountRepository_GetPasswordResetTokenByHash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPendingActionByCancelToken provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetPendingActionByCancelToken(ctx context.Context, cancelToken string) (*PendingAccountAction, error) {
+	ret := _mock.Called(ctx, cancelToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPendingActionByCancelToken")
+	}
+
+	var r0 *PendingAccountAction
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*PendingAccountAction, error)); ok {
+		return returnFunc(ctx, cancelToken)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *PendingAccountAction); ok {
+		r0 = returnFunc(ctx, cancelToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PendingAccountAction)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, cancelToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetPendingActionByCancelToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPendingActionByCancelToken'
+type MockAccountRepository_GetPendingActionByCancelToken_Call struct {
+	*mock.Call
+}
+
+// GetPendingActionByCancelToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - cancelToken string
+func (_e *MockAccountRepository_Expecter) GetPendingActionByCancelToken(ctx interface{}, cancelToken interface{}) *MockAccountRepository_GetPendingActionByCancelToken_Call {
+	return &MockAccountRepository_GetPendingActionByCancelToken_Call{Call: _e.mock.On("GetPendingActionByCancelToken", ctx, cancelToken)}
+}
+
+func (_c *MockAccountRepository_GetPendingActionByCancelToken_Call) Run(run func(ctx context.Context, cancelToken string)) *MockAccountRepository_GetPendingActionByCancelToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetPendingActionByCancelToken_Call) Return(pendingAccountAction *PendingAccountAction, err error) *MockAccountRepository_GetPendingActionByCancelToken_Call {
+	_c.Call.Return(pendingAccountAction, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetPendingActionByCancelToken_Call) RunAndReturn(run func(ctx context.Context, cancelToken string) (*PendingAccountAction, error)) *MockAccountRepository_GetPendingActionByCancelToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetRefreshTokenByHash provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	ret := _mock.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRefreshTokenByHash")
+	}
+
+	var r0 *RefreshToken
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*RefreshToken, error)); ok {
+		return returnFunc(ctx, tokenHash)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *RefreshToken); ok {
+		r0 = returnFunc(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*RefreshToken)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_GetRefreshTokenByHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRefreshTokenByHash'
+type MockAccountRepository_GetRefreshTokenByHash_Call struct {
+	*mock.Call
+}
+
+// GetRefreshTokenByHash is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tokenHash string
+func (_e *MockAccountRepository_Expecter) GetRefreshTokenByHash(ctx interface{}, tokenHash interface{}) *MockAccountRepository_GetRefreshTokenByHash_Call {
+	return &MockAccountRepository_GetRefreshTokenByHash_Call{Call: _e.mock.On("GetRefreshTokenByHash", ctx, tokenHash)}
+}
+
+func (_c *MockAccountRepository_GetRefreshTokenByHash_Call) Run(run func(ctx context.Context, tokenHash string)) *MockAccountRepository_GetRefreshTokenByHash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_GetRefreshTokenByHash_Call) Return(refreshToken *RefreshToken, err error) *MockAccountRepository_GetRefreshTokenByHash_Call {
+	_c.Call.Return(refreshToken, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_GetRefreshTokenByHash_Call) RunAndReturn(run func(ctx context.Context, tokenHash string) (*RefreshToken, error)) *MockAccountRepository_GetRefreshTokenByHash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// InvalidatePasswordResetTokens provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) InvalidatePasswordResetTokens(ctx context.Context, accountID uint, invalidatedAt time.Time) error {
+	ret := _mock.Called(ctx, accountID, invalidatedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InvalidatePasswordResetTokens")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, time.Time) error); ok {
+		r0 = returnFunc(ctx, accountID, invalidatedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_InvalidatePasswordResetTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InvalidatePasswordResetTokens'
+type MockAccountRepository_InvalidatePasswordResetTokens_Call struct {
+	*mock.Call
+}
+
+// InvalidatePasswordResetTokens is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - invalidatedAt time.Time
+func (_e *MockAccountRepository_Expecter) InvalidatePasswordResetTokens(ctx interface{}, accountID interface{}, invalidatedAt interface{}) *MockAccountRepository_InvalidatePasswordResetTokens_Call {
+	return &MockAccountRepository_InvalidatePasswordResetTokens_Call{Call: _e.mock.On("InvalidatePasswordResetTokens", ctx, accountID, invalidatedAt)}
+}
+
+func (_c *MockAccountRepository_InvalidatePasswordResetTokens_Call) Run(run func(ctx context.Context, accountID uint, invalidatedAt time.Time)) *MockAccountRepository_InvalidatePasswordResetTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_InvalidatePasswordResetTokens_Call) Return(err error) *MockAccountRepository_InvalidatePasswordResetTokens_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_InvalidatePasswordResetTokens_Call) RunAndReturn(run func(ctx context.Context, accountID uint, invalidatedAt time.Time) error) *MockAccountRepository_InvalidatePasswordResetTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IsTokenRevoked provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	ret := _mock.Called(ctx, tokenID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsTokenRevoked")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return returnFunc(ctx, tokenID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = returnFunc(ctx, tokenID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, tokenID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_IsTokenRevoked_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsTokenRevoked'
+type MockAccountRepository_IsTokenRevoked_Call struct {
+	*mock.Call
+}
+
+// IsTokenRevoked is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tokenID string
+func (_e *MockAccountRepository_Expecter) IsTokenRevoked(ctx interface{}, tokenID interface{}) *MockAccountRepository_IsTokenRevoked_Call {
+	return &MockAccountRepository_IsTokenRevoked_Call{Call: _e.mock.On("IsTokenRevoked", ctx, tokenID)}
+}
+
+func (_c *MockAccountRepository_IsTokenRevoked_Call) Run(run func(ctx context.Context, tokenID string)) *MockAccountRepository_IsTokenRevoked_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_IsTokenRevoked_Call) Return(b bool, err error) *MockAccountRepository_IsTokenRevoked_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_IsTokenRevoked_Call) RunAndReturn(run func(ctx context.Context, tokenID string) (bool, error)) *MockAccountRepository_IsTokenRevoked_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListAPIKeys provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) ListAPIKeys(ctx context.Context, accountID uint) ([]APIKey, error) {
+	ret := _mock.Called(ctx, accountID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAPIKeys")
+	}
+
+	var r0 []APIKey
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) ([]APIKey, error)); ok {
+		return returnFunc(ctx, accountID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) []APIKey); ok {
+		r0 = returnFunc(ctx, accountID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]APIKey)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint) error); ok {
+		r1 = returnFunc(ctx, accountID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_ListAPIKeys_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAPIKeys'
+type MockAccountRepository_ListAPIKeys_Call struct {
+	*mock.Call
+}
+
+// ListAPIKeys is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+func (_e *MockAccountRepository_Expecter) ListAPIKeys(ctx interface{}, accountID interface{}) *MockAccountRepository_ListAPIKeys_Call {
+	return &MockAccountRepository_ListAPIKeys_Call{Call: _e.mock.On("ListAPIKeys", ctx, accountID)}
+}
+
+func (_c *MockAccountRepository_ListAPIKeys_Call) Run(run func(ctx context.Context, accountID uint)) *MockAccountRepository_ListAPIKeys_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_ListAPIKeys_Call) Return(apiKeys []APIKey, err error) *MockAccountRepository_ListAPIKeys_Call {
+	_c.Call.Return(apiKeys, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_ListAPIKeys_Call) RunAndReturn(run func(ctx context.Context, accountID uint) ([]APIKey, error)) *MockAccountRepository_ListAPIKeys_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListAccounts provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) ListAccounts(ctx context.Context, sort func(*gorm.DB) *gorm.DB, limit int, offset int) ([]Account, int64, error) {
+	ret := _mock.Called(ctx, sort, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAccounts")
+	}
+
+	var r0 []Account
+	var r1 int64
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, func(*gorm.DB) *gorm.DB, int, int) ([]Account, int64, error)); ok {
+		return returnFunc(ctx, sort, limit, offset)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, func(*gorm.DB) *gorm.DB, int, int) []Account); ok {
+		r0 = returnFunc(ctx, sort, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Account)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, func(*gorm.DB) *gorm.DB, int, int) int64); ok {
+		r1 = returnFunc(ctx, sort, limit, offset)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, func(*gorm.DB) *gorm.DB, int, int) error); ok {
+		r2 = returnFunc(ctx, sort, limit, offset)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockAccountRepository_ListAccounts_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAccounts'
+type MockAccountRepository_ListAccounts_Call struct {
+	*mock.Call
+}
+
+// ListAccounts is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sort func(*gorm.DB) *gorm.DB
+//   - limit int
+//   - offset int
+func (_e *MockAccountRepository_Expecter) ListAccounts(ctx interface{}, sort interface{}, limit interface{}, offset interface{}) *MockAccountRepository_ListAccounts_Call {
+	return &MockAccountRepository_ListAccounts_Call{Call: _e.mock.On("ListAccounts", ctx, sort, limit, offset)}
+}
+
+func (_c *MockAccountRepository_ListAccounts_Call) Run(run func(ctx context.Context, sort func(*gorm.DB) *gorm.DB, limit int, offset int)) *MockAccountRepository_ListAccounts_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 func(*gorm.DB) *gorm.DB
+		if args[1] != nil {
+			arg1 = args[1].(func(*gorm.DB) *gorm.DB)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		var arg3 int
+		if args[3] != nil {
+			arg3 = args[3].(int)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_ListAccounts_Call) Return(accounts []Account, n int64, err error) *MockAccountRepository_ListAccounts_Call {
+	_c.Call.Return(accounts, n, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_ListAccounts_Call) RunAndReturn(run func(ctx context.Context, sort func(*gorm.DB) *gorm.DB, limit int, offset int) ([]Account, int64, error)) *MockAccountRepository_ListAccounts_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LogAccountActivities provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) LogAccountActivities(ctx context.Context, activities []AccountActivity) error {
+	ret := _mock.Called(ctx, activities)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogAccountActivities")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []AccountActivity) error); ok {
+		r0 = returnFunc(ctx, activities)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_LogAccountActivities_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LogAccountActivities'
+type MockAccountRepository_LogAccountActivities_Call struct {
+	*mock.Call
+}
+
+// LogAccountActivities is a helper method to define mock.On call
+//   - ctx context.Context
+//   - activities []AccountActivity
+func (_e *MockAccountRepository_Expecter) LogAccountActivities(ctx interface{}, activities interface{}) *MockAccountRepository_LogAccountActivities_Call {
+	return &MockAccountRepository_LogAccountActivities_Call{Call: _e.mock.On("LogAccountActivities", ctx, activities)}
+}
+
+func (_c *MockAccountRepository_LogAccountActivities_Call) Run(run func(ctx context.Context, activities []AccountActivity)) *MockAccountRepository_LogAccountActivities_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []AccountActivity
+		if args[1] != nil {
+			arg1 = args[1].([]AccountActivity)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_LogAccountActivities_Call) Return(err error) *MockAccountRepository_LogAccountActivities_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_LogAccountActivities_Call) RunAndReturn(run func(ctx context.Context, activities []AccountActivity) error) *MockAccountRepository_LogAccountActivities_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LogAccountActivity provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) LogAccountActivity(ctx context.Context, accountID uint, activity string) error {
+	ret := _mock.Called(ctx, accountID, activity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogAccountActivity")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) error); ok {
+		r0 = returnFunc(ctx, accountID, activity)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_LogAccountActivity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LogAccountActivity'
+type MockAccountRepository_LogAccountActivity_Call struct {
+	*mock.Call
+}
+
+// LogAccountActivity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - activity string
+func (_e *MockAccountRepository_Expecter) LogAccountActivity(ctx interface{}, accountID interface{}, activity interface{}) *MockAccountRepository_LogAccountActivity_Call {
+	return &MockAccountRepository_LogAccountActivity_Call{Call: _e.mock.On("LogAccountActivity", ctx, accountID, activity)}
+}
+
+func (_c *MockAccountRepository_LogAccountActivity_Call) Run(run func(ctx context.Context, accountID uint, activity string)) *MockAccountRepository_LogAccountActivity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_LogAccountActivity_Call) Return(err error) *MockAccountRepository_LogAccountActivity_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_LogAccountActivity_Call) RunAndReturn(run func(ctx context.Context, accountID uint, activity string) error) *MockAccountRepository_LogAccountActivity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LogEmail provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) LogEmail(ctx context.Context, emailLog *EmailLog) error {
+	ret := _mock.Called(ctx, emailLog)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogEmail")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *EmailLog) error); ok {
+		r0 = returnFunc(ctx, emailLog)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_LogEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LogEmail'
+type MockAccountRepository_LogEmail_Call struct {
+	*mock.Call
+}
+
+// LogEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - emailLog *EmailLog
+func (_e *MockAccountRepository_Expecter) LogEmail(ctx interface{}, emailLog interface{}) *MockAccountRepository_LogEmail_Call {
+	return &MockAccountRepository_LogEmail_Call{Call: _e.mock.On("LogEmail", ctx, emailLog)}
+}
+
+func (_c *MockAccountRepository_LogEmail_Call) Run(run func(ctx context.Context, emailLog *EmailLog)) *MockAccountRepository_LogEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *EmailLog
+		if args[1] != nil {
+			arg1 = args[1].(*EmailLog)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_LogEmail_Call) Return(err error) *MockAccountRepository_LogEmail_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_LogEmail_Call) RunAndReturn(run func(ctx context.Context, emailLog *EmailLog) error) *MockAccountRepository_LogEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeExpiredRevokedTokens provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) PurgeExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error) {
+	ret := _mock.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeExpiredRevokedTokens")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return returnFunc(ctx, now)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = returnFunc(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = returnFunc(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_PurgeExpiredRevokedTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeExpiredRevokedTokens'
+type MockAccountRepository_PurgeExpiredRevokedTokens_Call struct {
+	*mock.Call
+}
+
+// PurgeExpiredRevokedTokens is a helper method to define mock.On call
+//   - ctx context.Context
+//   - now time.Time
+func (_e *MockAccountRepository_Expecter) PurgeExpiredRevokedTokens(ctx interface{}, now interface{}) *MockAccountRepository_PurgeExpiredRevokedTokens_Call {
+	return &MockAccountRepository_PurgeExpiredRevokedTokens_Call{Call: _e.mock.On("PurgeExpiredRevokedTokens", ctx, now)}
+}
+
+func (_c *MockAccountRepository_PurgeExpiredRevokedTokens_Call) Run(run func(ctx context.Context, now time.Time)) *MockAccountRepository_PurgeExpiredRevokedTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 time.Time
+		if args[1] != nil {
+			arg1 = args[1].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_PurgeExpiredRevokedTokens_Call) Return(n int64, err error) *MockAccountRepository_PurgeExpiredRevokedTokens_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_PurgeExpiredRevokedTokens_Call) RunAndReturn(run func(ctx context.Context, now time.Time) (int64, error)) *MockAccountRepository_PurgeExpiredRevokedTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetPasswordWithToken provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) ResetPasswordWithToken(ctx context.Context, tokenID uint, accountID uint, hashedPassword string, usedAt time.Time) (bool, error) {
+	ret := _mock.Called(ctx, tokenID, accountID, hashedPassword, usedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPasswordWithToken")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, uint, string, time.Time) (bool, error)); ok {
+		return returnFunc(ctx, tokenID, accountID, hashedPassword, usedAt)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, uint, string, time.Time) bool); ok {
+		r0 = returnFunc(ctx, tokenID, accountID, hashedPassword, usedAt)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint, uint, string, time.Time) error); ok {
+		r1 = returnFunc(ctx, tokenID, accountID, hashedPassword, usedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_ResetPasswordWithToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetPasswordWithToken'
+type MockAccountRepository_ResetPasswordWithToken_Call struct {
+	*mock.Call
+}
+
+// ResetPasswordWithToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tokenID uint
+//   - accountID uint
+//   - hashedPassword string
+//   - usedAt time.Time
+func (_e *MockAccountRepository_Expecter) ResetPasswordWithToken(ctx interface{}, tokenID interface{}, accountID interface{}, hashedPassword interface{}, usedAt interface{}) *MockAccountRepository_ResetPasswordWithToken_Call {
+	return &MockAccountRepository_ResetPasswordWithToken_Call{Call: _e.mock.On("ResetPasswordWithToken", ctx, tokenID, accountID, hashedPassword, usedAt)}
+}
+
+func (_c *MockAccountRepository_ResetPasswordWithToken_Call) Run(run func(ctx context.Context, tokenID uint, accountID uint, hashedPassword string, usedAt time.Time)) *MockAccountRepository_ResetPasswordWithToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 uint
+		if args[2] != nil {
+			arg2 = args[2].(uint)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		var arg4 time.Time
+		if args[4] != nil {
+			arg4 = args[4].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_ResetPasswordWithToken_Call) Return(b bool, err error) *MockAccountRepository_ResetPasswordWithToken_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_ResetPasswordWithToken_Call) RunAndReturn(run func(ctx context.Context, tokenID uint, accountID uint, hashedPassword string, usedAt time.Time) (bool, error)) *MockAccountRepository_ResetPasswordWithToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeAPIKey provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) RevokeAPIKey(ctx context.Context, accountID uint, id uint, revokedAt time.Time) (bool, error) {
+	ret := _mock.Called(ctx, accountID, id, revokedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAPIKey")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, uint, time.Time) (bool, error)); ok {
+		return returnFunc(ctx, accountID, id, revokedAt)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, uint, time.Time) bool); ok {
+		r0 = returnFunc(ctx, accountID, id, revokedAt)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint, uint, time.Time) error); ok {
+		r1 = returnFunc(ctx, accountID, id, revokedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_RevokeAPIKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeAPIKey'
+type MockAccountRepository_RevokeAPIKey_Call struct {
+	*mock.Call
+}
+
+// RevokeAPIKey is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - id uint
+//   - revokedAt time.Time
+func (_e *MockAccountRepository_Expecter) RevokeAPIKey(ctx interface{}, accountID interface{}, id interface{}, revokedAt interface{}) *MockAccountRepository_RevokeAPIKey_Call {
+	return &MockAccountRepository_RevokeAPIKey_Call{Call: _e.mock.On("RevokeAPIKey", ctx, accountID, id, revokedAt)}
+}
+
+func (_c *MockAccountRepository_RevokeAPIKey_Call) Run(run func(ctx context.Context, accountID uint, id uint, revokedAt time.Time)) *MockAccountRepository_RevokeAPIKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 uint
+		if args[2] != nil {
+			arg2 = args[2].(uint)
+		}
+		var arg3 time.Time
+		if args[3] != nil {
+			arg3 = args[3].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeAPIKey_Call) Return(b bool, err error) *MockAccountRepository_RevokeAPIKey_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeAPIKey_Call) RunAndReturn(run func(ctx context.Context, accountID uint, id uint, revokedAt time.Time) (bool, error)) *MockAccountRepository_RevokeAPIKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeAPIKeys provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) RevokeAPIKeys(ctx context.Context, accountID uint, revokedAt time.Time) error {
+	ret := _mock.Called(ctx, accountID, revokedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAPIKeys")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, time.Time) error); ok {
+		r0 = returnFunc(ctx, accountID, revokedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_RevokeAPIKeys_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeAPIKeys'
+type MockAccountRepository_RevokeAPIKeys_Call struct {
+	*mock.Call
+}
+
+// RevokeAPIKeys is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - revokedAt time.Time
+func (_e *MockAccountRepository_Expecter) RevokeAPIKeys(ctx interface{}, accountID interface{}, revokedAt interface{}) *MockAccountRepository_RevokeAPIKeys_Call {
+	return &MockAccountRepository_RevokeAPIKeys_Call{Call: _e.mock.On("RevokeAPIKeys", ctx, accountID, revokedAt)}
+}
+
+func (_c *MockAccountRepository_RevokeAPIKeys_Call) Run(run func(ctx context.Context, accountID uint, revokedAt time.Time)) *MockAccountRepository_RevokeAPIKeys_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeAPIKeys_Call) Return(err error) *MockAccountRepository_RevokeAPIKeys_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeAPIKeys_Call) RunAndReturn(run func(ctx context.Context, accountID uint, revokedAt time.Time) error) *MockAccountRepository_RevokeAPIKeys_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeRefreshToken provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) RevokeRefreshToken(ctx context.Context, accountID uint, tokenHash string, revokedAt time.Time) error {
+	ret := _mock.Called(ctx, accountID, tokenHash, revokedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeRefreshToken")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string, time.Time) error); ok {
+		r0 = returnFunc(ctx, accountID, tokenHash, revokedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_RevokeRefreshToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeRefreshToken'
+type MockAccountRepository_RevokeRefreshToken_Call struct {
+	*mock.Call
+}
+
+// RevokeRefreshToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - tokenHash string
+//   - revokedAt time.Time
+func (_e *MockAccountRepository_Expecter) RevokeRefreshToken(ctx interface{}, accountID interface{}, tokenHash interface{}, revokedAt interface{}) *MockAccountRepository_RevokeRefreshToken_Call {
+	return &MockAccountRepository_RevokeRefreshToken_Call{Call: _e.mock.On("RevokeRefreshToken", ctx, accountID, tokenHash, revokedAt)}
+}
+
+func (_c *MockAccountRepository_RevokeRefreshToken_Call) Run(run func(ctx context.Context, accountID uint, tokenHash string, revokedAt time.Time)) *MockAccountRepository_RevokeRefreshToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 time.Time
+		if args[3] != nil {
+			arg3 = args[3].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeRefreshToken_Call) Return(err error) *MockAccountRepository_RevokeRefreshToken_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeRefreshToken_Call) RunAndReturn(run func(ctx context.Context, accountID uint, tokenHash string, revokedAt time.Time) error) *MockAccountRepository_RevokeRefreshToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeRefreshTokens provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) RevokeRefreshTokens(ctx context.Context, accountID uint, revokedAt time.Time) error {
+	ret := _mock.Called(ctx, accountID, revokedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeRefreshTokens")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, time.Time) error); ok {
+		r0 = returnFunc(ctx, accountID, revokedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_RevokeRefreshTokens_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeRefreshTokens'
+type MockAccountRepository_RevokeRefreshTokens_Call struct {
+	*mock.Call
+}
+
+// RevokeRefreshTokens is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID uint
+//   - revokedAt time.Time
+func (_e *MockAccountRepository_Expecter) RevokeRefreshTokens(ctx interface{}, accountID interface{}, revokedAt interface{}) *MockAccountRepository_RevokeRefreshTokens_Call {
+	return &MockAccountRepository_RevokeRefreshTokens_Call{Call: _e.mock.On("RevokeRefreshTokens", ctx, accountID, revokedAt)}
+}
+
+func (_c *MockAccountRepository_RevokeRefreshTokens_Call) Run(run func(ctx context.Context, accountID uint, revokedAt time.Time)) *MockAccountRepository_RevokeRefreshTokens_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeRefreshTokens_Call) Return(err error) *MockAccountRepository_RevokeRefreshTokens_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeRefreshTokens_Call) RunAndReturn(run func(ctx context.Context, accountID uint, revokedAt time.Time) error) *MockAccountRepository_RevokeRefreshTokens_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeToken provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) RevokeToken(ctx context.Context, token *RevokedToken) error {
+	ret := _mock.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeToken")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *RevokedToken) error); ok {
+		r0 = returnFunc(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_RevokeToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeToken'
+type MockAccountRepository_RevokeToken_Call struct {
+	*mock.Call
+}
+
+// RevokeToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - token *RevokedToken
+func (_e *MockAccountRepository_Expecter) RevokeToken(ctx interface{}, token interface{}) *MockAccountRepository_RevokeToken_Call {
+	return &MockAccountRepository_RevokeToken_Call{Call: _e.mock.On("RevokeToken", ctx, token)}
+}
+
+func (_c *MockAccountRepository_RevokeToken_Call) Run(run func(ctx context.Context, token *RevokedToken)) *MockAccountRepository_RevokeToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *RevokedToken
+		if args[1] != nil {
+			arg1 = args[1].(*RevokedToken)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeToken_Call) Return(err error) *MockAccountRepository_RevokeToken_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_RevokeToken_Call) RunAndReturn(run func(ctx context.Context, token *RevokedToken) error) *MockAccountRepository_RevokeToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TouchAPIKey provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error {
+	ret := _mock.Called(ctx, id, usedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TouchAPIKey")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, time.Time) error); ok {
+		r0 = returnFunc(ctx, id, usedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_TouchAPIKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TouchAPIKey'
+type MockAccountRepository_TouchAPIKey_Call struct {
+	*mock.Call
+}
+
+// TouchAPIKey is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+//   - usedAt time.Time
+func (_e *MockAccountRepository_Expecter) TouchAPIKey(ctx interface{}, id interface{}, usedAt interface{}) *MockAccountRepository_TouchAPIKey_Call {
+	return &MockAccountRepository_TouchAPIKey_Call{Call: _e.mock.On("TouchAPIKey", ctx, id, usedAt)}
+}
+
+func (_c *MockAccountRepository_TouchAPIKey_Call) Run(run func(ctx context.Context, id uint, usedAt time.Time)) *MockAccountRepository_TouchAPIKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_TouchAPIKey_Call) Return(err error) *MockAccountRepository_TouchAPIKey_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_TouchAPIKey_Call) RunAndReturn(run func(ctx context.Context, id uint, usedAt time.Time) error) *MockAccountRepository_TouchAPIKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TouchRefreshToken provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) TouchRefreshToken(ctx context.Context, id uint, usedAt time.Time) error {
+	ret := _mock.Called(ctx, id, usedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TouchRefreshToken")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, time.Time) error); ok {
+		r0 = returnFunc(ctx, id, usedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAccountRepository_TouchRefreshToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TouchRefreshToken'
+type MockAccountRepository_TouchRefreshToken_Call struct {
+	*mock.Call
+}
+
+// TouchRefreshToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+//   - usedAt time.Time
+func (_e *MockAccountRepository_Expecter) TouchRefreshToken(ctx interface{}, id interface{}, usedAt interface{}) *MockAccountRepository_TouchRefreshToken_Call {
+	return &MockAccountRepository_TouchRefreshToken_Call{Call: _e.mock.On("TouchRefreshToken", ctx, id, usedAt)}
+}
+
+func (_c *MockAccountRepository_TouchRefreshToken_Call) Run(run func(ctx context.Context, id uint, usedAt time.Time)) *MockAccountRepository_TouchRefreshToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_TouchRefreshToken_Call) Return(err error) *MockAccountRepository_TouchRefreshToken_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAccountRepository_TouchRefreshToken_Call) RunAndReturn(run func(ctx context.Context, id uint, usedAt time.Time) error) *MockAccountRepository_TouchRefreshToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateAccount provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) UpdateAccount(ctx context.Context, account *Account) (*Account, error) {
+	ret := _mock.Called(ctx, account)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAccount")
+	}
+
+	var r0 *Account
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) (*Account, error)); ok {
+		return returnFunc(ctx, account)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) *Account); ok {
+		r0 = returnFunc(ctx, account)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Account)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *Account) error); ok {
+		r1 = returnFunc(ctx, account)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_UpdateAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateAccount'
+type MockAccountRepository_UpdateAccount_Call struct {
+	*mock.Call
+}
+
+// UpdateAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - account *Account
+func (_e *MockAccountRepository_Expecter) UpdateAccount(ctx interface{}, account interface{}) *MockAccountRepository_UpdateAccount_Call {
+	return &MockAccountRepository_UpdateAccount_Call{Call: _e.mock.On("UpdateAccount", ctx, account)}
+}
+
+func (_c *MockAccountRepository_UpdateAccount_Call) Run(run func(ctx context.Context, account *Account)) *MockAccountRepository_UpdateAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *Account
+		if args[1] != nil {
+			arg1 = args[1].(*Account)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_UpdateAccount_Call) Return(account1 *Account, err error) *MockAccountRepository_UpdateAccount_Call {
+	_c.Call.Return(account1, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_UpdateAccount_Call) RunAndReturn(run func(ctx context.Context, account *Account) (*Account, error)) *MockAccountRepository_UpdateAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdatePendingAction provides a mock function for the type MockAccountRepository
+func (_mock *MockAccountRepository) UpdatePendingAction(ctx context.Context, action *PendingAccountAction) (*PendingAccountAction, error) {
+	ret := _mock.Called(ctx, action)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePendingAction")
+	}
+
+	var r0 *PendingAccountAction
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *PendingAccountAction) (*PendingAccountAction, error)); ok {
+		return returnFunc(ctx, action)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *PendingAccountAction) *PendingAccountAction); ok {
+		r0 = returnFunc(ctx, action)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PendingAccountAction)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *PendingAccountAction) error); ok {
+		r1 = returnFunc(ctx, action)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAccountRepository_UpdatePendingAction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdatePendingAction'
+type MockAccountRepository_UpdatePendingAction_Call struct {
+	*mock.Call
+}
+
+// UpdatePendingAction is a helper method to define mock.On call
+//   - ctx context.Context
+//   - action *PendingAccountAction
+func (_e *MockAccountRepository_Expecter) UpdatePendingAction(ctx interface{}, action interface{}) *MockAccountRepository_UpdatePendingAction_Call {
+	return &MockAccountRepository_UpdatePendingAction_Call{Call: _e.mock.On("UpdatePendingAction", ctx, action)}
+}
+
+func (_c *MockAccountRepository_UpdatePendingAction_Call) Run(run func(ctx context.Context, action *PendingAccountAction)) *MockAccountRepository_UpdatePendingAction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *PendingAccountAction
+		if args[1] != nil {
+			arg1 = args[1].(*PendingAccountAction)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAccountRepository_UpdatePendingAction_Call) Return(pendingAccountAction *PendingAccountAction, err error) *MockAccountRepository_UpdatePendingAction_Call {
+	_c.Call.Return(pendingAccountAction, err)
+	return _c
+}
+
+func (_c *MockAccountRepository_UpdatePendingAction_Call) RunAndReturn(run func(ctx context.Context, action *PendingAccountAction) (*PendingAccountAction, error)) *MockAccountRepository_UpdatePendingAction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockBreachedPasswordChecker creates a new instance of MockBreachedPasswordChecker. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockBreachedPasswordChecker(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockBreachedPasswordChecker {
+	mock := &MockBreachedPasswordChecker{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockBreachedPasswordChecker is an autogenerated mock type for the BreachedPasswordChecker type
+type MockBreachedPasswordChecker struct {
+	mock.Mock
+}
+
+type MockBreachedPasswordChecker_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockBreachedPasswordChecker) EXPECT() *MockBreachedPasswordChecker_Expecter {
+	return &MockBreachedPasswordChecker_Expecter{mock: &_m.Mock}
+}
+
+// IsBreached provides a mock function for the type MockBreachedPasswordChecker
+func (_mock *MockBreachedPasswordChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	ret := _mock.Called(ctx, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsBreached")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return returnFunc(ctx, password)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = returnFunc(ctx, password)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockBreachedPasswordChecker_IsBreached_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsBreached'
+type MockBreachedPasswordChecker_IsBreached_Call struct {
+	*mock.Call
+}
+
+// IsBreached is a helper method to define mock.On call
+//   - ctx context.Context
+//   - password string
+func (_e *MockBreachedPasswordChecker_Expecter) IsBreached(ctx interface{}, password interface{}) *MockBreachedPasswordChecker_IsBreached_Call {
+	return &MockBreachedPasswordChecker_IsBreached_Call{Call: _e.mock.On("IsBreached", ctx, password)}
+}
+
+func (_c *MockBreachedPasswordChecker_IsBreached_Call) Run(run func(ctx context.Context, password string)) *MockBreachedPasswordChecker_IsBreached_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockBreachedPasswordChecker_IsBreached_Call) Return(b bool, err error) *MockBreachedPasswordChecker_IsBreached_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockBreachedPasswordChecker_IsBreached_Call) RunAndReturn(run func(ctx context.Context, password string) (bool, error)) *MockBreachedPasswordChecker_IsBreached_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockFeatureFlagProvider creates a new instance of MockFeatureFlagProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockFeatureFlagProvider(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockFeatureFlagProvider {
+	mock := &MockFeatureFlagProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockFeatureFlagProvider is an autogenerated mock type for the FeatureFlagProvider type
+type MockFeatureFlagProvider struct {
+	mock.Mock
+}
+
+type MockFeatureFlagProvider_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockFeatureFlagProvider) EXPECT() *MockFeatureFlagProvider_Expecter {
+	return &MockFeatureFlagProvider_Expecter{mock: &_m.Mock}
+}
+
+// IsEnabled provides a mock function for the type MockFeatureFlagProvider
+func (_mock *MockFeatureFlagProvider) IsEnabled(ctx context.Context, feature string) bool {
+	ret := _mock.Called(ctx, feature)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsEnabled")
+	}
+
+	var r0 bool
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = returnFunc(ctx, feature)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	return r0
+}
+
+// MockFeatureFlagProvider_IsEnabled_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsEnabled'
+type MockFeatureFlagProvider_IsEnabled_Call struct {
+	*mock.Call
+}
+
+// IsEnabled is a helper method to define mock.On call
+//   - ctx context.Context
+//   - feature string
+func (_e *MockFeatureFlagProvider_Expecter) IsEnabled(ctx interface{}, feature interface{}) *MockFeatureFlagProvider_IsEnabled_Call {
+	return &MockFeatureFlagProvider_IsEnabled_Call{Call: _e.mock.On("IsEnabled", ctx, feature)}
+}
+
+func (_c *MockFeatureFlagProvider_IsEnabled_Call) Run(run func(ctx context.Context, feature string)) *MockFeatureFlagProvider_IsEnabled_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockFeatureFlagProvider_IsEnabled_Call) Return(b bool) *MockFeatureFlagProvider_IsEnabled_Call {
+	_c.Call.Return(b)
+	return _c
+}
+
+func (_c *MockFeatureFlagProvider_IsEnabled_Call) RunAndReturn(run func(ctx context.Context, feature string) bool) *MockFeatureFlagProvider_IsEnabled_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockLoginAttemptStore creates a new instance of MockLoginAttemptStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockLoginAttemptStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockLoginAttemptStore {
+	mock := &MockLoginAttemptStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockLoginAttemptStore is an autogenerated mock type for the LoginAttemptStore type
+type MockLoginAttemptStore struct {
+	mock.Mock
+}
+
+type MockLoginAttemptStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockLoginAttemptStore) EXPECT() *MockLoginAttemptStore_Expecter {
+	return &MockLoginAttemptStore_Expecter{mock: &_m.Mock}
+}
+
+// Failures provides a mock function for the type MockLoginAttemptStore
+func (_mock *MockLoginAttemptStore) Failures(ctx context.Context, key string) (int, time.Time, error) {
+	ret := _mock.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Failures")
+	}
+
+	var r0 int
+	var r1 time.Time
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (int, time.Time, error)); ok {
+		return returnFunc(ctx, key)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = returnFunc(ctx, key)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) time.Time); ok {
+		r1 = returnFunc(ctx, key)
+	} else {
+		r1 = ret.Get(1).(time.Time)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = returnFunc(ctx, key)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockLoginAttemptStore_Failures_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Failures'
+type MockLoginAttemptStore_Failures_Call struct {
+	*mock.Call
+}
+
+// Failures is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *MockLoginAttemptStore_Expecter) Failures(ctx interface{}, key interface{}) *MockLoginAttemptStore_Failures_Call {
+	return &MockLoginAttemptStore_Failures_Call{Call: _e.mock.On("Failures", ctx, key)}
+}
+
+func (_c *MockLoginAttemptStore_Failures_Call) Run(run func(ctx context.Context, key string)) *MockLoginAttemptStore_Failures_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptStore_Failures_Call) Return(n int, time1 time.Time, err error) *MockLoginAttemptStore_Failures_Call {
+	_c.Call.Return(n, time1, err)
+	return _c
+}
+
+func (_c *MockLoginAttemptStore_Failures_Call) RunAndReturn(run func(ctx context.Context, key string) (int, time.Time, error)) *MockLoginAttemptStore_Failures_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecordFailure provides a mock function for the type MockLoginAttemptStore
+func (_mock *MockLoginAttemptStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	ret := _mock.Called(ctx, key, window)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordFailure")
+	}
+
+	var r0 int
+	var r1 time.Time
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Duration) (int, time.Time, error)); ok {
+		return returnFunc(ctx, key, window)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Duration) int); ok {
+		r0 = returnFunc(ctx, key, window)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, time.Duration) time.Time); ok {
+		r1 = returnFunc(ctx, key, window)
+	} else {
+		r1 = ret.Get(1).(time.Time)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, string, time.Duration) error); ok {
+		r2 = returnFunc(ctx, key, window)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockLoginAttemptStore_RecordFailure_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordFailure'
+type MockLoginAttemptStore_RecordFailure_Call struct {
+	*mock.Call
+}
+
+// RecordFailure is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - window time.Duration
+func (_e *MockLoginAttemptStore_Expecter) RecordFailure(ctx interface{}, key interface{}, window interface{}) *MockLoginAttemptStore_RecordFailure_Call {
+	return &MockLoginAttemptStore_RecordFailure_Call{Call: _e.mock.On("RecordFailure", ctx, key, window)}
+}
+
+func (_c *MockLoginAttemptStore_RecordFailure_Call) Run(run func(ctx context.Context, key string, window time.Duration)) *MockLoginAttemptStore_RecordFailure_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 time.Duration
+		if args[2] != nil {
+			arg2 = args[2].(time.Duration)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptStore_RecordFailure_Call) Return(n int, time1 time.Time, err error) *MockLoginAttemptStore_RecordFailure_Call {
+	_c.Call.Return(n, time1, err)
+	return _c
+}
+
+func (_c *MockLoginAttemptStore_RecordFailure_Call) RunAndReturn(run func(ctx context.Context, key string, window time.Duration) (int, time.Time, error)) *MockLoginAttemptStore_RecordFailure_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Reset provides a mock function for the type MockLoginAttemptStore
+func (_mock *MockLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	ret := _mock.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reset")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockLoginAttemptStore_Reset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Reset'
+type MockLoginAttemptStore_Reset_Call struct {
+	*mock.Call
+}
+
+// Reset is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *MockLoginAttemptStore_Expecter) Reset(ctx interface{}, key interface{}) *MockLoginAttemptStore_Reset_Call {
+	return &MockLoginAttemptStore_Reset_Call{Call: _e.mock.On("Reset", ctx, key)}
+}
+
+func (_c *MockLoginAttemptStore_Reset_Call) Run(run func(ctx context.Context, key string)) *MockLoginAttemptStore_Reset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptStore_Reset_Call) Return(err error) *MockLoginAttemptStore_Reset_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockLoginAttemptStore_Reset_Call) RunAndReturn(run func(ctx context.Context, key string) error) *MockLoginAttemptStore_Reset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockOrganizationRepository creates a new instance of MockOrganizationRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockOrganizationRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockOrganizationRepository {
+	mock := &MockOrganizationRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockOrganizationRepository is an autogenerated mock type for the OrganizationRepository type
+type MockOrganizationRepository struct {
+	mock.Mock
+}
+
+type MockOrganizationRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockOrganizationRepository) EXPECT() *MockOrganizationRepository_Expecter {
+	return &MockOrganizationRepository_Expecter{mock: &_m.Mock}
+}
+
+// DeleteOrganizationByOwnerID provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) DeleteOrganizationByOwnerID(ctx context.Context, ownerID uint) error {
+	ret := _mock.Called(ctx, ownerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteOrganizationByOwnerID")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) error); ok {
+		r0 = returnFunc(ctx, ownerID)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockOrganizationRepository_DeleteOrganizationByOwnerID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteOrganizationByOwnerID'
+type MockOrganizationRepository_DeleteOrganizationByOwnerID_Call struct {
+	*mock.Call
+}
+
+// DeleteOrganizationByOwnerID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ownerID uint
+func (_e *MockOrganizationRepository_Expecter) DeleteOrganizationByOwnerID(ctx interface{}, ownerID interface{}) *MockOrganizationRepository_DeleteOrganizationByOwnerID_Call {
+	return &MockOrganizationRepository_DeleteOrganizationByOwnerID_Call{Call: _e.mock.On("DeleteOrganizationByOwnerID", ctx, ownerID)}
+}
+
+func (_c *MockOrganizationRepository_DeleteOrganizationByOwnerID_Call) Run(run func(ctx context.Context, ownerID uint)) *MockOrganizationRepository_DeleteOrganizationByOwnerID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_DeleteOrganizationByOwnerID_Call) Return(err error) *MockOrganizationRepository_DeleteOrganizationByOwnerID_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_DeleteOrganizationByOwnerID_Call) RunAndReturn(run func(ctx context.Context, ownerID uint) error) *MockOrganizationRepository_DeleteOrganizationByOwnerID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteOrphanedOrganizations provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) DeleteOrphanedOrganizations(ctx context.Context) (int64, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteOrphanedOrganizations")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationRepository_DeleteOrphanedOrganizations_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteOrphanedOrganizations'
+type MockOrganizationRepository_DeleteOrphanedOrganizations_Call struct {
+	*mock.Call
+}
+
+// DeleteOrphanedOrganizations is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockOrganizationRepository_Expecter) DeleteOrphanedOrganizations(ctx interface{}) *MockOrganizationRepository_DeleteOrphanedOrganizations_Call {
+	return &MockOrganizationRepository_DeleteOrphanedOrganizations_Call{Call: _e.mock.On("DeleteOrphanedOrganizations", ctx)}
+}
+
+func (_c *MockOrganizationRepository_DeleteOrphanedOrganizations_Call) Run(run func(ctx context.Context)) *MockOrganizationRepository_DeleteOrphanedOrganizations_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_DeleteOrphanedOrganizations_Call) Return(n int64, err error) *MockOrganizationRepository_DeleteOrphanedOrganizations_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_DeleteOrphanedOrganizations_Call) RunAndReturn(run func(ctx context.Context) (int64, error)) *MockOrganizationRepository_DeleteOrphanedOrganizations_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetOrganizationByID provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) GetOrganizationByID(ctx context.Context, id uint) (*Organization, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrganizationByID")
+	}
+
+	var r0 *Organization
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) (*Organization, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) *Organization); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Organization)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationRepository_GetOrganizationByID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetOrganizationByID'
+type MockOrganizationRepository_GetOrganizationByID_Call struct {
+	*mock.Call
+}
+
+// GetOrganizationByID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+func (_e *MockOrganizationRepository_Expecter) GetOrganizationByID(ctx interface{}, id interface{}) *MockOrganizationRepository_GetOrganizationByID_Call {
+	return &MockOrganizationRepository_GetOrganizationByID_Call{Call: _e.mock.On("GetOrganizationByID", ctx, id)}
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationByID_Call) Run(run func(ctx context.Context, id uint)) *MockOrganizationRepository_GetOrganizationByID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationByID_Call) Return(organization *Organization, err error) *MockOrganizationRepository_GetOrganizationByID_Call {
+	_c.Call.Return(organization, err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationByID_Call) RunAndReturn(run func(ctx context.Context, id uint) (*Organization, error)) *MockOrganizationRepository_GetOrganizationByID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetOrganizationByOwnerID provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) GetOrganizationByOwnerID(ctx context.Context, ownerID uint) (*Organization, error) {
+	ret := _mock.Called(ctx, ownerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrganizationByOwnerID")
+	}
+
+	var r0 *Organization
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) (*Organization, error)); ok {
+		return returnFunc(ctx, ownerID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) *Organization); ok {
+		r0 = returnFunc(ctx, ownerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Organization)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint) error); ok {
+		r1 = returnFunc(ctx, ownerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationRepository_GetOrganizationByOwnerID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetOrganizationByOwnerID'
+type MockOrganizationRepository_GetOrganizationByOwnerID_Call struct {
+	*mock.Call
+}
+
+// GetOrganizationByOwnerID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ownerID uint
+func (_e *MockOrganizationRepository_Expecter) GetOrganizationByOwnerID(ctx interface{}, ownerID interface{}) *MockOrganizationRepository_GetOrganizationByOwnerID_Call {
+	return &MockOrganizationRepository_GetOrganizationByOwnerID_Call{Call: _e.mock.On("GetOrganizationByOwnerID", ctx, ownerID)}
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationByOwnerID_Call) Run(run func(ctx context.Context, ownerID uint)) *MockOrganizationRepository_GetOrganizationByOwnerID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationByOwnerID_Call) Return(organization *Organization, err error) *MockOrganizationRepository_GetOrganizationByOwnerID_Call {
+	_c.Call.Return(organization, err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationByOwnerID_Call) RunAndReturn(run func(ctx context.Context, ownerID uint) (*Organization, error)) *MockOrganizationRepository_GetOrganizationByOwnerID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetOrganizationHealth provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) GetOrganizationHealth(ctx context.Context, expiringBefore time.Time) (*OrganizationHealth, error) {
+	ret := _mock.Called(ctx, expiringBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrganizationHealth")
+	}
+
+	var r0 *OrganizationHealth
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) (*OrganizationHealth, error)); ok {
+		return returnFunc(ctx, expiringBefore)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) *OrganizationHealth); ok {
+		r0 = returnFunc(ctx, expiringBefore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*OrganizationHealth)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = returnFunc(ctx, expiringBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationRepository_GetOrganizationHealth_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetOrganizationHealth'
+type MockOrganizationRepository_GetOrganizationHealth_Call struct {
+	*mock.Call
+}
+
+// GetOrganizationHealth is a helper method to define mock.On call
+//   - ctx context.Context
+//   - expiringBefore time.Time
+func (_e *MockOrganizationRepository_Expecter) GetOrganizationHealth(ctx interface{}, expiringBefore interface{}) *MockOrganizationRepository_GetOrganizationHealth_Call {
+	return &MockOrganizationRepository_GetOrganizationHealth_Call{Call: _e.mock.On("GetOrganizationHealth", ctx, expiringBefore)}
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationHealth_Call) Run(run func(ctx context.Context, expiringBefore time.Time)) *MockOrganizationRepository_GetOrganizationHealth_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 time.Time
+		if args[1] != nil {
+			arg1 = args[1].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationHealth_Call) Return(organizationHealth *OrganizationHealth, err error) *MockOrganizationRepository_GetOrganizationHealth_Call {
+	_c.Call.Return(organizationHealth, err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_GetOrganizationHealth_Call) RunAndReturn(run func(ctx context.Context, expiringBefore time.Time) (*OrganizationHealth, error)) *MockOrganizationRepository_GetOrganizationHealth_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListOrganizations provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOrganizations")
+	}
+
+	var r0 []Organization
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]Organization, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []Organization); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Organization)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationRepository_ListOrganizations_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListOrganizations'
+type MockOrganizationRepository_ListOrganizations_Call struct {
+	*mock.Call
+}
+
+// ListOrganizations is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockOrganizationRepository_Expecter) ListOrganizations(ctx interface{}) *MockOrganizationRepository_ListOrganizations_Call {
+	return &MockOrganizationRepository_ListOrganizations_Call{Call: _e.mock.On("ListOrganizations", ctx)}
+}
+
+func (_c *MockOrganizationRepository_ListOrganizations_Call) Run(run func(ctx context.Context)) *MockOrganizationRepository_ListOrganizations_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_ListOrganizations_Call) Return(organizations []Organization, err error) *MockOrganizationRepository_ListOrganizations_Call {
+	_c.Call.Return(organizations, err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_ListOrganizations_Call) RunAndReturn(run func(ctx context.Context) ([]Organization, error)) *MockOrganizationRepository_ListOrganizations_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateAuthorizationError provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) UpdateAuthorizationError(ctx context.Context, id uint, checkErr string) error {
+	ret := _mock.Called(ctx, id, checkErr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAuthorizationError")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) error); ok {
+		r0 = returnFunc(ctx, id, checkErr)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockOrganizationRepository_UpdateAuthorizationError_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateAuthorizationError'
+type MockOrganizationRepository_UpdateAuthorizationError_Call struct {
+	*mock.Call
+}
+
+// UpdateAuthorizationError is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+//   - checkErr string
+func (_e *MockOrganizationRepository_Expecter) UpdateAuthorizationError(ctx interface{}, id interface{}, checkErr interface{}) *MockOrganizationRepository_UpdateAuthorizationError_Call {
+	return &MockOrganizationRepository_UpdateAuthorizationError_Call{Call: _e.mock.On("UpdateAuthorizationError", ctx, id, checkErr)}
+}
+
+func (_c *MockOrganizationRepository_UpdateAuthorizationError_Call) Run(run func(ctx context.Context, id uint, checkErr string)) *MockOrganizationRepository_UpdateAuthorizationError_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_UpdateAuthorizationError_Call) Return(err error) *MockOrganizationRepository_UpdateAuthorizationError_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_UpdateAuthorizationError_Call) RunAndReturn(run func(ctx context.Context, id uint, checkErr string) error) *MockOrganizationRepository_UpdateAuthorizationError_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateAuthorizationStatus provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) UpdateAuthorizationStatus(ctx context.Context, id uint, authorized bool) error {
+	ret := _mock.Called(ctx, id, authorized)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAuthorizationStatus")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, bool) error); ok {
+		r0 = returnFunc(ctx, id, authorized)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockOrganizationRepository_UpdateAuthorizationStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateAuthorizationStatus'
+type MockOrganizationRepository_UpdateAuthorizationStatus_Call struct {
+	*mock.Call
+}
+
+// UpdateAuthorizationStatus is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+//   - authorized bool
+func (_e *MockOrganizationRepository_Expecter) UpdateAuthorizationStatus(ctx interface{}, id interface{}, authorized interface{}) *MockOrganizationRepository_UpdateAuthorizationStatus_Call {
+	return &MockOrganizationRepository_UpdateAuthorizationStatus_Call{Call: _e.mock.On("UpdateAuthorizationStatus", ctx, id, authorized)}
+}
+
+func (_c *MockOrganizationRepository_UpdateAuthorizationStatus_Call) Run(run func(ctx context.Context, id uint, authorized bool)) *MockOrganizationRepository_UpdateAuthorizationStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 bool
+		if args[2] != nil {
+			arg2 = args[2].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_UpdateAuthorizationStatus_Call) Return(err error) *MockOrganizationRepository_UpdateAuthorizationStatus_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_UpdateAuthorizationStatus_Call) RunAndReturn(run func(ctx context.Context, id uint, authorized bool) error) *MockOrganizationRepository_UpdateAuthorizationStatus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateClientSecret provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) UpdateClientSecret(ctx context.Context, id uint, clientSecret string) error {
+	ret := _mock.Called(ctx, id, clientSecret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateClientSecret")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) error); ok {
+		r0 = returnFunc(ctx, id, clientSecret)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockOrganizationRepository_UpdateClientSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateClientSecret'
+type MockOrganizationRepository_UpdateClientSecret_Call struct {
+	*mock.Call
+}
+
+// UpdateClientSecret is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+//   - clientSecret string
+func (_e *MockOrganizationRepository_Expecter) UpdateClientSecret(ctx interface{}, id interface{}, clientSecret interface{}) *MockOrganizationRepository_UpdateClientSecret_Call {
+	return &MockOrganizationRepository_UpdateClientSecret_Call{Call: _e.mock.On("UpdateClientSecret", ctx, id, clientSecret)}
+}
+
+func (_c *MockOrganizationRepository_UpdateClientSecret_Call) Run(run func(ctx context.Context, id uint, clientSecret string)) *MockOrganizationRepository_UpdateClientSecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_UpdateClientSecret_Call) Return(err error) *MockOrganizationRepository_UpdateClientSecret_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_UpdateClientSecret_Call) RunAndReturn(run func(ctx context.Context, id uint, clientSecret string) error) *MockOrganizationRepository_UpdateClientSecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpsertOrganization provides a mock function for the type MockOrganizationRepository
+func (_mock *MockOrganizationRepository) UpsertOrganization(ctx context.Context, organization *Organization) (*Organization, error) {
+	ret := _mock.Called(ctx, organization)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertOrganization")
+	}
+
+	var r0 *Organization
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Organization) (*Organization, error)); ok {
+		return returnFunc(ctx, organization)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *Organization) *Organization); ok {
+		r0 = returnFunc(ctx, organization)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Organization)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *Organization) error); ok {
+		r1 = returnFunc(ctx, organization)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationRepository_UpsertOrganization_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpsertOrganization'
+type MockOrganizationRepository_UpsertOrganization_Call struct {
+	*mock.Call
+}
+
+// UpsertOrganization is a helper method to define mock.On call
+//   - ctx context.Context
+//   - organization *Organization
+func (_e *MockOrganizationRepository_Expecter) UpsertOrganization(ctx interface{}, organization interface{}) *MockOrganizationRepository_UpsertOrganization_Call {
+	return &MockOrganizationRepository_UpsertOrganization_Call{Call: _e.mock.On("UpsertOrganization", ctx, organization)}
+}
+
+func (_c *MockOrganizationRepository_UpsertOrganization_Call) Run(run func(ctx context.Context, organization *Organization)) *MockOrganizationRepository_UpsertOrganization_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *Organization
+		if args[1] != nil {
+			arg1 = args[1].(*Organization)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationRepository_UpsertOrganization_Call) Return(organization1 *Organization, err error) *MockOrganizationRepository_UpsertOrganization_Call {
+	_c.Call.Return(organization1, err)
+	return _c
+}
+
+func (_c *MockOrganizationRepository_UpsertOrganization_Call) RunAndReturn(run func(ctx context.Context, organization *Organization) (*Organization, error)) *MockOrganizationRepository_UpsertOrganization_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockOrganizationService creates a new instance of MockOrganizationService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockOrganizationService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockOrganizationService {
+	mock := &MockOrganizationService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockOrganizationService is an autogenerated mock type for the OrganizationService type
+type MockOrganizationService struct {
+	mock.Mock
+}
+
+type MockOrganizationService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockOrganizationService) EXPECT() *MockOrganizationService_Expecter {
+	return &MockOrganizationService_Expecter{mock: &_m.Mock}
+}
+
+// DecryptClientSecret provides a mock function for the type MockOrganizationService
+func (_mock *MockOrganizationService) DecryptClientSecret(ctx context.Context, clientSecret string) (string, error) {
+	ret := _mock.Called(ctx, clientSecret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DecryptClientSecret")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return returnFunc(ctx, clientSecret)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = returnFunc(ctx, clientSecret)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, clientSecret)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationService_DecryptClientSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DecryptClientSecret'
+type MockOrganizationService_DecryptClientSecret_Call struct {
+	*mock.Call
+}
+
+// DecryptClientSecret is a helper method to define mock.On call
+//   - ctx context.Context
+//   - clientSecret string
+func (_e *MockOrganizationService_Expecter) DecryptClientSecret(ctx interface{}, clientSecret interface{}) *MockOrganizationService_DecryptClientSecret_Call {
+	return &MockOrganizationService_DecryptClientSecret_Call{Call: _e.mock.On("DecryptClientSecret", ctx, clientSecret)}
+}
+
+func (_c *MockOrganizationService_DecryptClientSecret_Call) Run(run func(ctx context.Context, clientSecret string)) *MockOrganizationService_DecryptClientSecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationService_DecryptClientSecret_Call) Return(s string, err error) *MockOrganizationService_DecryptClientSecret_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MockOrganizationService_DecryptClientSecret_Call) RunAndReturn(run func(ctx context.Context, clientSecret string) (string, error)) *MockOrganizationService_DecryptClientSecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// EncryptClientSecret provides a mock function for the type MockOrganizationService
+func (_mock *MockOrganizationService) EncryptClientSecret(ctx context.Context, clientSecret string) (string, error) {
+	ret := _mock.Called(ctx, clientSecret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EncryptClientSecret")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return returnFunc(ctx, clientSecret)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = returnFunc(ctx, clientSecret)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, clientSecret)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationService_EncryptClientSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EncryptClientSecret'
+type MockOrganizationService_EncryptClientSecret_Call struct {
+	*mock.Call
+}
+
+// EncryptClientSecret is a helper method to define mock.On call
+//   - ctx context.Context
+//   - clientSecret string
+func (_e *MockOrganizationService_Expecter) EncryptClientSecret(ctx interface{}, clientSecret interface{}) *MockOrganizationService_EncryptClientSecret_Call {
+	return &MockOrganizationService_EncryptClientSecret_Call{Call: _e.mock.On("EncryptClientSecret", ctx, clientSecret)}
+}
+
+func (_c *MockOrganizationService_EncryptClientSecret_Call) Run(run func(ctx context.Context, clientSecret string)) *MockOrganizationService_EncryptClientSecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationService_EncryptClientSecret_Call) Return(s string, err error) *MockOrganizationService_EncryptClientSecret_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MockOrganizationService_EncryptClientSecret_Call) RunAndReturn(run func(ctx context.Context, clientSecret string) (string, error)) *MockOrganizationService_EncryptClientSecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IsEncryptedClientSecret provides a mock function for the type MockOrganizationService
+func (_mock *MockOrganizationService) IsEncryptedClientSecret(ctx context.Context, clientSecret string) bool {
+	ret := _mock.Called(ctx, clientSecret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsEncryptedClientSecret")
+	}
+
+	var r0 bool
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = returnFunc(ctx, clientSecret)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	return r0
+}
+
+// MockOrganizationService_IsEncryptedClientSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsEncryptedClientSecret'
+type MockOrganizationService_IsEncryptedClientSecret_Call struct {
+	*mock.Call
+}
+
+// IsEncryptedClientSecret is a helper method to define mock.On call
+//   - ctx context.Context
+//   - clientSecret string
+func (_e *MockOrganizationService_Expecter) IsEncryptedClientSecret(ctx interface{}, clientSecret interface{}) *MockOrganizationService_IsEncryptedClientSecret_Call {
+	return &MockOrganizationService_IsEncryptedClientSecret_Call{Call: _e.mock.On("IsEncryptedClientSecret", ctx, clientSecret)}
+}
+
+func (_c *MockOrganizationService_IsEncryptedClientSecret_Call) Run(run func(ctx context.Context, clientSecret string)) *MockOrganizationService_IsEncryptedClientSecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationService_IsEncryptedClientSecret_Call) Return(b bool) *MockOrganizationService_IsEncryptedClientSecret_Call {
+	_c.Call.Return(b)
+	return _c
+}
+
+func (_c *MockOrganizationService_IsEncryptedClientSecret_Call) RunAndReturn(run func(ctx context.Context, clientSecret string) bool) *MockOrganizationService_IsEncryptedClientSecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RotateClientSecret provides a mock function for the type MockOrganizationService
+func (_mock *MockOrganizationService) RotateClientSecret(ctx context.Context, ownerID uint, newSecret string) (*Organization, error) {
+	ret := _mock.Called(ctx, ownerID, newSecret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateClientSecret")
+	}
+
+	var r0 *Organization
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) (*Organization, error)); ok {
+		return returnFunc(ctx, ownerID, newSecret)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) *Organization); ok {
+		r0 = returnFunc(ctx, ownerID, newSecret)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Organization)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint, string) error); ok {
+		r1 = returnFunc(ctx, ownerID, newSecret)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockOrganizationService_RotateClientSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RotateClientSecret'
+type MockOrganizationService_RotateClientSecret_Call struct {
+	*mock.Call
+}
+
+// RotateClientSecret is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ownerID uint
+//   - newSecret string
+func (_e *MockOrganizationService_Expecter) RotateClientSecret(ctx interface{}, ownerID interface{}, newSecret interface{}) *MockOrganizationService_RotateClientSecret_Call {
+	return &MockOrganizationService_RotateClientSecret_Call{Call: _e.mock.On("RotateClientSecret", ctx, ownerID, newSecret)}
+}
+
+func (_c *MockOrganizationService_RotateClientSecret_Call) Run(run func(ctx context.Context, ownerID uint, newSecret string)) *MockOrganizationService_RotateClientSecret_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint
+		if args[1] != nil {
+			arg1 = args[1].(uint)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockOrganizationService_RotateClientSecret_Call) Return(organization *Organization, err error) *MockOrganizationService_RotateClientSecret_Call {
+	_c.Call.Return(organization, err)
+	return _c
+}
+
+func (_c *MockOrganizationService_RotateClientSecret_Call) RunAndReturn(run func(ctx context.Context, ownerID uint, newSecret string) (*Organization, error)) *MockOrganizationService_RotateClientSecret_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockSigningKeyRepository creates a new instance of MockSigningKeyRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSigningKeyRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSigningKeyRepository {
+	mock := &MockSigningKeyRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockSigningKeyRepository is an autogenerated mock type for the SigningKeyRepository type
+type MockSigningKeyRepository struct {
+	mock.Mock
+}
+
+type MockSigningKeyRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockSigningKeyRepository) EXPECT() *MockSigningKeyRepository_Expecter {
+	return &MockSigningKeyRepository_Expecter{mock: &_m.Mock}
+}
+
+// CreateSigningKey provides a mock function for the type MockSigningKeyRepository
+func (_mock *MockSigningKeyRepository) CreateSigningKey(ctx context.Context, key *SigningKey) (*SigningKey, error) {
+	ret := _mock.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSigningKey")
+	}
+
+	var r0 *SigningKey
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *SigningKey) (*SigningKey, error)); ok {
+		return returnFunc(ctx, key)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *SigningKey) *SigningKey); ok {
+		r0 = returnFunc(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*SigningKey)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *SigningKey) error); ok {
+		r1 = returnFunc(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockSigningKeyRepository_CreateSigningKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateSigningKey'
+type MockSigningKeyRepository_CreateSigningKey_Call struct {
+	*mock.Call
+}
+
+// CreateSigningKey is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key *SigningKey
+func (_e *MockSigningKeyRepository_Expecter) CreateSigningKey(ctx interface{}, key interface{}) *MockSigningKeyRepository_CreateSigningKey_Call {
+	return &MockSigningKeyRepository_CreateSigningKey_Call{Call: _e.mock.On("CreateSigningKey", ctx, key)}
+}
+
+func (_c *MockSigningKeyRepository_CreateSigningKey_Call) Run(run func(ctx context.Context, key *SigningKey)) *MockSigningKeyRepository_CreateSigningKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *SigningKey
+		if args[1] != nil {
+			arg1 = args[1].(*SigningKey)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockSigningKeyRepository_CreateSigningKey_Call) Return(signingKey *SigningKey, err error) *MockSigningKeyRepository_CreateSigningKey_Call {
+	_c.Call.Return(signingKey, err)
+	return _c
+}
+
+func (_c *MockSigningKeyRepository_CreateSigningKey_Call) RunAndReturn(run func(ctx context.Context, key *SigningKey) (*SigningKey, error)) *MockSigningKeyRepository_CreateSigningKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetCurrentSigningKey provides a mock function for the type MockSigningKeyRepository
+func (_mock *MockSigningKeyRepository) GetCurrentSigningKey(ctx context.Context) (*SigningKey, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCurrentSigningKey")
+	}
+
+	var r0 *SigningKey
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) (*SigningKey, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) *SigningKey); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*SigningKey)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockSigningKeyRepository_GetCurrentSigningKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCurrentSigningKey'
+type MockSigningKeyRepository_GetCurrentSigningKey_Call struct {
+	*mock.Call
+}
+
+// GetCurrentSigningKey is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockSigningKeyRepository_Expecter) GetCurrentSigningKey(ctx interface{}) *MockSigningKeyRepository_GetCurrentSigningKey_Call {
+	return &MockSigningKeyRepository_GetCurrentSigningKey_Call{Call: _e.mock.On("GetCurrentSigningKey", ctx)}
+}
+
+func (_c *MockSigningKeyRepository_GetCurrentSigningKey_Call) Run(run func(ctx context.Context)) *MockSigningKeyRepository_GetCurrentSigningKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockSigningKeyRepository_GetCurrentSigningKey_Call) Return(signingKey *SigningKey, err error) *MockSigningKeyRepository_GetCurrentSigningKey_Call {
+	_c.Call.Return(signingKey, err)
+	return _c
+}
+
+func (_c *MockSigningKeyRepository_GetCurrentSigningKey_Call) RunAndReturn(run func(ctx context.Context) (*SigningKey, error)) *MockSigningKeyRepository_GetCurrentSigningKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetSigningKeyByKid provides a mock function for the type MockSigningKeyRepository
+func (_mock *MockSigningKeyRepository) GetSigningKeyByKid(ctx context.Context, kid string) (*SigningKey, error) {
+	ret := _mock.Called(ctx, kid)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSigningKeyByKid")
+	}
+
+	var r0 *SigningKey
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*SigningKey, error)); ok {
+		return returnFunc(ctx, kid)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *SigningKey); ok {
+		r0 = returnFunc(ctx, kid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*SigningKey)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, kid)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockSigningKeyRepository_GetSigningKeyByKid_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSigningKeyByKid'
+type MockSigningKeyRepository_GetSigningKeyByKid_Call struct {
+	*mock.Call
+}
+
+// GetSigningKeyByKid is a helper method to define mock.On call
+//   - ctx context.Context
+//   - kid string
+func (_e *MockSigningKeyRepository_Expecter) GetSigningKeyByKid(ctx interface{}, kid interface{}) *MockSigningKeyRepository_GetSigningKeyByKid_Call {
+	return &MockSigningKeyRepository_GetSigningKeyByKid_Call{Call: _e.mock.On("GetSigningKeyByKid", ctx, kid)}
+}
+
+func (_c *MockSigningKeyRepository_GetSigningKeyByKid_Call) Run(run func(ctx context.Context, kid string)) *MockSigningKeyRepository_GetSigningKeyByKid_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockSigningKeyRepository_GetSigningKeyByKid_Call) Return(signingKey *SigningKey, err error) *MockSigningKeyRepository_GetSigningKeyByKid_Call {
+	_c.Call.Return(signingKey, err)
+	return _c
+}
+
+func (_c *MockSigningKeyRepository_GetSigningKeyByKid_Call) RunAndReturn(run func(ctx context.Context, kid string) (*SigningKey, error)) *MockSigningKeyRepository_GetSigningKeyByKid_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RetireSigningKeys provides a mock function for the type MockSigningKeyRepository
+func (_mock *MockSigningKeyRepository) RetireSigningKeys(ctx context.Context, retiredAt time.Time) error {
+	ret := _mock.Called(ctx, retiredAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RetireSigningKeys")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = returnFunc(ctx, retiredAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockSigningKeyRepository_RetireSigningKeys_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RetireSigningKeys'
+type MockSigningKeyRepository_RetireSigningKeys_Call struct {
+	*mock.Call
+}
+
+// RetireSigningKeys is a helper method to define mock.On call
+//   - ctx context.Context
+//   - retiredAt time.Time
+func (_e *MockSigningKeyRepository_Expecter) RetireSigningKeys(ctx interface{}, retiredAt interface{}) *MockSigningKeyRepository_RetireSigningKeys_Call {
+	return &MockSigningKeyRepository_RetireSigningKeys_Call{Call: _e.mock.On("RetireSigningKeys", ctx, retiredAt)}
+}
+
+func (_c *MockSigningKeyRepository_RetireSigningKeys_Call) Run(run func(ctx context.Context, retiredAt time.Time)) *MockSigningKeyRepository_RetireSigningKeys_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 time.Time
+		if args[1] != nil {
+			arg1 = args[1].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockSigningKeyRepository_RetireSigningKeys_Call) Return(err error) *MockSigningKeyRepository_RetireSigningKeys_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockSigningKeyRepository_RetireSigningKeys_Call) RunAndReturn(run func(ctx context.Context, retiredAt time.Time) error) *MockSigningKeyRepository_RetireSigningKeys_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockTransactor creates a new instance of MockTransactor. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockTransactor(t interface {
 	mock.TestingT
 	Cleanup(func())
-}) *MockAccountRepository {
-	mock := &MockAccountRepository{}
+}) *MockTransactor {
+	mock := &MockTransactor{}
 	mock.Mock.Test(t)
 
 	t.Cleanup(func() { mock.AssertExpectations(t) })
@@ -516,68 +5983,152 @@ func NewMockAccountRepository(t interface {
 	return mock
 }
 
-// MockAccountRepository is an autogenerated mock type for the AccountRepository type
-type MockAccountRepository struct {
+// MockTransactor is an autogenerated mock type for the Transactor type
+type MockTransactor struct {
 	mock.Mock
 }
 
-type MockAccountRepository_Expecter struct {
+type MockTransactor_Expecter struct {
 	mock *mock.Mock
 }
 
-func (_m *MockAccountRepository) EXPECT() *MockAccountRepository_Expecter {
-	return &MockAccountRepository_Expecter{mock: &_m.Mock}
+func (_m *MockTransactor) EXPECT() *MockTransactor_Expecter {
+	return &MockTransactor_Expecter{mock: &_m.Mock}
 }
 
-// CreateAccount provides a mock function for the type MockAccountRepository
-func (_mock *MockAccountRepository) CreateAccount(ctx context.Context, account *Account) (*Account, error) {
-	ret := _mock.Called(ctx, account)
+// WithTransaction provides a mock function for the type MockTransactor
+func (_mock *MockTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	ret := _mock.Called(ctx, fn)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateAccount")
+		panic("no return value specified for WithTransaction")
 	}
 
-	var r0 *Account
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, func(ctx context.Context) error) error); ok {
+		r0 = returnFunc(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockTransactor_WithTransaction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WithTransaction'
+type MockTransactor_WithTransaction_Call struct {
+	*mock.Call
+}
+
+// WithTransaction is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(ctx context.Context) error
+func (_e *MockTransactor_Expecter) WithTransaction(ctx interface{}, fn interface{}) *MockTransactor_WithTransaction_Call {
+	return &MockTransactor_WithTransaction_Call{Call: _e.mock.On("WithTransaction", ctx, fn)}
+}
+
+func (_c *MockTransactor_WithTransaction_Call) Run(run func(ctx context.Context, fn func(ctx context.Context) error)) *MockTransactor_WithTransaction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 func(ctx context.Context) error
+		if args[1] != nil {
+			arg1 = args[1].(func(ctx context.Context) error)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockTransactor_WithTransaction_Call) Return(err error) *MockTransactor_WithTransaction_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockTransactor_WithTransaction_Call) RunAndReturn(run func(ctx context.Context, fn func(ctx context.Context) error) error) *MockTransactor_WithTransaction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockAccountWebhookRepository creates a new instance of MockAccountWebhookRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockAccountWebhookRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockAccountWebhookRepository {
+	mock := &MockAccountWebhookRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockAccountWebhookRepository is an autogenerated mock type for the AccountWebhookRepository type
+type MockAccountWebhookRepository struct {
+	mock.Mock
+}
+
+type MockAccountWebhookRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockAccountWebhookRepository) EXPECT() *MockAccountWebhookRepository_Expecter {
+	return &MockAccountWebhookRepository_Expecter{mock: &_m.Mock}
+}
+
+// CreateAccountWebhook provides a mock function for the type MockAccountWebhookRepository
+func (_mock *MockAccountWebhookRepository) CreateAccountWebhook(ctx context.Context, webhook *AccountWebhook) (*AccountWebhook, error) {
+	ret := _mock.Called(ctx, webhook)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAccountWebhook")
+	}
+
+	var r0 *AccountWebhook
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) (*Account, error)); ok {
-		return returnFunc(ctx, account)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *AccountWebhook) (*AccountWebhook, error)); ok {
+		return returnFunc(ctx, webhook)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) *Account); ok {
-		r0 = returnFunc(ctx, account)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *AccountWebhook) *AccountWebhook); ok {
+		r0 = returnFunc(ctx, webhook)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*Account)
+			r0 = ret.Get(0).(*AccountWebhook)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, *Account) error); ok {
-		r1 = returnFunc(ctx, account)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *AccountWebhook) error); ok {
+		r1 = returnFunc(ctx, webhook)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockAccountRepository_CreateAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateAccount'
-type MockAccountRepository_CreateAccount_Call struct {
+// MockAccountWebhookRepository_CreateAccountWebhook_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateAccountWebhook'
+type MockAccountWebhookRepository_CreateAccountWebhook_Call struct {
 	*mock.Call
 }
 
-// CreateAccount is a helper method to define mock.On call
+// CreateAccountWebhook is a helper method to define mock.On call
 //   - ctx context.Context
-//   - account *Account
-func (_e *MockAccountRepository_Expecter) CreateAccount(ctx interface{}, account interface{}) *MockAccountRepository_CreateAccount_Call {
-	return &MockAccountRepository_CreateAccount_Call{Call: _e.mock.On("CreateAccount", ctx, account)}
+//   - webhook *AccountWebhook
+func (_e *MockAccountWebhookRepository_Expecter) CreateAccountWebhook(ctx interface{}, webhook interface{}) *MockAccountWebhookRepository_CreateAccountWebhook_Call {
+	return &MockAccountWebhookRepository_CreateAccountWebhook_Call{Call: _e.mock.On("CreateAccountWebhook", ctx, webhook)}
 }
 
-func (_c *MockAccountRepository_CreateAccount_Call) Run(run func(ctx context.Context, account *Account)) *MockAccountRepository_CreateAccount_Call {
+func (_c *MockAccountWebhookRepository_CreateAccountWebhook_Call) Run(run func(ctx context.Context, webhook *AccountWebhook)) *MockAccountWebhookRepository_CreateAccountWebhook_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 *Account
+		var arg1 *AccountWebhook
 		if args[1] != nil {
-			arg1 = args[1].(*Account)
+			arg1 = args[1].(*AccountWebhook)
 		}
 		run(
 			arg0,
@@ -587,22 +6138,22 @@ func (_c *MockAccountRepository_CreateAccount_Call) Run(run func(ctx context.Con
 	return _c
 }
 
-func (_c *MockAccountRepository_CreateAccount_Call) Return(account1 *Account, err error) *MockAccountRepository_CreateAccount_Call {
-	_c.Call.Return(account1, err)
+func (_c *MockAccountWebhookRepository_CreateAccountWebhook_Call) Return(accountWebhook *AccountWebhook, err error) *MockAccountWebhookRepository_CreateAccountWebhook_Call {
+	_c.Call.Return(accountWebhook, err)
 	return _c
 }
 
-func (_c *MockAccountRepository_CreateAccount_Call) RunAndReturn(run func(ctx context.Context, account *Account) (*Account, error)) *MockAccountRepository_CreateAccount_Call {
+func (_c *MockAccountWebhookRepository_CreateAccountWebhook_Call) RunAndReturn(run func(ctx context.Context, webhook *AccountWebhook) (*AccountWebhook, error)) *MockAccountWebhookRepository_CreateAccountWebhook_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// DeleteAccount provides a mock function for the type MockAccountRepository
-func (_mock *MockAccountRepository) DeleteAccount(ctx context.Context, id uint) error {
+// DeleteAccountWebhook provides a mock function for the type MockAccountWebhookRepository
+func (_mock *MockAccountWebhookRepository) DeleteAccountWebhook(ctx context.Context, id uint) error {
 	ret := _mock.Called(ctx, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteAccount")
+		panic("no return value specified for DeleteAccountWebhook")
 	}
 
 	var r0 error
@@ -614,19 +6165,19 @@ func (_mock *MockAccountRepository) DeleteAccount(ctx context.Context, id uint)
 	return r0
 }
 
-// MockAccountRepository_DeleteAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteAccount'
-type MockAccountRepository_DeleteAccount_Call struct {
+// MockAccountWebhookRepository_DeleteAccountWebhook_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteAccountWebhook'
+type MockAccountWebhookRepository_DeleteAccountWebhook_Call struct {
 	*mock.Call
 }
 
-// DeleteAccount is a helper method to define mock.On call
+// DeleteAccountWebhook is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id uint
-func (_e *MockAccountRepository_Expecter) DeleteAccount(ctx interface{}, id interface{}) *MockAccountRepository_DeleteAccount_Call {
-	return &MockAccountRepository_DeleteAccount_Call{Call: _e.mock.On("DeleteAccount", ctx, id)}
+func (_e *MockAccountWebhookRepository_Expecter) DeleteAccountWebhook(ctx interface{}, id interface{}) *MockAccountWebhookRepository_DeleteAccountWebhook_Call {
+	return &MockAccountWebhookRepository_DeleteAccountWebhook_Call{Call: _e.mock.On("DeleteAccountWebhook", ctx, id)}
 }
 
-func (_c *MockAccountRepository_DeleteAccount_Call) Run(run func(ctx context.Context, id uint)) *MockAccountRepository_DeleteAccount_Call {
+func (_c *MockAccountWebhookRepository_DeleteAccountWebhook_Call) Run(run func(ctx context.Context, id uint)) *MockAccountWebhookRepository_DeleteAccountWebhook_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -644,65 +6195,65 @@ func (_c *MockAccountRepository_DeleteAccount_Call) Run(run func(ctx context.Con
 	return _c
 }
 
-func (_c *MockAccountRepository_DeleteAccount_Call) Return(err error) *MockAccountRepository_DeleteAccount_Call {
+func (_c *MockAccountWebhookRepository_DeleteAccountWebhook_Call) Return(err error) *MockAccountWebhookRepository_DeleteAccountWebhook_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockAccountRepository_DeleteAccount_Call) RunAndReturn(run func(ctx context.Context, id uint) error) *MockAccountRepository_DeleteAccount_Call {
+func (_c *MockAccountWebhookRepository_DeleteAccountWebhook_Call) RunAndReturn(run func(ctx context.Context, id uint) error) *MockAccountWebhookRepository_DeleteAccountWebhook_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetAccountByEmail provides a mock function for the type MockAccountRepository
-func (_mock *MockAccountRepository) GetAccountByEmail(ctx context.Context, email string) (*Account, error) {
-	ret := _mock.Called(ctx, email)
+// GetAccountWebhook provides a mock function for the type MockAccountWebhookRepository
+func (_mock *MockAccountWebhookRepository) GetAccountWebhook(ctx context.Context, id uint) (*AccountWebhook, error) {
+	ret := _mock.Called(ctx, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAccountByEmail")
+		panic("no return value specified for GetAccountWebhook")
 	}
 
-	var r0 *Account
+	var r0 *AccountWebhook
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*Account, error)); ok {
-		return returnFunc(ctx, email)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) (*AccountWebhook, error)); ok {
+		return returnFunc(ctx, id)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *Account); ok {
-		r0 = returnFunc(ctx, email)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) *AccountWebhook); ok {
+		r0 = returnFunc(ctx, id)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*Account)
+			r0 = ret.Get(0).(*AccountWebhook)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = returnFunc(ctx, email)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint) error); ok {
+		r1 = returnFunc(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockAccountRepository_GetAccountByEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountByEmail'
-type MockAccountRepository_GetAccountByEmail_Call struct {
+// MockAccountWebhookRepository_GetAccountWebhook_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountWebhook'
+type MockAccountWebhookRepository_GetAccountWebhook_Call struct {
 	*mock.Call
 }
 
-// GetAccountByEmail is a helper method to define mock.On call
+// GetAccountWebhook is a helper method to define mock.On call
 //   - ctx context.Context
-//   - email string
-func (_e *MockAccountRepository_Expecter) GetAccountByEmail(ctx interface{}, email interface{}) *MockAccountRepository_GetAccountByEmail_Call {
-	return &MockAccountRepository_GetAccountByEmail_Call{Call: _e.mock.On("GetAccountByEmail", ctx, email)}
+//   - id uint
+func (_e *MockAccountWebhookRepository_Expecter) GetAccountWebhook(ctx interface{}, id interface{}) *MockAccountWebhookRepository_GetAccountWebhook_Call {
+	return &MockAccountWebhookRepository_GetAccountWebhook_Call{Call: _e.mock.On("GetAccountWebhook", ctx, id)}
 }
 
-func (_c *MockAccountRepository_GetAccountByEmail_Call) Run(run func(ctx context.Context, email string)) *MockAccountRepository_GetAccountByEmail_Call {
+func (_c *MockAccountWebhookRepository_GetAccountWebhook_Call) Run(run func(ctx context.Context, id uint)) *MockAccountWebhookRepository_GetAccountWebhook_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 string
+		var arg1 uint
 		if args[1] != nil {
-			arg1 = args[1].(string)
+			arg1 = args[1].(uint)
 		}
 		run(
 			arg0,
@@ -712,115 +6263,121 @@ func (_c *MockAccountRepository_GetAccountByEmail_Call) Run(run func(ctx context
 	return _c
 }
 
-func (_c *MockAccountRepository_GetAccountByEmail_Call) Return(account *Account, err error) *MockAccountRepository_GetAccountByEmail_Call {
-	_c.Call.Return(account, err)
+func (_c *MockAccountWebhookRepository_GetAccountWebhook_Call) Return(accountWebhook *AccountWebhook, err error) *MockAccountWebhookRepository_GetAccountWebhook_Call {
+	_c.Call.Return(accountWebhook, err)
 	return _c
 }
 
-func (_c *MockAccountRepository_GetAccountByEmail_Call) RunAndReturn(run func(ctx context.Context, email string) (*Account, error)) *MockAccountRepository_GetAccountByEmail_Call {
+func (_c *MockAccountWebhookRepository_GetAccountWebhook_Call) RunAndReturn(run func(ctx context.Context, id uint) (*AccountWebhook, error)) *MockAccountWebhookRepository_GetAccountWebhook_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetAccountByID provides a mock function for the type MockAccountRepository
-func (_mock *MockAccountRepository) GetAccountByID(ctx context.Context, id uint) (*Account, error) {
-	ret := _mock.Called(ctx, id)
+// ListAccountWebhooks provides a mock function for the type MockAccountWebhookRepository
+func (_mock *MockAccountWebhookRepository) ListAccountWebhooks(ctx context.Context) ([]AccountWebhook, error) {
+	ret := _mock.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAccountByID")
+		panic("no return value specified for ListAccountWebhooks")
 	}
 
-	var r0 *Account
+	var r0 []AccountWebhook
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) (*Account, error)); ok {
-		return returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]AccountWebhook, error)); ok {
+		return returnFunc(ctx)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, uint) *Account); ok {
-		r0 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []AccountWebhook); ok {
+		r0 = returnFunc(ctx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*Account)
+			r0 = ret.Get(0).([]AccountWebhook)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, uint) error); ok {
-		r1 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockAccountRepository_GetAccountByID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountByID'
-type MockAccountRepository_GetAccountByID_Call struct {
+// MockAccountWebhookRepository_ListAccountWebhooks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAccountWebhooks'
+type MockAccountWebhookRepository_ListAccountWebhooks_Call struct {
 	*mock.Call
 }
 
-// GetAccountByID is a helper method to define mock.On call
+// ListAccountWebhooks is a helper method to define mock.On call
 //   - ctx context.Context
-//   - id uint
-func (_e *MockAccountRepository_Expecter) GetAccountByID(ctx interface{}, id interface{}) *MockAccountRepository_GetAccountByID_Call {
-	return &MockAccountRepository_GetAccountByID_Call{Call: _e.mock.On("GetAccountByID", ctx, id)}
+func (_e *MockAccountWebhookRepository_Expecter) ListAccountWebhooks(ctx interface{}) *MockAccountWebhookRepository_ListAccountWebhooks_Call {
+	return &MockAccountWebhookRepository_ListAccountWebhooks_Call{Call: _e.mock.On("ListAccountWebhooks", ctx)}
 }
 
-func (_c *MockAccountRepository_GetAccountByID_Call) Run(run func(ctx context.Context, id uint)) *MockAccountRepository_GetAccountByID_Call {
+func (_c *MockAccountWebhookRepository_ListAccountWebhooks_Call) Run(run func(ctx context.Context)) *MockAccountWebhookRepository_ListAccountWebhooks_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 uint
-		if args[1] != nil {
-			arg1 = args[1].(uint)
-		}
 		run(
 			arg0,
-			arg1,
 		)
 	})
 	return _c
 }
 
-func (_c *MockAccountRepository_GetAccountByID_Call) Return(account *Account, err error) *MockAccountRepository_GetAccountByID_Call {
-	_c.Call.Return(account, err)
+func (_c *MockAccountWebhookRepository_ListAccountWebhooks_Call) Return(accountWebhooks []AccountWebhook, err error) *MockAccountWebhookRepository_ListAccountWebhooks_Call {
+	_c.Call.Return(accountWebhooks, err)
 	return _c
 }
 
-func (_c *MockAccountRepository_GetAccountByID_Call) RunAndReturn(run func(ctx context.Context, id uint) (*Account, error)) *MockAccountRepository_GetAccountByID_Call {
+func (_c *MockAccountWebhookRepository_ListAccountWebhooks_Call) RunAndReturn(run func(ctx context.Context) ([]AccountWebhook, error)) *MockAccountWebhookRepository_ListAccountWebhooks_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// LogAccountActivity provides a mock function for the type MockAccountRepository
-func (_mock *MockAccountRepository) LogAccountActivity(ctx context.Context, accountID uint, activity string) error {
-	ret := _mock.Called(ctx, accountID, activity)
+// RotateAccountWebhookSecret provides a mock function for the type MockAccountWebhookRepository
+func (_mock *MockAccountWebhookRepository) RotateAccountWebhookSecret(ctx context.Context, id uint, secret string, previousExpiresAt time.Time) (*AccountWebhook, error) {
+	ret := _mock.Called(ctx, id, secret, previousExpiresAt)
 
 	if len(ret) == 0 {
-		panic("no return value specified for LogAccountActivity")
+		panic("no return value specified for RotateAccountWebhookSecret")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string) error); ok {
-		r0 = returnFunc(ctx, accountID, activity)
+	var r0 *AccountWebhook
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string, time.Time) (*AccountWebhook, error)); ok {
+		return returnFunc(ctx, id, secret, previousExpiresAt)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint, string, time.Time) *AccountWebhook); ok {
+		r0 = returnFunc(ctx, id, secret, previousExpiresAt)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*AccountWebhook)
+		}
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint, string, time.Time) error); ok {
+		r1 = returnFunc(ctx, id, secret, previousExpiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// MockAccountRepository_LogAccountActivity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LogAccountActivity'
-type MockAccountRepository_LogAccountActivity_Call struct {
+// MockAccountWebhookRepository_RotateAccountWebhookSecret_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RotateAccountWebhookSecret'
+type MockAccountWebhookRepository_RotateAccountWebhookSecret_Call struct {
 	*mock.Call
 }
 
-// LogAccountActivity is a helper method to define mock.On call
+// RotateAccountWebhookSecret is a helper method to define mock.On call
 //   - ctx context.Context
-//   - accountID uint
-//   - activity string
-func (_e *MockAccountRepository_Expecter) LogAccountActivity(ctx interface{}, accountID interface{}, activity interface{}) *MockAccountRepository_LogAccountActivity_Call {
-	return &MockAccountRepository_LogAccountActivity_Call{Call: _e.mock.On("LogAccountActivity", ctx, accountID, activity)}
+//   - id uint
+//   - secret string
+//   - previousExpiresAt time.Time
+func (_e *MockAccountWebhookRepository_Expecter) RotateAccountWebhookSecret(ctx interface{}, id interface{}, secret interface{}, previousExpiresAt interface{}) *MockAccountWebhookRepository_RotateAccountWebhookSecret_Call {
+	return &MockAccountWebhookRepository_RotateAccountWebhookSecret_Call{Call: _e.mock.On("RotateAccountWebhookSecret", ctx, id, secret, previousExpiresAt)}
 }
 
-func (_c *MockAccountRepository_LogAccountActivity_Call) Run(run func(ctx context.Context, accountID uint, activity string)) *MockAccountRepository_LogAccountActivity_Call {
+func (_c *MockAccountWebhookRepository_RotateAccountWebhookSecret_Call) Run(run func(ctx context.Context, id uint, secret string, previousExpiresAt time.Time)) *MockAccountWebhookRepository_RotateAccountWebhookSecret_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -834,89 +6391,105 @@ func (_c *MockAccountRepository_LogAccountActivity_Call) Run(run func(ctx contex
 		if args[2] != nil {
 			arg2 = args[2].(string)
 		}
+		var arg3 time.Time
+		if args[3] != nil {
+			arg3 = args[3].(time.Time)
+		}
 		run(
 			arg0,
 			arg1,
 			arg2,
+			arg3,
 		)
 	})
 	return _c
 }
 
-func (_c *MockAccountRepository_LogAccountActivity_Call) Return(err error) *MockAccountRepository_LogAccountActivity_Call {
-	_c.Call.Return(err)
+func (_c *MockAccountWebhookRepository_RotateAccountWebhookSecret_Call) Return(accountWebhook *AccountWebhook, err error) *MockAccountWebhookRepository_RotateAccountWebhookSecret_Call {
+	_c.Call.Return(accountWebhook, err)
 	return _c
 }
 
-func (_c *MockAccountRepository_LogAccountActivity_Call) RunAndReturn(run func(ctx context.Context, accountID uint, activity string) error) *MockAccountRepository_LogAccountActivity_Call {
+func (_c *MockAccountWebhookRepository_RotateAccountWebhookSecret_Call) RunAndReturn(run func(ctx context.Context, id uint, secret string, previousExpiresAt time.Time) (*AccountWebhook, error)) *MockAccountWebhookRepository_RotateAccountWebhookSecret_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// UpdateAccount provides a mock function for the type MockAccountRepository
-func (_mock *MockAccountRepository) UpdateAccount(ctx context.Context, account *Account) (*Account, error) {
-	ret := _mock.Called(ctx, account)
+// NewMockActivityNotifier creates a new instance of MockActivityNotifier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockActivityNotifier(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockActivityNotifier {
+	mock := &MockActivityNotifier{}
+	mock.Mock.Test(t)
 
-	if len(ret) == 0 {
-		panic("no return value specified for UpdateAccount")
-	}
+	t.Cleanup(func() { mock.AssertExpectations(t) })
 
-	var r0 *Account
-	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) (*Account, error)); ok {
-		return returnFunc(ctx, account)
-	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, *Account) *Account); ok {
-		r0 = returnFunc(ctx, account)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*Account)
-		}
-	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, *Account) error); ok {
-		r1 = returnFunc(ctx, account)
-	} else {
-		r1 = ret.Error(1)
-	}
-	return r0, r1
+	return mock
 }
 
-// MockAccountRepository_UpdateAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateAccount'
-type MockAccountRepository_UpdateAccount_Call struct {
+// MockActivityNotifier is an autogenerated mock type for the ActivityNotifier type
+type MockActivityNotifier struct {
+	mock.Mock
+}
+
+type MockActivityNotifier_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockActivityNotifier) EXPECT() *MockActivityNotifier_Expecter {
+	return &MockActivityNotifier_Expecter{mock: &_m.Mock}
+}
+
+// NotifyActivity provides a mock function for the type MockActivityNotifier
+func (_mock *MockActivityNotifier) NotifyActivity(ctx context.Context, accountID uint, activity string) {
+	_mock.Called(ctx, accountID, activity)
+	return
+}
+
+// MockActivityNotifier_NotifyActivity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NotifyActivity'
+type MockActivityNotifier_NotifyActivity_Call struct {
 	*mock.Call
 }
 
-// UpdateAccount is a helper method to define mock.On call
+// NotifyActivity is a helper method to define mock.On call
 //   - ctx context.Context
-//   - account *Account
-func (_e *MockAccountRepository_Expecter) UpdateAccount(ctx interface{}, account interface{}) *MockAccountRepository_UpdateAccount_Call {
-	return &MockAccountRepository_UpdateAccount_Call{Call: _e.mock.On("UpdateAccount", ctx, account)}
+//   - accountID uint
+//   - activity string
+func (_e *MockActivityNotifier_Expecter) NotifyActivity(ctx interface{}, accountID interface{}, activity interface{}) *MockActivityNotifier_NotifyActivity_Call {
+	return &MockActivityNotifier_NotifyActivity_Call{Call: _e.mock.On("NotifyActivity", ctx, accountID, activity)}
 }
 
-func (_c *MockAccountRepository_UpdateAccount_Call) Run(run func(ctx context.Context, account *Account)) *MockAccountRepository_UpdateAccount_Call {
+func (_c *MockActivityNotifier_NotifyActivity_Call) Run(run func(ctx context.Context, accountID uint, activity string)) *MockActivityNotifier_NotifyActivity_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 *Account
+		var arg1 uint
 		if args[1] != nil {
-			arg1 = args[1].(*Account)
+			arg1 = args[1].(uint)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
 		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
 }
 
-func (_c *MockAccountRepository_UpdateAccount_Call) Return(account1 *Account, err error) *MockAccountRepository_UpdateAccount_Call {
-	_c.Call.Return(account1, err)
+func (_c *MockActivityNotifier_NotifyActivity_Call) Return() *MockActivityNotifier_NotifyActivity_Call {
+	_c.Call.Return()
 	return _c
 }
 
-func (_c *MockAccountRepository_UpdateAccount_Call) RunAndReturn(run func(ctx context.Context, account *Account) (*Account, error)) *MockAccountRepository_UpdateAccount_Call {
-	_c.Call.Return(run)
+func (_c *MockActivityNotifier_NotifyActivity_Call) RunAndReturn(run func(ctx context.Context, accountID uint, activity string)) *MockActivityNotifier_NotifyActivity_Call {
+	_c.Run(run)
 	return _c
 }
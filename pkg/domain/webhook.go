@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AccountWebhook is an admin managed subscription to account activities, each
+// recorded activity it subscribes to is posted to URL as a signed event. The
+// secrets are encrypted at rest with ENCRYPTION_KEY.
+type AccountWebhook struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	URL    string `json:"url"`
+	Secret string `json:"-"`
+	// PreviousSecret is the secret replaced by the last rotation, events are
+	// signed with it as well until PreviousSecretExpiresAt
+	PreviousSecret          string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at"`
+	// Activities is the comma separated list of subscribed activities
+	Activities string `json:"activities"`
+	// OrganizationID is set for a webhook configured for an organization, the
+	// owner of the organization can test its delivery
+	OrganizationID *uint `json:"organization_id" gorm:"index"`
+}
+
+// ActivityList returns the subscribed activities
+func (w *AccountWebhook) ActivityList() []string {
+	if w.Activities == "" {
+		return []string{}
+	}
+	return strings.Split(w.Activities, ",")
+}
+
+// PreviousSecretActive reports whether events are still signed with the
+// previous secret at now
+func (w *AccountWebhook) PreviousSecretActive(now time.Time) bool {
+	return w.PreviousSecret != "" && w.PreviousSecretExpiresAt != nil && now.Before(*w.PreviousSecretExpiresAt)
+}
+
+// Subscribes reports whether the webhook receives the activity
+func (w *AccountWebhook) Subscribes(activity string) bool {
+	return slices.Contains(w.ActivityList(), activity)
+}
+
+// WebhookActivities are the account activities a webhook can subscribe to
+var WebhookActivities = []string{
+	ActivityLogin,
+	ActivityLogout,
+	ActivityRegister,
+	ActivityUpdate,
+	ActivityDelete,
+	ActivityResetPassword,
+	ActivityForgotPassword,
+	ActivityChangePassword,
+	ActivityDisable,
+	ActivityVerifyEmail,
+	ActivityLock,
+	ActivityUnlock,
+	ActivityRoleChange,
+	ActivityAnonymize,
+}
+
+var (
+	ErrWebhookNotFound        = errors.New("webhook not found")
+	ErrWebhookInvalidURL      = errors.New("webhook url must be an absolute http or https url")
+	ErrWebhookInvalidActivity = errors.New("webhook activities must be known account activities")
+)
+
+type AccountWebhookRepository interface {
+	CreateAccountWebhook(ctx context.Context, webhook *AccountWebhook) (*AccountWebhook, error)
+	ListAccountWebhooks(ctx context.Context) ([]AccountWebhook, error)
+	GetAccountWebhook(ctx context.Context, id uint) (*AccountWebhook, error)
+	DeleteAccountWebhook(ctx context.Context, id uint) error
+	// RotateAccountWebhookSecret makes secret the current secret and keeps the
+	// replaced one as the previous secret until previousExpiresAt
+	RotateAccountWebhookSecret(ctx context.Context, id uint, secret string, previousExpiresAt time.Time) (*AccountWebhook, error)
+}
+
+// ActivityNotifier is told about every recorded account activity, it must not
+// block the request that performed the activity
+type ActivityNotifier interface {
+	NotifyActivity(ctx context.Context, accountID uint, activity string)
+}
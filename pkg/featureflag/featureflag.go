@@ -0,0 +1,48 @@
+package featureflag
+
+import (
+	"context"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// defaults keep features that shipped before flags existed enabled, any
+// other feature is off until FEATURE_<NAME> turns it on
+var defaults = map[string]bool{
+	domain.FeatureRefreshTokens:     true,
+	domain.FeatureEmailVerification: true,
+}
+
+// EnvProvider reads FEATURE_<NAME> on every check, so a reloaded config
+// takes effect without a restart
+type EnvProvider struct{}
+
+func NewEnvProvider() domain.FeatureFlagProvider {
+	return &EnvProvider{}
+}
+
+func (p *EnvProvider) IsEnabled(ctx context.Context, feature string) bool {
+	key := "FEATURE_" + strings.ToUpper(feature)
+	if viper.IsSet(key) {
+		return viper.GetBool(key)
+	}
+	return defaults[feature]
+}
+
+// Require hides the route behind feature, a disabled feature responds as if
+// the route did not exist
+func Require(provider domain.FeatureFlagProvider, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !provider.IsEnabled(c.Request.Context(), feature) {
+			utils.RespondError(c, domain.ErrFeatureDisabled)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package featureflag_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/featureflag"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRouter(provider domain.FeatureFlagProvider, feature string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/feature", featureflag.Require(provider, feature), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+	return router
+}
+
+func request(router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/feature", nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequire(t *testing.T) {
+
+	anyContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	t.Run("should return not found for a disabled feature", func(t *testing.T) {
+		provider := domain.NewMockFeatureFlagProvider(t)
+		provider.On("IsEnabled", anyContext, domain.FeatureWebhooks).Return(false)
+
+		w := request(newRouter(provider, domain.FeatureWebhooks))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should serve the route for an enabled feature", func(t *testing.T) {
+		provider := domain.NewMockFeatureFlagProvider(t)
+		provider.On("IsEnabled", anyContext, domain.FeatureWebhooks).Return(true)
+
+		w := request(newRouter(provider, domain.FeatureWebhooks))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestEnvProvider(t *testing.T) {
+
+	provider := featureflag.NewEnvProvider()
+
+	t.Run("should keep shipped features enabled by default", func(t *testing.T) {
+		assert.True(t, provider.IsEnabled(context.Background(), domain.FeatureRefreshTokens))
+		assert.False(t, provider.IsEnabled(context.Background(), domain.FeatureWebhooks))
+	})
+
+	t.Run("should toggle a feature from the environment", func(t *testing.T) {
+		viper.Set("FEATURE_REFRESH_TOKENS", false)
+		viper.Set("FEATURE_WEBHOOKS", true)
+		defer viper.Reset()
+
+		assert.False(t, provider.IsEnabled(context.Background(), domain.FeatureRefreshTokens))
+		assert.True(t, provider.IsEnabled(context.Background(), domain.FeatureWebhooks))
+
+		w := request(newRouter(provider, domain.FeatureRefreshTokens))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
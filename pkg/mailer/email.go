@@ -1,13 +1,38 @@
 package mailer
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
 	"net/smtp"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
+// SendResult identifies an email accepted by the mail server
+type SendResult struct {
+	MessageID  string
+	AcceptedAt time.Time
+}
+
 type EmailService interface {
-	SendEmail(email string, subject string, body string) error
+	// SendEmail sends the html body with a plain text alternative
+	SendEmail(email string, subject string, body string) (*SendResult, error)
+	// SendTemplate renders the named template with data and sends it
+	SendTemplate(ctx context.Context, email string, name string, data any) (*SendResult, error)
+	// EnqueueEmail sends the templated email in the background
+	EnqueueEmail(ctx context.Context, job EmailJob) error
+	// Shutdown drains the background queue
+	Shutdown(ctx context.Context) error
 }
 
 type EmailServiceImpl struct {
@@ -16,34 +41,219 @@ type EmailServiceImpl struct {
 	smtpHost string
 	smtpPort string
 	smtpFrom string
+	tlsMode  string
+	caFile   string
+
+	templates *TemplateRegistry
+	queue     *EmailQueue
 }
 
-func NewEmailService() EmailService {
-	return &EmailServiceImpl{
+// SMTP_TLS modes, an empty mode upgrades with STARTTLS when the server offers
+// it and sends in plain text otherwise
+const (
+	SMTPTLSNone     = "none"
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "tls"
+)
+
+const smtpDialTimeout = 10 * time.Second
+
+var (
+	ErrSMTPTLS  = errors.New("smtp tls negotiation failed")
+	ErrSMTPAuth = errors.New("smtp authentication failed")
+)
+
+func NewEmailService(logger logrus.FieldLogger) EmailService {
+	e := &EmailServiceImpl{
 		user:     viper.GetString("SMTP_USER"),
 		password: viper.GetString("SMTP_PASSWORD"),
 		smtpHost: viper.GetString("SMTP_HOST"),
 		smtpPort: viper.GetString("SMTP_PORT"),
 		smtpFrom: viper.GetString("SMTP_FROM"),
+		tlsMode:  strings.ToLower(viper.GetString("SMTP_TLS")),
+		caFile:   viper.GetString("SMTP_TLS_CA_FILE"),
+
+		templates: NewTemplateRegistry(viper.GetString("EMAIL_TEMPLATE_DIR")),
 	}
+	e.queue = NewEmailQueue(logger, func(ctx context.Context, job EmailJob) (*SendResult, error) {
+		return e.SendTemplate(ctx, job.To, job.Template, job.Data)
+	})
+	return e
 }
 
-func (e *EmailServiceImpl) SendEmail(email string, subject string, body string) error {
+func (e *EmailServiceImpl) SendEmail(email string, subject string, body string) (*SendResult, error) {
 	// use nil auth if user and password are not set
 	var auth smtp.Auth
-
 	if viper.GetString("GIN_MODE") != "release" {
 		auth = nil
 	} else {
 		auth = smtp.PlainAuth("", e.user, e.password, e.smtpHost)
 	}
 
-	msg := []byte("To: " + email + "\r\n" + "Subject: " + subject + "\r\n" + "\r\n" + body)
+	messageID, err := NewMessageID(e.smtpFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := NewMessage(email, subject, messageID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.send(auth, email, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SendResult{
+		MessageID:  messageID,
+		AcceptedAt: time.Now(),
+	}, nil
+}
+
+func (e *EmailServiceImpl) SendTemplate(ctx context.Context, email string, name string, data any) (*SendResult, error) {
+	subject, body, err := e.templates.Render(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return e.SendEmail(email, subject, body)
+}
+
+func (e *EmailServiceImpl) EnqueueEmail(ctx context.Context, job EmailJob) error {
+	return e.queue.Enqueue(job)
+}
+
+func (e *EmailServiceImpl) Shutdown(ctx context.Context) error {
+	return e.queue.Shutdown(ctx)
+}
+
+// send delivers msg over a connection secured according to the tls mode,
+// failures to secure the connection or to authenticate wrap ErrSMTPTLS and
+// ErrSMTPAuth
+func (e *EmailServiceImpl) send(auth smtp.Auth, to string, msg []byte) error {
+	client, err := e.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = e.startTLS(client)
+	if err != nil {
+		return err
+	}
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("%w: %v", ErrSMTPAuth, err)
+		}
+	}
+
+	if err := client.Mail(e.smtpFrom); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
 
-	err := smtp.SendMail(e.smtpHost+":"+e.smtpPort, auth, e.smtpFrom, []string{email}, msg)
+	w, err := client.Data()
 	if err != nil {
 		return err
 	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (e *EmailServiceImpl) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(e.smtpHost, e.smtpPort)
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	switch e.tlsMode {
+	case "", SMTPTLSNone, SMTPTLSStartTLS:
+		conn, err := dialer.Dial("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return smtp.NewClient(conn, e.smtpHost)
+	case SMTPTLSImplicit:
+		tlsConfig, err := e.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSMTPTLS, err)
+		}
+		return smtp.NewClient(conn, e.smtpHost)
+	default:
+		return nil, fmt.Errorf("unknown SMTP_TLS mode %q, use none, starttls or tls", e.tlsMode)
+	}
+}
+
+// startTLS upgrades a plain connection, it is required in starttls mode and
+// used when offered without a mode
+func (e *EmailServiceImpl) startTLS(client *smtp.Client) error {
+	if e.tlsMode == SMTPTLSNone || e.tlsMode == SMTPTLSImplicit {
+		return nil
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		if e.tlsMode == SMTPTLSStartTLS {
+			return fmt.Errorf("%w: server does not offer STARTTLS", ErrSMTPTLS)
+		}
+		return nil
+	}
 
+	tlsConfig, err := e.tlsConfig()
+	if err != nil {
+		return err
+	}
+	if err := client.StartTLS(tlsConfig); err != nil {
+		return fmt.Errorf("%w: %v", ErrSMTPTLS, err)
+	}
 	return nil
 }
+
+// tlsConfig verifies the server against the system roots, or against the
+// certificates in SMTP_TLS_CA_FILE for a relay with a private ca
+func (e *EmailServiceImpl) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: e.smtpHost,
+		MinVersion: tls.VersionTLS12,
+	}
+	if e.caFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(e.caFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading SMTP_TLS_CA_FILE: %v", ErrSMTPTLS, err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: no certificates in SMTP_TLS_CA_FILE", ErrSMTPTLS)
+	}
+	tlsConfig.RootCAs = roots
+
+	return tlsConfig, nil
+}
+
+// NewMessageID generates a unique Message-ID header value in the domain of the sender
+func NewMessageID(from string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain), nil
+}
@@ -0,0 +1,294 @@
+package mailer_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"spsyncpro_api/pkg/mailer"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMessageID(t *testing.T) {
+	t.Run("should generate unique ids in the sender domain", func(t *testing.T) {
+		first, err := mailer.NewMessageID("test@developer.com")
+		assert.NoError(t, err)
+		second, err := mailer.NewMessageID("test@developer.com")
+		assert.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(first, "<"))
+		assert.True(t, strings.HasSuffix(first, "@developer.com>"))
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("should fall back to localhost without a sender domain", func(t *testing.T) {
+		id, err := mailer.NewMessageID("")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasSuffix(id, "@localhost>"))
+	})
+}
+
+// smtpStub is a minimal smtp server recording the messages it accepts and
+// whether they arrived over tls
+type smtpStub struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config
+	implicitTLS bool
+	offerTLS    bool
+	rejectAuth  bool
+
+	mu       sync.Mutex
+	messages []string
+	secured  []bool
+}
+
+func newSMTPStub(t *testing.T, serverTLS *tls.Config, implicitTLS bool, offerTLS bool) *smtpStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	stub := &smtpStub{listener: listener, tlsConfig: serverTLS, implicitTLS: implicitTLS, offerTLS: offerTLS}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+	return stub
+}
+
+func (s *smtpStub) port() string {
+	return strconv.Itoa(s.listener.Addr().(*net.TCPAddr).Port)
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+
+	secured := false
+	if s.implicitTLS {
+		conn = tls.Server(conn, s.tlsConfig)
+		secured = true
+	}
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 stub ESMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command, _, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(command) {
+		case "EHLO":
+			if s.offerTLS && !secured {
+				text.PrintfLine("250-stub")
+				text.PrintfLine("250-STARTTLS")
+			} else {
+				text.PrintfLine("250-stub")
+			}
+			text.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			text.PrintfLine("220 ready to start tls")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			secured = true
+		case "AUTH":
+			if s.rejectAuth {
+				text.PrintfLine("535 authentication credentials invalid")
+			} else {
+				text.PrintfLine("235 authenticated")
+			}
+		case "MAIL", "RCPT":
+			text.PrintfLine("250 ok")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.secured = append(s.secured, secured)
+			s.mu.Unlock()
+			text.PrintfLine("250 queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpStub) received() ([]string, []bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages, s.secured
+}
+
+// newTestCertificate returns a server tls config for 127.0.0.1 and the path
+// of its self signed certificate in pem
+func newTestCertificate(t *testing.T) (*tls.Config, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "smtp stub"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}, caFile
+}
+
+func TestEmailService_TLSMode(t *testing.T) {
+	serverTLS, caFile := newTestCertificate(t)
+
+	send := func(stub *smtpStub, tlsMode string, trustCA bool) error {
+		viper.Set("SMTP_HOST", "127.0.0.1")
+		viper.Set("SMTP_PORT", stub.port())
+		viper.Set("SMTP_FROM", "test@developer.com")
+		viper.Set("SMTP_USER", "test@developer.com")
+		viper.Set("SMTP_PASSWORD", "secret")
+		viper.Set("SMTP_TLS", tlsMode)
+		if trustCA {
+			viper.Set("SMTP_TLS_CA_FILE", caFile)
+		}
+
+		_, err := mailer.NewEmailService(logrus.New()).SendEmail("user@example.com", "Hello", "body")
+		return err
+	}
+
+	tests := []struct {
+		name        string
+		tlsMode     string
+		implicitTLS bool
+		offerTLS    bool
+		secured     bool
+	}{
+		{name: "should send in plain text with none", tlsMode: "none", offerTLS: true, secured: false},
+		{name: "should upgrade with starttls", tlsMode: "starttls", offerTLS: true, secured: true},
+		{name: "should connect over implicit tls", tlsMode: "tls", implicitTLS: true, secured: true},
+		{name: "should upgrade when offered without a mode", tlsMode: "", offerTLS: true, secured: true},
+		{name: "should send in plain text when not offered without a mode", tlsMode: "", offerTLS: false, secured: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer viper.Reset()
+
+			stub := newSMTPStub(t, serverTLS, tt.implicitTLS, tt.offerTLS)
+			assert.NoError(t, send(stub, tt.tlsMode, true))
+
+			messages, secured := stub.received()
+			assert.Len(t, messages, 1)
+			assert.Contains(t, messages[0], "Subject: Hello")
+			assert.Equal(t, []bool{tt.secured}, secured)
+		})
+	}
+
+	t.Run("should fail starttls against a server that does not offer it", func(t *testing.T) {
+		defer viper.Reset()
+
+		stub := newSMTPStub(t, serverTLS, false, false)
+		err := send(stub, "starttls", true)
+		assert.ErrorIs(t, err, mailer.ErrSMTPTLS)
+		assert.Contains(t, err.Error(), "does not offer STARTTLS")
+
+		messages, _ := stub.received()
+		assert.Empty(t, messages)
+	})
+
+	t.Run("should fail tls against an untrusted certificate", func(t *testing.T) {
+		defer viper.Reset()
+
+		stub := newSMTPStub(t, serverTLS, true, false)
+		err := send(stub, "tls", false)
+		assert.ErrorIs(t, err, mailer.ErrSMTPTLS)
+	})
+
+	t.Run("should fail starttls against an untrusted certificate", func(t *testing.T) {
+		defer viper.Reset()
+
+		stub := newSMTPStub(t, serverTLS, false, true)
+		err := send(stub, "starttls", false)
+		assert.ErrorIs(t, err, mailer.ErrSMTPTLS)
+	})
+
+	t.Run("should report rejected credentials", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Set("GIN_MODE", "release")
+
+		stub := newSMTPStub(t, serverTLS, false, true)
+		stub.rejectAuth = true
+		err := send(stub, "starttls", true)
+		assert.ErrorIs(t, err, mailer.ErrSMTPAuth)
+		assert.Contains(t, err.Error(), "535")
+	})
+
+	t.Run("should send a rendered template", func(t *testing.T) {
+		defer viper.Reset()
+
+		stub := newSMTPStub(t, serverTLS, false, false)
+		viper.Set("SMTP_HOST", "127.0.0.1")
+		viper.Set("SMTP_PORT", stub.port())
+		viper.Set("SMTP_FROM", "test@developer.com")
+
+		result, err := mailer.NewEmailService(logrus.New()).SendTemplate(context.Background(), "user@example.com", mailer.TemplatePasswordReset, mailer.PasswordResetData{
+			Link: "http://localhost:8080/reset",
+		})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, result.MessageID)
+
+		messages, _ := stub.received()
+		assert.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "Subject: Password Reset")
+		assert.Contains(t, messages[0], "Click here to reset your password (http://localhost:8080/reset)")
+	})
+
+	t.Run("should reject an unknown mode", func(t *testing.T) {
+		defer viper.Reset()
+
+		stub := newSMTPStub(t, serverTLS, false, false)
+		err := send(stub, "ssl", true)
+		assert.ErrorContains(t, err, "unknown SMTP_TLS mode")
+	})
+}
@@ -0,0 +1,106 @@
+package mailer_test
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"spsyncpro_api/pkg/mailer"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlainTextFromHTML(t *testing.T) {
+	t.Run("should keep link targets and split blocks into paragraphs", func(t *testing.T) {
+		text := mailer.PlainTextFromHTML(`
+			<html>
+			<head><style>p { color: red; }</style></head>
+			<body>
+				<h1>Password Reset Request</h1>
+				<p><a href="http://localhost:8080/reset?token=a&amp;b">Click here to reset your password</a></p>
+				<p>If you did not   request this,<br>ignore this email.</p>
+			</body>
+			</html>
+		`)
+
+		assert.Equal(t, "Password Reset Request\n\n"+
+			"Click here to reset your password (http://localhost:8080/reset?token=a&b)\n\n"+
+			"If you did not request this,\n\n"+
+			"ignore this email.", text)
+	})
+
+	t.Run("should not repeat a link shown as its own label", func(t *testing.T) {
+		text := mailer.PlainTextFromHTML(`<p><a href="http://localhost:8080">http://localhost:8080</a></p>`)
+		assert.Equal(t, "http://localhost:8080", text)
+	})
+
+	t.Run("should unescape entities", func(t *testing.T) {
+		text := mailer.PlainTextFromHTML(`<p>Tom &amp; Jerry&#39;s &lt;account&gt;</p>`)
+		assert.Equal(t, "Tom & Jerry's <account>", text)
+	})
+}
+
+func TestNewMessage(t *testing.T) {
+	htmlBody, err := mailer.RenderTemplatePreview(mailer.TemplatePasswordReset)
+	assert.NoError(t, err)
+
+	raw, err := mailer.NewMessage("user@example.com", "Password Reset", "<id@developer.com>", htmlBody)
+	assert.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	assert.NoError(t, err)
+
+	t.Run("should set the mime headers", func(t *testing.T) {
+		assert.Equal(t, "user@example.com", msg.Header.Get("To"))
+		assert.Equal(t, "Password Reset", msg.Header.Get("Subject"))
+		assert.Equal(t, "<id@developer.com>", msg.Header.Get("Message-ID"))
+		assert.Equal(t, "1.0", msg.Header.Get("MIME-Version"))
+
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		assert.NoError(t, err)
+		assert.Equal(t, "multipart/alternative", mediaType)
+		assert.NotEmpty(t, params["boundary"])
+	})
+
+	t.Run("should contain a text part followed by the html part", func(t *testing.T) {
+		_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		assert.NoError(t, err)
+
+		reader := multipart.NewReader(msg.Body, params["boundary"])
+
+		text, err := reader.NextPart()
+		assert.NoError(t, err)
+		assert.Equal(t, "text/plain; charset=utf-8", text.Header.Get("Content-Type"))
+		textBody, err := io.ReadAll(text)
+		assert.NoError(t, err)
+		assert.Contains(t, string(textBody), "Click here to reset your password (http://localhost:8080/reset-password?token=sample-token)")
+		assert.NotContains(t, string(textBody), "<p>")
+
+		html, err := reader.NextPart()
+		assert.NoError(t, err)
+		assert.Equal(t, "text/html; charset=utf-8", html.Header.Get("Content-Type"))
+		htmlPart, err := io.ReadAll(html)
+		assert.NoError(t, err)
+		// quoted-printable sends line breaks as CRLF
+		assert.Equal(t, htmlBody, strings.ReplaceAll(string(htmlPart), "\r\n", "\n"))
+
+		_, err = reader.NextPart()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("should encode a non ascii subject", func(t *testing.T) {
+		raw, err := mailer.NewMessage("user@example.com", "Réinitialiser", "<id@developer.com>", "<p>hi</p>")
+		assert.NoError(t, err)
+
+		msg, err := mail.ReadMessage(bytes.NewReader(raw))
+		assert.NoError(t, err)
+		assert.NotContains(t, string(raw), "Réinitialiser")
+
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Réinitialiser", subject)
+	})
+}
@@ -0,0 +1,167 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultEmailQueueSize  = 100
+	defaultEmailMaxRetries = 3
+	defaultEmailRetryDelay = 2 * time.Second
+)
+
+var (
+	ErrEmailQueueFull   = errors.New("email queue is full")
+	ErrEmailQueueClosed = errors.New("email queue is closed")
+)
+
+// EmailJob is a templated email sent in the background
+type EmailJob struct {
+	To       string
+	Template string
+	Data     any
+	// OnSent is called by the queue worker once the mail server accepted the email
+	OnSent func(result *SendResult)
+}
+
+// EmailQueue sends email jobs from a buffered channel on a single background
+// worker, retrying failed sends with a doubling delay. The worker starts with
+// the first job, Shutdown stops accepting jobs and drains the buffer.
+type EmailQueue struct {
+	logger     logrus.FieldLogger
+	send       func(ctx context.Context, job EmailJob) (*SendResult, error)
+	jobs       chan EmailJob
+	maxRetries int
+	retryDelay time.Duration
+
+	start sync.Once
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	// ctx is cancelled when a shutdown deadline passes, abandoning retries
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewEmailQueue reads EMAIL_QUEUE_SIZE, EMAIL_MAX_RETRIES and
+// EMAIL_RETRY_DELAY, send delivers a single attempt of a job
+func NewEmailQueue(logger logrus.FieldLogger, send func(ctx context.Context, job EmailJob) (*SendResult, error)) *EmailQueue {
+	size := viper.GetInt("EMAIL_QUEUE_SIZE")
+	if size <= 0 {
+		size = defaultEmailQueueSize
+	}
+	maxRetries := defaultEmailMaxRetries
+	if viper.IsSet("EMAIL_MAX_RETRIES") {
+		maxRetries = max(viper.GetInt("EMAIL_MAX_RETRIES"), 0)
+	}
+	retryDelay := viper.GetDuration("EMAIL_RETRY_DELAY")
+	if retryDelay <= 0 {
+		retryDelay = defaultEmailRetryDelay
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EmailQueue{
+		logger:     logger,
+		send:       send,
+		jobs:       make(chan EmailJob, size),
+		maxRetries: maxRetries,
+		retryDelay: retryDelay,
+		done:       make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Enqueue hands the job to the worker without waiting for it to be sent, a
+// full buffer is reported instead of blocking the caller
+func (q *EmailQueue) Enqueue(job EmailJob) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrEmailQueueClosed
+	}
+
+	q.start.Do(func() { go q.run() })
+
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrEmailQueueFull
+	}
+}
+
+// Shutdown stops accepting jobs and waits for the queued ones to be sent.
+// When ctx ends first the remaining retries are abandoned and ctx.Err() is
+// returned.
+func (q *EmailQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.jobs)
+	q.mu.Unlock()
+
+	// a queue that never received a job has no worker to wait for
+	q.start.Do(func() { close(q.done) })
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
+
+func (q *EmailQueue) run() {
+	defer close(q.done)
+
+	for job := range q.jobs {
+		if q.ctx.Err() != nil {
+			q.logger.WithField("template", job.Template).Errorf("email dropped on shutdown")
+			continue
+		}
+		q.process(job)
+	}
+}
+
+func (q *EmailQueue) process(job EmailJob) {
+	logger := q.logger.WithField("template", job.Template)
+
+	for attempt := 0; ; attempt++ {
+		result, err := q.send(q.ctx, job)
+		if err == nil {
+			if job.OnSent != nil {
+				job.OnSent(result)
+			}
+			return
+		}
+
+		if attempt >= q.maxRetries {
+			logger.Errorf("failed to send email after %d attempts: %v", attempt+1, err)
+			return
+		}
+		logger.Warnf("failed to send email, retrying: %v", err)
+
+		timer := time.NewTimer(q.retryDelay << attempt)
+		select {
+		case <-q.ctx.Done():
+			timer.Stop()
+			logger.Errorf("email dropped on shutdown: %v", err)
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package mailer_test
+
+import (
+	"context"
+	"errors"
+	"spsyncpro_api/pkg/mailer"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailQueue(t *testing.T) {
+	errSend := errors.New("connection refused")
+
+	t.Run("should send a queued job and report the result", func(t *testing.T) {
+		queue := mailer.NewEmailQueue(logrus.New(), func(ctx context.Context, job mailer.EmailJob) (*mailer.SendResult, error) {
+			return &mailer.SendResult{MessageID: "<" + job.To + ">"}, nil
+		})
+
+		sent := make(chan *mailer.SendResult, 1)
+		err := queue.Enqueue(mailer.EmailJob{
+			To:       "test@example.com",
+			Template: mailer.TemplatePasswordReset,
+			OnSent:   func(result *mailer.SendResult) { sent <- result },
+		})
+		assert.NoError(t, err)
+
+		select {
+		case result := <-sent:
+			assert.Equal(t, "<test@example.com>", result.MessageID)
+		case <-time.After(time.Second):
+			t.Fatal("job was not sent")
+		}
+		assert.NoError(t, queue.Shutdown(context.Background()))
+	})
+
+	t.Run("should retry a failed send", func(t *testing.T) {
+		viper.Set("EMAIL_RETRY_DELAY", "1ms")
+		defer viper.Reset()
+
+		var attempts atomic.Int32
+		queue := mailer.NewEmailQueue(logrus.New(), func(ctx context.Context, job mailer.EmailJob) (*mailer.SendResult, error) {
+			if attempts.Add(1) == 1 {
+				return nil, errSend
+			}
+			return &mailer.SendResult{}, nil
+		})
+
+		var sent atomic.Bool
+		err := queue.Enqueue(mailer.EmailJob{OnSent: func(*mailer.SendResult) { sent.Store(true) }})
+		assert.NoError(t, err)
+
+		assert.NoError(t, queue.Shutdown(context.Background()))
+		assert.Equal(t, int32(2), attempts.Load())
+		assert.True(t, sent.Load())
+	})
+
+	t.Run("should give up after the retries", func(t *testing.T) {
+		viper.Set("EMAIL_RETRY_DELAY", "1ms")
+		viper.Set("EMAIL_MAX_RETRIES", 2)
+		defer viper.Reset()
+
+		var attempts atomic.Int32
+		queue := mailer.NewEmailQueue(logrus.New(), func(ctx context.Context, job mailer.EmailJob) (*mailer.SendResult, error) {
+			attempts.Add(1)
+			return nil, errSend
+		})
+
+		err := queue.Enqueue(mailer.EmailJob{OnSent: func(*mailer.SendResult) { t.Error("failed job reported as sent") }})
+		assert.NoError(t, err)
+
+		assert.NoError(t, queue.Shutdown(context.Background()))
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("should reject a job when the queue is full", func(t *testing.T) {
+		viper.Set("EMAIL_QUEUE_SIZE", 1)
+		defer viper.Reset()
+
+		release := make(chan struct{})
+		started := make(chan struct{}, 1)
+		queue := mailer.NewEmailQueue(logrus.New(), func(ctx context.Context, job mailer.EmailJob) (*mailer.SendResult, error) {
+			started <- struct{}{}
+			<-release
+			return &mailer.SendResult{}, nil
+		})
+
+		// the first job occupies the worker, the second fills the buffer
+		assert.NoError(t, queue.Enqueue(mailer.EmailJob{}))
+		<-started
+		assert.NoError(t, queue.Enqueue(mailer.EmailJob{}))
+		assert.ErrorIs(t, queue.Enqueue(mailer.EmailJob{}), mailer.ErrEmailQueueFull)
+
+		close(release)
+		assert.NoError(t, queue.Shutdown(context.Background()))
+	})
+
+	t.Run("should drain queued jobs on shutdown", func(t *testing.T) {
+		var sent atomic.Int32
+		queue := mailer.NewEmailQueue(logrus.New(), func(ctx context.Context, job mailer.EmailJob) (*mailer.SendResult, error) {
+			time.Sleep(5 * time.Millisecond)
+			sent.Add(1)
+			return &mailer.SendResult{}, nil
+		})
+
+		for range 3 {
+			assert.NoError(t, queue.Enqueue(mailer.EmailJob{}))
+		}
+
+		assert.NoError(t, queue.Shutdown(context.Background()))
+		assert.Equal(t, int32(3), sent.Load())
+		assert.ErrorIs(t, queue.Enqueue(mailer.EmailJob{}), mailer.ErrEmailQueueClosed)
+	})
+
+	t.Run("should abandon retries when the shutdown deadline passes", func(t *testing.T) {
+		viper.Set("EMAIL_RETRY_DELAY", "1h")
+		defer viper.Reset()
+
+		queue := mailer.NewEmailQueue(logrus.New(), func(ctx context.Context, job mailer.EmailJob) (*mailer.SendResult, error) {
+			return nil, errSend
+		})
+		assert.NoError(t, queue.Enqueue(mailer.EmailJob{}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, queue.Shutdown(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("should shut down a queue that never received a job", func(t *testing.T) {
+		queue := mailer.NewEmailQueue(logrus.New(), func(ctx context.Context, job mailer.EmailJob) (*mailer.SendResult, error) {
+			return &mailer.SendResult{}, nil
+		})
+
+		assert.NoError(t, queue.Shutdown(context.Background()))
+	})
+}
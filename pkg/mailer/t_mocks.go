@@ -5,6 +5,8 @@
 package mailer
 
 import (
+	"context"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -35,21 +37,89 @@ func (_m *MockEmailService) EXPECT() *MockEmailService_Expecter {
 	return &MockEmailService_Expecter{mock: &_m.Mock}
 }
 
+// EnqueueEmail provides a mock function for the type MockEmailService
+func (_mock *MockEmailService) EnqueueEmail(ctx context.Context, job EmailJob) error {
+	ret := _mock.Called(ctx, job)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnqueueEmail")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, EmailJob) error); ok {
+		r0 = returnFunc(ctx, job)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockEmailService_EnqueueEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EnqueueEmail'
+type MockEmailService_EnqueueEmail_Call struct {
+	*mock.Call
+}
+
+// EnqueueEmail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - job EmailJob
+func (_e *MockEmailService_Expecter) EnqueueEmail(ctx interface{}, job interface{}) *MockEmailService_EnqueueEmail_Call {
+	return &MockEmailService_EnqueueEmail_Call{Call: _e.mock.On("EnqueueEmail", ctx, job)}
+}
+
+func (_c *MockEmailService_EnqueueEmail_Call) Run(run func(ctx context.Context, job EmailJob)) *MockEmailService_EnqueueEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 EmailJob
+		if args[1] != nil {
+			arg1 = args[1].(EmailJob)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEmailService_EnqueueEmail_Call) Return(err error) *MockEmailService_EnqueueEmail_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockEmailService_EnqueueEmail_Call) RunAndReturn(run func(ctx context.Context, job EmailJob) error) *MockEmailService_EnqueueEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SendEmail provides a mock function for the type MockEmailService
-func (_mock *MockEmailService) SendEmail(email string, subject string, body string) error {
+func (_mock *MockEmailService) SendEmail(email string, subject string, body string) (*SendResult, error) {
 	ret := _mock.Called(email, subject, body)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SendEmail")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(string, string, string) error); ok {
+	var r0 *SendResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string, string, string) (*SendResult, error)); ok {
+		return returnFunc(email, subject, body)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string, string, string) *SendResult); ok {
 		r0 = returnFunc(email, subject, body)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*SendResult)
+		}
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = returnFunc(email, subject, body)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
 // MockEmailService_SendEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendEmail'
@@ -88,12 +158,143 @@ func (_c *MockEmailService_SendEmail_Call) Run(run func(email string, subject st
 	return _c
 }
 
-func (_c *MockEmailService_SendEmail_Call) Return(err error) *MockEmailService_SendEmail_Call {
+func (_c *MockEmailService_SendEmail_Call) Return(sendResult *SendResult, err error) *MockEmailService_SendEmail_Call {
+	_c.Call.Return(sendResult, err)
+	return _c
+}
+
+func (_c *MockEmailService_SendEmail_Call) RunAndReturn(run func(email string, subject string, body string) (*SendResult, error)) *MockEmailService_SendEmail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendTemplate provides a mock function for the type MockEmailService
+func (_mock *MockEmailService) SendTemplate(ctx context.Context, email string, name string, data any) (*SendResult, error) {
+	ret := _mock.Called(ctx, email, name, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendTemplate")
+	}
+
+	var r0 *SendResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, any) (*SendResult, error)); ok {
+		return returnFunc(ctx, email, name, data)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, any) *SendResult); ok {
+		r0 = returnFunc(ctx, email, name, data)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*SendResult)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, any) error); ok {
+		r1 = returnFunc(ctx, email, name, data)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEmailService_SendTemplate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendTemplate'
+type MockEmailService_SendTemplate_Call struct {
+	*mock.Call
+}
+
+// SendTemplate is a helper method to define mock.On call
+//   - ctx context.Context
+//   - email string
+//   - name string
+//   - data any
+func (_e *MockEmailService_Expecter) SendTemplate(ctx interface{}, email interface{}, name interface{}, data interface{}) *MockEmailService_SendTemplate_Call {
+	return &MockEmailService_SendTemplate_Call{Call: _e.mock.On("SendTemplate", ctx, email, name, data)}
+}
+
+func (_c *MockEmailService_SendTemplate_Call) Run(run func(ctx context.Context, email string, name string, data any)) *MockEmailService_SendTemplate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 any
+		if args[3] != nil {
+			arg3 = args[3].(any)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEmailService_SendTemplate_Call) Return(sendResult *SendResult, err error) *MockEmailService_SendTemplate_Call {
+	_c.Call.Return(sendResult, err)
+	return _c
+}
+
+func (_c *MockEmailService_SendTemplate_Call) RunAndReturn(run func(ctx context.Context, email string, name string, data any) (*SendResult, error)) *MockEmailService_SendTemplate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Shutdown provides a mock function for the type MockEmailService
+func (_mock *MockEmailService) Shutdown(ctx context.Context) error {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Shutdown")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockEmailService_Shutdown_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Shutdown'
+type MockEmailService_Shutdown_Call struct {
+	*mock.Call
+}
+
+// Shutdown is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockEmailService_Expecter) Shutdown(ctx interface{}) *MockEmailService_Shutdown_Call {
+	return &MockEmailService_Shutdown_Call{Call: _e.mock.On("Shutdown", ctx)}
+}
+
+func (_c *MockEmailService_Shutdown_Call) Run(run func(ctx context.Context)) *MockEmailService_Shutdown_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEmailService_Shutdown_Call) Return(err error) *MockEmailService_Shutdown_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockEmailService_SendEmail_Call) RunAndReturn(run func(email string, subject string, body string) error) *MockEmailService_SendEmail_Call {
+func (_c *MockEmailService_Shutdown_Call) RunAndReturn(run func(ctx context.Context) error) *MockEmailService_Shutdown_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,200 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	TemplatePasswordReset = "password_reset"
+	TemplatePendingAction = "pending_action"
+	TemplateInactivity    = "inactivity_warning"
+	TemplateVerifyEmail   = "verify_email"
+	TemplateAccountLocked = "account_locked"
+	TemplateChangeEmail   = "change_email"
+	TemplateAccountExists = "account_exists"
+)
+
+var ErrTemplateNotFound = errors.New("email template not found")
+
+type PasswordResetData struct {
+	Link string
+}
+
+type PendingActionData struct {
+	Action       string
+	ExecuteAfter time.Time
+	CancelLink   string
+}
+
+type VerifyEmailData struct {
+	Link string
+}
+
+type ChangeEmailData struct {
+	Link string
+}
+
+type InactivityData struct {
+	DisableAt time.Time
+	LoginLink string
+}
+
+type AccountLockedData struct {
+	LockedUntil time.Time
+	ResetLink   string
+}
+
+// AccountExistsData is sent in place of a verification email when someone
+// registers with the email of an existing account
+type AccountExistsData struct {
+	LoginLink string
+	ResetLink string
+}
+
+// templateNames are the templates a registry serves, names outside this list
+// are never looked up on disk
+var templateNames = []string{
+	TemplatePasswordReset,
+	TemplatePendingAction,
+	TemplateInactivity,
+	TemplateVerifyEmail,
+	TemplateAccountLocked,
+	TemplateChangeEmail,
+	TemplateAccountExists,
+}
+
+// defaultTemplates are used for every template missing from the template
+// directory. Each template defines its "subject" next to the html body.
+//
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+// TemplateRegistry loads html/template files named "<name>.html" from a
+// directory, falling back to the embedded defaults. Templates are parsed on
+// first use and kept, a changed file is picked up on restart.
+type TemplateRegistry struct {
+	dir string
+
+	mu        sync.Mutex
+	templates map[string]*template.Template
+}
+
+// NewTemplateRegistry serves the templates of dir, only the embedded defaults
+// when dir is empty
+func NewTemplateRegistry(dir string) *TemplateRegistry {
+	return &TemplateRegistry{
+		dir:       dir,
+		templates: map[string]*template.Template{},
+	}
+}
+
+func (r *TemplateRegistry) lookup(name string) (*template.Template, error) {
+	if !slices.Contains(templateNames, name) {
+		return nil, ErrTemplateNotFound
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tmpl, ok := r.templates[name]; ok {
+		return tmpl, nil
+	}
+
+	file := name + ".html"
+	path := filepath.Join(r.dir, file)
+
+	var tmpl *template.Template
+	var err error
+	if _, statErr := os.Stat(path); r.dir != "" && statErr == nil {
+		tmpl, err = template.New(file).ParseFiles(path)
+	} else {
+		tmpl, err = template.New(file).ParseFS(defaultTemplates, "templates/"+file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing email template %s: %w", name, err)
+	}
+	if tmpl.Lookup("subject") == nil {
+		return nil, fmt.Errorf("email template %s defines no subject", name)
+	}
+
+	r.templates[name] = tmpl
+	return tmpl, nil
+}
+
+// Render renders the subject and html body of the named template
+func (r *TemplateRegistry) Render(name string, data any) (string, string, error) {
+	tmpl, err := r.lookup(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	var subject bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()), nil
+}
+
+// sample data used to preview templates without triggering a real flow
+var templateSamples = map[string]any{
+	TemplatePasswordReset: PasswordResetData{
+		Link: "http://localhost:8080/reset-password?token=sample-token",
+	},
+	TemplatePendingAction: PendingActionData{
+		Action:       "delete",
+		ExecuteAfter: time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC),
+		CancelLink:   "http://localhost:8080/pending-action/cancel?token=sample-token",
+	},
+	TemplateVerifyEmail: VerifyEmailData{
+		Link: "http://localhost:8080/verify-email?token=sample-token",
+	},
+	TemplateChangeEmail: ChangeEmailData{
+		Link: "http://localhost:8080/change-email/confirm?token=sample-token",
+	},
+	TemplateAccountLocked: AccountLockedData{
+		LockedUntil: time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC),
+		ResetLink:   "http://localhost:8080/forgot-password",
+	},
+	TemplateAccountExists: AccountExistsData{
+		LoginLink: "http://localhost:8080",
+		ResetLink: "http://localhost:8080/forgot-password",
+	},
+	TemplateInactivity: InactivityData{
+		DisableAt: time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC),
+		LoginLink: "http://localhost:8080",
+	},
+}
+
+// RenderTemplate renders the html body of the named template from
+// EMAIL_TEMPLATE_DIR
+func RenderTemplate(name string, data any) (string, error) {
+	_, body, err := NewTemplateRegistry(viper.GetString("EMAIL_TEMPLATE_DIR")).Render(name, data)
+	return body, err
+}
+
+// RenderTemplatePreview renders the named email template with its sample data
+func RenderTemplatePreview(name string) (string, error) {
+	data, ok := templateSamples[name]
+	if !ok {
+		return "", ErrTemplateNotFound
+	}
+	return RenderTemplate(name, data)
+}
@@ -0,0 +1,80 @@
+package mailer_test
+
+import (
+	"os"
+	"path/filepath"
+	"spsyncpro_api/pkg/mailer"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateRegistry_Render(t *testing.T) {
+	link := "http://localhost:8080/api/v1/account/reset-password?token=abc&next=1"
+
+	t.Run("should render the embedded template with the data", func(t *testing.T) {
+		registry := mailer.NewTemplateRegistry("")
+
+		subject, body, err := registry.Render(mailer.TemplatePasswordReset, mailer.PasswordResetData{Link: link})
+		assert.NoError(t, err)
+		assert.Equal(t, "Password Reset", subject)
+		assert.Contains(t, body, `<a href="http://localhost:8080/api/v1/account/reset-password?token=abc&amp;next=1">`)
+		assert.NotContains(t, body, "{{")
+	})
+
+	t.Run("should render every known template", func(t *testing.T) {
+		for _, name := range []string{
+			mailer.TemplatePasswordReset,
+			mailer.TemplatePendingAction,
+			mailer.TemplateInactivity,
+			mailer.TemplateVerifyEmail,
+			mailer.TemplateAccountLocked,
+			mailer.TemplateChangeEmail,
+			mailer.TemplateAccountExists,
+		} {
+			html, err := mailer.RenderTemplatePreview(name)
+			assert.NoError(t, err, name)
+			assert.Contains(t, html, "<html>", name)
+			assert.NotContains(t, html, "subject", name)
+		}
+	})
+
+	t.Run("should prefer a template from the directory", func(t *testing.T) {
+		dir := t.TempDir()
+		custom := `{{ define "subject" }}Réinitialisation{{ end }}<p><a href="{{ .Link }}">Réinitialiser</a></p>`
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "password_reset.html"), []byte(custom), 0o600))
+
+		registry := mailer.NewTemplateRegistry(dir)
+
+		subject, body, err := registry.Render(mailer.TemplatePasswordReset, mailer.PasswordResetData{Link: link})
+		assert.NoError(t, err)
+		assert.Equal(t, "Réinitialisation", subject)
+		assert.Equal(t, `<p><a href="http://localhost:8080/api/v1/account/reset-password?token=abc&amp;next=1">Réinitialiser</a></p>`, body)
+
+		// templates missing from the directory fall back to the embedded ones
+		subject, body, err = registry.Render(mailer.TemplateVerifyEmail, mailer.VerifyEmailData{Link: link})
+		assert.NoError(t, err)
+		assert.Equal(t, "Verify Your Email", subject)
+		assert.Contains(t, body, "Click here to verify your email address")
+	})
+
+	t.Run("should reject a template without a subject", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "password_reset.html"), []byte(`<p>{{ .Link }}</p>`), 0o600))
+
+		_, _, err := mailer.NewTemplateRegistry(dir).Render(mailer.TemplatePasswordReset, mailer.PasswordResetData{Link: link})
+		assert.ErrorContains(t, err, "defines no subject")
+	})
+
+	t.Run("should only serve known templates", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.html"), []byte(`{{ define "subject" }}x{{ end }}`), 0o600))
+
+		registry := mailer.NewTemplateRegistry(dir)
+
+		_, _, err := registry.Render("other", nil)
+		assert.ErrorIs(t, err, mailer.ErrTemplateNotFound)
+		_, _, err = registry.Render("../templates/password_reset", nil)
+		assert.ErrorIs(t, err, mailer.ErrTemplateNotFound)
+	})
+}
@@ -0,0 +1,88 @@
+package msgraphapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrGraphUnauthorized  = errors.New("graph request is not authorized")
+	ErrGraphForbidden     = errors.New("graph request is forbidden")
+	ErrGraphNotFound      = errors.New("graph resource not found")
+	ErrGraphThrottled     = errors.New("graph request was throttled")
+	ErrGraphRequestFailed = errors.New("graph request failed")
+
+	// ErrMsGraphAuthFailed is returned when Azure AD refuses to issue a token,
+	// e.g. for an invalid client secret or an unknown tenant
+	ErrMsGraphAuthFailed = errors.New("graph authentication failed")
+
+	// ErrUnexpectedResponse is returned for a response that is not JSON, e.g.
+	// the html page of a captive proxy in front of Azure AD
+	ErrUnexpectedResponse = errors.New("graph returned an unexpected response")
+
+	ErrTooManyPages = errors.New("graph collection has too many pages")
+)
+
+// GraphError is a non-2xx response from the Graph API, it unwraps to one of
+// the classified errors above so callers can match with errors.Is
+type GraphError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	// RetryAfter is the delay requested by a throttled response, zero when not sent
+	RetryAfter time.Duration
+}
+
+func (e *GraphError) Error() string {
+	return fmt.Sprintf("graph api error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *GraphError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrGraphUnauthorized
+	case http.StatusForbidden:
+		return ErrGraphForbidden
+	case http.StatusNotFound:
+		return ErrGraphNotFound
+	case http.StatusTooManyRequests:
+		return ErrGraphThrottled
+	default:
+		return ErrGraphRequestFailed
+	}
+}
+
+// AuthError is a 4xx response from the Azure AD token endpoint other than a
+// throttled one, Code and Description are the OAuth error and
+// error_description. It unwraps to ErrMsGraphAuthFailed.
+type AuthError struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("graph authentication error %d %s: %s", e.StatusCode, e.Code, e.Description)
+}
+
+func (e *AuthError) Unwrap() error {
+	return ErrMsGraphAuthFailed
+}
+
+// UnexpectedResponseError is a response that is not JSON, Snippet is the
+// start of its body. It unwraps to ErrUnexpectedResponse.
+type UnexpectedResponseError struct {
+	StatusCode  int
+	ContentType string
+	Snippet     string
+}
+
+func (e *UnexpectedResponseError) Error() string {
+	return fmt.Sprintf("graph returned %d %q instead of json: %s", e.StatusCode, e.ContentType, e.Snippet)
+}
+
+func (e *UnexpectedResponseError) Unwrap() error {
+	return ErrUnexpectedResponse
+}
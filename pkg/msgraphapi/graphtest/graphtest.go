@@ -0,0 +1,157 @@
+// Package graphtest serves a stand-in for the Azure AD token endpoint and the
+// Graph API, tests pick the scenario it answers with and count the requests
+// it received.
+package graphtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/msgraphapi"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Scenario selects how the server answers token and Graph requests
+type Scenario string
+
+const (
+	// ScenarioAuthorized issues tokens and answers Graph requests with 200
+	ScenarioAuthorized Scenario = "authorized"
+	// ScenarioUnauthorized refuses to issue a token, like an invalid client secret
+	ScenarioUnauthorized Scenario = "unauthorized"
+	// ScenarioThrottled issues tokens and answers Graph requests with 429 and
+	// a Retry-After longer than any retry policy waits for
+	ScenarioThrottled Scenario = "throttled"
+	// ScenarioConsentMissing issues tokens and answers Graph requests with
+	// 403, like an app registration without admin consent
+	ScenarioConsentMissing Scenario = "consent_missing"
+)
+
+// ThrottledRetryAfter is the Retry-After sent in ScenarioThrottled, in seconds
+const ThrottledRetryAfter = "3600"
+
+// AccessToken is the token issued by the server
+const AccessToken = "graphtest-access-token"
+
+// Server is a running stand-in for Azure AD and Graph, it answers with
+// ScenarioAuthorized until SetScenario is called
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	scenario      Scenario
+	tokenRequests int
+	graphRequests int
+}
+
+// NewServer starts a server that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{scenario: ScenarioAuthorized}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /{tenant}/oauth2/token", s.token)
+	mux.HandleFunc("/", s.graph)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// BaseURL is the Graph host to set as MsGraphApiConfig.BaseURL
+func (s *Server) BaseURL() string {
+	return s.URL
+}
+
+// AuthorityURL is the Azure AD authority to set as MsGraphApiConfig.AuthorityURL
+func (s *Server) AuthorityURL() string {
+	return s.URL
+}
+
+// UseAsDefault points msgraphapi.DefaultBaseURL and DefaultAuthorityURL at the
+// server until the test ends, for services built by the code under test
+func (s *Server) UseAsDefault(t testing.TB) {
+	baseURL, authorityURL := msgraphapi.DefaultBaseURL, msgraphapi.DefaultAuthorityURL
+	t.Cleanup(func() {
+		msgraphapi.DefaultBaseURL, msgraphapi.DefaultAuthorityURL = baseURL, authorityURL
+	})
+	msgraphapi.DefaultBaseURL, msgraphapi.DefaultAuthorityURL = s.BaseURL(), s.AuthorityURL()
+}
+
+func (s *Server) SetScenario(scenario Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenario = scenario
+}
+
+// TokenRequests is the number of token requests received
+func (s *Server) TokenRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokenRequests
+}
+
+// GraphRequests is the number of Graph requests received
+func (s *Server) GraphRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.graphRequests
+}
+
+// AssertRequests asserts the number of token and Graph requests received
+func (s *Server) AssertRequests(t testing.TB, tokenRequests, graphRequests int) bool {
+	t.Helper()
+	return assert.Equal(t, tokenRequests, s.TokenRequests(), "token requests") &&
+		assert.Equal(t, graphRequests, s.GraphRequests(), "graph requests")
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.tokenRequests++
+	scenario := s.scenario
+	s.mu.Unlock()
+
+	if scenario == ScenarioUnauthorized {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{
+			"error":             "invalid_client",
+			"error_description": "AADSTS7000215: Invalid client secret provided.",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"access_token": AccessToken, "expires_in": 3600})
+}
+
+func (s *Server) graph(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.graphRequests++
+	scenario := s.scenario
+	s.mu.Unlock()
+
+	switch scenario {
+	case ScenarioThrottled:
+		w.Header().Set("Retry-After", ThrottledRetryAfter)
+		writeJSON(w, http.StatusTooManyRequests, graphError("TooManyRequests", "Too many requests"))
+	case ScenarioConsentMissing:
+		writeJSON(w, http.StatusForbidden, graphError("Authorization_RequestDenied", "Insufficient privileges to complete the operation."))
+	case ScenarioUnauthorized:
+		writeJSON(w, http.StatusUnauthorized, graphError("InvalidAuthenticationToken", "Access token is empty."))
+	default:
+		if r.Header.Get("Authorization") != "Bearer "+AccessToken {
+			writeJSON(w, http.StatusUnauthorized, graphError("InvalidAuthenticationToken", "Access token validation failure."))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"value": []any{}})
+	}
+}
+
+func graphError(code, message string) map[string]any {
+	return map[string]any{"error": map[string]any{"code": code, "message": message}}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
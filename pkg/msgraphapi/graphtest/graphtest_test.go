@@ -0,0 +1,86 @@
+package graphtest_test
+
+import (
+	"context"
+	"spsyncpro_api/pkg/msgraphapi"
+	"spsyncpro_api/pkg/msgraphapi/graphtest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	newService := func(server *graphtest.Server) *msgraphapi.MsGraphApiService {
+		return msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+			ClientID:     "client",
+			TenantID:     "tenant",
+			ClientSecret: "secret",
+			Limiter:      msgraphapi.NewTenantLimiter(0),
+			TokenCache:   msgraphapi.NewTokenCache(),
+			RetryPolicy:  &msgraphapi.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second},
+		}).WithBaseURL(server.BaseURL(), server.AuthorityURL())
+	}
+
+	t.Run("should authorize by default", func(t *testing.T) {
+		server := graphtest.NewServer(t)
+
+		authorized, err := newService(server).CheckAuthorized(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, authorized)
+		server.AssertRequests(t, 1, 1)
+	})
+
+	t.Run("should refuse a token when unauthorized", func(t *testing.T) {
+		server := graphtest.NewServer(t)
+		server.SetScenario(graphtest.ScenarioUnauthorized)
+
+		_, err := newService(server).CheckAuthorized(context.Background())
+		assert.ErrorIs(t, err, msgraphapi.ErrMsGraphAuthFailed)
+		server.AssertRequests(t, 1, 0)
+	})
+
+	t.Run("should throttle graph requests", func(t *testing.T) {
+		server := graphtest.NewServer(t)
+		server.SetScenario(graphtest.ScenarioThrottled)
+
+		_, err := newService(server).ListSites(context.Background())
+		assert.ErrorIs(t, err, msgraphapi.ErrGraphThrottled)
+
+		var graphErr *msgraphapi.GraphError
+		assert.ErrorAs(t, err, &graphErr)
+		assert.Equal(t, time.Hour, graphErr.RetryAfter)
+		// the retry after is longer than the policy waits, so no retries
+		server.AssertRequests(t, 1, 1)
+	})
+
+	t.Run("should forbid graph requests without consent", func(t *testing.T) {
+		server := graphtest.NewServer(t)
+		server.SetScenario(graphtest.ScenarioConsentMissing)
+
+		authorized, err := newService(server).CheckAuthorized(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, authorized)
+
+		_, err = newService(server).ListSites(context.Background())
+		assert.ErrorIs(t, err, msgraphapi.ErrGraphForbidden)
+		server.AssertRequests(t, 2, 2)
+	})
+
+	t.Run("should serve services built with the defaults", func(t *testing.T) {
+		server := graphtest.NewServer(t)
+		server.UseAsDefault(t)
+
+		service := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+			ClientID:     "client",
+			TenantID:     "tenant",
+			ClientSecret: "secret",
+			Limiter:      msgraphapi.NewTenantLimiter(0),
+			TokenCache:   msgraphapi.NewTokenCache(),
+		})
+		authorized, err := service.CheckAuthorized(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, authorized)
+		server.AssertRequests(t, 1, 1)
+	})
+}
@@ -0,0 +1,88 @@
+package msgraphapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// TenantLimiter serializes Graph token requests per tenant and spaces them by
+// a minimum interval, a throttled tenant never delays requests for another
+type TenantLimiter struct {
+	mu          sync.Mutex
+	minInterval time.Duration
+	tenants     map[string]*tenantGate
+}
+
+// tenantGate admits one request at a time, notBefore is only read or written
+// while holding the slot
+type tenantGate struct {
+	slot      chan struct{}
+	notBefore time.Time
+}
+
+// DefaultTenantLimiter is shared by services created without a Limiter so
+// every check for a tenant goes through the same gate
+var DefaultTenantLimiter = NewTenantLimiter(0)
+
+func NewTenantLimiter(minInterval time.Duration) *TenantLimiter {
+	return &TenantLimiter{
+		minInterval: minInterval,
+		tenants:     make(map[string]*tenantGate),
+	}
+}
+
+// SetMinInterval changes the spacing between requests for the same tenant
+func (l *TenantLimiter) SetMinInterval(minInterval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.minInterval = minInterval
+}
+
+func (l *TenantLimiter) gate(tenantID string) (*tenantGate, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	g, ok := l.tenants[tenantID]
+	if !ok {
+		g = &tenantGate{slot: make(chan struct{}, 1)}
+		l.tenants[tenantID] = g
+	}
+	return g, l.minInterval
+}
+
+// Do runs fn once no other request for the tenant is in flight and the
+// tenant's interval has passed. A throttled response from fn holds back the
+// next request for the tenant until its Retry-After has elapsed.
+func (l *TenantLimiter) Do(ctx context.Context, tenantID string, fn func() error) error {
+	g, minInterval := l.gate(tenantID)
+
+	select {
+	case g.slot <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-g.slot }()
+
+	if wait := time.Until(g.notBefore); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	err := fn()
+
+	next := minInterval
+	var graphErr *GraphError
+	if errors.As(err, &graphErr) && graphErr.RetryAfter > next {
+		next = graphErr.RetryAfter
+	}
+	g.notBefore = time.Now().Add(next)
+
+	return err
+}
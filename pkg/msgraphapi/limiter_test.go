@@ -0,0 +1,139 @@
+package msgraphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/msgraphapi"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantLimiter(t *testing.T) {
+
+	t.Run("should serialize calls for the same tenant", func(t *testing.T) {
+		limiter := msgraphapi.NewTenantLimiter(0)
+
+		var active atomic.Int32
+		var overlapped atomic.Bool
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := limiter.Do(context.Background(), "tenant", func() error {
+					if active.Add(1) > 1 {
+						overlapped.Store(true)
+					}
+					time.Sleep(10 * time.Millisecond)
+					active.Add(-1)
+					return nil
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		assert.False(t, overlapped.Load())
+	})
+
+	t.Run("should run calls for different tenants concurrently", func(t *testing.T) {
+		limiter := msgraphapi.NewTenantLimiter(time.Hour)
+
+		var entered sync.WaitGroup
+		entered.Add(2)
+		bothEntered := make(chan struct{})
+		go func() {
+			entered.Wait()
+			close(bothEntered)
+		}()
+
+		var wg sync.WaitGroup
+		for _, tenant := range []string{"tenant-a", "tenant-b"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := limiter.Do(context.Background(), tenant, func() error {
+					entered.Done()
+					select {
+					case <-bothEntered:
+					case <-time.After(time.Second):
+						t.Errorf("%s ran alone, calls were serialized across tenants", tenant)
+					}
+					return nil
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("should space calls for the same tenant by the minimum interval", func(t *testing.T) {
+		limiter := msgraphapi.NewTenantLimiter(50 * time.Millisecond)
+
+		assert.NoError(t, limiter.Do(context.Background(), "tenant", func() error { return nil }))
+
+		start := time.Now()
+		assert.NoError(t, limiter.Do(context.Background(), "tenant", func() error { return nil }))
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+		start = time.Now()
+		assert.NoError(t, limiter.Do(context.Background(), "other", func() error { return nil }))
+		assert.Less(t, time.Since(start), 40*time.Millisecond)
+	})
+
+	t.Run("should hold back a throttled tenant until retry after", func(t *testing.T) {
+		limiter := msgraphapi.NewTenantLimiter(0)
+
+		err := limiter.Do(context.Background(), "tenant", func() error {
+			return &msgraphapi.GraphError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}
+		})
+		assert.ErrorIs(t, err, msgraphapi.ErrGraphThrottled)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err = limiter.Do(ctx, "tenant", func() error {
+			t.Error("throttled tenant should not be called before retry after")
+			return nil
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		assert.NoError(t, limiter.Do(context.Background(), "other", func() error { return nil }))
+	})
+}
+
+func TestGetAccessToken_Throttled(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tenant/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]string{"code": "TooManyRequests", "message": "Too many requests"},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	service := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+		ClientID:     "client",
+		TenantID:     "tenant",
+		ClientSecret: "secret",
+		AuthorityURL: server.URL,
+		Limiter:      msgraphapi.NewTenantLimiter(0),
+		TokenCache:   msgraphapi.NewTokenCache(),
+		RetryPolicy:  &msgraphapi.RetryPolicy{},
+	})
+
+	token, err := service.GetAccessToken(context.Background())
+	assert.Empty(t, token)
+	assert.ErrorIs(t, err, msgraphapi.ErrGraphThrottled)
+
+	var graphErr *msgraphapi.GraphError
+	assert.ErrorAs(t, err, &graphErr)
+	assert.Equal(t, 30*time.Second, graphErr.RetryAfter)
+}
@@ -0,0 +1,118 @@
+package msgraphapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy retries throttled and 5xx responses with exponential backoff,
+// a Retry-After sent with the response replaces the backoff delay
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt, 0 disables them
+	MaxRetries int
+	// BaseDelay doubles with every retry up to MaxDelay
+	BaseDelay time.Duration
+	// MaxDelay also bounds Retry-After, a longer one is returned to the caller
+	// instead of holding the request
+	MaxDelay time.Duration
+	// Sleep waits between attempts, time based when nil
+	Sleep func(ctx context.Context, delay time.Duration) error
+}
+
+// DefaultRetryPolicy is used by services created without a RetryPolicy
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+// delay returns how long to wait before retrying the response of the given
+// attempt, false when it should not be retried
+func (p *RetryPolicy) delay(attempt int, response *http.Response) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || !isRetryable(response.StatusCode) {
+		return 0, false
+	}
+
+	if retryAfter := parseRetryAfter(response.Header.Get("Retry-After")); retryAfter > 0 {
+		return retryAfter, retryAfter <= p.MaxDelay
+	}
+
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, true
+}
+
+func (p *RetryPolicy) sleep(ctx context.Context, delay time.Duration) error {
+	if p.Sleep != nil {
+		return p.Sleep(ctx, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func isRetryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// parseRetryAfter reads Retry-After as seconds or an http date, zero when
+// it is missing or already passed
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// do sends the request, retrying it under the configured policy. The last
+// response is returned as it is once the retries are exhausted.
+func (s *MsGraphApiService) do(request *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		response, err := s.httpClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+
+		delay, retry := s.Config.RetryPolicy.delay(attempt, response)
+		if !retry {
+			return response, nil
+		}
+
+		// drain the body so the connection is reused for the retry
+		_, _ = io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+
+		err = s.Config.RetryPolicy.sleep(request.Context(), delay)
+		if err != nil {
+			return nil, err
+		}
+
+		if request.GetBody != nil {
+			request.Body, err = request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package msgraphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/msgraphapi"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordSleeps returns a retry policy that records its delays instead of waiting
+func recordSleeps(delays *[]time.Duration) *msgraphapi.RetryPolicy {
+	return &msgraphapi.RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  100 * time.Millisecond,
+		MaxDelay:   time.Minute,
+		Sleep: func(ctx context.Context, delay time.Duration) error {
+			*delays = append(*delays, delay)
+			return nil
+		},
+	}
+}
+
+// newFlakyServer fails the sites endpoint with failures before answering it
+func newFlakyServer(requests *atomic.Int32, failures []func(w http.ResponseWriter)) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tenant/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "access_token", "expires_in": 3600})
+	})
+	mux.HandleFunc("GET /v1.0/sites/{id}", func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(failures) {
+			failures[n-1](w)
+			return
+		}
+		json.NewEncoder(w).Encode(msgraphapi.Site{ID: r.PathValue("id")})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	newService := func(server *httptest.Server, policy *msgraphapi.RetryPolicy) *msgraphapi.MsGraphApiService {
+		return msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+			ClientID:     "client",
+			TenantID:     "tenant",
+			ClientSecret: "secret",
+			BaseURL:      server.URL,
+			AuthorityURL: server.URL,
+			Limiter:      msgraphapi.NewTenantLimiter(0),
+			TokenCache:   msgraphapi.NewTokenCache(),
+			RetryPolicy:  policy,
+		})
+	}
+
+	unavailable := func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) }
+	throttled := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+
+	t.Run("should succeed after two transient failures", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newFlakyServer(&requests, []func(http.ResponseWriter){unavailable, throttled})
+		defer server.Close()
+
+		var delays []time.Duration
+		site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), newService(server, recordSleeps(&delays)), "/sites/site-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "site-1", site.ID)
+
+		assert.Equal(t, int32(3), requests.Load())
+		// the backoff for the 503, then the Retry-After of the 429
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 2 * time.Second}, delays)
+	})
+
+	t.Run("should return the last failure once the retries are exhausted", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newFlakyServer(&requests, []func(http.ResponseWriter){unavailable, unavailable, unavailable, unavailable, unavailable})
+		defer server.Close()
+
+		var delays []time.Duration
+		site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), newService(server, recordSleeps(&delays)), "/sites/site-1")
+		assert.Nil(t, site)
+		assert.ErrorIs(t, err, msgraphapi.ErrGraphRequestFailed)
+
+		var graphErr *msgraphapi.GraphError
+		assert.ErrorAs(t, err, &graphErr)
+		assert.Equal(t, http.StatusServiceUnavailable, graphErr.StatusCode)
+
+		assert.Equal(t, int32(4), requests.Load())
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}, delays)
+	})
+
+	t.Run("should not retry a Retry-After beyond the max delay", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newFlakyServer(&requests, []func(http.ResponseWriter){throttled})
+		defer server.Close()
+
+		var delays []time.Duration
+		policy := recordSleeps(&delays)
+		policy.MaxDelay = time.Second
+
+		_, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), newService(server, policy), "/sites/site-1")
+		assert.ErrorIs(t, err, msgraphapi.ErrGraphThrottled)
+		assert.Equal(t, int32(1), requests.Load())
+		assert.Empty(t, delays)
+	})
+
+	t.Run("should not retry a client error", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newFlakyServer(&requests, []func(http.ResponseWriter){
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+		})
+		defer server.Close()
+
+		var delays []time.Duration
+		_, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), newService(server, recordSleeps(&delays)), "/sites/site-1")
+		assert.ErrorIs(t, err, msgraphapi.ErrGraphNotFound)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("should resend the token request body on a retry", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "client", r.PostForm.Get("client_id"))
+			if requests.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "access_token", "expires_in": 3600})
+		}))
+		defer server.Close()
+
+		var delays []time.Duration
+		token, err := newService(server, recordSleeps(&delays)).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "access_token", token)
+		assert.Equal(t, int32(2), requests.Load())
+	})
+}
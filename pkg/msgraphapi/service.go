@@ -4,30 +4,143 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"spsyncpro_api/pkg/utils"
+	"strings"
+	"time"
 )
 
 type MsGraphApiConfig struct {
 	ClientID     string `json:"client_id"`
 	TenantID     string `json:"tenant_id"`
 	ClientSecret string `json:"client_secret"`
+
+	// BaseURL and AuthorityURL default to DefaultBaseURL and
+	// DefaultAuthorityURL, BaseURL is the host only, the API version is
+	// appended to it
+	BaseURL      string `json:"-"`
+	AuthorityURL string `json:"-"`
+
+	// APIVersion selects the Graph version segment, DefaultAPIVersion when empty
+	APIVersion string `json:"-"`
+
+	// Limiter throttles token requests per tenant, DefaultTenantLimiter when nil
+	Limiter *TenantLimiter `json:"-"`
+
+	// TokenCache reuses access tokens until they expire, DefaultTokenCache when nil
+	TokenCache *TokenCache `json:"-"`
+
+	// Timeout bounds each attempt of a token or Graph request,
+	// DefaultRequestTimeout when zero
+	Timeout time.Duration `json:"-"`
+
+	// RetryPolicy retries throttled and 5xx responses, DefaultRetryPolicy when nil
+	RetryPolicy *RetryPolicy `json:"-"`
+
+	// MaxPages bounds the pages read for one collection, DefaultMaxPages when zero
+	MaxPages int `json:"-"`
+
+	// Transport sends the token and Graph requests, http.DefaultTransport when nil
+	Transport http.RoundTripper `json:"-"`
 }
 
 type MsGraphApiService struct {
-	Config      MsGraphApiConfig
-	httpClient  *http.Client
-	accessToken string
+	Config     MsGraphApiConfig
+	httpClient *http.Client
 }
 
 func NewMsGraphApiService(config MsGraphApiConfig) *MsGraphApiService {
+	if config.Limiter == nil {
+		config.Limiter = DefaultTenantLimiter
+	}
+	if config.APIVersion == "" {
+		config.APIVersion = DefaultAPIVersion
+	}
+	if config.TokenCache == nil {
+		config.TokenCache = DefaultTokenCache
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultRequestTimeout
+	}
+	if config.RetryPolicy == nil {
+		config.RetryPolicy = DefaultRetryPolicy
+	}
+	if config.MaxPages <= 0 {
+		config.MaxPages = DefaultMaxPages
+	}
 	return &MsGraphApiService{
 		Config:     config,
-		httpClient: &http.Client{},
+		httpClient: utils.NewHTTPClient(config.Timeout, config.Transport),
+	}
+}
+
+const (
+	GRAPH_API_HOST      = "https://graph.microsoft.com"
+	GRAPH_API_VERSION   = "v1.0"
+	GRAPH_API_BETA      = "beta"
+	GRAPH_API_URL       = GRAPH_API_HOST + "/" + GRAPH_API_VERSION
+	GRAPH_AUTHORITY_URL = "https://login.microsoftonline.com"
+)
+
+// DefaultAPIVersion is used by services created without an APIVersion
+var DefaultAPIVersion = GRAPH_API_VERSION
+
+// DefaultBaseURL and DefaultAuthorityURL are used by services created without
+// their own, pointing them elsewhere covers national clouds and test servers
+var (
+	DefaultBaseURL      = GRAPH_API_HOST
+	DefaultAuthorityURL = GRAPH_AUTHORITY_URL
+)
+
+// DefaultMaxPages bounds the pages followed through @odata.nextLink, a
+// collection that keeps linking to more pages fails instead of looping
+var DefaultMaxPages = 100
+
+// DefaultRequestTimeout bounds every token and Graph request of services
+// created without a Timeout, a hanging Azure AD or Graph call fails instead
+var DefaultRequestTimeout = 10 * time.Second
+
+// WithAPIVersion returns a copy of the service that calls the given Graph
+// version, for single calls that need e.g. the beta endpoint
+func (s *MsGraphApiService) WithAPIVersion(version string) *MsGraphApiService {
+	service := *s
+	service.Config.APIVersion = version
+	return &service
+}
+
+// WithBaseURL returns a copy of the service that calls the Graph host at
+// baseURL and acquires tokens from authorityURL, e.g. a national cloud or the
+// graphtest server
+func (s *MsGraphApiService) WithBaseURL(baseURL, authorityURL string) *MsGraphApiService {
+	service := *s
+	service.Config.BaseURL = baseURL
+	service.Config.AuthorityURL = authorityURL
+	return &service
+}
+
+func (s *MsGraphApiService) baseURL() string {
+	host := strings.TrimSuffix(DefaultBaseURL, "/")
+	if s.Config.BaseURL != "" {
+		host = strings.TrimSuffix(s.Config.BaseURL, "/")
 	}
+
+	version := s.Config.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+
+	return host + "/" + version
 }
 
-const GRAPH_API_URL = "https://graph.microsoft.com/v1.0"
+func (s *MsGraphApiService) authorityURL() string {
+	if s.Config.AuthorityURL != "" {
+		return s.Config.AuthorityURL
+	}
+	return DefaultAuthorityURL
+}
 
 func (s *MsGraphApiService) CheckAuthorized(ctx context.Context) (bool, error) {
 	accessToken, err := s.GetAccessToken(ctx)
@@ -38,8 +151,53 @@ func (s *MsGraphApiService) CheckAuthorized(ctx context.Context) (bool, error) {
 	return s.ValidateToken(ctx, accessToken)
 }
 
+// GetAccessToken returns a cached token for the credentials or acquires a
+// client credentials token, requests for the same tenant are throttled
+// through the configured limiter
 func (s *MsGraphApiService) GetAccessToken(ctx context.Context) (string, error) {
-	tokenUrl := fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/token", s.Config.TenantID)
+	key := tokenCacheKey(s.authorityURL(), s.Config)
+	if accessToken, ok := s.Config.TokenCache.Get(key); ok {
+		return accessToken, nil
+	}
+
+	var accessToken string
+	err := s.Config.Limiter.Do(ctx, s.Config.TenantID, func() error {
+		// a request queued behind another one for the tenant finds its token cached
+		if cached, ok := s.Config.TokenCache.Get(key); ok {
+			accessToken = cached
+			return nil
+		}
+
+		token, expiresIn, err := s.requestAccessToken(ctx)
+		if err != nil {
+			return err
+		}
+		s.Config.TokenCache.Set(key, token, expiresIn)
+		accessToken = token
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return accessToken, nil
+}
+
+// RefreshAccessToken drops the cached token for the credentials and acquires
+// a new one, for a token that went stale before its expiry
+func (s *MsGraphApiService) RefreshAccessToken(ctx context.Context) (string, error) {
+	s.DiscardAccessToken()
+	return s.GetAccessToken(ctx)
+}
+
+// DiscardAccessToken drops the cached token for the credentials, for a secret
+// that was replaced and must not be used again
+func (s *MsGraphApiService) DiscardAccessToken() {
+	s.Config.TokenCache.Delete(tokenCacheKey(s.authorityURL(), s.Config))
+}
+
+func (s *MsGraphApiService) requestAccessToken(ctx context.Context) (string, time.Duration, error) {
+	tokenUrl := fmt.Sprintf("%s/%s/oauth2/token", s.authorityURL(), s.Config.TenantID)
 
 	formData := url.Values{
 		"grant_type":    {"client_credentials"},
@@ -48,12 +206,31 @@ func (s *MsGraphApiService) GetAccessToken(ctx context.Context) (string, error)
 		"scope":         {"https://graph.microsoft.com/.default"},
 	}
 
-	response, err := http.PostForm(tokenUrl, formData)
+	request, err := http.NewRequestWithContext(ctx, "POST", tokenUrl, strings.NewReader(formData.Encode()))
 	if err != nil {
-		return "", err
+		return "", 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := s.do(request)
+	if err != nil {
+		return "", 0, err
 	}
 	defer response.Body.Close()
 
+	// throttling and server errors are not authentication failures, the
+	// limiter needs the Retry-After of a throttled response
+	if isRetryable(response.StatusCode) {
+		return "", 0, parseGraphError(response)
+	}
+	if !isJSONResponse(response) {
+		return "", 0, parseUnexpectedResponse(response)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", 0, parseAuthError(response)
+	}
+
 	var result struct {
 		AccessToken string `json:"access_token"`
 		ExpiresIn   int    `json:"expires_in"`
@@ -61,25 +238,26 @@ func (s *MsGraphApiService) GetAccessToken(ctx context.Context) (string, error)
 
 	err = json.NewDecoder(response.Body).Decode(&result)
 	if err != nil {
-		return "", err
+		return "", 0, err
+	}
+	if result.AccessToken == "" {
+		return "", 0, &AuthError{StatusCode: response.StatusCode, Description: "token response has no access_token"}
 	}
 
-	s.accessToken = result.AccessToken
-
-	return s.accessToken, nil
+	return result.AccessToken, time.Duration(result.ExpiresIn) * time.Second, nil
 }
 
 func (s *MsGraphApiService) ValidateToken(ctx context.Context, token string) (bool, error) {
-	siteUrl := fmt.Sprintf("%s/sites/root", GRAPH_API_URL)
+	siteUrl := fmt.Sprintf("%s/sites/root", s.baseURL())
 
 	request, err := http.NewRequestWithContext(ctx, "GET", siteUrl, nil)
 	if err != nil {
 		return false, err
 	}
 
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	response, err := s.httpClient.Do(request)
+	response, err := s.do(request)
 	if err != nil {
 		return false, err
 	}
@@ -93,3 +271,144 @@ type MsGraphResponse[T any] struct {
 	Value   []T    `json:"value"`
 	Next    string `json:"@odata.nextLink"`
 }
+
+// GetResource fetches a single Graph resource at path, relative to the API
+// base url, and decodes it into T. Non-2xx responses are returned as *GraphError.
+func GetResource[T any](ctx context.Context, s *MsGraphApiService, path string) (*T, error) {
+	var resource T
+	err := s.getJSON(ctx, s.baseURL()+path, &resource)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resource, nil
+}
+
+// ListResources fetches the collection at path, relative to the API base url,
+// following @odata.nextLink until every page is read. More than MaxPages
+// pages fail with ErrTooManyPages.
+func ListResources[T any](ctx context.Context, s *MsGraphApiService, path string) ([]T, error) {
+	var resources []T
+
+	pageURL := s.baseURL() + path
+	for pages := 0; pageURL != ""; pages++ {
+		if pages == s.Config.MaxPages {
+			return nil, fmt.Errorf("%w: stopped after %d pages of %s", ErrTooManyPages, pages, path)
+		}
+
+		var page MsGraphResponse[T]
+		err := s.getJSON(ctx, pageURL, &page)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, page.Value...)
+
+		// the access token is sent along, never follow a link off the Graph host
+		if page.Next != "" && !strings.HasPrefix(page.Next, s.baseURL()+"/") {
+			return nil, fmt.Errorf("%w: next link %q is not on the graph host", ErrUnexpectedResponse, page.Next)
+		}
+		pageURL = page.Next
+	}
+
+	return resources, nil
+}
+
+// ListSites returns every SharePoint site the app can see
+func (s *MsGraphApiService) ListSites(ctx context.Context) ([]Site, error) {
+	return ListResources[Site](ctx, s, "/sites?search=*")
+}
+
+// getJSON sends an authorized GET to url and decodes the response into out
+func (s *MsGraphApiService) getJSON(ctx context.Context, url string, out any) error {
+	accessToken, err := s.GetAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	response, err := s.do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return parseGraphError(response)
+	}
+
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// parseGraphError reads the Graph error envelope, the body is optional
+func parseGraphError(response *http.Response) error {
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(response.Body).Decode(&body)
+
+	graphErr := &GraphError{
+		StatusCode: response.StatusCode,
+		Code:       body.Error.Code,
+		Message:    body.Error.Message,
+	}
+
+	graphErr.RetryAfter = parseRetryAfter(response.Header.Get("Retry-After"))
+
+	return graphErr
+}
+
+// parseAuthError reads the OAuth error response of the token endpoint, the
+// body is optional
+func parseAuthError(response *http.Response) error {
+	var body struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.NewDecoder(response.Body).Decode(&body)
+
+	return &AuthError{
+		StatusCode:  response.StatusCode,
+		Code:        body.Error,
+		Description: body.ErrorDescription,
+	}
+}
+
+// isJSONResponse reports whether the response declares a JSON body
+func isJSONResponse(response *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// unexpectedSnippetLen bounds the body quoted in an UnexpectedResponseError
+const unexpectedSnippetLen = 200
+
+// parseUnexpectedResponse quotes the start of a body that is not JSON, with
+// its whitespace collapsed so an html page stays on one line
+func parseUnexpectedResponse(response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, unexpectedSnippetLen))
+
+	return &UnexpectedResponseError{
+		StatusCode:  response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+		Snippet:     strings.Join(strings.Fields(strings.ToValidUTF8(string(body), "")), " "),
+	}
+}
+
+type Site struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	WebURL      string `json:"webUrl"`
+}
@@ -0,0 +1,468 @@
+package msgraphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/msgraphapi"
+	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newGraphTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tenant/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "access_token", "expires_in": 3600})
+	})
+	mux.HandleFunc("GET /v1.0/sites/{id}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer access_token", r.Header.Get("Authorization"))
+
+		if r.PathValue("id") != "site-1" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error": map[string]string{"code": "itemNotFound", "message": "The requested site was not found"},
+			})
+			return
+		}
+
+		json.NewEncoder(w).Encode(msgraphapi.Site{
+			ID:          "site-1",
+			Name:        "team",
+			DisplayName: "Team Site",
+			WebURL:      "https://contoso.sharepoint.com/sites/team",
+		})
+	})
+	mux.HandleFunc("GET /beta/sites/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(msgraphapi.Site{ID: r.PathValue("id"), DisplayName: "Beta Site"})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestGetResource(t *testing.T) {
+	server := newGraphTestServer(t)
+	defer server.Close()
+
+	service := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+		ClientID:     "client",
+		TenantID:     "tenant",
+		ClientSecret: "secret",
+		BaseURL:      server.URL,
+		AuthorityURL: server.URL,
+	})
+
+	t.Run("should fetch a single site by id", func(t *testing.T) {
+		site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), service, "/sites/site-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "site-1", site.ID)
+		assert.Equal(t, "Team Site", site.DisplayName)
+	})
+
+	t.Run("should classify a missing site as not found", func(t *testing.T) {
+		site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), service, "/sites/missing")
+		assert.Nil(t, site)
+		assert.ErrorIs(t, err, msgraphapi.ErrGraphNotFound)
+
+		var graphErr *msgraphapi.GraphError
+		assert.ErrorAs(t, err, &graphErr)
+		assert.Equal(t, "itemNotFound", graphErr.Code)
+	})
+}
+
+func TestAPIVersion(t *testing.T) {
+	server := newGraphTestServer(t)
+	defer server.Close()
+
+	config := msgraphapi.MsGraphApiConfig{
+		ClientID:     "client",
+		TenantID:     "tenant",
+		ClientSecret: "secret",
+		BaseURL:      server.URL,
+		AuthorityURL: server.URL,
+	}
+
+	t.Run("should default to v1.0", func(t *testing.T) {
+		service := msgraphapi.NewMsGraphApiService(config)
+		assert.Equal(t, msgraphapi.GRAPH_API_VERSION, service.Config.APIVersion)
+
+		site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), service, "/sites/site-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Team Site", site.DisplayName)
+	})
+
+	t.Run("should call beta when configured on the service", func(t *testing.T) {
+		betaConfig := config
+		betaConfig.APIVersion = msgraphapi.GRAPH_API_BETA
+		service := msgraphapi.NewMsGraphApiService(betaConfig)
+
+		site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), service, "/sites/site-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Beta Site", site.DisplayName)
+	})
+
+	t.Run("should call beta for a single call only", func(t *testing.T) {
+		service := msgraphapi.NewMsGraphApiService(config)
+
+		site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), service.WithAPIVersion(msgraphapi.GRAPH_API_BETA), "/sites/site-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Beta Site", site.DisplayName)
+
+		site, err = msgraphapi.GetResource[msgraphapi.Site](context.Background(), service, "/sites/site-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Team Site", site.DisplayName)
+	})
+
+	t.Run("should use the default version for new services", func(t *testing.T) {
+		defer func(version string) { msgraphapi.DefaultAPIVersion = version }(msgraphapi.DefaultAPIVersion)
+		msgraphapi.DefaultAPIVersion = msgraphapi.GRAPH_API_BETA
+
+		service := msgraphapi.NewMsGraphApiService(config)
+
+		site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), service, "/sites/site-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Beta Site", site.DisplayName)
+	})
+}
+
+// stubTransport answers every request without a network and records the
+// user agents it was sent with
+type stubTransport struct {
+	userAgents []string
+}
+
+func (s *stubTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	s.userAgents = append(s.userAgents, r.Header.Get("User-Agent"))
+
+	body := `{"id":"site-1","displayName":"Team Site"}`
+	if strings.HasSuffix(r.URL.Path, "/oauth2/token") {
+		body = `{"access_token":"access_token","expires_in":3600}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+func TestUserAgent(t *testing.T) {
+	defer func(userAgent string) { utils.OutboundUserAgent = userAgent }(utils.OutboundUserAgent)
+	utils.OutboundUserAgent = "spsyncpro-api/1.2.3"
+
+	transport := &stubTransport{}
+	service := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+		ClientID:     "client",
+		TenantID:     "tenant",
+		ClientSecret: "secret",
+		TokenCache:   msgraphapi.NewTokenCache(),
+		Transport:    transport,
+	})
+
+	site, err := msgraphapi.GetResource[msgraphapi.Site](context.Background(), service, "/sites/site-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Team Site", site.DisplayName)
+
+	// the token request and the graph request
+	assert.Equal(t, []string{"spsyncpro-api/1.2.3", "spsyncpro-api/1.2.3"}, transport.userAgents)
+}
+
+func TestGetAccessToken_AuthFailed(t *testing.T) {
+	newService := func(server *httptest.Server) *msgraphapi.MsGraphApiService {
+		return msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+			ClientID:     "client",
+			TenantID:     "tenant",
+			ClientSecret: "secret",
+			AuthorityURL: server.URL,
+			Limiter:      msgraphapi.NewTenantLimiter(0),
+			TokenCache:   msgraphapi.NewTokenCache(),
+		})
+	}
+
+	tests := []struct {
+		name        string
+		status      int
+		code        string
+		description string
+	}{
+		{
+			name:        "should fail on an unknown tenant",
+			status:      http.StatusBadRequest,
+			code:        "invalid_request",
+			description: "AADSTS90002: Tenant 'tenant' not found.",
+		},
+		{
+			name:        "should fail on an invalid client secret",
+			status:      http.StatusUnauthorized,
+			code:        "invalid_client",
+			description: "AADSTS7000215: Invalid client secret provided.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(map[string]any{"error": tt.code, "error_description": tt.description})
+			}))
+			defer server.Close()
+
+			token, err := newService(server).GetAccessToken(context.Background())
+			assert.Empty(t, token)
+			assert.ErrorIs(t, err, msgraphapi.ErrMsGraphAuthFailed)
+
+			var authErr *msgraphapi.AuthError
+			assert.ErrorAs(t, err, &authErr)
+			assert.Equal(t, tt.status, authErr.StatusCode)
+			assert.Equal(t, tt.code, authErr.Code)
+			assert.Equal(t, tt.description, authErr.Description)
+		})
+	}
+
+	t.Run("should fail on a response without a token", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"token_type": "Bearer"})
+		}))
+		defer server.Close()
+
+		token, err := newService(server).GetAccessToken(context.Background())
+		assert.Empty(t, token)
+		assert.ErrorIs(t, err, msgraphapi.ErrMsGraphAuthFailed)
+	})
+
+	t.Run("should time out a hanging token endpoint", func(t *testing.T) {
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer server.Close()
+		defer close(done)
+
+		service := newService(server)
+		service.Config.Timeout = 50 * time.Millisecond
+		token, err := msgraphapi.NewMsGraphApiService(service.Config).GetAccessToken(context.Background())
+		assert.Empty(t, token)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, msgraphapi.ErrMsGraphAuthFailed)
+	})
+}
+
+func TestGetAccessToken_UnexpectedResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: "should explain an html page instead of a token", status: http.StatusOK},
+		{name: "should not mistake a proxy error page for an auth failure", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(tt.status)
+				w.Write([]byte("<html>\n  <body>\n    <h1>Sign in to the guest network</h1>\n  </body>\n</html>"))
+			}))
+			defer server.Close()
+
+			service := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+				ClientID:     "client",
+				TenantID:     "tenant",
+				ClientSecret: "secret",
+				AuthorityURL: server.URL,
+				Limiter:      msgraphapi.NewTenantLimiter(0),
+				TokenCache:   msgraphapi.NewTokenCache(),
+			})
+
+			token, err := service.GetAccessToken(context.Background())
+			assert.Empty(t, token)
+			assert.ErrorIs(t, err, msgraphapi.ErrUnexpectedResponse)
+			assert.NotErrorIs(t, err, msgraphapi.ErrMsGraphAuthFailed)
+			assert.NotContains(t, err.Error(), "invalid character")
+
+			var unexpectedErr *msgraphapi.UnexpectedResponseError
+			assert.ErrorAs(t, err, &unexpectedErr)
+			assert.Equal(t, tt.status, unexpectedErr.StatusCode)
+			assert.Equal(t, "text/html; charset=utf-8", unexpectedErr.ContentType)
+			assert.Equal(t, "<html> <body> <h1>Sign in to the guest network</h1> </body> </html>", unexpectedErr.Snippet)
+		})
+	}
+}
+
+func TestListSites(t *testing.T) {
+	pages := map[string]msgraphapi.MsGraphResponse[msgraphapi.Site]{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tenant/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "access_token", "expires_in": 3600})
+	})
+	mux.HandleFunc("GET /v1.0/sites", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer access_token", r.Header.Get("Authorization"))
+		assert.Equal(t, "*", r.URL.Query().Get("search"))
+
+		page, ok := pages[r.URL.Query().Get("$skiptoken")]
+		if !ok {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	service := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+		ClientID:     "client",
+		TenantID:     "tenant",
+		ClientSecret: "secret",
+		BaseURL:      server.URL,
+		AuthorityURL: server.URL,
+		Limiter:      msgraphapi.NewTenantLimiter(0),
+		TokenCache:   msgraphapi.NewTokenCache(),
+		RetryPolicy:  &msgraphapi.RetryPolicy{},
+	})
+
+	nextLink := func(skipToken string) string {
+		return server.URL + "/v1.0/sites?search=*&$skiptoken=" + skipToken
+	}
+
+	t.Run("should aggregate every page", func(t *testing.T) {
+		pages = map[string]msgraphapi.MsGraphResponse[msgraphapi.Site]{
+			"": {
+				Value: []msgraphapi.Site{{ID: "site-1", DisplayName: "One"}, {ID: "site-2", DisplayName: "Two"}},
+				Next:  nextLink("page-2"),
+			},
+			"page-2": {
+				Value: []msgraphapi.Site{{ID: "site-3", DisplayName: "Three"}},
+				Next:  nextLink("page-3"),
+			},
+			"page-3": {
+				Value: []msgraphapi.Site{{ID: "site-4", DisplayName: "Four", WebURL: "https://contoso.sharepoint.com/sites/four"}},
+			},
+		}
+
+		sites, err := service.ListSites(context.Background())
+		assert.NoError(t, err)
+
+		var ids []string
+		for _, site := range sites {
+			ids = append(ids, site.ID)
+		}
+		assert.Equal(t, []string{"site-1", "site-2", "site-3", "site-4"}, ids)
+		assert.Equal(t, "https://contoso.sharepoint.com/sites/four", sites[3].WebURL)
+	})
+
+	t.Run("should return a throttled page as an error", func(t *testing.T) {
+		pages = map[string]msgraphapi.MsGraphResponse[msgraphapi.Site]{
+			"": {Value: []msgraphapi.Site{{ID: "site-1"}}, Next: nextLink("throttled")},
+		}
+
+		sites, err := service.ListSites(context.Background())
+		assert.Nil(t, sites)
+		assert.ErrorIs(t, err, msgraphapi.ErrGraphThrottled)
+
+		var graphErr *msgraphapi.GraphError
+		assert.ErrorAs(t, err, &graphErr)
+		assert.Equal(t, 5*time.Second, graphErr.RetryAfter)
+	})
+
+	t.Run("should not follow a next link off the graph host", func(t *testing.T) {
+		pages = map[string]msgraphapi.MsGraphResponse[msgraphapi.Site]{
+			"": {Value: []msgraphapi.Site{{ID: "site-1"}}, Next: "https://attacker.example/v1.0/sites"},
+		}
+
+		sites, err := service.ListSites(context.Background())
+		assert.Nil(t, sites)
+		assert.ErrorIs(t, err, msgraphapi.ErrUnexpectedResponse)
+	})
+}
+
+func TestListResources(t *testing.T) {
+	type item struct {
+		ID string `json:"id"`
+	}
+
+	// serves /v1.0/items in pages of one item, the page count is set per test
+	var lastPage int
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tenant/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "access_token", "expires_in": 3600})
+	})
+	var server *httptest.Server
+	mux.HandleFunc("GET /v1.0/items", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// every follow up request carries the token too
+		assert.Equal(t, "Bearer access_token", r.Header.Get("Authorization"))
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		response := msgraphapi.MsGraphResponse[item]{Value: []item{{ID: strconv.Itoa(page)}}}
+		if page < lastPage {
+			response.Next = fmt.Sprintf("%s/v1.0/items?page=%d", server.URL, page+1)
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	service := msgraphapi.NewMsGraphApiService(msgraphapi.MsGraphApiConfig{
+		ClientID:     "client",
+		TenantID:     "tenant",
+		ClientSecret: "secret",
+		BaseURL:      server.URL,
+		AuthorityURL: server.URL,
+		Limiter:      msgraphapi.NewTenantLimiter(0),
+		TokenCache:   msgraphapi.NewTokenCache(),
+		MaxPages:     3,
+	})
+
+	ids := func(items []item) []string {
+		var ids []string
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+
+	t.Run("should read a single page", func(t *testing.T) {
+		lastPage = 0
+		requests.Store(0)
+
+		items, err := msgraphapi.ListResources[item](context.Background(), service, "/items?page=0")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"0"}, ids(items))
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("should follow next links up to the page cap", func(t *testing.T) {
+		lastPage = 2
+		requests.Store(0)
+
+		items, err := msgraphapi.ListResources[item](context.Background(), service, "/items?page=0")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"0", "1", "2"}, ids(items))
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("should stop a collection beyond the page cap", func(t *testing.T) {
+		lastPage = 1000
+		requests.Store(0)
+
+		items, err := msgraphapi.ListResources[item](context.Background(), service, "/items?page=0")
+		assert.Nil(t, items)
+		assert.ErrorIs(t, err, msgraphapi.ErrTooManyPages)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+}
@@ -0,0 +1,80 @@
+package msgraphapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin drops a cached token this long before Azure AD expires
+// it, so a token is never sent with only seconds left
+const tokenExpiryMargin = 2 * time.Minute
+
+// TokenCache keeps client credentials tokens until shortly before they
+// expire, it is safe for concurrent use
+type TokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+type cachedToken struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+// DefaultTokenCache is shared by services created without a TokenCache, the
+// services are created per request so the cache has to outlive them
+var DefaultTokenCache = NewTokenCache()
+
+func NewTokenCache() *TokenCache {
+	return &TokenCache{
+		tokens: make(map[string]cachedToken),
+	}
+}
+
+// Get returns the token cached under key while it is not expired
+func (c *TokenCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token, ok := c.tokens[key]
+	if !ok {
+		return "", false
+	}
+	if !time.Now().Before(token.expiresAt) {
+		delete(c.tokens, key)
+		return "", false
+	}
+	return token.accessToken, true
+}
+
+// Set caches a token issued with expiresIn, less the safety margin. A token
+// that expires within the margin is not cached.
+func (c *TokenCache) Set(key string, accessToken string, expiresIn time.Duration) {
+	lifetime := expiresIn - tokenExpiryMargin
+	if lifetime <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[key] = cachedToken{
+		accessToken: accessToken,
+		expiresAt:   time.Now().Add(lifetime),
+	}
+}
+
+// Delete drops the token cached under key
+func (c *TokenCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, key)
+}
+
+// tokenCacheKey identifies the credentials a token was issued for, the secret
+// is part of it so a rotated secret never reuses a token of the old one
+func tokenCacheKey(authorityURL string, config MsGraphApiConfig) string {
+	secret := sha256.Sum256([]byte(config.ClientSecret))
+	return authorityURL + "|" + config.TenantID + "|" + config.ClientID + "|" + hex.EncodeToString(secret[:])
+}
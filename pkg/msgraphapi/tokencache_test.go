@@ -0,0 +1,123 @@
+package msgraphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/msgraphapi"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTokenServer counts token requests and issues tokens with expiresIn seconds
+func newTokenServer(requests *atomic.Int32, expiresIn int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": fmt.Sprintf("token-%d", n), "expires_in": expiresIn})
+	}))
+}
+
+func TestGetAccessToken_Cached(t *testing.T) {
+	config := func(server *httptest.Server, secret string) msgraphapi.MsGraphApiConfig {
+		return msgraphapi.MsGraphApiConfig{
+			ClientID:     "client",
+			TenantID:     "tenant",
+			ClientSecret: secret,
+			AuthorityURL: server.URL,
+			Limiter:      msgraphapi.NewTenantLimiter(0),
+			TokenCache:   msgraphapi.NewTokenCache(),
+		}
+	}
+
+	t.Run("should reuse the token within its lifetime", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newTokenServer(&requests, 3600)
+		defer server.Close()
+
+		cfg := config(server, "secret")
+		first, err := msgraphapi.NewMsGraphApiService(cfg).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+
+		// a new service for the same credentials shares the cache
+		second, err := msgraphapi.NewMsGraphApiService(cfg).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("should not cache a token expiring within the safety margin", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newTokenServer(&requests, 60)
+		defer server.Close()
+
+		service := msgraphapi.NewMsGraphApiService(config(server, "secret"))
+		for range 2 {
+			_, err := service.GetAccessToken(context.Background())
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("should request a new token for a different secret", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newTokenServer(&requests, 3600)
+		defer server.Close()
+
+		cfg := config(server, "secret")
+		_, err := msgraphapi.NewMsGraphApiService(cfg).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+
+		cfg.ClientSecret = "rotated"
+		_, err = msgraphapi.NewMsGraphApiService(cfg).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("should request a new token after it was discarded", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newTokenServer(&requests, 3600)
+		defer server.Close()
+
+		cfg := config(server, "secret")
+		first, err := msgraphapi.NewMsGraphApiService(cfg).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+
+		msgraphapi.NewMsGraphApiService(cfg).DiscardAccessToken()
+
+		second, err := msgraphapi.NewMsGraphApiService(cfg).GetAccessToken(context.Background())
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, first, second)
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("should request a token once for concurrent callers", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newTokenServer(&requests, 3600)
+		defer server.Close()
+
+		service := msgraphapi.NewMsGraphApiService(config(server, "secret"))
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.GetAccessToken(context.Background())
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), requests.Load())
+	})
+}
@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"spsyncpro_api/pkg/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountFromContext returns the account loaded for the request by the
+// account loading middleware, false on routes that do not load it
+func AccountFromContext(c *gin.Context) (*domain.Account, bool) {
+	value, ok := c.Get(AccountContextKey)
+	if !ok {
+		return nil, false
+	}
+	acc, ok := value.(*domain.Account)
+	return acc, ok && acc != nil
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"spsyncpro_api/pkg/domain"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// BindJSONWithAudit decodes the JSON body into obj and returns a snapshot of
+// the body with secret fields redacted. The body is read once and cached on
+// the context, so later binds and middleware can still read it.
+func BindJSONWithAudit(c *gin.Context, obj any) (string, error) {
+	if err := c.ShouldBindBodyWith(obj, binding.JSON); err != nil {
+		return "", err
+	}
+
+	body, _ := c.Get(gin.BodyBytesKey)
+	raw, _ := body.([]byte)
+
+	snapshot := RedactJSON(raw)
+	c.Set(AuditBodyContextKey, snapshot)
+
+	return snapshot, nil
+}
+
+// RedactJSON returns data with the values of secret keys replaced at any
+// depth, input that is not JSON is redacted entirely
+func RedactJSON(data []byte) string {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return RedactedValue
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return RedactedValue
+	}
+	return string(redacted)
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if IsSecretKey(key) {
+				v[key] = RedactedValue
+				continue
+			}
+			v[key] = redactValue(field)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func init() {
+	// field errors name the json field the client sent, not the go field
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(requestFieldName)
+	}
+}
+
+func requestFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// RespondBindError writes a failed bind of the request. A body that breaks
+// the binding tags of its struct is a 422 listing every failed field, a body
+// that cannot be decoded at all stays a 400.
+func RespondBindError(c *gin.Context, err error) {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		RespondErrorStatus(c, http.StatusBadRequest, err)
+		return
+	}
+
+	fields := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		fields = append(fields, fieldMessage(fieldError))
+	}
+	RespondError(c, &domain.ValidationError{Fields: fields})
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	field := fieldError.Field()
+	param := fieldError.Param()
+
+	switch fieldError.Tag() {
+	case "required":
+		return field + " is required"
+	case "email":
+		return field + " must be a valid email address"
+	case "url", "http_url":
+		return field + " must be a valid url"
+	case "oneof":
+		return field + " must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "min", "max":
+		bound := "at least"
+		if fieldError.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fieldError.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("%s must be %s %s characters", field, bound, param)
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return fmt.Sprintf("%s must have %s %s items", field, bound, param)
+		default:
+			return fmt.Sprintf("%s must be %s %s", field, bound, param)
+		}
+	default:
+		return field + " is invalid"
+	}
+}
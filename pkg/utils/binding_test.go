@@ -0,0 +1,113 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/utils"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindJSONWithAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type request struct {
+		TenantID     string `json:"tenant_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+
+	t.Run("should bind the body and redact secrets in the snapshot", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"tenant_id":"tenant","client_secret":"plaintext-secret"}`))
+
+		var req request
+		snapshot, err := utils.BindJSONWithAudit(c, &req)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "tenant", req.TenantID)
+		assert.Equal(t, "plaintext-secret", req.ClientSecret)
+
+		assert.NotContains(t, snapshot, "plaintext-secret")
+		assert.Contains(t, snapshot, `"client_secret":"[REDACTED]"`)
+		assert.Contains(t, snapshot, `"tenant_id":"tenant"`)
+		assert.Equal(t, snapshot, c.GetString(utils.AuditBodyContextKey))
+
+		var again request
+		assert.NoError(t, c.ShouldBindBodyWithJSON(&again))
+		assert.Equal(t, req, again)
+	})
+
+	t.Run("should redact nested secrets", func(t *testing.T) {
+		snapshot := utils.RedactJSON([]byte(`{"items":[{"password":"hunter2","name":"a"}]}`))
+		assert.Equal(t, `{"items":[{"name":"a","password":"[REDACTED]"}]}`, snapshot)
+	})
+
+	t.Run("should fail on malformed json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(`{"tenant_id":`)))
+
+		var req request
+		_, err := utils.BindJSONWithAudit(c, &req)
+		assert.Error(t, err)
+	})
+}
+
+func TestRespondBindError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type request struct {
+		Email  string   `json:"email" binding:"required,email"`
+		Name   string   `json:"name" binding:"required,min=2,max=5"`
+		Role   string   `json:"role" binding:"omitempty,oneof=user admin"`
+		Scopes []string `json:"scopes" binding:"max=1"`
+		Count  int      `form:"count" binding:"min=1"`
+	}
+
+	bind := func(body string) (*httptest.ResponseRecorder, utils.ErrorResponse) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+		var req request
+		err := c.ShouldBindJSON(&req)
+		assert.Error(t, err)
+		utils.RespondBindError(c, err)
+
+		var response utils.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	t.Run("should list every missing field by its json name", func(t *testing.T) {
+		w, response := bind(`{"count":1}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, "request.validation_failed", response.Code)
+		assert.Equal(t, []string{"email is required", "name is required"}, response.Details)
+	})
+
+	t.Run("should describe each failed rule", func(t *testing.T) {
+		w, response := bind(`{"email":"not-an-email","name":"x","role":"owner","scopes":["a","b"],"count":0}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, []string{
+			"email must be a valid email address",
+			"name must be at least 2 characters",
+			"role must be one of user, admin",
+			"scopes must have at most 1 items",
+			"count must be at least 1",
+		}, response.Details)
+	})
+
+	t.Run("should keep a malformed body a bad request", func(t *testing.T) {
+		w, response := bind(`{"email":`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "request.invalid", response.Code)
+		assert.Empty(t, response.Details)
+	})
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// Cache-Control defaults of the read endpoints, each can be overridden through
+// its CACHE_CONTROL_* config key
+const (
+	// CacheControlPrivate keeps account data out of shared and browser caches
+	CacheControlPrivate = "private, no-store"
+	// CacheControlVersion lets the version be cached briefly, it only changes on deploy
+	CacheControlVersion = "public, max-age=60"
+	// CacheControlHealth keeps probes from reading a stale status for long
+	CacheControlHealth = "public, max-age=5"
+)
+
+// SetCacheControl sets the Cache-Control header to the value of configKey,
+// or to fallback when the key is not configured
+func SetCacheControl(c *gin.Context, configKey string, fallback string) {
+	value := viper.GetString(configKey)
+	if value == "" {
+		value = fallback
+	}
+	c.Header("Cache-Control", value)
+}
@@ -0,0 +1,24 @@
+package utils
+
+import "time"
+
+// Clock tells the current time, services take one in place of calling
+// time.Now so tests can move time instead of sleeping
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is the wall clock
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// ClockFunc adapts a function to a Clock, a test clock is a func returning a
+// time it controls
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
@@ -1,5 +1,10 @@
 package utils
 
 const (
-	AccountIdContextKey = "account_id"
+	AccountIdContextKey      = "account_id"
+	AuthClaimsContextKey     = "auth_claims"
+	AccountContextKey        = "account"
+	AuditBodyContextKey      = "audit_body"
+	CookieAuthContextKey     = "cookie_auth"
+	OrganizationIdContextKey = "organization_id"
 )
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"net/mail"
+	"spsyncpro_api/pkg/domain"
+	"strings"
+)
+
+// NormalizeEmail trims and lowercases an email address so the same mailbox
+// always maps to one account, it returns domain.ErrInvalidEmail for anything
+// that is not a bare addr-spec with a dotted domain
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", domain.ErrInvalidEmail
+	}
+
+	// rejects display names and angle brackets, "Name <a@b.c>" is not an email
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", domain.ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	host := email[at+1:]
+	if !strings.Contains(host, ".") || strings.HasPrefix(host, ".") || strings.HasSuffix(host, ".") || strings.Contains(host, "..") {
+		return "", domain.ErrInvalidEmail
+	}
+	return email, nil
+}
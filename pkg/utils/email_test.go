@@ -0,0 +1,54 @@
+package utils_test
+
+import (
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{name: "should keep a normalized email", raw: "test@example.com", expected: "test@example.com"},
+		{name: "should lowercase a mixed case email", raw: "Test@Example.COM", expected: "test@example.com"},
+		{name: "should trim surrounding whitespace", raw: "  test@example.com\n", expected: "test@example.com"},
+		{name: "should keep plus addressing", raw: "test+sync@example.com", expected: "test+sync@example.com"},
+		{name: "should keep a subdomain", raw: "test@mail.example.co.uk", expected: "test@mail.example.co.uk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, err := utils.NormalizeEmail(tt.raw)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+
+	invalid := []struct {
+		name string
+		raw  string
+	}{
+		{name: "should reject an empty email", raw: "   "},
+		{name: "should reject a missing at sign", raw: "test.example.com"},
+		{name: "should reject a missing local part", raw: "@example.com"},
+		{name: "should reject a missing domain", raw: "test@"},
+		{name: "should reject an undotted domain", raw: "test@localhost"},
+		{name: "should reject a trailing dot", raw: "test@example.com."},
+		{name: "should reject consecutive dots", raw: "test@example..com"},
+		{name: "should reject a display name", raw: "Test <test@example.com>"},
+		{name: "should reject inner whitespace", raw: "te st@example.com"},
+		{name: "should reject two addresses", raw: "a@example.com, b@example.com"},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := utils.NormalizeEmail(tt.raw)
+			assert.ErrorIs(t, err, domain.ErrInvalidEmail)
+		})
+	}
+}
@@ -1,81 +1,218 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ErrUnknownEncryptionKey is returned for ciphertext written under a key id
+// that is neither the primary nor one of the previous keys
+var ErrUnknownEncryptionKey = errors.New("ciphertext was encrypted with an unknown key")
+
+const (
+	// keyIDSeparator ends the key id in front of the base64 ciphertext, it
+	// is not part of the base64 alphabet
+	keyIDSeparator = ":"
+	// keyIDSize is the length of the raw key id leading byte ciphertext
+	keyIDSize = 4
 )
 
-// Encryptor provides authenticated encryption using AES-GCM
+// Encryptor provides authenticated encryption using AES-GCM. It encrypts with
+// the primary key and decrypts with the primary or any previous key, so
+// ENCRYPTION_KEY can be rotated while stored data is re-encrypted with
+// ReEncrypt. Byte ciphertext is the raw key id, the nonce and the sealed
+// data. String ciphertext is "<hex key id>:<base64 of the rest>", ciphertext
+// written before key ids has no prefix and is tried against every key
 type Encryptor struct {
-	key []byte
+	keys []encryptionKey
+}
+
+type encryptionKey struct {
+	id    string
+	rawID []byte
+	key   []byte
+}
+
+// NewEncryptor creates a new encryptor with the provided primary key and the
+// previous keys still accepted for decryption, newest first. Keys must be 16,
+// 24, or 32 bytes (AES-128, AES-192, or AES-256)
+func NewEncryptor(key []byte, previous ...[]byte) (*Encryptor, error) {
+	encryptor := &Encryptor{}
+	for _, k := range append([][]byte{key}, previous...) {
+		if len(k) != 16 && len(k) != 24 && len(k) != 32 {
+			return nil, fmt.Errorf("invalid key size: must be 16, 24, or 32 bytes, got %d", len(k))
+		}
+		id := EncryptionKeyID(k)
+		rawID, _ := hex.DecodeString(id)
+		encryptor.keys = append(encryptor.keys, encryptionKey{id: id, rawID: rawID, key: k})
+	}
+	return encryptor, nil
 }
 
-// NewEncryptor creates a new encryptor with the provided key
-// Key must be 16, 24, or 32 bytes (AES-128, AES-192, or AES-256)
-func NewEncryptor(key []byte) (*Encryptor, error) {
-	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
-		return nil, fmt.Errorf("invalid key size: must be 16, 24, or 32 bytes")
+// NewEncryptorFromConfig creates the encryptor of ENCRYPTION_KEY with the
+// comma separated ENCRYPTION_PREVIOUS_KEYS
+func NewEncryptorFromConfig() (*Encryptor, error) {
+	var previous [][]byte
+	for _, key := range strings.Split(viper.GetString("ENCRYPTION_PREVIOUS_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			previous = append(previous, []byte(key))
+		}
 	}
-	return &Encryptor{key: key}, nil
+	return NewEncryptor([]byte(viper.GetString("ENCRYPTION_KEY")), previous...)
+}
+
+// EncryptionKeyID identifies key in ciphertext without revealing it
+func EncryptionKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:keyIDSize])
 }
 
-// Encrypt encrypts the plaintext using AES-GCM and returns base64 encoded result
-// The result includes the nonce and ciphertext concatenated together
+// Encrypt encrypts the plaintext with EncryptBytes and returns the hex key
+// id and the base64 of the nonce and sealed data
 func (e *Encryptor) Encrypt(plaintext string) (string, error) {
-	// Create AES cipher block
-	block, err := aes.NewCipher(e.key)
+	ciphertext, err := e.EncryptBytes([]byte(plaintext))
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return "", err
 	}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+	// Encode to base64 for safe string representation behind the key id
+	id, sealed := ciphertext[:keyIDSize], ciphertext[keyIDSize:]
+	return hex.EncodeToString(id) + keyIDSeparator + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// EncryptBytes encrypts the plaintext using AES-GCM under the primary key, the
+// result is the raw key id, the nonce and the sealed data concatenated
+func (e *Encryptor) EncryptBytes(plaintext []byte) ([]byte, error) {
+	primary := e.keys[0]
+	gcm, err := newGCM(primary.key)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return nil, err
 	}
 
 	// Generate random nonce
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", fmt.Errorf("failed to generate nonce: %w", err)
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	// Encrypt and authenticate the plaintext
-	plaintextBytes := []byte(plaintext)
-	ciphertext := gcm.Seal(nonce, nonce, plaintextBytes, nil)
-
-	// Encode to base64 for safe string representation
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	// Encrypt and authenticate the plaintext behind the key id and nonce
+	ciphertext := make([]byte, 0, keyIDSize+len(nonce)+len(plaintext)+gcm.Overhead())
+	ciphertext = append(ciphertext, primary.rawID...)
+	ciphertext = append(ciphertext, nonce...)
+	return gcm.Seal(ciphertext, nonce, plaintext, nil), nil
 }
 
-// Decrypt decrypts the base64 encoded ciphertext and verifies authenticity
+// Decrypt decrypts the ciphertext with the key named by its key id, or with
+// each key in turn for ciphertext without one, and verifies authenticity
 func (e *Encryptor) Decrypt(ciphertext string) (string, error) {
-	// Decode from base64
-	ciphertextBytes, err := base64.StdEncoding.DecodeString(ciphertext)
+	plaintext, _, err := e.decrypt(ciphertext)
+	return string(plaintext), err
+}
+
+// DecryptBytes decrypts ciphertext from EncryptBytes with the key named by its
+// key id and verifies authenticity
+func (e *Encryptor) DecryptBytes(ciphertext []byte) ([]byte, error) {
+	plaintext, _, err := e.decryptBytes(ciphertext)
+	return plaintext, err
+}
+
+// ReEncrypt moves ciphertext to the primary key, reencrypted is false when it
+// already is under the primary key and the ciphertext is returned unchanged
+func (e *Encryptor) ReEncrypt(ciphertext string) (result string, reencrypted bool, err error) {
+	plaintext, key, err := e.decrypt(ciphertext)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode base64: %w", err)
+		return "", false, err
+	}
+	if key.id == e.keys[0].id && strings.HasPrefix(ciphertext, key.id+keyIDSeparator) {
+		return ciphertext, false, nil
 	}
 
-	// Create AES cipher block
-	block, err := aes.NewCipher(e.key)
+	result, err = e.Encrypt(string(plaintext))
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return "", false, err
 	}
+	return result, true, nil
+}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+// decrypt returns the plaintext of string ciphertext and the key that opened it
+func (e *Encryptor) decrypt(ciphertext string) ([]byte, encryptionKey, error) {
+	id, encoded, hasID := strings.Cut(ciphertext, keyIDSeparator)
+	if !hasID {
+		encoded = ciphertext
+	}
+
+	// Decode from base64
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, encryptionKey{}, fmt.Errorf("failed to decode base64: %w", err)
+	}
+
+	if hasID {
+		rawID, err := hex.DecodeString(id)
+		if err != nil || len(rawID) != keyIDSize {
+			return nil, encryptionKey{}, ErrUnknownEncryptionKey
+		}
+		return e.decryptBytes(append(rawID, sealed...))
+	}
+
+	// written before key ids, the newest key that authenticates it wins
+	for _, key := range e.keys {
+		var plaintext []byte
+		plaintext, err = open(key.key, sealed)
+		if err == nil {
+			return plaintext, key, nil
+		}
+	}
+	return nil, encryptionKey{}, err
+}
+
+// decryptBytes returns the plaintext of byte ciphertext and the key that
+// opened it
+func (e *Encryptor) decryptBytes(ciphertext []byte) ([]byte, encryptionKey, error) {
+	if len(ciphertext) < keyIDSize {
+		return nil, encryptionKey{}, fmt.Errorf("ciphertext too short")
+	}
+
+	key, ok := e.keyByID(ciphertext[:keyIDSize])
+	if !ok {
+		return nil, encryptionKey{}, ErrUnknownEncryptionKey
+	}
+	plaintext, err := open(key.key, ciphertext[keyIDSize:])
+	return plaintext, key, err
+}
+
+// keyByID returns the primary or previous key with the raw id
+func (e *Encryptor) keyByID(rawID []byte) (encryptionKey, bool) {
+	for _, key := range e.keys {
+		if bytes.Equal(key.rawID, rawID) {
+			return key, true
+		}
+	}
+	return encryptionKey{}, false
+}
+
+// open decrypts nonce and ciphertext concatenated together under key
+func open(key []byte, ciphertextBytes []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return nil, err
 	}
 
 	// Check minimum length (nonce + some ciphertext)
 	nonceSize := gcm.NonceSize()
 	if len(ciphertextBytes) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
+		return nil, fmt.Errorf("ciphertext too short")
 	}
 
 	// Extract nonce and ciphertext
@@ -84,8 +221,23 @@ func (e *Encryptor) Decrypt(ciphertext string) (string, error) {
 	// Decrypt and verify authenticity
 	plaintextBytes, err := gcm.Open(nil, nonce, ciphertextOnly, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt or authenticate: %w", err)
+		return nil, fmt.Errorf("failed to decrypt or authenticate: %w", err)
+	}
+
+	return plaintextBytes, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	// Create AES cipher block
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
-	return string(plaintextBytes), nil
+	// Create GCM mode
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
 }
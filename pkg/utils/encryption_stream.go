@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// streamChunkSize is the plaintext sealed per chunk, a stream never holds
+	// more than one chunk in memory
+	streamChunkSize = 64 * 1024
+	// streamFrameHeaderSize is the final flag and the sealed chunk length
+	streamFrameHeaderSize = 1 + 4
+)
+
+// ErrTruncatedStream is returned by DecryptStream when the stream ends before
+// its final chunk or carries data after it
+var ErrTruncatedStream = errors.New("encrypted stream is truncated or has trailing data")
+
+// EncryptStream encrypts src into dst with the primary key in chunks, for
+// payloads too large to hold in memory. The stream is the raw key id and a
+// nonce base, followed by frames of a final flag, the sealed length and the
+// sealed chunk. Each chunk is sealed under the nonce base xor its index with
+// the flag as additional data, so chunks cannot be reordered, dropped or
+// truncated without DecryptStream failing.
+func (e *Encryptor) EncryptStream(dst io.Writer, src io.Reader) error {
+	primary := e.keys[0]
+	gcm, err := newGCM(primary.key)
+	if err != nil {
+		return err
+	}
+
+	nonceBase := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonceBase); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := dst.Write(append(append([]byte{}, primary.rawID...), nonceBase...)); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(src, streamChunkSize)
+	chunk := make([]byte, streamChunkSize)
+	frame := make([]byte, 0, streamFrameHeaderSize+streamChunkSize+gcm.Overhead())
+	for index := uint64(0); ; index++ {
+		n, err := io.ReadFull(reader, chunk)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+
+		// a full chunk is only the last one when nothing follows it
+		final := n < streamChunkSize
+		if !final {
+			if _, err := reader.Peek(1); errors.Is(err, io.EOF) {
+				final = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		flag := streamFlag(final)
+		frame = append(frame[:0], flag[0], 0, 0, 0, 0)
+		frame = gcm.Seal(frame, streamNonce(nonceBase, index), chunk[:n], flag)
+		binary.BigEndian.PutUint32(frame[1:streamFrameHeaderSize], uint32(len(frame)-streamFrameHeaderSize))
+		if _, err := dst.Write(frame); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// DecryptStream decrypts a stream written by EncryptStream into dst, with the
+// key named by its key id. Chunks are authenticated before they are written,
+// but a failure part way leaves the chunks before it in dst.
+func (e *Encryptor) DecryptStream(dst io.Writer, src io.Reader) error {
+	reader := bufio.NewReader(src)
+
+	rawID := make([]byte, keyIDSize)
+	if _, err := io.ReadFull(reader, rawID); err != nil {
+		return ErrTruncatedStream
+	}
+	key, ok := e.keyByID(rawID)
+	if !ok {
+		return ErrUnknownEncryptionKey
+	}
+	gcm, err := newGCM(key.key)
+	if err != nil {
+		return err
+	}
+
+	nonceBase := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(reader, nonceBase); err != nil {
+		return ErrTruncatedStream
+	}
+
+	header := make([]byte, streamFrameHeaderSize)
+	sealed := make([]byte, streamChunkSize+gcm.Overhead())
+	for index := uint64(0); ; index++ {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return ErrTruncatedStream
+		}
+		flag := header[:1]
+		length := binary.BigEndian.Uint32(header[1:])
+		if flag[0] > 1 || length < uint32(gcm.Overhead()) || length > uint32(len(sealed)) {
+			return fmt.Errorf("failed to decrypt or authenticate: invalid chunk header")
+		}
+		if _, err := io.ReadFull(reader, sealed[:length]); err != nil {
+			return ErrTruncatedStream
+		}
+
+		plaintext, err := gcm.Open(sealed[:0], streamNonce(nonceBase, index), sealed[:length], flag)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt or authenticate: %w", err)
+		}
+		if _, err := dst.Write(plaintext); err != nil {
+			return err
+		}
+
+		if flag[0] == 1 {
+			if _, err := reader.Peek(1); !errors.Is(err, io.EOF) {
+				return ErrTruncatedStream
+			}
+			return nil
+		}
+	}
+}
+
+func streamFlag(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// streamNonce xors the chunk index into the last 8 bytes of the nonce base
+func streamNonce(nonceBase []byte, index uint64) []byte {
+	nonce := append([]byte{}, nonceBase...)
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], index)
+	for i := range counter {
+		nonce[len(nonce)-8+i] ^= counter[i]
+	}
+	return nonce
+}
@@ -1,7 +1,11 @@
 package utils_test
 
 import (
+	"bytes"
+	"crypto/rand"
+	"io"
 	"spsyncpro_api/pkg/utils"
+	"strings"
 
 	"testing"
 
@@ -31,3 +35,215 @@ func TestEncryptDecrypt(t *testing.T) {
 
 	assert.Equal(t, plaintext, decrypted2, "Decrypted text is not equal to the original text")
 }
+
+func TestEncryptor_KeyRotation(t *testing.T) {
+	oldKey := []byte("myoldpasswordthatwas32bytes-long")
+	newKey := []byte("myverystrongpasswordo32bitlength")
+
+	t.Run("should prefix the ciphertext with the primary key id", func(t *testing.T) {
+		encryptor, err := utils.NewEncryptor(newKey, oldKey)
+		assert.NoError(t, err)
+
+		ciphertext, err := encryptor.Encrypt("secret")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(ciphertext, utils.EncryptionKeyID(newKey)+":"))
+	})
+
+	t.Run("should decrypt data written under a previous key", func(t *testing.T) {
+		old, err := utils.NewEncryptor(oldKey)
+		assert.NoError(t, err)
+		ciphertext, err := old.Encrypt("secret")
+		assert.NoError(t, err)
+
+		rotated, err := utils.NewEncryptor(newKey, oldKey)
+		assert.NoError(t, err)
+		plaintext, err := rotated.Decrypt(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+
+		// once the old key is dropped the data is unreadable
+		dropped, err := utils.NewEncryptor(newKey)
+		assert.NoError(t, err)
+		_, err = dropped.Decrypt(ciphertext)
+		assert.ErrorIs(t, err, utils.ErrUnknownEncryptionKey)
+	})
+
+	t.Run("should decrypt ciphertext written before key ids with any key", func(t *testing.T) {
+		old, err := utils.NewEncryptor(oldKey)
+		assert.NoError(t, err)
+		ciphertext, err := old.Encrypt("secret")
+		assert.NoError(t, err)
+		_, legacy, _ := strings.Cut(ciphertext, ":")
+
+		rotated, err := utils.NewEncryptor(newKey, oldKey)
+		assert.NoError(t, err)
+		plaintext, err := rotated.Decrypt(legacy)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+
+		dropped, err := utils.NewEncryptor(newKey)
+		assert.NoError(t, err)
+		_, err = dropped.Decrypt(legacy)
+		assert.Error(t, err)
+	})
+
+	t.Run("should re-encrypt data under the primary key", func(t *testing.T) {
+		old, err := utils.NewEncryptor(oldKey)
+		assert.NoError(t, err)
+		ciphertext, err := old.Encrypt("secret")
+		assert.NoError(t, err)
+
+		rotated, err := utils.NewEncryptor(newKey, oldKey)
+		assert.NoError(t, err)
+		reencrypted, changed, err := rotated.ReEncrypt(ciphertext)
+		assert.NoError(t, err)
+		assert.True(t, changed)
+		assert.True(t, strings.HasPrefix(reencrypted, utils.EncryptionKeyID(newKey)+":"))
+
+		// readable without the old key, and already current on the next run
+		current, err := utils.NewEncryptor(newKey)
+		assert.NoError(t, err)
+		plaintext, err := current.Decrypt(reencrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+
+		again, changed, err := rotated.ReEncrypt(reencrypted)
+		assert.NoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, reencrypted, again)
+	})
+
+	t.Run("should reject a previous key of the wrong size", func(t *testing.T) {
+		_, err := utils.NewEncryptor(newKey, []byte("short"))
+		assert.ErrorContains(t, err, "got 5")
+	})
+}
+
+func TestEncryptor_Bytes(t *testing.T) {
+	oldKey := []byte("myoldpasswordthatwas32bytes-long")
+	newKey := []byte("myverystrongpasswordo32bitlength")
+	binary := []byte{0x00, 0xff, 0x00, 0x00, 'a', 0x00, 0x7f, 0x80, 0x00}
+
+	t.Run("should round trip binary data with null bytes", func(t *testing.T) {
+		encryptor, err := utils.NewEncryptor(newKey)
+		assert.NoError(t, err)
+
+		ciphertext, err := encryptor.EncryptBytes(binary)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(ciphertext), string(binary))
+
+		plaintext, err := encryptor.DecryptBytes(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, binary, plaintext)
+	})
+
+	t.Run("should round trip an empty payload", func(t *testing.T) {
+		encryptor, err := utils.NewEncryptor(newKey)
+		assert.NoError(t, err)
+
+		ciphertext, err := encryptor.EncryptBytes(nil)
+		assert.NoError(t, err)
+		plaintext, err := encryptor.DecryptBytes(ciphertext)
+		assert.NoError(t, err)
+		assert.Empty(t, plaintext)
+	})
+
+	t.Run("should decrypt bytes written under a previous key", func(t *testing.T) {
+		previous, err := utils.NewEncryptor(oldKey)
+		assert.NoError(t, err)
+		ciphertext, err := previous.EncryptBytes(binary)
+		assert.NoError(t, err)
+
+		rotated, err := utils.NewEncryptor(newKey, oldKey)
+		assert.NoError(t, err)
+		plaintext, err := rotated.DecryptBytes(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, binary, plaintext)
+
+		current, err := utils.NewEncryptor(newKey)
+		assert.NoError(t, err)
+		_, err = current.DecryptBytes(ciphertext)
+		assert.ErrorIs(t, err, utils.ErrUnknownEncryptionKey)
+	})
+
+	t.Run("should reject tampered bytes", func(t *testing.T) {
+		encryptor, err := utils.NewEncryptor(newKey)
+		assert.NoError(t, err)
+		ciphertext, err := encryptor.EncryptBytes(binary)
+		assert.NoError(t, err)
+
+		ciphertext[len(ciphertext)-1] ^= 0x01
+		_, err = encryptor.DecryptBytes(ciphertext)
+		assert.Error(t, err)
+	})
+
+	t.Run("should keep string ciphertext readable by the byte methods", func(t *testing.T) {
+		encryptor, err := utils.NewEncryptor(newKey)
+		assert.NoError(t, err)
+
+		ciphertext, err := encryptor.Encrypt("hello\x00world")
+		assert.NoError(t, err)
+		plaintext, err := encryptor.Decrypt(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello\x00world", plaintext)
+	})
+}
+
+func TestEncryptor_Stream(t *testing.T) {
+	key := []byte("myverystrongpasswordo32bitlength")
+	encryptor, err := utils.NewEncryptor(key)
+	assert.NoError(t, err)
+
+	// spans several chunks and ends part way through one, with null bytes
+	payload := make([]byte, 200*1024+7)
+	_, err = rand.Read(payload)
+	assert.NoError(t, err)
+	payload[0], payload[len(payload)-1] = 0x00, 0x00
+
+	encrypt := func(t *testing.T, data []byte) []byte {
+		var ciphertext bytes.Buffer
+		assert.NoError(t, encryptor.EncryptStream(&ciphertext, bytes.NewReader(data)))
+		return ciphertext.Bytes()
+	}
+
+	t.Run("should round trip a stream", func(t *testing.T) {
+		for _, size := range []int{0, 1, 64 * 1024, len(payload)} {
+			var plaintext bytes.Buffer
+			err := encryptor.DecryptStream(&plaintext, bytes.NewReader(encrypt(t, payload[:size])))
+			assert.NoError(t, err, "size %d", size)
+			assert.True(t, bytes.Equal(payload[:size], plaintext.Bytes()), "size %d", size)
+		}
+	})
+
+	t.Run("should reject a truncated stream", func(t *testing.T) {
+		ciphertext := encrypt(t, payload)
+
+		// cut on the first chunk boundary, every frame before it is intact
+		boundary := 4 + 12 + 5 + 64*1024 + 16
+		err := encryptor.DecryptStream(io.Discard, bytes.NewReader(ciphertext[:boundary]))
+		assert.ErrorIs(t, err, utils.ErrTruncatedStream)
+
+		err = encryptor.DecryptStream(io.Discard, bytes.NewReader(ciphertext[:len(ciphertext)-1]))
+		assert.ErrorIs(t, err, utils.ErrTruncatedStream)
+	})
+
+	t.Run("should reject trailing data", func(t *testing.T) {
+		ciphertext := append(encrypt(t, payload), 0x00)
+		err := encryptor.DecryptStream(io.Discard, bytes.NewReader(ciphertext))
+		assert.ErrorIs(t, err, utils.ErrTruncatedStream)
+	})
+
+	t.Run("should reject a tampered chunk", func(t *testing.T) {
+		ciphertext := encrypt(t, payload)
+		ciphertext[len(ciphertext)/2] ^= 0x01
+		err := encryptor.DecryptStream(io.Discard, bytes.NewReader(ciphertext))
+		assert.ErrorContains(t, err, "failed to decrypt or authenticate")
+	})
+
+	t.Run("should reject a stream under an unknown key", func(t *testing.T) {
+		other, err := utils.NewEncryptor([]byte("myoldpasswordthatwas32bytes-long"))
+		assert.NoError(t, err)
+		err = other.DecryptStream(io.Discard, bytes.NewReader(encrypt(t, payload)))
+		assert.ErrorIs(t, err, utils.ErrUnknownEncryptionKey)
+	})
+}
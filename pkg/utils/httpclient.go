@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"net/http"
+	"time"
+)
+
+// OutboundUserAgent is sent on Graph, webhook and other outbound requests
+// that do not set their own, routing sets it from OUTBOUND_USER_AGENT
+var OutboundUserAgent = "spsyncpro-api"
+
+// NewHTTPClient returns the client for calls to external services, base sends
+// the requests and is http.DefaultTransport when nil
+func NewHTTPClient(timeout time.Duration, base http.RoundTripper) *http.Client {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &userAgentTransport{base: base},
+	}
+}
+
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get("User-Agent") != "" || OutboundUserAgent == "" {
+		return t.base.RoundTrip(request)
+	}
+	// a round tripper must not modify the caller's request
+	request = request.Clone(request.Context())
+	request.Header.Set("User-Agent", OutboundUserAgent)
+	return t.base.RoundTrip(request)
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+var ErrInvalidIPAllowList = errors.New("invalid ip allow list")
+
+// IPAllowList matches client IPs against addresses and CIDRs, a nil or empty
+// list matches nothing
+type IPAllowList struct {
+	prefixes []netip.Prefix
+}
+
+// ParseIPAllowList reads a comma separated list of IPs and CIDRs such as
+// "10.0.0.0/8, 203.0.113.7", a bare IP matches only itself
+func ParseIPAllowList(value string) (*IPAllowList, error) {
+	list := &IPAllowList{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidIPAllowList, err)
+			}
+			list.prefixes = append(list.prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidIPAllowList, err)
+		}
+		addr = addr.Unmap()
+		list.prefixes = append(list.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return list, nil
+}
+
+// Contains reports whether ip is in the list, an unparsable ip is never in it
+func (l *IPAllowList) Contains(ip string) bool {
+	if l == nil || len(l.prefixes) == 0 {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return false
+	}
+	// an IPv4 client seen through an IPv6 socket matches IPv4 entries
+	addr = addr.Unmap()
+	for _, prefix := range l.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
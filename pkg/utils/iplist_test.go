@@ -0,0 +1,49 @@
+package utils_test
+
+import (
+	"spsyncpro_api/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPAllowList(t *testing.T) {
+	list, err := utils.ParseIPAllowList(" 10.0.0.0/8, 203.0.113.7 ,2001:db8::/32,")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		ip       string
+		expected bool
+	}{
+		{name: "should match an ip in a cidr", ip: "10.20.30.40", expected: true},
+		{name: "should match a single ip", ip: "203.0.113.7", expected: true},
+		{name: "should match an ipv6 cidr", ip: "2001:db8::1", expected: true},
+		{name: "should match an ipv4 mapped ipv6 address", ip: "::ffff:10.0.0.1", expected: true},
+		{name: "should not match a neighbour of a single ip", ip: "203.0.113.8", expected: false},
+		{name: "should not match an ip outside the cidrs", ip: "192.168.1.1", expected: false},
+		{name: "should not match an unparsable ip", ip: "not-an-ip", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, list.Contains(tt.ip))
+		})
+	}
+
+	t.Run("should match nothing when empty", func(t *testing.T) {
+		empty, err := utils.ParseIPAllowList("")
+		assert.NoError(t, err)
+		assert.False(t, empty.Contains("10.0.0.1"))
+
+		var unset *utils.IPAllowList
+		assert.False(t, unset.Contains("10.0.0.1"))
+	})
+
+	t.Run("should reject an invalid entry", func(t *testing.T) {
+		for _, value := range []string{"10.0.0.0/33", "10.0.0", "office"} {
+			_, err := utils.ParseIPAllowList(value)
+			assert.ErrorIs(t, err, utils.ErrInvalidIPAllowList, value)
+		}
+	})
+}
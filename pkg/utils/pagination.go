@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"spsyncpro_api/pkg/domain"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// Pagination is the page of a list request
+type Pagination struct {
+	Limit  int
+	Offset int
+}
+
+type paginationQuery struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+// ParsePagination reads the limit and offset query parameters, a missing
+// limit is defaultLimit and a limit above maxLimit is capped. A negative
+// offset is ErrInvalidPagination.
+func ParsePagination(c *gin.Context, defaultLimit, maxLimit int) (Pagination, error) {
+	var query paginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return Pagination{}, err
+	}
+	if query.Offset < 0 {
+		return Pagination{}, domain.ErrInvalidPagination
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultLimit
+	}
+
+	return Pagination{
+		Limit:  min(query.Limit, maxLimit),
+		Offset: query.Offset,
+	}, nil
+}
+
+// SortFields are the sort values a list endpoint accepts, each mapped to the
+// column it orders by. Only whitelisted columns ever reach the query.
+type SortFields map[string]string
+
+// Sorting is the order of a list request
+type Sorting struct {
+	Field string
+	Order string
+
+	column string
+}
+
+type sortingQuery struct {
+	Sort  string `form:"sort"`
+	Order string `form:"order"`
+}
+
+// ParseSorting reads the sort and order query parameters. A sort outside
+// fields is ErrInvalidSort and an order other than asc or desc is
+// ErrInvalidSortOrder, either falls back to its default when missing.
+func ParseSorting(c *gin.Context, fields SortFields, defaultField string, defaultOrder string) (Sorting, error) {
+	var query sortingQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return Sorting{}, err
+	}
+
+	field := query.Sort
+	if field == "" {
+		field = defaultField
+	}
+	column, ok := fields[field]
+	if !ok {
+		return Sorting{}, domain.ErrInvalidSort
+	}
+
+	order := strings.ToLower(query.Order)
+	if order == "" {
+		order = defaultOrder
+	}
+	if order != SortAsc && order != SortDesc {
+		return Sorting{}, domain.ErrInvalidSortOrder
+	}
+
+	return Sorting{Field: field, Order: order, column: column}, nil
+}
+
+// Scope orders a query by the sort column, ties are broken by id in the same
+// direction so pages never overlap
+func (s Sorting) Scope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		desc := s.Order == SortDesc
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: s.column}, Desc: desc})
+		if s.column != "id" {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc})
+		}
+		return db
+	}
+}
@@ -0,0 +1,97 @@
+package utils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newQueryContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	return c
+}
+
+func TestParsePagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("should default the limit", func(t *testing.T) {
+		page, err := utils.ParsePagination(newQueryContext(""), 20, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, utils.Pagination{Limit: 20, Offset: 0}, page)
+	})
+
+	t.Run("should cap the limit", func(t *testing.T) {
+		page, err := utils.ParsePagination(newQueryContext("limit=500&offset=40"), 20, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, utils.Pagination{Limit: 100, Offset: 40}, page)
+	})
+
+	t.Run("should reject a negative offset", func(t *testing.T) {
+		_, err := utils.ParsePagination(newQueryContext("offset=-1"), 20, 100)
+		assert.ErrorIs(t, err, domain.ErrInvalidPagination)
+	})
+
+	t.Run("should reject a limit that is not a number", func(t *testing.T) {
+		_, err := utils.ParsePagination(newQueryContext("limit=ten"), 20, 100)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseSorting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fields := utils.SortFields{
+		"id":    "id",
+		"email": "email",
+		"name":  "display_name",
+	}
+
+	// toSQL renders the order clause the sorting scope adds to a query
+	toSQL := func(t *testing.T, sorting utils.Sorting) string {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DryRun: true})
+		assert.NoError(t, err)
+		stmt := db.Table("accounts").Scopes(sorting.Scope()).Find(&[]map[string]any{}).Statement
+		return stmt.SQL.String()
+	}
+
+	t.Run("should use the defaults", func(t *testing.T) {
+		sorting, err := utils.ParseSorting(newQueryContext(""), fields, "email", utils.SortDesc)
+		assert.NoError(t, err)
+		assert.Equal(t, "email", sorting.Field)
+		assert.Equal(t, utils.SortDesc, sorting.Order)
+		assert.Contains(t, toSQL(t, sorting), "ORDER BY `email` DESC,`id` DESC")
+	})
+
+	t.Run("should sort by a whitelisted field", func(t *testing.T) {
+		sorting, err := utils.ParseSorting(newQueryContext("sort=name&order=ASC"), fields, "email", utils.SortDesc)
+		assert.NoError(t, err)
+		assert.Equal(t, "name", sorting.Field)
+		assert.Equal(t, utils.SortAsc, sorting.Order)
+		assert.Contains(t, toSQL(t, sorting), "ORDER BY `display_name`,`id`")
+	})
+
+	t.Run("should not add the id twice", func(t *testing.T) {
+		sorting, err := utils.ParseSorting(newQueryContext("sort=id"), fields, "email", utils.SortAsc)
+		assert.NoError(t, err)
+		assert.Contains(t, toSQL(t, sorting), "ORDER BY `id`")
+		assert.NotContains(t, toSQL(t, sorting), "`id`,`id`")
+	})
+
+	t.Run("should reject a field that is not whitelisted", func(t *testing.T) {
+		_, err := utils.ParseSorting(newQueryContext("sort=password"), fields, "email", utils.SortDesc)
+		assert.ErrorIs(t, err, domain.ErrInvalidSort)
+	})
+
+	t.Run("should reject an unknown order", func(t *testing.T) {
+		_, err := utils.ParseSorting(newQueryContext("order=sideways"), fields, "email", utils.SortDesc)
+		assert.ErrorIs(t, err, domain.ErrInvalidSortOrder)
+	})
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const RedactedValue = "[REDACTED]"
+
+// secretKeyMarkers are matched case-insensitively against attribute and log field keys
+var secretKeyMarkers = []string{
+	"secret",
+	"password",
+	"token",
+	"authorization",
+}
+
+// IsSecretKey reports whether a field key is known to carry sensitive data
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range secretKeyMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactAttributes returns a copy of attrs with the values of secret keys replaced
+func RedactAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	redacted := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		if IsSecretKey(string(attr.Key)) {
+			attr = attribute.String(string(attr.Key), RedactedValue)
+		}
+		redacted[i] = attr
+	}
+	return redacted
+}
+
+// RedactHook is a logrus hook that redacts secret fields before an entry is written
+type RedactHook struct{}
+
+func (h *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *RedactHook) Fire(entry *logrus.Entry) error {
+	for key := range entry.Data {
+		if IsSecretKey(key) {
+			entry.Data[key] = RedactedValue
+		}
+	}
+	return nil
+}
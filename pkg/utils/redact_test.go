@@ -0,0 +1,37 @@
+package utils_test
+
+import (
+	"bytes"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRedactAttributes(t *testing.T) {
+	attrs := []attribute.KeyValue{
+		attribute.String("client_secret", "plaintext-secret"),
+		attribute.String("tenant_id", "tenant"),
+	}
+
+	redacted := utils.RedactAttributes(attrs)
+
+	assert.Equal(t, utils.RedactedValue, redacted[0].Value.AsString())
+	assert.Equal(t, "tenant", redacted[1].Value.AsString())
+	assert.Equal(t, "plaintext-secret", attrs[0].Value.AsString(), "input should not be mutated")
+}
+
+func TestRedactHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(&utils.RedactHook{})
+
+	logger.WithField("client_secret", "plaintext-secret").WithField("tenant_id", "tenant").Info("upsert")
+
+	assert.NotContains(t, buf.String(), "plaintext-secret")
+	assert.Contains(t, buf.String(), utils.RedactedValue)
+	assert.Contains(t, buf.String(), "tenant")
+}
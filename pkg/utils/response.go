@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"spsyncpro_api/pkg/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// responseLogger reports responses that failed to encode
+var responseLogger logrus.FieldLogger = logrus.StandardLogger()
+
+// SetResponseLogger replaces the logger RespondJSON reports encoding failures to
+func SetResponseLogger(logger logrus.FieldLogger) {
+	responseLogger = logger
+}
+
+// ErrorResponse is the error envelope returned by every endpoint
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	// Details lists what made a request invalid, for errors that carry them
+	Details []string `json:"details,omitempty"`
+}
+
+// detailedError is implemented by errors with details for the client, such as
+// the unmet rules of a password policy
+type detailedError interface {
+	Details() []string
+}
+
+// RespondError writes err in the standard error envelope with the status and
+// code mapped to it in the domain
+func RespondError(c *gin.Context, err error) {
+	RespondErrorStatus(c, domain.ErrorStatus(err), err)
+}
+
+// RespondErrorStatus writes err with an explicit status, for errors without a
+// domain mapping such as request binding failures
+func RespondErrorStatus(c *gin.Context, status int, err error) {
+	response := ErrorResponse{
+		Error: err.Error(),
+		Code:  domain.ErrorCode(err, status),
+	}
+
+	var detailed detailedError
+	if errors.As(err, &detailed) {
+		response.Details = detailed.Details()
+	}
+	RespondJSON(c, status, response)
+}
+
+// RespondJSON encodes value before anything is written, so a value that fails
+// to encode becomes a clean 500 error envelope instead of a truncated body
+// behind an already committed status
+func RespondJSON(c *gin.Context, status int, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		responseLogger.WithField("path", c.FullPath()).Errorf("failed to encode response: %v", err)
+		_ = c.Error(err)
+
+		body, _ = json.Marshal(ErrorResponse{
+			Error: domain.ErrInternal.Error(),
+			Code:  domain.ErrorCode(domain.ErrInternal, http.StatusInternalServerError),
+		})
+		status = http.StatusInternalServerError
+	}
+
+	c.Data(status, "application/json; charset=utf-8", body)
+}
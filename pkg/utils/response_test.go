@@ -0,0 +1,175 @@
+package utils_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/domain"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// respond writes err with status, or with its mapped status when status is 0
+func respond(status int, err error) (*httptest.ResponseRecorder, utils.ErrorResponse) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	if status == 0 {
+		utils.RespondError(c, err)
+	} else {
+		utils.RespondErrorStatus(c, status, err)
+	}
+
+	var response utils.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestRespondError(t *testing.T) {
+
+	t.Run("should return the stable code of each mapped error", func(t *testing.T) {
+		codes := map[error]string{
+			domain.ErrInternal:                 "server.internal",
+			domain.ErrUnauthorized:             "auth.unauthorized",
+			domain.ErrForbidden:                "auth.forbidden",
+			domain.ErrInvalidCredentials:       "auth.invalid_credentials",
+			domain.ErrInvalidOldPassword:       "auth.invalid_old_password",
+			domain.ErrTokenGenerationFailed:    "auth.token_generation_failed",
+			domain.ErrInvalidRefreshToken:      "auth.invalid_refresh_token",
+			domain.ErrRefreshTokenRevoked:      "auth.refresh_token_revoked",
+			domain.ErrInvalidTokenType:         "auth.invalid_token_type",
+			domain.ErrResetTokenInvalid:        "auth.reset_token_invalid",
+			domain.ErrTooManyLoginAttempts:     "auth.too_many_attempts",
+			domain.ErrAccountAlreadyExists:     "account.already_exists",
+			domain.ErrAccountNotFound:          "account.not_found",
+			domain.ErrAccountDisabled:          "account.disabled",
+			domain.ErrAccountLocked:            "account.locked",
+			domain.ErrPasswordEmpty:            "account.password_empty",
+			domain.ErrEmailNotVerified:         "account.email_not_verified",
+			domain.ErrEmailAlreadyVerified:     "account.email_already_verified",
+			domain.ErrVerificationTokenInvalid: "account.verification_token_invalid",
+			domain.ErrPendingActionNotFound:    "account.pending_action_not_found",
+			domain.ErrPendingActionExpired:     "account.pending_action_expired",
+			domain.ErrTokenRequired:            "request.token_required",
+			domain.ErrVerificationEmailFailed:  "email.delivery_failed",
+			domain.ErrResetEmailFailed:         "email.delivery_failed",
+			domain.ErrOrganizationNotFound:     "org.not_found",
+		}
+
+		for err, code := range codes {
+			w, response := respond(http.StatusBadRequest, err)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, err.Error(), response.Error)
+			assert.Equal(t, code, response.Code, err.Error())
+		}
+	})
+
+	t.Run("should respond with the status mapped to the error", func(t *testing.T) {
+		statuses := map[error]int{
+			domain.ErrInternal:             http.StatusInternalServerError,
+			domain.ErrUnauthorized:         http.StatusUnauthorized,
+			domain.ErrInvalidCredentials:   http.StatusBadRequest,
+			domain.ErrAccountNotFound:      http.StatusNotFound,
+			domain.ErrAccountLocked:        http.StatusLocked,
+			domain.ErrTooManyLoginAttempts: http.StatusTooManyRequests,
+			domain.ErrOrganizationNotFound: http.StatusNotFound,
+			errors.New("unmapped"):         http.StatusInternalServerError,
+		}
+
+		for err, status := range statuses {
+			w, _ := respond(0, err)
+			assert.Equal(t, status, w.Code, err.Error())
+		}
+	})
+
+	t.Run("should match wrapped errors", func(t *testing.T) {
+		_, response := respond(http.StatusNotFound, fmt.Errorf("lookup failed: %w", domain.ErrOrganizationNotFound))
+		assert.Equal(t, "org.not_found", response.Code)
+	})
+
+	t.Run("should include the details of an error that has them", func(t *testing.T) {
+		err := &domain.PasswordPolicyError{Unmet: []string{domain.PasswordRuleMinLength, domain.PasswordRuleDigit}}
+		w, response := respond(0, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "account.password_weak", response.Code)
+		assert.Equal(t, []string{"min_length", "digit"}, response.Details)
+
+		w, _ = respond(0, domain.ErrAccountNotFound)
+		assert.NotContains(t, w.Body.String(), "details")
+	})
+
+	t.Run("should fall back to a code for the status", func(t *testing.T) {
+		_, response := respond(http.StatusBadRequest, errors.New("EOF"))
+		assert.Equal(t, "request.invalid", response.Code)
+
+		_, response = respond(http.StatusInternalServerError, errors.New("connection refused"))
+		assert.Equal(t, "server.internal", response.Code)
+	})
+}
+
+// failingMarshaler cannot be encoded, like a response carrying a NaN or a channel
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("cannot encode")
+}
+
+func TestRespondJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("should write the encoded value with its status", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		utils.RespondJSON(c, http.StatusCreated, map[string]string{"message": "created"})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"message":"created"}`, w.Body.String())
+	})
+
+	t.Run("should respond with a clean 500 when the value fails to encode", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := logrus.New()
+		logger.SetOutput(&logs)
+		utils.SetResponseLogger(logger)
+		defer utils.SetResponseLogger(logrus.StandardLogger())
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		utils.RespondJSON(c, http.StatusOK, gin.H{"message": "partial", "value": failingMarshaler{}})
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.NotContains(t, w.Body.String(), "partial")
+
+		var response utils.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "server.internal", response.Code)
+
+		assert.Contains(t, logs.String(), "failed to encode response")
+		assert.Len(t, c.Errors, 1)
+	})
+
+	t.Run("should respond with a clean 500 for an unsupported value", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		utils.SetResponseLogger(logrus.New())
+		defer utils.SetResponseLogger(logrus.StandardLogger())
+
+		utils.RespondJSON(c, http.StatusOK, map[string]any{"ratio": math.NaN()})
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"error":"internal server error","code":"server.internal"}`, w.Body.String())
+	})
+}
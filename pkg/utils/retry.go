@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy retries an operation with a delay that doubles from BaseDelay
+// up to MaxDelay
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt, 1 or less runs the operation once
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Retry runs fn until it succeeds, fails with an error retryable rejects or
+// the attempts run out, the last error is returned. A cancelled ctx stops the
+// wait between attempts.
+func Retry(ctx context.Context, policy RetryPolicy, retryable func(error) bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		delay := policy.BaseDelay << (attempt - 1)
+		if delay <= 0 || (policy.MaxDelay > 0 && delay > policy.MaxDelay) {
+			delay = policy.MaxDelay
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
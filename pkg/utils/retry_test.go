@@ -0,0 +1,64 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+	retryable := func(err error) bool { return errors.Is(err, errTransient) }
+	policy := utils.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	t.Run("should retry a transient error until it succeeds", func(t *testing.T) {
+		calls := 0
+		err := utils.Retry(context.Background(), policy, retryable, func() error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("should return the last error once the attempts run out", func(t *testing.T) {
+		calls := 0
+		err := utils.Retry(context.Background(), policy, retryable, func() error {
+			calls++
+			return errTransient
+		})
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("should not retry an error the predicate rejects", func(t *testing.T) {
+		calls := 0
+		err := utils.Retry(context.Background(), policy, retryable, func() error {
+			calls++
+			return errPermanent
+		})
+		assert.ErrorIs(t, err, errPermanent)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should stop waiting when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calls := 0
+		err := utils.Retry(ctx, utils.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}, retryable, func() error {
+			calls++
+			return errTransient
+		})
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,18 @@
+package utils
+
+import "context"
+
+type sessionIDKey struct{}
+
+// WithSessionID returns a context carrying the jti of the token behind the
+// request, activities logged with it can be grouped by session
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, sessionID)
+}
+
+// SessionIDFromContext returns the session id set by WithSessionID, empty
+// when the request was not made with a session token
+func SessionIDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionIDKey{}).(string)
+	return sessionID
+}
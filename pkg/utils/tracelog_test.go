@@ -0,0 +1,37 @@
+package utils_test
+
+import (
+	"context"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestTraceHook(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	logger.AddHook(&utils.TraceHook{})
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	t.Run("should add the ids of the span in the context", func(t *testing.T) {
+		ctx, span := tp.Tracer("test").Start(context.Background(), "operation")
+		defer span.End()
+
+		logger.WithContext(ctx).Info("inside a span")
+
+		assert.Equal(t, span.SpanContext().TraceID().String(), hook.LastEntry().Data["trace_id"])
+		assert.Equal(t, span.SpanContext().SpanID().String(), hook.LastEntry().Data["span_id"])
+	})
+
+	t.Run("should leave entries outside a span alone", func(t *testing.T) {
+		logger.WithContext(context.Background()).Info("no span")
+		assert.NotContains(t, hook.LastEntry().Data, "trace_id")
+
+		logger.Info("no context")
+		assert.NotContains(t, hook.LastEntry().Data, "trace_id")
+	})
+}
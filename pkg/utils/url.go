@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidBaseURL = errors.New("invalid base url")
+
+// NormalizeBaseURL validates a configured base url like SERVER_URL. A missing
+// scheme defaults to https and trailing slashes are trimmed, so links built
+// from it never contain a double slash.
+func NormalizeBaseURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidBaseURL)
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	base, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidBaseURL, err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return "", fmt.Errorf("%w: scheme %q is not http or https", ErrInvalidBaseURL, base.Scheme)
+	}
+	if base.Host == "" {
+		return "", fmt.Errorf("%w: missing host", ErrInvalidBaseURL)
+	}
+	if base.RawQuery != "" || base.Fragment != "" {
+		return "", fmt.Errorf("%w: query and fragment are not allowed", ErrInvalidBaseURL)
+	}
+
+	base.Path = strings.TrimRight(base.Path, "/")
+	base.RawPath = ""
+
+	return base.String(), nil
+}
+
+// JoinURL appends path to the normalized base url and encodes query, the
+// path of the base is kept so the api can be served under a prefix
+func JoinURL(baseURL string, path string, query url.Values) (string, error) {
+	normalized, err := NormalizeBaseURL(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	link, err := url.Parse(normalized)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidBaseURL, err)
+	}
+	link = link.JoinPath(path)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,112 @@
+package utils_test
+
+import (
+	"net/url"
+	"spsyncpro_api/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeBaseURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{name: "should keep a url without trailing slash", raw: "http://localhost:8080", expected: "http://localhost:8080"},
+		{name: "should trim a trailing slash", raw: "http://localhost:8080/", expected: "http://localhost:8080"},
+		{name: "should trim repeated trailing slashes", raw: "https://api.example.com//", expected: "https://api.example.com"},
+		{name: "should default a missing scheme to https", raw: "api.example.com", expected: "https://api.example.com"},
+		{name: "should default a missing scheme with a port to https", raw: "localhost:8080", expected: "https://localhost:8080"},
+		{name: "should keep a path prefix", raw: "https://example.com/sync/", expected: "https://example.com/sync"},
+		{name: "should trim surrounding whitespace", raw: "  https://example.com  ", expected: "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, err := utils.NormalizeBaseURL(tt.raw)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+
+	invalid := []struct {
+		name string
+		raw  string
+	}{
+		{name: "should reject an empty url", raw: ""},
+		{name: "should reject an unsupported scheme", raw: "ftp://example.com"},
+		{name: "should reject a missing host", raw: "https://"},
+		{name: "should reject a query", raw: "https://example.com?next=/"},
+		{name: "should reject a fragment", raw: "https://example.com#top"},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := utils.NormalizeBaseURL(tt.raw)
+			assert.ErrorIs(t, err, utils.ErrInvalidBaseURL)
+		})
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		path     string
+		query    url.Values
+		expected string
+	}{
+		{
+			name:     "should join a path to a base without trailing slash",
+			base:     "http://localhost:8080",
+			path:     "/api/v1/account/reset-password",
+			expected: "http://localhost:8080/api/v1/account/reset-password",
+		},
+		{
+			name:     "should not double the slash after a trailing slash",
+			base:     "http://localhost:8080/",
+			path:     "/api/v1/account/reset-password",
+			expected: "http://localhost:8080/api/v1/account/reset-password",
+		},
+		{
+			name:     "should join a relative path",
+			base:     "https://example.com",
+			path:     "api/v1/account/forgot-password",
+			expected: "https://example.com/api/v1/account/forgot-password",
+		},
+		{
+			name:     "should keep the base path prefix",
+			base:     "https://example.com/sync/",
+			path:     "/api/v1/account/verify-email",
+			expected: "https://example.com/sync/api/v1/account/verify-email",
+		},
+		{
+			name:     "should encode the query",
+			base:     "https://example.com",
+			path:     "/api/v1/account/reset-password",
+			query:    url.Values{"token": {"a+b/c=&d"}},
+			expected: "https://example.com/api/v1/account/reset-password?token=a%2Bb%2Fc%3D%26d",
+		},
+		{
+			name:     "should add a scheme to the base",
+			base:     "example.com",
+			path:     "/login",
+			expected: "https://example.com/login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := utils.JoinURL(tt.base, tt.path, tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, link)
+		})
+	}
+
+	t.Run("should reject an invalid base", func(t *testing.T) {
+		_, err := utils.JoinURL("ftp://example.com", "/login", nil)
+		assert.ErrorIs(t, err, utils.ErrInvalidBaseURL)
+	})
+}
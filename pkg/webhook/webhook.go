@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"spsyncpro_api/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// SignatureHeader carries a "kid=<key id>,sha256=<hex hmac>" entry for
+	// every active secret separated by spaces, the current secret first. See
+	// Sign and KeyID.
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	IDHeader        = "X-Webhook-ID"
+
+	defaultTimeout = 5 * time.Second
+)
+
+// Event is the json body posted to a webhook
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the hex hmac-sha256 of "<timestamp>.<body>" under secret.
+// Receivers recompute it to authenticate the event and reject old timestamps
+// to stop replays.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// KeyID identifies secret in the signature header without revealing it, a
+// receiver holding the old and new secret during a rotation verifies the
+// entry of the secret it knows
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Signature returns the signature header entry of secret for the timestamp and body
+func Signature(secret string, timestamp string, body []byte) string {
+	return "kid=" + KeyID(secret) + ",sha256=" + Sign(secret, timestamp, body)
+}
+
+// Sender posts signed events to webhook urls
+type Sender struct {
+	tracer     trace.Tracer
+	httpClient *http.Client
+}
+
+// NewSender reads WEBHOOK_TIMEOUT, the timeout bounds each delivery
+func NewSender() *Sender {
+	timeout := viper.GetDuration("WEBHOOK_TIMEOUT")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Sender{
+		tracer:     otel.Tracer("webhookSender"),
+		httpClient: utils.NewHTTPClient(timeout, nil),
+	}
+}
+
+// Delivery is the outcome of posting an event, StatusCode is 0 when no
+// response was received
+type Delivery struct {
+	StatusCode int
+	Latency    time.Duration
+	Err        error
+}
+
+// Send posts the event to url signed with each of secrets, the current secret
+// first, a response outside 2xx is a failed delivery
+func (s *Sender) Send(ctx context.Context, url string, secrets []string, event Event) error {
+	return s.Deliver(ctx, url, secrets, event).Err
+}
+
+// Deliver posts the event like Send and reports the response status and how
+// long the receiver took along with the error
+func (s *Sender) Deliver(ctx context.Context, url string, secrets []string, event Event) Delivery {
+	ctx, span := s.tracer.Start(ctx, "Deliver")
+	defer span.End()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return Delivery{Err: err}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Delivery{Err: err}
+	}
+
+	timestamp := strconv.FormatInt(event.CreatedAt.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(IDHeader, event.ID)
+	request.Header.Set(EventHeader, event.Type)
+	request.Header.Set(TimestampHeader, timestamp)
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		signatures = append(signatures, Signature(secret, timestamp, body))
+	}
+	request.Header.Set(SignatureHeader, strings.Join(signatures, " "))
+
+	start := time.Now()
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return Delivery{Latency: time.Since(start), Err: err}
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	delivery := Delivery{StatusCode: response.StatusCode, Latency: time.Since(start)}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		delivery.Err = fmt.Errorf("webhook delivery failed with status %d", response.StatusCode)
+	}
+	return delivery
+}
@@ -0,0 +1,121 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"spsyncpro_api/pkg/webhook"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSender_Send(t *testing.T) {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	event := webhook.Event{
+		ID:        "evt_1",
+		Type:      "account.register",
+		CreatedAt: time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC),
+		Data:      map[string]any{"account_id": 1},
+	}
+
+	t.Run("should post the signed event", func(t *testing.T) {
+		var header http.Header
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		err := webhook.NewSender().Send(context.Background(), server.URL, []string{"secret"}, event)
+		assert.NoError(t, err)
+
+		timestamp := strconv.FormatInt(event.CreatedAt.Unix(), 10)
+		assert.Equal(t, "application/json", header.Get("Content-Type"))
+		assert.Equal(t, "evt_1", header.Get(webhook.IDHeader))
+		assert.Equal(t, "account.register", header.Get(webhook.EventHeader))
+		assert.Equal(t, timestamp, header.Get(webhook.TimestampHeader))
+		assert.Equal(t, "kid="+webhook.KeyID("secret")+",sha256="+webhook.Sign("secret", timestamp, body), header.Get(webhook.SignatureHeader))
+
+		var received webhook.Event
+		assert.NoError(t, json.Unmarshal(body, &received))
+		assert.Equal(t, event.ID, received.ID)
+		assert.Equal(t, event.Type, received.Type)
+		assert.True(t, event.CreatedAt.Equal(received.CreatedAt))
+	})
+
+	t.Run("should fail on a non 2xx response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		err := webhook.NewSender().Send(context.Background(), server.URL, []string{"secret"}, event)
+		assert.ErrorContains(t, err, "500")
+	})
+
+	t.Run("should report the status and latency of a delivery", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		delivery := webhook.NewSender().Deliver(context.Background(), server.URL, []string{"secret"}, event)
+		assert.Equal(t, http.StatusBadGateway, delivery.StatusCode)
+		assert.GreaterOrEqual(t, delivery.Latency, 10*time.Millisecond)
+		assert.ErrorContains(t, delivery.Err, "502")
+	})
+
+	t.Run("should sign with every secret, the current first", func(t *testing.T) {
+		var header http.Header
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		err := webhook.NewSender().Send(context.Background(), server.URL, []string{"new", "old"}, event)
+		assert.NoError(t, err)
+
+		timestamp := header.Get(webhook.TimestampHeader)
+		assert.Equal(t,
+			webhook.Signature("new", timestamp, body)+" "+webhook.Signature("old", timestamp, body),
+			header.Get(webhook.SignatureHeader),
+		)
+	})
+}
+
+func TestKeyID(t *testing.T) {
+	t.Run("should identify a secret without revealing it", func(t *testing.T) {
+		keyID := webhook.KeyID("secret")
+
+		assert.Len(t, keyID, 16)
+		assert.Equal(t, keyID, webhook.KeyID("secret"))
+		assert.NotEqual(t, keyID, webhook.KeyID("other"))
+		assert.NotContains(t, keyID, "secret")
+	})
+}
+
+func TestSign(t *testing.T) {
+	t.Run("should depend on the secret, timestamp and body", func(t *testing.T) {
+		signature := webhook.Sign("secret", "1", []byte("body"))
+
+		assert.Len(t, signature, 64)
+		assert.Equal(t, signature, webhook.Sign("secret", "1", []byte("body")))
+		assert.NotEqual(t, signature, webhook.Sign("other", "1", []byte("body")))
+		assert.NotEqual(t, signature, webhook.Sign("secret", "2", []byte("body")))
+		assert.NotEqual(t, signature, webhook.Sign("secret", "1", []byte("other")))
+	})
+}